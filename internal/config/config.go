@@ -18,6 +18,15 @@ type ServerConfig struct {
 	Log             *log    `json:"log,omitempty" yaml:"log,omitempty"`
 	Github          *Github `json:"github,omitempty" yaml:"github,omitempty"`
 	LogDataSaveDays int     `json:"logDataSaveDays,omitempty" yaml:"logDataSaveDays,omitempty"`
+	// MetricsSaveDays 监控数据 小时精度 保留天数，原始数据 保留1天，分钟数据 保留7天
+	MetricsSaveDays int `json:"metricsSaveDays,omitempty" yaml:"metricsSaveDays,omitempty"`
+	// Metrics /metrics 采集 接口，默认 关闭
+	Metrics *Metrics `json:"metrics,omitempty" yaml:"metrics,omitempty"`
+}
+
+type Metrics struct {
+	Open  bool   `json:"open,omitempty" yaml:"open,omitempty"`
+	Token string `json:"token,omitempty" yaml:"token,omitempty"` // 不为 空 时 需要 携带 Authorization: Bearer <token>
 }
 
 type server struct {
@@ -60,6 +69,7 @@ func CreateServerConfig(configPath string) (config *ServerConfig, err error) {
 
 	config = &ServerConfig{
 		LogDataSaveDays: 15,
+		MetricsSaveDays: 30,
 	}
 	if configPath != "" {
 		var exists bool
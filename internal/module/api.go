@@ -10,6 +10,7 @@ import (
 	"go.uber.org/zap"
 	"strings"
 	"teamide/internal/context"
+	"teamide/internal/module/module_alert"
 	"teamide/internal/module/module_database"
 	"teamide/internal/module/module_datamove"
 	"teamide/internal/module/module_elasticsearch"
@@ -37,11 +38,14 @@ import (
 	"teamide/internal/module/module_user"
 	"teamide/internal/module/module_zookeeper"
 	"teamide/pkg/base"
+	"teamide/pkg/system"
 	"time"
 )
 
 func NewApi(ServerContext *context.ServerContext) (api *Api, err error) {
 
+	nodeService := module_node.NewNodeService(ServerContext)
+	toolboxService := module_toolbox.NewToolboxService(ServerContext)
 	api = &Api{
 		ServerContext:          ServerContext,
 		userService:            module_user.NewUserService(ServerContext),
@@ -49,13 +53,15 @@ func NewApi(ServerContext *context.ServerContext) (api *Api, err error) {
 		registerService:        module_register.NewRegisterService(ServerContext),
 		loginService:           module_login.NewLoginService(ServerContext),
 		installService:         NewInstallService(ServerContext),
-		toolboxService:         module_toolbox.NewToolboxService(ServerContext),
-		nodeService:            module_node.NewNodeService(ServerContext),
+		toolboxService:         toolboxService,
+		nodeService:            nodeService,
+		alertService:           module_alert.NewAlertService(ServerContext, nodeService),
 		powerRoleService:       module_power.NewPowerRoleService(ServerContext),
 		powerRouteService:      module_power.NewPowerRouteService(ServerContext),
 		powerUserService:       module_power.NewPowerUserService(ServerContext),
 		settingService:         module_setting.NewSettingService(ServerContext),
 		terminalCommandService: module_terminal.NewTerminalCommandService(ServerContext),
+		databaseSqlService:     module_database.NewSqlService(ServerContext, toolboxService),
 		idService:              module_id.NewIDService(ServerContext),
 		logService:             module_log.NewLogService(ServerContext),
 		apiCache:               make(map[string]*base.ApiWorker),
@@ -93,6 +99,11 @@ func NewApi(ServerContext *context.ServerContext) (api *Api, err error) {
 			return
 		}
 	}
+	err = system.OpenMetricStore(ServerContext.ServerConfig.Server.Data+"metrics/", ServerContext.ServerConfig.MetricsSaveDays)
+	if err != nil {
+		return
+	}
+	go system.StartCollectMonitorData()
 	go api.nodeService.InitContext()
 
 	err = api.logService.ServerReady()
@@ -103,6 +114,10 @@ func NewApi(ServerContext *context.ServerContext) (api *Api, err error) {
 	if err != nil {
 		return
 	}
+	err = api.alertService.ServerReady()
+	if err != nil {
+		return
+	}
 
 	return
 }
@@ -130,6 +145,8 @@ type Api struct {
 	toolboxService         *module_toolbox.ToolboxService
 	nodeService            *module_node.NodeService
 	terminalCommandService *module_terminal.TerminalCommandService
+	alertService           *module_alert.AlertService
+	databaseSqlService     *module_database.SqlService
 	userService            *module_user.UserService
 	userSettingService     *module_user.UserSettingService
 	registerService        *module_register.RegisterService
@@ -175,9 +192,10 @@ func (this_ *Api) GetApis() (apis []*base.ApiWorker, err error) {
 	apis = append(apis, module_node.NewNodeApi(this_.nodeService).GetApis()...)
 	apis = append(apis, module_file_manager.NewApi(this_.toolboxService, this_.nodeService).GetApis()...)
 	apis = append(apis, module_terminal.NewApi(this_.toolboxService, this_.nodeService, this_.terminalCommandService).GetApis()...)
+	apis = append(apis, module_alert.NewApi(this_.alertService).GetApis()...)
 	apis = append(apis, module_user.NewApi(this_.userService).GetApis()...)
 	apis = append(apis, module_redis.NewApi(this_.toolboxService).GetApis()...)
-	apis = append(apis, module_database.NewApi(this_.toolboxService).GetApis()...)
+	apis = append(apis, module_database.NewApi(this_.toolboxService, this_.databaseSqlService, this_.hasPower).GetApis()...)
 	apis = append(apis, module_datamove.NewApi(this_.toolboxService).GetApis()...)
 	apis = append(apis, module_zookeeper.NewApi(this_.toolboxService).GetApis()...)
 	apis = append(apis, module_kafka.NewApi(this_.toolboxService).GetApis()...)
@@ -300,6 +318,9 @@ func (this_ *Api) DoApi(path string, c *gin.Context) bool {
 			if !api.IsUpload {
 				var data = make(map[string]interface{})
 				_ = c.ShouldBindBodyWith(&data, binding.JSON)
+				if len(data) > 0 && api.LogData != nil {
+					api.LogData(data)
+				}
 				if len(data) > 0 {
 					bs, _ := json.Marshal(data)
 					logRecode.Data = string(bs)
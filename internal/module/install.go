@@ -7,6 +7,8 @@ import (
 	"strings"
 	"teamide/internal/context"
 	"teamide/internal/install"
+	"teamide/internal/module/module_alert"
+	"teamide/internal/module/module_database"
 	"teamide/internal/module/module_id"
 	"teamide/internal/module/module_log"
 	"teamide/internal/module/module_login"
@@ -132,6 +134,16 @@ func (this_ *InstallService) Install() (err error) {
 		return
 	}
 
+	err = this_.InstallSteps(module_alert.GetInstallStages())
+	if err != nil {
+		return
+	}
+
+	err = this_.InstallSteps(module_database.GetInstallStages())
+	if err != nil {
+		return
+	}
+
 	return
 }
 
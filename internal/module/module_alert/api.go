@@ -0,0 +1,198 @@
+package module_alert
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"teamide/pkg/alert"
+	"teamide/pkg/base"
+	"time"
+)
+
+type api struct {
+	alertService *AlertService
+}
+
+func NewApi(alertService *AlertService) *api {
+	return &api{
+		alertService: alertService,
+	}
+}
+
+var (
+	// 告警 权限
+
+	// Power 告警 基本 权限
+	Power              = base.AppendPower(&base.PowerAction{Action: "alert", Text: "告警", ShouldLogin: true, StandAlone: true})
+	metricsPower       = base.AppendPower(&base.PowerAction{Action: "metrics", Text: "告警指标", ShouldLogin: true, StandAlone: true, Parent: Power})
+	rulePower          = base.AppendPower(&base.PowerAction{Action: "rule", Text: "告警规则", ShouldLogin: true, StandAlone: true, Parent: Power})
+	ruleListPower      = base.AppendPower(&base.PowerAction{Action: "list", Text: "告警规则列表", ShouldLogin: true, StandAlone: true, Parent: rulePower})
+	ruleSavePower      = base.AppendPower(&base.PowerAction{Action: "save", Text: "告警规则保存", ShouldLogin: true, StandAlone: true, Parent: rulePower})
+	ruleDeletePower    = base.AppendPower(&base.PowerAction{Action: "delete", Text: "告警规则删除", ShouldLogin: true, StandAlone: true, Parent: rulePower})
+	channelPower       = base.AppendPower(&base.PowerAction{Action: "channel", Text: "告警渠道", ShouldLogin: true, StandAlone: true, Parent: Power})
+	channelListPower   = base.AppendPower(&base.PowerAction{Action: "list", Text: "告警渠道列表", ShouldLogin: true, StandAlone: true, Parent: channelPower})
+	channelSavePower   = base.AppendPower(&base.PowerAction{Action: "save", Text: "告警渠道保存", ShouldLogin: true, StandAlone: true, Parent: channelPower})
+	channelDeletePower = base.AppendPower(&base.PowerAction{Action: "delete", Text: "告警渠道删除", ShouldLogin: true, StandAlone: true, Parent: channelPower})
+	channelTestPower   = base.AppendPower(&base.PowerAction{Action: "test", Text: "告警渠道测试", ShouldLogin: true, StandAlone: true, Parent: channelPower})
+	eventListPower     = base.AppendPower(&base.PowerAction{Action: "event/list", Text: "告警事件列表", ShouldLogin: true, StandAlone: true, Parent: Power})
+	eventFiringPower   = base.AppendPower(&base.PowerAction{Action: "event/firing", Text: "告警中事件", ShouldLogin: true, StandAlone: true, Parent: Power})
+)
+
+func (this_ *api) GetApis() (apis []*base.ApiWorker) {
+	apis = append(apis, &base.ApiWorker{Power: metricsPower, Do: this_.metrics, NotRecodeLog: true})
+	apis = append(apis, &base.ApiWorker{Power: ruleListPower, Do: this_.ruleList, NotRecodeLog: true})
+	apis = append(apis, &base.ApiWorker{Power: ruleSavePower, Do: this_.ruleSave})
+	apis = append(apis, &base.ApiWorker{Power: ruleDeletePower, Do: this_.ruleDelete})
+	apis = append(apis, &base.ApiWorker{Power: channelListPower, Do: this_.channelList, NotRecodeLog: true})
+	apis = append(apis, &base.ApiWorker{Power: channelSavePower, Do: this_.channelSave})
+	apis = append(apis, &base.ApiWorker{Power: channelDeletePower, Do: this_.channelDelete})
+	apis = append(apis, &base.ApiWorker{Power: channelTestPower, Do: this_.channelTest})
+	apis = append(apis, &base.ApiWorker{Power: eventListPower, Do: this_.eventList, NotRecodeLog: true})
+	apis = append(apis, &base.ApiWorker{Power: eventFiringPower, Do: this_.eventFiring, NotRecodeLog: true})
+
+	return
+}
+
+type MetricsResponse struct {
+	Metrics   []*alert.Metric `json:"metrics"`
+	Operators []string        `json:"operators"`
+}
+
+func (this_ *api) metrics(_ *base.RequestBean, _ *gin.Context) (res interface{}, err error) {
+	res = &MetricsResponse{
+		Metrics:   alert.Metrics,
+		Operators: alert.Operators,
+	}
+	return
+}
+
+type Request struct {
+	RuleId    int64 `json:"ruleId,omitempty"`
+	ChannelId int64 `json:"channelId,omitempty"`
+}
+
+func (this_ *api) ruleList(requestBean *base.RequestBean, _ *gin.Context) (res interface{}, err error) {
+	list, err := this_.alertService.QueryRule(requestBean.JWT.UserId)
+	if err != nil {
+		return
+	}
+	var rules []*alert.Rule
+	for _, one := range list {
+		rule, e := ToRule(one)
+		if e != nil {
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	res = rules
+	return
+}
+
+func (this_ *api) ruleSave(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	request := &alert.Rule{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+	err = this_.alertService.SaveRule(requestBean.JWT.UserId, request)
+	if err != nil {
+		return
+	}
+	res = request
+	return
+}
+
+func (this_ *api) ruleDelete(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	request := &Request{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+	if request.RuleId == 0 {
+		err = errors.New("ruleId is empty")
+		return
+	}
+	err = this_.alertService.DeleteRule(requestBean.JWT.UserId, request.RuleId)
+	return
+}
+
+func (this_ *api) channelList(requestBean *base.RequestBean, _ *gin.Context) (res interface{}, err error) {
+	list, err := this_.alertService.QueryChannel(requestBean.JWT.UserId)
+	if err != nil {
+		return
+	}
+	var channels []*alert.Channel
+	for _, one := range list {
+		channel, e := ToChannel(one)
+		if e != nil {
+			continue
+		}
+		channel.Password = ""
+		channel.Secret = ""
+		channels = append(channels, channel)
+	}
+	res = channels
+	return
+}
+
+func (this_ *api) channelSave(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	request := &alert.Channel{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+	err = this_.alertService.SaveChannel(requestBean.JWT.UserId, request)
+	if err != nil {
+		return
+	}
+	request.Password = ""
+	request.Secret = ""
+	res = request
+	return
+}
+
+func (this_ *api) channelDelete(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	request := &Request{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+	if request.ChannelId == 0 {
+		err = errors.New("channelId is empty")
+		return
+	}
+	err = this_.alertService.DeleteChannel(requestBean.JWT.UserId, request.ChannelId)
+	return
+}
+
+// channelTest 使用 当前 配置 发送 一条 测试消息，不需要 先保存，未 填写 密码、密钥 使用 已保存的
+func (this_ *api) channelTest(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	request := &alert.Channel{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+	err = this_.alertService.FillChannelSecret(requestBean.JWT.UserId, request)
+	if err != nil {
+		return
+	}
+	now := time.Now().UnixMilli()
+	err = alert.Send(request, &alert.Event{
+		EventId:   "test",
+		RuleName:  "测试",
+		Level:     "info",
+		Status:    alert.EventStatusFiring,
+		StartTime: now,
+		Time:      now,
+		Message:   "[测试] TeamIDE 告警渠道[" + request.Name + "]测试消息",
+	})
+	return
+}
+
+func (this_ *api) eventList(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	request := &Request{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+	res = this_.alertService.GetEvents(requestBean.JWT.UserId, request.RuleId)
+	return
+}
+
+func (this_ *api) eventFiring(requestBean *base.RequestBean, _ *gin.Context) (res interface{}, err error) {
+	res = this_.alertService.GetFiring(requestBean.JWT.UserId)
+	return
+}
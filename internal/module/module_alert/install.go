@@ -0,0 +1,87 @@
+package module_alert
+
+import (
+	"teamide/internal/install"
+)
+
+func GetInstallStages() []*install.StageModel {
+
+	return []*install.StageModel{
+
+		// 创建 告警规则 表 开始
+		{
+			Version: "1.0",
+			Module:  ModuleAlert,
+			Stage:   `创建表[` + TableAlertRule + `]`,
+			Sql: &install.StageSqlModel{
+				Mysql: []string{`
+CREATE TABLE ` + TableAlertRule + ` (
+	ruleId bigint(20) NOT NULL COMMENT '规则ID',
+	name varchar(100) NOT NULL COMMENT '名称',
+	option text DEFAULT NULL COMMENT '规则配置',
+	enabled tinyint(4) DEFAULT 1 COMMENT '启用',
+	userId bigint(20) DEFAULT NULL COMMENT '用户ID',
+	createTime datetime NOT NULL COMMENT '创建时间',
+	updateTime datetime DEFAULT NULL COMMENT '修改时间',
+	PRIMARY KEY (ruleId),
+	KEY index_userId (userId)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='` + TableAlertRuleComment + `';
+`},
+				Sqlite: []string{`
+CREATE TABLE ` + TableAlertRule + ` (
+	ruleId bigint(20) NOT NULL,
+	name varchar(100) NOT NULL,
+	option text DEFAULT NULL,
+	enabled tinyint(4) DEFAULT 1,
+	userId bigint(20) DEFAULT NULL,
+	createTime datetime NOT NULL,
+	updateTime datetime DEFAULT NULL,
+	PRIMARY KEY (ruleId)
+);
+`,
+					`CREATE INDEX ` + TableAlertRule + `_index_userId on ` + TableAlertRule + ` (userId);`,
+				},
+			},
+		},
+		// 创建 告警规则 表 结束
+
+		// 创建 告警通知渠道 表 开始
+		{
+			Version: "1.0",
+			Module:  ModuleAlert,
+			Stage:   `创建表[` + TableAlertChannel + `]`,
+			Sql: &install.StageSqlModel{
+				Mysql: []string{`
+CREATE TABLE ` + TableAlertChannel + ` (
+	channelId bigint(20) NOT NULL COMMENT '渠道ID',
+	name varchar(100) NOT NULL COMMENT '名称',
+	type varchar(20) NOT NULL COMMENT '类型',
+	option text DEFAULT NULL COMMENT '渠道配置',
+	enabled tinyint(4) DEFAULT 1 COMMENT '启用',
+	userId bigint(20) DEFAULT NULL COMMENT '用户ID',
+	createTime datetime NOT NULL COMMENT '创建时间',
+	updateTime datetime DEFAULT NULL COMMENT '修改时间',
+	PRIMARY KEY (channelId),
+	KEY index_userId (userId)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='` + TableAlertChannelComment + `';
+`},
+				Sqlite: []string{`
+CREATE TABLE ` + TableAlertChannel + ` (
+	channelId bigint(20) NOT NULL,
+	name varchar(100) NOT NULL,
+	type varchar(20) NOT NULL,
+	option text DEFAULT NULL,
+	enabled tinyint(4) DEFAULT 1,
+	userId bigint(20) DEFAULT NULL,
+	createTime datetime NOT NULL,
+	updateTime datetime DEFAULT NULL,
+	PRIMARY KEY (channelId)
+);
+`,
+					`CREATE INDEX ` + TableAlertChannel + `_index_userId on ` + TableAlertChannel + ` (userId);`,
+				},
+			},
+		},
+		// 创建 告警通知渠道 表 结束
+	}
+}
@@ -0,0 +1,37 @@
+package module_alert
+
+import "time"
+
+const (
+	// ModuleAlert 告警模块
+	ModuleAlert = "alert"
+	// TableAlertRule 告警规则表
+	TableAlertRule        = "TM_ALERT_RULE"
+	TableAlertRuleComment = "告警规则"
+	// TableAlertChannel 告警通知渠道表
+	TableAlertChannel        = "TM_ALERT_CHANNEL"
+	TableAlertChannelComment = "告警通知渠道"
+)
+
+// AlertRuleModel 告警规则，规则内容 以 JSON 存储在 option 中
+type AlertRuleModel struct {
+	RuleId     int64     `json:"ruleId,omitempty"`
+	Name       string    `json:"name,omitempty"`
+	Option     string    `json:"option,omitempty"`
+	Enabled    int8      `json:"enabled"`
+	UserId     int64     `json:"userId,omitempty"`
+	CreateTime time.Time `json:"createTime,omitempty"`
+	UpdateTime time.Time `json:"updateTime,omitempty"`
+}
+
+// AlertChannelModel 告警通知渠道，渠道配置 以 JSON 存储在 option 中
+type AlertChannelModel struct {
+	ChannelId  int64     `json:"channelId,omitempty"`
+	Name       string    `json:"name,omitempty"`
+	Type       string    `json:"type,omitempty"`
+	Option     string    `json:"option,omitempty"`
+	Enabled    int8      `json:"enabled"`
+	UserId     int64     `json:"userId,omitempty"`
+	CreateTime time.Time `json:"createTime,omitempty"`
+	UpdateTime time.Time `json:"updateTime,omitempty"`
+}
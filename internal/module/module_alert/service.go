@@ -0,0 +1,458 @@
+package module_alert
+
+import (
+	"encoding/json"
+	"errors"
+	"go.uber.org/zap"
+	"strconv"
+	"sync"
+	"teamide/internal/context"
+	"teamide/internal/module/module_id"
+	"teamide/internal/module/module_node"
+	"teamide/pkg/alert"
+	"teamide/pkg/node"
+	"teamide/pkg/system"
+	"time"
+)
+
+const (
+	// 保留 最近 告警事件 数量
+	eventCacheSize = 500
+	// 监控数据 超过 该时间 未更新 则 不参与 计算
+	monitorDataTimeout = 60 * 1000
+)
+
+// NewAlertService 根据库配置创建AlertService
+func NewAlertService(ServerContext *context.ServerContext, nodeService *module_node.NodeService) (res *AlertService) {
+
+	idService := module_id.NewIDService(ServerContext)
+
+	res = &AlertService{
+		ServerContext:  ServerContext,
+		idService:      idService,
+		nodeService:    nodeService,
+		engine:         alert.NewEngine(),
+		ruleUserIds:    make(map[int64]int64),
+		channelUserIds: make(map[int64]int64),
+		channelCache:   make(map[int64]*alert.Channel),
+	}
+	return
+}
+
+// AlertService 告警服务
+type AlertService struct {
+	*context.ServerContext
+	idService      *module_id.IDService
+	nodeService    *module_node.NodeService
+	engine         *alert.Engine
+	ruleUserIds    map[int64]int64
+	channelUserIds map[int64]int64
+	channelCache   map[int64]*alert.Channel
+	cacheLock      sync.Mutex
+	events         []*alert.Event
+	eventsLock     sync.Mutex
+}
+
+func (this_ *AlertService) ServerReady() (err error) {
+	err = this_.reload()
+	if err != nil {
+		return
+	}
+	_, err = this_.CronHandler.AddFunc("*/10 * * * * *", this_.evaluate)
+	if err != nil {
+		return
+	}
+	return
+}
+
+// reload 重新加载 规则 及 渠道
+func (this_ *AlertService) reload() (err error) {
+	ruleModels, err := this_.QueryRule(0)
+	if err != nil {
+		return
+	}
+	channelModels, err := this_.QueryChannel(0)
+	if err != nil {
+		return
+	}
+	var rules []*alert.Rule
+	var ruleUserIds = make(map[int64]int64)
+	for _, one := range ruleModels {
+		rule, e := ToRule(one)
+		if e != nil {
+			this_.Logger.Error("alert rule option parse error", zap.Any("ruleId", one.RuleId), zap.Error(e))
+			continue
+		}
+		rules = append(rules, rule)
+		ruleUserIds[rule.RuleId] = one.UserId
+	}
+	var channelCache = make(map[int64]*alert.Channel)
+	var channelUserIds = make(map[int64]int64)
+	for _, one := range channelModels {
+		channel, e := ToChannel(one)
+		if e != nil {
+			this_.Logger.Error("alert channel option parse error", zap.Any("channelId", one.ChannelId), zap.Error(e))
+			continue
+		}
+		channelCache[channel.ChannelId] = channel
+		channelUserIds[channel.ChannelId] = one.UserId
+	}
+
+	this_.cacheLock.Lock()
+	this_.ruleUserIds = ruleUserIds
+	this_.channelUserIds = channelUserIds
+	this_.channelCache = channelCache
+	this_.cacheLock.Unlock()
+
+	this_.engine.SetRules(rules)
+	return
+}
+
+// evaluate 使用 最新的 监控数据 及 节点状态 计算 告警规则
+func (this_ *AlertService) evaluate() {
+	defer func() {
+		if e := recover(); e != nil {
+			this_.Logger.Error("alert evaluate error", zap.Any("error", e))
+		}
+	}()
+	now := time.Now()
+	nowMilli := now.UnixMilli()
+
+	var samples []*alert.Sample
+	for hostKey, monitorData := range system.GetLatestMonitorData() {
+		if nowMilli-monitorData.StartTime > monitorDataTimeout {
+			continue
+		}
+		samples = append(samples, alert.MonitorDataSamples(hostKey, monitorData)...)
+	}
+	if nodeContext := this_.nodeService.GetContext(); nodeContext != nil {
+		for _, one := range nodeContext.GetNodeModelList() {
+			// 未启用、本地节点、状态 未检测 的 不计算
+			if one.Enabled != 1 || one.Status == 0 || nodeContext.IsLocalNode(one.ServerId) {
+				continue
+			}
+			samples = append(samples, alert.NodeSample(one.ServerId, one.Status == node.StatusStarted))
+		}
+	}
+
+	events := this_.engine.Evaluate(now, samples)
+	for _, event := range events {
+		this_.onEvent(event)
+	}
+}
+
+// onEvent 记录事件，推送 站内通知，非静默 则 发送到 规则 配置的、规则 所属 用户 的 渠道
+func (this_ *AlertService) onEvent(event *alert.Event) {
+	this_.eventsLock.Lock()
+	this_.events = append(this_.events, event)
+	if len(this_.events) > eventCacheSize {
+		this_.events = this_.events[len(this_.events)-eventCacheSize:]
+	}
+	this_.eventsLock.Unlock()
+
+	this_.Logger.Info("alert event", zap.Any("event", event))
+
+	this_.cacheLock.Lock()
+	userId := this_.ruleUserIds[event.RuleId]
+	var channels []*alert.Channel
+	for _, rule := range this_.engine.GetRules() {
+		if rule.RuleId != event.RuleId {
+			continue
+		}
+		for _, channelId := range rule.ChannelIds {
+			if this_.channelUserIds[channelId] != userId {
+				continue
+			}
+			if channel := this_.channelCache[channelId]; channel != nil && channel.Enabled {
+				channels = append(channels, channel)
+			}
+		}
+	}
+	this_.cacheLock.Unlock()
+
+	listenEvent := context.NewListenEvent("alert-event", event)
+	if userId != 0 {
+		context.CallUserEvent(userId, listenEvent)
+	} else {
+		for _, listener := range context.GetListeners() {
+			listener.AddEvent(listenEvent)
+		}
+	}
+
+	if event.Silenced {
+		return
+	}
+	for _, channel := range channels {
+		go func(channel *alert.Channel) {
+			e := alert.Send(channel, event)
+			if e != nil {
+				this_.Logger.Error("alert send error", zap.Any("channel", channel.Name), zap.Any("type", channel.Type), zap.Error(e))
+			}
+		}(channel)
+	}
+}
+
+// getRuleUserIds 规则 所属 用户
+func (this_ *AlertService) getRuleUserIds() (ruleUserIds map[int64]int64) {
+	this_.cacheLock.Lock()
+	defer this_.cacheLock.Unlock()
+	ruleUserIds = this_.ruleUserIds
+	return
+}
+
+// GetEvents 用户 规则 最近的 告警事件，倒序
+func (this_ *AlertService) GetEvents(userId int64, ruleId int64) (events []*alert.Event) {
+	ruleUserIds := this_.getRuleUserIds()
+
+	this_.eventsLock.Lock()
+	defer this_.eventsLock.Unlock()
+
+	for i := len(this_.events) - 1; i >= 0; i-- {
+		one := this_.events[i]
+		if ruleUserIds[one.RuleId] != userId {
+			continue
+		}
+		if ruleId != 0 && one.RuleId != ruleId {
+			continue
+		}
+		events = append(events, one)
+	}
+	return
+}
+
+// GetFiring 用户 规则 当前 告警中的 事件
+func (this_ *AlertService) GetFiring(userId int64) (events []*alert.Event) {
+	ruleUserIds := this_.getRuleUserIds()
+	for _, one := range this_.engine.GetFiring() {
+		if ruleUserIds[one.RuleId] == userId {
+			events = append(events, one)
+		}
+	}
+	return
+}
+
+// ToRule 将 规则表 数据 转为 规则
+func ToRule(model *AlertRuleModel) (rule *alert.Rule, err error) {
+	rule = &alert.Rule{}
+	if model.Option != "" {
+		err = json.Unmarshal([]byte(model.Option), rule)
+		if err != nil {
+			return
+		}
+	}
+	rule.RuleId = model.RuleId
+	rule.Name = model.Name
+	rule.Enabled = model.Enabled == 1
+	return
+}
+
+// ToChannel 将 渠道表 数据 转为 渠道
+func ToChannel(model *AlertChannelModel) (channel *alert.Channel, err error) {
+	channel = &alert.Channel{}
+	if model.Option != "" {
+		err = json.Unmarshal([]byte(model.Option), channel)
+		if err != nil {
+			return
+		}
+	}
+	channel.ChannelId = model.ChannelId
+	channel.Name = model.Name
+	channel.Type = model.Type
+	channel.Enabled = model.Enabled == 1
+	return
+}
+
+func toEnabled(enabled bool) int8 {
+	if enabled {
+		return 1
+	}
+	return 2
+}
+
+// QueryRule 查询 规则，userId 为 0 查询所有
+func (this_ *AlertService) QueryRule(userId int64) (res []*AlertRuleModel, err error) {
+	var values []interface{}
+	sql := `SELECT * FROM ` + TableAlertRule + ` WHERE 1=1 `
+	if userId != 0 {
+		sql += " AND userId = ?"
+		values = append(values, userId)
+	}
+	sql += " ORDER BY createTime ASC "
+
+	err = this_.DatabaseWorker.Query(sql, values, &res)
+	if err != nil {
+		this_.Logger.Error("QueryRule Error", zap.Error(err))
+		return
+	}
+	return
+}
+
+// SaveRule 新增或更新 规则
+func (this_ *AlertService) SaveRule(userId int64, rule *alert.Rule) (err error) {
+	if rule.Name == "" {
+		err = errors.New("规则名称不能为空")
+		return
+	}
+	if alert.GetMetric(rule.Metric) == nil {
+		err = errors.New("规则指标[" + rule.Metric + "]不支持")
+		return
+	}
+	if !alert.IsOperator(rule.Operator) {
+		err = errors.New("规则比较符[" + rule.Operator + "]不支持")
+		return
+	}
+	// 只能 使用 自己的 渠道
+	this_.cacheLock.Lock()
+	for _, channelId := range rule.ChannelIds {
+		if this_.channelUserIds[channelId] != userId {
+			err = errors.New("告警渠道[" + strconv.FormatInt(channelId, 10) + "]不存在")
+			break
+		}
+	}
+	this_.cacheLock.Unlock()
+	if err != nil {
+		return
+	}
+	option, err := json.Marshal(rule)
+	if err != nil {
+		return
+	}
+
+	if rule.RuleId > 0 {
+		var rowsAffected int64
+		sql := `UPDATE ` + TableAlertRule + ` SET name=?,option=?,enabled=?,updateTime=? WHERE ruleId=? AND userId=? `
+		rowsAffected, err = this_.DatabaseWorker.Exec(sql, []interface{}{rule.Name, string(option), toEnabled(rule.Enabled), time.Now(), rule.RuleId, userId})
+		if err == nil && rowsAffected == 0 {
+			err = errors.New("规则不存在")
+			return
+		}
+	} else {
+		rule.RuleId, err = this_.idService.GetNextID(module_id.IDTypeAlertRule)
+		if err != nil {
+			return
+		}
+		sql := `INSERT INTO ` + TableAlertRule + `(ruleId, name, option, enabled, userId, createTime) VALUES (?, ?, ?, ?, ?, ?) `
+		_, err = this_.DatabaseWorker.Exec(sql, []interface{}{rule.RuleId, rule.Name, string(option), toEnabled(rule.Enabled), userId, time.Now()})
+	}
+	if err != nil {
+		this_.Logger.Error("SaveRule Error", zap.Error(err))
+		return
+	}
+	err = this_.reload()
+	return
+}
+
+// DeleteRule 删除 用户 自己的 规则
+func (this_ *AlertService) DeleteRule(userId int64, ruleId int64) (err error) {
+	sql := `DELETE FROM ` + TableAlertRule + ` WHERE ruleId=? AND userId=? `
+	_, err = this_.DatabaseWorker.Exec(sql, []interface{}{ruleId, userId})
+	if err != nil {
+		this_.Logger.Error("DeleteRule Error", zap.Error(err))
+		return
+	}
+	err = this_.reload()
+	return
+}
+
+// QueryChannel 查询 渠道，userId 为 0 查询所有
+func (this_ *AlertService) QueryChannel(userId int64) (res []*AlertChannelModel, err error) {
+	var values []interface{}
+	sql := `SELECT * FROM ` + TableAlertChannel + ` WHERE 1=1 `
+	if userId != 0 {
+		sql += " AND userId = ?"
+		values = append(values, userId)
+	}
+	sql += " ORDER BY createTime ASC "
+
+	err = this_.DatabaseWorker.Query(sql, values, &res)
+	if err != nil {
+		this_.Logger.Error("QueryChannel Error", zap.Error(err))
+		return
+	}
+	return
+}
+
+// SaveChannel 新增或更新 渠道
+func (this_ *AlertService) SaveChannel(userId int64, channel *alert.Channel) (err error) {
+	if channel.Name == "" {
+		err = errors.New("渠道名称不能为空")
+		return
+	}
+	err = this_.FillChannelSecret(userId, channel)
+	if err != nil {
+		return
+	}
+	option, err := json.Marshal(channel)
+	if err != nil {
+		return
+	}
+
+	if channel.ChannelId > 0 {
+		var rowsAffected int64
+		sql := `UPDATE ` + TableAlertChannel + ` SET name=?,type=?,option=?,enabled=?,updateTime=? WHERE channelId=? AND userId=? `
+		rowsAffected, err = this_.DatabaseWorker.Exec(sql, []interface{}{channel.Name, channel.Type, string(option), toEnabled(channel.Enabled), time.Now(), channel.ChannelId, userId})
+		if err == nil && rowsAffected == 0 {
+			err = errors.New("渠道不存在")
+			return
+		}
+	} else {
+		channel.ChannelId, err = this_.idService.GetNextID(module_id.IDTypeAlertChannel)
+		if err != nil {
+			return
+		}
+		sql := `INSERT INTO ` + TableAlertChannel + `(channelId, name, type, option, enabled, userId, createTime) VALUES (?, ?, ?, ?, ?, ?, ?) `
+		_, err = this_.DatabaseWorker.Exec(sql, []interface{}{channel.ChannelId, channel.Name, channel.Type, string(option), toEnabled(channel.Enabled), userId, time.Now()})
+	}
+	if err != nil {
+		this_.Logger.Error("SaveChannel Error", zap.Error(err))
+		return
+	}
+	err = this_.reload()
+	return
+}
+
+// GetChannel 查询 用户 自己的 渠道，不存在 返回 nil
+func (this_ *AlertService) GetChannel(userId int64, channelId int64) (res *alert.Channel, err error) {
+	var list []*AlertChannelModel
+	sql := `SELECT * FROM ` + TableAlertChannel + ` WHERE channelId=? AND userId=? `
+	err = this_.DatabaseWorker.Query(sql, []interface{}{channelId, userId}, &list)
+	if err != nil {
+		this_.Logger.Error("GetChannel Error", zap.Error(err))
+		return
+	}
+	if len(list) == 0 {
+		return
+	}
+	res, err = ToChannel(list[0])
+	return
+}
+
+// FillChannelSecret 列表 中 不返回 密码、密钥，修改 或 测试 时 未 填写 则 使用 已保存的
+func (this_ *AlertService) FillChannelSecret(userId int64, channel *alert.Channel) (err error) {
+	if channel.ChannelId == 0 || (channel.Password != "" && channel.Secret != "") {
+		return
+	}
+	find, err := this_.GetChannel(userId, channel.ChannelId)
+	if err != nil || find == nil {
+		return
+	}
+	if channel.Password == "" {
+		channel.Password = find.Password
+	}
+	if channel.Secret == "" {
+		channel.Secret = find.Secret
+	}
+	return
+}
+
+// DeleteChannel 删除 用户 自己的 渠道
+func (this_ *AlertService) DeleteChannel(userId int64, channelId int64) (err error) {
+	sql := `DELETE FROM ` + TableAlertChannel + ` WHERE channelId=? AND userId=? `
+	_, err = this_.DatabaseWorker.Exec(sql, []interface{}{channelId, userId})
+	if err != nil {
+		this_.Logger.Error("DeleteChannel Error", zap.Error(err))
+		return
+	}
+	err = this_.reload()
+	return
+}
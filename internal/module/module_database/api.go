@@ -26,16 +26,22 @@ import (
 	"sync"
 	"teamide/internal/module/module_toolbox"
 	"teamide/pkg/base"
+	"teamide/pkg/sqlguard"
 	"teamide/pkg/ssh"
+	"time"
 )
 
 type api struct {
 	toolboxService *module_toolbox.ToolboxService
+	sqlService     *SqlService
+	hasPower       func(requestBean *base.RequestBean, power *base.PowerAction) bool
 }
 
-func NewApi(toolboxService *module_toolbox.ToolboxService) *api {
+func NewApi(toolboxService *module_toolbox.ToolboxService, sqlService *SqlService, hasPower func(requestBean *base.RequestBean, power *base.PowerAction) bool) *api {
 	return &api{
 		toolboxService: toolboxService,
+		sqlService:     sqlService,
+		hasPower:       hasPower,
 	}
 }
 
@@ -62,6 +68,7 @@ var (
 	dataListSqlPower    = base.AppendPower(&base.PowerAction{Action: "dataListSql", Text: "数据库数据转换SQL", ShouldLogin: true, StandAlone: true, Parent: Power})
 	dataListExecPower   = base.AppendPower(&base.PowerAction{Action: "dataListExec", Text: "数据库数据执行", ShouldLogin: true, StandAlone: true, Parent: Power})
 	executeSQLPower     = base.AppendPower(&base.PowerAction{Action: "executeSQL", Text: "数据库SQL执行", ShouldLogin: true, StandAlone: true, Parent: Power})
+	explainPower        = base.AppendPower(&base.PowerAction{Action: "explain", Text: "数据库SQL执行计划", ShouldLogin: true, StandAlone: true, Parent: Power})
 	importPower         = base.AppendPower(&base.PowerAction{Action: "import", Text: "数据库导入", ShouldLogin: true, StandAlone: true, Parent: Power})
 	exportPower         = base.AppendPower(&base.PowerAction{Action: "export", Text: "数据库导出", ShouldLogin: true, StandAlone: true, Parent: Power})
 	exportDownloadPower = base.AppendPower(&base.PowerAction{Action: "exportDownload", Text: "数据库导出下载", ShouldLogin: true, StandAlone: true, Parent: Power})
@@ -71,6 +78,45 @@ var (
 	taskCleanPower      = base.AppendPower(&base.PowerAction{Action: "taskClean", Text: "数据库任务清理", ShouldLogin: true, StandAlone: true, Parent: Power})
 	closePower          = base.AppendPower(&base.PowerAction{Action: "close", Text: "数据库关闭", ShouldLogin: true, StandAlone: true, Parent: Power})
 
+	sqlHistoryQuery  = base.AppendPower(&base.PowerAction{Action: "sql/history/query", Text: "SQL执行历史查询", ShouldLogin: true, StandAlone: true, Parent: Power})
+	sqlHistoryDelete = base.AppendPower(&base.PowerAction{Action: "sql/history/delete", Text: "SQL执行历史删除", ShouldLogin: true, StandAlone: true, Parent: Power})
+	sqlHistoryClean  = base.AppendPower(&base.PowerAction{Action: "sql/history/clean", Text: "SQL执行历史清理", ShouldLogin: true, StandAlone: true, Parent: Power})
+	sqlSavedList     = base.AppendPower(&base.PowerAction{Action: "sql/saved/list", Text: "保存的SQL查询", ShouldLogin: true, StandAlone: true, Parent: Power})
+	sqlSavedSave     = base.AppendPower(&base.PowerAction{Action: "sql/saved/save", Text: "保存的SQL保存", ShouldLogin: true, StandAlone: true, Parent: Power})
+	sqlSavedDelete   = base.AppendPower(&base.PowerAction{Action: "sql/saved/delete", Text: "保存的SQL删除", ShouldLogin: true, StandAlone: true, Parent: Power})
+	sqlSnippetRender = base.AppendPower(&base.PowerAction{Action: "sql/snippet/render", Text: "SQL片段填充", ShouldLogin: true, StandAlone: true, Parent: Power})
+
+	txBeginPower    = base.AppendPower(&base.PowerAction{Action: "tx/begin", Text: "数据库事务开启", ShouldLogin: true, StandAlone: true, Parent: Power})
+	txCommitPower   = base.AppendPower(&base.PowerAction{Action: "tx/commit", Text: "数据库事务提交", ShouldLogin: true, StandAlone: true, Parent: Power})
+	txRollbackPower = base.AppendPower(&base.PowerAction{Action: "tx/rollback", Text: "数据库事务回滚", ShouldLogin: true, StandAlone: true, Parent: Power})
+	txStatusPower   = base.AppendPower(&base.PowerAction{Action: "tx/status", Text: "数据库事务状态", ShouldLogin: true, StandAlone: true, Parent: Power})
+	txListPower     = base.AppendPower(&base.PowerAction{Action: "tx/list", Text: "数据库未结束事务", ShouldLogin: true, StandAlone: true, Parent: Power})
+
+	schemaDiffPower            = base.AppendPower(&base.PowerAction{Action: "schema/diff", Text: "数据库结构比较", ShouldLogin: true, StandAlone: true, Parent: Power})
+	schemaMigrationPower       = base.AppendPower(&base.PowerAction{Action: "schema/migration", Text: "数据库结构迁移脚本生成", ShouldLogin: true, StandAlone: true, Parent: Power})
+	schemaMigrationSavePower   = base.AppendPower(&base.PowerAction{Action: "schema/migration/save", Text: "数据库结构迁移脚本保存", ShouldLogin: true, StandAlone: true, Parent: Power})
+	schemaMigrationListPower   = base.AppendPower(&base.PowerAction{Action: "schema/migration/list", Text: "数据库结构迁移脚本查询", ShouldLogin: true, StandAlone: true, Parent: Power})
+	schemaMigrationGetPower    = base.AppendPower(&base.PowerAction{Action: "schema/migration/get", Text: "数据库结构迁移脚本详情", ShouldLogin: true, StandAlone: true, Parent: Power})
+	schemaMigrationDeletePower = base.AppendPower(&base.PowerAction{Action: "schema/migration/delete", Text: "数据库结构迁移脚本删除", ShouldLogin: true, StandAlone: true, Parent: Power})
+	schemaMigrationExecPower   = base.AppendPower(&base.PowerAction{Action: "schema/migration/exec", Text: "数据库结构迁移执行", ShouldLogin: true, StandAlone: true, Parent: Power})
+	schemaMigrationStatusPower = base.AppendPower(&base.PowerAction{Action: "schema/migration/status", Text: "数据库结构迁移任务状态", ShouldLogin: true, StandAlone: true, Parent: Power})
+	schemaMigrationStopPower   = base.AppendPower(&base.PowerAction{Action: "schema/migration/stop", Text: "数据库结构迁移任务停止", ShouldLogin: true, StandAlone: true, Parent: Power})
+	schemaMigrationCleanPower  = base.AppendPower(&base.PowerAction{Action: "schema/migration/clean", Text: "数据库结构迁移任务清理", ShouldLogin: true, StandAlone: true, Parent: Power})
+
+	erPower = base.AppendPower(&base.PowerAction{Action: "er", Text: "数据库ER图", ShouldLogin: true, StandAlone: true, Parent: Power})
+
+	maskRuleListPower   = base.AppendPower(&base.PowerAction{Action: "mask/rule/list", Text: "数据库脱敏规则查询", ShouldLogin: true, StandAlone: true, Parent: Power})
+	maskRuleSavePower   = base.AppendPower(&base.PowerAction{Action: "mask/rule/save", Text: "数据库脱敏规则保存", ShouldLogin: true, StandAlone: true, ShouldPower: true, Parent: Power})
+	maskRuleDeletePower = base.AppendPower(&base.PowerAction{Action: "mask/rule/delete", Text: "数据库脱敏规则删除", ShouldLogin: true, StandAlone: true, ShouldPower: true, Parent: Power})
+
+	sqlApprovalListPower    = base.AppendPower(&base.PowerAction{Action: "sql/approval/list", Text: "数据库危险SQL审批查询", ShouldLogin: true, StandAlone: true, Parent: Power})
+	sqlApprovalGetPower     = base.AppendPower(&base.PowerAction{Action: "sql/approval/get", Text: "数据库危险SQL审批详情", ShouldLogin: true, StandAlone: true, Parent: Power})
+	sqlApprovalApprovePower = base.AppendPower(&base.PowerAction{Action: "sql/approval/approve", Text: "数据库危险SQL审批通过", ShouldLogin: true, StandAlone: true, ShouldPower: true, Parent: Power})
+	sqlApprovalRejectPower  = base.AppendPower(&base.PowerAction{Action: "sql/approval/reject", Text: "数据库危险SQL审批拒绝", ShouldLogin: true, StandAlone: true, ShouldPower: true, Parent: Power})
+
+	// unmaskPower 查看 明文，不 对应 接口，查询、导出 时 判断
+	unmaskPower = base.AppendPower(&base.PowerAction{Action: "mask/unmask", Text: "数据库查看脱敏数据明文", ShouldLogin: true, ShouldPower: true, Parent: Power})
+
 	testStart  = base.AppendPower(&base.PowerAction{Action: "test/start", Text: "测试开始", ShouldLogin: true, StandAlone: true, Parent: Power})
 	testInfo   = base.AppendPower(&base.PowerAction{Action: "test/info", Text: "测试任务信息", ShouldLogin: true, StandAlone: true, Parent: Power})
 	testStop   = base.AppendPower(&base.PowerAction{Action: "test/stop", Text: "测试停止", ShouldLogin: true, StandAlone: true, Parent: Power})
@@ -100,6 +146,7 @@ func (this_ *api) GetApis() (apis []*base.ApiWorker) {
 	apis = append(apis, &base.ApiWorker{Power: dataListSqlPower, Do: this_.dataListSql})
 	apis = append(apis, &base.ApiWorker{Power: dataListExecPower, Do: this_.dataListExec})
 	apis = append(apis, &base.ApiWorker{Power: executeSQLPower, Do: this_.executeSQL})
+	apis = append(apis, &base.ApiWorker{Power: explainPower, Do: this_.explain})
 	apis = append(apis, &base.ApiWorker{Power: importPower, Do: this_._import})
 	apis = append(apis, &base.ApiWorker{Power: exportPower, Do: this_.export})
 	apis = append(apis, &base.ApiWorker{Power: exportDownloadPower, Do: this_.exportDownload})
@@ -116,6 +163,39 @@ func (this_ *api) GetApis() (apis []*base.ApiWorker) {
 
 	apis = append(apis, &base.ApiWorker{Power: closePower, Do: this_.close})
 
+	apis = append(apis, &base.ApiWorker{Power: sqlHistoryQuery, Do: this_.sqlHistoryQuery, NotRecodeLog: true})
+	apis = append(apis, &base.ApiWorker{Power: sqlHistoryDelete, Do: this_.sqlHistoryDelete})
+	apis = append(apis, &base.ApiWorker{Power: sqlHistoryClean, Do: this_.sqlHistoryClean})
+	apis = append(apis, &base.ApiWorker{Power: sqlSavedList, Do: this_.sqlSavedList, NotRecodeLog: true})
+	apis = append(apis, &base.ApiWorker{Power: sqlSavedSave, Do: this_.sqlSavedSave})
+	apis = append(apis, &base.ApiWorker{Power: sqlSavedDelete, Do: this_.sqlSavedDelete})
+	apis = append(apis, &base.ApiWorker{Power: sqlSnippetRender, Do: this_.sqlSnippetRender, NotRecodeLog: true})
+
+	apis = append(apis, &base.ApiWorker{Power: txBeginPower, Do: this_.txBegin})
+	apis = append(apis, &base.ApiWorker{Power: txCommitPower, Do: this_.txCommit})
+	apis = append(apis, &base.ApiWorker{Power: txRollbackPower, Do: this_.txRollback})
+	apis = append(apis, &base.ApiWorker{Power: txStatusPower, Do: this_.txStatus, NotRecodeLog: true})
+	apis = append(apis, &base.ApiWorker{Power: txListPower, Do: this_.txList, NotRecodeLog: true})
+
+	apis = append(apis, &base.ApiWorker{Power: schemaDiffPower, Do: this_.schemaDiff})
+	apis = append(apis, &base.ApiWorker{Power: schemaMigrationPower, Do: this_.schemaMigration})
+	apis = append(apis, &base.ApiWorker{Power: schemaMigrationSavePower, Do: this_.schemaMigrationSave})
+	apis = append(apis, &base.ApiWorker{Power: schemaMigrationListPower, Do: this_.schemaMigrationList, NotRecodeLog: true})
+	apis = append(apis, &base.ApiWorker{Power: schemaMigrationGetPower, Do: this_.schemaMigrationGet, NotRecodeLog: true})
+	apis = append(apis, &base.ApiWorker{Power: schemaMigrationDeletePower, Do: this_.schemaMigrationDelete})
+	apis = append(apis, &base.ApiWorker{Power: schemaMigrationExecPower, Do: this_.schemaMigrationExec})
+	apis = append(apis, &base.ApiWorker{Power: schemaMigrationStatusPower, Do: this_.schemaMigrationStatus, NotRecodeLog: true})
+	apis = append(apis, &base.ApiWorker{Power: schemaMigrationStopPower, Do: this_.schemaMigrationStop})
+	apis = append(apis, &base.ApiWorker{Power: schemaMigrationCleanPower, Do: this_.schemaMigrationClean})
+	apis = append(apis, &base.ApiWorker{Power: erPower, Do: this_.er, NotRecodeLog: true})
+	apis = append(apis, &base.ApiWorker{Power: maskRuleListPower, Do: this_.maskRuleList, NotRecodeLog: true})
+	apis = append(apis, &base.ApiWorker{Power: maskRuleSavePower, Do: this_.maskRuleSave})
+	apis = append(apis, &base.ApiWorker{Power: maskRuleDeletePower, Do: this_.maskRuleDelete})
+	apis = append(apis, &base.ApiWorker{Power: sqlApprovalListPower, Do: this_.sqlApprovalList, NotRecodeLog: true})
+	apis = append(apis, &base.ApiWorker{Power: sqlApprovalGetPower, Do: this_.sqlApprovalGet, NotRecodeLog: true})
+	apis = append(apis, &base.ApiWorker{Power: sqlApprovalApprovePower, Do: this_.sqlApprovalApprove})
+	apis = append(apis, &base.ApiWorker{Power: sqlApprovalRejectPower, Do: this_.sqlApprovalReject})
+
 	return
 }
 
@@ -241,6 +321,8 @@ type BaseRequest struct {
 	Password   string          `json:"password,omitempty"`
 	TestSql    string          `json:"testSql,omitempty"`
 	ScriptVars []*db.ScriptVar `json:"scriptVars,omitempty"`
+
+	ApprovalId int64 `json:"approvalId,omitempty"` // 生产 环境 危险 操作 审批 通过 后 执行
 }
 
 func (this_ *api) check(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
@@ -350,6 +432,21 @@ func (this_ *api) ownerDelete(requestBean *base.RequestBean, c *gin.Context) (re
 	}
 
 	param := this_.getParam(requestBean, c)
+
+	var finish func(executeErr error)
+	res, finish, err = this_.checkSqlGuard(requestBean, &sqlGuardTarget{
+		Action:     "ownerDelete",
+		ToolboxId:  request.ToolboxId,
+		OwnerName:  request.OwnerName,
+		ApprovalId: request.ApprovalId,
+	}, func(guard *SqlGuardConfig) ([]*sqlguard.Risk, error) {
+		return []*sqlguard.Risk{{Type: sqlguard.RiskDrop, Message: "删除库[" + request.OwnerName + "]"}}, nil
+	})
+	if err != nil || res != nil {
+		return
+	}
+	defer func() { finish(err) }()
+
 	res, err = service.OwnerDelete(param, request.OwnerName)
 	if err != nil {
 		return
@@ -600,6 +697,21 @@ func (this_ *api) tableDelete(requestBean *base.RequestBean, c *gin.Context) (re
 	}
 	param := this_.getParam(requestBean, c)
 
+	var finish func(executeErr error)
+	res, finish, err = this_.checkSqlGuard(requestBean, &sqlGuardTarget{
+		Action:     "tableDelete",
+		ToolboxId:  request.ToolboxId,
+		OwnerName:  request.OwnerName,
+		TableName:  request.TableName,
+		ApprovalId: request.ApprovalId,
+	}, func(guard *SqlGuardConfig) ([]*sqlguard.Risk, error) {
+		return []*sqlguard.Risk{{Type: sqlguard.RiskDrop, Message: "删除表[" + request.TableName + "]"}}, nil
+	})
+	if err != nil || res != nil {
+		return
+	}
+	defer func() { finish(err) }()
+
 	err = service.TableDelete(param, request.OwnerName, request.TableName)
 	if err != nil {
 		return
@@ -623,6 +735,21 @@ func (this_ *api) tableDataTrim(requestBean *base.RequestBean, c *gin.Context) (
 	}
 	param := this_.getParam(requestBean, c)
 
+	var finish func(executeErr error)
+	res, finish, err = this_.checkSqlGuard(requestBean, &sqlGuardTarget{
+		Action:     "tableDataTrim",
+		ToolboxId:  request.ToolboxId,
+		OwnerName:  request.OwnerName,
+		TableName:  request.TableName,
+		ApprovalId: request.ApprovalId,
+	}, func(guard *SqlGuardConfig) ([]*sqlguard.Risk, error) {
+		return []*sqlguard.Risk{{Type: sqlguard.RiskTruncate, Message: "清空表[" + request.TableName + "]数据"}}, nil
+	})
+	if err != nil || res != nil {
+		return
+	}
+	defer func() { finish(err) }()
+
 	err = service.TableDataTrim(param, request.OwnerName, request.TableName)
 	if err != nil {
 		return
@@ -646,10 +773,16 @@ func (this_ *api) tableData(requestBean *base.RequestBean, c *gin.Context) (res
 	}
 	param := this_.getParam(requestBean, c)
 
+	masker, err := this_.getMasker(requestBean, request.ToolboxId)
+	if err != nil {
+		return
+	}
+
 	res, err = service.TableData(param, request.OwnerName, request.TableName, request.ColumnList, request.Wheres, request.Orders, request.PageSize, request.PageNo)
 	if err != nil {
 		return
 	}
+	masker.MaskResult(request.ToolboxId, request.OwnerName, request.TableName, res)
 	return
 }
 
@@ -709,6 +842,31 @@ func (this_ *api) dataListExec(requestBean *base.RequestBean, c *gin.Context) (r
 	}
 	param := this_.getParam(requestBean, c)
 
+	var finish func(executeErr error)
+	res, finish, err = this_.checkSqlGuard(requestBean, &sqlGuardTarget{
+		Action:     "dataListExec",
+		ToolboxId:  request.ToolboxId,
+		OwnerName:  request.OwnerName,
+		TableName:  request.TableName,
+		Content:    dataListGuardContent(request),
+		ApprovalId: request.ApprovalId,
+	}, func(guard *SqlGuardConfig) (risks []*sqlguard.Risk, err error) {
+		// 按 主键 逐行 修改、删除，影响 行数 即 数据 条数
+		count := int64(len(request.UpdateList) + len(request.DeleteList))
+		if guard.GuardMaxRows > 0 && count > guard.GuardMaxRows {
+			risks = append(risks, &sqlguard.Risk{
+				Type:    sqlguard.RiskRows,
+				Rows:    count,
+				Message: fmt.Sprint("修改、删除行数[", count, "]超过限制[", guard.GuardMaxRows, "]"),
+			})
+		}
+		return
+	})
+	if err != nil || res != nil {
+		return
+	}
+	defer func() { finish(err) }()
+
 	res, err = service.DataListExec(param, request.OwnerName, request.TableName, request.ColumnList,
 		request.InsertList,
 		request.UpdateList, request.UpdateWhereList,
@@ -735,18 +893,112 @@ func (this_ *api) executeSQL(requestBean *base.RequestBean, c *gin.Context) (res
 		return
 	}
 	param := this_.getParam(requestBean, c)
-	data := make(map[string]interface{})
-	data["executeList"], data["error"], err = service.ExecuteSQL(param, request.OwnerName, request.ExecuteSQL, &db.ExecuteOptions{
-		SelectDataMax: request.ShowDataMaxSize,
-		OpenProfiling: request.OpenProfiling,
+	executeSql := request.ExecuteSQL
+	if len(request.ScriptVars) > 0 {
+		executeSql, err = RenderScriptVars(executeSql, request.ScriptVars)
+		if err != nil {
+			return
+		}
+	}
+	txStatus, err := CheckTxStatus(request.WorkerId, requestBean.JWT.UserId, request.ToolboxId, request.OwnerName)
+	if err != nil {
+		return
+	}
+	var finish func(executeErr error)
+	res, finish, err = this_.checkSqlGuard(requestBean, &sqlGuardTarget{
+		Action:     "executeSQL",
+		ToolboxId:  request.ToolboxId,
+		OwnerName:  request.OwnerName,
+		Content:    executeSql,
+		ApprovalId: request.ApprovalId,
+	}, func(guard *SqlGuardConfig) ([]*sqlguard.Risk, error) {
+		return getSqlRisks(service.GetDb(), config.Type, request.OwnerName, executeSql, guard)
 	})
+	if err != nil || res != nil {
+		return
+	}
+	var executeErr error
+	defer func() {
+		if executeErr == nil {
+			executeErr = err
+		}
+		finish(executeErr)
+	}()
+	masker, err := this_.getMasker(requestBean, request.ToolboxId)
+	if err != nil {
+		return
+	}
+	startTime := time.Now()
+	data := make(map[string]interface{})
+	if txStatus != nil {
+		// 标签页 已开启 事务，在 事务 连接 上 执行
+		data["executeList"], data["error"], err = executeInTx(request.WorkerId, requestBean.JWT.UserId, request.ToolboxId, request.OwnerName, executeSql, request.ShowDataMaxSize)
+		data["transaction"] = GetTxStatus(request.WorkerId, requestBean.JWT.UserId)
+	} else {
+		data["executeList"], data["error"], err = service.ExecuteSQL(param, request.OwnerName, executeSql, &db.ExecuteOptions{
+			SelectDataMax: request.ShowDataMaxSize,
+			OpenProfiling: request.OpenProfiling,
+		})
+	}
+
+	history := &SqlHistoryModel{
+		ToolboxId:  request.ToolboxId,
+		OwnerName:  request.OwnerName,
+		UserId:     requestBean.JWT.UserId,
+		ExecuteSql: executeSql,
+		Duration:   time.Since(startTime).Milliseconds(),
+		RowCount:   countExecuteRows(data["executeList"]),
+	}
+	if err != nil {
+		history.Error = err.Error()
+	} else if data["error"] != nil && data["error"] != "" {
+		history.Error = fmt.Sprint(data["error"])
+	}
+	_ = this_.sqlService.SaveHistory(history)
+	if history.Error != "" {
+		executeErr = errors.New(history.Error)
+	}
+
 	if err != nil {
 		return
 	}
+	// SQL 结果 无法 确定 来源 表，只 按 结果 列名 匹配 规则，不 限制 库、表；
+	// 别名（如 select phone as p）及 表达式（如 concat(phone, '')）的 列名 与 源 列 不同，无法 匹配，不会 脱敏，
+	// 需 配合 列名 正则 规则，或 不 授予 SQL 执行 权限
+	if maskedColumns := masker.MaskResult(request.ToolboxId, "", "", data["executeList"]); len(maskedColumns) > 0 {
+		data["maskedColumns"] = maskedColumns
+	}
 	res = data
 	return
 }
 
+// explain 获取 SQL 执行计划，转换为 统一的 计划树
+func (this_ *api) explain(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	config, sshConfig, err := this_.getConfig(requestBean, c)
+	if err != nil {
+		return
+	}
+	service, err := getService(config, sshConfig)
+	if err != nil {
+		return
+	}
+
+	var request = &BaseRequest{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+	executeSql := request.ExecuteSQL
+	if len(request.ScriptVars) > 0 {
+		executeSql, err = RenderScriptVars(executeSql, request.ScriptVars)
+		if err != nil {
+			return
+		}
+	}
+
+	res, err = Explain(service.GetDb(), config.Type, request.OwnerName, executeSql)
+	return
+}
+
 func (this_ *api) _import(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
 	config, sshConfig, err := this_.getConfig(requestBean, c)
 	if err != nil {
@@ -801,6 +1053,16 @@ func (this_ *api) export(requestBean *base.RequestBean, c *gin.Context) (res int
 	if !base.RequestJSON(exportParam, c) {
 		return
 	}
+	masker, err := this_.getMasker(requestBean, request.ToolboxId)
+	if err != nil {
+		return
+	}
+	if masker != nil {
+		err = maskExportParam(masker, service, param, request.ToolboxId, exportParam)
+		if err != nil {
+			return
+		}
+	}
 
 	var task *worker.Task
 	task, err = service.StartExport(param, exportParam)
@@ -993,6 +1255,7 @@ func (this_ *api) close(requestBean *base.RequestBean, c *gin.Context) (res inte
 	}
 
 	removeWorkerTasks(request.WorkerId)
+	rollbackWorkerTx(request.WorkerId)
 	return
 }
 
@@ -0,0 +1,343 @@
+package module_database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"strings"
+	"teamide/internal/module/module_toolbox"
+	"teamide/pkg/base"
+	"teamide/pkg/dbplan"
+	"teamide/pkg/sqlguard"
+	"time"
+)
+
+const (
+	// SqlGuardModeApprove 危险 操作 需要 其他 用户 审批，默认
+	SqlGuardModeApprove = "approve"
+	// SqlGuardModeBlock 危险 操作 直接 拦截
+	SqlGuardModeBlock = "block"
+
+	sqlGuardCountTimeout = 30 * time.Second
+)
+
+// SqlGuardConfig 危险SQL 防护，配置 在 工具箱 选项 中
+type SqlGuardConfig struct {
+	Production   bool   `json:"production,omitempty"`   // 生产 环境，开启 防护
+	GuardMode    string `json:"guardMode,omitempty"`    // approve、block
+	GuardMaxRows int64  `json:"guardMaxRows,omitempty"` // UPDATE、DELETE 影响 行数 超过 时 视为 危险，为 0 不 统计
+	GuardRoleId  int64  `json:"guardRoleId,omitempty"`  // 审批人 需要 的 角色，为 0 时 拥有 审批 权限 即可
+}
+
+type SqlApprovalRequest struct {
+	ApprovalId int64  `json:"approvalId,omitempty"`
+	ToolboxId  int64  `json:"toolboxId,omitempty"`
+	Status     int    `json:"status,omitempty"`
+	Mine       bool   `json:"mine,omitempty"` // 只 查询 自己 申请 的
+	Comment    string `json:"comment,omitempty"`
+}
+
+// sqlGuardTarget 需要 检查 的 操作，Content 为 执行 内容，审批 通过 后 执行 时 需 一致
+type sqlGuardTarget struct {
+	Action     string
+	ToolboxId  int64
+	OwnerName  string
+	TableName  string
+	Content    string
+	ApprovalId int64
+}
+
+func getSqlGuardConfig(option string) (guard *SqlGuardConfig) {
+	guard = &SqlGuardConfig{}
+	if option != "" {
+		_ = json.Unmarshal([]byte(option), guard)
+	}
+	if guard.GuardMode == "" {
+		guard.GuardMode = SqlGuardModeApprove
+	}
+	return
+}
+
+// getRequestSqlGuardConfig 当前 请求 工具箱 的 防护 配置，getConfig 后 调用
+func getRequestSqlGuardConfig(requestBean *base.RequestBean) (guard *SqlGuardConfig) {
+	var option string
+	if toolbox, ok := requestBean.GetExtend("toolboxModel").(*module_toolbox.ToolboxModel); ok && toolbox != nil {
+		option = toolbox.Option
+	}
+	return getSqlGuardConfig(option)
+}
+
+// checkSqlGuard 生产 工具箱 的 危险 操作 检查，无 风险 时 返回 空 继续 执行；
+// 拦截 模式 返回 错误；审批 模式 创建 审批单 并 返回，审批 通过 后 申请人 带 approvalId 重新 提交 执行 一次；
+// 继续 执行 时 执行 结束 后 需 调用 finish 记录 执行 结果
+func (this_ *api) checkSqlGuard(requestBean *base.RequestBean, target *sqlGuardTarget, getRisks func(guard *SqlGuardConfig) ([]*sqlguard.Risk, error)) (hold interface{}, finish func(executeErr error), err error) {
+	finish = func(executeErr error) {}
+	guard := getRequestSqlGuardConfig(requestBean)
+	if !guard.Production {
+		return
+	}
+
+	if target.ApprovalId > 0 {
+		var approval *SqlApprovalModel
+		approval, err = this_.sqlService.GetSqlApproval(target.ApprovalId)
+		if err != nil {
+			return
+		}
+		if approval == nil || approval.UserId != requestBean.JWT.UserId || approval.Action != target.Action ||
+			approval.ToolboxId != target.ToolboxId || approval.OwnerName != target.OwnerName || approval.TableName != target.TableName ||
+			approval.Content != target.Content {
+			err = errors.New("审批单不存在或与执行内容不一致")
+			return
+		}
+		err = this_.sqlService.ExecuteSqlApproval(approval.ApprovalId)
+		if err != nil {
+			this_.sqlService.SaveGuardLog(requestBean, "database/sqlGuard/execute", approval, err)
+			return
+		}
+		finish = func(executeErr error) {
+			var executeError string
+			if executeErr != nil {
+				executeError = executeErr.Error()
+			}
+			_ = this_.sqlService.FinishSqlApproval(approval.ApprovalId, executeError)
+			approval.ExecuteError = executeError
+			this_.sqlService.SaveGuardLog(requestBean, "database/sqlGuard/execute", approval, executeErr)
+		}
+		return
+	}
+
+	risks, err := getRisks(guard)
+	if err != nil || len(risks) == 0 {
+		return
+	}
+	var messages []string
+	for _, one := range risks {
+		messages = append(messages, one.Message)
+	}
+	bs, _ := json.Marshal(risks)
+	approval := &SqlApprovalModel{
+		ToolboxId: target.ToolboxId,
+		OwnerName: target.OwnerName,
+		TableName: target.TableName,
+		Action:    target.Action,
+		Content:   target.Content,
+		Risks:     string(bs),
+		UserId:    requestBean.JWT.UserId,
+	}
+	if guard.GuardMode == SqlGuardModeBlock {
+		err = errors.New("生产环境危险操作已拦截:" + strings.Join(messages, ";"))
+		this_.sqlService.SaveGuardLog(requestBean, "database/sqlGuard/block", approval, err)
+		return
+	}
+
+	err = this_.sqlService.InsertSqlApproval(approval)
+	if err != nil {
+		return
+	}
+	this_.sqlService.SaveGuardLog(requestBean, "database/sqlGuard/hold", approval, nil)
+	hold = map[string]interface{}{
+		"needApproval": true,
+		"approval":     approval,
+		"risks":        risks,
+	}
+	return
+}
+
+// getSqlRisks SQL 语句 的 风险，配置 了 行数 限制 时 统计 UPDATE、DELETE 影响 行数
+func getSqlRisks(sqlDb *sql.DB, databaseType string, ownerName string, executeSql string, guard *SqlGuardConfig) (risks []*sqlguard.Risk, err error) {
+	for _, one := range splitSqlStatements(executeSql) {
+		statement := sqlguard.Parse(one)
+		if risk := sqlguard.Check(statement); risk != nil {
+			risks = append(risks, risk)
+			continue
+		}
+		if guard.GuardMaxRows <= 0 || statement.CountSql == "" {
+			continue
+		}
+		count, e := countAffectRows(sqlDb, databaseType, ownerName, statement.CountSql)
+		if e != nil {
+			risks = append(risks, &sqlguard.Risk{
+				Type:    sqlguard.RiskRowsUnknown,
+				Sql:     statement.Sql,
+				Message: "无法统计影响行数:" + e.Error(),
+			})
+		} else if count > guard.GuardMaxRows {
+			risks = append(risks, &sqlguard.Risk{
+				Type:    sqlguard.RiskRows,
+				Sql:     statement.Sql,
+				Rows:    count,
+				Message: fmt.Sprint("影响行数[", count, "]超过限制[", guard.GuardMaxRows, "]"),
+			})
+		}
+	}
+	return
+}
+
+func countAffectRows(sqlDb *sql.DB, databaseType string, ownerName string, countSql string) (count int64, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), sqlGuardCountTimeout)
+	defer cancel()
+
+	conn, err := sqlDb.Conn(ctx)
+	if err != nil {
+		return
+	}
+	defer func() { _ = conn.Close() }()
+
+	if useSql := getUseOwnerSql(dbplan.GetDialect(databaseType), ownerName); useSql != "" {
+		_, err = conn.ExecContext(ctx, useSql)
+		if err != nil {
+			return
+		}
+	}
+	err = conn.QueryRowContext(ctx, countSql).Scan(&count)
+	return
+}
+
+// checkApprover 审批人 不能 是 申请人，需要 有 工具箱 的 审批 权限
+func (this_ *api) checkApprover(requestBean *base.RequestBean, approval *SqlApprovalModel) (err error) {
+	if approval.UserId == requestBean.JWT.UserId {
+		err = errors.New("不能审批自己的申请")
+		return
+	}
+	err = this_.checkApprovePower(requestBean, approval.ToolboxId)
+	return
+}
+
+// checkApprovePower 需要 有 工具箱 权限，工具箱 配置 了 角色 时 需要 拥有 该 角色
+func (this_ *api) checkApprovePower(requestBean *base.RequestBean, toolboxId int64) (err error) {
+	toolbox, err := this_.toolboxService.Get(toolboxId)
+	if err != nil {
+		return
+	}
+	if toolbox == nil {
+		err = errors.New("工具箱不存在")
+		return
+	}
+	err = this_.toolboxService.CheckToolboxPower(requestBean, toolbox)
+	if err != nil {
+		return
+	}
+	guard := getSqlGuardConfig(toolbox.Option)
+	if guard.GuardRoleId == 0 {
+		return
+	}
+	has, err := this_.sqlService.HasPowerRole(requestBean.JWT.UserId, guard.GuardRoleId)
+	if err != nil {
+		return
+	}
+	if !has {
+		err = errors.New("当前用户不是该工具箱的审批人")
+		return
+	}
+	return
+}
+
+// sqlApprovalList 自己 申请 的，及 有 审批 权限 的 工具箱 的 审批单
+func (this_ *api) sqlApprovalList(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	request := &SqlApprovalRequest{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+
+	if request.Mine {
+		res, err = this_.sqlService.QuerySqlApproval(requestBean.JWT.UserId, request.ToolboxId, request.Status)
+		return
+	}
+	list, err := this_.sqlService.QuerySqlApproval(0, request.ToolboxId, request.Status)
+	if err != nil {
+		return
+	}
+	var approvals = []*SqlApprovalModel{}
+	var canApprove = map[int64]bool{}
+	for _, one := range list {
+		if one.UserId != requestBean.JWT.UserId {
+			can, find := canApprove[one.ToolboxId]
+			if !find {
+				can = this_.checkApprovePower(requestBean, one.ToolboxId) == nil
+				canApprove[one.ToolboxId] = can
+			}
+			if !can {
+				continue
+			}
+		}
+		approvals = append(approvals, one)
+	}
+	res = approvals
+	return
+}
+
+func (this_ *api) sqlApprovalGet(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	request := &SqlApprovalRequest{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+
+	approval, err := this_.sqlService.GetSqlApproval(request.ApprovalId)
+	if err != nil || approval == nil {
+		return
+	}
+	if approval.UserId != requestBean.JWT.UserId {
+		err = this_.checkApprovePower(requestBean, approval.ToolboxId)
+		if err != nil {
+			return
+		}
+	}
+	res = approval
+	return
+}
+
+func (this_ *api) sqlApprovalApprove(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	return this_.sqlApprovalDo(requestBean, c, true)
+}
+
+func (this_ *api) sqlApprovalReject(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	return this_.sqlApprovalDo(requestBean, c, false)
+}
+
+func (this_ *api) sqlApprovalDo(requestBean *base.RequestBean, c *gin.Context, approved bool) (res interface{}, err error) {
+	request := &SqlApprovalRequest{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+
+	approval, err := this_.sqlService.GetSqlApproval(request.ApprovalId)
+	if err != nil {
+		return
+	}
+	if approval == nil {
+		err = errors.New("审批单不存在")
+		return
+	}
+	err = this_.checkApprover(requestBean, approval)
+	if err != nil {
+		return
+	}
+	err = this_.sqlService.ApproveSqlApproval(approval.ApprovalId, requestBean.JWT.UserId, approved, request.Comment)
+
+	action := "database/sqlGuard/reject"
+	if approved {
+		action = "database/sqlGuard/approve"
+	}
+	approval, _ = this_.sqlService.GetSqlApproval(request.ApprovalId)
+	this_.sqlService.SaveGuardLog(requestBean, action, approval, err)
+	if err != nil {
+		return
+	}
+	res = approval
+	return
+}
+
+// dataListGuardContent 数据 列表 执行 的 内容，用于 审批 比对
+func dataListGuardContent(request *BaseRequest) string {
+	bs, _ := json.Marshal(map[string]interface{}{
+		"columnList":      request.ColumnList,
+		"insertList":      request.InsertList,
+		"updateList":      request.UpdateList,
+		"updateWhereList": request.UpdateWhereList,
+		"deleteList":      request.DeleteList,
+	})
+	return string(bs)
+}
@@ -0,0 +1,216 @@
+package module_database
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/team-ide/go-dialect/worker"
+	"github.com/team-ide/go-tool/db"
+	"path/filepath"
+	"strings"
+	"teamide/pkg/base"
+	"teamide/pkg/datamask"
+)
+
+const (
+	// maskExportValue 表 导出 时 脱敏 列 的 值
+	maskExportValue = "******"
+)
+
+type MaskRuleRequest struct {
+	ToolboxId int64 `json:"toolboxId,omitempty"`
+	RuleId    int64 `json:"ruleId,omitempty"`
+}
+
+// getMasker 当前 用户 需要 脱敏 时 返回 工具箱 的 脱敏 规则，拥有 查看 明文 权限 时 返回 空
+func (this_ *api) getMasker(requestBean *base.RequestBean, toolboxId int64) (masker *datamask.Masker, err error) {
+	if this_.hasPower != nil && this_.hasPower(requestBean, unmaskPower) {
+		return
+	}
+	masker, err = this_.sqlService.GetMasker(toolboxId)
+	if err != nil {
+		return
+	}
+	if masker.IsEmpty() {
+		masker = nil
+	}
+	return
+}
+
+// maskExportParam 导出 脱敏，数据列表 导出 逐行 处理；表 导出 的 数据 由 导出 任务 分页 查询，
+// excel、csv、text 在 写入 文件 时 按 规则 策略 逐行 脱敏，sql 的 插入 语句 在 写入 前 已 生成，脱敏 列 统一 导出 为 掩码
+func maskExportParam(masker *datamask.Masker, service db.IService, param *db.Param, toolboxId int64, exportParam *worker.TaskExportParam) (err error) {
+	if exportParam.IsDataListExport {
+		var ownerName, tableName string
+		if len(exportParam.Owners) == 1 {
+			ownerName = exportParam.Owners[0].SourceName
+			if len(exportParam.Owners[0].Tables) == 1 {
+				tableName = exportParam.Owners[0].Tables[0].SourceName
+			}
+		}
+		masker.MaskDataList(toolboxId, ownerName, tableName, exportParam.DataList)
+		return
+	}
+	if !exportParam.ExportData {
+		return
+	}
+	if len(exportParam.Owners) == 0 {
+		err = errors.New("存在脱敏规则，导出数据时需指定导出的库")
+		return
+	}
+	dataSourceType := exportParam.DataSourceType
+	if dataSourceType != nil && dataSourceType.New == nil {
+		dataSourceType = worker.GetDataSource(dataSourceType.Name)
+	}
+	isSql := dataSourceType == nil || dataSourceType == worker.DataSourceTypeSql
+	// 目标 库.表 对应 的 目标 列名 及 规则
+	var tableRules = map[string]map[string]*datamask.Rule{}
+	for _, owner := range exportParam.Owners {
+		var tableNames []string
+		for _, one := range owner.Tables {
+			tableNames = append(tableNames, one.SourceName)
+		}
+		tables, e := loadSchemaTables(service, param, owner.SourceName, tableNames)
+		if e != nil {
+			err = e
+			return
+		}
+		// 未 指定 表 时 导出 库 下 所有 表，需要 明确 列出 才能 设置 列 值
+		if len(owner.Tables) == 0 {
+			for _, table := range tables {
+				if containsFold(owner.SkipTableNames, table.TableName) {
+					continue
+				}
+				owner.Tables = append(owner.Tables, &worker.TaskExportTable{
+					SourceName: table.TableName,
+				})
+			}
+		}
+		for _, exportTable := range owner.Tables {
+			var columnNames []string
+			for _, table := range tables {
+				if !strings.EqualFold(table.TableName, exportTable.SourceName) {
+					continue
+				}
+				for _, column := range table.ColumnList {
+					columnNames = append(columnNames, column.ColumnName)
+				}
+			}
+			var masked bool
+			for _, columnName := range columnNames {
+				if masker.GetRule(toolboxId, owner.SourceName, exportTable.SourceName, columnName) != nil {
+					masked = true
+					break
+				}
+			}
+			if !masked {
+				continue
+			}
+			if len(exportTable.Columns) == 0 {
+				for _, columnName := range columnNames {
+					exportTable.Columns = append(exportTable.Columns, &worker.TaskExportColumn{
+						SourceName: columnName,
+					})
+				}
+			}
+			var rules = map[string]*datamask.Rule{}
+			for _, column := range exportTable.Columns {
+				if column.SourceName == "" || column.Value != "" {
+					continue
+				}
+				rule := masker.GetRule(toolboxId, owner.SourceName, exportTable.SourceName, column.SourceName)
+				if rule == nil {
+					continue
+				}
+				if isSql {
+					column.Value = maskExportValue
+					continue
+				}
+				rules[firstNotEmpty(column.TargetName, column.SourceName)] = rule
+			}
+			key := firstNotEmpty(owner.TargetName, owner.SourceName) + "." + firstNotEmpty(exportTable.TargetName, exportTable.SourceName)
+			tableRules[key] = rules
+		}
+	}
+	if isSql || len(tableRules) == 0 {
+		return
+	}
+	exportParam.DataSourceType = &worker.DataSourceType{
+		Name:       dataSourceType.Name,
+		FileSuffix: dataSourceType.FileSuffix,
+		New: func(dataSourceParam *worker.DataSourceParam) (dataSource worker.DataSource) {
+			dataSource = dataSourceType.New(dataSourceParam)
+			// 表 数据 文件 为 <目标 库>/<目标 表>.<后缀>，SheetName 为 目标 表
+			key := filepath.Base(filepath.Dir(dataSourceParam.Path)) + "." + dataSourceParam.SheetName
+			if rules := tableRules[key]; len(rules) > 0 {
+				dataSource = &maskDataSource{
+					DataSource: dataSource,
+					masker:     masker,
+					rules:      rules,
+				}
+			}
+			return
+		},
+	}
+	return
+}
+
+// maskDataSource 写入 数据 前 按 列 规则 脱敏
+type maskDataSource struct {
+	worker.DataSource
+	masker *datamask.Masker
+	rules  map[string]*datamask.Rule
+}
+
+func (this_ *maskDataSource) Write(data *worker.DataSourceData) (err error) {
+	if data.HasData {
+		for columnName, rule := range this_.rules {
+			if value, ok := data.Data[columnName]; ok {
+				data.Data[columnName] = this_.masker.MaskValue(rule, value)
+			}
+		}
+	}
+	return this_.DataSource.Write(data)
+}
+
+func firstNotEmpty(values ...string) string {
+	for _, one := range values {
+		if one != "" {
+			return one
+		}
+	}
+	return ""
+}
+
+func (this_ *api) maskRuleList(_ *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	request := &MaskRuleRequest{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+
+	res, err = this_.sqlService.QueryMaskRule(request.ToolboxId, false)
+	return
+}
+
+func (this_ *api) maskRuleSave(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	rule := &MaskRuleModel{}
+	if !base.RequestJSON(rule, c) {
+		return
+	}
+
+	err = this_.sqlService.SaveMaskRule(requestBean.JWT.UserId, rule)
+	if err != nil {
+		return
+	}
+	res = rule
+	return
+}
+
+func (this_ *api) maskRuleDelete(_ *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	request := &MaskRuleRequest{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+
+	err = this_.sqlService.DeleteMaskRule(request.RuleId)
+	return
+}
@@ -0,0 +1,327 @@
+package module_database
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/team-ide/go-tool/db"
+	"teamide/pkg/base"
+	"teamide/pkg/dbschema"
+	"teamide/pkg/sqlguard"
+	"teamide/pkg/ssh"
+	"time"
+)
+
+type SchemaDiffRequest struct {
+	ToolboxId       int64  `json:"toolboxId,omitempty"`
+	OwnerName       string `json:"ownerName,omitempty"`
+	SourceToolboxId int64  `json:"sourceToolboxId,omitempty"` // 为 0 时 与 目标 同一个 工具箱
+	SourceOwnerName string `json:"sourceOwnerName,omitempty"`
+	*dbschema.Options
+	*dbschema.MigrationOptions
+}
+
+type SchemaMigrationRequest struct {
+	ToolboxId       int64  `json:"toolboxId,omitempty"`
+	OwnerName       string `json:"ownerName,omitempty"`
+	MigrationId     int64  `json:"migrationId,omitempty"`
+	ForwardSql      string `json:"forwardSql,omitempty"` // 未 保存 时 直接 执行 预览 的 脚本
+	IsRollback      bool   `json:"isRollback,omitempty"`
+	ContinueIsError bool   `json:"continueIsError,omitempty"`
+	TaskId          string `json:"taskId,omitempty"`
+	ApprovalId      int64  `json:"approvalId,omitempty"` // 生产 环境 危险 操作 审批 通过 后 执行
+}
+
+// getConfigById 源 工具箱 配置，校验 当前 用户 权限
+func (this_ *api) getConfigById(requestBean *base.RequestBean, toolboxId int64) (config *db.Config, sshConfig *ssh.Config, err error) {
+	toolbox, err := this_.toolboxService.Get(toolboxId)
+	if err != nil {
+		return
+	}
+	if toolbox == nil {
+		err = errors.New("工具箱不存在")
+		return
+	}
+	err = this_.toolboxService.CheckToolboxPower(requestBean, toolbox)
+	if err != nil {
+		return
+	}
+	config = &db.Config{}
+	sshConfig, err = this_.toolboxService.BindConfigByOption(toolbox.Option, config, nil)
+	return
+}
+
+// schemaCompare 比较 源 与 当前 工具箱（目标）的 表 结构
+func (this_ *api) schemaCompare(requestBean *base.RequestBean, c *gin.Context) (request *SchemaDiffRequest, diff *dbschema.Diff, service db.IService, param *db.Param, err error) {
+	config, sshConfig, err := this_.getConfig(requestBean, c)
+	if err != nil {
+		return
+	}
+	service, err = getService(config, sshConfig)
+	if err != nil {
+		return
+	}
+
+	request = &SchemaDiffRequest{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+	if request.Options == nil {
+		request.Options = &dbschema.Options{}
+	}
+	param = this_.getParam(requestBean, c)
+
+	sourceService := service
+	if request.SourceToolboxId != 0 && request.SourceToolboxId != request.ToolboxId {
+		var sourceConfig *db.Config
+		var sourceSSHConfig *ssh.Config
+		sourceConfig, sourceSSHConfig, err = this_.getConfigById(requestBean, request.SourceToolboxId)
+		if err != nil {
+			return
+		}
+		sourceService, err = getService(sourceConfig, sourceSSHConfig)
+		if err != nil {
+			return
+		}
+	} else if request.SourceOwnerName == request.OwnerName {
+		err = errors.New("源与目标不能相同")
+		return
+	}
+
+	sourceTables, err := loadSchemaTables(sourceService, param, request.SourceOwnerName, request.TableNames)
+	if err != nil {
+		return
+	}
+	targetTables, err := loadSchemaTables(service, param, request.OwnerName, request.TableNames)
+	if err != nil {
+		return
+	}
+	diff = dbschema.Compare(request.SourceOwnerName, sourceTables, request.OwnerName, targetTables, request.Options)
+	return
+}
+
+func (this_ *api) schemaDiff(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	_, diff, _, _, err := this_.schemaCompare(requestBean, c)
+	if err != nil {
+		return
+	}
+	res = diff
+	return
+}
+
+// schemaMigration 生成 目标 库 方言 的 迁移 及 回滚 脚本，用于 预览
+func (this_ *api) schemaMigration(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	request, diff, service, param, err := this_.schemaCompare(requestBean, c)
+	if err != nil {
+		return
+	}
+
+	migration := dbschema.BuildMigration(service.GetTargetDialect(param), param.ParamModel, diff, request.MigrationOptions)
+	data := make(map[string]interface{})
+	data["diff"] = diff
+	data["migration"] = migration
+	data["forwardSql"] = joinMigrationSql(migration.Forward)
+	data["rollbackSql"] = joinMigrationSql(migration.Rollback)
+	res = data
+	return
+}
+
+func (this_ *api) schemaMigrationSave(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	migration := &SchemaMigrationModel{}
+	if !base.RequestJSON(migration, c) {
+		return
+	}
+
+	err = this_.sqlService.SaveMigration(requestBean.JWT.UserId, migration)
+	if err != nil {
+		return
+	}
+	res = migration
+	return
+}
+
+func (this_ *api) schemaMigrationList(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	request := &SchemaMigrationRequest{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+
+	res, err = this_.sqlService.QueryMigration(requestBean.JWT.UserId, request.ToolboxId)
+	return
+}
+
+func (this_ *api) getMigration(requestBean *base.RequestBean, migrationId int64) (migration *SchemaMigrationModel, err error) {
+	migration, err = this_.sqlService.GetMigration(migrationId)
+	if err != nil {
+		return
+	}
+	if migration == nil || migration.UserId != requestBean.JWT.UserId {
+		migration = nil
+		err = errors.New("迁移脚本不存在")
+		return
+	}
+	return
+}
+
+func (this_ *api) schemaMigrationGet(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	request := &SchemaMigrationRequest{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+
+	res, err = this_.getMigration(requestBean, request.MigrationId)
+	return
+}
+
+func (this_ *api) schemaMigrationDelete(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	request := &SchemaMigrationRequest{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+
+	err = this_.sqlService.DeleteMigration(requestBean.JWT.UserId, request.MigrationId)
+	return
+}
+
+// schemaMigrationExec 在 当前 工具箱 执行 保存的 迁移（或 回滚）脚本，或 直接 执行 预览 的 脚本
+func (this_ *api) schemaMigrationExec(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	config, sshConfig, err := this_.getConfig(requestBean, c)
+	if err != nil {
+		return
+	}
+	service, err := getService(config, sshConfig)
+	if err != nil {
+		return
+	}
+
+	request := &SchemaMigrationRequest{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+
+	script := request.ForwardSql
+	ownerName := request.OwnerName
+	if request.MigrationId > 0 {
+		var migration *SchemaMigrationModel
+		migration, err = this_.getMigration(requestBean, request.MigrationId)
+		if err != nil {
+			return
+		}
+		if migration.ToolboxId != request.ToolboxId {
+			err = errors.New("迁移脚本的目标工具箱与当前工具箱不一致")
+			return
+		}
+		ownerName = migration.OwnerName
+		script = migration.ForwardSql
+		if request.IsRollback {
+			script = migration.RollbackSql
+		}
+	} else if request.IsRollback {
+		err = errors.New("未保存的迁移脚本不能回滚")
+		return
+	}
+
+	var finish func(executeErr error)
+	res, finish, err = this_.checkSqlGuard(requestBean, &sqlGuardTarget{
+		Action:     "schemaMigrationExec",
+		ToolboxId:  request.ToolboxId,
+		OwnerName:  ownerName,
+		Content:    script,
+		ApprovalId: request.ApprovalId,
+	}, func(guard *SqlGuardConfig) ([]*sqlguard.Risk, error) {
+		return getSqlRisks(service.GetDb(), config.Type, ownerName, script, guard)
+	})
+	if err != nil || res != nil {
+		return
+	}
+
+	task := &MigrationTask{
+		MigrationId:     request.MigrationId,
+		ToolboxId:       request.ToolboxId,
+		OwnerName:       ownerName,
+		IsRollback:      request.IsRollback,
+		ContinueIsError: request.ContinueIsError,
+		userId:          requestBean.JWT.UserId,
+	}
+	res, err = StartMigrationTask(service.GetDb(), config.Type, task, script, func(task *MigrationTask) {
+		info := task.getInfo()
+		history := &SqlHistoryModel{
+			ToolboxId:  info.ToolboxId,
+			OwnerName:  info.OwnerName,
+			UserId:     task.userId,
+			ExecuteSql: script,
+			Duration:   info.EndTime - info.StartTime,
+			RowCount:   int64(info.SuccessCount),
+			Error:      info.Error,
+		}
+		_ = this_.sqlService.SaveHistory(history)
+
+		var executeErr error
+		if info.Error != "" {
+			executeErr = errors.New(info.Error)
+		} else if info.ErrorCount > 0 {
+			executeErr = fmt.Errorf("%d条语句执行失败", info.ErrorCount)
+		}
+		finish(executeErr)
+
+		if info.MigrationId == 0 {
+			return
+		}
+		status := SchemaMigrationStatusExecuted
+		if info.IsRollback {
+			status = SchemaMigrationStatusRolledBack
+		}
+		if info.Error != "" || info.ErrorCount > 0 {
+			status = SchemaMigrationStatusFailed
+		}
+		_ = this_.sqlService.UpdateMigrationStatus(info.MigrationId, status, info.Error)
+	})
+	if err != nil {
+		finish(err)
+	}
+	return
+}
+
+func (this_ *api) schemaMigrationStatus(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	request := &SchemaMigrationRequest{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+
+	task := getMigrationTask(request.TaskId, requestBean.JWT.UserId)
+	if task != nil {
+		res = task.getInfo()
+	}
+	return
+}
+
+func (this_ *api) schemaMigrationStop(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	request := &SchemaMigrationRequest{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+
+	task := getMigrationTask(request.TaskId, requestBean.JWT.UserId)
+	if task != nil {
+		task.stop()
+		// 等待 当前 语句 执行 结束
+		for i := 0; i < 10; i++ {
+			if task.getInfo().IsEnd {
+				break
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+		res = task.getInfo()
+	}
+	return
+}
+
+func (this_ *api) schemaMigrationClean(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	request := &SchemaMigrationRequest{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+
+	removeMigrationTask(request.TaskId, requestBean.JWT.UserId)
+	return
+}
@@ -0,0 +1,181 @@
+package module_database
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/team-ide/go-dialect/worker"
+	"github.com/team-ide/go-tool/db"
+	"teamide/pkg/base"
+	"time"
+)
+
+type SqlHistoryRequest struct {
+	*SqlHistoryPage
+	*SqlHistoryQuery
+	SqlHistoryId int64 `json:"sqlHistoryId,omitempty"`
+	StartTime    int64 `json:"startTime,omitempty"`
+	EndTime      int64 `json:"endTime,omitempty"`
+}
+
+func (this_ *SqlHistoryRequest) getQuery(userId int64) (query *SqlHistoryQuery) {
+	query = &SqlHistoryQuery{}
+	if this_.SqlHistoryQuery != nil {
+		*query = *this_.SqlHistoryQuery
+	}
+	// 只能 查询 自己的 历史
+	query.UserId = userId
+	if this_.StartTime > 0 {
+		query.StartTime = time.UnixMilli(this_.StartTime)
+	}
+	if this_.EndTime > 0 {
+		query.EndTime = time.UnixMilli(this_.EndTime)
+	}
+	return
+}
+
+func (this_ *api) sqlHistoryQuery(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	request := &SqlHistoryRequest{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+	if request.SqlHistoryPage == nil {
+		request.SqlHistoryPage = &SqlHistoryPage{}
+	}
+	if request.SqlHistoryPage.Page == nil || request.SqlHistoryPage.Page.PageSize <= 0 {
+		request.SqlHistoryPage.Page = worker.NewPage()
+		request.SqlHistoryPage.Page.PageSize = 50
+	}
+
+	err = this_.sqlService.QueryHistoryPage(request.getQuery(requestBean.JWT.UserId), request.SqlHistoryPage)
+	if err != nil {
+		return
+	}
+	res = request.SqlHistoryPage
+	return
+}
+
+func (this_ *api) sqlHistoryDelete(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	request := &SqlHistoryRequest{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+
+	err = this_.sqlService.DeleteHistory(requestBean.JWT.UserId, request.SqlHistoryId)
+	return
+}
+
+func (this_ *api) sqlHistoryClean(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	request := &SqlHistoryRequest{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+
+	err = this_.sqlService.CleanHistory(request.getQuery(requestBean.JWT.UserId))
+	return
+}
+
+type SqlSavedRequest struct {
+	*SqlSavedModel
+	ScriptVars []*db.ScriptVar `json:"scriptVars,omitempty"`
+}
+
+func (this_ *api) sqlSavedList(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	request := &SqlSavedModel{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+
+	groupIds, err := this_.sqlService.GetVisibleGroupIds(requestBean, request.ToolboxId)
+	if err != nil {
+		return
+	}
+	res, err = this_.sqlService.QuerySaved(requestBean.JWT.UserId, request.ToolboxId, request.SavedType, groupIds)
+	return
+}
+
+func (this_ *api) sqlSavedSave(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	request := &SqlSavedRequest{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+	if request.SqlSavedModel == nil {
+		err = errors.New("保存的SQL不能为空")
+		return
+	}
+	saved := request.SqlSavedModel
+	saved.ScriptVars = ""
+	if len(request.ScriptVars) > 0 {
+		bs, e := json.Marshal(request.ScriptVars)
+		if e != nil {
+			err = e
+			return
+		}
+		saved.ScriptVars = string(bs)
+	}
+
+	err = this_.sqlService.SaveSaved(requestBean.JWT.UserId, saved)
+	if err != nil {
+		return
+	}
+	res = saved
+	return
+}
+
+func (this_ *api) sqlSavedDelete(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	request := &SqlSavedModel{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+
+	err = this_.sqlService.DeleteSaved(requestBean.JWT.UserId, request.SqlSavedId)
+	return
+}
+
+type SqlSnippetRenderRequest struct {
+	SqlSavedId int64           `json:"sqlSavedId,omitempty"`
+	ExecuteSql string          `json:"executeSql,omitempty"`
+	ScriptVars []*db.ScriptVar `json:"scriptVars,omitempty"`
+}
+
+// sqlSnippetRender 片段 填充 脚本变量，未传入的 变量 使用 片段 保存的 默认值
+func (this_ *api) sqlSnippetRender(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	request := &SqlSnippetRenderRequest{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+	executeSql := request.ExecuteSql
+	var scriptVars []*db.ScriptVar
+	if request.SqlSavedId != 0 {
+		var saved *SqlSavedModel
+		saved, err = this_.sqlService.GetSaved(request.SqlSavedId)
+		if err != nil {
+			return
+		}
+		if saved == nil {
+			err = errors.New("保存的SQL不存在")
+			return
+		}
+		var groupIds []int64
+		groupIds, err = this_.sqlService.GetVisibleGroupIds(requestBean, saved.ToolboxId)
+		if err != nil {
+			return
+		}
+		if !IsSavedVisible(saved, requestBean.JWT.UserId, groupIds) {
+			err = errors.New("保存的SQL不存在")
+			return
+		}
+		executeSql = saved.ExecuteSql
+		if saved.ScriptVars != "" {
+			err = json.Unmarshal([]byte(saved.ScriptVars), &scriptVars)
+			if err != nil {
+				return
+			}
+		}
+	}
+	// 后面的 覆盖 前面的 同名 变量
+	scriptVars = append(scriptVars, request.ScriptVars...)
+
+	res, err = RenderScriptVars(executeSql, scriptVars)
+	return
+}
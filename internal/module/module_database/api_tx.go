@@ -0,0 +1,70 @@
+package module_database
+
+import (
+	"github.com/gin-gonic/gin"
+	"teamide/pkg/base"
+)
+
+type TxRequest struct {
+	WorkerId    string `json:"workerId,omitempty"`
+	OwnerName   string `json:"ownerName,omitempty"`
+	IdleTimeout int64  `json:"idleTimeout,omitempty"` // 空闲 超时 毫秒，超时 自动 回滚
+}
+
+func (this_ *api) txBegin(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	config, sshConfig, err := this_.getConfig(requestBean, c)
+	if err != nil {
+		return
+	}
+	service, err := getService(config, sshConfig)
+	if err != nil {
+		return
+	}
+
+	var baseRequest = &BaseRequest{}
+	if !base.RequestJSON(baseRequest, c) {
+		return
+	}
+	var request = &TxRequest{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+
+	res, err = BeginTx(service.GetDb(), config.Type, request.WorkerId, baseRequest.ToolboxId, requestBean.JWT.UserId, request.OwnerName, request.IdleTimeout)
+	return
+}
+
+func (this_ *api) txCommit(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	var request = &TxRequest{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+
+	err = EndTx(request.WorkerId, requestBean.JWT.UserId, true)
+	return
+}
+
+func (this_ *api) txRollback(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	var request = &TxRequest{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+
+	err = EndTx(request.WorkerId, requestBean.JWT.UserId, false)
+	return
+}
+
+func (this_ *api) txStatus(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	var request = &TxRequest{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+
+	res = GetTxStatus(request.WorkerId, requestBean.JWT.UserId)
+	return
+}
+
+func (this_ *api) txList(requestBean *base.RequestBean, _ *gin.Context) (res interface{}, err error) {
+	res = QueryTxStatus(requestBean.JWT.UserId)
+	return
+}
@@ -0,0 +1,161 @@
+package module_database
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/team-ide/go-tool/util"
+	"go.uber.org/zap"
+	"teamide/internal/module/module_id"
+	"teamide/internal/module/module_log"
+	"teamide/pkg/base"
+	"time"
+)
+
+// GetSqlApproval 查询单个
+func (this_ *SqlService) GetSqlApproval(approvalId int64) (res *SqlApprovalModel, err error) {
+	res = &SqlApprovalModel{}
+
+	sql := `SELECT * FROM ` + TableDatabaseSqlApproval + ` WHERE approvalId=? `
+	find, err := this_.DatabaseWorker.QueryOne(sql, []interface{}{approvalId}, res)
+	if err != nil {
+		this_.Logger.Error("GetSqlApproval Error", zap.Error(err))
+		return
+	}
+
+	if !find {
+		res = nil
+	}
+	return
+}
+
+// QuerySqlApproval 查询 审批，userId、toolboxId、status 不为 0 时 过滤
+func (this_ *SqlService) QuerySqlApproval(userId int64, toolboxId int64, status int) (res []*SqlApprovalModel, err error) {
+	var values []interface{}
+	sql := `SELECT * FROM ` + TableDatabaseSqlApproval + ` WHERE 1=1 `
+	if userId != 0 {
+		sql += " AND userId=?"
+		values = append(values, userId)
+	}
+	if toolboxId != 0 {
+		sql += " AND toolboxId=?"
+		values = append(values, toolboxId)
+	}
+	if status != 0 {
+		sql += " AND status=?"
+		values = append(values, status)
+	}
+	sql += " ORDER BY createTime DESC "
+
+	err = this_.DatabaseWorker.Query(sql, values, &res)
+	if err != nil {
+		this_.Logger.Error("QuerySqlApproval Error", zap.Error(err))
+		return
+	}
+	return
+}
+
+// InsertSqlApproval 新增 待审批
+func (this_ *SqlService) InsertSqlApproval(approval *SqlApprovalModel) (err error) {
+	approval.ApprovalId, err = this_.idService.GetNextID(module_id.IDTypeDatabaseSqlApproval)
+	if err != nil {
+		return
+	}
+	approval.Status = SqlApprovalStatusPending
+	approval.CreateTime = time.Now()
+
+	sql := `INSERT INTO ` + TableDatabaseSqlApproval + `(approvalId, toolboxId, ownerName, tableName, action, content, risks, status, userId, createTime) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?) `
+	_, err = this_.DatabaseWorker.Exec(sql, []interface{}{approval.ApprovalId, approval.ToolboxId, approval.OwnerName, approval.TableName, approval.Action, approval.Content, approval.Risks, approval.Status, approval.UserId, approval.CreateTime})
+	if err != nil {
+		this_.Logger.Error("InsertSqlApproval Error", zap.Error(err))
+		return
+	}
+	return
+}
+
+// ApproveSqlApproval 审批，只能 处理 待审批 的
+func (this_ *SqlService) ApproveSqlApproval(approvalId int64, approveUserId int64, approved bool, comment string) (err error) {
+	status := SqlApprovalStatusRejected
+	if approved {
+		status = SqlApprovalStatusApproved
+	}
+	sql := `UPDATE ` + TableDatabaseSqlApproval + ` SET status=?,approveUserId=?,approveComment=?,approveTime=?,updateTime=? WHERE approvalId=? AND status=? `
+	count, err := this_.DatabaseWorker.Exec(sql, []interface{}{status, approveUserId, comment, time.Now(), time.Now(), approvalId, SqlApprovalStatusPending})
+	if err != nil {
+		this_.Logger.Error("ApproveSqlApproval Error", zap.Error(err))
+		return
+	}
+	if count == 0 {
+		err = errors.New("审批单不是待审批状态")
+		return
+	}
+	return
+}
+
+// ExecuteSqlApproval 开始 执行，标记 为 执行中，审批 通过 的 只能 执行 一次
+func (this_ *SqlService) ExecuteSqlApproval(approvalId int64) (err error) {
+	sql := `UPDATE ` + TableDatabaseSqlApproval + ` SET status=?,executeTime=?,updateTime=? WHERE approvalId=? AND status=? `
+	count, err := this_.DatabaseWorker.Exec(sql, []interface{}{SqlApprovalStatusExecuting, time.Now(), time.Now(), approvalId, SqlApprovalStatusApproved})
+	if err != nil {
+		this_.Logger.Error("ExecuteSqlApproval Error", zap.Error(err))
+		return
+	}
+	if count == 0 {
+		err = errors.New("审批单未通过或已执行")
+		return
+	}
+	return
+}
+
+// FinishSqlApproval 执行 结束，记录 执行 结果
+func (this_ *SqlService) FinishSqlApproval(approvalId int64, executeError string) (err error) {
+	status := SqlApprovalStatusExecuted
+	if executeError != "" {
+		status = SqlApprovalStatusExecuteFailed
+	}
+	sql := `UPDATE ` + TableDatabaseSqlApproval + ` SET status=?,executeError=?,updateTime=? WHERE approvalId=? AND status=? `
+	_, err = this_.DatabaseWorker.Exec(sql, []interface{}{status, executeError, time.Now(), approvalId, SqlApprovalStatusExecuting})
+	if err != nil {
+		this_.Logger.Error("FinishSqlApproval Error", zap.Error(err))
+		return
+	}
+	return
+}
+
+// HasPowerRole 用户 是否 拥有 角色，超管 拥有 所有 角色
+func (this_ *SqlService) HasPowerRole(userId int64, powerRoleId int64) (has bool, err error) {
+	roles, err := this_.powerUserService.QueryPowerRolesByUserId(userId)
+	if err != nil {
+		return
+	}
+	for _, one := range roles {
+		if one.PowerRoleId == powerRoleId || one.RoleType == base.SuperRoleType {
+			has = true
+			return
+		}
+	}
+	return
+}
+
+// SaveGuardLog 危险SQL 拦截、审批、执行 记录 到 操作 日志
+func (this_ *SqlService) SaveGuardLog(requestBean *base.RequestBean, action string, approval *SqlApprovalModel, errLog error) {
+	now := util.GetNow()
+	log := &module_log.LogModel{
+		Action:     action,
+		StartTime:  now,
+		EndTime:    now,
+		CreateTime: now,
+	}
+	if requestBean.JWT != nil {
+		log.UserId = requestBean.JWT.UserId
+		log.UserName = requestBean.JWT.Name
+		log.UserAccount = requestBean.JWT.Account
+		log.LoginId = requestBean.JWT.LoginId
+	}
+	if approval != nil {
+		bs, _ := json.Marshal(approval)
+		log.Data = string(bs)
+	}
+	if err := this_.logService.Insert(log, errLog); err != nil {
+		this_.Logger.Error("SaveGuardLog Error", zap.Error(err))
+	}
+}
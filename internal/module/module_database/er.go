@@ -0,0 +1,135 @@
+package module_database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/team-ide/go-dialect/dialect"
+	"strings"
+	"teamide/pkg/base"
+	"teamide/pkg/dbplan"
+	"teamide/pkg/erd"
+	"time"
+)
+
+const (
+	erQueryTimeout = 60 * time.Second
+)
+
+type ERRequest struct {
+	OwnerName  string   `json:"ownerName,omitempty"`
+	TableNames []string `json:"tableNames,omitempty"` // 为 空 时 为 库 下 所有 表
+	Format     string   `json:"format,omitempty"`     // dot、mermaid、plantuml，为 空 时 只 返回 模型
+	*erd.Options
+}
+
+// er 根据 表 结构 及 外键 生成 ER 模型，可选 按 命名 推断 关系 并 导出 文本
+func (this_ *api) er(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	config, sshConfig, err := this_.getConfig(requestBean, c)
+	if err != nil {
+		return
+	}
+	service, err := getService(config, sshConfig)
+	if err != nil {
+		return
+	}
+
+	request := &ERRequest{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+	param := this_.getParam(requestBean, c)
+
+	tables, err := loadSchemaTables(service, param, request.OwnerName, request.TableNames)
+	if err != nil {
+		return
+	}
+	foreignKeys, err := LoadForeignKeys(service.GetDb(), config.Type, request.OwnerName, tables)
+	if err != nil {
+		return
+	}
+	model := erd.NewModel(request.OwnerName, tables, foreignKeys, request.Options)
+
+	data := make(map[string]interface{})
+	data["model"] = model
+	if request.Format != "" {
+		var text string
+		text, err = erd.Render(model, request.Format)
+		if err != nil {
+			return
+		}
+		data["format"] = request.Format
+		data["text"] = text
+	}
+	res = data
+	return
+}
+
+// LoadForeignKeys 查询 库 下 声明的 外键，不支持的 数据库 返回 空
+func LoadForeignKeys(sqlDb *sql.DB, databaseType string, ownerName string, tables []*dialect.TableModel) (foreignKeys []*erd.ForeignKey, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), erQueryTimeout)
+	defer cancel()
+
+	switch dbplan.GetDialect(databaseType) {
+	case dbplan.DialectSqlite:
+		for _, table := range tables {
+			var rows []map[string]interface{}
+			rows, err = queryMaps(ctx, sqlDb, `PRAGMA foreign_key_list("`+strings.ReplaceAll(table.TableName, `"`, `""`)+`")`)
+			if err != nil {
+				return
+			}
+			foreignKeys = append(foreignKeys, erd.ParseSqliteForeignKeys(table.TableName, rows)...)
+		}
+	default:
+		query := getForeignKeySql(dbplan.GetDialect(databaseType), ownerName)
+		if query == "" {
+			return
+		}
+		var rows []map[string]interface{}
+		rows, err = queryMaps(ctx, sqlDb, query)
+		if err != nil {
+			err = errors.New("查询外键失败:" + err.Error())
+			return
+		}
+		foreignKeys = erd.ParseForeignKeys(rows)
+	}
+	return
+}
+
+// getForeignKeySql 各 数据库 外键 查询，列 统一 为 CONSTRAINT_NAME、TABLE_NAME、COLUMN_NAME、REFERENCED_TABLE_NAME、REFERENCED_COLUMN_NAME
+func getForeignKeySql(dialect string, ownerName string) (query string) {
+	owner := "'" + strings.ReplaceAll(ownerName, "'", "''") + "'"
+	switch dialect {
+	case dbplan.DialectMysql:
+		if ownerName == "" {
+			owner = "DATABASE()"
+		}
+		query = `SELECT CONSTRAINT_NAME, TABLE_NAME, COLUMN_NAME, REFERENCED_TABLE_NAME, REFERENCED_COLUMN_NAME
+FROM information_schema.KEY_COLUMN_USAGE
+WHERE TABLE_SCHEMA = ` + owner + ` AND REFERENCED_TABLE_NAME IS NOT NULL
+ORDER BY TABLE_NAME, CONSTRAINT_NAME, ORDINAL_POSITION`
+	case dbplan.DialectPostgresql:
+		if ownerName == "" {
+			owner = "current_schema()"
+		}
+		query = `SELECT kcu.constraint_name AS CONSTRAINT_NAME, kcu.table_name AS TABLE_NAME, kcu.column_name AS COLUMN_NAME,
+	rku.table_name AS REFERENCED_TABLE_NAME, rku.column_name AS REFERENCED_COLUMN_NAME
+FROM information_schema.referential_constraints rc
+JOIN information_schema.key_column_usage kcu ON kcu.constraint_schema = rc.constraint_schema AND kcu.constraint_name = rc.constraint_name
+JOIN information_schema.key_column_usage rku ON rku.constraint_schema = rc.unique_constraint_schema AND rku.constraint_name = rc.unique_constraint_name AND rku.ordinal_position = kcu.position_in_unique_constraint
+WHERE kcu.table_schema = ` + owner + `
+ORDER BY kcu.table_name, kcu.constraint_name, kcu.ordinal_position`
+	case dbplan.DialectOracle, dbplan.DialectDm:
+		if ownerName == "" {
+			owner = "USER"
+		}
+		query = `SELECT c.CONSTRAINT_NAME, a.TABLE_NAME, a.COLUMN_NAME, r.TABLE_NAME AS REFERENCED_TABLE_NAME, r.COLUMN_NAME AS REFERENCED_COLUMN_NAME
+FROM ALL_CONSTRAINTS c
+JOIN ALL_CONS_COLUMNS a ON a.OWNER = c.OWNER AND a.CONSTRAINT_NAME = c.CONSTRAINT_NAME
+JOIN ALL_CONS_COLUMNS r ON r.OWNER = c.R_OWNER AND r.CONSTRAINT_NAME = c.R_CONSTRAINT_NAME AND r.POSITION = a.POSITION
+WHERE c.CONSTRAINT_TYPE = 'R' AND c.OWNER = ` + owner + `
+ORDER BY a.TABLE_NAME, c.CONSTRAINT_NAME, a.POSITION`
+	}
+	return
+}
@@ -0,0 +1,160 @@
+package module_database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/team-ide/go-tool/util"
+	"strings"
+	"teamide/pkg/dbplan"
+	"time"
+)
+
+const (
+	explainTimeout = 30 * time.Second
+)
+
+// Explain 使用 独立连接 切换 库 后 获取 执行计划，只 解释 第一条 语句
+func Explain(sqlDb *sql.DB, databaseType string, ownerName string, executeSql string) (plan *dbplan.Plan, err error) {
+	dialect := dbplan.GetDialect(databaseType)
+	if dialect == "" {
+		err = errors.New("数据库类型[" + databaseType + "]不支持执行计划")
+		return
+	}
+	statements := splitSqlStatements(executeSql)
+	if len(statements) == 0 {
+		err = errors.New("SQL不能为空")
+		return
+	}
+	executeSql = statements[0]
+
+	ctx, cancel := context.WithTimeout(context.Background(), explainTimeout)
+	defer cancel()
+	conn, err := sqlDb.Conn(ctx)
+	if err != nil {
+		return
+	}
+	defer func() { _ = conn.Close() }()
+
+	if useSql := getUseOwnerSql(dialect, ownerName); useSql != "" {
+		_, err = conn.ExecContext(ctx, useSql)
+		if err != nil {
+			return
+		}
+	}
+
+	var root *dbplan.Node
+	var rows []map[string]interface{}
+	switch dialect {
+	case dbplan.DialectMysql:
+		rows, err = queryMaps(ctx, conn, "EXPLAIN "+executeSql)
+		if err != nil {
+			return
+		}
+		root = dbplan.ParseMysql(rows)
+	case dbplan.DialectPostgresql:
+		rows, err = queryMaps(ctx, conn, "EXPLAIN (FORMAT JSON) "+executeSql)
+		if err != nil {
+			return
+		}
+		root, err = dbplan.ParsePostgresql(dbplan.RowsText(rows))
+		if err != nil {
+			return
+		}
+	case dbplan.DialectOracle:
+		statementId := "TEAMIDE_" + util.GetUUID()[:20]
+		_, err = conn.ExecContext(ctx, "EXPLAIN PLAN SET STATEMENT_ID = '"+statementId+"' FOR "+executeSql)
+		if err != nil {
+			return
+		}
+		defer func() {
+			_, _ = conn.ExecContext(context.Background(), "DELETE FROM PLAN_TABLE WHERE STATEMENT_ID = '"+statementId+"'")
+		}()
+		rows, err = queryMaps(ctx, conn, `SELECT ID, PARENT_ID, OPERATION, OPTIONS, OBJECT_NAME, CARDINALITY, COST, ACCESS_PREDICATES, FILTER_PREDICATES FROM PLAN_TABLE WHERE STATEMENT_ID = '`+statementId+`' ORDER BY ID`)
+		if err != nil {
+			return
+		}
+		root = dbplan.ParseOracle(rows)
+	case dbplan.DialectDm:
+		rows, err = queryMaps(ctx, conn, "EXPLAIN "+executeSql)
+		if err != nil {
+			return
+		}
+		root = dbplan.ParseDm(dbplan.RowsText(rows))
+	case dbplan.DialectSqlite:
+		rows, err = queryMaps(ctx, conn, "EXPLAIN QUERY PLAN "+executeSql)
+		if err != nil {
+			return
+		}
+		root = dbplan.ParseSqlite(rows)
+	}
+	plan = dbplan.NewPlan(dialect, root)
+	return
+}
+
+// getUseOwnerSql 连接 切换 库 的 SQL，不支持的 返回 空
+func getUseOwnerSql(dialect string, ownerName string) (useSql string) {
+	if ownerName == "" {
+		return
+	}
+	switch dialect {
+	case dbplan.DialectMysql:
+		useSql = "USE `" + strings.ReplaceAll(ownerName, "`", "``") + "`"
+	case dbplan.DialectPostgresql:
+		useSql = `SET search_path TO "` + strings.ReplaceAll(ownerName, `"`, `""`) + `"`
+	case dbplan.DialectOracle:
+		useSql = `ALTER SESSION SET CURRENT_SCHEMA = "` + strings.ReplaceAll(ownerName, `"`, `""`) + `"`
+	case dbplan.DialectDm:
+		useSql = `SET SCHEMA "` + strings.ReplaceAll(ownerName, `"`, `""`) + `"`
+	}
+	return
+}
+
+type sqlQueryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+func queryMaps(ctx context.Context, queryer sqlQueryer, query string) (list []map[string]interface{}, err error) {
+	rows, err := queryer.QueryContext(ctx, query)
+	if err != nil {
+		return
+	}
+	defer func() { _ = rows.Close() }()
+
+	_, list, err = scanRows(rows, 0)
+	return
+}
+
+// scanRows 读取 结果集，[]byte 转为 字符串，maxSize 大于 0 时 最多 读取 maxSize 行
+func scanRows(rows *sql.Rows, maxSize int) (columns []string, list []map[string]interface{}, err error) {
+	columns, err = rows.Columns()
+	if err != nil {
+		return
+	}
+	list = []map[string]interface{}{}
+	for rows.Next() {
+		if maxSize > 0 && len(list) >= maxSize {
+			break
+		}
+		var values = make([]interface{}, len(columns))
+		var pointers = make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		err = rows.Scan(pointers...)
+		if err != nil {
+			return
+		}
+		var one = make(map[string]interface{})
+		for i, column := range columns {
+			if bs, ok := values[i].([]byte); ok {
+				one[column] = string(bs)
+			} else {
+				one[column] = values[i]
+			}
+		}
+		list = append(list, one)
+	}
+	err = rows.Err()
+	return
+}
@@ -0,0 +1,276 @@
+package module_database
+
+import (
+	"teamide/internal/install"
+)
+
+func GetInstallStages() []*install.StageModel {
+
+	return []*install.StageModel{
+
+		// 创建 SQL执行历史 表 开始
+		{
+			Version: "1.0",
+			Module:  ModuleDatabaseSql,
+			Stage:   `创建表[` + TableDatabaseSqlHistory + `]`,
+			Sql: &install.StageSqlModel{
+				Mysql: []string{`
+CREATE TABLE ` + TableDatabaseSqlHistory + ` (
+	sqlHistoryId bigint(20) NOT NULL COMMENT '历史ID',
+	toolboxId bigint(20) NOT NULL COMMENT '工具箱ID',
+	ownerName varchar(200) DEFAULT NULL COMMENT '库名',
+	userId bigint(20) DEFAULT NULL COMMENT '用户ID',
+	executeSql text DEFAULT NULL COMMENT '执行SQL',
+	duration bigint(20) DEFAULT NULL COMMENT '耗时毫秒',
+	rowCount bigint(20) DEFAULT NULL COMMENT '行数',
+	error text DEFAULT NULL COMMENT '错误',
+	createTime datetime NOT NULL COMMENT '创建时间',
+	PRIMARY KEY (sqlHistoryId),
+	KEY index_toolboxId (toolboxId),
+	KEY index_userId (userId),
+	KEY index_createTime (createTime)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='` + TableDatabaseSqlHistoryComment + `';
+`},
+				Sqlite: []string{`
+CREATE TABLE ` + TableDatabaseSqlHistory + ` (
+	sqlHistoryId bigint(20) NOT NULL,
+	toolboxId bigint(20) NOT NULL,
+	ownerName varchar(200) DEFAULT NULL,
+	userId bigint(20) DEFAULT NULL,
+	executeSql text DEFAULT NULL,
+	duration bigint(20) DEFAULT NULL,
+	rowCount bigint(20) DEFAULT NULL,
+	error text DEFAULT NULL,
+	createTime datetime NOT NULL,
+	PRIMARY KEY (sqlHistoryId)
+);
+`,
+					`CREATE INDEX ` + TableDatabaseSqlHistory + `_index_toolboxId on ` + TableDatabaseSqlHistory + ` (toolboxId);`,
+					`CREATE INDEX ` + TableDatabaseSqlHistory + `_index_userId on ` + TableDatabaseSqlHistory + ` (userId);`,
+					`CREATE INDEX ` + TableDatabaseSqlHistory + `_index_createTime on ` + TableDatabaseSqlHistory + ` (createTime);`,
+				},
+			},
+		},
+		// 创建 SQL执行历史 表 结束
+
+		// 创建 保存的SQL 表 开始
+		{
+			Version: "1.0",
+			Module:  ModuleDatabaseSql,
+			Stage:   `创建表[` + TableDatabaseSqlSaved + `]`,
+			Sql: &install.StageSqlModel{
+				Mysql: []string{`
+CREATE TABLE ` + TableDatabaseSqlSaved + ` (
+	sqlSavedId bigint(20) NOT NULL COMMENT '保存ID',
+	savedType int(10) NOT NULL COMMENT '类型',
+	toolboxId bigint(20) DEFAULT NULL COMMENT '工具箱ID',
+	groupId bigint(20) DEFAULT NULL COMMENT '分组ID',
+	ownerName varchar(200) DEFAULT NULL COMMENT '库名',
+	name varchar(100) NOT NULL COMMENT '名称',
+	comment varchar(500) DEFAULT NULL COMMENT '说明',
+	executeSql text DEFAULT NULL COMMENT 'SQL',
+	scriptVars text DEFAULT NULL COMMENT '脚本变量',
+	visibility int(10) DEFAULT 1 COMMENT '可见性',
+	userId bigint(20) DEFAULT NULL COMMENT '用户ID',
+	createTime datetime NOT NULL COMMENT '创建时间',
+	updateTime datetime DEFAULT NULL COMMENT '修改时间',
+	PRIMARY KEY (sqlSavedId),
+	KEY index_toolboxId (toolboxId),
+	KEY index_groupId (groupId),
+	KEY index_userId (userId)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='` + TableDatabaseSqlSavedComment + `';
+`},
+				Sqlite: []string{`
+CREATE TABLE ` + TableDatabaseSqlSaved + ` (
+	sqlSavedId bigint(20) NOT NULL,
+	savedType int(10) NOT NULL,
+	toolboxId bigint(20) DEFAULT NULL,
+	groupId bigint(20) DEFAULT NULL,
+	ownerName varchar(200) DEFAULT NULL,
+	name varchar(100) NOT NULL,
+	comment varchar(500) DEFAULT NULL,
+	executeSql text DEFAULT NULL,
+	scriptVars text DEFAULT NULL,
+	visibility int(10) DEFAULT 1,
+	userId bigint(20) DEFAULT NULL,
+	createTime datetime NOT NULL,
+	updateTime datetime DEFAULT NULL,
+	PRIMARY KEY (sqlSavedId)
+);
+`,
+					`CREATE INDEX ` + TableDatabaseSqlSaved + `_index_toolboxId on ` + TableDatabaseSqlSaved + ` (toolboxId);`,
+					`CREATE INDEX ` + TableDatabaseSqlSaved + `_index_groupId on ` + TableDatabaseSqlSaved + ` (groupId);`,
+					`CREATE INDEX ` + TableDatabaseSqlSaved + `_index_userId on ` + TableDatabaseSqlSaved + ` (userId);`,
+				},
+			},
+		},
+		// 创建 保存的SQL 表 结束
+
+		// 创建 结构迁移脚本 表 开始
+		{
+			Version: "1.0",
+			Module:  ModuleDatabaseSql,
+			Stage:   `创建表[` + TableDatabaseSchemaMigration + `]`,
+			Sql: &install.StageSqlModel{
+				Mysql: []string{`
+CREATE TABLE ` + TableDatabaseSchemaMigration + ` (
+	migrationId bigint(20) NOT NULL COMMENT '迁移ID',
+	name varchar(100) NOT NULL COMMENT '名称',
+	comment varchar(500) DEFAULT NULL COMMENT '说明',
+	sourceToolboxId bigint(20) DEFAULT NULL COMMENT '源工具箱ID',
+	sourceOwnerName varchar(200) DEFAULT NULL COMMENT '源库名',
+	toolboxId bigint(20) NOT NULL COMMENT '目标工具箱ID',
+	ownerName varchar(200) DEFAULT NULL COMMENT '目标库名',
+	forwardSql longtext DEFAULT NULL COMMENT '迁移SQL',
+	rollbackSql longtext DEFAULT NULL COMMENT '回滚SQL',
+	status int(10) DEFAULT 1 COMMENT '状态',
+	executeError text DEFAULT NULL COMMENT '执行错误',
+	executeTime datetime DEFAULT NULL COMMENT '执行时间',
+	userId bigint(20) DEFAULT NULL COMMENT '用户ID',
+	createTime datetime NOT NULL COMMENT '创建时间',
+	updateTime datetime DEFAULT NULL COMMENT '修改时间',
+	PRIMARY KEY (migrationId),
+	KEY index_toolboxId (toolboxId),
+	KEY index_userId (userId)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='` + TableDatabaseSchemaMigrationComment + `';
+`},
+				Sqlite: []string{`
+CREATE TABLE ` + TableDatabaseSchemaMigration + ` (
+	migrationId bigint(20) NOT NULL,
+	name varchar(100) NOT NULL,
+	comment varchar(500) DEFAULT NULL,
+	sourceToolboxId bigint(20) DEFAULT NULL,
+	sourceOwnerName varchar(200) DEFAULT NULL,
+	toolboxId bigint(20) NOT NULL,
+	ownerName varchar(200) DEFAULT NULL,
+	forwardSql text DEFAULT NULL,
+	rollbackSql text DEFAULT NULL,
+	status int(10) DEFAULT 1,
+	executeError text DEFAULT NULL,
+	executeTime datetime DEFAULT NULL,
+	userId bigint(20) DEFAULT NULL,
+	createTime datetime NOT NULL,
+	updateTime datetime DEFAULT NULL,
+	PRIMARY KEY (migrationId)
+);
+`,
+					`CREATE INDEX ` + TableDatabaseSchemaMigration + `_index_toolboxId on ` + TableDatabaseSchemaMigration + ` (toolboxId);`,
+					`CREATE INDEX ` + TableDatabaseSchemaMigration + `_index_userId on ` + TableDatabaseSchemaMigration + ` (userId);`,
+				},
+			},
+		},
+		// 创建 结构迁移脚本 表 结束
+
+		// 创建 脱敏规则 表 开始
+		{
+			Version: "1.0",
+			Module:  ModuleDatabaseSql,
+			Stage:   `创建表[` + TableDatabaseMaskRule + `]`,
+			Sql: &install.StageSqlModel{
+				Mysql: []string{`
+CREATE TABLE ` + TableDatabaseMaskRule + ` (
+	ruleId bigint(20) NOT NULL COMMENT '规则ID',
+	name varchar(100) DEFAULT NULL COMMENT '名称',
+	toolboxId bigint(20) DEFAULT NULL COMMENT '工具箱ID',
+	ownerName varchar(200) DEFAULT NULL COMMENT '库名',
+	tableName varchar(200) DEFAULT NULL COMMENT '表名',
+	columnName varchar(200) DEFAULT NULL COMMENT '列名',
+	columnPattern varchar(500) DEFAULT NULL COMMENT '列名正则',
+	strategy varchar(20) NOT NULL COMMENT '脱敏策略',
+	keepStart int(10) DEFAULT 0 COMMENT '保留前几位',
+	keepEnd int(10) DEFAULT 0 COMMENT '保留后几位',
+	maskChar varchar(10) DEFAULT NULL COMMENT '掩码字符',
+	status int(10) DEFAULT 1 COMMENT '状态',
+	userId bigint(20) DEFAULT NULL COMMENT '用户ID',
+	createTime datetime NOT NULL COMMENT '创建时间',
+	updateTime datetime DEFAULT NULL COMMENT '修改时间',
+	PRIMARY KEY (ruleId),
+	KEY index_toolboxId (toolboxId)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='` + TableDatabaseMaskRuleComment + `';
+`},
+				Sqlite: []string{`
+CREATE TABLE ` + TableDatabaseMaskRule + ` (
+	ruleId bigint(20) NOT NULL,
+	name varchar(100) DEFAULT NULL,
+	toolboxId bigint(20) DEFAULT NULL,
+	ownerName varchar(200) DEFAULT NULL,
+	tableName varchar(200) DEFAULT NULL,
+	columnName varchar(200) DEFAULT NULL,
+	columnPattern varchar(500) DEFAULT NULL,
+	strategy varchar(20) NOT NULL,
+	keepStart int(10) DEFAULT 0,
+	keepEnd int(10) DEFAULT 0,
+	maskChar varchar(10) DEFAULT NULL,
+	status int(10) DEFAULT 1,
+	userId bigint(20) DEFAULT NULL,
+	createTime datetime NOT NULL,
+	updateTime datetime DEFAULT NULL,
+	PRIMARY KEY (ruleId)
+);
+`,
+					`CREATE INDEX ` + TableDatabaseMaskRule + `_index_toolboxId on ` + TableDatabaseMaskRule + ` (toolboxId);`,
+				},
+			},
+		},
+		// 创建 脱敏规则 表 结束
+
+		// 创建 危险SQL审批 表 开始
+		{
+			Version: "1.0",
+			Module:  ModuleDatabaseSql,
+			Stage:   `创建表[` + TableDatabaseSqlApproval + `]`,
+			Sql: &install.StageSqlModel{
+				Mysql: []string{`
+CREATE TABLE ` + TableDatabaseSqlApproval + ` (
+	approvalId bigint(20) NOT NULL COMMENT '审批ID',
+	toolboxId bigint(20) NOT NULL COMMENT '工具箱ID',
+	ownerName varchar(200) DEFAULT NULL COMMENT '库名',
+	tableName varchar(200) DEFAULT NULL COMMENT '表名',
+	action varchar(50) NOT NULL COMMENT '操作',
+	content longtext DEFAULT NULL COMMENT '执行内容',
+	risks text DEFAULT NULL COMMENT '风险',
+	status int(10) DEFAULT 1 COMMENT '状态',
+	userId bigint(20) NOT NULL COMMENT '申请用户ID',
+	approveUserId bigint(20) DEFAULT NULL COMMENT '审批用户ID',
+	approveComment varchar(500) DEFAULT NULL COMMENT '审批意见',
+	approveTime datetime DEFAULT NULL COMMENT '审批时间',
+	executeTime datetime DEFAULT NULL COMMENT '执行时间',
+	executeError text DEFAULT NULL COMMENT '执行错误',
+	createTime datetime NOT NULL COMMENT '创建时间',
+	updateTime datetime DEFAULT NULL COMMENT '修改时间',
+	PRIMARY KEY (approvalId),
+	KEY index_toolboxId (toolboxId),
+	KEY index_userId (userId),
+	KEY index_status (status)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='` + TableDatabaseSqlApprovalComment + `';
+`},
+				Sqlite: []string{`
+CREATE TABLE ` + TableDatabaseSqlApproval + ` (
+	approvalId bigint(20) NOT NULL,
+	toolboxId bigint(20) NOT NULL,
+	ownerName varchar(200) DEFAULT NULL,
+	tableName varchar(200) DEFAULT NULL,
+	action varchar(50) NOT NULL,
+	content text DEFAULT NULL,
+	risks text DEFAULT NULL,
+	status int(10) DEFAULT 1,
+	userId bigint(20) NOT NULL,
+	approveUserId bigint(20) DEFAULT NULL,
+	approveComment varchar(500) DEFAULT NULL,
+	approveTime datetime DEFAULT NULL,
+	executeTime datetime DEFAULT NULL,
+	executeError text DEFAULT NULL,
+	createTime datetime NOT NULL,
+	updateTime datetime DEFAULT NULL,
+	PRIMARY KEY (approvalId)
+);
+`,
+					`CREATE INDEX ` + TableDatabaseSqlApproval + `_index_toolboxId on ` + TableDatabaseSqlApproval + ` (toolboxId);`,
+					`CREATE INDEX ` + TableDatabaseSqlApproval + `_index_userId on ` + TableDatabaseSqlApproval + ` (userId);`,
+					`CREATE INDEX ` + TableDatabaseSqlApproval + `_index_status on ` + TableDatabaseSqlApproval + ` (status);`,
+				},
+			},
+		},
+		// 创建 危险SQL审批 表 结束
+	}
+}
@@ -0,0 +1,160 @@
+package module_database
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"go.uber.org/zap"
+	"teamide/internal/module/module_id"
+	"teamide/internal/module/module_setting"
+	"teamide/pkg/datamask"
+	"time"
+)
+
+const (
+	// maskSecretSettingName 脱敏 密钥 保存 在 设置表 中 的 名称，首次 使用 时 生成，每个 安装 不同
+	maskSecretSettingName = "databaseMaskSecret"
+)
+
+// GetMaskRule 查询单个
+func (this_ *SqlService) GetMaskRule(ruleId int64) (res *MaskRuleModel, err error) {
+	res = &MaskRuleModel{}
+
+	sql := `SELECT * FROM ` + TableDatabaseMaskRule + ` WHERE ruleId=? `
+	find, err := this_.DatabaseWorker.QueryOne(sql, []interface{}{ruleId}, res)
+	if err != nil {
+		this_.Logger.Error("GetMaskRule Error", zap.Error(err))
+		return
+	}
+
+	if !find {
+		res = nil
+	}
+	return
+}
+
+// QueryMaskRule 查询 规则，toolboxId 不为 0 时 查询 该 工具箱 及 所有 工具箱 通用 的 规则
+func (this_ *SqlService) QueryMaskRule(toolboxId int64, onlyEnabled bool) (res []*MaskRuleModel, err error) {
+	var values []interface{}
+	sql := `SELECT * FROM ` + TableDatabaseMaskRule + ` WHERE 1=1 `
+	if toolboxId != 0 {
+		sql += " AND (toolboxId=? OR toolboxId=0 OR toolboxId IS NULL)"
+		values = append(values, toolboxId)
+	}
+	if onlyEnabled {
+		sql += " AND status=?"
+		values = append(values, MaskRuleStatusEnabled)
+	}
+	sql += " ORDER BY createTime "
+
+	err = this_.DatabaseWorker.Query(sql, values, &res)
+	if err != nil {
+		this_.Logger.Error("QueryMaskRule Error", zap.Error(err))
+		return
+	}
+	return
+}
+
+// SaveMaskRule 新增或更新
+func (this_ *SqlService) SaveMaskRule(userId int64, rule *MaskRuleModel) (err error) {
+	err = datamask.ValidateRule(rule.toRule())
+	if err != nil {
+		return
+	}
+	if rule.Status == 0 {
+		rule.Status = MaskRuleStatusEnabled
+	}
+
+	if rule.RuleId > 0 {
+		var find *MaskRuleModel
+		find, err = this_.GetMaskRule(rule.RuleId)
+		if err != nil {
+			return
+		}
+		if find == nil {
+			err = errors.New("脱敏规则不存在")
+			return
+		}
+		sql := `UPDATE ` + TableDatabaseMaskRule + ` SET name=?,toolboxId=?,ownerName=?,tableName=?,columnName=?,columnPattern=?,strategy=?,keepStart=?,keepEnd=?,maskChar=?,status=?,updateTime=? WHERE ruleId=? `
+		_, err = this_.DatabaseWorker.Exec(sql, []interface{}{rule.Name, rule.ToolboxId, rule.OwnerName, rule.TableName, rule.ColumnName, rule.ColumnPattern, rule.Strategy, rule.KeepStart, rule.KeepEnd, rule.MaskChar, rule.Status, time.Now(), rule.RuleId})
+	} else {
+		rule.RuleId, err = this_.idService.GetNextID(module_id.IDTypeDatabaseMaskRule)
+		if err != nil {
+			return
+		}
+		rule.UserId = userId
+		rule.CreateTime = time.Now()
+		sql := `INSERT INTO ` + TableDatabaseMaskRule + `(ruleId, name, toolboxId, ownerName, tableName, columnName, columnPattern, strategy, keepStart, keepEnd, maskChar, status, userId, createTime) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) `
+		_, err = this_.DatabaseWorker.Exec(sql, []interface{}{rule.RuleId, rule.Name, rule.ToolboxId, rule.OwnerName, rule.TableName, rule.ColumnName, rule.ColumnPattern, rule.Strategy, rule.KeepStart, rule.KeepEnd, rule.MaskChar, rule.Status, rule.UserId, rule.CreateTime})
+	}
+	if err != nil {
+		this_.Logger.Error("SaveMaskRule Error", zap.Error(err))
+		return
+	}
+	return
+}
+
+// DeleteMaskRule 删除
+func (this_ *SqlService) DeleteMaskRule(ruleId int64) (err error) {
+	sql := `DELETE FROM ` + TableDatabaseMaskRule + ` WHERE ruleId=? `
+	_, err = this_.DatabaseWorker.Exec(sql, []interface{}{ruleId})
+	if err != nil {
+		this_.Logger.Error("DeleteMaskRule Error", zap.Error(err))
+		return
+	}
+	return
+}
+
+// GetMasker 工具箱 启用的 脱敏 规则
+func (this_ *SqlService) GetMasker(toolboxId int64) (masker *datamask.Masker, err error) {
+	list, err := this_.QueryMaskRule(toolboxId, true)
+	if err != nil {
+		return
+	}
+	var rules []*datamask.Rule
+	for _, one := range list {
+		rules = append(rules, one.toRule())
+	}
+	secret, err := this_.getMaskSecret()
+	if err != nil {
+		return
+	}
+	masker, err = datamask.NewMasker(rules, secret)
+	return
+}
+
+// getMaskSecret hash、fake 脱敏 的 密钥，设置表 中 没有 则 生成 并 保存
+func (this_ *SqlService) getMaskSecret() (secret []byte, err error) {
+	this_.maskSecretLock.Lock()
+	defer this_.maskSecretLock.Unlock()
+	if this_.maskSecret != nil {
+		secret = this_.maskSecret
+		return
+	}
+
+	find, err := this_.settingService.Get(maskSecretSettingName)
+	if err != nil {
+		return
+	}
+	if find == nil {
+		bs := make([]byte, 32)
+		if _, err = rand.Read(bs); err != nil {
+			return
+		}
+		find = &module_setting.SettingModel{
+			Name:  maskSecretSettingName,
+			Value: hex.EncodeToString(bs),
+		}
+		if err = this_.settingService.Insert(find); err != nil {
+			// 其它 服务 实例 已 生成
+			if find, _ = this_.settingService.Get(maskSecretSettingName); find == nil {
+				this_.Logger.Error("save mask secret error", zap.Error(err))
+				return
+			}
+			err = nil
+		}
+	}
+	this_.maskSecret = []byte(find.Value)
+	secret = this_.maskSecret
+	return
+}
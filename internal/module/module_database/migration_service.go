@@ -0,0 +1,107 @@
+package module_database
+
+import (
+	"errors"
+	"go.uber.org/zap"
+	"teamide/internal/module/module_id"
+	"time"
+)
+
+// GetMigration 查询单个
+func (this_ *SqlService) GetMigration(migrationId int64) (res *SchemaMigrationModel, err error) {
+	res = &SchemaMigrationModel{}
+
+	sql := `SELECT * FROM ` + TableDatabaseSchemaMigration + ` WHERE migrationId=? `
+	find, err := this_.DatabaseWorker.QueryOne(sql, []interface{}{migrationId}, res)
+	if err != nil {
+		this_.Logger.Error("GetMigration Error", zap.Error(err))
+		return
+	}
+
+	if !find {
+		res = nil
+	}
+	return
+}
+
+// QueryMigration 查询 自己的 迁移脚本，toolboxId 不为 0 时 只 查询 该 目标 工具箱
+func (this_ *SqlService) QueryMigration(userId int64, toolboxId int64) (res []*SchemaMigrationModel, err error) {
+	var values []interface{}
+	sql := `SELECT migrationId,name,comment,sourceToolboxId,sourceOwnerName,toolboxId,ownerName,status,executeError,executeTime,userId,createTime,updateTime FROM ` + TableDatabaseSchemaMigration + ` WHERE userId=? `
+	values = append(values, userId)
+	if toolboxId != 0 {
+		sql += " AND toolboxId=?"
+		values = append(values, toolboxId)
+	}
+	sql += " ORDER BY createTime DESC "
+
+	err = this_.DatabaseWorker.Query(sql, values, &res)
+	if err != nil {
+		this_.Logger.Error("QueryMigration Error", zap.Error(err))
+		return
+	}
+	return
+}
+
+// SaveMigration 新增或更新，只能 修改 自己的，修改 脚本 后 状态 重置 为 未执行
+func (this_ *SqlService) SaveMigration(userId int64, migration *SchemaMigrationModel) (err error) {
+	if migration.Name == "" {
+		err = errors.New("名称不能为空")
+		return
+	}
+	if migration.ToolboxId == 0 {
+		err = errors.New("目标工具箱不能为空")
+		return
+	}
+	migration.Status = SchemaMigrationStatusReady
+
+	if migration.MigrationId > 0 {
+		var find *SchemaMigrationModel
+		find, err = this_.GetMigration(migration.MigrationId)
+		if err != nil {
+			return
+		}
+		if find == nil || find.UserId != userId {
+			err = errors.New("迁移脚本不存在")
+			return
+		}
+		sql := `UPDATE ` + TableDatabaseSchemaMigration + ` SET name=?,comment=?,sourceToolboxId=?,sourceOwnerName=?,toolboxId=?,ownerName=?,forwardSql=?,rollbackSql=?,status=?,executeError=?,updateTime=? WHERE migrationId=? `
+		_, err = this_.DatabaseWorker.Exec(sql, []interface{}{migration.Name, migration.Comment, migration.SourceToolboxId, migration.SourceOwnerName, migration.ToolboxId, migration.OwnerName, migration.ForwardSql, migration.RollbackSql, migration.Status, "", time.Now(), migration.MigrationId})
+	} else {
+		migration.MigrationId, err = this_.idService.GetNextID(module_id.IDTypeDatabaseSchemaMigration)
+		if err != nil {
+			return
+		}
+		migration.UserId = userId
+		migration.CreateTime = time.Now()
+		sql := `INSERT INTO ` + TableDatabaseSchemaMigration + `(migrationId, name, comment, sourceToolboxId, sourceOwnerName, toolboxId, ownerName, forwardSql, rollbackSql, status, userId, createTime) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) `
+		_, err = this_.DatabaseWorker.Exec(sql, []interface{}{migration.MigrationId, migration.Name, migration.Comment, migration.SourceToolboxId, migration.SourceOwnerName, migration.ToolboxId, migration.OwnerName, migration.ForwardSql, migration.RollbackSql, migration.Status, migration.UserId, migration.CreateTime})
+	}
+	if err != nil {
+		this_.Logger.Error("SaveMigration Error", zap.Error(err))
+		return
+	}
+	return
+}
+
+// UpdateMigrationStatus 记录 执行 结果
+func (this_ *SqlService) UpdateMigrationStatus(migrationId int64, status int, executeError string) (err error) {
+	sql := `UPDATE ` + TableDatabaseSchemaMigration + ` SET status=?,executeError=?,executeTime=? WHERE migrationId=? `
+	_, err = this_.DatabaseWorker.Exec(sql, []interface{}{status, executeError, time.Now(), migrationId})
+	if err != nil {
+		this_.Logger.Error("UpdateMigrationStatus Error", zap.Error(err))
+		return
+	}
+	return
+}
+
+// DeleteMigration 删除 自己的
+func (this_ *SqlService) DeleteMigration(userId int64, migrationId int64) (err error) {
+	sql := `DELETE FROM ` + TableDatabaseSchemaMigration + ` WHERE migrationId=? AND userId=? `
+	_, err = this_.DatabaseWorker.Exec(sql, []interface{}{migrationId, userId})
+	if err != nil {
+		this_.Logger.Error("DeleteMigration Error", zap.Error(err))
+		return
+	}
+	return
+}
@@ -0,0 +1,174 @@
+package module_database
+
+import (
+	"teamide/pkg/datamask"
+	"time"
+)
+
+const (
+	// ModuleDatabaseSql 数据库SQL模块
+	ModuleDatabaseSql = "database_sql"
+	// TableDatabaseSqlHistory 数据库SQL执行历史表
+	TableDatabaseSqlHistory        = "TM_DATABASE_SQL_HISTORY"
+	TableDatabaseSqlHistoryComment = "数据库SQL执行历史"
+	// TableDatabaseSqlSaved 数据库保存的SQL表
+	TableDatabaseSqlSaved        = "TM_DATABASE_SQL_SAVED"
+	TableDatabaseSqlSavedComment = "数据库保存的SQL"
+	// TableDatabaseSchemaMigration 数据库结构迁移脚本表
+	TableDatabaseSchemaMigration        = "TM_DATABASE_SCHEMA_MIGRATION"
+	TableDatabaseSchemaMigrationComment = "数据库结构迁移脚本"
+	// TableDatabaseMaskRule 数据库脱敏规则表
+	TableDatabaseMaskRule        = "TM_DATABASE_MASK_RULE"
+	TableDatabaseMaskRuleComment = "数据库脱敏规则"
+	// TableDatabaseSqlApproval 数据库危险SQL审批表
+	TableDatabaseSqlApproval        = "TM_DATABASE_SQL_APPROVAL"
+	TableDatabaseSqlApprovalComment = "数据库危险SQL审批"
+)
+
+const (
+	// SqlSavedTypeQuery 保存的 查询
+	SqlSavedTypeQuery = 1
+	// SqlSavedTypeSnippet 片段，执行时 填充 脚本变量
+	SqlSavedTypeSnippet = 2
+
+	// SqlSavedVisibilitySelf 仅 自己 可见
+	SqlSavedVisibilitySelf = 1
+	// SqlSavedVisibilityGroup 工具箱 所在分组 共享
+	SqlSavedVisibilityGroup = 2
+)
+
+const (
+	// SchemaMigrationStatusReady 未执行
+	SchemaMigrationStatusReady = 1
+	// SchemaMigrationStatusExecuted 已执行
+	SchemaMigrationStatusExecuted = 2
+	// SchemaMigrationStatusFailed 执行失败
+	SchemaMigrationStatusFailed = 3
+	// SchemaMigrationStatusRolledBack 已回滚
+	SchemaMigrationStatusRolledBack = 4
+)
+
+const (
+	// MaskRuleStatusEnabled 启用
+	MaskRuleStatusEnabled = 1
+	// MaskRuleStatusDisabled 停用
+	MaskRuleStatusDisabled = 2
+)
+
+const (
+	// SqlApprovalStatusPending 待审批
+	SqlApprovalStatusPending = 1
+	// SqlApprovalStatusApproved 已通过，申请人 可 执行 一次
+	SqlApprovalStatusApproved = 2
+	// SqlApprovalStatusRejected 已拒绝
+	SqlApprovalStatusRejected = 3
+	// SqlApprovalStatusExecuted 已执行
+	SqlApprovalStatusExecuted = 4
+	// SqlApprovalStatusExecuting 执行中，开始 执行 时 标记，防止 重复 执行
+	SqlApprovalStatusExecuting = 5
+	// SqlApprovalStatusExecuteFailed 执行失败，错误 记录 在 executeError
+	SqlApprovalStatusExecuteFailed = 6
+)
+
+// SqlHistoryModel SQL执行历史
+type SqlHistoryModel struct {
+	SqlHistoryId int64     `json:"sqlHistoryId,omitempty"`
+	ToolboxId    int64     `json:"toolboxId,omitempty"`
+	OwnerName    string    `json:"ownerName,omitempty"`
+	UserId       int64     `json:"userId,omitempty"`
+	ExecuteSql   string    `json:"executeSql,omitempty"`
+	Duration     int64     `json:"duration"` // 耗时 毫秒
+	RowCount     int64     `json:"rowCount"` // 查询 行数 及 影响 行数
+	Error        string    `json:"error,omitempty"`
+	CreateTime   time.Time `json:"createTime,omitempty"`
+}
+
+// SqlSavedModel 保存的 查询 及 片段，片段 的 脚本变量 以 JSON 存储在 scriptVars 中
+type SqlSavedModel struct {
+	SqlSavedId int64     `json:"sqlSavedId,omitempty"`
+	SavedType  int       `json:"savedType,omitempty"`
+	ToolboxId  int64     `json:"toolboxId,omitempty"` // 为 0 时 所有 数据库 可用
+	GroupId    int64     `json:"groupId,omitempty"`
+	OwnerName  string    `json:"ownerName,omitempty"`
+	Name       string    `json:"name,omitempty"`
+	Comment    string    `json:"comment,omitempty"`
+	ExecuteSql string    `json:"executeSql,omitempty"`
+	ScriptVars string    `json:"scriptVars,omitempty"`
+	Visibility int       `json:"visibility,omitempty"`
+	UserId     int64     `json:"userId,omitempty"`
+	CreateTime time.Time `json:"createTime,omitempty"`
+	UpdateTime time.Time `json:"updateTime,omitempty"`
+}
+
+// SchemaMigrationModel 结构迁移脚本，在 目标 库 执行 后 与 源 库 结构 一致
+type SchemaMigrationModel struct {
+	MigrationId     int64     `json:"migrationId,omitempty"`
+	Name            string    `json:"name,omitempty"`
+	Comment         string    `json:"comment,omitempty"`
+	SourceToolboxId int64     `json:"sourceToolboxId,omitempty"`
+	SourceOwnerName string    `json:"sourceOwnerName,omitempty"`
+	ToolboxId       int64     `json:"toolboxId,omitempty"`
+	OwnerName       string    `json:"ownerName,omitempty"`
+	ForwardSql      string    `json:"forwardSql,omitempty"`
+	RollbackSql     string    `json:"rollbackSql,omitempty"`
+	Status          int       `json:"status,omitempty"`
+	ExecuteError    string    `json:"executeError,omitempty"`
+	ExecuteTime     time.Time `json:"executeTime,omitempty"`
+	UserId          int64     `json:"userId,omitempty"`
+	CreateTime      time.Time `json:"createTime,omitempty"`
+	UpdateTime      time.Time `json:"updateTime,omitempty"`
+}
+
+// MaskRuleModel 脱敏规则，toolboxId、ownerName、tableName 为 空 时 匹配 所有，列 按 列名 或 列名 正则 匹配
+type MaskRuleModel struct {
+	RuleId        int64     `json:"ruleId,omitempty"`
+	Name          string    `json:"name,omitempty"`
+	ToolboxId     int64     `json:"toolboxId,omitempty"`
+	OwnerName     string    `json:"ownerName,omitempty"`
+	TableName     string    `json:"tableName,omitempty"`
+	ColumnName    string    `json:"columnName,omitempty"`
+	ColumnPattern string    `json:"columnPattern,omitempty"`
+	Strategy      string    `json:"strategy,omitempty"`
+	KeepStart     int       `json:"keepStart,omitempty"`
+	KeepEnd       int       `json:"keepEnd,omitempty"`
+	MaskChar      string    `json:"maskChar,omitempty"`
+	Status        int       `json:"status,omitempty"`
+	UserId        int64     `json:"userId,omitempty"`
+	CreateTime    time.Time `json:"createTime,omitempty"`
+	UpdateTime    time.Time `json:"updateTime,omitempty"`
+}
+
+func (this_ *MaskRuleModel) toRule() *datamask.Rule {
+	return &datamask.Rule{
+		RuleId:        this_.RuleId,
+		ToolboxId:     this_.ToolboxId,
+		OwnerName:     this_.OwnerName,
+		TableName:     this_.TableName,
+		ColumnName:    this_.ColumnName,
+		ColumnPattern: this_.ColumnPattern,
+		Strategy:      this_.Strategy,
+		KeepStart:     this_.KeepStart,
+		KeepEnd:       this_.KeepEnd,
+		MaskChar:      this_.MaskChar,
+	}
+}
+
+// SqlApprovalModel 危险SQL审批，content 为 执行 内容（SQL 或 数据 列表 JSON），执行 时 需 与 审批 时 一致
+type SqlApprovalModel struct {
+	ApprovalId     int64     `json:"approvalId,omitempty"`
+	ToolboxId      int64     `json:"toolboxId,omitempty"`
+	OwnerName      string    `json:"ownerName,omitempty"`
+	TableName      string    `json:"tableName,omitempty"`
+	Action         string    `json:"action,omitempty"`
+	Content        string    `json:"content,omitempty"`
+	Risks          string    `json:"risks,omitempty"`
+	Status         int       `json:"status,omitempty"`
+	UserId         int64     `json:"userId,omitempty"`
+	ApproveUserId  int64     `json:"approveUserId,omitempty"`
+	ApproveComment string    `json:"approveComment,omitempty"`
+	ApproveTime    time.Time `json:"approveTime,omitempty"`
+	ExecuteTime    time.Time `json:"executeTime,omitempty"`
+	ExecuteError   string    `json:"executeError,omitempty"`
+	CreateTime     time.Time `json:"createTime,omitempty"`
+	UpdateTime     time.Time `json:"updateTime,omitempty"`
+}
@@ -0,0 +1,231 @@
+package module_database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/team-ide/go-dialect/dialect"
+	"github.com/team-ide/go-tool/db"
+	"github.com/team-ide/go-tool/util"
+	"go.uber.org/zap"
+	"strings"
+	"sync"
+	"teamide/pkg/dbplan"
+)
+
+// loadSchemaTables 查询 库 下 表 的 详细 结构，tableNames 不为空 时 只 查询 指定 表
+func loadSchemaTables(service db.IService, param *db.Param, ownerName string, tableNames []string) (tables []*dialect.TableModel, err error) {
+	list, err := service.TablesSelect(param, ownerName)
+	if err != nil {
+		return
+	}
+	for _, one := range list {
+		if len(tableNames) > 0 && !containsFold(tableNames, one.TableName) {
+			continue
+		}
+		var table *dialect.TableModel
+		table, err = service.TableDetail(param, ownerName, one.TableName)
+		if err != nil {
+			err = errors.New("查询表[" + one.TableName + "]结构失败:" + err.Error())
+			return
+		}
+		if table != nil {
+			tables = append(tables, table)
+		}
+	}
+	return
+}
+
+func containsFold(list []string, value string) bool {
+	for _, one := range list {
+		if strings.EqualFold(one, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// joinMigrationSql 合并 为 可 预览、保存 的 脚本
+func joinMigrationSql(sqlList []string) string {
+	if len(sqlList) == 0 {
+		return ""
+	}
+	return strings.Join(sqlList, ";\n\n") + ";\n"
+}
+
+// MigrationTask 迁移 执行 任务，按顺序 执行，默认 出错 停止
+type MigrationTask struct {
+	TaskId          string   `json:"taskId"`
+	MigrationId     int64    `json:"migrationId,omitempty"`
+	ToolboxId       int64    `json:"toolboxId"`
+	OwnerName       string   `json:"ownerName,omitempty"`
+	IsRollback      bool     `json:"isRollback,omitempty"`
+	ContinueIsError bool     `json:"continueIsError,omitempty"`
+	SqlCount        int      `json:"sqlCount"`
+	SuccessCount    int      `json:"successCount"`
+	ErrorCount      int      `json:"errorCount"`
+	CurrentSql      string   `json:"currentSql,omitempty"`
+	Errors          []string `json:"errors,omitempty"`
+	Error           string   `json:"error,omitempty"`
+	StartTime       int64    `json:"startTime"`
+	EndTime         int64    `json:"endTime,omitempty"`
+	IsEnd           bool     `json:"isEnd"`
+	IsStop          bool     `json:"isStop"`
+
+	userId  int64
+	sqlList []string
+	lock    sync.Mutex
+}
+
+func (this_ *MigrationTask) getInfo() (res *MigrationTask) {
+	this_.lock.Lock()
+	defer this_.lock.Unlock()
+	res = &MigrationTask{
+		TaskId:          this_.TaskId,
+		MigrationId:     this_.MigrationId,
+		ToolboxId:       this_.ToolboxId,
+		OwnerName:       this_.OwnerName,
+		IsRollback:      this_.IsRollback,
+		ContinueIsError: this_.ContinueIsError,
+		SqlCount:        this_.SqlCount,
+		SuccessCount:    this_.SuccessCount,
+		ErrorCount:      this_.ErrorCount,
+		CurrentSql:      this_.CurrentSql,
+		Errors:          append([]string{}, this_.Errors...),
+		Error:           this_.Error,
+		StartTime:       this_.StartTime,
+		EndTime:         this_.EndTime,
+		IsEnd:           this_.IsEnd,
+		IsStop:          this_.IsStop,
+	}
+	return
+}
+
+func (this_ *MigrationTask) stop() {
+	this_.lock.Lock()
+	defer this_.lock.Unlock()
+	this_.IsStop = true
+}
+
+func (this_ *MigrationTask) isStop() bool {
+	this_.lock.Lock()
+	defer this_.lock.Unlock()
+	return this_.IsStop
+}
+
+// run 使用 独立连接 切换 库 后 依次 执行，DDL 多数 数据库 不支持 事务，出错 后 需要 根据 进度 手动 处理
+func (this_ *MigrationTask) run(sqlDb *sql.DB, databaseType string, onEnd func(task *MigrationTask)) {
+	defer func() {
+		if e := recover(); e != nil {
+			util.Logger.Error("migration task panic", zap.Any("taskId", this_.TaskId), zap.Any("error", e))
+			this_.lock.Lock()
+			this_.Error = util.GetStringValue(e)
+			this_.lock.Unlock()
+		}
+		this_.lock.Lock()
+		this_.CurrentSql = ""
+		this_.EndTime = util.GetNowMilli()
+		this_.IsEnd = true
+		this_.lock.Unlock()
+		if onEnd != nil {
+			onEnd(this_)
+		}
+	}()
+
+	ctx := context.Background()
+	conn, err := sqlDb.Conn(ctx)
+	if err != nil {
+		this_.lock.Lock()
+		this_.Error = err.Error()
+		this_.lock.Unlock()
+		return
+	}
+	defer func() { _ = conn.Close() }()
+
+	if useSql := getUseOwnerSql(dbplan.GetDialect(databaseType), this_.OwnerName); useSql != "" {
+		_, err = conn.ExecContext(ctx, useSql)
+		if err != nil {
+			this_.lock.Lock()
+			this_.Error = err.Error()
+			this_.lock.Unlock()
+			return
+		}
+	}
+
+	for _, one := range this_.sqlList {
+		if this_.isStop() {
+			this_.lock.Lock()
+			this_.Error = "任务已停止"
+			this_.lock.Unlock()
+			return
+		}
+		this_.lock.Lock()
+		this_.CurrentSql = one
+		this_.lock.Unlock()
+
+		_, err = conn.ExecContext(ctx, one)
+
+		this_.lock.Lock()
+		if err != nil {
+			this_.ErrorCount++
+			this_.Errors = append(this_.Errors, one+"\n"+err.Error())
+			if !this_.ContinueIsError {
+				this_.Error = err.Error()
+				this_.lock.Unlock()
+				return
+			}
+		} else {
+			this_.SuccessCount++
+		}
+		this_.lock.Unlock()
+	}
+}
+
+var migrationTaskCache = map[string]*MigrationTask{}
+var migrationTaskCacheLock = &sync.Mutex{}
+
+// StartMigrationTask 异步 执行 迁移 脚本
+func StartMigrationTask(sqlDb *sql.DB, databaseType string, task *MigrationTask, script string, onEnd func(task *MigrationTask)) (res *MigrationTask, err error) {
+	task.sqlList = splitSqlStatements(script)
+	if len(task.sqlList) == 0 {
+		err = errors.New("迁移脚本为空")
+		return
+	}
+	task.TaskId = util.GetUUID()
+	task.SqlCount = len(task.sqlList)
+	task.StartTime = util.GetNowMilli()
+
+	migrationTaskCacheLock.Lock()
+	migrationTaskCache[task.TaskId] = task
+	migrationTaskCacheLock.Unlock()
+
+	go task.run(sqlDb, databaseType, onEnd)
+	res = task.getInfo()
+	return
+}
+
+func getMigrationTask(taskId string, userId int64) (task *MigrationTask) {
+	migrationTaskCacheLock.Lock()
+	defer migrationTaskCacheLock.Unlock()
+	task = migrationTaskCache[taskId]
+	if task != nil && task.userId != userId {
+		task = nil
+	}
+	return
+}
+
+// removeMigrationTask 只 移除 已结束 的 任务
+func removeMigrationTask(taskId string, userId int64) {
+	migrationTaskCacheLock.Lock()
+	defer migrationTaskCacheLock.Unlock()
+	task := migrationTaskCache[taskId]
+	if task == nil || task.userId != userId {
+		return
+	}
+	task.lock.Lock()
+	isEnd := task.IsEnd
+	task.lock.Unlock()
+	if isEnd {
+		delete(migrationTaskCache, taskId)
+	}
+}
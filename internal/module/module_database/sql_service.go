@@ -0,0 +1,393 @@
+package module_database
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/team-ide/go-dialect/worker"
+	"github.com/team-ide/go-tool/db"
+	"go.uber.org/zap"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"teamide/internal/context"
+	"teamide/internal/module/module_id"
+	"teamide/internal/module/module_log"
+	"teamide/internal/module/module_power"
+	"teamide/internal/module/module_setting"
+	"teamide/internal/module/module_toolbox"
+	"teamide/pkg/base"
+	"time"
+)
+
+// NewSqlService 根据库配置创建SqlService
+func NewSqlService(ServerContext *context.ServerContext, toolboxService *module_toolbox.ToolboxService) (res *SqlService) {
+
+	idService := module_id.NewIDService(ServerContext)
+
+	res = &SqlService{
+		ServerContext:    ServerContext,
+		idService:        idService,
+		toolboxService:   toolboxService,
+		powerUserService: module_power.NewPowerUserService(ServerContext),
+		logService:       module_log.NewLogService(ServerContext),
+		settingService:   module_setting.NewSettingService(ServerContext),
+	}
+	return
+}
+
+// SqlService SQL执行历史、保存的查询 及 片段
+type SqlService struct {
+	*context.ServerContext
+	idService        *module_id.IDService
+	toolboxService   *module_toolbox.ToolboxService
+	powerUserService *module_power.PowerUserService
+	logService       *module_log.LogService
+	settingService   *module_setting.SettingService
+	maskSecret       []byte
+	maskSecretLock   sync.Mutex
+}
+
+// SaveHistory 记录 执行历史
+func (this_ *SqlService) SaveHistory(history *SqlHistoryModel) (err error) {
+	history.SqlHistoryId, err = this_.idService.GetNextID(module_id.IDTypeDatabaseSqlHistory)
+	if err != nil {
+		return
+	}
+	if history.CreateTime.IsZero() {
+		history.CreateTime = time.Now()
+	}
+	sql := `INSERT INTO ` + TableDatabaseSqlHistory + `(sqlHistoryId, toolboxId, ownerName, userId, executeSql, duration, rowCount, error, createTime) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?) `
+	_, err = this_.DatabaseWorker.Exec(sql, []interface{}{
+		history.SqlHistoryId,
+		history.ToolboxId,
+		history.OwnerName,
+		history.UserId,
+		history.ExecuteSql,
+		history.Duration,
+		history.RowCount,
+		history.Error,
+		history.CreateTime,
+	})
+	if err != nil {
+		this_.Logger.Error("SaveHistory Error", zap.Error(err))
+		return
+	}
+	return
+}
+
+type SqlHistoryQuery struct {
+	ToolboxId int64     `json:"toolboxId,omitempty"`
+	OwnerName string    `json:"ownerName,omitempty"`
+	UserId    int64     `json:"userId,omitempty"`
+	Keyword   string    `json:"keyword,omitempty"` // 匹配 SQL
+	OnlyError bool      `json:"onlyError,omitempty"`
+	StartTime time.Time `json:"-"`
+	EndTime   time.Time `json:"-"`
+}
+
+type SqlHistoryPage struct {
+	*worker.Page
+	DataList []*SqlHistoryModel `json:"dataList"`
+}
+
+func (this_ *SqlService) appendHistoryWhere(query *SqlHistoryQuery, sql string, values []interface{}) (string, []interface{}) {
+	if query.UserId != 0 {
+		sql += " AND userId=?"
+		values = append(values, query.UserId)
+	}
+	if query.ToolboxId != 0 {
+		sql += " AND toolboxId=?"
+		values = append(values, query.ToolboxId)
+	}
+	if query.OwnerName != "" {
+		sql += " AND ownerName=?"
+		values = append(values, query.OwnerName)
+	}
+	if query.Keyword != "" {
+		sql += " AND executeSql LIKE ?"
+		values = append(values, fmt.Sprint("%", query.Keyword, "%"))
+	}
+	if query.OnlyError {
+		sql += " AND error IS NOT NULL AND error<>''"
+	}
+	if !query.StartTime.IsZero() {
+		sql += " AND createTime>=?"
+		values = append(values, query.StartTime)
+	}
+	if !query.EndTime.IsZero() {
+		sql += " AND createTime<=?"
+		values = append(values, query.EndTime)
+	}
+	return sql, values
+}
+
+// QueryHistoryPage 分页 搜索 执行历史，最新的 在前
+func (this_ *SqlService) QueryHistoryPage(query *SqlHistoryQuery, page *SqlHistoryPage) (err error) {
+	sql, values := this_.appendHistoryWhere(query, "SELECT * FROM "+TableDatabaseSqlHistory+" WHERE 1=1", nil)
+	sql += " ORDER BY createTime DESC"
+	page.DataList = []*SqlHistoryModel{}
+	err = this_.DatabaseWorker.QueryPage(sql, values, &page.DataList, page.Page)
+	if err != nil {
+		this_.Logger.Error("QueryHistoryPage Error", zap.Error(err))
+		return
+	}
+	return
+}
+
+// DeleteHistory 删除 自己的 执行历史
+func (this_ *SqlService) DeleteHistory(userId int64, sqlHistoryId int64) (err error) {
+	sql := `DELETE FROM ` + TableDatabaseSqlHistory + ` WHERE sqlHistoryId=? AND userId=? `
+	_, err = this_.DatabaseWorker.Exec(sql, []interface{}{sqlHistoryId, userId})
+	if err != nil {
+		this_.Logger.Error("DeleteHistory Error", zap.Error(err))
+		return
+	}
+	return
+}
+
+// CleanHistory 清理 执行历史，必须 指定 用户
+func (this_ *SqlService) CleanHistory(query *SqlHistoryQuery) (err error) {
+	if query.UserId == 0 {
+		err = errors.New("清理执行历史需要指定用户")
+		return
+	}
+	sql, values := this_.appendHistoryWhere(query, "DELETE FROM "+TableDatabaseSqlHistory+" WHERE 1=1", nil)
+	_, err = this_.DatabaseWorker.Exec(sql, values)
+	if err != nil {
+		this_.Logger.Error("CleanHistory Error", zap.Error(err))
+		return
+	}
+	return
+}
+
+// GetSaved 查询单个
+func (this_ *SqlService) GetSaved(sqlSavedId int64) (res *SqlSavedModel, err error) {
+	res = &SqlSavedModel{}
+
+	sql := `SELECT * FROM ` + TableDatabaseSqlSaved + ` WHERE sqlSavedId=? `
+	find, err := this_.DatabaseWorker.QueryOne(sql, []interface{}{sqlSavedId}, res)
+	if err != nil {
+		this_.Logger.Error("GetSaved Error", zap.Error(err))
+		return
+	}
+
+	if !find {
+		res = nil
+	}
+	return
+}
+
+// getToolboxGroupId 工具箱 所在分组，共享 按 分组 匹配
+func (this_ *SqlService) getToolboxGroupId(toolboxId int64) (groupId int64, err error) {
+	if toolboxId == 0 {
+		return
+	}
+	toolbox, err := this_.toolboxService.Get(toolboxId)
+	if err != nil {
+		return
+	}
+	if toolbox != nil {
+		groupId = toolbox.GroupId
+	}
+	return
+}
+
+// GetVisibleGroupIds 可以 看到 共享 的 分组：自己的 分组，及 有 权限 的 工具箱 所在 分组
+func (this_ *SqlService) GetVisibleGroupIds(requestBean *base.RequestBean, toolboxId int64) (groupIds []int64, err error) {
+	groups, err := this_.toolboxService.QueryGroup(&module_toolbox.ToolboxGroupModel{UserId: requestBean.JWT.UserId})
+	if err != nil {
+		return
+	}
+	for _, one := range groups {
+		groupIds = append(groupIds, one.GroupId)
+	}
+	if toolboxId == 0 {
+		return
+	}
+	toolbox, err := this_.toolboxService.Get(toolboxId)
+	if err != nil {
+		return
+	}
+	if toolbox == nil || toolbox.GroupId == 0 || this_.toolboxService.CheckToolboxPower(requestBean, toolbox) != nil {
+		return
+	}
+	groupIds = append(groupIds, toolbox.GroupId)
+	return
+}
+
+// IsSavedVisible 自己的，或 共享 到 可见 分组 的
+func IsSavedVisible(saved *SqlSavedModel, userId int64, groupIds []int64) bool {
+	if saved.UserId == userId {
+		return true
+	}
+	if saved.Visibility != SqlSavedVisibilityGroup || saved.GroupId == 0 {
+		return false
+	}
+	for _, groupId := range groupIds {
+		if groupId == saved.GroupId {
+			return true
+		}
+	}
+	return false
+}
+
+// QuerySaved 查询 可用的 保存的 查询 及 片段：自己的，及 共享 到 groupIds 分组 的
+func (this_ *SqlService) QuerySaved(userId int64, toolboxId int64, savedType int, groupIds []int64) (res []*SqlSavedModel, err error) {
+	groupId, err := this_.getToolboxGroupId(toolboxId)
+	if err != nil {
+		return
+	}
+	var values []interface{}
+	sql := `SELECT * FROM ` + TableDatabaseSqlSaved + ` WHERE 1=1 `
+	if savedType != 0 {
+		sql += " AND savedType=?"
+		values = append(values, savedType)
+	}
+	if toolboxId != 0 {
+		sql += " AND (toolboxId=? OR toolboxId=0 OR (groupId<>0 AND groupId=?))"
+		values = append(values, toolboxId, groupId)
+	}
+	if len(groupIds) > 0 {
+		sql += " AND (userId=? OR (visibility=? AND groupId IN (?" + strings.Repeat(",?", len(groupIds)-1) + ")))"
+		values = append(values, userId, SqlSavedVisibilityGroup)
+		for _, one := range groupIds {
+			values = append(values, one)
+		}
+	} else {
+		sql += " AND userId=?"
+		values = append(values, userId)
+	}
+	sql += " ORDER BY name ASC "
+
+	err = this_.DatabaseWorker.Query(sql, values, &res)
+	if err != nil {
+		this_.Logger.Error("QuerySaved Error", zap.Error(err))
+		return
+	}
+	return
+}
+
+// SaveSaved 新增或更新，只能 修改 自己的
+func (this_ *SqlService) SaveSaved(userId int64, saved *SqlSavedModel) (err error) {
+	if saved.Name == "" {
+		err = errors.New("名称不能为空")
+		return
+	}
+	if saved.SavedType != SqlSavedTypeQuery && saved.SavedType != SqlSavedTypeSnippet {
+		saved.SavedType = SqlSavedTypeQuery
+	}
+	if saved.Visibility != SqlSavedVisibilityGroup {
+		saved.Visibility = SqlSavedVisibilitySelf
+	}
+	if saved.ScriptVars != "" {
+		var scriptVars []*db.ScriptVar
+		if e := json.Unmarshal([]byte(saved.ScriptVars), &scriptVars); e != nil {
+			err = errors.New("脚本变量格式错误:" + e.Error())
+			return
+		}
+	}
+	saved.GroupId, err = this_.getToolboxGroupId(saved.ToolboxId)
+	if err != nil {
+		return
+	}
+
+	if saved.SqlSavedId > 0 {
+		var find *SqlSavedModel
+		find, err = this_.GetSaved(saved.SqlSavedId)
+		if err != nil {
+			return
+		}
+		if find == nil || find.UserId != userId {
+			err = errors.New("保存的SQL不存在")
+			return
+		}
+		sql := `UPDATE ` + TableDatabaseSqlSaved + ` SET savedType=?,toolboxId=?,groupId=?,ownerName=?,name=?,comment=?,executeSql=?,scriptVars=?,visibility=?,updateTime=? WHERE sqlSavedId=? `
+		_, err = this_.DatabaseWorker.Exec(sql, []interface{}{saved.SavedType, saved.ToolboxId, saved.GroupId, saved.OwnerName, saved.Name, saved.Comment, saved.ExecuteSql, saved.ScriptVars, saved.Visibility, time.Now(), saved.SqlSavedId})
+	} else {
+		saved.SqlSavedId, err = this_.idService.GetNextID(module_id.IDTypeDatabaseSqlSaved)
+		if err != nil {
+			return
+		}
+		saved.UserId = userId
+		saved.CreateTime = time.Now()
+		sql := `INSERT INTO ` + TableDatabaseSqlSaved + `(sqlSavedId, savedType, toolboxId, groupId, ownerName, name, comment, executeSql, scriptVars, visibility, userId, createTime) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) `
+		_, err = this_.DatabaseWorker.Exec(sql, []interface{}{saved.SqlSavedId, saved.SavedType, saved.ToolboxId, saved.GroupId, saved.OwnerName, saved.Name, saved.Comment, saved.ExecuteSql, saved.ScriptVars, saved.Visibility, saved.UserId, saved.CreateTime})
+	}
+	if err != nil {
+		this_.Logger.Error("SaveSaved Error", zap.Error(err))
+		return
+	}
+	return
+}
+
+// DeleteSaved 删除 自己的
+func (this_ *SqlService) DeleteSaved(userId int64, sqlSavedId int64) (err error) {
+	sql := `DELETE FROM ` + TableDatabaseSqlSaved + ` WHERE sqlSavedId=? AND userId=? `
+	_, err = this_.DatabaseWorker.Exec(sql, []interface{}{sqlSavedId, userId})
+	if err != nil {
+		this_.Logger.Error("DeleteSaved Error", zap.Error(err))
+		return
+	}
+	return
+}
+
+var (
+	scriptVarRegexp = regexp.MustCompile(`\$\{\s*([\w.\-]+)\s*}`)
+)
+
+// RenderScriptVars 使用 脚本变量 替换 SQL 中的 ${name}，未设置的 报错
+func RenderScriptVars(executeSql string, scriptVars []*db.ScriptVar) (res string, err error) {
+	var values = make(map[string]string)
+	for _, one := range scriptVars {
+		if one != nil {
+			values[one.Name] = one.Value
+		}
+	}
+	res = scriptVarRegexp.ReplaceAllStringFunc(executeSql, func(s string) string {
+		name := scriptVarRegexp.FindStringSubmatch(s)[1]
+		value, ok := values[name]
+		if !ok {
+			if err == nil {
+				err = errors.New("脚本变量[" + name + "]未设置")
+			}
+			return s
+		}
+		return value
+	})
+	return
+}
+
+// countExecuteRows 统计 执行结果 的 查询行数 及 影响行数
+func countExecuteRows(executeList interface{}) (rowCount int64) {
+	list := reflect.ValueOf(executeList)
+	if list.Kind() != reflect.Slice {
+		return
+	}
+	for i := 0; i < list.Len(); i++ {
+		one := reflect.Indirect(list.Index(i))
+		if one.Kind() == reflect.Interface {
+			one = reflect.Indirect(one.Elem())
+		}
+		if one.Kind() != reflect.Map {
+			continue
+		}
+		if dataList := one.MapIndex(reflect.ValueOf("dataList")); dataList.IsValid() {
+			dataList = reflect.Indirect(dataList)
+			if dataList.Kind() == reflect.Interface {
+				dataList = reflect.Indirect(dataList.Elem())
+			}
+			if dataList.Kind() == reflect.Slice {
+				rowCount += int64(dataList.Len())
+				continue
+			}
+		}
+		if rowsAffected := one.MapIndex(reflect.ValueOf("rowsAffected")); rowsAffected.IsValid() {
+			var n int64
+			_, _ = fmt.Sscan(fmt.Sprint(rowsAffected.Interface()), &n)
+			rowCount += n
+		}
+	}
+	return
+}
@@ -30,6 +30,11 @@ func (this_ *api) testStart(requestBean *base.RequestBean, c *gin.Context) (res
 	if !base.RequestJSON(request, c) {
 		return
 	}
+	// 压测 会 反复 执行 SQL，无法 按 单次 审批 放行，生产 环境 直接 拒绝
+	if getRequestSqlGuardConfig(requestBean).Production {
+		err = errors.New("生产环境不支持压测")
+		return
+	}
 
 	data := map[string]interface{}{}
 	res = data
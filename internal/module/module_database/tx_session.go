@@ -0,0 +1,450 @@
+package module_database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/team-ide/go-tool/util"
+	"go.uber.org/zap"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"teamide/pkg/dbplan"
+	"time"
+)
+
+const (
+	// 事务 默认 空闲 超时，超时 自动 回滚
+	txDefaultIdleTimeout = 5 * 60 * 1000
+	txMaxIdleTimeout     = 60 * 60 * 1000
+	txDefaultSelectMax   = 200
+)
+
+// txSession SQL 标签页 绑定的 连接 及 事务，同一个 事务 的 语句 串行 执行
+// lastUseTime、statementCount 原子 读写，查询 状态 不 等待 正在 执行 的 语句
+type txSession struct {
+	lastUseTime    int64 // 原子 读写 的 字段 放在 开头，保证 32 位 平台 对齐
+	statementCount int64
+	workerId       string
+	toolboxId      int64
+	userId         int64
+	ownerName      string
+	conn           *sql.Conn
+	tx             *sql.Tx
+	startTime      int64
+	idleTimeout    int64
+	lock           sync.Mutex
+	cancel         context.CancelFunc // 正在 执行 的 语句，结束 事务 时 取消
+	cancelLock     sync.Mutex
+}
+
+// TxStatus 事务 状态，用于 标签页 显示 未提交 事务
+type TxStatus struct {
+	WorkerId       string `json:"workerId"`
+	ToolboxId      int64  `json:"toolboxId"`
+	OwnerName      string `json:"ownerName,omitempty"`
+	StartTime      int64  `json:"startTime"`
+	LastUseTime    int64  `json:"lastUseTime"`
+	IdleTimeout    int64  `json:"idleTimeout"` // 毫秒
+	StatementCount int64  `json:"statementCount"`
+}
+
+func (this_ *txSession) getStatus() *TxStatus {
+	return &TxStatus{
+		WorkerId:       this_.workerId,
+		ToolboxId:      this_.toolboxId,
+		OwnerName:      this_.ownerName,
+		StartTime:      this_.startTime,
+		LastUseTime:    atomic.LoadInt64(&this_.lastUseTime),
+		IdleTimeout:    this_.idleTimeout,
+		StatementCount: atomic.LoadInt64(&this_.statementCount),
+	}
+}
+
+func (this_ *txSession) setCancel(cancel context.CancelFunc) {
+	this_.cancelLock.Lock()
+	defer this_.cancelLock.Unlock()
+	this_.cancel = cancel
+}
+
+// cancelStatement 取消 正在 执行 的 语句
+func (this_ *txSession) cancelStatement() {
+	this_.cancelLock.Lock()
+	defer this_.cancelLock.Unlock()
+	if this_.cancel != nil {
+		this_.cancel()
+	}
+}
+
+// end 取消 正在 执行 的 语句 后 提交 或 回滚，并 释放 连接
+func (this_ *txSession) end(commit bool) (err error) {
+	this_.cancelStatement()
+	this_.lock.Lock()
+	defer this_.lock.Unlock()
+
+	err = this_.endLocked(commit)
+	return
+}
+
+func (this_ *txSession) endLocked(commit bool) (err error) {
+	if this_.tx == nil {
+		return
+	}
+	if commit {
+		err = this_.tx.Commit()
+	} else {
+		err = this_.tx.Rollback()
+	}
+	this_.tx = nil
+	_ = this_.conn.Close()
+	return
+}
+
+var txSessionCache = map[string]*txSession{}
+var txSessionCacheLock = &sync.Mutex{}
+var txSessionCheckOnce = &sync.Once{}
+
+func getTxSession(workerId string) *txSession {
+	txSessionCacheLock.Lock()
+	defer txSessionCacheLock.Unlock()
+	return txSessionCache[workerId]
+}
+
+func removeTxSession(workerId string) (session *txSession) {
+	txSessionCacheLock.Lock()
+	defer txSessionCacheLock.Unlock()
+	session = txSessionCache[workerId]
+	delete(txSessionCache, workerId)
+	return
+}
+
+// BeginTx 标签页 开启 事务，一个 标签页 同时 只能 有一个 事务
+func BeginTx(sqlDb *sql.DB, databaseType string, workerId string, toolboxId int64, userId int64, ownerName string, idleTimeout int64) (status *TxStatus, err error) {
+	if workerId == "" {
+		err = errors.New("workerId不能为空")
+		return
+	}
+	if getTxSession(workerId) != nil {
+		err = errors.New("当前标签页已开启事务，请先提交或回滚")
+		return
+	}
+	if idleTimeout <= 0 {
+		idleTimeout = txDefaultIdleTimeout
+	}
+	if idleTimeout > txMaxIdleTimeout {
+		idleTimeout = txMaxIdleTimeout
+	}
+
+	ctx := context.Background()
+	conn, err := sqlDb.Conn(ctx)
+	if err != nil {
+		return
+	}
+	if useSql := getUseOwnerSql(dbplan.GetDialect(databaseType), ownerName); useSql != "" {
+		_, err = conn.ExecContext(ctx, useSql)
+		if err != nil {
+			_ = conn.Close()
+			return
+		}
+	}
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		_ = conn.Close()
+		return
+	}
+	now := util.GetNowMilli()
+	session := &txSession{
+		workerId:    workerId,
+		toolboxId:   toolboxId,
+		userId:      userId,
+		ownerName:   ownerName,
+		conn:        conn,
+		tx:          tx,
+		startTime:   now,
+		lastUseTime: now,
+		idleTimeout: idleTimeout,
+	}
+
+	txSessionCacheLock.Lock()
+	if txSessionCache[workerId] != nil {
+		txSessionCacheLock.Unlock()
+		_ = tx.Rollback()
+		_ = conn.Close()
+		err = errors.New("当前标签页已开启事务，请先提交或回滚")
+		return
+	}
+	txSessionCache[workerId] = session
+	txSessionCacheLock.Unlock()
+
+	txSessionCheckOnce.Do(func() {
+		go checkTxSessionIdle()
+	})
+	status = session.getStatus()
+	return
+}
+
+// EndTx 提交 或 回滚 标签页 的 事务
+func EndTx(workerId string, userId int64, commit bool) (err error) {
+	session := getTxSession(workerId)
+	if session == nil || session.userId != userId {
+		err = errors.New("当前标签页没有开启的事务")
+		return
+	}
+	removeTxSession(workerId)
+	err = session.end(commit)
+	return
+}
+
+// checkTarget 事务 只能 在 开启 时 的 工具箱 及 库 上 执行，避免 切换 工具箱 绕过 拦截、脱敏
+func (this_ *txSession) checkTarget(toolboxId int64, ownerName string) (err error) {
+	if this_.toolboxId != toolboxId || this_.ownerName != ownerName {
+		err = errors.New("当前标签页的事务不是在该库上开启的，请先提交或回滚事务")
+	}
+	return
+}
+
+// CheckTxStatus 标签页 开启 了 事务 则 校验 工具箱 及 库 与 开启 时 一致，没有 开启 事务 返回 nil
+func CheckTxStatus(workerId string, userId int64, toolboxId int64, ownerName string) (status *TxStatus, err error) {
+	session := getTxSession(workerId)
+	if session == nil || session.userId != userId {
+		return
+	}
+	if err = session.checkTarget(toolboxId, ownerName); err != nil {
+		return
+	}
+	status = session.getStatus()
+	return
+}
+
+// GetTxStatus 标签页 事务 状态，没有 开启 事务 返回 nil
+func GetTxStatus(workerId string, userId int64) (status *TxStatus) {
+	session := getTxSession(workerId)
+	if session == nil || session.userId != userId {
+		return
+	}
+	status = session.getStatus()
+	return
+}
+
+// QueryTxStatus 用户 所有 未结束的 事务
+func QueryTxStatus(userId int64) (list []*TxStatus) {
+	txSessionCacheLock.Lock()
+	var sessions []*txSession
+	for _, one := range txSessionCache {
+		if one.userId == userId {
+			sessions = append(sessions, one)
+		}
+	}
+	txSessionCacheLock.Unlock()
+
+	list = []*TxStatus{}
+	for _, one := range sessions {
+		list = append(list, one.getStatus())
+	}
+	return
+}
+
+// rollbackWorkerTx 标签页 关闭 时 回滚 未提交 的 事务
+func rollbackWorkerTx(workerId string) {
+	session := removeTxSession(workerId)
+	if session == nil {
+		return
+	}
+	err := session.end(false)
+	if err != nil {
+		util.Logger.Error("worker close rollback tx error", zap.Any("workerId", workerId), zap.Error(err))
+	}
+}
+
+func checkTxSessionIdle() {
+	for {
+		time.Sleep(10 * time.Second)
+
+		txSessionCacheLock.Lock()
+		var sessions []*txSession
+		for _, one := range txSessionCache {
+			sessions = append(sessions, one)
+		}
+		txSessionCacheLock.Unlock()
+
+		for _, one := range sessions {
+			rollbackIdleTxSession(one)
+		}
+	}
+}
+
+// rollbackIdleTxSession 空闲 超时 回滚，正在 执行 语句 的 不算 空闲
+func rollbackIdleTxSession(session *txSession) {
+	if util.GetNowMilli()-atomic.LoadInt64(&session.lastUseTime) <= session.idleTimeout {
+		return
+	}
+	if !session.lock.TryLock() {
+		return
+	}
+	defer session.lock.Unlock()
+	// 获取 锁 前 可能 刚 执行 完 语句
+	if util.GetNowMilli()-atomic.LoadInt64(&session.lastUseTime) <= session.idleTimeout {
+		return
+	}
+
+	txSessionCacheLock.Lock()
+	if txSessionCache[session.workerId] == session {
+		delete(txSessionCache, session.workerId)
+	}
+	txSessionCacheLock.Unlock()
+
+	err := session.endLocked(false)
+	util.Logger.Warn("tx idle timeout rollback", zap.Any("workerId", session.workerId), zap.Any("toolboxId", session.toolboxId), zap.Error(err))
+}
+
+// executeInTx 在 事务 中 依次 执行 语句，出错 后 停止，事务 保持 打开 由 用户 决定 回滚
+func executeInTx(workerId string, userId int64, toolboxId int64, ownerName string, executeSql string, selectDataMax int) (executeList []map[string]interface{}, errStr string, err error) {
+	session := getTxSession(workerId)
+	if session == nil || session.userId != userId {
+		err = errors.New("当前标签页没有开启的事务")
+		return
+	}
+	if err = session.checkTarget(toolboxId, ownerName); err != nil {
+		return
+	}
+	session.lock.Lock()
+	defer session.lock.Unlock()
+	if session.tx == nil {
+		err = errors.New("事务已结束")
+		return
+	}
+	if selectDataMax <= 0 {
+		selectDataMax = txDefaultSelectMax
+	}
+	defer func() {
+		atomic.StoreInt64(&session.lastUseTime, util.GetNowMilli())
+	}()
+
+	// 语句 执行 超时 与 空闲 超时 相同，避免 一直 占用 事务
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(session.idleTimeout)*time.Millisecond)
+	session.setCancel(cancel)
+	defer func() {
+		session.setCancel(nil)
+		cancel()
+	}()
+	for _, one := range splitSqlStatements(executeSql) {
+		atomic.AddInt64(&session.statementCount, 1)
+		execute := map[string]interface{}{
+			"sql":       one,
+			"startTime": util.GetNowMilli(),
+		}
+		executeList = append(executeList, execute)
+
+		if isQueryStatement(one) {
+			var rows *sql.Rows
+			rows, err = session.tx.QueryContext(ctx, one)
+			if err == nil {
+				var columns []string
+				var dataList []map[string]interface{}
+				columns, dataList, err = scanRows(rows, selectDataMax)
+				_ = rows.Close()
+				execute["isSelect"] = true
+				execute["columnList"] = columns
+				execute["dataList"] = dataList
+			}
+		} else {
+			var result sql.Result
+			result, err = session.tx.ExecContext(ctx, one)
+			if err == nil {
+				execute["rowsAffected"], _ = result.RowsAffected()
+			}
+		}
+		execute["endTime"] = util.GetNowMilli()
+		if err != nil {
+			errStr = err.Error()
+			execute["error"] = errStr
+			err = nil
+			return
+		}
+	}
+	return
+}
+
+var (
+	queryStatementPrefixes = []string{"SELECT", "SHOW", "WITH", "DESC", "DESCRIBE", "EXPLAIN", "PRAGMA", "VALUES"}
+)
+
+func isQueryStatement(statement string) bool {
+	fields := strings.Fields(strings.ToUpper(statement))
+	if len(fields) == 0 {
+		return false
+	}
+	for _, one := range queryStatementPrefixes {
+		if fields[0] == one || strings.HasPrefix(fields[0], one+"(") {
+			return true
+		}
+	}
+	return false
+}
+
+// splitSqlStatements 按 分号 拆分 语句，忽略 引号、注释 中的 分号
+func splitSqlStatements(text string) (statements []string) {
+	var builder strings.Builder
+	var quote rune
+	var lineComment, blockComment bool
+	runes := []rune(text)
+	appendStatement := func() {
+		statement := strings.TrimSpace(builder.String())
+		if statement != "" {
+			statements = append(statements, statement)
+		}
+		builder.Reset()
+	}
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		var next rune
+		if i+1 < len(runes) {
+			next = runes[i+1]
+		}
+		switch {
+		case lineComment:
+			if c == '\n' {
+				lineComment = false
+				builder.WriteRune(c)
+			}
+			continue
+		case blockComment:
+			if c == '*' && next == '/' {
+				blockComment = false
+				i++
+			}
+			continue
+		case quote != 0:
+			builder.WriteRune(c)
+			if c == quote {
+				// 连续 两个 引号 为 转义
+				if next == quote {
+					builder.WriteRune(next)
+					i++
+				} else {
+					quote = 0
+				}
+			} else if c == '\\' && quote != '`' && next != 0 {
+				builder.WriteRune(next)
+				i++
+			}
+			continue
+		}
+		switch {
+		case c == '-' && next == '-':
+			lineComment = true
+		case c == '/' && next == '*':
+			blockComment = true
+			i++
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+			builder.WriteRune(c)
+		case c == ';':
+			appendStatement()
+		default:
+			builder.WriteRune(c)
+		}
+	}
+	appendStatement()
+	return
+}
@@ -45,6 +45,35 @@ var (
 	taskStopPower    = base.AppendPower(&base.PowerAction{Action: "taskStop", Text: "ES任务停止", ShouldLogin: true, StandAlone: true, Parent: Power})
 	taskCleanPower   = base.AppendPower(&base.PowerAction{Action: "taskClean", Text: "ES任务清理", ShouldLogin: true, StandAlone: true, Parent: Power})
 	closePower       = base.AppendPower(&base.PowerAction{Action: "close", Text: "ES关闭", ShouldLogin: true, StandAlone: true, Parent: Power})
+
+	sqlPower          = base.AppendPower(&base.PowerAction{Action: "sql", Text: "ES SQL查询", ShouldLogin: true, StandAlone: true, Parent: Power})
+	sqlClosePower     = base.AppendPower(&base.PowerAction{Action: "sqlClose", Text: "ES SQL游标关闭", ShouldLogin: true, StandAlone: true, Parent: Power})
+	sqlTranslatePower = base.AppendPower(&base.PowerAction{Action: "sqlTranslate", Text: "ES SQL转换", ShouldLogin: true, StandAlone: true, Parent: Power})
+
+	templatesPower      = base.AppendPower(&base.PowerAction{Action: "templates", Text: "ES模板查询", ShouldLogin: true, StandAlone: true, Parent: Power})
+	templateGetPower    = base.AppendPower(&base.PowerAction{Action: "templateGet", Text: "ES模板详情", ShouldLogin: true, StandAlone: true, Parent: Power})
+	templateSavePower   = base.AppendPower(&base.PowerAction{Action: "templateSave", Text: "ES模板保存", ShouldLogin: true, StandAlone: true, Parent: Power})
+	templateDeletePower = base.AppendPower(&base.PowerAction{Action: "templateDelete", Text: "ES模板删除", ShouldLogin: true, StandAlone: true, Parent: Power})
+
+	ilmPoliciesPower     = base.AppendPower(&base.PowerAction{Action: "ilmPolicies", Text: "ES生命周期策略查询", ShouldLogin: true, StandAlone: true, Parent: Power})
+	ilmPolicySavePower   = base.AppendPower(&base.PowerAction{Action: "ilmPolicySave", Text: "ES生命周期策略保存", ShouldLogin: true, StandAlone: true, Parent: Power})
+	ilmPolicyDeletePower = base.AppendPower(&base.PowerAction{Action: "ilmPolicyDelete", Text: "ES生命周期策略删除", ShouldLogin: true, StandAlone: true, Parent: Power})
+	ilmAttachPower       = base.AppendPower(&base.PowerAction{Action: "ilmAttach", Text: "ES索引指定生命周期策略", ShouldLogin: true, StandAlone: true, Parent: Power})
+	ilmDetachPower       = base.AppendPower(&base.PowerAction{Action: "ilmDetach", Text: "ES索引移除生命周期策略", ShouldLogin: true, StandAlone: true, Parent: Power})
+	ilmExplainPower      = base.AppendPower(&base.PowerAction{Action: "ilmExplain", Text: "ES索引生命周期状态", ShouldLogin: true, StandAlone: true, Parent: Power})
+	ilmRetryPower        = base.AppendPower(&base.PowerAction{Action: "ilmRetry", Text: "ES索引生命周期重试", ShouldLogin: true, StandAlone: true, Parent: Power})
+
+	repositoriesPower     = base.AppendPower(&base.PowerAction{Action: "repositories", Text: "ES快照仓库查询", ShouldLogin: true, StandAlone: true, Parent: Power})
+	repositorySavePower   = base.AppendPower(&base.PowerAction{Action: "repositorySave", Text: "ES快照仓库保存", ShouldLogin: true, StandAlone: true, Parent: Power})
+	repositoryDeletePower = base.AppendPower(&base.PowerAction{Action: "repositoryDelete", Text: "ES快照仓库删除", ShouldLogin: true, StandAlone: true, Parent: Power})
+	repositoryVerifyPower = base.AppendPower(&base.PowerAction{Action: "repositoryVerify", Text: "ES快照仓库校验", ShouldLogin: true, StandAlone: true, Parent: Power})
+	snapshotsPower        = base.AppendPower(&base.PowerAction{Action: "snapshots", Text: "ES快照查询", ShouldLogin: true, StandAlone: true, Parent: Power})
+	snapshotCreatePower   = base.AppendPower(&base.PowerAction{Action: "snapshotCreate", Text: "ES快照创建", ShouldLogin: true, StandAlone: true, Parent: Power})
+	snapshotRestorePower  = base.AppendPower(&base.PowerAction{Action: "snapshotRestore", Text: "ES快照恢复", ShouldLogin: true, StandAlone: true, Parent: Power})
+	snapshotDeletePower   = base.AppendPower(&base.PowerAction{Action: "snapshotDelete", Text: "ES快照删除", ShouldLogin: true, StandAlone: true, Parent: Power})
+
+	clusterHealthPower     = base.AppendPower(&base.PowerAction{Action: "clusterHealth", Text: "ES集群健康", ShouldLogin: true, StandAlone: true, Parent: Power})
+	allocationExplainPower = base.AppendPower(&base.PowerAction{Action: "allocationExplain", Text: "ES分片分配说明", ShouldLogin: true, StandAlone: true, Parent: Power})
 )
 
 func (this_ *api) GetApis() (apis []*base.ApiWorker) {
@@ -72,6 +101,35 @@ func (this_ *api) GetApis() (apis []*base.ApiWorker) {
 	apis = append(apis, &base.ApiWorker{Power: taskCleanPower, Do: this_.taskClean})
 	apis = append(apis, &base.ApiWorker{Power: closePower, Do: this_.close})
 
+	apis = append(apis, &base.ApiWorker{Power: sqlPower, Do: this_.sql})
+	apis = append(apis, &base.ApiWorker{Power: sqlClosePower, Do: this_.sqlClose, NotRecodeLog: true})
+	apis = append(apis, &base.ApiWorker{Power: sqlTranslatePower, Do: this_.sqlTranslate})
+
+	apis = append(apis, &base.ApiWorker{Power: templatesPower, Do: this_.templates})
+	apis = append(apis, &base.ApiWorker{Power: templateGetPower, Do: this_.templateGet})
+	apis = append(apis, &base.ApiWorker{Power: templateSavePower, Do: this_.templateSave})
+	apis = append(apis, &base.ApiWorker{Power: templateDeletePower, Do: this_.templateDelete})
+
+	apis = append(apis, &base.ApiWorker{Power: ilmPoliciesPower, Do: this_.ilmPolicies})
+	apis = append(apis, &base.ApiWorker{Power: ilmPolicySavePower, Do: this_.ilmPolicySave})
+	apis = append(apis, &base.ApiWorker{Power: ilmPolicyDeletePower, Do: this_.ilmPolicyDelete})
+	apis = append(apis, &base.ApiWorker{Power: ilmAttachPower, Do: this_.ilmAttach})
+	apis = append(apis, &base.ApiWorker{Power: ilmDetachPower, Do: this_.ilmDetach})
+	apis = append(apis, &base.ApiWorker{Power: ilmExplainPower, Do: this_.ilmExplain, NotRecodeLog: true})
+	apis = append(apis, &base.ApiWorker{Power: ilmRetryPower, Do: this_.ilmRetry})
+
+	apis = append(apis, &base.ApiWorker{Power: repositoriesPower, Do: this_.repositories})
+	apis = append(apis, &base.ApiWorker{Power: repositorySavePower, Do: this_.repositorySave, NotRecodeLog: true})
+	apis = append(apis, &base.ApiWorker{Power: repositoryDeletePower, Do: this_.repositoryDelete})
+	apis = append(apis, &base.ApiWorker{Power: repositoryVerifyPower, Do: this_.repositoryVerify})
+	apis = append(apis, &base.ApiWorker{Power: snapshotsPower, Do: this_.snapshots})
+	apis = append(apis, &base.ApiWorker{Power: snapshotCreatePower, Do: this_.snapshotCreate})
+	apis = append(apis, &base.ApiWorker{Power: snapshotRestorePower, Do: this_.snapshotRestore})
+	apis = append(apis, &base.ApiWorker{Power: snapshotDeletePower, Do: this_.snapshotDelete})
+
+	apis = append(apis, &base.ApiWorker{Power: clusterHealthPower, Do: this_.clusterHealth, NotRecodeLog: true})
+	apis = append(apis, &base.ApiWorker{Power: allocationExplainPower, Do: this_.allocationExplain, NotRecodeLog: true})
+
 	return
 }
 
@@ -540,6 +598,13 @@ func (this_ *api) taskStatus(requestBean *base.RequestBean, c *gin.Context) (res
 		return
 	}
 
+	if task := getSnapshotTask(request.TaskId, requestBean.JWT.UserId); task != nil {
+		res = task.getInfo()
+		return
+	}
+	if isOtherUserSnapshotTask(request.TaskId, requestBean.JWT.UserId) {
+		return
+	}
 	res = elasticsearch.GetTask(request.TaskId)
 	return
 }
@@ -551,6 +616,13 @@ func (this_ *api) taskStop(requestBean *base.RequestBean, c *gin.Context) (res i
 		return
 	}
 
+	if task := getSnapshotTask(request.TaskId, requestBean.JWT.UserId); task != nil {
+		err = stopSnapshotTask(task)
+		return
+	}
+	if isOtherUserSnapshotTask(request.TaskId, requestBean.JWT.UserId) {
+		return
+	}
 	elasticsearch.StopTask(request.TaskId)
 	return
 }
@@ -562,7 +634,7 @@ func (this_ *api) taskClean(requestBean *base.RequestBean, c *gin.Context) (res
 		return
 	}
 
-	removeWorkerTask(request.WorkerId, request.TaskId)
+	removeWorkerTask(request.WorkerId, request.TaskId, requestBean.JWT.UserId)
 	return
 }
 
@@ -573,7 +645,7 @@ func (this_ *api) taskList(requestBean *base.RequestBean, c *gin.Context) (res i
 		return
 	}
 
-	res = getWorkerTasks(request.WorkerId)
+	res = getWorkerTasks(request.WorkerId, requestBean.JWT.UserId)
 	return
 }
 
@@ -582,7 +654,7 @@ func (this_ *api) close(requestBean *base.RequestBean, c *gin.Context) (res inte
 	if !base.RequestJSON(request, c) {
 		return
 	}
-	removeWorkerTasks(request.WorkerId)
+	removeWorkerTasks(request.WorkerId, requestBean.JWT.UserId)
 	return
 }
 
@@ -602,11 +674,18 @@ func addWorkerTask(workerId string, taskId string) {
 	return
 }
 
-func getWorkerTasks(workerId string) (taskList []*elasticsearch.Task) {
+func getWorkerTasks(workerId string, userId int64) (taskList []interface{}) {
 	workerTasksCacheLock.Lock()
 	defer workerTasksCacheLock.Unlock()
 	taskIds := workerTasksCache[workerId]
 	for _, id := range taskIds {
+		if snapshotTask := getSnapshotTask(id, userId); snapshotTask != nil {
+			taskList = append(taskList, snapshotTask.getInfo())
+			continue
+		}
+		if isOtherUserSnapshotTask(id, userId) {
+			continue
+		}
 		task := elasticsearch.GetTask(id)
 		if task != nil {
 			taskList = append(taskList, task)
@@ -615,22 +694,38 @@ func getWorkerTasks(workerId string) (taskList []*elasticsearch.Task) {
 	return
 }
 
-func removeWorkerTasks(workerId string) {
+// removeWorkerTasks 清理 标签页 的 任务，其他 用户 的 快照 任务 保留
+func removeWorkerTasks(workerId string, userId int64) {
 	workerTasksCacheLock.Lock()
 	defer workerTasksCacheLock.Unlock()
 	taskIds := workerTasksCache[workerId]
+	var newIds []string
 	for _, taskId := range taskIds {
+		if isOtherUserSnapshotTask(taskId, userId) {
+			newIds = append(newIds, taskId)
+			continue
+		}
+		cleanSnapshotTask(taskId)
 		elasticsearch.StopTask(taskId)
 		elasticsearch.CleanTask(taskId)
 	}
-	delete(workerTasksCache, workerId)
+	if len(newIds) == 0 {
+		delete(workerTasksCache, workerId)
+	} else {
+		workerTasksCache[workerId] = newIds
+	}
 	return
 }
 
-func removeWorkerTask(workerId string, taskId string) {
+func removeWorkerTask(workerId string, taskId string, userId int64) {
 	workerTasksCacheLock.Lock()
 	defer workerTasksCacheLock.Unlock()
 
+	if isOtherUserSnapshotTask(taskId, userId) {
+		return
+	}
+
+	cleanSnapshotTask(taskId)
 	elasticsearch.StopTask(taskId)
 	elasticsearch.CleanTask(taskId)
 
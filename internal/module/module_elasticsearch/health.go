@@ -0,0 +1,94 @@
+package module_elasticsearch
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/team-ide/go-tool/util"
+	"go.uber.org/zap"
+	"net/http"
+	"net/url"
+	"teamide/pkg/base"
+	"teamide/pkg/esops"
+)
+
+// maxExplainShards 集群 健康 中 最多 查询 分配 说明 的 未 分配 分片 数
+const maxExplainShards = 20
+
+// clusterHealth 集群 健康、非 green 的 索引、未 启动 的 分片，explain 时 附带 未 分配 分片 的 分配 说明
+func (this_ *api) clusterHealth(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	service, request, err := this_.getOpsService(requestBean, c)
+	if err != nil || service == nil {
+		return
+	}
+	bs, err := perform(service, http.MethodGet, esops.HealthPath, url.Values{"level": {"indices"}}, nil)
+	if err != nil {
+		return
+	}
+	health, err := esops.ParseHealth(bs)
+	if err != nil {
+		return
+	}
+	bs, err = perform(service, http.MethodGet, esops.CatShardsPath, url.Values{"format": {"json"}, "h": {esops.CatShardsColumns}}, nil)
+	if err != nil {
+		return
+	}
+	shards, err := esops.ParseProblemShards(bs)
+	if err != nil {
+		return
+	}
+
+	data := map[string]interface{}{}
+	data["problemIndices"] = health.ProblemIndices()
+	data["problemShards"] = shards
+	health.Indices = nil
+	data["health"] = health
+
+	if request.Explain {
+		var explains []*esops.AllocationExplain
+		for _, shard := range shards {
+			if shard.State != "UNASSIGNED" {
+				continue
+			}
+			if len(explains) >= maxExplainShards {
+				break
+			}
+			// 查询 期间 分片 可能 已 分配，跳过 即可
+			bs, err = perform(service, http.MethodPost, esops.AllocationExplainPath, nil, esops.AllocationExplainBody(shard))
+			if err != nil {
+				util.Logger.Error("elasticsearch allocation explain error", zap.Any("index", shard.Index), zap.Any("shard", shard.Shard), zap.Error(err))
+				err = nil
+				continue
+			}
+			var explain *esops.AllocationExplain
+			explain, err = esops.ParseAllocationExplain(bs)
+			if err != nil {
+				return
+			}
+			explains = append(explains, explain)
+		}
+		data["explains"] = explains
+	}
+	res = data
+	return
+}
+
+// allocationExplain 指定 分片 的 分配 说明，未 指定 索引 时 说明 第一个 未 分配 的 分片
+func (this_ *api) allocationExplain(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	service, request, err := this_.getOpsService(requestBean, c)
+	if err != nil || service == nil {
+		return
+	}
+	var body interface{}
+	if request.IndexName != "" {
+		shard := &esops.ShardInfo{Index: request.IndexName, Shard: request.Shard}
+		if request.Primary {
+			shard.Prirep = "p"
+		}
+		body = esops.AllocationExplainBody(shard)
+	}
+	bs, err := perform(service, http.MethodPost, esops.AllocationExplainPath, nil, body)
+	if err != nil {
+		return
+	}
+	res, err = esops.ParseAllocationExplain(bs)
+	return
+}
@@ -0,0 +1,113 @@
+package module_elasticsearch
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"teamide/pkg/base"
+	"teamide/pkg/esops"
+)
+
+func (this_ *api) ilmPolicies(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	service, _, err := this_.getOpsService(requestBean, c)
+	if err != nil || service == nil {
+		return
+	}
+	res, err = performMap(service, http.MethodGet, esops.IlmPolicyPath, nil, nil)
+	return
+}
+
+// ilmPolicySave 创建 或 覆盖 策略，body 为 policy 的 内容
+func (this_ *api) ilmPolicySave(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	service, request, err := this_.getOpsService(requestBean, c)
+	if err != nil || service == nil {
+		return
+	}
+	if request.Name == "" || request.Body == nil {
+		err = errors.New("策略名称和定义不能为空")
+		return
+	}
+	body := request.Body
+	if _, ok := body["policy"]; !ok {
+		body = map[string]interface{}{"policy": body}
+	}
+	res, err = performMap(service, http.MethodPut, esops.IlmPolicyNamePath(request.Name), nil, body)
+	return
+}
+
+func (this_ *api) ilmPolicyDelete(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	service, request, err := this_.getOpsService(requestBean, c)
+	if err != nil || service == nil {
+		return
+	}
+	if request.Name == "" {
+		err = errors.New("策略名称不能为空")
+		return
+	}
+	res, err = performMap(service, http.MethodDelete, esops.IlmPolicyNamePath(request.Name), nil, nil)
+	return
+}
+
+// ilmAttach 为 索引 指定 生命周期 策略
+func (this_ *api) ilmAttach(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	service, request, err := this_.getOpsService(requestBean, c)
+	if err != nil || service == nil {
+		return
+	}
+	if request.IndexName == "" {
+		err = errors.New("索引不能为空")
+		return
+	}
+	body, err := esops.IlmAttachBody(request.Policy, request.RolloverAlias)
+	if err != nil {
+		return
+	}
+	res, err = performMap(service, http.MethodPut, esops.IndexPath(request.IndexName, "/_settings"), nil, body)
+	return
+}
+
+// ilmDetach 移除 索引 的 生命周期 策略
+func (this_ *api) ilmDetach(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	service, request, err := this_.getOpsService(requestBean, c)
+	if err != nil || service == nil {
+		return
+	}
+	if request.IndexName == "" {
+		err = errors.New("索引不能为空")
+		return
+	}
+	res, err = performMap(service, http.MethodPost, esops.IndexPath(request.IndexName, "/_ilm/remove"), nil, nil)
+	return
+}
+
+// ilmExplain 索引 当前 所处 的 阶段，执行 出错 的 在 前
+func (this_ *api) ilmExplain(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	service, request, err := this_.getOpsService(requestBean, c)
+	if err != nil || service == nil {
+		return
+	}
+	if request.IndexName == "" {
+		err = errors.New("索引不能为空")
+		return
+	}
+	bs, err := perform(service, http.MethodGet, esops.IndexPath(request.IndexName, "/_ilm/explain"), nil, nil)
+	if err != nil {
+		return
+	}
+	res, err = esops.ParseIlmExplain(bs)
+	return
+}
+
+// ilmRetry 重试 出错 的 步骤
+func (this_ *api) ilmRetry(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	service, request, err := this_.getOpsService(requestBean, c)
+	if err != nil || service == nil {
+		return
+	}
+	if request.IndexName == "" {
+		err = errors.New("索引不能为空")
+		return
+	}
+	res, err = performMap(service, http.MethodPost, esops.IndexPath(request.IndexName, "/_ilm/retry"), nil, nil)
+	return
+}
@@ -0,0 +1,72 @@
+package module_elasticsearch
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/team-ide/go-tool/elasticsearch"
+	"net/url"
+	"teamide/pkg/base"
+	"teamide/pkg/esops"
+)
+
+// OpsRequest SQL、模板、生命周期、快照、集群 健康 等 运维 接口 的 请求
+type OpsRequest struct {
+	BaseRequest
+	esops.SqlQuery
+	Kind          string                 `json:"kind"` // 模板 类型 index、component
+	Name          string                 `json:"name"` // 模板 或 生命周期 策略 名称
+	Body          map[string]interface{} `json:"body"` // 模板、策略、仓库 定义，快照、恢复 参数
+	Policy        string                 `json:"policy"`
+	RolloverAlias string                 `json:"rolloverAlias"`
+	Repository    string                 `json:"repository"`
+	Snapshot      string                 `json:"snapshot"`
+	Shard         string                 `json:"shard"`
+	Primary       bool                   `json:"primary"`
+	Explain       bool                   `json:"explain"` // 集群 健康 中 附带 未 分配 分片 的 分配 说明
+}
+
+func (this_ *api) getOpsService(requestBean *base.RequestBean, c *gin.Context) (service elasticsearch.IService, request *OpsRequest, err error) {
+	config, err := this_.getConfig(requestBean, c)
+	if err != nil {
+		return
+	}
+	service, err = getService(config)
+	if err != nil {
+		return
+	}
+
+	request = &OpsRequest{}
+	if !base.RequestJSON(request, c) {
+		service = nil
+		return
+	}
+	return
+}
+
+// perform 通过 PerformRequest 调用 go-tool 未 封装 的 接口，返回 响应 体
+func perform(service elasticsearch.IService, method string, path string, params url.Values, body interface{}) (bs []byte, err error) {
+	response, err := service.PerformRequest(elasticsearch.PerformRequestOptions{
+		Method: method,
+		Path:   path,
+		Params: params,
+		Body:   body,
+	})
+	if err != nil {
+		return
+	}
+	bs = []byte(response.Body)
+	return
+}
+
+// performMap 返回 解析 后 的 响应 体，用于 直接 展示 的 接口
+func performMap(service elasticsearch.IService, method string, path string, params url.Values, body interface{}) (res map[string]interface{}, err error) {
+	bs, err := perform(service, method, path, params, body)
+	if err != nil {
+		return
+	}
+	res = map[string]interface{}{}
+	if len(bs) > 0 {
+		err = json.Unmarshal(bs, &res)
+	}
+	return
+}
@@ -0,0 +1,435 @@
+package module_elasticsearch
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/team-ide/go-tool/elasticsearch"
+	"github.com/team-ide/go-tool/util"
+	"go.uber.org/zap"
+	"net/http"
+	"net/url"
+	"sync"
+	"teamide/pkg/base"
+	"teamide/pkg/esops"
+	"time"
+)
+
+const (
+	snapshotTypeCreate  = "snapshot"
+	snapshotTypeRestore = "restore"
+
+	snapshotPollInterval = 2 * time.Second
+	snapshotMaxPollError = 5
+	// restoreStartTimeout 提交 恢复 后 超过 该 时间 仍 没有 分片 开始 恢复 的 结束 任务
+	restoreStartTimeout = 2 * time.Minute
+)
+
+// SnapshotTask 快照 创建、恢复 任务，通过 taskStatus、taskList 查询 进度
+type SnapshotTask struct {
+	TaskId     string          `json:"taskId"`
+	Type       string          `json:"type"`
+	Repository string          `json:"repository"`
+	Snapshot   string          `json:"snapshot"`
+	Progress   *esops.Progress `json:"progress"`
+	Error      string          `json:"error,omitempty"`
+	StartTime  int64           `json:"startTime"`
+	EndTime    int64           `json:"endTime,omitempty"`
+	IsEnd      bool            `json:"isEnd"`
+	IsStop     bool            `json:"isStop"`
+
+	config *elasticsearch.Config
+	userId int64
+	lock   sync.Mutex
+}
+
+func (this_ *SnapshotTask) getInfo() (res *SnapshotTask) {
+	this_.lock.Lock()
+	defer this_.lock.Unlock()
+	res = &SnapshotTask{
+		TaskId:     this_.TaskId,
+		Type:       this_.Type,
+		Repository: this_.Repository,
+		Snapshot:   this_.Snapshot,
+		Progress:   this_.Progress,
+		Error:      this_.Error,
+		StartTime:  this_.StartTime,
+		EndTime:    this_.EndTime,
+		IsEnd:      this_.IsEnd,
+		IsStop:     this_.IsStop,
+	}
+	return
+}
+
+// stopWatch 只 停止 跟踪 进度，不 影响 ES 中 的 快照 或 恢复
+func (this_ *SnapshotTask) stopWatch() {
+	this_.lock.Lock()
+	defer this_.lock.Unlock()
+	this_.IsStop = true
+}
+
+func (this_ *SnapshotTask) isStop() bool {
+	this_.lock.Lock()
+	defer this_.lock.Unlock()
+	return this_.IsStop
+}
+
+func (this_ *SnapshotTask) setProgress(progress *esops.Progress) {
+	this_.lock.Lock()
+	defer this_.lock.Unlock()
+	this_.Progress = progress
+}
+
+func (this_ *SnapshotTask) end(err error) {
+	this_.lock.Lock()
+	defer this_.lock.Unlock()
+	if err != nil {
+		util.Logger.Error("elasticsearch snapshot task error", zap.Any("taskId", this_.TaskId), zap.Error(err))
+		this_.Error = err.Error()
+	} else if this_.Progress != nil && this_.Progress.Error != "" {
+		this_.Error = this_.Progress.Error
+	}
+	this_.EndTime = util.GetNowMilli()
+	this_.IsEnd = true
+}
+
+// watchSnapshot 轮询 _status 直到 快照 结束
+func (this_ *SnapshotTask) watchSnapshot() {
+	path, err := esops.SnapshotRepositoryPath(this_.Repository, this_.Snapshot)
+	if err != nil {
+		this_.end(err)
+		return
+	}
+	var errCount int
+	for !this_.isStop() {
+		var service elasticsearch.IService
+		var bs []byte
+		var progress *esops.Progress
+		// 每次 获取 服务，避免 空闲 回收，并 在 回收 后 重建
+		service, err = getService(this_.config)
+		if err == nil {
+			bs, err = perform(service, http.MethodGet, path+"/_status", nil, nil)
+		}
+		if err == nil {
+			progress, err = esops.SnapshotProgress(bs)
+		}
+		if err != nil {
+			errCount++
+			if errCount >= snapshotMaxPollError {
+				break
+			}
+		} else {
+			errCount = 0
+			this_.setProgress(progress)
+			if progress.Done {
+				if progress.Failed && progress.Error == "" {
+					err = errors.New("快照" + progress.State)
+				}
+				break
+			}
+		}
+		time.Sleep(snapshotPollInterval)
+	}
+	this_.end(err)
+}
+
+// watchRestore 轮询 _recovery 直到 已 开始 的 分片 全部 完成，且 连续 两次 没有 新 的 分片 开始
+func (this_ *SnapshotTask) watchRestore(since int64) {
+	var err error
+	var errCount int
+	var lastDone *esops.Progress
+	startTime := time.Now()
+	for !this_.isStop() {
+		var service elasticsearch.IService
+		var bs []byte
+		var progress *esops.Progress
+		service, err = getService(this_.config)
+		if err == nil {
+			bs, err = perform(service, http.MethodGet, esops.RecoveryPath, nil, nil)
+		}
+		if err == nil {
+			progress, err = esops.RestoreProgress(bs, this_.Repository, this_.Snapshot, since)
+		}
+		if err != nil {
+			errCount++
+			if errCount >= snapshotMaxPollError {
+				break
+			}
+		} else {
+			errCount = 0
+			if progress.Done && lastDone != nil && lastDone.Total == progress.Total {
+				this_.setProgress(progress)
+				break
+			}
+			if progress.Done {
+				lastDone = progress
+				progress = &esops.Progress{State: "IN_PROGRESS", Total: progress.Total, Finished: progress.Finished, Percent: progress.Percent}
+			} else {
+				lastDone = nil
+			}
+			this_.setProgress(progress)
+			if progress.Total == 0 && time.Since(startTime) > restoreStartTimeout {
+				err = errors.New("没有从快照恢复的分片，请检查索引状态")
+				break
+			}
+		}
+		time.Sleep(snapshotPollInterval)
+	}
+	this_.end(err)
+}
+
+var (
+	snapshotTaskCache     = map[string]*SnapshotTask{}
+	snapshotTaskCacheLock = &sync.Mutex{}
+)
+
+func addSnapshotTask(workerId string, task *SnapshotTask) {
+	snapshotTaskCacheLock.Lock()
+	snapshotTaskCache[task.TaskId] = task
+	snapshotTaskCacheLock.Unlock()
+	addWorkerTask(workerId, task.TaskId)
+}
+
+func getSnapshotTask(taskId string, userId int64) (task *SnapshotTask) {
+	snapshotTaskCacheLock.Lock()
+	defer snapshotTaskCacheLock.Unlock()
+	task = snapshotTaskCache[taskId]
+	if task != nil && task.userId != userId {
+		task = nil
+	}
+	return
+}
+
+// isOtherUserSnapshotTask 是否 为 其他 用户 创建 的 快照 任务
+func isOtherUserSnapshotTask(taskId string, userId int64) bool {
+	snapshotTaskCacheLock.Lock()
+	defer snapshotTaskCacheLock.Unlock()
+	task := snapshotTaskCache[taskId]
+	return task != nil && task.userId != userId
+}
+
+// cleanSnapshotTask 停止 跟踪 并 移除 任务
+func cleanSnapshotTask(taskId string) {
+	snapshotTaskCacheLock.Lock()
+	task := snapshotTaskCache[taskId]
+	delete(snapshotTaskCache, taskId)
+	snapshotTaskCacheLock.Unlock()
+	if task != nil {
+		task.stopWatch()
+	}
+}
+
+// stopSnapshotTask 中止 正在 创建 的 快照，恢复 无法 中止，需要 删除 正在 恢复 的 索引
+func stopSnapshotTask(task *SnapshotTask) (err error) {
+	info := task.getInfo()
+	if info.IsEnd {
+		return
+	}
+	if info.Type == snapshotTypeRestore {
+		err = errors.New("恢复任务不支持停止，可删除正在恢复的索引")
+		return
+	}
+	path, err := esops.SnapshotRepositoryPath(info.Repository, info.Snapshot)
+	if err != nil {
+		return
+	}
+	service, err := getService(task.config)
+	if err != nil {
+		return
+	}
+	_, err = perform(service, http.MethodDelete, path, nil, nil)
+	return
+}
+
+func (this_ *api) repositories(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	service, _, err := this_.getOpsService(requestBean, c)
+	if err != nil || service == nil {
+		return
+	}
+	bs, err := perform(service, http.MethodGet, esops.SnapshotPath, nil, nil)
+	if err != nil {
+		return
+	}
+	res, err = esops.ParseRepositories(bs)
+	return
+}
+
+// repositorySave 创建 或 修改 仓库，body 为 type 与 settings
+func (this_ *api) repositorySave(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	service, request, err := this_.getOpsService(requestBean, c)
+	if err != nil || service == nil {
+		return
+	}
+	path, err := esops.SnapshotRepositoryPath(request.Repository, "")
+	if err != nil {
+		return
+	}
+	if request.Body == nil || request.Body["type"] == nil {
+		err = errors.New("仓库类型不能为空")
+		return
+	}
+	res, err = performMap(service, http.MethodPut, path, nil, request.Body)
+	return
+}
+
+func (this_ *api) repositoryDelete(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	service, request, err := this_.getOpsService(requestBean, c)
+	if err != nil || service == nil {
+		return
+	}
+	path, err := esops.SnapshotRepositoryPath(request.Repository, "")
+	if err != nil {
+		return
+	}
+	res, err = performMap(service, http.MethodDelete, path, nil, nil)
+	return
+}
+
+// repositoryVerify 校验 各 节点 能否 访问 仓库
+func (this_ *api) repositoryVerify(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	service, request, err := this_.getOpsService(requestBean, c)
+	if err != nil || service == nil {
+		return
+	}
+	path, err := esops.SnapshotRepositoryPath(request.Repository, "")
+	if err != nil {
+		return
+	}
+	res, err = performMap(service, http.MethodPost, path+"/_verify", nil, nil)
+	return
+}
+
+func (this_ *api) snapshots(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	service, request, err := this_.getOpsService(requestBean, c)
+	if err != nil || service == nil {
+		return
+	}
+	path, err := esops.SnapshotRepositoryPath(request.Repository, "_all")
+	if err != nil {
+		return
+	}
+	bs, err := perform(service, http.MethodGet, path, nil, nil)
+	if err != nil {
+		return
+	}
+	res, err = esops.ParseSnapshots(bs)
+	return
+}
+
+// snapshotCreate 异步 创建 快照，body 为 indices、include_global_state 等 参数
+func (this_ *api) snapshotCreate(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	config, err := this_.getConfig(requestBean, c)
+	if err != nil {
+		return
+	}
+	service, err := getService(config)
+	if err != nil {
+		return
+	}
+	request := &OpsRequest{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+	if request.Snapshot == "" {
+		err = errors.New("快照名称不能为空")
+		return
+	}
+	path, err := esops.SnapshotRepositoryPath(request.Repository, request.Snapshot)
+	if err != nil {
+		return
+	}
+	var body interface{}
+	if request.Body != nil {
+		body = request.Body
+	}
+	_, err = perform(service, http.MethodPut, path, url.Values{"wait_for_completion": {"false"}}, body)
+	if err != nil {
+		return
+	}
+
+	task := &SnapshotTask{
+		TaskId:     util.GetUUID(),
+		Type:       snapshotTypeCreate,
+		Repository: request.Repository,
+		Snapshot:   request.Snapshot,
+		StartTime:  util.GetNowMilli(),
+		config:     config,
+		userId:     requestBean.JWT.UserId,
+	}
+	addSnapshotTask(request.WorkerId, task)
+	go task.watchSnapshot()
+	res = task.getInfo()
+	return
+}
+
+// snapshotRestore 恢复 快照，body 为 indices、rename_pattern、rename_replacement 等 参数
+func (this_ *api) snapshotRestore(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	config, err := this_.getConfig(requestBean, c)
+	if err != nil {
+		return
+	}
+	service, err := getService(config)
+	if err != nil {
+		return
+	}
+	request := &OpsRequest{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+	if request.Snapshot == "" {
+		err = errors.New("快照名称不能为空")
+		return
+	}
+	path, err := esops.SnapshotRepositoryPath(request.Repository, request.Snapshot)
+	if err != nil {
+		return
+	}
+	// 记录 之前 从 该 快照 恢复 的 分片，进度 中 排除
+	bs, err := perform(service, http.MethodGet, esops.RecoveryPath, nil, nil)
+	if err != nil {
+		return
+	}
+	since, err := esops.LastRestoreTime(bs, request.Repository, request.Snapshot)
+	if err != nil {
+		return
+	}
+	var body interface{}
+	if request.Body != nil {
+		body = request.Body
+	}
+	// 不 等待 完成，避免 长时间 占用 连接，通过 _recovery 跟踪 进度
+	_, err = perform(service, http.MethodPost, path+"/_restore", url.Values{"wait_for_completion": {"false"}}, body)
+	if err != nil {
+		return
+	}
+
+	task := &SnapshotTask{
+		TaskId:     util.GetUUID(),
+		Type:       snapshotTypeRestore,
+		Repository: request.Repository,
+		Snapshot:   request.Snapshot,
+		StartTime:  util.GetNowMilli(),
+		config:     config,
+		userId:     requestBean.JWT.UserId,
+	}
+	addSnapshotTask(request.WorkerId, task)
+	go task.watchRestore(since)
+	res = task.getInfo()
+	return
+}
+
+func (this_ *api) snapshotDelete(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	service, request, err := this_.getOpsService(requestBean, c)
+	if err != nil || service == nil {
+		return
+	}
+	if request.Snapshot == "" {
+		err = errors.New("快照名称不能为空")
+		return
+	}
+	path, err := esops.SnapshotRepositoryPath(request.Repository, request.Snapshot)
+	if err != nil {
+		return
+	}
+	res, err = performMap(service, http.MethodDelete, path, nil, nil)
+	return
+}
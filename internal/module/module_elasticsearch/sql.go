@@ -0,0 +1,52 @@
+package module_elasticsearch
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/url"
+	"teamide/pkg/base"
+	"teamide/pkg/esops"
+)
+
+// sql 执行 SQL 查询，指定 cursor 时 查询 下一页，只有 第一页 返回 列
+func (this_ *api) sql(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	service, request, err := this_.getOpsService(requestBean, c)
+	if err != nil || service == nil {
+		return
+	}
+	body, err := esops.SqlBody(&request.SqlQuery)
+	if err != nil {
+		return
+	}
+	bs, err := perform(service, http.MethodPost, esops.SqlPath, url.Values{"format": {"json"}}, body)
+	if err != nil {
+		return
+	}
+	res, err = esops.ParseSqlResult(bs)
+	return
+}
+
+// sqlClose 不再 翻页 时 释放 游标
+func (this_ *api) sqlClose(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	service, request, err := this_.getOpsService(requestBean, c)
+	if err != nil || service == nil || request.Cursor == "" {
+		return
+	}
+	res, err = performMap(service, http.MethodPost, esops.SqlClosePath, nil, map[string]interface{}{"cursor": request.Cursor})
+	return
+}
+
+// sqlTranslate 查看 SQL 转换 后 的 查询 DSL
+func (this_ *api) sqlTranslate(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	service, request, err := this_.getOpsService(requestBean, c)
+	if err != nil || service == nil {
+		return
+	}
+	request.Cursor = ""
+	body, err := esops.SqlBody(&request.SqlQuery)
+	if err != nil {
+		return
+	}
+	res, err = performMap(service, http.MethodPost, esops.SqlTranslatePath, nil, body)
+	return
+}
@@ -0,0 +1,108 @@
+package module_elasticsearch
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"teamide/pkg/base"
+	"teamide/pkg/esops"
+)
+
+// templates 模板 列表，未 指定 类型 时 返回 索引 模板 与 组件 模板
+func (this_ *api) templates(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	service, request, err := this_.getOpsService(requestBean, c)
+	if err != nil || service == nil {
+		return
+	}
+	kinds := []string{request.Kind}
+	if request.Kind == "" {
+		kinds = []string{esops.TemplateKindIndex, esops.TemplateKindComponent}
+	}
+	var list []*esops.TemplateInfo
+	for _, kind := range kinds {
+		var path string
+		path, err = esops.TemplatePath(kind, "")
+		if err != nil {
+			return
+		}
+		var bs []byte
+		bs, err = perform(service, http.MethodGet, path, nil, nil)
+		if err != nil {
+			return
+		}
+		var one []*esops.TemplateInfo
+		one, err = esops.ParseTemplates(bs)
+		if err != nil {
+			return
+		}
+		list = append(list, one...)
+	}
+	res = list
+	return
+}
+
+func (this_ *api) templateGet(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	service, request, err := this_.getOpsService(requestBean, c)
+	if err != nil || service == nil {
+		return
+	}
+	if request.Name == "" {
+		err = errors.New("模板名称不能为空")
+		return
+	}
+	path, err := esops.TemplatePath(request.Kind, request.Name)
+	if err != nil {
+		return
+	}
+	bs, err := perform(service, http.MethodGet, path, nil, nil)
+	if err != nil {
+		return
+	}
+	list, err := esops.ParseTemplates(bs)
+	if err != nil {
+		return
+	}
+	for _, one := range list {
+		if one.Name == request.Name {
+			res = one
+			return
+		}
+	}
+	err = errors.New("模板[" + request.Name + "]不存在")
+	return
+}
+
+// templateSave 创建 或 覆盖 模板，body 为 模板 定义
+func (this_ *api) templateSave(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	service, request, err := this_.getOpsService(requestBean, c)
+	if err != nil || service == nil {
+		return
+	}
+	if request.Name == "" || request.Body == nil {
+		err = errors.New("模板名称和定义不能为空")
+		return
+	}
+	path, err := esops.TemplatePath(request.Kind, request.Name)
+	if err != nil {
+		return
+	}
+	res, err = performMap(service, http.MethodPut, path, nil, request.Body)
+	return
+}
+
+func (this_ *api) templateDelete(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	service, request, err := this_.getOpsService(requestBean, c)
+	if err != nil || service == nil {
+		return
+	}
+	if request.Name == "" {
+		err = errors.New("模板名称不能为空")
+		return
+	}
+	path, err := esops.TemplatePath(request.Kind, request.Name)
+	if err != nil {
+		return
+	}
+	res, err = performMap(service, http.MethodDelete, path, nil, nil)
+	return
+}
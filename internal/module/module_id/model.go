@@ -63,4 +63,20 @@ const (
 	IDTypeTerminalLog = 8001
 	// IDTypeTerminalCommand 控制台命令
 	IDTypeTerminalCommand = 8002
+
+	// IDTypeAlertRule 告警规则
+	IDTypeAlertRule = 9001
+	// IDTypeAlertChannel 告警通知渠道
+	IDTypeAlertChannel = 9002
+
+	// IDTypeDatabaseSqlHistory 数据库SQL执行历史
+	IDTypeDatabaseSqlHistory = 10001
+	// IDTypeDatabaseSqlSaved 数据库保存的SQL、片段
+	IDTypeDatabaseSqlSaved = 10002
+	// IDTypeDatabaseSchemaMigration 数据库结构迁移脚本
+	IDTypeDatabaseSchemaMigration = 10003
+	// IDTypeDatabaseMaskRule 数据库脱敏规则
+	IDTypeDatabaseMaskRule = 10004
+	// IDTypeDatabaseSqlApproval 数据库危险SQL审批
+	IDTypeDatabaseSqlApproval = 10005
 )
@@ -0,0 +1,475 @@
+package module_kafka
+
+import (
+	"errors"
+	"github.com/Shopify/sarama"
+	"github.com/team-ide/go-tool/util"
+	"go.uber.org/zap"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"teamide/pkg/kafkaadmin"
+)
+
+var (
+	configSources = map[sarama.ConfigSource]string{
+		sarama.SourceTopic:                kafkaadmin.SourceDynamicTopic,
+		sarama.SourceDynamicBroker:        "dynamicBroker",
+		sarama.SourceDynamicDefaultBroker: "dynamicDefaultBroker",
+		sarama.SourceStaticBroker:         "staticBroker",
+		sarama.SourceDefault:              "default",
+	}
+	aclResourceTypes = map[string]sarama.AclResourceType{
+		"topic":           sarama.AclResourceTopic,
+		"group":           sarama.AclResourceGroup,
+		"cluster":         sarama.AclResourceCluster,
+		"transactionalId": sarama.AclResourceTransactionalID,
+		"delegationToken": sarama.AclResourceDelegationToken,
+	}
+	aclPatternTypes = map[string]sarama.AclResourcePatternType{
+		"literal":  sarama.AclPatternLiteral,
+		"prefixed": sarama.AclPatternPrefixed,
+	}
+	aclOperations = map[string]sarama.AclOperation{
+		"all":             sarama.AclOperationAll,
+		"read":            sarama.AclOperationRead,
+		"write":           sarama.AclOperationWrite,
+		"create":          sarama.AclOperationCreate,
+		"delete":          sarama.AclOperationDelete,
+		"alter":           sarama.AclOperationAlter,
+		"describe":        sarama.AclOperationDescribe,
+		"clusterAction":   sarama.AclOperationClusterAction,
+		"describeConfigs": sarama.AclOperationDescribeConfigs,
+		"alterConfigs":    sarama.AclOperationAlterConfigs,
+		"idempotentWrite": sarama.AclOperationIdempotentWrite,
+	}
+	aclPermissions = map[string]sarama.AclPermissionType{
+		"allow": sarama.AclPermissionAllow,
+		"deny":  sarama.AclPermissionDeny,
+	}
+	scramMechanisms = map[string]sarama.ScramMechanismType{
+		"SCRAM-SHA-256": sarama.SCRAM_MECHANISM_SHA_256,
+		"SCRAM-SHA-512": sarama.SCRAM_MECHANISM_SHA_512,
+	}
+
+	aclResourceTypeNames = map[sarama.AclResourceType]string{}
+	aclPatternTypeNames  = map[sarama.AclResourcePatternType]string{}
+	aclOperationNames    = map[sarama.AclOperation]string{}
+	aclPermissionNames   = map[sarama.AclPermissionType]string{}
+	scramMechanismNames  = map[sarama.ScramMechanismType]string{}
+)
+
+func init() {
+	for name, one := range aclResourceTypes {
+		aclResourceTypeNames[one] = name
+	}
+	for name, one := range aclPatternTypes {
+		aclPatternTypeNames[one] = name
+	}
+	for name, one := range aclOperations {
+		aclOperationNames[one] = name
+	}
+	for name, one := range aclPermissions {
+		aclPermissionNames[one] = name
+	}
+	for name, one := range scramMechanisms {
+		scramMechanismNames[one] = name
+	}
+}
+
+var (
+	// kafkaDefaultVersion 无法 探测 时 使用 的 保守 版本，避免 向 旧 Broker 发送 不 支持 的 请求
+	kafkaDefaultVersion = sarama.V1_0_0_0
+	kafkaVersionCache   = map[string]sarama.KafkaVersion{}
+	kafkaVersionLock    = &sync.Mutex{}
+)
+
+// getKafkaVersion 通过 ApiVersions 探测 Broker 版本，探测 失败 时 使用 保守 版本，按 地址 缓存
+func getKafkaVersion(address string, config *sarama.Config) (version sarama.KafkaVersion) {
+	kafkaVersionLock.Lock()
+	find, ok := kafkaVersionCache[address]
+	kafkaVersionLock.Unlock()
+	if ok {
+		version = find
+		return
+	}
+	version = kafkaDefaultVersion
+	for _, addr := range strings.Split(address, ",") {
+		broker := sarama.NewBroker(strings.TrimSpace(addr))
+		if err := broker.Open(config); err != nil {
+			continue
+		}
+		res, err := broker.ApiVersions(&sarama.ApiVersionsRequest{})
+		_ = broker.Close()
+		if err != nil {
+			util.Logger.Warn("kafka api versions error", zap.Any("address", addr), zap.Error(err))
+			continue
+		}
+		version = versionFromApiKeys(res.ApiKeys)
+		break
+	}
+	kafkaVersionLock.Lock()
+	kafkaVersionCache[address] = version
+	kafkaVersionLock.Unlock()
+	return
+}
+
+// versionFromApiKeys 按 Broker 支持 的 接口 推断 版本，只 区分 管理 功能 需要 的 版本
+func versionFromApiKeys(apiKeys []sarama.ApiVersionsResponseKey) sarama.KafkaVersion {
+	maxVersions := map[int16]int16{}
+	for _, one := range apiKeys {
+		maxVersions[one.ApiKey] = one.MaxVersion
+	}
+	// 50 DescribeUserScramCredentials、44 IncrementalAlterConfigs、32 DescribeConfigs
+	if _, ok := maxVersions[50]; ok {
+		return sarama.V2_7_0_0
+	}
+	if _, ok := maxVersions[44]; ok {
+		return sarama.V2_3_0_0
+	}
+	if v, ok := maxVersions[32]; ok && v >= 2 {
+		return sarama.V2_0_0_0
+	}
+	if v, ok := maxVersions[32]; ok && v >= 1 {
+		return sarama.V1_1_0_0
+	}
+	return sarama.V1_0_0_0
+}
+
+// describeConfig 查询 Topic 或 Broker 配置，按 名称 排序
+func describeConfig(admin sarama.ClusterAdmin, resourceType sarama.ConfigResourceType, name string) (entries []*kafkaadmin.ConfigEntry, err error) {
+	list, err := admin.DescribeConfig(sarama.ConfigResource{Type: resourceType, Name: name})
+	if err != nil {
+		return
+	}
+	for _, one := range list {
+		source := configSources[one.Source]
+		if source == "" {
+			source = "unknown"
+		}
+		entries = append(entries, &kafkaadmin.ConfigEntry{
+			Name:      one.Name,
+			Value:     one.Value,
+			IsDefault: one.Default || one.Source == sarama.SourceDefault,
+			ReadOnly:  one.ReadOnly,
+			Sensitive: one.Sensitive,
+			Source:    source,
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	return
+}
+
+type BrokerInfo struct {
+	Id           int32  `json:"id"`
+	Addr         string `json:"addr"`
+	IsController bool   `json:"isController"`
+}
+
+func describeBrokers(admin sarama.ClusterAdmin) (brokers []*BrokerInfo, err error) {
+	list, controllerId, err := admin.DescribeCluster()
+	if err != nil {
+		return
+	}
+	for _, one := range list {
+		brokers = append(brokers, &BrokerInfo{Id: one.ID(), Addr: one.Addr(), IsController: one.ID() == controllerId})
+	}
+	sort.Slice(brokers, func(i, j int) bool { return brokers[i].Id < brokers[j].Id })
+	return
+}
+
+func brokerResourceName(brokerId int32) string {
+	return strconv.Itoa(int(brokerId))
+}
+
+// TopicConfigPlan Topic 配置 变更 预览，ValidateError 为 Broker 校验 结果
+type TopicConfigPlan struct {
+	Topic         string                       `json:"topic"`
+	Items         []*kafkaadmin.ConfigDiffItem `json:"items"`
+	ValidateError string                       `json:"validateError,omitempty"`
+	Applied       bool                         `json:"applied,omitempty"`
+}
+
+// planTopicConfig 计算 变更 并 使用 validateOnly 让 Broker 校验，变更 使用 增量 修改，不 影响 其它 覆盖 配置
+func planTopicConfig(admin sarama.ClusterAdmin, topic string, changes map[string]*string) (plan *TopicConfigPlan, alter map[string]sarama.IncrementalAlterConfigsEntry, err error) {
+	if topic == "" {
+		err = errors.New("topic is empty")
+		return
+	}
+	current, err := describeConfig(admin, sarama.TopicResource, topic)
+	if err != nil {
+		return
+	}
+	plan = &TopicConfigPlan{Topic: topic}
+	var values map[string]*string
+	if plan.Items, values, err = kafkaadmin.DiffConfig(current, changes); err != nil {
+		return
+	}
+	alter = map[string]sarama.IncrementalAlterConfigsEntry{}
+	for name, value := range values {
+		entry := sarama.IncrementalAlterConfigsEntry{Operation: sarama.IncrementalAlterConfigsOperationSet, Value: value}
+		if value == nil {
+			entry.Operation = sarama.IncrementalAlterConfigsOperationDelete
+		}
+		alter[name] = entry
+	}
+	if len(plan.Items) > 0 {
+		if e := admin.IncrementalAlterConfig(sarama.TopicResource, topic, alter, true); e != nil {
+			plan.ValidateError = e.Error()
+		}
+	}
+	return
+}
+
+func toSaramaAcl(binding *kafkaadmin.AclBinding) (resource sarama.Resource, acl sarama.Acl) {
+	resource = sarama.Resource{
+		ResourceType:        aclResourceTypes[binding.ResourceType],
+		ResourceName:        binding.ResourceName,
+		ResourcePatternType: aclPatternTypes[binding.PatternType],
+	}
+	acl = sarama.Acl{
+		Principal:      binding.Principal,
+		Host:           binding.Host,
+		Operation:      aclOperations[binding.Operation],
+		PermissionType: aclPermissions[binding.Permission],
+	}
+	return
+}
+
+// AclQuery ACL 查询 条件，为 空 的 条件 不 过滤
+type AclQuery struct {
+	ResourceType string `json:"resourceType,omitempty"`
+	ResourceName string `json:"resourceName,omitempty"`
+	Principal    string `json:"principal,omitempty"`
+}
+
+func listAcls(admin sarama.ClusterAdmin, query *AclQuery) (bindings []*kafkaadmin.AclBinding, err error) {
+	filter := sarama.AclFilter{
+		Version:                   1,
+		ResourceType:              sarama.AclResourceAny,
+		ResourcePatternTypeFilter: sarama.AclPatternAny,
+		Operation:                 sarama.AclOperationAny,
+		PermissionType:            sarama.AclPermissionAny,
+	}
+	if query != nil {
+		if query.ResourceType != "" {
+			resourceType, ok := aclResourceTypes[query.ResourceType]
+			if !ok {
+				err = errors.New("资源类型[" + query.ResourceType + "]不支持")
+				return
+			}
+			filter.ResourceType = resourceType
+		}
+		if query.ResourceName != "" {
+			filter.ResourceName = &query.ResourceName
+		}
+		if query.Principal != "" {
+			filter.Principal = &query.Principal
+		}
+	}
+	list, err := admin.ListAcls(filter)
+	if err != nil {
+		return
+	}
+	for _, resource := range list {
+		for _, acl := range resource.Acls {
+			bindings = append(bindings, &kafkaadmin.AclBinding{
+				ResourceType: aclResourceTypeNames[resource.ResourceType],
+				ResourceName: resource.ResourceName,
+				PatternType:  aclPatternTypeNames[resource.ResourcePatternType],
+				Principal:    acl.Principal,
+				Host:         acl.Host,
+				Operation:    aclOperationNames[acl.Operation],
+				Permission:   aclPermissionNames[acl.PermissionType],
+			})
+		}
+	}
+	return
+}
+
+// AclChangeRequest ACL 变更
+type AclChangeRequest struct {
+	Create []*kafkaadmin.AclBinding `json:"create,omitempty"`
+	Delete []*kafkaadmin.AclBinding `json:"delete,omitempty"`
+}
+
+func planAcls(admin sarama.ClusterAdmin, request *AclChangeRequest) (plan *kafkaadmin.AclPlan, err error) {
+	existing, err := listAcls(admin, nil)
+	if err != nil {
+		return
+	}
+	plan, err = kafkaadmin.PlanAcls(existing, request.Create, request.Delete)
+	return
+}
+
+// applyAcls 删除 使用 精确 过滤，只 删除 计划 中 的 那 一条
+func applyAcls(admin sarama.ClusterAdmin, plan *kafkaadmin.AclPlan) (err error) {
+	for _, one := range plan.Create {
+		resource, acl := toSaramaAcl(one)
+		if err = admin.CreateACL(resource, acl); err != nil {
+			return
+		}
+	}
+	for _, one := range plan.Delete {
+		resource, acl := toSaramaAcl(one)
+		filter := sarama.AclFilter{
+			Version:                   1,
+			ResourceType:              resource.ResourceType,
+			ResourceName:              &resource.ResourceName,
+			ResourcePatternTypeFilter: resource.ResourcePatternType,
+			Principal:                 &acl.Principal,
+			Host:                      &acl.Host,
+			Operation:                 acl.Operation,
+			PermissionType:            acl.PermissionType,
+		}
+		if _, err = admin.DeleteACL(filter, false); err != nil {
+			return
+		}
+	}
+	return
+}
+
+type ScramCredential struct {
+	User       string `json:"user"`
+	Mechanism  string `json:"mechanism"`
+	Iterations int32  `json:"iterations,omitempty"`
+}
+
+func describeScramUsers(admin sarama.ClusterAdmin, users []string) (credentials []*ScramCredential, err error) {
+	list, err := admin.DescribeUserScramCredentials(users)
+	if err != nil {
+		return
+	}
+	for _, one := range list {
+		if one.ErrorCode != sarama.ErrNoError {
+			// 查询 指定 用户 时 不存在 的 用户 返回 错误 码，忽略
+			continue
+		}
+		for _, info := range one.CredentialInfos {
+			credentials = append(credentials, &ScramCredential{
+				User:       one.User,
+				Mechanism:  scramMechanismNames[info.Mechanism],
+				Iterations: info.Iterations,
+			})
+		}
+	}
+	sort.Slice(credentials, func(i, j int) bool {
+		if credentials[i].User != credentials[j].User {
+			return credentials[i].User < credentials[j].User
+		}
+		return credentials[i].Mechanism < credentials[j].Mechanism
+	})
+	return
+}
+
+// ScramChange SCRAM 用户 变更，Password 为 空 时 删除 该 机制 的 凭证
+type ScramChange struct {
+	User       string `json:"user"`
+	Mechanism  string `json:"mechanism"` // SCRAM-SHA-256、SCRAM-SHA-512
+	Password   string `json:"password,omitempty"`
+	Iterations int32  `json:"iterations,omitempty"` // 默认 4096
+}
+
+// ScramPlanItem 预览，不 包含 密码
+type ScramPlanItem struct {
+	User       string `json:"user"`
+	Mechanism  string `json:"mechanism"`
+	Action     string `json:"action"` // add、update、delete、skip
+	Iterations int32  `json:"iterations,omitempty"`
+}
+
+func planScram(admin sarama.ClusterAdmin, changes []*ScramChange) (items []*ScramPlanItem, err error) {
+	var users []string
+	for _, one := range changes {
+		if one.User == "" {
+			err = errors.New("用户名不能为空")
+			return
+		}
+		one.Mechanism = strings.ToUpper(one.Mechanism)
+		if _, ok := scramMechanisms[one.Mechanism]; !ok {
+			err = errors.New("SCRAM机制[" + one.Mechanism + "]不支持，支持 SCRAM-SHA-256、SCRAM-SHA-512")
+			return
+		}
+		if one.Iterations <= 0 {
+			one.Iterations = 4096
+		}
+		users = append(users, one.User)
+	}
+	existing, err := describeScramUsers(admin, users)
+	if err != nil {
+		return
+	}
+	exists := map[string]bool{}
+	for _, one := range existing {
+		exists[one.User+"|"+one.Mechanism] = true
+	}
+	for _, one := range changes {
+		item := &ScramPlanItem{User: one.User, Mechanism: one.Mechanism}
+		found := exists[one.User+"|"+one.Mechanism]
+		switch {
+		case one.Password == "" && found:
+			item.Action = kafkaadmin.DiffDelete
+		case one.Password == "":
+			item.Action = "skip"
+		case found:
+			item.Action = kafkaadmin.DiffUpdate
+			item.Iterations = one.Iterations
+		default:
+			item.Action = kafkaadmin.DiffAdd
+			item.Iterations = one.Iterations
+		}
+		items = append(items, item)
+	}
+	return
+}
+
+func applyScram(admin sarama.ClusterAdmin, changes []*ScramChange, items []*ScramPlanItem) (err error) {
+	var upserts []sarama.AlterUserScramCredentialsUpsert
+	var deletes []sarama.AlterUserScramCredentialsDelete
+	for i, one := range changes {
+		switch items[i].Action {
+		case kafkaadmin.DiffAdd, kafkaadmin.DiffUpdate:
+			upserts = append(upserts, sarama.AlterUserScramCredentialsUpsert{
+				Name:       one.User,
+				Mechanism:  scramMechanisms[one.Mechanism],
+				Iterations: one.Iterations,
+				Password:   []byte(one.Password),
+			})
+		case kafkaadmin.DiffDelete:
+			deletes = append(deletes, sarama.AlterUserScramCredentialsDelete{
+				Name:      one.User,
+				Mechanism: scramMechanisms[one.Mechanism],
+			})
+		}
+	}
+	var results []*sarama.AlterUserScramCredentialsResult
+	if len(upserts) > 0 {
+		var list []*sarama.AlterUserScramCredentialsResult
+		if list, err = admin.UpsertUserScramCredentials(upserts); err != nil {
+			return
+		}
+		results = append(results, list...)
+	}
+	if len(deletes) > 0 {
+		var list []*sarama.AlterUserScramCredentialsResult
+		if list, err = admin.DeleteUserScramCredentials(deletes); err != nil {
+			return
+		}
+		results = append(results, list...)
+	}
+	var errs []string
+	for _, one := range results {
+		if one.ErrorCode != sarama.ErrNoError {
+			msg := one.ErrorCode.Error()
+			if one.ErrorMessage != nil && *one.ErrorMessage != "" {
+				msg = *one.ErrorMessage
+			}
+			errs = append(errs, one.User+":"+msg)
+		}
+	}
+	if len(errs) > 0 {
+		err = errors.New(strings.Join(errs, ";"))
+	}
+	return
+}
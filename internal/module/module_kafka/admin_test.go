@@ -0,0 +1,124 @@
+package module_kafka
+
+import (
+	"github.com/Shopify/sarama"
+	"github.com/team-ide/go-tool/kafka"
+	"net"
+	"teamide/pkg/kafkaadmin"
+	"testing"
+)
+
+func newMockAdmin(t *testing.T, apiKeys []sarama.ApiVersionsResponseKey) (broker *sarama.MockBroker, admin sarama.ClusterAdmin) {
+	broker = sarama.NewMockBroker(t, 1)
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"ApiVersionsRequest": sarama.NewMockApiVersionsResponse(t).SetApiKeys(apiKeys),
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetController(broker.BrokerID()).
+			SetBroker(broker.Addr(), broker.BrokerID()),
+		"DescribeConfigsRequest": sarama.NewMockWrapper(&sarama.DescribeConfigsResponse{
+			Version: 2,
+			Resources: []*sarama.ResourceResponse{{
+				Type: sarama.TopicResource,
+				Name: "orders",
+				Configs: []*sarama.ConfigEntry{
+					{Name: "retention.ms", Value: "5000", Source: sarama.SourceTopic},
+					{Name: "segment.bytes", Value: "1048576", Source: sarama.SourceTopic},
+					{Name: "max.message.bytes", Value: "1000000", Source: sarama.SourceDefault},
+				},
+			}},
+		}),
+		"IncrementalAlterConfigsRequest": sarama.NewMockIncrementalAlterConfigsResponse(t),
+	})
+
+	config, err := newSaramaConfig(&kafka.Config{Address: broker.Addr()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Version != sarama.V2_7_0_0 {
+		t.Fatalf("version %s", config.Version)
+	}
+	admin, err = sarama.NewClusterAdmin([]string{broker.Addr()}, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
+func TestVersionFromApiKeys(t *testing.T) {
+	tests := []struct {
+		apiKeys []sarama.ApiVersionsResponseKey
+		version sarama.KafkaVersion
+	}{
+		{apiKeys: []sarama.ApiVersionsResponseKey{{ApiKey: 32, MaxVersion: 4}, {ApiKey: 44}, {ApiKey: 50}}, version: sarama.V2_7_0_0},
+		{apiKeys: []sarama.ApiVersionsResponseKey{{ApiKey: 32, MaxVersion: 2}, {ApiKey: 44, MaxVersion: 1}}, version: sarama.V2_3_0_0},
+		{apiKeys: []sarama.ApiVersionsResponseKey{{ApiKey: 32, MaxVersion: 2}}, version: sarama.V2_0_0_0},
+		{apiKeys: []sarama.ApiVersionsResponseKey{{ApiKey: 32, MaxVersion: 1}}, version: sarama.V1_1_0_0},
+		{apiKeys: nil, version: sarama.V1_0_0_0},
+	}
+	for _, one := range tests {
+		if version := versionFromApiKeys(one.apiKeys); version != one.version {
+			t.Fatalf("api keys %+v version %s want %s", one.apiKeys, version, one.version)
+		}
+	}
+}
+
+// 探测 失败 时 使用 保守 版本 并 缓存，不 每次 重新 探测
+func TestGetKafkaVersionFallback(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+	_ = listener.Close()
+
+	if version := getKafkaVersion(address, sarama.NewConfig()); version != sarama.V1_0_0_0 {
+		t.Fatalf("fallback version %s", version)
+	}
+	kafkaVersionLock.Lock()
+	version, ok := kafkaVersionCache[address]
+	kafkaVersionLock.Unlock()
+	if !ok || version != sarama.V1_0_0_0 {
+		t.Fatalf("fallback version not cached: %s %v", version, ok)
+	}
+}
+
+// 覆盖 配置 需要 读 到 Source，修改 时 只 提交 变更 的 配置
+func TestPlanTopicConfig(t *testing.T) {
+	broker, admin := newMockAdmin(t, []sarama.ApiVersionsResponseKey{
+		{ApiKey: 3, MaxVersion: 9}, {ApiKey: 18, MaxVersion: 3}, {ApiKey: 32, MaxVersion: 4}, {ApiKey: 44, MaxVersion: 1}, {ApiKey: 50},
+	})
+	defer broker.Close()
+	defer func() { _ = admin.Close() }()
+
+	entries, err := describeConfig(admin, sarama.TopicResource, "orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 || entries[1].Name != "retention.ms" || entries[1].Source != kafkaadmin.SourceDynamicTopic || entries[1].IsDefault {
+		t.Fatalf("entries %+v", entries[1])
+	}
+
+	value := "6000"
+	plan, alter, err := planTopicConfig(admin, "orders", map[string]*string{"retention.ms": &value, "segment.bytes": nil})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.ValidateError != "" || len(plan.Items) != 2 || plan.Items[0].Name != "retention.ms" || plan.Items[0].Action != kafkaadmin.DiffUpdate ||
+		plan.Items[1].Action != kafkaadmin.DiffDelete {
+		t.Fatalf("plan %+v", plan)
+	}
+	if len(alter) != 2 || alter["retention.ms"].Operation != sarama.IncrementalAlterConfigsOperationSet ||
+		alter["segment.bytes"].Operation != sarama.IncrementalAlterConfigsOperationDelete {
+		t.Fatalf("alter %+v", alter)
+	}
+
+	var validated bool
+	for _, one := range broker.History() {
+		if request, ok := one.Request.(*sarama.IncrementalAlterConfigsRequest); ok {
+			validated = request.ValidateOnly && len(request.Resources[0].ConfigEntries) == 2
+		}
+	}
+	if !validated {
+		t.Fatal("validate only request not sent")
+	}
+}
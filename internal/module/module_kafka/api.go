@@ -42,6 +42,33 @@ var (
 	groupDeleteOffsets = base.AppendPower(&base.PowerAction{Action: "deleteOffsets", Text: "删除组Offsets", ShouldLogin: true, StandAlone: true, Parent: group})
 	groupDelete        = base.AppendPower(&base.PowerAction{Action: "delete", Text: "删除组", ShouldLogin: true, StandAlone: true, Parent: group})
 
+	tailKeyPower   = base.AppendPower(&base.PowerAction{Action: "tailKey", Text: "Kafka跟踪会话", ShouldLogin: true, StandAlone: true, Parent: Power})
+	websocketPower = base.AppendPower(&base.PowerAction{Action: "websocket", Text: "Kafka跟踪WebSocket", ShouldLogin: true, StandAlone: true, Parent: Power})
+	tailClosePower = base.AppendPower(&base.PowerAction{Action: "tailClose", Text: "Kafka跟踪关闭", ShouldLogin: true, StandAlone: true, Parent: Power})
+
+	schemaSubjectsPower = base.AppendPower(&base.PowerAction{Action: "schemaSubjects", Text: "Kafka Schema主题", ShouldLogin: true, StandAlone: true, Parent: Power})
+	schemaVersionsPower = base.AppendPower(&base.PowerAction{Action: "schemaVersions", Text: "Kafka Schema版本", ShouldLogin: true, StandAlone: true, Parent: Power})
+	schemaGetPower      = base.AppendPower(&base.PowerAction{Action: "schemaGet", Text: "Kafka Schema查询", ShouldLogin: true, StandAlone: true, Parent: Power})
+
+	lagPower              = base.AppendPower(&base.PowerAction{Action: "lag", Text: "Kafka积压", ShouldLogin: true, StandAlone: true, Parent: Power})
+	lagMonitorStartPower  = base.AppendPower(&base.PowerAction{Action: "lagMonitorStart", Text: "Kafka积压采样", ShouldLogin: true, StandAlone: true, Parent: Power})
+	lagMonitorStatusPower = base.AppendPower(&base.PowerAction{Action: "lagMonitorStatus", Text: "Kafka积压采样状态", ShouldLogin: true, StandAlone: true, Parent: Power})
+	lagMonitorStopPower   = base.AppendPower(&base.PowerAction{Action: "lagMonitorStop", Text: "Kafka积压采样停止", ShouldLogin: true, StandAlone: true, Parent: Power})
+	resetPlanPower        = base.AppendPower(&base.PowerAction{Action: "resetPlan", Text: "Kafka重置预览", ShouldLogin: true, StandAlone: true, Parent: Power})
+	resetExecutePower     = base.AppendPower(&base.PowerAction{Action: "resetExecute", Text: "Kafka重置执行", ShouldLogin: true, StandAlone: true, Parent: Power})
+
+	brokersPower          = base.AppendPower(&base.PowerAction{Action: "brokers", Text: "Kafka Broker列表", ShouldLogin: true, StandAlone: true, Parent: Power})
+	brokerConfigsPower    = base.AppendPower(&base.PowerAction{Action: "brokerConfigs", Text: "Kafka Broker配置", ShouldLogin: true, StandAlone: true, Parent: Power})
+	topicConfigsPower     = base.AppendPower(&base.PowerAction{Action: "topicConfigs", Text: "Kafka Topic配置", ShouldLogin: true, StandAlone: true, Parent: Power})
+	topicConfigPlanPower  = base.AppendPower(&base.PowerAction{Action: "topicConfigPlan", Text: "Kafka Topic配置预览", ShouldLogin: true, StandAlone: true, Parent: Power})
+	topicConfigApplyPower = base.AppendPower(&base.PowerAction{Action: "topicConfigApply", Text: "Kafka Topic配置修改", ShouldLogin: true, StandAlone: true, Parent: Power})
+	aclsPower             = base.AppendPower(&base.PowerAction{Action: "acls", Text: "Kafka ACL查询", ShouldLogin: true, StandAlone: true, Parent: Power})
+	aclPlanPower          = base.AppendPower(&base.PowerAction{Action: "aclPlan", Text: "Kafka ACL预览", ShouldLogin: true, StandAlone: true, Parent: Power})
+	aclApplyPower         = base.AppendPower(&base.PowerAction{Action: "aclApply", Text: "Kafka ACL修改", ShouldLogin: true, StandAlone: true, Parent: Power})
+	scramUsersPower       = base.AppendPower(&base.PowerAction{Action: "scramUsers", Text: "Kafka SCRAM用户", ShouldLogin: true, StandAlone: true, Parent: Power})
+	scramPlanPower        = base.AppendPower(&base.PowerAction{Action: "scramPlan", Text: "Kafka SCRAM用户预览", ShouldLogin: true, StandAlone: true, Parent: Power})
+	scramApplyPower       = base.AppendPower(&base.PowerAction{Action: "scramApply", Text: "Kafka SCRAM用户修改", ShouldLogin: true, StandAlone: true, Parent: Power})
+
 	closePower = base.AppendPower(&base.PowerAction{Action: "close", Text: "Kafka关闭", ShouldLogin: true, StandAlone: true, Parent: Power})
 )
 
@@ -66,6 +93,33 @@ func (this_ *api) GetApis() (apis []*base.ApiWorker) {
 	apis = append(apis, &base.ApiWorker{Power: groupDeleteOffsets, Do: this_.groupDeleteOffsets})
 	apis = append(apis, &base.ApiWorker{Power: groupDelete, Do: this_.groupDelete})
 
+	apis = append(apis, &base.ApiWorker{Power: tailKeyPower, Do: this_.tailKey})
+	apis = append(apis, &base.ApiWorker{Power: websocketPower, Do: this_.websocket, IsWebSocket: true})
+	apis = append(apis, &base.ApiWorker{Power: tailClosePower, Do: this_.tailClose})
+
+	apis = append(apis, &base.ApiWorker{Power: schemaSubjectsPower, Do: this_.schemaSubjects})
+	apis = append(apis, &base.ApiWorker{Power: schemaVersionsPower, Do: this_.schemaVersions})
+	apis = append(apis, &base.ApiWorker{Power: schemaGetPower, Do: this_.schemaGet})
+
+	apis = append(apis, &base.ApiWorker{Power: lagPower, Do: this_.lag})
+	apis = append(apis, &base.ApiWorker{Power: lagMonitorStartPower, Do: this_.lagMonitorStart})
+	apis = append(apis, &base.ApiWorker{Power: lagMonitorStatusPower, Do: this_.lagMonitorStatus, NotRecodeLog: true})
+	apis = append(apis, &base.ApiWorker{Power: lagMonitorStopPower, Do: this_.lagMonitorStop})
+	apis = append(apis, &base.ApiWorker{Power: resetPlanPower, Do: this_.resetPlan})
+	apis = append(apis, &base.ApiWorker{Power: resetExecutePower, Do: this_.resetExecute})
+
+	apis = append(apis, &base.ApiWorker{Power: brokersPower, Do: this_.brokers})
+	apis = append(apis, &base.ApiWorker{Power: brokerConfigsPower, Do: this_.brokerConfigs})
+	apis = append(apis, &base.ApiWorker{Power: topicConfigsPower, Do: this_.topicConfigs})
+	apis = append(apis, &base.ApiWorker{Power: topicConfigPlanPower, Do: this_.topicConfigPlan})
+	apis = append(apis, &base.ApiWorker{Power: topicConfigApplyPower, Do: this_.topicConfigApply})
+	apis = append(apis, &base.ApiWorker{Power: aclsPower, Do: this_.acls})
+	apis = append(apis, &base.ApiWorker{Power: aclPlanPower, Do: this_.aclPlan})
+	apis = append(apis, &base.ApiWorker{Power: aclApplyPower, Do: this_.aclApply})
+	apis = append(apis, &base.ApiWorker{Power: scramUsersPower, Do: this_.scramUsers})
+	apis = append(apis, &base.ApiWorker{Power: scramPlanPower, Do: this_.scramPlan, LogData: removeScramPassword})
+	apis = append(apis, &base.ApiWorker{Power: scramApplyPower, Do: this_.scramApply, LogData: removeScramPassword})
+
 	apis = append(apis, &base.ApiWorker{Power: closePower, Do: this_.close})
 
 	return
@@ -142,6 +196,9 @@ type BaseRequest struct {
 	Count     int32  `json:"count"`
 	KeyType   string `json:"keyType"`
 	ValueType string `json:"valueType"`
+
+	KeySchema   *SchemaOption `json:"keySchema"`
+	ValueSchema *SchemaOption `json:"valueSchema"`
 }
 
 func (this_ *api) check(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
@@ -277,6 +334,10 @@ func (this_ *api) pull(requestBean *base.RequestBean, c *gin.Context) (res inter
 		return
 	}
 
+	if isSchemaType(request.KeyType) || isSchemaType(request.ValueType) {
+		res, err = this_.pullWithSchema(requestBean, service, request)
+		return
+	}
 	res, err = service.Pull(request.GroupId, []string{request.Topic}, request.PullSize, request.PullTimeout, request.KeyType, request.ValueType)
 	if err != nil {
 		return
@@ -298,6 +359,14 @@ func (this_ *api) push(requestBean *base.RequestBean, c *gin.Context) (res inter
 	if !base.RequestJSON(request, c) {
 		return
 	}
+	schemaRequest := &SchemaRequest{}
+	if !base.RequestJSON(schemaRequest, c) {
+		return
+	}
+	err = this_.encodeWithSchema(requestBean, request, schemaRequest)
+	if err != nil {
+		return
+	}
 
 	err = service.Push(request)
 	if err != nil {
@@ -0,0 +1,296 @@
+package module_kafka
+
+import (
+	"errors"
+	"github.com/Shopify/sarama"
+	"github.com/gin-gonic/gin"
+	"github.com/team-ide/go-tool/util"
+	"go.uber.org/zap"
+	"teamide/pkg/base"
+)
+
+type AdminRequest struct {
+	BrokerId int32              `json:"brokerId"`
+	Topic    string             `json:"topic"`
+	Configs  map[string]*string `json:"configs"`
+	Users    []string           `json:"users"`
+	Changes  []*ScramChange     `json:"changes"`
+}
+
+func (this_ *api) brokers(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	config, err := this_.getConfig(requestBean, c)
+	if err != nil {
+		return
+	}
+
+	_, admin, err := newClusterAdmin(config)
+	if err != nil {
+		return
+	}
+	defer func() { _ = admin.Close() }()
+
+	res, err = describeBrokers(admin)
+	return
+}
+
+func (this_ *api) brokerConfigs(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	config, err := this_.getConfig(requestBean, c)
+	if err != nil {
+		return
+	}
+
+	request := &AdminRequest{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+
+	_, admin, err := newClusterAdmin(config)
+	if err != nil {
+		return
+	}
+	defer func() { _ = admin.Close() }()
+
+	res, err = describeConfig(admin, sarama.BrokerResource, brokerResourceName(request.BrokerId))
+	return
+}
+
+func (this_ *api) topicConfigs(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	config, err := this_.getConfig(requestBean, c)
+	if err != nil {
+		return
+	}
+
+	request := &AdminRequest{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+	if request.Topic == "" {
+		err = errors.New("topic is empty")
+		return
+	}
+
+	_, admin, err := newClusterAdmin(config)
+	if err != nil {
+		return
+	}
+	defer func() { _ = admin.Close() }()
+
+	res, err = describeConfig(admin, sarama.TopicResource, request.Topic)
+	return
+}
+
+// topicConfigPlan 预览 配置 变更，不 修改
+func (this_ *api) topicConfigPlan(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	config, err := this_.getConfig(requestBean, c)
+	if err != nil {
+		return
+	}
+
+	request := &AdminRequest{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+
+	_, admin, err := newClusterAdmin(config)
+	if err != nil {
+		return
+	}
+	defer func() { _ = admin.Close() }()
+
+	res, _, err = planTopicConfig(admin, request.Topic, request.Configs)
+	return
+}
+
+// topicConfigApply 重新 计算 预览 后 提交，校验 不 通过 时 不 执行
+func (this_ *api) topicConfigApply(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	config, err := this_.getConfig(requestBean, c)
+	if err != nil {
+		return
+	}
+
+	request := &AdminRequest{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+
+	_, admin, err := newClusterAdmin(config)
+	if err != nil {
+		return
+	}
+	defer func() { _ = admin.Close() }()
+
+	plan, alter, err := planTopicConfig(admin, request.Topic, request.Configs)
+	if err != nil {
+		return
+	}
+	if plan.ValidateError != "" {
+		err = errors.New(plan.ValidateError)
+		return
+	}
+	if len(plan.Items) == 0 {
+		err = errors.New("配置没有变更")
+		return
+	}
+	err = admin.IncrementalAlterConfig(sarama.TopicResource, request.Topic, alter, false)
+	if err != nil {
+		util.Logger.Error("kafka alter topic config error", zap.Any("topic", request.Topic), zap.Error(err))
+		return
+	}
+	plan.Applied = true
+	res = plan
+	return
+}
+
+func (this_ *api) acls(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	config, err := this_.getConfig(requestBean, c)
+	if err != nil {
+		return
+	}
+
+	request := &AclQuery{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+
+	_, admin, err := newClusterAdmin(config)
+	if err != nil {
+		return
+	}
+	defer func() { _ = admin.Close() }()
+
+	res, err = listAcls(admin, request)
+	return
+}
+
+func (this_ *api) aclPlan(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	config, err := this_.getConfig(requestBean, c)
+	if err != nil {
+		return
+	}
+
+	request := &AclChangeRequest{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+
+	_, admin, err := newClusterAdmin(config)
+	if err != nil {
+		return
+	}
+	defer func() { _ = admin.Close() }()
+
+	res, err = planAcls(admin, request)
+	return
+}
+
+func (this_ *api) aclApply(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	config, err := this_.getConfig(requestBean, c)
+	if err != nil {
+		return
+	}
+
+	request := &AclChangeRequest{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+
+	_, admin, err := newClusterAdmin(config)
+	if err != nil {
+		return
+	}
+	defer func() { _ = admin.Close() }()
+
+	plan, err := planAcls(admin, request)
+	if err != nil {
+		return
+	}
+	err = applyAcls(admin, plan)
+	if err != nil {
+		util.Logger.Error("kafka apply acl error", zap.Error(err))
+		return
+	}
+	res = plan
+	return
+}
+
+func (this_ *api) scramUsers(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	config, err := this_.getConfig(requestBean, c)
+	if err != nil {
+		return
+	}
+
+	request := &AdminRequest{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+
+	_, admin, err := newClusterAdmin(config)
+	if err != nil {
+		return
+	}
+	defer func() { _ = admin.Close() }()
+
+	res, err = describeScramUsers(admin, request.Users)
+	return
+}
+
+// removeScramPassword 记录 日志 时 去掉 变更 中 的 密码
+func removeScramPassword(data map[string]interface{}) {
+	changes, _ := data["changes"].([]interface{})
+	for _, one := range changes {
+		if change, ok := one.(map[string]interface{}); ok {
+			delete(change, "password")
+		}
+	}
+}
+
+func (this_ *api) scramPlan(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	config, err := this_.getConfig(requestBean, c)
+	if err != nil {
+		return
+	}
+
+	request := &AdminRequest{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+
+	_, admin, err := newClusterAdmin(config)
+	if err != nil {
+		return
+	}
+	defer func() { _ = admin.Close() }()
+
+	res, err = planScram(admin, request.Changes)
+	return
+}
+
+func (this_ *api) scramApply(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	config, err := this_.getConfig(requestBean, c)
+	if err != nil {
+		return
+	}
+
+	request := &AdminRequest{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+
+	_, admin, err := newClusterAdmin(config)
+	if err != nil {
+		return
+	}
+	defer func() { _ = admin.Close() }()
+
+	items, err := planScram(admin, request.Changes)
+	if err != nil {
+		return
+	}
+	err = applyScram(admin, request.Changes, items)
+	if err != nil {
+		util.Logger.Error("kafka apply scram error", zap.Error(err))
+		return
+	}
+	res = items
+	return
+}
@@ -0,0 +1,143 @@
+package module_kafka
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/team-ide/go-tool/util"
+	"go.uber.org/zap"
+	"teamide/pkg/base"
+)
+
+type LagRequest struct {
+	LagMonitorConfig
+	MonitorKey string `json:"monitorKey"`
+}
+
+// lag 计算 Topic 上 各 组 的 分区 积压 与 合计
+func (this_ *api) lag(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	config, err := this_.getConfig(requestBean, c)
+	if err != nil {
+		return
+	}
+
+	request := &LagRequest{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+	if request.Topic == "" {
+		err = errors.New("topic is empty")
+		return
+	}
+
+	client, admin, err := newClusterAdmin(config)
+	if err != nil {
+		return
+	}
+	defer func() { _ = admin.Close() }()
+
+	res, err = collectTopicLag(client, admin, request.Topic, request.GroupIds)
+	return
+}
+
+// lagMonitorStart 开始 定时 采样 积压，返回 查询 使用 的 key
+func (this_ *api) lagMonitorStart(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	config, err := this_.getConfig(requestBean, c)
+	if err != nil {
+		return
+	}
+
+	request := &LagRequest{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+
+	monitor, err := startLagMonitor(&request.LagMonitorConfig, config)
+	if err != nil {
+		return
+	}
+	res = monitor.getInfo()
+	return
+}
+
+func (this_ *api) lagMonitorStatus(_ *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	request := &LagRequest{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+
+	monitor := getLagMonitor(request.MonitorKey)
+	if monitor == nil {
+		err = errors.New("采样[" + request.MonitorKey + "]不存在或已停止")
+		return
+	}
+	res = monitor.getInfo()
+	return
+}
+
+func (this_ *api) lagMonitorStop(_ *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	request := &LagRequest{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+
+	monitor := getLagMonitor(request.MonitorKey)
+	if monitor != nil {
+		monitor.stop()
+	}
+	return
+}
+
+// resetPlan 预览 重置 后 的 位置，同 kafka-consumer-groups --dry-run
+func (this_ *api) resetPlan(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	config, err := this_.getConfig(requestBean, c)
+	if err != nil {
+		return
+	}
+
+	request := &ResetPlanRequest{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+
+	res, err = planReset(config, request)
+	return
+}
+
+// resetExecute 重新 计算 预览 后 提交，组 有 活跃 成员 时 不 执行
+func (this_ *api) resetExecute(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	config, err := this_.getConfig(requestBean, c)
+	if err != nil {
+		return
+	}
+	service, err := getService(config)
+	if err != nil {
+		return
+	}
+
+	request := &ResetPlanRequest{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+
+	plan, err := planReset(config, request)
+	if err != nil {
+		return
+	}
+	if !plan.Executable {
+		err = errors.New(plan.Warning)
+		return
+	}
+	for _, item := range plan.Items {
+		if item.Target == item.Current {
+			continue
+		}
+		err = service.ResetOffset(request.GroupId, request.Topic, item.Partition, item.Target)
+		if err != nil {
+			util.Logger.Error("kafka reset offset error", zap.Any("groupId", request.GroupId), zap.Any("topic", request.Topic), zap.Any("partition", item.Partition), zap.Error(err))
+			return
+		}
+	}
+	plan.Executed = true
+	res = plan
+	return
+}
@@ -0,0 +1,288 @@
+package module_kafka
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/team-ide/go-tool/kafka"
+	"os"
+	"sync"
+	"teamide/internal/module/module_toolbox"
+	"teamide/pkg/base"
+	"teamide/pkg/kafkaschema"
+	"teamide/pkg/msgfilter"
+)
+
+const (
+	// DataTypeAvro Confluent 帧 或 本地 .avsc 的 Avro
+	DataTypeAvro = "avro"
+	// DataTypeProtobuf Confluent 帧 或 本地 .proto 的 Protobuf
+	DataTypeProtobuf = "protobuf"
+)
+
+func isSchemaType(dataType string) bool {
+	return dataType == DataTypeAvro || dataType == DataTypeProtobuf
+}
+
+// SchemaOption Key 或 Value 的 结构 选项，配置 了 本地 文件 时 不 使用 Schema Registry
+type SchemaOption struct {
+	Subject     string `json:"subject,omitempty"`     // 推送 编码 使用 的 主题
+	Version     string `json:"version,omitempty"`     // 推送 编码 使用 的 版本，为 空 时 latest
+	SchemaFile  string `json:"schemaFile,omitempty"`  // 本地 .avsc、.proto 文件，上传 后 的 路径
+	MessageName string `json:"messageName,omitempty"` // protobuf 消息 名称，为 空 时 取 帧 中 的 下标 或 第一个 消息
+}
+
+type SchemaRequest struct {
+	KeySchema   *SchemaOption `json:"keySchema,omitempty"`
+	ValueSchema *SchemaOption `json:"valueSchema,omitempty"`
+	Subject     string        `json:"subject,omitempty"`
+	Version     string        `json:"version,omitempty"`
+}
+
+// SchemaMessage 拉取 的 消息，附带 结构 ID 与 解码 错误，解码 失败 时 保留 原始 值
+type SchemaMessage struct {
+	*kafka.Message
+	KeySchemaId   int    `json:"keySchemaId,omitempty"`
+	ValueSchemaId int    `json:"valueSchemaId,omitempty"`
+	DecodeError   string `json:"decodeError,omitempty"`
+}
+
+var (
+	registryCache     = map[string]*kafkaschema.Registry{}
+	registryCacheLock = &sync.Mutex{}
+)
+
+// getRegistry 读取 工具箱 配置 的 Schema Registry，未 配置 时 返回 nil，需 在 getConfig 之后 调用
+func (this_ *api) getRegistry(requestBean *base.RequestBean) (registry *kafkaschema.Registry, err error) {
+	toolbox, ok := requestBean.GetExtend("toolboxModel").(*module_toolbox.ToolboxModel)
+	if !ok || toolbox == nil || toolbox.Option == "" {
+		return
+	}
+	config := &kafkaschema.RegistryConfig{}
+	if err = json.Unmarshal([]byte(toolbox.Option), config); err != nil {
+		return
+	}
+	if config.Url == "" {
+		return
+	}
+	config.Password = this_.toolboxService.DecryptOptionAttr(config.Password)
+
+	key := config.Url
+	if config.Username != "" {
+		key += "-" + base.GetMd5String(key+config.Username)
+	}
+	if config.Password != "" {
+		key += "-" + base.GetMd5String(key+config.Password)
+	}
+	registryCacheLock.Lock()
+	defer registryCacheLock.Unlock()
+	registry = registryCache[key]
+	if registry == nil {
+		if registry, err = kafkaschema.NewRegistry(config); err != nil {
+			return
+		}
+		registryCache[key] = registry
+	}
+	return
+}
+
+// schemaCodec 一侧 数据 的 编解码，本地 结构 优先
+type schemaCodec struct {
+	registry *kafkaschema.Registry
+	local    *kafkaschema.Schema
+	option   *SchemaOption
+}
+
+// getSchemaCodec 数据 类型 不是 avro、protobuf 时 返回 nil
+func (this_ *api) getSchemaCodec(requestBean *base.RequestBean, dataType string, option *SchemaOption) (codec *schemaCodec, err error) {
+	if !isSchemaType(dataType) {
+		return
+	}
+	if option == nil {
+		option = &SchemaOption{}
+	}
+	codec = &schemaCodec{option: option}
+	if option.SchemaFile != "" {
+		filePath := this_.toolboxService.GetFilesFile(option.SchemaFile)
+		var bs []byte
+		if bs, err = os.ReadFile(filePath); err != nil {
+			return
+		}
+		if codec.local, err = kafkaschema.NewLocalSchema(filePath, string(bs)); err != nil {
+			return
+		}
+		if (dataType == DataTypeAvro) != (codec.local.SchemaType == kafkaschema.SchemaTypeAvro) {
+			err = errors.New("本地结构文件[" + option.SchemaFile + "]与数据类型[" + dataType + "]不匹配")
+		}
+		return
+	}
+	if codec.registry, err = this_.getRegistry(requestBean); err != nil {
+		return
+	}
+	if codec.registry == nil {
+		err = errors.New("未配置 Schema Registry，请配置或指定本地结构文件")
+	}
+	return
+}
+
+func (this_ *schemaCodec) decode(bs []byte) (value string, schemaId int, err error) {
+	if this_.local != nil {
+		value, err = this_.local.Decode(bs, this_.option.MessageName)
+		return
+	}
+	value, schemaId, err = this_.registry.Decode(bs)
+	return
+}
+
+func (this_ *schemaCodec) encode(value string) (bs []byte, err error) {
+	schema := this_.local
+	if schema == nil {
+		if this_.option.Subject == "" {
+			err = errors.New("编码需要指定Subject")
+			return
+		}
+		if schema, err = this_.registry.GetSubjectVersion(this_.option.Subject, this_.option.Version); err != nil {
+			return
+		}
+	}
+	bs, err = schema.Encode(value, this_.option.MessageName)
+	return
+}
+
+// decodeData 有 结构 时 按 结构 解码，否则 按 简单 类型 解码
+func decodeData(bs []byte, dataType string, codec *schemaCodec) (value string, err error) {
+	if codec != nil {
+		value, _, err = codec.decode(bs)
+		return
+	}
+	value, err = msgfilter.Decode(bs, dataType)
+	return
+}
+
+// pullWithSchema 结构 类型 的 一侧 按 string 拉取 原始 字节 后 解码
+func (this_ *api) pullWithSchema(requestBean *base.RequestBean, service kafka.IService, request *BaseRequest) (res interface{}, err error) {
+	keyCodec, err := this_.getSchemaCodec(requestBean, request.KeyType, request.KeySchema)
+	if err != nil {
+		return
+	}
+	valueCodec, err := this_.getSchemaCodec(requestBean, request.ValueType, request.ValueSchema)
+	if err != nil {
+		return
+	}
+	keyType, valueType := request.KeyType, request.ValueType
+	if keyCodec != nil {
+		keyType = "string"
+	}
+	if valueCodec != nil {
+		valueType = "string"
+	}
+	msgList, err := service.Pull(request.GroupId, []string{request.Topic}, request.PullSize, request.PullTimeout, keyType, valueType)
+	if err != nil {
+		return
+	}
+	var list []*SchemaMessage
+	for _, msg := range msgList {
+		one := &SchemaMessage{Message: msg}
+		var decodeErr error
+		if keyCodec != nil && msg.Key != "" {
+			var value string
+			if value, one.KeySchemaId, decodeErr = keyCodec.decode([]byte(msg.Key)); decodeErr != nil {
+				one.DecodeError = "key:" + decodeErr.Error()
+			} else {
+				msg.Key = value
+			}
+		}
+		if valueCodec != nil && msg.Value != "" {
+			var value string
+			if value, one.ValueSchemaId, decodeErr = valueCodec.decode([]byte(msg.Value)); decodeErr != nil {
+				if one.DecodeError != "" {
+					one.DecodeError += ";"
+				}
+				one.DecodeError += "value:" + decodeErr.Error()
+			} else {
+				msg.Value = value
+			}
+		}
+		list = append(list, one)
+	}
+	res = list
+	return
+}
+
+// encodeWithSchema 结构 类型 的 一侧 编码 为 字节 后 按 string 推送
+func (this_ *api) encodeWithSchema(requestBean *base.RequestBean, msg *kafka.Message, request *SchemaRequest) (err error) {
+	keyCodec, err := this_.getSchemaCodec(requestBean, msg.KeyType, request.KeySchema)
+	if err != nil {
+		return
+	}
+	valueCodec, err := this_.getSchemaCodec(requestBean, msg.ValueType, request.ValueSchema)
+	if err != nil {
+		return
+	}
+	var bs []byte
+	if keyCodec != nil {
+		if bs, err = keyCodec.encode(msg.Key); err != nil {
+			err = errors.New("key编码失败:" + err.Error())
+			return
+		}
+		msg.Key = string(bs)
+		msg.KeyType = "string"
+	}
+	if valueCodec != nil {
+		if bs, err = valueCodec.encode(msg.Value); err != nil {
+			err = errors.New("value编码失败:" + err.Error())
+			return
+		}
+		msg.Value = string(bs)
+		msg.ValueType = "string"
+	}
+	return
+}
+
+func (this_ *api) getRegistryOrError(requestBean *base.RequestBean, c *gin.Context) (registry *kafkaschema.Registry, err error) {
+	if _, err = this_.getConfig(requestBean, c); err != nil {
+		return
+	}
+	if registry, err = this_.getRegistry(requestBean); err != nil {
+		return
+	}
+	if registry == nil {
+		err = errors.New("未配置 Schema Registry")
+	}
+	return
+}
+
+func (this_ *api) schemaSubjects(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	registry, err := this_.getRegistryOrError(requestBean, c)
+	if err != nil {
+		return
+	}
+	res, err = registry.GetSubjects()
+	return
+}
+
+func (this_ *api) schemaVersions(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	registry, err := this_.getRegistryOrError(requestBean, c)
+	if err != nil {
+		return
+	}
+	request := &SchemaRequest{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+	res, err = registry.GetVersions(request.Subject)
+	return
+}
+
+func (this_ *api) schemaGet(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	registry, err := this_.getRegistryOrError(requestBean, c)
+	if err != nil {
+		return
+	}
+	request := &SchemaRequest{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+	res, err = registry.GetSubjectVersion(request.Subject, request.Version)
+	return
+}
@@ -0,0 +1,97 @@
+package module_kafka
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/team-ide/go-tool/util"
+	"go.uber.org/zap"
+	"teamide/pkg/base"
+	"teamide/pkg/wsattach"
+)
+
+type TailRequest struct {
+	TailConfig
+	TailKey string `json:"tailKey"`
+}
+
+// tailKey 创建 Topic 跟踪 会话，返回 websocket 连接 使用 的 key
+func (this_ *api) tailKey(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	config, err := this_.getConfig(requestBean, c)
+	if err != nil {
+		return
+	}
+
+	request := &TailRequest{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+
+	service, err := createTailService(&request.TailConfig, config, requestBean.JWT.UserId)
+	if err != nil {
+		return
+	}
+	if service.keyCodec, err = this_.getSchemaCodec(requestBean, request.KeyType, request.KeySchema); err != nil {
+		return
+	}
+	if service.valueCodec, err = this_.getSchemaCodec(requestBean, request.ValueType, request.ValueSchema); err != nil {
+		return
+	}
+	setTailService(service.Key, service)
+	data := make(map[string]interface{})
+	data["key"] = service.Key
+	res = data
+	return
+}
+
+func (this_ *api) websocket(request *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+
+	if request.JWT == nil || request.JWT.UserId == 0 {
+		err = errors.New("登录用户获取失败")
+		return
+	}
+	key := c.Query("key")
+	if key == "" {
+		err = errors.New("key获取失败")
+		return
+	}
+	//升级get请求为webSocket协议
+	ws, err := wsattach.Upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		return
+	}
+
+	service := getTailService(key, request.JWT.UserId)
+	if service == nil {
+		err = errors.New("会话[" + key + "]不存在")
+
+		_ = ws.WriteMessage(websocket.TextMessage, []byte(`{"type":"error","error":"service not found"}`))
+		util.Logger.Error("kafka websocket start error", zap.Error(err))
+		_ = ws.Close()
+		return
+	}
+
+	err = service.attach(ws)
+	if err != nil {
+		service.write(&TailEvent{Type: "error", Error: "start error:" + err.Error()})
+		util.Logger.Error("kafka websocket start error", zap.Error(err))
+		service.stop()
+		return
+	}
+
+	res = base.HttpNotResponse
+	return
+}
+
+func (this_ *api) tailClose(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	request := &TailRequest{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+
+	service := getTailService(request.TailKey, requestBean.JWT.UserId)
+	if service != nil {
+		service.stop()
+	}
+	return
+}
@@ -0,0 +1,391 @@
+package module_kafka
+
+import (
+	"errors"
+	"github.com/Shopify/sarama"
+	"github.com/team-ide/go-tool/kafka"
+	"github.com/team-ide/go-tool/util"
+	"go.uber.org/zap"
+	"strings"
+	"sync"
+	"teamide/pkg/kafkalag"
+	"time"
+)
+
+const (
+	defaultLagInterval   = 10
+	minLagInterval       = 2
+	defaultLagMaxSamples = 360
+	// lagMonitorIdleTime 超过 该 时间 没有 查询 状态 时 自动 停止
+	lagMonitorIdleTime = 10 * time.Minute
+)
+
+// newClusterAdmin 创建 管理 客户端，关闭 admin 时 会 关闭 底层 client
+func newClusterAdmin(kafkaConfig *kafka.Config) (client sarama.Client, admin sarama.ClusterAdmin, err error) {
+	config, err := newSaramaConfig(kafkaConfig)
+	if err != nil {
+		return
+	}
+	config.ClientID = "team-ide-admin"
+	client, err = sarama.NewClient(strings.Split(kafkaConfig.Address, ","), config)
+	if err != nil {
+		return
+	}
+	admin, err = sarama.NewClusterAdminFromClient(client)
+	if err != nil {
+		_ = client.Close()
+		return
+	}
+	return
+}
+
+// getPartitionOffsets 查询 分区 最早、最新 位置，partitions 为 空 时 所有 分区
+func getPartitionOffsets(client sarama.Client, topic string, partitions []int32) (offsets []*kafkalag.PartitionOffset, err error) {
+	if len(partitions) == 0 {
+		if partitions, err = client.Partitions(topic); err != nil {
+			return
+		}
+	}
+	for _, partition := range partitions {
+		one := &kafkalag.PartitionOffset{Partition: partition, Committed: -1}
+		if one.LogStart, err = client.GetOffset(topic, partition, sarama.OffsetOldest); err != nil {
+			return
+		}
+		if one.LogEnd, err = client.GetOffset(topic, partition, sarama.OffsetNewest); err != nil {
+			return
+		}
+		offsets = append(offsets, one)
+	}
+	return
+}
+
+// fillCommitted 填充 组 的 提交 位置，返回 是否 有 提交 过 的 分区
+func fillCommitted(admin sarama.ClusterAdmin, groupId string, topic string, offsets []*kafkalag.PartitionOffset) (committed bool, err error) {
+	var partitions []int32
+	for _, one := range offsets {
+		partitions = append(partitions, one.Partition)
+	}
+	res, err := admin.ListConsumerGroupOffsets(groupId, map[string][]int32{topic: partitions})
+	if err != nil {
+		return
+	}
+	for _, one := range offsets {
+		one.Committed = -1
+		block := res.GetBlock(topic, one.Partition)
+		if block != nil && block.Err == sarama.ErrNoError && block.Offset >= 0 {
+			one.Committed = block.Offset
+			committed = true
+		}
+	}
+	return
+}
+
+func copyOffsets(offsets []*kafkalag.PartitionOffset) (res []*kafkalag.PartitionOffset) {
+	for _, one := range offsets {
+		copied := *one
+		res = append(res, &copied)
+	}
+	return
+}
+
+// getGroupStates 查询 组 状态，如 Empty、Stable
+func getGroupStates(admin sarama.ClusterAdmin, groupIds []string) (states map[string]string, err error) {
+	states = map[string]string{}
+	if len(groupIds) == 0 {
+		return
+	}
+	list, err := admin.DescribeConsumerGroups(groupIds)
+	if err != nil {
+		return
+	}
+	for _, one := range list {
+		states[one.GroupId] = one.State
+	}
+	return
+}
+
+// collectTopicLag 计算 Topic 上 各 组 的 积压，groupIds 为 空 时 查找 所有 在 该 Topic 上 提交 过 位置 的 组
+func collectTopicLag(client sarama.Client, admin sarama.ClusterAdmin, topic string, groupIds []string) (sample *kafkalag.Sample, err error) {
+	offsets, err := getPartitionOffsets(client, topic, nil)
+	if err != nil {
+		return
+	}
+	sample = &kafkalag.Sample{
+		Time:   util.GetNowMilli(),
+		Groups: map[string]*kafkalag.GroupLag{},
+	}
+	for _, one := range offsets {
+		sample.LogEnd += one.LogEnd
+	}
+	onlyCommitted := len(groupIds) == 0
+	if onlyCommitted {
+		var groups map[string]string
+		if groups, err = admin.ListConsumerGroups(); err != nil {
+			return
+		}
+		for groupId := range groups {
+			groupIds = append(groupIds, groupId)
+		}
+	}
+	var found []string
+	for _, groupId := range groupIds {
+		groupOffsets := copyOffsets(offsets)
+		var committed bool
+		if committed, err = fillCommitted(admin, groupId, topic, groupOffsets); err != nil {
+			return
+		}
+		if onlyCommitted && !committed {
+			continue
+		}
+		sample.Groups[groupId] = kafkalag.ComputeLag(groupId, topic, groupOffsets)
+		found = append(found, groupId)
+	}
+	states, err := getGroupStates(admin, found)
+	if err != nil {
+		return
+	}
+	for groupId, one := range sample.Groups {
+		one.State = states[groupId]
+	}
+	return
+}
+
+// LagMonitorConfig 积压 采样 配置
+type LagMonitorConfig struct {
+	Topic      string   `json:"topic"`
+	GroupIds   []string `json:"groupIds,omitempty"`   // 为 空 时 所有 在 该 Topic 上 提交 过 位置 的 组
+	Interval   int      `json:"interval,omitempty"`   // 采样 间隔 秒，默认 10，最小 2
+	MaxSamples int      `json:"maxSamples,omitempty"` // 保留 采样 数，默认 360
+}
+
+// LagMonitor 定时 采样 积压，用于 展示 趋势 与 消费 速率
+type LagMonitor struct {
+	Key string
+	*LagMonitorConfig
+	kafkaConfig *kafka.Config
+	sampler     *kafkalag.Sampler
+	client      sarama.Client
+	admin       sarama.ClusterAdmin
+	lastError   string
+	lastQuery   time.Time
+	isStopped   bool
+	done        chan struct{}
+	lock        sync.Mutex
+}
+
+type LagMonitorInfo struct {
+	Key       string             `json:"key"`
+	Topic     string             `json:"topic"`
+	Interval  int                `json:"interval"`
+	Samples   []*kafkalag.Sample `json:"samples"`
+	Trends    []*kafkalag.Trend  `json:"trends"`
+	LastError string             `json:"lastError,omitempty"`
+	IsStopped bool               `json:"isStopped"`
+}
+
+var (
+	lagMonitorCache     = map[string]*LagMonitor{}
+	lagMonitorCacheLock = &sync.Mutex{}
+)
+
+func getLagMonitor(key string) (monitor *LagMonitor) {
+	lagMonitorCacheLock.Lock()
+	defer lagMonitorCacheLock.Unlock()
+	monitor = lagMonitorCache[key]
+	return
+}
+
+func setLagMonitor(key string, monitor *LagMonitor) {
+	lagMonitorCacheLock.Lock()
+	defer lagMonitorCacheLock.Unlock()
+	lagMonitorCache[key] = monitor
+}
+
+func removeLagMonitor(key string) {
+	lagMonitorCacheLock.Lock()
+	defer lagMonitorCacheLock.Unlock()
+	delete(lagMonitorCache, key)
+}
+
+func startLagMonitor(config *LagMonitorConfig, kafkaConfig *kafka.Config) (monitor *LagMonitor, err error) {
+	if config.Topic == "" {
+		err = errors.New("topic is empty")
+		return
+	}
+	if config.Interval <= 0 {
+		config.Interval = defaultLagInterval
+	}
+	if config.Interval < minLagInterval {
+		config.Interval = minLagInterval
+	}
+	if config.MaxSamples <= 0 {
+		config.MaxSamples = defaultLagMaxSamples
+	}
+	monitor = &LagMonitor{
+		Key:              util.GetUUID(),
+		LagMonitorConfig: config,
+		kafkaConfig:      kafkaConfig,
+		sampler:          kafkalag.NewSampler(config.MaxSamples),
+		lastQuery:        time.Now(),
+		done:             make(chan struct{}),
+	}
+	if monitor.client, monitor.admin, err = newClusterAdmin(kafkaConfig); err != nil {
+		return
+	}
+	// 先 采样 一次，配置 错误 时 直接 返回
+	if err = monitor.sample(); err != nil {
+		_ = monitor.admin.Close()
+		return
+	}
+	setLagMonitor(monitor.Key, monitor)
+	go monitor.run()
+	return
+}
+
+func (this_ *LagMonitor) sample() (err error) {
+	sample, err := collectTopicLag(this_.client, this_.admin, this_.Topic, this_.GroupIds)
+	this_.lock.Lock()
+	defer this_.lock.Unlock()
+	if err != nil {
+		this_.lastError = err.Error()
+		return
+	}
+	this_.lastError = ""
+	this_.sampler.Add(sample)
+	return
+}
+
+func (this_ *LagMonitor) run() {
+	defer func() {
+		if e := recover(); e != nil {
+			util.Logger.Error("kafka lag monitor error", zap.Any("error", e))
+		}
+		this_.stop()
+	}()
+	ticker := time.NewTicker(time.Duration(this_.Interval) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-this_.done:
+			return
+		case <-ticker.C:
+			this_.lock.Lock()
+			idle := time.Since(this_.lastQuery) > lagMonitorIdleTime
+			this_.lock.Unlock()
+			if idle {
+				util.Logger.Info("kafka lag monitor idle stop", zap.Any("key", this_.Key), zap.Any("topic", this_.Topic))
+				return
+			}
+			if err := this_.sample(); err != nil {
+				util.Logger.Error("kafka lag monitor sample error", zap.Any("topic", this_.Topic), zap.Error(err))
+			}
+		}
+	}
+}
+
+func (this_ *LagMonitor) getInfo() (info *LagMonitorInfo) {
+	this_.lock.Lock()
+	this_.lastQuery = time.Now()
+	info = &LagMonitorInfo{
+		Key:       this_.Key,
+		Topic:     this_.Topic,
+		Interval:  this_.Interval,
+		LastError: this_.lastError,
+		IsStopped: this_.isStopped,
+	}
+	this_.lock.Unlock()
+	info.Samples = this_.sampler.Samples()
+	info.Trends = this_.sampler.Trends()
+	return
+}
+
+func (this_ *LagMonitor) stop() {
+	this_.lock.Lock()
+	if this_.isStopped {
+		this_.lock.Unlock()
+		return
+	}
+	this_.isStopped = true
+	close(this_.done)
+	this_.lock.Unlock()
+
+	removeLagMonitor(this_.Key)
+	if this_.admin != nil {
+		_ = this_.admin.Close()
+	}
+}
+
+// ResetPlanRequest 重置 预览、执行 参数，Partitions 为 空 时 所有 分区
+type ResetPlanRequest struct {
+	kafkalag.ResetOptions
+	GroupId    string  `json:"groupId"`
+	Topic      string  `json:"topic"`
+	Partitions []int32 `json:"partitions,omitempty"`
+}
+
+// ResetPlan 重置 预览，组 有 活跃 成员 时 不能 执行
+type ResetPlan struct {
+	GroupId    string               `json:"groupId"`
+	Topic      string               `json:"topic"`
+	State      string               `json:"state"`
+	Strategy   string               `json:"strategy"`
+	Executable bool                 `json:"executable"`
+	Warning    string               `json:"warning,omitempty"`
+	Items      []*kafkalag.PlanItem `json:"items"`
+	Executed   bool                 `json:"executed,omitempty"`
+}
+
+// planReset 计算 重置 预览，不 修改 任何 位置
+func planReset(kafkaConfig *kafka.Config, request *ResetPlanRequest) (plan *ResetPlan, err error) {
+	if request.GroupId == "" || request.Topic == "" {
+		err = errors.New("groupId、topic 不能为空")
+		return
+	}
+	if err = request.Check(); err != nil {
+		return
+	}
+	client, admin, err := newClusterAdmin(kafkaConfig)
+	if err != nil {
+		return
+	}
+	defer func() { _ = admin.Close() }()
+
+	offsets, err := getPartitionOffsets(client, request.Topic, request.Partitions)
+	if err != nil {
+		return
+	}
+	if _, err = fillCommitted(admin, request.GroupId, request.Topic, offsets); err != nil {
+		return
+	}
+	var timestampOffsets map[int32]int64
+	if request.Strategy == kafkalag.ResetToTimestamp {
+		timestampOffsets = map[int32]int64{}
+		for _, one := range offsets {
+			if timestampOffsets[one.Partition], err = client.GetOffset(request.Topic, one.Partition, request.Timestamp); err != nil {
+				return
+			}
+		}
+	}
+	items, err := kafkalag.PlanReset(&request.ResetOptions, offsets, timestampOffsets)
+	if err != nil {
+		return
+	}
+	states, err := getGroupStates(admin, []string{request.GroupId})
+	if err != nil {
+		return
+	}
+	plan = &ResetPlan{
+		GroupId:  request.GroupId,
+		Topic:    request.Topic,
+		State:    states[request.GroupId],
+		Strategy: request.Strategy,
+		Items:    items,
+	}
+	switch plan.State {
+	case "Empty", "Dead", "":
+		plan.Executable = true
+	default:
+		plan.Warning = "消费组[" + request.GroupId + "]状态为[" + plan.State + "]，需停止所有消费者后才能重置"
+	}
+	return
+}
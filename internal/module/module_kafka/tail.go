@@ -0,0 +1,502 @@
+package module_kafka
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Shopify/sarama"
+	"github.com/gorilla/websocket"
+	"github.com/team-ide/go-tool/kafka"
+	"github.com/team-ide/go-tool/util"
+	"go.uber.org/zap"
+	"os"
+	"strings"
+	"sync"
+	"teamide/pkg/msgfilter"
+	"teamide/pkg/wsattach"
+	"time"
+)
+
+const (
+	// TailStartLatest 从 最新 开始
+	TailStartLatest = "latest"
+	// TailStartEarliest 从 最早 开始
+	TailStartEarliest = "earliest"
+	// TailStartTimestamp 从 时间戳 开始
+	TailStartTimestamp = "timestamp"
+	// TailStartOffset 从 指定 分区 位置 开始
+	TailStartOffset = "offset"
+
+	defaultTailRate   = 200
+	tailStatsInterval = 2 * time.Second
+
+	// tailAttachTimeout 创建 后 超过 该 时间 未 连接 websocket 的 会话 停止
+	tailAttachTimeout = time.Minute
+)
+
+// TailConfig 跟踪 配置，使用 分区 消费者 直接 读取，不 加入 消费组，不 提交 位置
+type TailConfig struct {
+	Topic       string            `json:"topic"`
+	Partitions  []int32           `json:"partitions,omitempty"` // 为 空 时 所有 分区
+	StartFrom   string            `json:"startFrom,omitempty"`  // latest、earliest、timestamp、offset，默认 latest
+	Timestamp   int64             `json:"timestamp,omitempty"`  // 毫秒
+	Offsets     map[int32]int64   `json:"offsets,omitempty"`    // 分区 起始 位置，未 指定 的 分区 从 最新 开始
+	KeyType     string            `json:"keyType,omitempty"`    // 支持 avro、protobuf
+	ValueType   string            `json:"valueType,omitempty"`
+	KeySchema   *SchemaOption     `json:"keySchema,omitempty"`
+	ValueSchema *SchemaOption     `json:"valueSchema,omitempty"`
+	Filter      *msgfilter.Filter `json:"filter,omitempty"`
+	Rate        int               `json:"rate,omitempty"` // 每秒 最多 推送 消息 数，默认 200，超出 时 等待
+}
+
+// TailEvent 推送 到 websocket 的 事件
+type TailEvent struct {
+	Type      string            `json:"type"` // message、stats、error
+	Partition int32             `json:"partition,omitempty"`
+	Offset    int64             `json:"offset,omitempty"`
+	Key       string            `json:"key,omitempty"`
+	Value     string            `json:"value,omitempty"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Timestamp int64             `json:"timestamp,omitempty"`
+	Error     string            `json:"error,omitempty"`
+	Received  int64             `json:"received,omitempty"`
+	Matched   int64             `json:"matched,omitempty"`
+	Paused    bool              `json:"paused,omitempty"`
+}
+
+// tailAction websocket 收到 的 指令
+type tailAction struct {
+	Action string            `json:"action"` // pause、resume、filter
+	Filter *msgfilter.Filter `json:"filter,omitempty"`
+}
+
+var (
+	tailCache     = map[string]*TailService{}
+	tailCacheLock = &sync.Mutex{}
+)
+
+// getTailService 获取 用户 的 会话，不是 该 用户 的 返回 nil
+func getTailService(key string, userId int64) (service *TailService) {
+	tailCacheLock.Lock()
+	defer tailCacheLock.Unlock()
+	service = tailCache[key]
+	if service != nil && service.userId != userId {
+		service = nil
+	}
+	return
+}
+
+func removeTailService(key string) {
+	tailCacheLock.Lock()
+	defer tailCacheLock.Unlock()
+	delete(tailCache, key)
+}
+
+func setTailService(key string, service *TailService) {
+	tailCacheLock.Lock()
+	defer tailCacheLock.Unlock()
+	tailCache[key] = service
+}
+
+// newSaramaConfig 按 工具箱 配置 生成 客户端 配置，版本 按 Broker 探测
+func newSaramaConfig(kafkaConfig *kafka.Config) (config *sarama.Config, err error) {
+	config = sarama.NewConfig()
+	config.ClientID = "team-ide-tail"
+	config.Consumer.Return.Errors = true
+	if kafkaConfig.Username != "" {
+		config.Net.SASL.Enable = true
+		config.Net.SASL.User = kafkaConfig.Username
+		config.Net.SASL.Password = kafkaConfig.Password
+	}
+	if kafkaConfig.CertPath != "" {
+		var bs []byte
+		bs, err = os.ReadFile(kafkaConfig.CertPath)
+		if err != nil {
+			return
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(bs) {
+			err = errors.New("证书[" + kafkaConfig.CertPath + "]解析失败")
+			return
+		}
+		config.Net.TLS.Enable = true
+		config.Net.TLS.Config = &tls.Config{RootCAs: pool}
+	}
+	config.Version = getKafkaVersion(kafkaConfig.Address, config)
+	return
+}
+
+// TailService 一个 websocket 的 Topic 跟踪 会话，首次 连接 websocket 时 开始，当前 websocket 关闭 时 停止
+type TailService struct {
+	Key string
+	*TailConfig
+	kafkaConfig *kafka.Config
+	keyCodec    *schemaCodec
+	valueCodec  *schemaCodec
+	userId      int64
+
+	socket    *wsattach.Socket
+	client    sarama.Client
+	consumer  sarama.Consumer
+	consumers []sarama.PartitionConsumer
+	filter    *msgfilter.Filter
+	rate      int
+	sent      int
+	sentTime  time.Time
+	rateLock  sync.Mutex
+	received  int64
+	matched   int64
+	paused    bool
+	isStopped bool
+	done      chan struct{}
+	lock      sync.Mutex
+}
+
+func createTailService(config *TailConfig, kafkaConfig *kafka.Config, userId int64) (service *TailService, err error) {
+	if config.Topic == "" {
+		err = errors.New("topic is empty")
+		return
+	}
+	switch config.StartFrom {
+	case "":
+		config.StartFrom = TailStartLatest
+	case TailStartLatest, TailStartEarliest, TailStartTimestamp, TailStartOffset:
+	default:
+		err = errors.New("不支持的起始位置[" + config.StartFrom + "]，支持 latest、earliest、timestamp、offset")
+		return
+	}
+	for _, one := range []string{config.KeyType, config.ValueType} {
+		if isSchemaType(one) {
+			continue
+		}
+		if err = msgfilter.CheckDataType(one); err != nil {
+			return
+		}
+	}
+	service = &TailService{
+		Key:         util.GetUUID(),
+		TailConfig:  config,
+		kafkaConfig: kafkaConfig,
+		userId:      userId,
+		rate:        config.Rate,
+		done:        make(chan struct{}),
+	}
+	if service.rate <= 0 {
+		service.rate = defaultTailRate
+	}
+	if err = service.setFilter(config.Filter); err != nil {
+		return
+	}
+	service.socket = wsattach.New(tailAttachTimeout, func() {
+		util.Logger.Info("kafka tail attach timeout stop", zap.Any("key", service.Key))
+		service.stop()
+	})
+	return
+}
+
+func (this_ *TailService) setFilter(filter *msgfilter.Filter) (err error) {
+	if filter != nil {
+		if err = filter.Compile(); err != nil {
+			return
+		}
+	}
+	this_.lock.Lock()
+	this_.filter = filter
+	this_.lock.Unlock()
+	return
+}
+
+// getStartOffset 分区 的 起始 位置
+func (this_ *TailService) getStartOffset(partition int32) (offset int64, err error) {
+	switch this_.StartFrom {
+	case TailStartEarliest:
+		offset = sarama.OffsetOldest
+	case TailStartTimestamp:
+		offset, err = this_.client.GetOffset(this_.Topic, partition, this_.Timestamp)
+		if err == nil && offset < 0 {
+			offset = sarama.OffsetNewest
+		}
+	case TailStartOffset:
+		var ok bool
+		if offset, ok = this_.Offsets[partition]; !ok {
+			offset = sarama.OffsetNewest
+		}
+	default:
+		offset = sarama.OffsetNewest
+	}
+	return
+}
+
+// attach 连接 websocket，首次 连接 时 开始 读取，重复 连接 时 替换 之前 的 websocket
+func (this_ *TailService) attach(ws *websocket.Conn) (err error) {
+	first, err := this_.socket.Attach(ws)
+	if err != nil {
+		return
+	}
+	if first {
+		if err = this_.start(); err != nil {
+			return
+		}
+	}
+	go this_.startReadWS(ws)
+	return
+}
+
+// start 创建 客户端 并 从 各 分区 开始 读取，出错 时 由 调用方 stop 释放
+func (this_ *TailService) start() (err error) {
+	config, err := newSaramaConfig(this_.kafkaConfig)
+	if err != nil {
+		return
+	}
+	client, err := sarama.NewClient(strings.Split(this_.kafkaConfig.Address, ","), config)
+	if err != nil {
+		return
+	}
+	this_.lock.Lock()
+	if this_.isStopped {
+		this_.lock.Unlock()
+		_ = client.Close()
+		err = wsattach.ErrClosed
+		return
+	}
+	this_.client = client
+	this_.lock.Unlock()
+	this_.consumer, err = sarama.NewConsumerFromClient(this_.client)
+	if err != nil {
+		return
+	}
+	partitions := this_.Partitions
+	if len(partitions) == 0 {
+		partitions, err = this_.client.Partitions(this_.Topic)
+		if err != nil {
+			return
+		}
+	}
+	for _, partition := range partitions {
+		var offset int64
+		var partitionConsumer sarama.PartitionConsumer
+		offset, err = this_.getStartOffset(partition)
+		if err == nil {
+			partitionConsumer, err = this_.consumer.ConsumePartition(this_.Topic, partition, offset)
+		}
+		if err != nil {
+			err = fmt.Errorf("分区[%d]消费失败:%s", partition, err.Error())
+			return
+		}
+		this_.lock.Lock()
+		this_.consumers = append(this_.consumers, partitionConsumer)
+		this_.lock.Unlock()
+		go this_.startReadPartition(partitionConsumer)
+	}
+
+	go this_.startSendStats()
+	return
+}
+
+func (this_ *TailService) stop() {
+	this_.lock.Lock()
+	if this_.isStopped {
+		this_.lock.Unlock()
+		return
+	}
+	this_.isStopped = true
+	consumers := this_.consumers
+	client := this_.client
+	this_.lock.Unlock()
+
+	removeTailService(this_.Key)
+	close(this_.done)
+	for _, one := range consumers {
+		one.AsyncClose()
+	}
+	if this_.consumer != nil {
+		_ = this_.consumer.Close()
+	}
+	if client != nil {
+		_ = client.Close()
+	}
+	this_.socket.Close()
+}
+
+func (this_ *TailService) stopped() bool {
+	this_.lock.Lock()
+	defer this_.lock.Unlock()
+	return this_.isStopped
+}
+
+func (this_ *TailService) setPaused(paused bool) {
+	this_.lock.Lock()
+	this_.paused = paused
+	consumers := this_.consumers
+	this_.lock.Unlock()
+	for _, one := range consumers {
+		if paused {
+			one.Pause()
+		} else {
+			one.Resume()
+		}
+	}
+	this_.sendStats()
+}
+
+func (this_ *TailService) startReadPartition(partitionConsumer sarama.PartitionConsumer) {
+	defer func() {
+		if e := recover(); e != nil {
+			util.Logger.Error("kafka tail read partition panic error", zap.Any("error", e))
+		}
+	}()
+
+	messages := partitionConsumer.Messages()
+	errs := partitionConsumer.Errors()
+	for messages != nil || errs != nil {
+		select {
+		case msg, ok := <-messages:
+			if !ok {
+				messages = nil
+				continue
+			}
+			this_.onMessage(msg)
+		case consumerErr, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			this_.write(&TailEvent{Type: "error", Partition: consumerErr.Partition, Error: consumerErr.Error()})
+		}
+	}
+}
+
+func (this_ *TailService) onMessage(msg *sarama.ConsumerMessage) {
+	event := &TailEvent{
+		Type:      "message",
+		Partition: msg.Partition,
+		Offset:    msg.Offset,
+		Headers:   map[string]string{},
+		Timestamp: msg.Timestamp.UnixMilli(),
+	}
+	for _, one := range msg.Headers {
+		if one != nil {
+			event.Headers[string(one.Key)] = string(one.Value)
+		}
+	}
+	var decodeErrors []string
+	var err error
+	if event.Key, err = decodeData(msg.Key, this_.KeyType, this_.keyCodec); err != nil {
+		event.Key = string(msg.Key)
+		decodeErrors = append(decodeErrors, "key:"+err.Error())
+	}
+	if event.Value, err = decodeData(msg.Value, this_.ValueType, this_.valueCodec); err != nil {
+		event.Value = string(msg.Value)
+		decodeErrors = append(decodeErrors, "value:"+err.Error())
+	}
+	event.Error = strings.Join(decodeErrors, ";")
+
+	this_.lock.Lock()
+	this_.received++
+	filter := this_.filter
+	this_.lock.Unlock()
+	if filter != nil && !filter.Match(&msgfilter.Message{Key: event.Key, Value: event.Value, Headers: event.Headers}) {
+		return
+	}
+	this_.lock.Lock()
+	this_.matched++
+	this_.lock.Unlock()
+
+	this_.waitRate()
+	this_.write(event)
+}
+
+// waitRate 超过 每秒 推送 数 时 等待 到 下一秒，分区 消费者 随之 阻塞，不 丢弃 消息
+func (this_ *TailService) waitRate() {
+	this_.rateLock.Lock()
+	defer this_.rateLock.Unlock()
+	now := time.Now()
+	if now.Sub(this_.sentTime) >= time.Second {
+		this_.sentTime = now
+		this_.sent = 0
+	}
+	if this_.sent >= this_.rate {
+		wait := time.Second - now.Sub(this_.sentTime)
+		select {
+		case <-this_.done:
+		case <-time.After(wait):
+		}
+		this_.sentTime = time.Now()
+		this_.sent = 0
+	}
+	this_.sent++
+}
+
+func (this_ *TailService) write(event *TailEvent) {
+	if this_.stopped() {
+		return
+	}
+	bs, _ := json.Marshal(event)
+	ws, err := this_.socket.Write(bs)
+	if err != nil && !this_.stopped() && this_.socket.Current(ws) {
+		util.Logger.Error("kafka tail ws write error", zap.Error(err))
+		this_.stop()
+	}
+}
+
+func (this_ *TailService) sendStats() {
+	this_.lock.Lock()
+	event := &TailEvent{
+		Type:     "stats",
+		Received: this_.received,
+		Matched:  this_.matched,
+		Paused:   this_.paused,
+	}
+	this_.lock.Unlock()
+	this_.write(event)
+}
+
+func (this_ *TailService) startSendStats() {
+	ticker := time.NewTicker(tailStatsInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-this_.done:
+			return
+		case <-ticker.C:
+			this_.sendStats()
+		}
+	}
+}
+
+// startReadWS 读取 websocket 指令，websocket 关闭 时 停止 会话，已 被 新 连接 替换 的 不 停止
+func (this_ *TailService) startReadWS(ws *websocket.Conn) {
+	defer func() {
+		if e := recover(); e != nil {
+			util.Logger.Error("kafka tail read ws panic error", zap.Any("error", e))
+		}
+		if this_.socket.Current(ws) {
+			this_.stop()
+		}
+	}()
+
+	for {
+		_, buf, err := ws.ReadMessage()
+		if err != nil {
+			return
+		}
+		action := &tailAction{}
+		if err = json.Unmarshal(buf, action); err == nil {
+			switch action.Action {
+			case "pause":
+				this_.setPaused(true)
+			case "resume":
+				this_.setPaused(false)
+			case "filter":
+				err = this_.setFilter(action.Filter)
+			default:
+				err = errors.New("不支持的指令[" + action.Action + "]")
+			}
+		}
+		if err != nil {
+			this_.write(&TailEvent{Type: "error", Error: err.Error()})
+		}
+	}
+}
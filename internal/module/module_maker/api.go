@@ -38,6 +38,16 @@ var (
 	rename       = base.AppendPower(&base.PowerAction{Action: "rename", Text: "rename", ShouldLogin: true, StandAlone: true, Parent: Power})
 	gen          = base.AppendPower(&base.PowerAction{Action: "gen", Text: "gen", ShouldLogin: true, StandAlone: true, Parent: Power})
 	closePower   = base.AppendPower(&base.PowerAction{Action: "close", Text: "关闭", ShouldLogin: true, StandAlone: true, Parent: Power})
+
+	debugStart       = base.AppendPower(&base.PowerAction{Action: "debugStart", Text: "调试启动", ShouldLogin: true, StandAlone: true, Parent: Power})
+	debugBreakpoints = base.AppendPower(&base.PowerAction{Action: "debugBreakpoints", Text: "调试断点", ShouldLogin: true, StandAlone: true, Parent: Power})
+	debugResume      = base.AppendPower(&base.PowerAction{Action: "debugResume", Text: "调试继续", ShouldLogin: true, StandAlone: true, Parent: Power})
+	debugEvaluate    = base.AppendPower(&base.PowerAction{Action: "debugEvaluate", Text: "调试求值", ShouldLogin: true, StandAlone: true, Parent: Power})
+	debugStatus      = base.AppendPower(&base.PowerAction{Action: "debugStatus", Text: "调试状态", ShouldLogin: true, StandAlone: true, Parent: Power})
+	debugStop        = base.AppendPower(&base.PowerAction{Action: "debugStop", Text: "调试停止", ShouldLogin: true, StandAlone: true, Parent: Power})
+	testGet          = base.AppendPower(&base.PowerAction{Action: "testGet", Text: "测试用例查询", ShouldLogin: true, StandAlone: true, Parent: Power})
+	testSave         = base.AppendPower(&base.PowerAction{Action: "testSave", Text: "测试用例保存", ShouldLogin: true, StandAlone: true, Parent: Power})
+	testRun          = base.AppendPower(&base.PowerAction{Action: "testRun", Text: "测试用例执行", ShouldLogin: true, StandAlone: true, Parent: Power})
 )
 
 func (this_ *api) GetApis() (apis []*base.ApiWorker) {
@@ -50,6 +60,15 @@ func (this_ *api) GetApis() (apis []*base.ApiWorker) {
 	apis = append(apis, &base.ApiWorker{Power: rename, Do: this_.rename})
 	apis = append(apis, &base.ApiWorker{Power: gen, Do: this_.gen})
 	apis = append(apis, &base.ApiWorker{Power: closePower, Do: this_.close})
+	apis = append(apis, &base.ApiWorker{Power: debugStart, Do: this_.debugStart})
+	apis = append(apis, &base.ApiWorker{Power: debugBreakpoints, Do: this_.debugBreakpoints})
+	apis = append(apis, &base.ApiWorker{Power: debugResume, Do: this_.debugResume})
+	apis = append(apis, &base.ApiWorker{Power: debugEvaluate, Do: this_.debugEvaluate})
+	apis = append(apis, &base.ApiWorker{Power: debugStatus, Do: this_.debugStatus})
+	apis = append(apis, &base.ApiWorker{Power: debugStop, Do: this_.debugStop})
+	apis = append(apis, &base.ApiWorker{Power: testGet, Do: this_.testGet})
+	apis = append(apis, &base.ApiWorker{Power: testSave, Do: this_.testSave})
+	apis = append(apis, &base.ApiWorker{Power: testRun, Do: this_.testRun})
 
 	return
 }
@@ -105,6 +124,16 @@ type Request struct {
 	NewModelName string      `json:"newModelName"`
 	Model        interface{} `json:"model"`
 	IsPack       bool        `json:"isPack"`
+
+	DebugId     string                 `json:"debugId"`
+	Args        map[string]interface{} `json:"args"`
+	Mocks       []*maker.ComponentMock `json:"mocks"`
+	Breakpoints map[string][]int       `json:"breakpoints"`
+	Lines       []int                  `json:"lines"`
+	Action      string                 `json:"action"`
+	Expression  string                 `json:"expression"`
+	ModelNames  []string               `json:"modelNames"`
+	Test        *maker.ModelTest       `json:"test"`
 }
 
 func (this_ *api) context(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
@@ -339,3 +368,231 @@ func (this_ *api) close(_ *base.RequestBean, c *gin.Context) (res interface{}, e
 
 	return
 }
+
+func (this_ *api) debugStart(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	service, err := this_.getService(requestBean, c)
+	if err != nil {
+		return
+	}
+
+	request := &Request{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+
+	if request.ModelType == "" || request.ModelName == "" {
+		err = errors.New("参数丢失")
+		return
+	}
+	modelType := modelers.GetModelType(request.ModelType)
+	if modelType == nil {
+		err = errors.New("model type [" + request.ModelType + "] is error")
+		return
+	}
+	if modelType != modelers.TypeService && modelType != modelers.TypeFunc {
+		err = errors.New("暂不支持 [" + modelType.Comment + "] 调试")
+		return
+	}
+
+	res = service.startDebug(requestBean.ClientTabKey, modelType, request.ModelName, request.Args, request.Mocks, request.Breakpoints)
+	return
+}
+
+func (this_ *api) debugBreakpoints(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	service, err := this_.getService(requestBean, c)
+	if err != nil {
+		return
+	}
+
+	request := &Request{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+
+	session, err := service.getDebug(request.DebugId)
+	if err != nil {
+		return
+	}
+	if request.Key != "" {
+		session.debugger.SetBreakpoints(request.Key, request.Lines)
+	}
+	res = session.debugger.GetBreakpoints()
+	return
+}
+
+func (this_ *api) debugResume(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	service, err := this_.getService(requestBean, c)
+	if err != nil {
+		return
+	}
+
+	request := &Request{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+
+	session, err := service.getDebug(request.DebugId)
+	if err != nil {
+		return
+	}
+	err = session.debugger.Resume(request.Action)
+	return
+}
+
+func (this_ *api) debugEvaluate(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	service, err := this_.getService(requestBean, c)
+	if err != nil {
+		return
+	}
+
+	request := &Request{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+
+	session, err := service.getDebug(request.DebugId)
+	if err != nil {
+		return
+	}
+	res, err = session.debugger.Evaluate(request.Expression)
+	return
+}
+
+func (this_ *api) debugStatus(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	service, err := this_.getService(requestBean, c)
+	if err != nil {
+		return
+	}
+
+	request := &Request{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+
+	session, err := service.getDebug(request.DebugId)
+	if err != nil {
+		return
+	}
+	data := make(map[string]interface{})
+	data["debugId"] = session.DebugId
+	data["key"] = session.Key
+	data["pause"] = session.debugger.GetPaused()
+	data["breakpoints"] = session.debugger.GetBreakpoints()
+	res = data
+	return
+}
+
+func (this_ *api) debugStop(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	service, err := this_.getService(requestBean, c)
+	if err != nil {
+		return
+	}
+
+	request := &Request{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+
+	session, err := service.getDebug(request.DebugId)
+	if err != nil {
+		return
+	}
+	session.debugger.Stop()
+	return
+}
+
+func (this_ *api) testGet(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	service, err := this_.getService(requestBean, c)
+	if err != nil {
+		return
+	}
+
+	request := &Request{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+
+	if request.ModelType == "" || request.ModelName == "" {
+		err = errors.New("参数丢失")
+		return
+	}
+	modelType := modelers.GetModelType(request.ModelType)
+	if modelType == nil {
+		err = errors.New("model type [" + request.ModelType + "] is error")
+		return
+	}
+
+	res, err = service.app.GetModelTest(modelType, request.ModelName)
+	return
+}
+
+func (this_ *api) testSave(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	service, err := this_.getService(requestBean, c)
+	if err != nil {
+		return
+	}
+
+	request := &Request{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+
+	if request.ModelType == "" || request.ModelName == "" {
+		err = errors.New("参数丢失")
+		return
+	}
+	modelType := modelers.GetModelType(request.ModelType)
+	if modelType == nil {
+		err = errors.New("model type [" + request.ModelType + "] is error")
+		return
+	}
+
+	err = service.app.SaveModelTest(modelType, request.ModelName, request.Test)
+	return
+}
+
+func (this_ *api) testRun(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	service, err := this_.getService(requestBean, c)
+	if err != nil {
+		return
+	}
+
+	request := &Request{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+
+	if request.ModelType == "" {
+		err = errors.New("参数丢失")
+		return
+	}
+	modelType := modelers.GetModelType(request.ModelType)
+	if modelType == nil {
+		err = errors.New("model type [" + request.ModelType + "] is error")
+		return
+	}
+	var modelNames = request.ModelNames
+	if request.ModelName != "" {
+		modelNames = append(modelNames, request.ModelName)
+	}
+
+	// 重新加载模型，避免 测试用例 影响其它调用
+	results, err := maker.RunModelTests(maker.Load(service.Dir), modelType, modelNames)
+	if err != nil {
+		return
+	}
+	data := make(map[string]interface{})
+	var pass, fail int
+	for _, one := range results {
+		pass += one.Pass
+		fail += one.Fail
+		if one.Error != "" {
+			fail++
+		}
+	}
+	data["pass"] = pass
+	data["fail"] = fail
+	data["results"] = results
+	res = data
+	return
+}
@@ -2,7 +2,12 @@ package module_maker
 
 import (
 	"errors"
+	"github.com/team-ide/go-tool/util"
+	"go.uber.org/zap"
+	"sync"
+	"teamide/internal/context"
 	"teamide/pkg/maker"
+	"teamide/pkg/maker/modelers"
 )
 
 type Config struct {
@@ -11,7 +16,9 @@ type Config struct {
 
 func createService(config *Config) (service *Service, err error) {
 	service = &Service{
-		Config: config,
+		Config:      config,
+		debugCache:  make(map[string]*DebugSession),
+		debugLocker: &sync.Mutex{},
 	}
 	err = service.init()
 	return
@@ -19,8 +26,16 @@ func createService(config *Config) (service *Service, err error) {
 
 type Service struct {
 	*Config
-	app       *maker.Application
-	isStopped bool
+	app         *maker.Application
+	isStopped   bool
+	debugCache  map[string]*DebugSession
+	debugLocker sync.Locker
+}
+
+type DebugSession struct {
+	DebugId  string `json:"debugId"`
+	Key      string `json:"key"`
+	debugger *maker.Debugger
 }
 
 func (this_ *Service) init() (err error) {
@@ -32,7 +47,87 @@ func (this_ *Service) init() (err error) {
 	return
 }
 
+func (this_ *Service) getDebug(debugId string) (session *DebugSession, err error) {
+	this_.debugLocker.Lock()
+	defer this_.debugLocker.Unlock()
+
+	session = this_.debugCache[debugId]
+	if session == nil {
+		err = errors.New("debug [" + debugId + "] not found")
+		return
+	}
+	return
+}
+
+func (this_ *Service) removeDebug(debugId string) {
+	this_.debugLocker.Lock()
+	defer this_.debugLocker.Unlock()
+
+	delete(this_.debugCache, debugId)
+}
+
+// startDebug 启动调试，重新加载模型，避免 调试脚本 影响其它调用，暂停、结束 通过 clientTabKey 事件通知
+func (this_ *Service) startDebug(clientTabKey string, modelType *modelers.Type, modelName string, args map[string]interface{}, mocks []*maker.ComponentMock, breakpoints map[string][]int) (session *DebugSession) {
+	debugId := util.GetUUID()
+	debugger := maker.NewDebugger(func(pause *maker.DebugPause) {
+		listen := context.NewListenEvent("maker-debug-pause", map[string]interface{}{
+			"debugId": debugId,
+			"pause":   pause,
+		})
+		context.CallClientTabKeyEvent(clientTabKey, listen)
+	})
+	for key, lines := range breakpoints {
+		debugger.SetBreakpoints(key, lines)
+	}
+	session = &DebugSession{
+		DebugId:  debugId,
+		Key:      modelType.Name + "/" + modelName,
+		debugger: debugger,
+	}
+	this_.debugLocker.Lock()
+	this_.debugCache[debugId] = session
+	this_.debugLocker.Unlock()
+
+	go func() {
+		defer this_.removeDebug(debugId)
+		data := map[string]interface{}{
+			"debugId": debugId,
+		}
+		defer func() {
+			if e := recover(); e != nil {
+				util.Logger.Error("maker debug error", zap.Any("key", session.Key), zap.Any("error", e))
+			}
+			listen := context.NewListenEvent("maker-debug-end", data)
+			context.CallClientTabKeyEvent(clientTabKey, listen)
+		}()
+
+		app := maker.Load(this_.Dir)
+		res, recorder, err := maker.InvokeModel(app, modelType, modelName, args, &maker.InvokerOptions{
+			Debugger: debugger,
+			Mocks:    mocks,
+		})
+		data["result"] = res
+		if recorder != nil {
+			data["calls"] = recorder.GetCalls()
+		}
+		if err != nil {
+			data["error"] = err.Error()
+		}
+	}()
+	return
+}
+
 func (this_ *Service) Close() {
 	this_.isStopped = true
+
+	this_.debugLocker.Lock()
+	var list []*DebugSession
+	for _, one := range this_.debugCache {
+		list = append(list, one)
+	}
+	this_.debugLocker.Unlock()
+	for _, one := range list {
+		one.debugger.Stop()
+	}
 	return
 }
@@ -0,0 +1,257 @@
+package module_mongodb
+
+import (
+	"context"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/team-ide/go-tool/util"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+	"reflect"
+	"teamide/pkg/base"
+	"teamide/pkg/mongoquery"
+	"time"
+)
+
+type AggregateRequest struct {
+	BaseRequest
+	Pipeline string `json:"pipeline"`
+	Timeout  int64  `json:"timeout"` // 秒，默认 60
+	Explain  bool   `json:"explain"`
+	Total    bool   `json:"total"`
+}
+
+func (this_ *AggregateRequest) getTimeout() time.Duration {
+	if this_.Timeout <= 0 {
+		return 60 * time.Second
+	}
+	return time.Duration(this_.Timeout) * time.Second
+}
+
+type AggregateResult struct {
+	PageIndex int64          `json:"pageIndex"`
+	PageSize  int64          `json:"pageSize"`
+	Total     int64          `json:"total"` // 未 统计 时 为 -1
+	List      []interface{}  `json:"list"`
+	Written   bool           `json:"written,omitempty"`
+	Explain   *ExplainResult `json:"explain,omitempty"`
+}
+
+type ExplainResult struct {
+	Summary *mongoquery.ExplainSummary `json:"summary"`
+	Explain map[string]interface{}     `json:"explain"`
+}
+
+// formatDoc 与 queryPage 返回 格式 一致，value 为 Relaxed Extended JSON
+func formatDoc(doc bson.M) (d map[string]interface{}, err error) {
+	d = map[string]interface{}{}
+	bs, err := bson.MarshalExtJSONIndent(doc, false, false, "", "  ")
+	if err != nil {
+		return
+	}
+	if _id, ok := doc["_id"]; ok && _id != nil {
+		typeName := reflect.TypeOf(_id).Name()
+		if id, isObjectID := _id.(primitive.ObjectID); isObjectID {
+			d["_id"] = id.Hex()
+		} else {
+			d["_id"] = util.GetStringValue(_id)
+		}
+		d["_id_type"] = typeName
+	}
+	d["value"] = string(bs)
+	return
+}
+
+// runExplain 执行 explain 命令，写入 集合 的 管道 只 支持 queryPlanner
+func runExplain(ctx context.Context, db *mongo.Database, command bson.D, verbosity string) (res *ExplainResult, err error) {
+	explain, err := runCommand(ctx, db, bson.D{
+		{Key: "explain", Value: command},
+		{Key: "verbosity", Value: verbosity},
+	})
+	if err != nil {
+		return
+	}
+	res = &ExplainResult{
+		Summary: mongoquery.SummarizeExplain(explain),
+		Explain: explain,
+	}
+	return
+}
+
+func explainAggregate(ctx context.Context, db *mongo.Database, collectionName string, pipeline []bson.D, timeout time.Duration) (res *ExplainResult, err error) {
+	verbosity := "executionStats"
+	if mongoquery.IsWritePipeline(pipeline) {
+		verbosity = "queryPlanner"
+	}
+	res, err = runExplain(ctx, db, bson.D{
+		{Key: "aggregate", Value: collectionName},
+		{Key: "pipeline", Value: pipeline},
+		{Key: "cursor", Value: bson.D{}},
+		{Key: "allowDiskUse", Value: true},
+		{Key: "maxTimeMS", Value: timeout.Milliseconds()},
+	}, verbosity)
+	return
+}
+
+// aggregate 执行 聚合 管道 并 分页，管道 以 $out、$merge 结尾 时 直接 执行 不 分页
+func (this_ *api) aggregate(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	config, err := this_.getConfig(requestBean, c)
+	if err != nil {
+		return
+	}
+	client, err := getClient(config)
+	if err != nil {
+		return
+	}
+
+	request := &AggregateRequest{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+	if request.DatabaseName == "" || request.CollectionName == "" {
+		err = errors.New("database or collection is empty")
+		return
+	}
+	pipeline, err := mongoquery.ParsePipeline(request.Pipeline)
+	if err != nil {
+		return
+	}
+	if request.PageSize <= 0 {
+		request.PageSize = 20
+	}
+	if request.PageIndex <= 0 {
+		request.PageIndex = 1
+	}
+
+	timeout := request.getTimeout()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	db := client.Database(request.DatabaseName)
+	collection := db.Collection(request.CollectionName)
+	opts := options.Aggregate().SetAllowDiskUse(true).SetMaxTime(timeout)
+
+	result := &AggregateResult{
+		PageIndex: request.PageIndex,
+		PageSize:  request.PageSize,
+		Total:     -1,
+		List:      []interface{}{},
+	}
+	if request.Explain {
+		result.Explain, err = explainAggregate(ctx, db, request.CollectionName, pipeline, timeout)
+		if err != nil {
+			return
+		}
+	}
+
+	if mongoquery.IsWritePipeline(pipeline) {
+		var cursor *mongo.Cursor
+		cursor, err = collection.Aggregate(ctx, pipeline, opts)
+		if err != nil {
+			util.Logger.Error("mongodb aggregate error", zap.Any("collection", request.CollectionName), zap.Error(err))
+			return
+		}
+		_ = cursor.Close(ctx)
+		result.Written = true
+		res = result
+		return
+	}
+
+	cursor, err := collection.Aggregate(ctx, mongoquery.PagePipeline(pipeline, request.PageIndex, request.PageSize), opts)
+	if err != nil {
+		util.Logger.Error("mongodb aggregate error", zap.Any("collection", request.CollectionName), zap.Error(err))
+		return
+	}
+	defer func() { _ = cursor.Close(ctx) }()
+	for cursor.Next(ctx) {
+		doc := bson.M{}
+		if err = cursor.Decode(&doc); err != nil {
+			return
+		}
+		var d map[string]interface{}
+		if d, err = formatDoc(doc); err != nil {
+			return
+		}
+		result.List = append(result.List, d)
+	}
+	if err = cursor.Err(); err != nil {
+		return
+	}
+
+	if request.Total {
+		var countCursor *mongo.Cursor
+		countCursor, err = collection.Aggregate(ctx, mongoquery.CountPipeline(pipeline), opts)
+		if err != nil {
+			return
+		}
+		defer func() { _ = countCursor.Close(ctx) }()
+		result.Total = 0
+		if countCursor.Next(ctx) {
+			result.Total = countCursor.Current.Lookup("total").AsInt64()
+		}
+		if err = countCursor.Err(); err != nil {
+			return
+		}
+	}
+
+	res = result
+	return
+}
+
+// explain 返回 executionStats，带 pipeline 时 为 聚合，否则 为 与 queryPage 相同 条件 的 find
+func (this_ *api) explain(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	config, err := this_.getConfig(requestBean, c)
+	if err != nil {
+		return
+	}
+	client, err := getClient(config)
+	if err != nil {
+		return
+	}
+
+	request := &AggregateRequest{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+	if request.DatabaseName == "" || request.CollectionName == "" {
+		err = errors.New("database or collection is empty")
+		return
+	}
+
+	timeout := request.getTimeout()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	db := client.Database(request.DatabaseName)
+
+	if request.Pipeline != "" {
+		var pipeline []bson.D
+		pipeline, err = mongoquery.ParsePipeline(request.Pipeline)
+		if err != nil {
+			return
+		}
+		res, err = explainAggregate(ctx, db, request.CollectionName, pipeline, timeout)
+		return
+	}
+
+	filter, err := request.getFilter()
+	if err != nil {
+		return
+	}
+	command := bson.D{
+		{Key: "find", Value: request.CollectionName},
+		{Key: "filter", Value: filter},
+		{Key: "sort", Value: request.getSort()},
+		{Key: "maxTimeMS", Value: timeout.Milliseconds()},
+	}
+	if request.PageSize > 0 {
+		if request.PageIndex > 1 {
+			command = append(command, bson.E{Key: "skip", Value: (request.PageIndex - 1) * request.PageSize})
+		}
+		command = append(command, bson.E{Key: "limit", Value: request.PageSize})
+	}
+	res, err = runExplain(ctx, db, command, "executionStats")
+	return
+}
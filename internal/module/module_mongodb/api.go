@@ -53,6 +53,33 @@ var (
 	delete_    = base.AppendPower(&base.PowerAction{Action: "delete", Text: "删除", ShouldLogin: true, StandAlone: true, Parent: Power})
 	deleteById = base.AppendPower(&base.PowerAction{Action: "deleteById", Text: "删除", ShouldLogin: true, StandAlone: true, Parent: Power})
 	queryPage  = base.AppendPower(&base.PowerAction{Action: "queryPage", Text: "分页查询", ShouldLogin: true, StandAlone: true, Parent: Power})
+	aggregate  = base.AppendPower(&base.PowerAction{Action: "aggregate", Text: "聚合", ShouldLogin: true, StandAlone: true, Parent: Power})
+	explain    = base.AppendPower(&base.PowerAction{Action: "explain", Text: "执行计划", ShouldLogin: true, StandAlone: true, Parent: Power})
+
+	importPower         = base.AppendPower(&base.PowerAction{Action: "import", Text: "导入", ShouldLogin: true, StandAlone: true, Parent: Power})
+	exportPower         = base.AppendPower(&base.PowerAction{Action: "export", Text: "导出", ShouldLogin: true, StandAlone: true, Parent: Power})
+	exportDownloadPower = base.AppendPower(&base.PowerAction{Action: "exportDownload", Text: "导出下载", ShouldLogin: true, StandAlone: true, Parent: Power})
+	taskListPower       = base.AppendPower(&base.PowerAction{Action: "taskList", Text: "任务列表", ShouldLogin: true, StandAlone: true, Parent: Power})
+	taskStatusPower     = base.AppendPower(&base.PowerAction{Action: "taskStatus", Text: "任务状态", ShouldLogin: true, StandAlone: true, Parent: Power})
+	taskStopPower       = base.AppendPower(&base.PowerAction{Action: "taskStop", Text: "任务停止", ShouldLogin: true, StandAlone: true, Parent: Power})
+	taskCleanPower      = base.AppendPower(&base.PowerAction{Action: "taskClean", Text: "任务清理", ShouldLogin: true, StandAlone: true, Parent: Power})
+
+	replSetStatusPower  = base.AppendPower(&base.PowerAction{Action: "replSetStatus", Text: "副本集状态", ShouldLogin: true, StandAlone: true, Parent: Power})
+	shardingStatusPower = base.AppendPower(&base.PowerAction{Action: "shardingStatus", Text: "分片状态", ShouldLogin: true, StandAlone: true, Parent: Power})
+	currentOpPower      = base.AppendPower(&base.PowerAction{Action: "currentOp", Text: "当前操作", ShouldLogin: true, StandAlone: true, Parent: Power})
+	killOpPower         = base.AppendPower(&base.PowerAction{Action: "killOp", Text: "终止操作", ShouldLogin: true, StandAlone: true, Parent: Power})
+
+	user       = base.AppendPower(&base.PowerAction{Action: "user", Text: "用户", ShouldLogin: true, StandAlone: true, Parent: Power})
+	userList   = base.AppendPower(&base.PowerAction{Action: "list", Text: "列表", ShouldLogin: true, StandAlone: true, Parent: user})
+	userCreate = base.AppendPower(&base.PowerAction{Action: "create", Text: "创建", ShouldLogin: true, StandAlone: true, Parent: user})
+	userUpdate = base.AppendPower(&base.PowerAction{Action: "update", Text: "修改", ShouldLogin: true, StandAlone: true, Parent: user})
+	userDelete = base.AppendPower(&base.PowerAction{Action: "delete", Text: "删除", ShouldLogin: true, StandAlone: true, Parent: user})
+
+	role       = base.AppendPower(&base.PowerAction{Action: "role", Text: "角色", ShouldLogin: true, StandAlone: true, Parent: Power})
+	roleList   = base.AppendPower(&base.PowerAction{Action: "list", Text: "列表", ShouldLogin: true, StandAlone: true, Parent: role})
+	roleCreate = base.AppendPower(&base.PowerAction{Action: "create", Text: "创建", ShouldLogin: true, StandAlone: true, Parent: role})
+	roleUpdate = base.AppendPower(&base.PowerAction{Action: "update", Text: "修改", ShouldLogin: true, StandAlone: true, Parent: role})
+	roleDelete = base.AppendPower(&base.PowerAction{Action: "delete", Text: "删除", ShouldLogin: true, StandAlone: true, Parent: role})
 
 	closePower = base.AppendPower(&base.PowerAction{Action: "close", Text: "关闭", ShouldLogin: true, StandAlone: true, Parent: Power})
 )
@@ -79,6 +106,31 @@ func (this_ *api) GetApis() (apis []*base.ApiWorker) {
 	apis = append(apis, &base.ApiWorker{Power: delete_, Do: this_.delete})
 	apis = append(apis, &base.ApiWorker{Power: deleteById, Do: this_.deleteById})
 	apis = append(apis, &base.ApiWorker{Power: queryPage, Do: this_.queryPage})
+	apis = append(apis, &base.ApiWorker{Power: aggregate, Do: this_.aggregate})
+	apis = append(apis, &base.ApiWorker{Power: explain, Do: this_.explain})
+
+	apis = append(apis, &base.ApiWorker{Power: importPower, Do: this_._import})
+	apis = append(apis, &base.ApiWorker{Power: exportPower, Do: this_.export})
+	apis = append(apis, &base.ApiWorker{Power: exportDownloadPower, Do: this_.exportDownload})
+	apis = append(apis, &base.ApiWorker{Power: taskListPower, Do: this_.taskList})
+	apis = append(apis, &base.ApiWorker{Power: taskStatusPower, Do: this_.taskStatus, NotRecodeLog: true})
+	apis = append(apis, &base.ApiWorker{Power: taskStopPower, Do: this_.taskStop})
+	apis = append(apis, &base.ApiWorker{Power: taskCleanPower, Do: this_.taskClean})
+
+	apis = append(apis, &base.ApiWorker{Power: replSetStatusPower, Do: this_.replSetStatus, NotRecodeLog: true})
+	apis = append(apis, &base.ApiWorker{Power: shardingStatusPower, Do: this_.shardingStatus, NotRecodeLog: true})
+	apis = append(apis, &base.ApiWorker{Power: currentOpPower, Do: this_.currentOp, NotRecodeLog: true})
+	apis = append(apis, &base.ApiWorker{Power: killOpPower, Do: this_.killOp})
+
+	apis = append(apis, &base.ApiWorker{Power: userList, Do: this_.users})
+	apis = append(apis, &base.ApiWorker{Power: userCreate, Do: this_.userCreate, NotRecodeLog: true})
+	apis = append(apis, &base.ApiWorker{Power: userUpdate, Do: this_.userUpdate, NotRecodeLog: true})
+	apis = append(apis, &base.ApiWorker{Power: userDelete, Do: this_.userDelete})
+
+	apis = append(apis, &base.ApiWorker{Power: roleList, Do: this_.roles})
+	apis = append(apis, &base.ApiWorker{Power: roleCreate, Do: this_.roleCreate})
+	apis = append(apis, &base.ApiWorker{Power: roleUpdate, Do: this_.roleUpdate})
+	apis = append(apis, &base.ApiWorker{Power: roleDelete, Do: this_.roleDelete})
 
 	apis = append(apis, &base.ApiWorker{Power: closePower, Do: this_.close})
 
@@ -94,8 +146,8 @@ func (this_ *api) getConfig(requestBean *base.RequestBean, c *gin.Context) (conf
 	return
 }
 
-func getService(config *mongodb.Config) (res mongodb.IService, err error) {
-	key := "mongodb-" + config.Address
+func getServiceKey(config *mongodb.Config) (key string) {
+	key = "mongodb-" + config.Address
 	if config.Username != "" {
 		key += "-" + base.GetMd5String(key+config.Username)
 	}
@@ -105,6 +157,11 @@ func getService(config *mongodb.Config) (res mongodb.IService, err error) {
 	if config.CertPath != "" {
 		key += "-" + base.GetMd5String(key+config.CertPath)
 	}
+	return
+}
+
+func getService(config *mongodb.Config) (res mongodb.IService, err error) {
+	key := getServiceKey(config)
 
 	var serviceInfo *base.ServiceInfo
 	serviceInfo, err = base.GetService(key, func() (res *base.ServiceInfo, err error) {
@@ -160,6 +217,7 @@ type BaseRequest struct {
 	ObjectIDKey        string                 `json:"objectIDKey"`
 	IndexType          string                 `json:"indexType"`
 	ExpireAfterSeconds int32                  `json:"expireAfterSeconds"`
+	TaskId             string                 `json:"taskId"`
 }
 
 type Where struct {
@@ -192,7 +250,11 @@ func (this_ *api) check(requestBean *base.RequestBean, c *gin.Context) (res inte
 }
 
 func (this_ *api) close(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
-
+	var request = &BaseRequest{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+	removeWorkerTasks(request.WorkerId, requestBean.JWT.UserId)
 	return
 }
 func (this_ *api) info(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
@@ -554,36 +616,12 @@ func getNumberValue(s string) (interface{}, error) {
 	}
 	return n.Int64()
 }
-func (this_ *api) queryPage(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
-	config, err := this_.getConfig(requestBean, c)
-	if err != nil {
-		return
-	}
-	service, err := getService(config)
-	if err != nil {
-		return
-	}
 
-	request := &BaseRequest{}
-	if !base.RequestJSON(request, c) {
-		return
-	}
-
-	if request.IsObjectID && request.ObjectIDKey != "" && request.Filter[request.ObjectIDKey] != nil {
-		v, e := primitive.ObjectIDFromHex(util.GetStringValue(request.Filter[request.ObjectIDKey]))
-		if e == nil {
-			request.Filter[request.ObjectIDKey] = v
-		}
-	}
-
-	page := &mongodb.Page{
-		PageSize: request.PageSize,
-		PageNo:   request.PageIndex,
-	}
-
-	filter := bson.M{}
-	if request.WhereDoc != "" {
-		err = util.JSONDecodeUseNumber([]byte(request.WhereDoc), &filter)
+// getFilter 根据 WhereDoc 或 WhereList 生成 查询 条件
+func (this_ *BaseRequest) getFilter() (filter bson.M, err error) {
+	filter = bson.M{}
+	if this_.WhereDoc != "" {
+		err = util.JSONDecodeUseNumber([]byte(this_.WhereDoc), &filter)
 		if err != nil {
 			return
 		}
@@ -603,7 +641,7 @@ func (this_ *api) queryPage(requestBean *base.RequestBean, c *gin.Context) (res
 		}
 	} else {
 
-		for _, where := range request.WhereList {
+		for _, where := range this_.WhereList {
 			if where.Name == "" {
 				continue
 			}
@@ -705,8 +743,13 @@ func (this_ *api) queryPage(requestBean *base.RequestBean, c *gin.Context) (res
 			}
 		}
 	}
-	sort := bson.D{}
-	for _, order := range request.OrderList {
+	return
+}
+
+// getSort 根据 OrderList 生成 排序
+func (this_ *BaseRequest) getSort() (sort bson.D) {
+	sort = bson.D{}
+	for _, order := range this_.OrderList {
 		if order.Name == "" {
 			continue
 		}
@@ -722,9 +765,43 @@ func (this_ *api) queryPage(requestBean *base.RequestBean, c *gin.Context) (res
 			})
 		}
 	}
+	return
+}
+
+func (this_ *api) queryPage(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	config, err := this_.getConfig(requestBean, c)
+	if err != nil {
+		return
+	}
+	service, err := getService(config)
+	if err != nil {
+		return
+	}
+
+	request := &BaseRequest{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+
+	if request.IsObjectID && request.ObjectIDKey != "" && request.Filter[request.ObjectIDKey] != nil {
+		v, e := primitive.ObjectIDFromHex(util.GetStringValue(request.Filter[request.ObjectIDKey]))
+		if e == nil {
+			request.Filter[request.ObjectIDKey] = v
+		}
+	}
+
+	page := &mongodb.Page{
+		PageSize: request.PageSize,
+		PageNo:   request.PageIndex,
+	}
+
+	filter, err := request.getFilter()
+	if err != nil {
+		return
+	}
 
 	opts := options.Find()
-	opts.SetSort(sort)
+	opts.SetSort(request.getSort())
 	result, err := service.QueryMapPageResult(request.DatabaseName, request.CollectionName, filter, page, opts)
 	if err != nil {
 		return
@@ -0,0 +1,109 @@
+package module_mongodb
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"github.com/team-ide/go-tool/mongodb"
+	"github.com/team-ide/go-tool/util"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+	"os"
+	"strings"
+	"teamide/pkg/base"
+	"time"
+)
+
+// newClient 创建 驱动 客户端，用于 聚合、explain 等 go-tool 未 提供 的 操作
+func newClient(config *mongodb.Config) (client *mongo.Client, err error) {
+	uri := config.Address
+	if !strings.Contains(uri, "://") {
+		uri = "mongodb://" + uri
+	}
+	opts := options.Client().ApplyURI(uri)
+	if config.Username != "" {
+		opts.SetAuth(options.Credential{
+			Username: config.Username,
+			Password: config.Password,
+		})
+	}
+	if config.CertPath != "" {
+		var bs []byte
+		bs, err = os.ReadFile(config.CertPath)
+		if err != nil {
+			return
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(bs) {
+			err = errors.New("证书[" + config.CertPath + "]解析失败")
+			return
+		}
+		opts.SetTLSConfig(&tls.Config{RootCAs: pool})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client, err = mongo.Connect(ctx, opts)
+	if err != nil {
+		return
+	}
+	err = client.Ping(ctx, nil)
+	if err != nil {
+		_ = client.Disconnect(context.Background())
+		client = nil
+		return
+	}
+	return
+}
+
+func getClient(config *mongodb.Config) (res *mongo.Client, err error) {
+	key := "client-" + getServiceKey(config)
+
+	var serviceInfo *base.ServiceInfo
+	serviceInfo, err = base.GetService(key, func() (res *base.ServiceInfo, err error) {
+		var client *mongo.Client
+		client, err = newClient(config)
+		if err != nil {
+			util.Logger.Error("getClient error", zap.Any("key", key), zap.Error(err))
+			return
+		}
+		res = &base.ServiceInfo{
+			WaitTime:    10 * 60 * 1000,
+			LastUseTime: util.GetNowMilli(),
+			Service:     client,
+			Stop: func() {
+				_ = client.Disconnect(context.Background())
+			},
+		}
+		return
+	})
+	if err != nil {
+		return
+	}
+	res = serviceInfo.Service.(*mongo.Client)
+	serviceInfo.SetLastUseTime()
+	return
+}
+
+// rawToMap 转为 Relaxed Extended JSON 结构 的 map，便于 返回 前端 与 解析
+func rawToMap(raw bson.Raw) (res map[string]interface{}, err error) {
+	bs, err := bson.MarshalExtJSON(raw, false, false)
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(bs, &res)
+	return
+}
+
+func runCommand(ctx context.Context, db *mongo.Database, command bson.D) (res map[string]interface{}, err error) {
+	raw, err := db.RunCommand(ctx, command).Raw()
+	if err != nil {
+		return
+	}
+	res, err = rawToMap(raw)
+	return
+}
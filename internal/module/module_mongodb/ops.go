@@ -0,0 +1,324 @@
+package module_mongodb
+
+import (
+	"context"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/team-ide/go-tool/util"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+	"regexp"
+	"teamide/pkg/base"
+	"teamide/pkg/mongoops"
+	"time"
+)
+
+type OpsRequest struct {
+	BaseRequest
+	All              bool               `json:"all"`     // currentOp 包含 空闲 连接 与 系统 操作
+	MinSecs          int64              `json:"minSecs"` // currentOp 运行 时间 不少于
+	Ns               string             `json:"ns"`      // currentOp 命名 空间 前缀
+	OpId             interface{}        `json:"opid"`
+	User             *mongoops.UserSpec `json:"user"`
+	Role             *mongoops.RoleSpec `json:"role"`
+	UserName         string             `json:"userName"`
+	RoleName         string             `json:"roleName"`
+	ShowBuiltinRoles bool               `json:"showBuiltinRoles"`
+}
+
+// getDatabaseName 用户、角色 所在 库，默认 admin
+func (this_ *OpsRequest) getDatabaseName() string {
+	if this_.DatabaseName == "" {
+		return "admin"
+	}
+	return this_.DatabaseName
+}
+
+func (this_ *api) getOpsClient(requestBean *base.RequestBean, c *gin.Context) (client *mongo.Client, request *OpsRequest, err error) {
+	config, err := this_.getConfig(requestBean, c)
+	if err != nil {
+		return
+	}
+	client, err = getClient(config)
+	if err != nil {
+		return
+	}
+
+	request = &OpsRequest{}
+	if !base.RequestJSON(request, c) {
+		// 错误 已 响应，返回 错误 终止 调用 方
+		client = nil
+		err = errors.New("request json parse error")
+		return
+	}
+	return
+}
+
+func opsContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), 30*time.Second)
+}
+
+// replSetStatus 副本集 成员 状态 与 复制 延迟
+func (this_ *api) replSetStatus(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	client, _, err := this_.getOpsClient(requestBean, c)
+	if err != nil {
+		return
+	}
+	ctx, cancel := opsContext()
+	defer cancel()
+
+	status, err := runCommand(ctx, client.Database("admin"), bson.D{{Key: "replSetGetStatus", Value: 1}})
+	if err != nil {
+		return
+	}
+	res = map[string]interface{}{
+		"summary": mongoops.SummarizeReplSet(status),
+		"status":  status,
+	}
+	return
+}
+
+// shardingStatus 分片 概览，需 连接 mongos
+func (this_ *api) shardingStatus(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	client, _, err := this_.getOpsClient(requestBean, c)
+	if err != nil {
+		return
+	}
+	ctx, cancel := opsContext()
+	defer cancel()
+
+	admin := client.Database("admin")
+	shards, err := runCommand(ctx, admin, bson.D{{Key: "listShards", Value: 1}})
+	if err != nil {
+		return
+	}
+	balancer, err := runCommand(ctx, admin, bson.D{{Key: "balancerStatus", Value: 1}})
+	if err != nil {
+		return
+	}
+
+	config := client.Database("config")
+	databases, err := findMaps(ctx, config.Collection("databases"), bson.D{})
+	if err != nil {
+		return
+	}
+	collections, err := findMaps(ctx, config.Collection("collections"), bson.D{})
+	if err != nil {
+		return
+	}
+	cursor, err := config.Collection("chunks").Aggregate(ctx, bson.A{
+		bson.D{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: bson.D{
+				{Key: "coll", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$uuid", "$ns"}}}},
+				{Key: "shard", Value: "$shard"},
+			}},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+		}}},
+	})
+	if err != nil {
+		return
+	}
+	chunkCounts, err := cursorMaps(ctx, cursor)
+	if err != nil {
+		return
+	}
+
+	res = map[string]interface{}{
+		"shards":      shards["shards"],
+		"balancer":    balancer,
+		"databases":   databases,
+		"collections": mongoops.ChunkDistribution(collections, chunkCounts),
+	}
+	return
+}
+
+func findMaps(ctx context.Context, collection *mongo.Collection, filter bson.D) (list []map[string]interface{}, err error) {
+	cursor, err := collection.Find(ctx, filter)
+	if err != nil {
+		return
+	}
+	list, err = cursorMaps(ctx, cursor)
+	return
+}
+
+func cursorMaps(ctx context.Context, cursor *mongo.Cursor) (list []map[string]interface{}, err error) {
+	defer func() { _ = cursor.Close(ctx) }()
+	for cursor.Next(ctx) {
+		var one map[string]interface{}
+		if one, err = rawToMap(cursor.Current); err != nil {
+			return
+		}
+		list = append(list, one)
+	}
+	err = cursor.Err()
+	return
+}
+
+// currentOp 当前 操作，默认 只 包含 活跃 的 用户 操作
+func (this_ *api) currentOp(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	client, request, err := this_.getOpsClient(requestBean, c)
+	if err != nil {
+		return
+	}
+	ctx, cancel := opsContext()
+	defer cancel()
+
+	command := bson.D{{Key: "currentOp", Value: 1}}
+	if request.All {
+		command = append(command, bson.E{Key: "$all", Value: true})
+	} else {
+		command = append(command, bson.E{Key: "active", Value: true})
+	}
+	if request.MinSecs > 0 {
+		command = append(command, bson.E{Key: "secs_running", Value: bson.D{{Key: "$gte", Value: request.MinSecs}}})
+	}
+	if request.Ns != "" {
+		command = append(command, bson.E{Key: "ns", Value: bson.D{{Key: "$regex", Value: "^" + regexp.QuoteMeta(request.Ns)}}})
+	}
+	status, err := runCommand(ctx, client.Database("admin"), command)
+	if err != nil {
+		return
+	}
+	inprog, _ := status["inprog"].([]interface{})
+	res = mongoops.SummarizeOps(inprog)
+	return
+}
+
+func (this_ *api) killOp(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	client, request, err := this_.getOpsClient(requestBean, c)
+	if err != nil {
+		return
+	}
+	opId, err := mongoops.ParseOpId(request.OpId)
+	if err != nil {
+		return
+	}
+	ctx, cancel := opsContext()
+	defer cancel()
+
+	res, err = runCommand(ctx, client.Database("admin"), bson.D{{Key: "killOp", Value: 1}, {Key: "op", Value: opId}})
+	if err != nil {
+		util.Logger.Error("mongodb killOp error", zap.Any("opid", opId), zap.Error(err))
+		return
+	}
+	return
+}
+
+func (this_ *api) users(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	client, request, err := this_.getOpsClient(requestBean, c)
+	if err != nil {
+		return
+	}
+	ctx, cancel := opsContext()
+	defer cancel()
+
+	info, err := runCommand(ctx, client.Database(request.getDatabaseName()), bson.D{{Key: "usersInfo", Value: 1}})
+	if err != nil {
+		return
+	}
+	res = info["users"]
+	return
+}
+
+// userSave 创建 或 修改 用户
+func (this_ *api) userSave(requestBean *base.RequestBean, c *gin.Context, isUpdate bool) (res interface{}, err error) {
+	client, request, err := this_.getOpsClient(requestBean, c)
+	if err != nil {
+		return
+	}
+	command, err := mongoops.UserCommand(request.User, request.getDatabaseName(), isUpdate)
+	if err != nil {
+		return
+	}
+	ctx, cancel := opsContext()
+	defer cancel()
+
+	_, err = runCommand(ctx, client.Database(request.getDatabaseName()), command)
+	return
+}
+
+func (this_ *api) userCreate(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	return this_.userSave(requestBean, c, false)
+}
+
+func (this_ *api) userUpdate(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	return this_.userSave(requestBean, c, true)
+}
+
+func (this_ *api) userDelete(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	client, request, err := this_.getOpsClient(requestBean, c)
+	if err != nil {
+		return
+	}
+	if request.UserName == "" {
+		err = errors.New("用户名不能为空")
+		return
+	}
+	ctx, cancel := opsContext()
+	defer cancel()
+
+	_, err = runCommand(ctx, client.Database(request.getDatabaseName()), bson.D{{Key: "dropUser", Value: request.UserName}})
+	return
+}
+
+func (this_ *api) roles(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	client, request, err := this_.getOpsClient(requestBean, c)
+	if err != nil {
+		return
+	}
+	ctx, cancel := opsContext()
+	defer cancel()
+
+	info, err := runCommand(ctx, client.Database(request.getDatabaseName()), bson.D{
+		{Key: "rolesInfo", Value: 1},
+		{Key: "showPrivileges", Value: true},
+		{Key: "showBuiltinRoles", Value: request.ShowBuiltinRoles},
+	})
+	if err != nil {
+		return
+	}
+	res = info["roles"]
+	return
+}
+
+// roleSave 创建 或 修改 自定义 角色
+func (this_ *api) roleSave(requestBean *base.RequestBean, c *gin.Context, isUpdate bool) (res interface{}, err error) {
+	client, request, err := this_.getOpsClient(requestBean, c)
+	if err != nil {
+		return
+	}
+	command, err := mongoops.RoleCommand(request.Role, request.getDatabaseName(), isUpdate)
+	if err != nil {
+		return
+	}
+	ctx, cancel := opsContext()
+	defer cancel()
+
+	_, err = runCommand(ctx, client.Database(request.getDatabaseName()), command)
+	return
+}
+
+func (this_ *api) roleCreate(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	return this_.roleSave(requestBean, c, false)
+}
+
+func (this_ *api) roleUpdate(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	return this_.roleSave(requestBean, c, true)
+}
+
+func (this_ *api) roleDelete(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	client, request, err := this_.getOpsClient(requestBean, c)
+	if err != nil {
+		return
+	}
+	if request.RoleName == "" {
+		err = errors.New("角色名不能为空")
+		return
+	}
+	ctx, cancel := opsContext()
+	defer cancel()
+
+	_, err = runCommand(ctx, client.Database(request.getDatabaseName()), bson.D{{Key: "dropRole", Value: request.RoleName}})
+	return
+}
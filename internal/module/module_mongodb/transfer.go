@@ -0,0 +1,635 @@
+package module_mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/team-ide/go-tool/util"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"teamide/pkg/base"
+	"teamide/pkg/mongoquery"
+	"teamide/pkg/mongotransfer"
+	"time"
+)
+
+const (
+	transferImport = "import"
+	transferExport = "export"
+
+	importModeInsert = "insert"
+	importModeUpsert = "upsert"
+)
+
+type TransferRequest struct {
+	BaseRequest
+	Format string `json:"format"` // json、csv、archive
+
+	// 导出
+	Fields []string `json:"fields"` // CSV 字段，支持 a.b
+	Query  string   `json:"query"`  // Extended JSON 查询 条件
+	Sort   string   `json:"sort"`   // Extended JSON 排序
+	Limit  int64    `json:"limit"`
+
+	// 导入
+	Path              string   `json:"path"`      // 上传 的 文件
+	BatchSize         int      `json:"batchSize"` // 默认 1000
+	Mode              string   `json:"mode"`      // insert、upsert
+	UpsertFields      []string `json:"upsertFields"`
+	MaxErrors         int64    `json:"maxErrors"` // 错误 数 超过 后 停止，为 0 不 限制
+	StopOnError       bool     `json:"stopOnError"`
+	IgnoreBlanks      bool     `json:"ignoreBlanks"`
+	ArchiveCollection string   `json:"archiveCollection"` // archive 中 导入 的 集合，为 空 导入 全部
+}
+
+// TransferTask 导入 导出 任务
+type TransferTask struct {
+	TaskId         string `json:"taskId"`
+	Type           string `json:"type"`
+	Format         string `json:"format"`
+	DatabaseName   string `json:"databaseName"`
+	CollectionName string `json:"collectionName"`
+	ReadCount      int64  `json:"readCount"`
+	SuccessCount   int64  `json:"successCount"`
+	ErrorCount     int64  `json:"errorCount"`
+	LastError      string `json:"lastError,omitempty"`
+	Error          string `json:"error,omitempty"`
+	FileName       string `json:"fileName,omitempty"`
+	FileSize       int64  `json:"fileSize,omitempty"`
+	StartTime      int64  `json:"startTime"`
+	EndTime        int64  `json:"endTime,omitempty"`
+	IsEnd          bool   `json:"isEnd"`
+	IsStop         bool   `json:"isStop"`
+
+	request *TransferRequest
+	path    string
+	userId  int64
+	lock    sync.Mutex
+}
+
+func (this_ *TransferTask) getInfo() (res *TransferTask) {
+	this_.lock.Lock()
+	defer this_.lock.Unlock()
+	res = &TransferTask{
+		TaskId:         this_.TaskId,
+		Type:           this_.Type,
+		Format:         this_.Format,
+		DatabaseName:   this_.DatabaseName,
+		CollectionName: this_.CollectionName,
+		ReadCount:      this_.ReadCount,
+		SuccessCount:   this_.SuccessCount,
+		ErrorCount:     this_.ErrorCount,
+		LastError:      this_.LastError,
+		Error:          this_.Error,
+		FileName:       this_.FileName,
+		FileSize:       this_.FileSize,
+		StartTime:      this_.StartTime,
+		EndTime:        this_.EndTime,
+		IsEnd:          this_.IsEnd,
+		IsStop:         this_.IsStop,
+	}
+	return
+}
+
+func (this_ *TransferTask) stop() {
+	this_.lock.Lock()
+	defer this_.lock.Unlock()
+	this_.IsStop = true
+}
+
+func (this_ *TransferTask) isStop() bool {
+	this_.lock.Lock()
+	defer this_.lock.Unlock()
+	return this_.IsStop
+}
+
+// addError 记录 错误，超过 容忍 数量 时 停止
+func (this_ *TransferTask) addError(count int64, msg string) {
+	this_.lock.Lock()
+	defer this_.lock.Unlock()
+	this_.ErrorCount += count
+	this_.LastError = msg
+	if this_.request.StopOnError || (this_.request.MaxErrors > 0 && this_.ErrorCount > this_.request.MaxErrors) {
+		this_.IsStop = true
+	}
+}
+
+func (this_ *TransferTask) run(client *mongo.Client) {
+	defer func() {
+		if e := recover(); e != nil {
+			util.Logger.Error("mongodb transfer task panic", zap.Any("taskId", this_.TaskId), zap.Any("error", e))
+			this_.lock.Lock()
+			this_.Error = util.GetStringValue(e)
+			this_.lock.Unlock()
+		}
+		this_.lock.Lock()
+		this_.EndTime = util.GetNowMilli()
+		this_.IsEnd = true
+		this_.lock.Unlock()
+	}()
+
+	collection := client.Database(this_.DatabaseName).Collection(this_.CollectionName)
+	var err error
+	if this_.Type == transferExport {
+		err = this_.export(collection)
+	} else {
+		err = this_._import(collection)
+	}
+	if err != nil {
+		util.Logger.Error("mongodb transfer task error", zap.Any("taskId", this_.TaskId), zap.Error(err))
+		this_.lock.Lock()
+		this_.Error = err.Error()
+		this_.lock.Unlock()
+	}
+}
+
+// export 导出 到 临时 目录，完成 后 通过 exportDownload 下载
+func (this_ *TransferTask) export(collection *mongo.Collection) (err error) {
+	ctx := context.Background()
+	request := this_.request
+	filter, err := mongoquery.ParseDoc(request.Query)
+	if err != nil {
+		return
+	}
+	sort, err := mongoquery.ParseDoc(request.Sort)
+	if err != nil {
+		return
+	}
+	opts := options.Find().SetBatchSize(int32(request.BatchSize))
+	if len(sort) > 0 {
+		opts.SetSort(sort)
+	}
+	if request.Limit > 0 {
+		opts.SetLimit(request.Limit)
+	}
+
+	writerOptions := &mongotransfer.WriterOptions{
+		Fields:     request.Fields,
+		Database:   this_.DatabaseName,
+		Collection: this_.CollectionName,
+	}
+	if this_.Format == mongotransfer.FormatArchive {
+		var indexCursor *mongo.Cursor
+		indexCursor, err = collection.Indexes().List(ctx)
+		if err != nil {
+			return
+		}
+		for indexCursor.Next(ctx) {
+			writerOptions.Indexes = append(writerOptions.Indexes, append(bson.Raw{}, indexCursor.Current...))
+		}
+		_ = indexCursor.Close(ctx)
+	}
+
+	file, err := os.Create(this_.path)
+	if err != nil {
+		return
+	}
+	defer func() { _ = file.Close() }()
+	writer, err := mongotransfer.NewWriter(this_.Format, file, writerOptions)
+	if err != nil {
+		return
+	}
+
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return
+	}
+	defer func() { _ = cursor.Close(ctx) }()
+	for cursor.Next(ctx) {
+		if this_.isStop() {
+			break
+		}
+		if err = writer.Write(cursor.Current); err != nil {
+			return
+		}
+		this_.lock.Lock()
+		this_.ReadCount++
+		this_.SuccessCount++
+		this_.lock.Unlock()
+	}
+	if err = cursor.Err(); err != nil {
+		return
+	}
+	if err = writer.Close(); err != nil {
+		return
+	}
+	if info, e := file.Stat(); e == nil {
+		this_.lock.Lock()
+		this_.FileSize = info.Size()
+		this_.lock.Unlock()
+	}
+	return
+}
+
+// _import 按 批次 写入，insert 模式 重复 的 _id 记为 错误，upsert 模式 按 UpsertFields 替换
+func (this_ *TransferTask) _import(collection *mongo.Collection) (err error) {
+	request := this_.request
+	file, err := os.Open(this_.path)
+	if err != nil {
+		return
+	}
+	defer func() { _ = file.Close() }()
+	reader, err := mongotransfer.NewReader(this_.Format, file, &mongotransfer.ReaderOptions{
+		IgnoreBlanks: request.IgnoreBlanks,
+		Collection:   request.ArchiveCollection,
+	})
+	if err != nil {
+		return
+	}
+
+	var batch []bson.D
+	for !this_.isStop() {
+		var doc bson.D
+		doc, err = reader.Next()
+		if err == io.EOF {
+			err = nil
+			break
+		}
+		if err != nil {
+			var recordError *mongotransfer.RecordError
+			if !errors.As(err, &recordError) {
+				return
+			}
+			err = nil
+			this_.addError(1, recordError.Message)
+			continue
+		}
+		this_.lock.Lock()
+		this_.ReadCount++
+		this_.lock.Unlock()
+		batch = append(batch, doc)
+		if len(batch) >= request.BatchSize {
+			this_.writeBatch(collection, batch)
+			batch = nil
+		}
+	}
+	if len(batch) > 0 && !this_.isStop() {
+		this_.writeBatch(collection, batch)
+	}
+	return
+}
+
+func (this_ *TransferTask) writeBatch(collection *mongo.Collection, batch []bson.D) {
+	ctx := context.Background()
+	var err error
+	var writeCount = int64(len(batch))
+	if this_.request.Mode == importModeUpsert {
+		var models []mongo.WriteModel
+		for _, doc := range batch {
+			filter := bson.D{}
+			for _, field := range this_.request.UpsertFields {
+				value, find := lookupField(doc, strings.Split(field, "."))
+				if !find {
+					filter = nil
+					break
+				}
+				filter = append(filter, bson.E{Key: field, Value: value})
+			}
+			if filter == nil {
+				writeCount--
+				this_.addError(1, "upsert字段["+strings.Join(this_.request.UpsertFields, ",")+"]缺失")
+				continue
+			}
+			models = append(models, mongo.NewReplaceOneModel().SetFilter(filter).SetReplacement(doc).SetUpsert(true))
+		}
+		if len(models) == 0 {
+			return
+		}
+		_, err = collection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	} else {
+		docs := make([]interface{}, len(batch))
+		for i, doc := range batch {
+			docs[i] = doc
+		}
+		_, err = collection.InsertMany(ctx, docs, options.InsertMany().SetOrdered(false))
+	}
+	var errorCount int64
+	if err != nil {
+		var bulkError mongo.BulkWriteException
+		if errors.As(err, &bulkError) && len(bulkError.WriteErrors) > 0 {
+			errorCount = int64(len(bulkError.WriteErrors))
+			err = errors.New(bulkError.WriteErrors[0].Message)
+		} else {
+			errorCount = writeCount
+		}
+		this_.addError(errorCount, err.Error())
+	}
+	this_.lock.Lock()
+	this_.SuccessCount += writeCount - errorCount
+	this_.lock.Unlock()
+}
+
+// lookupField 按 a.b 路径 查找 值
+func lookupField(doc bson.D, path []string) (value interface{}, find bool) {
+	for _, one := range doc {
+		if one.Key != path[0] {
+			continue
+		}
+		if len(path) == 1 {
+			return one.Value, true
+		}
+		if sub, ok := one.Value.(bson.D); ok {
+			return lookupField(sub, path[1:])
+		}
+		return
+	}
+	return
+}
+
+var (
+	transferTaskCache     = map[string]*TransferTask{}
+	transferTaskCacheLock = &sync.Mutex{}
+	workerTasksCache      = map[string][]string{}
+	workerTasksCacheLock  = &sync.Mutex{}
+)
+
+// getTransferTask 只 返回 当前 用户 的 任务
+func getTransferTask(taskId string, userId int64) (task *TransferTask) {
+	transferTaskCacheLock.Lock()
+	defer transferTaskCacheLock.Unlock()
+	task = transferTaskCache[taskId]
+	if task != nil && task.userId != userId {
+		task = nil
+	}
+	return
+}
+
+// cleanTransferTask 停止 并 移除 任务，删除 导出 的 文件
+func cleanTransferTask(taskId string) {
+	transferTaskCacheLock.Lock()
+	task := transferTaskCache[taskId]
+	delete(transferTaskCache, taskId)
+	transferTaskCacheLock.Unlock()
+	if task == nil {
+		return
+	}
+	task.stop()
+	if task.Type == transferExport {
+		go func() {
+			// 等待 任务 结束 后 删除 文件
+			for i := 0; i < 600 && !task.getInfo().IsEnd; i++ {
+				time.Sleep(100 * time.Millisecond)
+			}
+			_ = os.Remove(task.path)
+		}()
+	}
+}
+
+func addWorkerTask(workerId string, taskId string) {
+	workerTasksCacheLock.Lock()
+	defer workerTasksCacheLock.Unlock()
+	taskIds := workerTasksCache[workerId]
+	if util.StringIndexOf(taskIds, taskId) < 0 {
+		taskIds = append(taskIds, taskId)
+		workerTasksCache[workerId] = taskIds
+	}
+	return
+}
+
+func getWorkerTasks(workerId string, userId int64) (taskList []*TransferTask) {
+	workerTasksCacheLock.Lock()
+	defer workerTasksCacheLock.Unlock()
+	taskIds := workerTasksCache[workerId]
+	for _, id := range taskIds {
+		task := getTransferTask(id, userId)
+		if task != nil {
+			taskList = append(taskList, task.getInfo())
+		}
+	}
+	return
+}
+
+// removeWorkerTasks 清理 当前 用户 在 标签页 的 任务
+func removeWorkerTasks(workerId string, userId int64) {
+	workerTasksCacheLock.Lock()
+	defer workerTasksCacheLock.Unlock()
+	taskIds := workerTasksCache[workerId]
+	var newIds []string
+	for _, taskId := range taskIds {
+		transferTaskCacheLock.Lock()
+		task := transferTaskCache[taskId]
+		transferTaskCacheLock.Unlock()
+		if task == nil {
+			continue
+		}
+		if task.userId != userId {
+			newIds = append(newIds, taskId)
+			continue
+		}
+		cleanTransferTask(taskId)
+	}
+	if len(newIds) == 0 {
+		delete(workerTasksCache, workerId)
+	} else {
+		workerTasksCache[workerId] = newIds
+	}
+	return
+}
+
+func removeWorkerTask(workerId string, taskId string, userId int64) {
+	workerTasksCacheLock.Lock()
+	defer workerTasksCacheLock.Unlock()
+
+	if getTransferTask(taskId, userId) == nil {
+		return
+	}
+	cleanTransferTask(taskId)
+
+	taskIds := workerTasksCache[workerId]
+	var newIds []string
+	for _, id := range taskIds {
+		if id != taskId {
+			newIds = append(newIds, id)
+		}
+	}
+	taskIds = newIds
+	if len(taskIds) == 0 {
+		delete(workerTasksCache, workerId)
+	} else {
+		workerTasksCache[workerId] = taskIds
+	}
+	return
+}
+
+func (this_ *api) startTransfer(requestBean *base.RequestBean, c *gin.Context, transferType string) (res interface{}, err error) {
+	config, err := this_.getConfig(requestBean, c)
+	if err != nil {
+		return
+	}
+	client, err := getClient(config)
+	if err != nil {
+		return
+	}
+
+	request := &TransferRequest{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+	if request.DatabaseName == "" || request.CollectionName == "" {
+		err = errors.New("database or collection is empty")
+		return
+	}
+	if request.Format == "" {
+		request.Format = mongotransfer.FormatJSON
+	}
+	if request.BatchSize <= 0 {
+		request.BatchSize = 1000
+	}
+
+	task := &TransferTask{
+		TaskId:         util.GetUUID(),
+		Type:           transferType,
+		Format:         request.Format,
+		DatabaseName:   request.DatabaseName,
+		CollectionName: request.CollectionName,
+		StartTime:      util.GetNowMilli(),
+		request:        request,
+		userId:         requestBean.JWT.UserId,
+	}
+	if transferType == transferExport {
+		if request.Format == mongotransfer.FormatCSV && len(request.Fields) == 0 {
+			err = errors.New("CSV导出需要指定字段")
+			return
+		}
+		var tempDir string
+		tempDir, err = util.GetTempDir()
+		if err != nil {
+			return
+		}
+		dir := tempDir + "mongodb-export/"
+		if err = os.MkdirAll(dir, os.ModePerm); err != nil {
+			return
+		}
+		task.FileName = fmt.Sprint(request.DatabaseName, "-", request.CollectionName, "-", time.Now().Format("20060102150405"), mongotransfer.Ext(request.Format))
+		task.path = dir + task.TaskId + mongotransfer.Ext(request.Format)
+	} else {
+		if request.Path == "" {
+			err = errors.New("导入文件不能为空")
+			return
+		}
+		if request.Mode == "" {
+			request.Mode = importModeInsert
+		}
+		if request.Mode == importModeUpsert && len(request.UpsertFields) == 0 {
+			request.UpsertFields = []string{"_id"}
+		}
+		task.path = this_.toolboxService.GetFilesFile(request.Path)
+	}
+
+	transferTaskCacheLock.Lock()
+	transferTaskCache[task.TaskId] = task
+	transferTaskCacheLock.Unlock()
+	addWorkerTask(request.WorkerId, task.TaskId)
+
+	go task.run(client)
+	res = task.getInfo()
+	return
+}
+
+// _import 从 JSON、CSV、archive 文件 导入
+func (this_ *api) _import(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	return this_.startTransfer(requestBean, c, transferImport)
+}
+
+// export 导出 集合 或 查询 结果
+func (this_ *api) export(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	return this_.startTransfer(requestBean, c, transferExport)
+}
+
+func (this_ *api) taskStatus(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	request := &BaseRequest{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+
+	task := getTransferTask(request.TaskId, requestBean.JWT.UserId)
+	if task != nil {
+		res = task.getInfo()
+	}
+	return
+}
+
+func (this_ *api) taskStop(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	request := &BaseRequest{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+
+	task := getTransferTask(request.TaskId, requestBean.JWT.UserId)
+	if task != nil {
+		task.stop()
+	}
+	return
+}
+
+func (this_ *api) taskClean(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	request := &BaseRequest{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+
+	removeWorkerTask(request.WorkerId, request.TaskId, requestBean.JWT.UserId)
+	return
+}
+
+func (this_ *api) taskList(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	request := &BaseRequest{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+
+	res = getWorkerTasks(request.WorkerId, requestBean.JWT.UserId)
+	return
+}
+
+func (this_ *api) exportDownload(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	data := map[string]string{}
+	err = c.Bind(&data)
+	if err != nil {
+		return
+	}
+
+	task := getTransferTask(data["taskId"], requestBean.JWT.UserId)
+	if task == nil || task.Type != transferExport {
+		err = errors.New("任务不存在")
+		return
+	}
+	info := task.getInfo()
+	if !info.IsEnd {
+		err = errors.New("任务未结束")
+		return
+	}
+	if info.Error != "" {
+		err = errors.New(info.Error)
+		return
+	}
+	file, err := os.Open(task.path)
+	if err != nil {
+		return
+	}
+	defer func() { _ = file.Close() }()
+
+	c.Header("Content-Type", "application/octet-stream")
+	c.Header("Content-Disposition", "attachment; filename="+url.QueryEscape(info.FileName))
+	c.Header("Content-Transfer-Encoding", "binary")
+	c.Header("Content-Length", fmt.Sprint(info.FileSize))
+	c.Header("download-file-name", info.FileName)
+
+	_, err = io.Copy(c.Writer, file)
+	if err != nil {
+		return
+	}
+
+	c.Status(http.StatusOK)
+	res = base.HttpNotResponse
+	return
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"go.uber.org/zap"
 	"teamide/pkg/node"
+	"teamide/pkg/system"
 	"time"
 )
 
@@ -69,6 +70,7 @@ func (this_ *NodeContext) doAlive() {
 		} else {
 			find.Status = 0
 		}
+		this_.checkMonitorSource(find)
 	}
 
 	for _, id := range netProxyModelIdList {
@@ -101,6 +103,48 @@ func (this_ *NodeContext) doAlive() {
 	return
 }
 
+// checkMonitorSource 已启动的 节点 采集 监控数据，本地节点 即为 本机 不需要采集
+func (this_ *NodeContext) checkMonitorSource(nodeModel *NodeModel) {
+	sourceKey := "node-" + nodeModel.ServerId
+	if nodeModel.Status != node.StatusStarted || this_.isLocalNode(nodeModel.ServerId) {
+		system.RemoveMonitorSource(sourceKey)
+		return
+	}
+	serverId := nodeModel.ServerId
+	system.AddMonitorSource(&system.MonitorSource{
+		Key:     sourceKey,
+		HostKey: sourceKey,
+		Collect: func() (monitorData *system.MonitorData, err error) {
+			lineNodeIdList := this_.GetNodeLineTo(serverId)
+			if len(lineNodeIdList) == 0 {
+				err = errors.New("node [" + serverId + "] line not found")
+				return
+			}
+			monitorData = this_.GetServer().SystemMonitorData(lineNodeIdList)
+			return
+		},
+	})
+}
+
+func (this_ *NodeContext) isLocalNode(serverId string) bool {
+	for _, one := range this_.localNodeList {
+		if one.ServerId == serverId {
+			return true
+		}
+	}
+	return false
+}
+
+// GetNodeModelList 所有节点 及 状态，供 告警 等模块 使用
+func (this_ *NodeContext) GetNodeModelList() []*NodeModel {
+	return this_.getNodeModelList()
+}
+
+// IsLocalNode 是否 本地节点
+func (this_ *NodeContext) IsLocalNode(serverId string) bool {
+	return this_.isLocalNode(serverId)
+}
+
 func (this_ *NodeContext) getNodeModelList() []*NodeModel {
 	var nodeModelList []*NodeModel
 
@@ -252,6 +252,11 @@ func (this_ *NodeContext) SystemGetInfo(nodeId string) (info *system.Info) {
 }
 
 func (this_ *NodeContext) SystemQueryMonitorData(nodeId string, request *system.QueryRequest) (info *system.QueryResponse) {
+	// 范围查询 使用 本服务 持久化的 节点数据
+	if request != nil && (request.StartTime > 0 || request.EndTime > 0) && system.GetMetricStore() != nil && !this_.isLocalNode(nodeId) {
+		request.HostKey = "node-" + nodeId
+		return system.QueryMonitorData(request)
+	}
 	lineNodeIdList := this_.GetNodeLineTo(nodeId)
 	return this_.GetServer().SystemQueryMonitorData(lineNodeIdList, request)
 }
@@ -7,6 +7,7 @@ import (
 	"teamide/pkg/node"
 	"teamide/pkg/system"
 	"teamide/pkg/terminal"
+	"time"
 )
 
 func NewTerminalService(nodeId string, nodeService *NodeService) (res *terminalService) {
@@ -158,3 +159,45 @@ func (this_ *terminalService) SystemMonitorData() (res *system.MonitorData, err
 	res = server.SystemMonitorData(this_.nodeLine)
 	return
 }
+
+func (this_ *terminalService) SystemProcesses() (res []*system.ProcessInfo, err error) {
+	var server *node.Server
+	server, err = this_.getServer()
+	if err != nil {
+		return
+	}
+	res, err = server.SystemProcesses(this_.nodeLine)
+	return
+}
+
+func (this_ *terminalService) SystemPorts() (res []*system.PortInfo, err error) {
+	var server *node.Server
+	server, err = this_.getServer()
+	if err != nil {
+		return
+	}
+	res, err = server.SystemPorts(this_.nodeLine)
+	return
+}
+
+func (this_ *terminalService) SystemSignal(pid int32, signal string) (err error) {
+	var server *node.Server
+	server, err = this_.getServer()
+	if err != nil {
+		return
+	}
+	err = server.SystemSignal(this_.nodeLine, pid, signal)
+	return
+}
+
+// Exec 在 节点 执行 脚本
+func (this_ *terminalService) Exec(script string, timeout time.Duration) (res *terminal.ExecResult, err error) {
+	var server *node.Server
+	server, err = this_.getServer()
+	if err != nil {
+		return
+	}
+
+	res, err = server.TerminalExec(this_.nodeLine, script, timeout)
+	return
+}
@@ -2,7 +2,9 @@ package module_redis
 
 import (
 	"encoding/base64"
+	"errors"
 	"github.com/gin-gonic/gin"
+	goRedis "github.com/go-redis/redis/v8"
 	"github.com/team-ide/go-tool/redis"
 	"github.com/team-ide/go-tool/util"
 	"go.uber.org/zap"
@@ -46,6 +48,39 @@ var (
 	ttlPower           = base.AppendPower(&base.PowerAction{Action: "ttl", Text: "Redis过期时间查询", ShouldLogin: true, StandAlone: true, Parent: Power})
 	persistPower       = base.AppendPower(&base.PowerAction{Action: "persist", Text: "Redis移除过期时间", ShouldLogin: true, StandAlone: true, Parent: Power})
 	closePower         = base.AppendPower(&base.PowerAction{Action: "close", Text: "Redis关闭", ShouldLogin: true, StandAlone: true, Parent: Power})
+
+	zaddPower          = base.AppendPower(&base.PowerAction{Action: "zadd", Text: "Redis ZAdd", ShouldLogin: true, StandAlone: true, Parent: Power})
+	zremPower          = base.AppendPower(&base.PowerAction{Action: "zrem", Text: "Redis ZRem", ShouldLogin: true, StandAlone: true, Parent: Power})
+	zincrbyPower       = base.AppendPower(&base.PowerAction{Action: "zincrby", Text: "Redis ZIncrBy", ShouldLogin: true, StandAlone: true, Parent: Power})
+	zrangePower        = base.AppendPower(&base.PowerAction{Action: "zrange", Text: "Redis ZRange", ShouldLogin: true, StandAlone: true, Parent: Power})
+	zrangeByScorePower = base.AppendPower(&base.PowerAction{Action: "zrangeByScore", Text: "Redis ZRangeByScore", ShouldLogin: true, StandAlone: true, Parent: Power})
+	zscorePower        = base.AppendPower(&base.PowerAction{Action: "zscore", Text: "Redis ZScore", ShouldLogin: true, StandAlone: true, Parent: Power})
+
+	xaddPower              = base.AppendPower(&base.PowerAction{Action: "xadd", Text: "Redis XAdd", ShouldLogin: true, StandAlone: true, Parent: Power})
+	xdelPower              = base.AppendPower(&base.PowerAction{Action: "xdel", Text: "Redis XDel", ShouldLogin: true, StandAlone: true, Parent: Power})
+	xlenPower              = base.AppendPower(&base.PowerAction{Action: "xlen", Text: "Redis XLen", ShouldLogin: true, StandAlone: true, Parent: Power})
+	xrangePower            = base.AppendPower(&base.PowerAction{Action: "xrange", Text: "Redis XRange", ShouldLogin: true, StandAlone: true, Parent: Power})
+	xgroupCreatePower      = base.AppendPower(&base.PowerAction{Action: "xgroupCreate", Text: "Redis创建消费组", ShouldLogin: true, StandAlone: true, Parent: Power})
+	xgroupDestroyPower     = base.AppendPower(&base.PowerAction{Action: "xgroupDestroy", Text: "Redis删除消费组", ShouldLogin: true, StandAlone: true, Parent: Power})
+	xgroupSetIdPower       = base.AppendPower(&base.PowerAction{Action: "xgroupSetId", Text: "Redis设置消费组位置", ShouldLogin: true, StandAlone: true, Parent: Power})
+	xgroupDelConsumerPower = base.AppendPower(&base.PowerAction{Action: "xgroupDelConsumer", Text: "Redis删除消费者", ShouldLogin: true, StandAlone: true, Parent: Power})
+	xinfoGroupsPower       = base.AppendPower(&base.PowerAction{Action: "xinfoGroups", Text: "Redis消费组信息", ShouldLogin: true, StandAlone: true, Parent: Power})
+	xreadgroupPower        = base.AppendPower(&base.PowerAction{Action: "xreadgroup", Text: "Redis XReadGroup", ShouldLogin: true, StandAlone: true, Parent: Power})
+	xackPower              = base.AppendPower(&base.PowerAction{Action: "xack", Text: "Redis XAck", ShouldLogin: true, StandAlone: true, Parent: Power})
+	xpendingPower          = base.AppendPower(&base.PowerAction{Action: "xpending", Text: "Redis XPending", ShouldLogin: true, StandAlone: true, Parent: Power})
+
+	consolePower = base.AppendPower(&base.PowerAction{Action: "console", Text: "Redis命令控制台", ShouldLogin: true, StandAlone: true, Parent: Power})
+
+	analysisPower         = base.AppendPower(&base.PowerAction{Action: "analysis", Text: "Redis内存分析", ShouldLogin: true, StandAlone: true, Parent: Power})
+	analysisStatusPower   = base.AppendPower(&base.PowerAction{Action: "analysisStatus", Text: "Redis内存分析状态", ShouldLogin: true, StandAlone: true, Parent: Power})
+	analysisStopPower     = base.AppendPower(&base.PowerAction{Action: "analysisStop", Text: "Redis内存分析停止", ShouldLogin: true, StandAlone: true, Parent: Power})
+	analysisCleanPower    = base.AppendPower(&base.PowerAction{Action: "analysisClean", Text: "Redis内存分析清理", ShouldLogin: true, StandAlone: true, Parent: Power})
+	analysisDownloadPower = base.AppendPower(&base.PowerAction{Action: "analysisDownload", Text: "Redis内存分析下载", ShouldLogin: true, StandAlone: true, Parent: Power})
+
+	watchKeyPower   = base.AppendPower(&base.PowerAction{Action: "watchKey", Text: "Redis订阅监听会话", ShouldLogin: true, StandAlone: true, Parent: Power})
+	websocketPower  = base.AppendPower(&base.PowerAction{Action: "websocket", Text: "Redis订阅监听WebSocket", ShouldLogin: true, StandAlone: true, Parent: Power})
+	watchClosePower = base.AppendPower(&base.PowerAction{Action: "watchClose", Text: "Redis订阅监听关闭", ShouldLogin: true, StandAlone: true, Parent: Power})
+	publishPower    = base.AppendPower(&base.PowerAction{Action: "publish", Text: "Redis发布消息", ShouldLogin: true, StandAlone: true, Parent: Power})
 )
 
 func (this_ *api) GetApis() (apis []*base.ApiWorker) {
@@ -68,6 +103,34 @@ func (this_ *api) GetApis() (apis []*base.ApiWorker) {
 	apis = append(apis, &base.ApiWorker{Power: expirePower, Do: this_.expire})
 	apis = append(apis, &base.ApiWorker{Power: ttlPower, Do: this_.ttl})
 	apis = append(apis, &base.ApiWorker{Power: persistPower, Do: this_.persist})
+	apis = append(apis, &base.ApiWorker{Power: zaddPower, Do: this_.zadd})
+	apis = append(apis, &base.ApiWorker{Power: zremPower, Do: this_.zrem})
+	apis = append(apis, &base.ApiWorker{Power: zincrbyPower, Do: this_.zincrby})
+	apis = append(apis, &base.ApiWorker{Power: zrangePower, Do: this_.zrange})
+	apis = append(apis, &base.ApiWorker{Power: zrangeByScorePower, Do: this_.zrangeByScore})
+	apis = append(apis, &base.ApiWorker{Power: zscorePower, Do: this_.zscore})
+	apis = append(apis, &base.ApiWorker{Power: xaddPower, Do: this_.xadd})
+	apis = append(apis, &base.ApiWorker{Power: xdelPower, Do: this_.xdel})
+	apis = append(apis, &base.ApiWorker{Power: xlenPower, Do: this_.xlen})
+	apis = append(apis, &base.ApiWorker{Power: xrangePower, Do: this_.xrange})
+	apis = append(apis, &base.ApiWorker{Power: xgroupCreatePower, Do: this_.xgroupCreate})
+	apis = append(apis, &base.ApiWorker{Power: xgroupDestroyPower, Do: this_.xgroupDestroy})
+	apis = append(apis, &base.ApiWorker{Power: xgroupSetIdPower, Do: this_.xgroupSetId})
+	apis = append(apis, &base.ApiWorker{Power: xgroupDelConsumerPower, Do: this_.xgroupDelConsumer})
+	apis = append(apis, &base.ApiWorker{Power: xinfoGroupsPower, Do: this_.xinfoGroups})
+	apis = append(apis, &base.ApiWorker{Power: xreadgroupPower, Do: this_.xreadgroup})
+	apis = append(apis, &base.ApiWorker{Power: xackPower, Do: this_.xack})
+	apis = append(apis, &base.ApiWorker{Power: xpendingPower, Do: this_.xpending})
+	apis = append(apis, &base.ApiWorker{Power: consolePower, Do: this_.console})
+	apis = append(apis, &base.ApiWorker{Power: analysisPower, Do: this_.analysis})
+	apis = append(apis, &base.ApiWorker{Power: analysisStatusPower, Do: this_.analysisStatus, NotRecodeLog: true})
+	apis = append(apis, &base.ApiWorker{Power: analysisStopPower, Do: this_.analysisStop})
+	apis = append(apis, &base.ApiWorker{Power: analysisCleanPower, Do: this_.analysisClean, NotRecodeLog: true})
+	apis = append(apis, &base.ApiWorker{Power: analysisDownloadPower, Do: this_.analysisDownload})
+	apis = append(apis, &base.ApiWorker{Power: watchKeyPower, Do: this_.watchKey})
+	apis = append(apis, &base.ApiWorker{Power: websocketPower, Do: this_.websocket, IsWebSocket: true})
+	apis = append(apis, &base.ApiWorker{Power: watchClosePower, Do: this_.watchClose})
+	apis = append(apis, &base.ApiWorker{Power: publishPower, Do: this_.publish})
 	apis = append(apis, &base.ApiWorker{Power: closePower, Do: this_.close})
 
 	return
@@ -148,6 +211,29 @@ func getService(redisConfig *redis.Config, sshConfig *ssh.Config) (res redis.ISe
 	return
 }
 
+type databaseRequest interface {
+	getDatabase() int
+}
+
+// getClient 解析 请求 并 返回 已 切换 到 请求 库 的 go-redis 客户端
+func (this_ *api) getClient(requestBean *base.RequestBean, c *gin.Context, request databaseRequest) (client goRedis.Cmdable, err error) {
+	config, sshConfig, err := this_.getConfig(requestBean, c)
+	if err != nil {
+		return
+	}
+	service, err := getService(config, sshConfig)
+	if err != nil {
+		return
+	}
+
+	if !base.RequestJSON(request, c) {
+		err = errors.New("request json error")
+		return
+	}
+	client, err = service.GetClient(&redis.Param{Database: request.getDatabase()})
+	return
+}
+
 type BaseRequest struct {
 	Key        string `json:"key"`
 	KeyBase64  string `json:"keyBase64"`
@@ -175,6 +261,10 @@ func (this_ *BaseRequest) getKey() string {
 	return this_.Key
 }
 
+func (this_ *BaseRequest) getDatabase() int {
+	return this_.Database
+}
+
 func (this_ *api) check(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
 	config, sshConfig, err := this_.getConfig(requestBean, c)
 	if err != nil {
@@ -0,0 +1,357 @@
+package module_redis
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	goRedis "github.com/go-redis/redis/v8"
+	"github.com/team-ide/go-tool/util"
+	"go.uber.org/zap"
+	"net/http"
+	"net/url"
+	"sync"
+	"teamide/pkg/base"
+	"teamide/pkg/keyanalysis"
+	"time"
+)
+
+type AnalysisRequest struct {
+	BaseRequest
+	ScanCount int64                `json:"scanCount"` // 每次 SCAN 的 数量，默认 1000
+	Samples   int                  `json:"samples"`   // MEMORY USAGE 嵌套 类型 采样 数，默认 5
+	MaxKeys   int64                `json:"maxKeys"`   // 最多 分析 Key 数，为 0 不 限制
+	Options   *keyanalysis.Options `json:"options"`
+}
+
+// AnalysisTask 内存 及 大 Key 分析 任务，SCAN 库 中 的 Key，采样 内存、TTL、类型 并 按 前缀 汇总；集群 模式 扫描 所有 主节点
+type AnalysisTask struct {
+	TaskKey    string              `json:"taskKey"`
+	Database   int                 `json:"database"`
+	Pattern    string              `json:"pattern,omitempty"`
+	IsCluster  bool                `json:"isCluster"`
+	Nodes      []string            `json:"nodes,omitempty"`
+	ScanKeys   int64               `json:"scanKeys"`
+	ErrorCount int64               `json:"errorCount"`
+	LastError  string              `json:"lastError,omitempty"`
+	Error      string              `json:"error,omitempty"`
+	StartTime  int64               `json:"startTime"`
+	EndTime    int64               `json:"endTime,omitempty"`
+	IsEnd      bool                `json:"isEnd"`
+	IsStop     bool                `json:"isStop"`
+	Report     *keyanalysis.Report `json:"report,omitempty"`
+
+	scanCount int64
+	samples   int
+	maxKeys   int64
+	userId    int64
+	analyzer  *keyanalysis.Analyzer
+	lock      sync.Mutex
+}
+
+func (this_ *AnalysisTask) getInfo(withReport bool) (res *AnalysisTask) {
+	this_.lock.Lock()
+	defer this_.lock.Unlock()
+	res = &AnalysisTask{
+		TaskKey:    this_.TaskKey,
+		Database:   this_.Database,
+		Pattern:    this_.Pattern,
+		IsCluster:  this_.IsCluster,
+		Nodes:      append([]string{}, this_.Nodes...),
+		ScanKeys:   this_.ScanKeys,
+		ErrorCount: this_.ErrorCount,
+		LastError:  this_.LastError,
+		Error:      this_.Error,
+		StartTime:  this_.StartTime,
+		EndTime:    this_.EndTime,
+		IsEnd:      this_.IsEnd,
+		IsStop:     this_.IsStop,
+	}
+	if withReport {
+		// 结束 后 结果 不再 变化，只 汇总 一次
+		if this_.IsEnd {
+			if this_.Report == nil {
+				this_.Report = this_.analyzer.Report()
+			}
+			res.Report = this_.Report
+		} else {
+			res.Report = this_.analyzer.Report()
+		}
+	}
+	return
+}
+
+func (this_ *AnalysisTask) stop() {
+	this_.lock.Lock()
+	defer this_.lock.Unlock()
+	this_.IsStop = true
+}
+
+// isDone 已 停止 或 达到 最大 Key 数
+func (this_ *AnalysisTask) isDone() bool {
+	this_.lock.Lock()
+	defer this_.lock.Unlock()
+	return this_.IsStop || (this_.maxKeys > 0 && this_.ScanKeys >= this_.maxKeys)
+}
+
+func (this_ *AnalysisTask) run(client goRedis.Cmdable) {
+	defer func() {
+		if e := recover(); e != nil {
+			util.Logger.Error("redis analysis task panic", zap.Any("taskKey", this_.TaskKey), zap.Any("error", e))
+			this_.lock.Lock()
+			this_.Error = util.GetStringValue(e)
+			this_.lock.Unlock()
+		}
+		this_.lock.Lock()
+		this_.EndTime = util.GetNowMilli()
+		this_.IsEnd = true
+		this_.lock.Unlock()
+	}()
+
+	ctx := context.Background()
+	var err error
+	if cluster, ok := client.(*goRedis.ClusterClient); ok {
+		this_.lock.Lock()
+		this_.IsCluster = true
+		this_.lock.Unlock()
+		err = cluster.ForEachMaster(ctx, func(ctx context.Context, node *goRedis.Client) error {
+			return this_.scanNode(ctx, node, node.Options().Addr)
+		})
+	} else {
+		err = this_.scanNode(ctx, client, "")
+	}
+	if err != nil {
+		this_.lock.Lock()
+		this_.Error = err.Error()
+		this_.lock.Unlock()
+	}
+}
+
+// scanNode 扫描 单个 节点，每批 Key 使用 管道 查询 类型、TTL、内存，Key 已 删除 的 跳过
+func (this_ *AnalysisTask) scanNode(ctx context.Context, client goRedis.Cmdable, node string) (err error) {
+	if node != "" {
+		this_.lock.Lock()
+		this_.Nodes = append(this_.Nodes, node)
+		this_.lock.Unlock()
+	}
+	var cursor uint64
+	for {
+		if this_.isDone() {
+			return
+		}
+		var keys []string
+		keys, cursor, err = client.Scan(ctx, cursor, this_.Pattern, this_.scanCount).Result()
+		if err != nil {
+			return
+		}
+		if len(keys) > 0 {
+			this_.sampleKeys(ctx, client, node, keys)
+		}
+		if cursor == 0 {
+			return
+		}
+	}
+}
+
+func (this_ *AnalysisTask) sampleKeys(ctx context.Context, client goRedis.Cmdable, node string, keys []string) {
+	pipe := client.Pipeline()
+	typeCmdList := make([]*goRedis.StatusCmd, len(keys))
+	ttlCmdList := make([]*goRedis.DurationCmd, len(keys))
+	memoryCmdList := make([]*goRedis.IntCmd, len(keys))
+	for i, key := range keys {
+		typeCmdList[i] = pipe.Type(ctx, key)
+		ttlCmdList[i] = pipe.TTL(ctx, key)
+		memoryCmdList[i] = pipe.MemoryUsage(ctx, key, this_.samples)
+	}
+	_, _ = pipe.Exec(ctx)
+
+	this_.lock.Lock()
+	defer this_.lock.Unlock()
+	for i, key := range keys {
+		if this_.maxKeys > 0 && this_.ScanKeys >= this_.maxKeys {
+			return
+		}
+		keyType, err := typeCmdList[i].Result()
+		if err == nil && keyType == "none" {
+			continue
+		}
+		var memory int64
+		var ttl time.Duration
+		if err == nil {
+			memory, err = memoryCmdList[i].Result()
+		}
+		if err == nil {
+			ttl, err = ttlCmdList[i].Result()
+		}
+		if errors.Is(err, goRedis.Nil) {
+			continue
+		}
+		if err != nil {
+			this_.ErrorCount++
+			this_.LastError = key + ":" + err.Error()
+			continue
+		}
+		// 没有 过期 时间 返回 -1，不存在 返回 -2
+		ttlSecond := int64(ttl)
+		if ttl > 0 {
+			ttlSecond = int64(ttl / time.Second)
+		}
+		if ttlSecond == -2 {
+			continue
+		}
+		this_.ScanKeys++
+		this_.analyzer.Add(&keyanalysis.KeyInfo{
+			Key:    key,
+			Type:   keyType,
+			Memory: memory,
+			TTL:    ttlSecond,
+			Node:   node,
+		})
+	}
+}
+
+var analysisTaskCache = map[string]*AnalysisTask{}
+var analysisTaskCacheLock = &sync.Mutex{}
+
+func getAnalysisTask(taskKey string, userId int64) (task *AnalysisTask) {
+	analysisTaskCacheLock.Lock()
+	defer analysisTaskCacheLock.Unlock()
+	task = analysisTaskCache[taskKey]
+	if task != nil && task.userId != userId {
+		task = nil
+	}
+	return
+}
+
+// removeAnalysisTask 只 移除 已结束 的 任务
+func removeAnalysisTask(taskKey string, userId int64) {
+	analysisTaskCacheLock.Lock()
+	defer analysisTaskCacheLock.Unlock()
+	task := analysisTaskCache[taskKey]
+	if task == nil || task.userId != userId {
+		return
+	}
+	task.lock.Lock()
+	isEnd := task.IsEnd
+	task.lock.Unlock()
+	if isEnd {
+		delete(analysisTaskCache, taskKey)
+	}
+}
+
+func (this_ *api) analysis(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	request := &AnalysisRequest{}
+	client, err := this_.getClient(requestBean, c, request)
+	if err != nil {
+		return
+	}
+
+	task := &AnalysisTask{
+		TaskKey:   util.GetUUID(),
+		Database:  request.Database,
+		Pattern:   request.Pattern,
+		StartTime: util.GetNowMilli(),
+		scanCount: request.ScanCount,
+		samples:   request.Samples,
+		maxKeys:   request.MaxKeys,
+		userId:    requestBean.JWT.UserId,
+		analyzer:  keyanalysis.NewAnalyzer(request.Options),
+	}
+	if task.scanCount <= 0 {
+		task.scanCount = 1000
+	}
+	if task.samples <= 0 {
+		task.samples = 5
+	}
+
+	analysisTaskCacheLock.Lock()
+	analysisTaskCache[task.TaskKey] = task
+	analysisTaskCacheLock.Unlock()
+
+	go task.run(client)
+	res = task.getInfo(false)
+	return
+}
+
+func (this_ *api) analysisStatus(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	request := &AnalysisRequest{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+
+	// 运行 中 只 返回 进度，结束 后 返回 结果
+	task := getAnalysisTask(request.TaskKey, requestBean.JWT.UserId)
+	if task != nil {
+		info := task.getInfo(false)
+		if info.IsEnd {
+			info = task.getInfo(true)
+		}
+		res = info
+	}
+	return
+}
+
+func (this_ *api) analysisStop(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	request := &AnalysisRequest{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+
+	task := getAnalysisTask(request.TaskKey, requestBean.JWT.UserId)
+	if task != nil {
+		task.stop()
+		res = task.getInfo(false)
+	}
+	return
+}
+
+func (this_ *api) analysisClean(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	request := &AnalysisRequest{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+
+	removeAnalysisTask(request.TaskKey, requestBean.JWT.UserId)
+	return
+}
+
+// analysisDownload 下载 分析 结果 CSV，kind 为 bigKeys、noExpire、prefix，任务 未 结束 时 为 当前 进度 的 结果
+func (this_ *api) analysisDownload(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	data := map[string]string{}
+	err = c.Bind(&data)
+	if err != nil {
+		return
+	}
+
+	task := getAnalysisTask(data["taskKey"], requestBean.JWT.UserId)
+	if task == nil {
+		err = errors.New("任务不存在")
+		return
+	}
+	kind := data["kind"]
+	if kind == "" {
+		kind = keyanalysis.CsvBigKeys
+	}
+	buf := &bytes.Buffer{}
+	err = keyanalysis.WriteCSV(buf, task.getInfo(true).Report, kind)
+	if err != nil {
+		return
+	}
+
+	fileName := fmt.Sprint("redis-analysis-", kind, "-", time.Now().Format("20060102150405"), ".csv")
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", "attachment; filename="+url.QueryEscape(fileName))
+	c.Header("Content-Transfer-Encoding", "binary")
+	c.Header("Content-Length", fmt.Sprint(buf.Len()))
+	c.Header("download-file-name", fileName)
+
+	_, err = c.Writer.Write(buf.Bytes())
+	if err != nil {
+		return
+	}
+
+	c.Status(http.StatusOK)
+	res = base.HttpNotResponse
+	return
+}
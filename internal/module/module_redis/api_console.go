@@ -0,0 +1,205 @@
+package module_redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	goRedis "github.com/go-redis/redis/v8"
+	"strings"
+	"teamide/internal/module/module_toolbox"
+	"teamide/pkg/base"
+	"time"
+)
+
+const consoleTimeout = 30 * time.Second
+
+var (
+	// consoleForbidCommands 会 占用 或 改变 连接 状态 的 命令，控制台 始终 不允许 执行，库 使用 database 选择
+	consoleForbidCommands = []string{
+		"SUBSCRIBE", "PSUBSCRIBE", "SSUBSCRIBE", "MONITOR", "SYNC", "PSYNC",
+		"SELECT", "QUIT", "RESET", "MULTI", "EXEC", "DISCARD", "WATCH", "UNWATCH",
+	}
+	// ConsoleDefaultDenyCommands 工具箱 未 配置 commandDenyList 时 禁止 的 命令
+	ConsoleDefaultDenyCommands = []string{
+		"FLUSHALL", "FLUSHDB", "SHUTDOWN", "DEBUG", "CONFIG", "SAVE", "BGSAVE", "BGREWRITEAOF",
+		"REPLICAOF", "SLAVEOF", "MODULE", "ACL", "MIGRATE", "CLUSTER",
+	}
+	// consoleScriptCommands 脚本 中 可以 通过 redis.call 执行 任意 命令，存在 禁止 命令 时 一并 禁止
+	consoleScriptCommands = []string{
+		"EVAL", "EVALSHA", "EVAL_RO", "EVALSHA_RO", "FCALL", "FCALL_RO", "FUNCTION", "SCRIPT",
+	}
+)
+
+type ConsoleRequest struct {
+	BaseRequest
+	Command string `json:"command"`
+}
+
+// ConsoleConfig 控制台 配置，配置 在 工具箱 选项 中
+type ConsoleConfig struct {
+	CommandDenyList *string `json:"commandDenyList,omitempty"` // 禁止 的 命令，逗号 或 空格 分隔，配置 为 空 时 不 禁止
+}
+
+type doClient interface {
+	Do(ctx context.Context, args ...interface{}) *goRedis.Cmd
+}
+
+// getDenyCommands 工具箱 配置 的 禁止 命令，未 配置 时 使用 默认，存在 禁止 命令 时 同时 禁止 脚本 命令
+func getDenyCommands(requestBean *base.RequestBean) (denyCommands []string) {
+	consoleConfig := &ConsoleConfig{}
+	if toolbox, ok := requestBean.GetExtend("toolboxModel").(*module_toolbox.ToolboxModel); ok && toolbox != nil && toolbox.Option != "" {
+		_ = json.Unmarshal([]byte(toolbox.Option), consoleConfig)
+	}
+	if consoleConfig.CommandDenyList == nil {
+		denyCommands = append(denyCommands, ConsoleDefaultDenyCommands...)
+	} else {
+		for _, one := range strings.FieldsFunc(*consoleConfig.CommandDenyList, func(r rune) bool {
+			return r == ',' || r == ';' || r == ' ' || r == '\n' || r == '\t'
+		}) {
+			denyCommands = append(denyCommands, strings.ToUpper(one))
+		}
+	}
+	if len(denyCommands) > 0 {
+		denyCommands = append(denyCommands, consoleScriptCommands...)
+	}
+	return
+}
+
+// parseCommandLine 按 redis-cli 规则 拆分 命令，支持 单双引号，双引号 内 转义，单引号 内 \' 转义
+func parseCommandLine(line string) (args []string, err error) {
+	var current strings.Builder
+	var inArg bool
+	var quote rune
+	runes := []rune(line)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else if r == '\\' && quote == '"' && i+1 < len(runes) {
+				i++
+				switch runes[i] {
+				case 'n':
+					current.WriteRune('\n')
+				case 'r':
+					current.WriteRune('\r')
+				case 't':
+					current.WriteRune('\t')
+				default:
+					current.WriteRune(runes[i])
+				}
+			} else if r == '\\' && quote == '\'' && i+1 < len(runes) && runes[i+1] == '\'' {
+				i++
+				current.WriteRune('\'')
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		err = errors.New("unbalanced quotes in command")
+		return
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return
+}
+
+// formatConsoleResult 转换 返回 值，[]byte 转 字符串，便于 展示
+func formatConsoleResult(value interface{}) interface{} {
+	switch v := value.(type) {
+	case []byte:
+		return string(v)
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, one := range v {
+			list[i] = formatConsoleResult(one)
+		}
+		return list
+	case map[interface{}]interface{}:
+		data := map[string]interface{}{}
+		for key, one := range v {
+			data[fmt.Sprint(formatConsoleResult(key))] = formatConsoleResult(one)
+		}
+		return data
+	case error:
+		return v.Error()
+	}
+	return value
+}
+
+// console 在 指定 库 执行 任意 命令，禁止 的 命令 返回 错误
+func (this_ *api) console(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	request := &ConsoleRequest{}
+	client, err := this_.getClient(requestBean, c, request)
+	if err != nil {
+		return
+	}
+
+	args, err := parseCommandLine(request.Command)
+	if err != nil {
+		return
+	}
+	if len(args) == 0 {
+		err = errors.New("command is empty")
+		return
+	}
+	command := strings.ToUpper(args[0])
+	for _, one := range consoleForbidCommands {
+		if one == command {
+			err = errors.New("command [" + command + "] is not supported in console")
+			return
+		}
+	}
+	for _, one := range getDenyCommands(requestBean) {
+		if one == command {
+			err = errors.New("command [" + command + "] is denied by toolbox config")
+			return
+		}
+	}
+
+	do, ok := client.(doClient)
+	if !ok {
+		err = errors.New("redis client not support do command")
+		return
+	}
+	var cmdArgs []interface{}
+	for _, one := range args {
+		cmdArgs = append(cmdArgs, one)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), consoleTimeout)
+	defer cancel()
+
+	startTime := time.Now()
+	value, err := do.Do(ctx, cmdArgs...).Result()
+	if errors.Is(err, goRedis.Nil) {
+		err = nil
+	}
+	if err != nil {
+		return
+	}
+	res = map[string]interface{}{
+		"command":  command,
+		"args":     args,
+		"result":   formatConsoleResult(value),
+		"useTime":  time.Since(startTime).Milliseconds(),
+		"database": request.Database,
+	}
+	return
+}
@@ -0,0 +1,286 @@
+package module_redis
+
+import (
+	"context"
+	"errors"
+	"github.com/gin-gonic/gin"
+	goRedis "github.com/go-redis/redis/v8"
+	"strings"
+	"teamide/pkg/base"
+)
+
+type StreamRequest struct {
+	BaseRequest
+	Id       string                 `json:"id"`       // 消息 ID，XADD 默认 *
+	Ids      []string               `json:"ids"`      // XDEL、XACK 的 消息 ID
+	Values   map[string]interface{} `json:"values"`   // XADD 的 字段
+	MaxLen   int64                  `json:"maxLen"`   // XADD 后 裁剪 长度，为 0 不 裁剪
+	Approx   bool                   `json:"approx"`   // 裁剪 使用 ~ 近似
+	Start    string                 `json:"start"`    // 范围 起始，默认 -
+	End      string                 `json:"end"`      // 范围 结束，默认 +
+	Rev      bool                   `json:"rev"`      // 倒序 查询
+	Group    string                 `json:"group"`    // 消费组
+	Consumer string                 `json:"consumer"` // 消费者
+	MkStream bool                   `json:"mkStream"` // 创建 消费组 时 Stream 不存在 则 创建
+}
+
+type StreamMessage struct {
+	Id     string                 `json:"id"`
+	Values map[string]interface{} `json:"values"`
+}
+
+func toStreamMessages(list []goRedis.XMessage) (messages []*StreamMessage) {
+	messages = []*StreamMessage{}
+	for _, one := range list {
+		messages = append(messages, &StreamMessage{
+			Id:     one.ID,
+			Values: one.Values,
+		})
+	}
+	return
+}
+
+func (this_ *api) getStreamClient(requestBean *base.RequestBean, c *gin.Context) (client goRedis.Cmdable, request *StreamRequest, err error) {
+	request = &StreamRequest{}
+	client, err = this_.getClient(requestBean, c, request)
+	return
+}
+
+func (this_ *api) xadd(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	client, request, err := this_.getStreamClient(requestBean, c)
+	if err != nil {
+		return
+	}
+	if len(request.Values) == 0 {
+		err = errors.New("stream values is empty")
+		return
+	}
+
+	args := &goRedis.XAddArgs{
+		Stream: request.getKey(),
+		ID:     request.Id,
+		Values: request.Values,
+	}
+	if request.MaxLen > 0 {
+		if request.Approx {
+			args.MaxLenApprox = request.MaxLen
+		} else {
+			args.MaxLen = request.MaxLen
+		}
+	}
+	res, err = client.XAdd(context.Background(), args).Result()
+	return
+}
+
+func (this_ *api) xdel(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	client, request, err := this_.getStreamClient(requestBean, c)
+	if err != nil {
+		return
+	}
+
+	res, err = client.XDel(context.Background(), request.getKey(), request.Ids...).Result()
+	return
+}
+
+func (this_ *api) xlen(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	client, request, err := this_.getStreamClient(requestBean, c)
+	if err != nil {
+		return
+	}
+
+	res, err = client.XLen(context.Background(), request.getKey()).Result()
+	return
+}
+
+// xrange 范围 查询，count 为 0 时 最多 返回 100 条
+func (this_ *api) xrange(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	client, request, err := this_.getStreamClient(requestBean, c)
+	if err != nil {
+		return
+	}
+
+	ctx := context.Background()
+	start, end := request.Start, request.End
+	if start == "" {
+		start = "-"
+	}
+	if end == "" {
+		end = "+"
+	}
+	count := request.Count
+	if count <= 0 {
+		count = 100
+	}
+	total, err := client.XLen(ctx, request.getKey()).Result()
+	if err != nil {
+		return
+	}
+	var list []goRedis.XMessage
+	if request.Rev {
+		list, err = client.XRevRangeN(ctx, request.getKey(), end, start, count).Result()
+	} else {
+		list, err = client.XRangeN(ctx, request.getKey(), start, end, count).Result()
+	}
+	if err != nil {
+		return
+	}
+	res = map[string]interface{}{
+		"total":    total,
+		"messages": toStreamMessages(list),
+	}
+	return
+}
+
+// xgroupCreate 创建 消费组，id 为 起始 消息，默认 $ 只 消费 新 消息
+func (this_ *api) xgroupCreate(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	client, request, err := this_.getStreamClient(requestBean, c)
+	if err != nil {
+		return
+	}
+
+	id := request.Id
+	if id == "" {
+		id = "$"
+	}
+	if request.MkStream {
+		res, err = client.XGroupCreateMkStream(context.Background(), request.getKey(), request.Group, id).Result()
+	} else {
+		res, err = client.XGroupCreate(context.Background(), request.getKey(), request.Group, id).Result()
+	}
+	return
+}
+
+func (this_ *api) xgroupDestroy(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	client, request, err := this_.getStreamClient(requestBean, c)
+	if err != nil {
+		return
+	}
+
+	res, err = client.XGroupDestroy(context.Background(), request.getKey(), request.Group).Result()
+	return
+}
+
+func (this_ *api) xgroupSetId(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	client, request, err := this_.getStreamClient(requestBean, c)
+	if err != nil {
+		return
+	}
+
+	res, err = client.XGroupSetID(context.Background(), request.getKey(), request.Group, request.Id).Result()
+	return
+}
+
+func (this_ *api) xgroupDelConsumer(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	client, request, err := this_.getStreamClient(requestBean, c)
+	if err != nil {
+		return
+	}
+
+	res, err = client.XGroupDelConsumer(context.Background(), request.getKey(), request.Group, request.Consumer).Result()
+	return
+}
+
+// xinfoGroups 消费组 列表，指定 group 时 返回 该组 的 消费者
+func (this_ *api) xinfoGroups(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	client, request, err := this_.getStreamClient(requestBean, c)
+	if err != nil {
+		return
+	}
+
+	if request.Group != "" {
+		res, err = client.XInfoConsumers(context.Background(), request.getKey(), request.Group).Result()
+		return
+	}
+	res, err = client.XInfoGroups(context.Background(), request.getKey()).Result()
+	return
+}
+
+// xreadgroup 以 消费者 身份 读取，不 阻塞，id 默认 > 读取 新 消息，传 0 读取 自己 未确认 的 消息
+func (this_ *api) xreadgroup(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	client, request, err := this_.getStreamClient(requestBean, c)
+	if err != nil {
+		return
+	}
+
+	id := request.Id
+	if id == "" {
+		id = ">"
+	}
+	count := request.Count
+	if count <= 0 {
+		count = 100
+	}
+	streams, err := client.XReadGroup(context.Background(), &goRedis.XReadGroupArgs{
+		Group:    request.Group,
+		Consumer: request.Consumer,
+		Streams:  []string{request.getKey(), id},
+		Count:    count,
+		Block:    -1,
+	}).Result()
+	if errors.Is(err, goRedis.Nil) {
+		err = nil
+	}
+	if err != nil {
+		return
+	}
+	var messages []*StreamMessage
+	for _, one := range streams {
+		messages = append(messages, toStreamMessages(one.Messages)...)
+	}
+	if messages == nil {
+		messages = []*StreamMessage{}
+	}
+	res = messages
+	return
+}
+
+func (this_ *api) xack(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	client, request, err := this_.getStreamClient(requestBean, c)
+	if err != nil {
+		return
+	}
+
+	res, err = client.XAck(context.Background(), request.getKey(), request.Group, request.Ids...).Result()
+	return
+}
+
+// xpending 待确认 消息，返回 汇总 及 明细，可 按 消费者 过滤
+func (this_ *api) xpending(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	client, request, err := this_.getStreamClient(requestBean, c)
+	if err != nil {
+		return
+	}
+
+	ctx := context.Background()
+	summary, err := client.XPending(ctx, request.getKey(), request.Group).Result()
+	if err != nil {
+		return
+	}
+	start, end := request.Start, request.End
+	if start == "" {
+		start = "-"
+	}
+	if end == "" {
+		end = "+"
+	}
+	count := request.Count
+	if count <= 0 {
+		count = 100
+	}
+	list, err := client.XPendingExt(ctx, &goRedis.XPendingExtArgs{
+		Stream:   request.getKey(),
+		Group:    request.Group,
+		Start:    start,
+		End:      end,
+		Count:    count,
+		Consumer: strings.TrimSpace(request.Consumer),
+	}).Result()
+	if err != nil {
+		return
+	}
+	res = map[string]interface{}{
+		"summary": summary,
+		"list":    list,
+	}
+	return
+}
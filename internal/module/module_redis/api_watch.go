@@ -0,0 +1,106 @@
+package module_redis
+
+import (
+	"context"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/team-ide/go-tool/util"
+	"go.uber.org/zap"
+	"teamide/pkg/base"
+	"teamide/pkg/wsattach"
+)
+
+type WatchRequest struct {
+	BaseRequest
+	WatchConfig
+	WatchKey string `json:"watchKey"`
+	Channel  string `json:"channel"`
+	Message  string `json:"message"`
+}
+
+// watchKey 创建 订阅 或 MONITOR 会话，返回 websocket 连接 使用 的 key
+func (this_ *api) watchKey(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	request := &WatchRequest{}
+	client, err := this_.getClient(requestBean, c, request)
+	if err != nil {
+		return
+	}
+
+	service, err := createWatchService(&request.WatchConfig, client, requestBean.JWT.UserId)
+	if err != nil {
+		return
+	}
+	setWatchService(service.Key, service)
+	data := make(map[string]interface{})
+	data["key"] = service.Key
+	res = data
+	return
+}
+
+func (this_ *api) websocket(request *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+
+	if request.JWT == nil || request.JWT.UserId == 0 {
+		err = errors.New("登录用户获取失败")
+		return
+	}
+	key := c.Query("key")
+	if key == "" {
+		err = errors.New("key获取失败")
+		return
+	}
+	//升级get请求为webSocket协议
+	ws, err := wsattach.Upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		return
+	}
+
+	service := getWatchService(key, request.JWT.UserId)
+	if service == nil {
+		err = errors.New("会话[" + key + "]不存在")
+
+		_ = ws.WriteMessage(websocket.TextMessage, []byte(`{"type":"error","payload":"service not found"}`))
+		util.Logger.Error("redis websocket start error", zap.Error(err))
+		_ = ws.Close()
+		return
+	}
+
+	err = service.attach(ws)
+	if err != nil {
+		service.sendError(err)
+		util.Logger.Error("redis websocket start error", zap.Error(err))
+		service.stop()
+		return
+	}
+
+	res = base.HttpNotResponse
+	return
+}
+
+func (this_ *api) watchClose(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	request := &WatchRequest{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+
+	service := getWatchService(request.WatchKey, requestBean.JWT.UserId)
+	if service != nil {
+		service.stop()
+	}
+	return
+}
+
+func (this_ *api) publish(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	request := &WatchRequest{}
+	client, err := this_.getClient(requestBean, c, request)
+	if err != nil {
+		return
+	}
+	if request.Channel == "" {
+		err = errors.New("channel is empty")
+		return
+	}
+
+	res, err = client.Publish(context.Background(), request.Channel, request.Message).Result()
+	return
+}
@@ -0,0 +1,187 @@
+package module_redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	goRedis "github.com/go-redis/redis/v8"
+	"teamide/pkg/base"
+	"time"
+)
+
+type ZSetRequest struct {
+	BaseRequest
+	Members []*ZMember `json:"members"`
+	Score   float64    `json:"score"`
+	Start   int64      `json:"start"`
+	Stop    int64      `json:"stop"`
+	Min     string     `json:"min"` // 分数 范围，支持 -inf、+inf、( 开区间
+	Max     string     `json:"max"`
+	Offset  int64      `json:"offset"`
+	Rev     bool       `json:"rev"`
+}
+
+type ZMember struct {
+	Member string  `json:"member"`
+	Score  float64 `json:"score"`
+}
+
+type ZRangeResult struct {
+	Total   int64      `json:"total"`
+	Members []*ZMember `json:"members"`
+}
+
+func (this_ *api) getZSetClient(requestBean *base.RequestBean, c *gin.Context) (client goRedis.Cmdable, request *ZSetRequest, err error) {
+	request = &ZSetRequest{}
+	client, err = this_.getClient(requestBean, c, request)
+	return
+}
+
+func toZMembers(list []goRedis.Z) (members []*ZMember) {
+	members = []*ZMember{}
+	for _, one := range list {
+		members = append(members, &ZMember{
+			Member: fmt.Sprint(one.Member),
+			Score:  one.Score,
+		})
+	}
+	return
+}
+
+func (this_ *api) zadd(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	client, request, err := this_.getZSetClient(requestBean, c)
+	if err != nil {
+		return
+	}
+
+	var members []*goRedis.Z
+	for _, one := range request.Members {
+		members = append(members, &goRedis.Z{Member: one.Member, Score: one.Score})
+	}
+	if len(members) == 0 {
+		members = append(members, &goRedis.Z{Member: request.Value, Score: request.Score})
+	}
+	res, err = client.ZAdd(context.Background(), request.getKey(), members...).Result()
+	if err != nil {
+		return
+	}
+	if request.Expire > 0 {
+		_, err = client.Expire(context.Background(), request.getKey(), time.Duration(request.Expire)*time.Second).Result()
+	}
+	return
+}
+
+func (this_ *api) zrem(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	client, request, err := this_.getZSetClient(requestBean, c)
+	if err != nil {
+		return
+	}
+
+	var members []interface{}
+	for _, one := range request.Members {
+		members = append(members, one.Member)
+	}
+	if len(members) == 0 {
+		members = append(members, request.Value)
+	}
+	res, err = client.ZRem(context.Background(), request.getKey(), members...).Result()
+	return
+}
+
+func (this_ *api) zincrby(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	client, request, err := this_.getZSetClient(requestBean, c)
+	if err != nil {
+		return
+	}
+
+	res, err = client.ZIncrBy(context.Background(), request.getKey(), request.Score, request.Value).Result()
+	return
+}
+
+// zrange 按 排名 查询，start、stop 同 ZRANGE，stop 为 0 时 查询 前 100 个
+func (this_ *api) zrange(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	client, request, err := this_.getZSetClient(requestBean, c)
+	if err != nil {
+		return
+	}
+
+	ctx := context.Background()
+	stop := request.Stop
+	if stop == 0 {
+		stop = request.Start + 99
+	}
+	result := &ZRangeResult{}
+	result.Total, err = client.ZCard(ctx, request.getKey()).Result()
+	if err != nil {
+		return
+	}
+	var list []goRedis.Z
+	if request.Rev {
+		list, err = client.ZRevRangeWithScores(ctx, request.getKey(), request.Start, stop).Result()
+	} else {
+		list, err = client.ZRangeWithScores(ctx, request.getKey(), request.Start, stop).Result()
+	}
+	if err != nil {
+		return
+	}
+	result.Members = toZMembers(list)
+	res = result
+	return
+}
+
+// zrangeByScore 按 分数 查询，count 为 0 时 最多 返回 100 个
+func (this_ *api) zrangeByScore(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	client, request, err := this_.getZSetClient(requestBean, c)
+	if err != nil {
+		return
+	}
+
+	ctx := context.Background()
+	by := &goRedis.ZRangeBy{
+		Min:    request.Min,
+		Max:    request.Max,
+		Offset: request.Offset,
+		Count:  request.Count,
+	}
+	if by.Min == "" {
+		by.Min = "-inf"
+	}
+	if by.Max == "" {
+		by.Max = "+inf"
+	}
+	if by.Count <= 0 {
+		by.Count = 100
+	}
+	result := &ZRangeResult{}
+	result.Total, err = client.ZCount(ctx, request.getKey(), by.Min, by.Max).Result()
+	if err != nil {
+		return
+	}
+	var list []goRedis.Z
+	if request.Rev {
+		list, err = client.ZRevRangeByScoreWithScores(ctx, request.getKey(), by).Result()
+	} else {
+		list, err = client.ZRangeByScoreWithScores(ctx, request.getKey(), by).Result()
+	}
+	if err != nil {
+		return
+	}
+	result.Members = toZMembers(list)
+	res = result
+	return
+}
+
+func (this_ *api) zscore(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	client, request, err := this_.getZSetClient(requestBean, c)
+	if err != nil {
+		return
+	}
+
+	res, err = client.ZScore(context.Background(), request.getKey(), request.Value).Result()
+	if errors.Is(err, goRedis.Nil) {
+		res = nil
+		err = nil
+	}
+	return
+}
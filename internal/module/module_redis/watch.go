@@ -0,0 +1,524 @@
+package module_redis
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	goRedis "github.com/go-redis/redis/v8"
+	"github.com/gorilla/websocket"
+	"github.com/team-ide/go-tool/util"
+	"go.uber.org/zap"
+	"net"
+	"regexp"
+	"strings"
+	"sync"
+	"teamide/pkg/wsattach"
+	"time"
+)
+
+const (
+	// WatchTypePubSub 订阅 频道 及 模式
+	WatchTypePubSub = "pubsub"
+	// WatchTypeMonitor 实时 查看 MONITOR 输出
+	WatchTypeMonitor = "monitor"
+
+	defaultWatchRate = 200
+	droppedInterval  = time.Second
+
+	// watchAttachTimeout 创建 后 超过 该 时间 未 连接 websocket 的 会话 停止
+	watchAttachTimeout = time.Minute
+)
+
+// WatchConfig 监听 配置
+type WatchConfig struct {
+	WatchType string   `json:"watchType"`
+	Channels  []string `json:"channels,omitempty"`
+	Patterns  []string `json:"patterns,omitempty"`
+	Filter    string   `json:"filter,omitempty"` // 正则，匹配 频道 或 内容 的 事件 才 推送
+	Rate      int      `json:"rate,omitempty"`   // 每秒 最多 推送 事件 数，默认 200，超出 的 丢弃 并 汇总 推送 丢弃 数
+}
+
+// WatchEvent 推送 到 websocket 的 事件
+type WatchEvent struct {
+	Type    string `json:"type"` // message、pmessage、subscription、monitor、dropped、error
+	Channel string `json:"channel,omitempty"`
+	Pattern string `json:"pattern,omitempty"`
+	Payload string `json:"payload,omitempty"`
+	Node    string `json:"node,omitempty"`
+	Count   int64  `json:"count,omitempty"`
+	Time    int64  `json:"time"`
+}
+
+// watchAction websocket 收到 的 指令
+type watchAction struct {
+	Action   string   `json:"action"` // subscribe、unsubscribe、psubscribe、punsubscribe、filter
+	Channels []string `json:"channels,omitempty"`
+	Filter   string   `json:"filter,omitempty"`
+}
+
+type subscriber interface {
+	Subscribe(ctx context.Context, channels ...string) *goRedis.PubSub
+}
+
+type optionsClient interface {
+	Options() *goRedis.Options
+}
+
+var (
+	watchCache     = map[string]*WatchService{}
+	watchCacheLock = &sync.Mutex{}
+)
+
+// getWatchService 获取 用户 的 会话，不是 该 用户 的 返回 nil
+func getWatchService(key string, userId int64) (service *WatchService) {
+	watchCacheLock.Lock()
+	defer watchCacheLock.Unlock()
+	service = watchCache[key]
+	if service != nil && service.userId != userId {
+		service = nil
+	}
+	return
+}
+
+func removeWatchService(key string) {
+	watchCacheLock.Lock()
+	defer watchCacheLock.Unlock()
+	delete(watchCache, key)
+}
+
+func setWatchService(key string, service *WatchService) {
+	watchCacheLock.Lock()
+	defer watchCacheLock.Unlock()
+	watchCache[key] = service
+}
+
+// rateLimiter 令牌桶
+type rateLimiter struct {
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+func (this_ *rateLimiter) allow(now time.Time) bool {
+	if this_.last.IsZero() {
+		this_.tokens = this_.rate
+	} else {
+		this_.tokens += now.Sub(this_.last).Seconds() * this_.rate
+		if this_.tokens > this_.rate {
+			this_.tokens = this_.rate
+		}
+	}
+	this_.last = now
+	if this_.tokens < 1 {
+		return false
+	}
+	this_.tokens--
+	return true
+}
+
+// WatchService 一个 websocket 的 订阅 或 MONITOR 会话，首次 连接 websocket 时 开始，当前 websocket 关闭 时 停止
+type WatchService struct {
+	Key string
+	*WatchConfig
+	client goRedis.Cmdable
+	userId int64
+
+	socket    *wsattach.Socket
+	pubSub    *goRedis.PubSub
+	conns     []net.Conn
+	filter    *regexp.Regexp
+	limiter   *rateLimiter
+	dropped   int64
+	ctx       context.Context
+	cancel    context.CancelFunc
+	isStopped bool
+	lock      sync.Mutex
+}
+
+func createWatchService(config *WatchConfig, client goRedis.Cmdable, userId int64) (service *WatchService, err error) {
+	if config.WatchType != WatchTypePubSub && config.WatchType != WatchTypeMonitor {
+		err = errors.New("不支持的监听类型[" + config.WatchType + "]，支持 pubsub、monitor")
+		return
+	}
+	rate := config.Rate
+	if rate <= 0 {
+		rate = defaultWatchRate
+	}
+	service = &WatchService{
+		Key:         util.GetUUID(),
+		WatchConfig: config,
+		client:      client,
+		userId:      userId,
+		limiter:     &rateLimiter{rate: float64(rate)},
+	}
+	if err = service.setFilter(config.Filter); err != nil {
+		return
+	}
+	service.socket = wsattach.New(watchAttachTimeout, func() {
+		util.Logger.Info("redis watch attach timeout stop", zap.Any("key", service.Key))
+		service.stop()
+	})
+	return
+}
+
+func (this_ *WatchService) setFilter(filter string) (err error) {
+	var reg *regexp.Regexp
+	if filter != "" {
+		reg, err = regexp.Compile(filter)
+		if err != nil {
+			err = errors.New("过滤正则[" + filter + "]错误:" + err.Error())
+			return
+		}
+	}
+	this_.lock.Lock()
+	this_.filter = reg
+	this_.lock.Unlock()
+	return
+}
+
+// attach 连接 websocket，首次 连接 时 开始 订阅 或 MONITOR，重复 连接 时 替换 之前 的 websocket
+func (this_ *WatchService) attach(ws *websocket.Conn) (err error) {
+	first, err := this_.socket.Attach(ws)
+	if err != nil {
+		return
+	}
+	if first {
+		if err = this_.start(); err != nil {
+			return
+		}
+	}
+	go this_.startReadWS(ws)
+	return
+}
+
+func (this_ *WatchService) start() (err error) {
+	this_.lock.Lock()
+	if this_.isStopped {
+		this_.lock.Unlock()
+		err = wsattach.ErrClosed
+		return
+	}
+	this_.ctx, this_.cancel = context.WithCancel(context.Background())
+	this_.lock.Unlock()
+
+	if this_.WatchType == WatchTypePubSub {
+		err = this_.startPubSub()
+	} else {
+		err = this_.startMonitor()
+	}
+	if err != nil {
+		this_.cancel()
+		return
+	}
+
+	go this_.startSendDropped()
+	return
+}
+
+func (this_ *WatchService) stop() {
+	this_.lock.Lock()
+	if this_.isStopped {
+		this_.lock.Unlock()
+		return
+	}
+	this_.isStopped = true
+	conns := this_.conns
+	cancel := this_.cancel
+	this_.lock.Unlock()
+
+	removeWatchService(this_.Key)
+	if cancel != nil {
+		cancel()
+	}
+	if this_.pubSub != nil {
+		_ = this_.pubSub.Close()
+	}
+	for _, conn := range conns {
+		_ = conn.Close()
+	}
+	this_.socket.Close()
+}
+
+func (this_ *WatchService) stopped() bool {
+	this_.lock.Lock()
+	defer this_.lock.Unlock()
+	return this_.isStopped
+}
+
+// send 过滤、限流 后 推送，被 限流 的 累计 丢弃 数
+func (this_ *WatchService) send(event *WatchEvent) {
+	this_.lock.Lock()
+	if this_.filter != nil && !this_.filter.MatchString(event.Channel) && !this_.filter.MatchString(event.Payload) {
+		this_.lock.Unlock()
+		return
+	}
+	now := time.Now()
+	if !this_.limiter.allow(now) {
+		this_.dropped++
+		this_.lock.Unlock()
+		return
+	}
+	this_.lock.Unlock()
+
+	event.Time = now.UnixMilli()
+	this_.write(event)
+}
+
+func (this_ *WatchService) write(event *WatchEvent) {
+	bs, _ := json.Marshal(event)
+	ws, err := this_.socket.Write(bs)
+	if err != nil && !this_.stopped() && this_.socket.Current(ws) {
+		util.Logger.Error("redis watch ws write error", zap.Error(err))
+		this_.stop()
+	}
+}
+
+func (this_ *WatchService) sendError(err error) {
+	this_.write(&WatchEvent{Type: "error", Payload: err.Error(), Time: util.GetNowMilli()})
+}
+
+func (this_ *WatchService) startSendDropped() {
+	ticker := time.NewTicker(droppedInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-this_.ctx.Done():
+			return
+		case <-ticker.C:
+			this_.lock.Lock()
+			dropped := this_.dropped
+			this_.dropped = 0
+			this_.lock.Unlock()
+			if dropped > 0 {
+				this_.write(&WatchEvent{Type: "dropped", Count: dropped, Time: util.GetNowMilli()})
+			}
+		}
+	}
+}
+
+func (this_ *WatchService) startPubSub() (err error) {
+	sub, ok := this_.client.(subscriber)
+	if !ok {
+		err = errors.New("redis client not support subscribe")
+		return
+	}
+	this_.pubSub = sub.Subscribe(this_.ctx)
+	if len(this_.Channels) > 0 {
+		if err = this_.pubSub.Subscribe(this_.ctx, this_.Channels...); err != nil {
+			return
+		}
+	}
+	if len(this_.Patterns) > 0 {
+		if err = this_.pubSub.PSubscribe(this_.ctx, this_.Patterns...); err != nil {
+			return
+		}
+	}
+	go this_.startReadPubSub()
+	return
+}
+
+func (this_ *WatchService) startReadPubSub() {
+	defer func() {
+		if e := recover(); e != nil {
+			util.Logger.Error("redis watch pubsub panic error", zap.Any("error", e))
+		}
+		this_.stop()
+	}()
+
+	for {
+		msg, err := this_.pubSub.Receive(this_.ctx)
+		if err != nil {
+			if !this_.stopped() {
+				util.Logger.Error("redis watch pubsub receive error", zap.Error(err))
+			}
+			return
+		}
+		switch m := msg.(type) {
+		case *goRedis.Message:
+			eventType := "message"
+			if m.Pattern != "" {
+				eventType = "pmessage"
+			}
+			this_.send(&WatchEvent{Type: eventType, Channel: m.Channel, Pattern: m.Pattern, Payload: m.Payload})
+		case *goRedis.Subscription:
+			this_.write(&WatchEvent{Type: "subscription", Channel: m.Channel, Payload: m.Kind, Count: int64(m.Count), Time: util.GetNowMilli()})
+		}
+	}
+}
+
+// startMonitor 使用 独立 连接 执行 MONITOR，集群 模式 监听 所有 主节点
+func (this_ *WatchService) startMonitor() (err error) {
+	var optionsList []*goRedis.Options
+	if cluster, ok := this_.client.(*goRedis.ClusterClient); ok {
+		var lock sync.Mutex
+		err = cluster.ForEachMaster(this_.ctx, func(ctx context.Context, node *goRedis.Client) error {
+			lock.Lock()
+			optionsList = append(optionsList, node.Options())
+			lock.Unlock()
+			return nil
+		})
+		if err != nil {
+			return
+		}
+	} else if one, ok := this_.client.(optionsClient); ok {
+		optionsList = append(optionsList, one.Options())
+	} else {
+		err = errors.New("redis client not support monitor")
+		return
+	}
+
+	for _, options := range optionsList {
+		var conn net.Conn
+		var reader *bufio.Reader
+		conn, reader, err = openMonitor(this_.ctx, options)
+		if err != nil {
+			err = errors.New("monitor [" + options.Addr + "] error:" + err.Error())
+			return
+		}
+		this_.lock.Lock()
+		this_.conns = append(this_.conns, conn)
+		this_.lock.Unlock()
+		go this_.startReadMonitor(options.Addr, reader)
+	}
+	return
+}
+
+// openMonitor 使用 客户端 的 拨号器 建立 连接，认证 后 发送 MONITOR
+func openMonitor(ctx context.Context, options *goRedis.Options) (conn net.Conn, reader *bufio.Reader, err error) {
+	dialer := options.Dialer
+	if dialer == nil {
+		dialer = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return (&net.Dialer{Timeout: 5 * time.Second}).DialContext(ctx, network, addr)
+		}
+	}
+	network := options.Network
+	if network == "" {
+		network = "tcp"
+	}
+	conn, err = dialer(ctx, network, options.Addr)
+	if err != nil {
+		return
+	}
+	reader = bufio.NewReader(conn)
+
+	var commands [][]string
+	if options.Password != "" {
+		if options.Username != "" {
+			commands = append(commands, []string{"AUTH", options.Username, options.Password})
+		} else {
+			commands = append(commands, []string{"AUTH", options.Password})
+		}
+	}
+	commands = append(commands, []string{"MONITOR"})
+	_ = conn.SetDeadline(time.Now().Add(10 * time.Second))
+	for _, args := range commands {
+		if _, err = conn.Write(encodeCommand(args)); err != nil {
+			break
+		}
+		var line string
+		if line, err = readLine(reader); err != nil {
+			break
+		}
+		if strings.HasPrefix(line, "-") {
+			err = errors.New(strings.TrimPrefix(line, "-"))
+			break
+		}
+	}
+	if err != nil {
+		_ = conn.Close()
+		return
+	}
+	_ = conn.SetDeadline(time.Time{})
+	return
+}
+
+func encodeCommand(args []string) []byte {
+	var buf strings.Builder
+	buf.WriteString(fmt.Sprint("*", len(args), "\r\n"))
+	for _, one := range args {
+		buf.WriteString(fmt.Sprint("$", len(one), "\r\n", one, "\r\n"))
+	}
+	return []byte(buf.String())
+}
+
+func readLine(reader *bufio.Reader) (line string, err error) {
+	line, err = reader.ReadString('\n')
+	line = strings.TrimRight(line, "\r\n")
+	return
+}
+
+func (this_ *WatchService) startReadMonitor(node string, reader *bufio.Reader) {
+	defer func() {
+		if e := recover(); e != nil {
+			util.Logger.Error("redis watch monitor panic error", zap.Any("error", e))
+		}
+		this_.stop()
+	}()
+
+	for {
+		line, err := readLine(reader)
+		if err != nil {
+			if !this_.stopped() {
+				util.Logger.Error("redis watch monitor read error", zap.Any("node", node), zap.Error(err))
+			}
+			return
+		}
+		if strings.HasPrefix(line, "-") {
+			this_.sendError(errors.New(strings.TrimPrefix(line, "-")))
+			return
+		}
+		this_.send(&WatchEvent{Type: "monitor", Node: node, Payload: strings.TrimPrefix(line, "+")})
+	}
+}
+
+// startReadWS 读取 websocket 指令，websocket 关闭 时 停止 会话，已 被 新 连接 替换 的 不 停止
+func (this_ *WatchService) startReadWS(ws *websocket.Conn) {
+	defer func() {
+		if e := recover(); e != nil {
+			util.Logger.Error("redis watch read ws panic error", zap.Any("error", e))
+		}
+		if this_.socket.Current(ws) {
+			this_.stop()
+		}
+	}()
+
+	for {
+		_, buf, err := ws.ReadMessage()
+		if err != nil {
+			return
+		}
+		action := &watchAction{}
+		if err = json.Unmarshal(buf, action); err != nil {
+			this_.sendError(err)
+			continue
+		}
+		if err = this_.doAction(action); err != nil {
+			this_.sendError(err)
+		}
+	}
+}
+
+func (this_ *WatchService) doAction(action *watchAction) (err error) {
+	if action.Action == "filter" {
+		return this_.setFilter(action.Filter)
+	}
+	if this_.pubSub == nil {
+		err = errors.New("action [" + action.Action + "] only support pubsub")
+		return
+	}
+	switch action.Action {
+	case "subscribe":
+		err = this_.pubSub.Subscribe(this_.ctx, action.Channels...)
+	case "unsubscribe":
+		err = this_.pubSub.Unsubscribe(this_.ctx, action.Channels...)
+	case "psubscribe":
+		err = this_.pubSub.PSubscribe(this_.ctx, action.Channels...)
+	case "punsubscribe":
+		err = this_.pubSub.PUnsubscribe(this_.ctx, action.Channels...)
+	default:
+		err = errors.New("不支持的指令[" + action.Action + "]")
+	}
+	return
+}
@@ -18,6 +18,7 @@ import (
 	"teamide/internal/module/module_toolbox"
 	"teamide/pkg/base"
 	"teamide/pkg/ssh"
+	"teamide/pkg/system"
 	"teamide/pkg/terminal"
 )
 
@@ -50,6 +51,11 @@ var (
 	upload          = base.AppendPower(&base.PowerAction{Action: "upload", Text: "upload", ShouldLogin: true, StandAlone: true, Parent: Power})
 	systemInfo      = base.AppendPower(&base.PowerAction{Action: "system/info", Text: "system", ShouldLogin: true, StandAlone: true, Parent: Power})
 	systemMonitor   = base.AppendPower(&base.PowerAction{Action: "system/monitor", Text: "system", ShouldLogin: true, StandAlone: true, Parent: Power})
+	systemQuery     = base.AppendPower(&base.PowerAction{Action: "system/query", Text: "system", ShouldLogin: true, StandAlone: true, Parent: Power})
+	systemProcesses = base.AppendPower(&base.PowerAction{Action: "system/processes", Text: "进程列表", ShouldLogin: true, StandAlone: true, Parent: Power})
+	systemPorts     = base.AppendPower(&base.PowerAction{Action: "system/ports", Text: "监听端口", ShouldLogin: true, StandAlone: true, Parent: Power})
+	systemSignal    = base.AppendPower(&base.PowerAction{Action: "system/signal", Text: "进程信号", ShouldLogin: true, StandAlone: true, Parent: Power})
+	batchExec       = base.AppendPower(&base.PowerAction{Action: "batch/exec", Text: "多主机批量执行", ShouldLogin: true, StandAlone: true, Parent: Power})
 
 	command       = base.AppendPower(&base.PowerAction{Action: "command", Text: "命令行", ShouldLogin: true, StandAlone: true, Parent: Power})
 	commandSave   = base.AppendPower(&base.PowerAction{Action: "save", Text: "插入", ShouldLogin: true, StandAlone: true, Parent: command})
@@ -72,6 +78,11 @@ func (this_ *api) GetApis() (apis []*base.ApiWorker) {
 	apis = append(apis, &base.ApiWorker{Power: upload, Do: this_.upload, IsUpload: true, NotRecodeLog: true})
 	apis = append(apis, &base.ApiWorker{Power: systemInfo, Do: this_.systemInfo})
 	apis = append(apis, &base.ApiWorker{Power: systemMonitor, Do: this_.systemMonitor, NotRecodeLog: true})
+	apis = append(apis, &base.ApiWorker{Power: systemQuery, Do: this_.systemQuery, NotRecodeLog: true})
+	apis = append(apis, &base.ApiWorker{Power: systemProcesses, Do: this_.systemProcesses, NotRecodeLog: true})
+	apis = append(apis, &base.ApiWorker{Power: systemPorts, Do: this_.systemPorts, NotRecodeLog: true})
+	apis = append(apis, &base.ApiWorker{Power: systemSignal, Do: this_.systemSignal})
+	apis = append(apis, &base.ApiWorker{Power: batchExec, Do: this_.batchExec})
 	apis = append(apis, &base.ApiWorker{Power: commandSave, Do: this_.commandSave, NotRecodeLog: true})
 	apis = append(apis, &base.ApiWorker{Power: commandQuery, Do: this_.commandQuery, NotRecodeLog: true})
 	apis = append(apis, &base.ApiWorker{Power: commandCount, Do: this_.commandCount, NotRecodeLog: true})
@@ -237,6 +248,110 @@ func (this_ *api) systemMonitor(_ *base.RequestBean, c *gin.Context) (res interf
 	return
 }
 
+type SystemProcessRequest struct {
+	Key    string `json:"key,omitempty"`
+	IsTree bool   `json:"isTree,omitempty"`
+	Pid    int32  `json:"pid,omitempty"`
+	Signal string `json:"signal,omitempty"`
+}
+
+// systemProcesses 进程列表，isTree 为 true 返回 进程树
+func (this_ *api) systemProcesses(_ *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	request := &SystemProcessRequest{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+
+	service := this_.GetService(request.Key)
+	if service == nil || service.service == nil {
+		err = errors.New("会话[" + request.Key + "]不存在")
+		return
+	}
+
+	list, err := service.service.SystemProcesses()
+	if err != nil {
+		return
+	}
+	if request.IsTree {
+		res = system.BuildProcessTree(list)
+		return
+	}
+	res = list
+	return
+}
+
+func (this_ *api) systemPorts(_ *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	request := &SystemProcessRequest{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+
+	service := this_.GetService(request.Key)
+	if service == nil || service.service == nil {
+		err = errors.New("会话[" + request.Key + "]不存在")
+		return
+	}
+
+	res, err = service.service.SystemPorts()
+	return
+}
+
+func (this_ *api) systemSignal(_ *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	request := &SystemProcessRequest{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+
+	service := this_.GetService(request.Key)
+	if service == nil || service.service == nil {
+		err = errors.New("会话[" + request.Key + "]不存在")
+		return
+	}
+
+	err = service.service.SystemSignal(request.Pid, request.Signal)
+	return
+}
+
+// batchExec 多主机 并发 执行 命令 或 运行手册，返回 每个主机 输出 及 输出 差异
+func (this_ *api) batchExec(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	request := &BatchExecRequest{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+	if requestBean.JWT == nil || requestBean.JWT.UserId == 0 {
+		err = errors.New("登录用户获取失败")
+		return
+	}
+
+	res, err = this_.BatchExec(requestBean, request)
+	return
+}
+
+type SystemQueryRequest struct {
+	Place   string `json:"place,omitempty"`
+	PlaceId string `json:"placeId,omitempty"`
+	*system.QueryRequest
+}
+
+// systemQuery 查询 持久化的 监控数据，SSH 主机 只有 终端 打开期间 才会采集
+func (this_ *api) systemQuery(_ *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	request := &SystemQueryRequest{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+	if request.QueryRequest == nil {
+		request.QueryRequest = &system.QueryRequest{}
+	}
+	switch request.Place {
+	case "", "local":
+		request.HostKey = system.LocalHostKey
+	default:
+		request.HostKey = request.Place + "-" + request.PlaceId
+	}
+	res = system.QueryMonitorData(request.QueryRequest)
+	return
+}
+
 func (this_ *api) commandSave(r *base.RequestBean, c *gin.Context) (res interface{}, err error) {
 	request := &TerminalCommandModel{}
 	if !base.RequestJSON(request, c) {
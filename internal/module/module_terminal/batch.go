@@ -0,0 +1,344 @@
+package module_terminal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/team-ide/go-tool/util"
+	"go.uber.org/zap"
+	goSSH "golang.org/x/crypto/ssh"
+	"strconv"
+	"sync"
+	"teamide/internal/module/module_node"
+	"teamide/internal/module/module_toolbox"
+	"teamide/pkg/base"
+	"teamide/pkg/ssh"
+	"teamide/pkg/terminal"
+	"time"
+)
+
+const (
+	batchDefaultConcurrency = 5
+	batchMaxConcurrency     = 50
+	batchDefaultTimeout     = 60
+	// 节点 调用 最长 等待 60 秒，留出 传输 时间
+	batchNodeMaxTimeout = 55
+)
+
+// BatchTarget 执行 目标，place 为 ssh、node、local
+type BatchTarget struct {
+	Place   string `json:"place,omitempty"`
+	PlaceId string `json:"placeId,omitempty"`
+	Name    string `json:"name,omitempty"`
+}
+
+func (this_ *BatchTarget) GetKey() string {
+	return this_.Place + ":" + this_.PlaceId
+}
+
+type BatchExecRequest struct {
+	Targets        []*BatchTarget               `json:"targets,omitempty"`
+	GroupIds       []int64                      `json:"groupIds,omitempty"` // 分组 下的 所有 SSH，包含 子分组
+	Script         string                       `json:"script,omitempty"`
+	QuickCommandId int64                        `json:"quickCommandId,omitempty"`
+	Params         map[string]string            `json:"params,omitempty"`
+	HostParams     map[string]map[string]string `json:"hostParams,omitempty"` // 主机 Key -> 参数
+	Concurrency    int                          `json:"concurrency,omitempty"`
+	Timeout        int                          `json:"timeout,omitempty"` // 秒
+}
+
+type BatchExecResult struct {
+	HostKey string `json:"hostKey"`
+	Place   string `json:"place"`
+	PlaceId string `json:"placeId"`
+	Name    string `json:"name"`
+	Script  string `json:"script"`
+	*terminal.ExecResult
+}
+
+type BatchExecResponse struct {
+	Results  []*BatchExecResult      `json:"results"`
+	Groups   []*terminal.OutputGroup `json:"groups"` // 按 输出 分组 及 差异
+	Duration int64                   `json:"duration"`
+}
+
+// getBatchRunbook 获取 运行手册，快速命令 优先
+func (this_ *WorkerFactory) getBatchRunbook(userId int64, request *BatchExecRequest) (runbook *terminal.Runbook, err error) {
+	runbook = &terminal.Runbook{
+		Script: request.Script,
+	}
+	if request.QuickCommandId == 0 {
+		return
+	}
+	quickCommand, err := this_.toolboxService.GetQuickCommand(request.QuickCommandId)
+	if err != nil {
+		return
+	}
+	if quickCommand == nil || quickCommand.UserId != userId {
+		err = errors.New("快速命令[" + strconv.FormatInt(request.QuickCommandId, 10) + "]不存在")
+		return
+	}
+	switch quickCommand.QuickCommandType {
+	case module_toolbox.QuickCommandTypeRunbook:
+		err = json.Unmarshal([]byte(quickCommand.Option), runbook)
+		if err != nil {
+			err = errors.New("运行手册[" + quickCommand.Name + "]配置错误:" + err.Error())
+			return
+		}
+	default:
+		var option = map[string]interface{}{}
+		if e := json.Unmarshal([]byte(quickCommand.Option), &option); e == nil && option["command"] != nil {
+			runbook.Script = fmt.Sprint(option["command"])
+		} else {
+			runbook.Script = quickCommand.Option
+		}
+	}
+	return
+}
+
+// getBatchTargets 合并 目标 及 分组 下的 SSH，去重，指定的 SSH 需要 有 操作 权限
+func (this_ *WorkerFactory) getBatchTargets(requestBean *base.RequestBean, request *BatchExecRequest) (targets []*BatchTarget, err error) {
+	userId := requestBean.JWT.UserId
+	var exist = make(map[string]bool)
+	var appendTarget = func(target *BatchTarget) {
+		if exist[target.GetKey()] {
+			return
+		}
+		exist[target.GetKey()] = true
+		targets = append(targets, target)
+	}
+	for _, one := range request.Targets {
+		target := &BatchTarget{
+			Place:   one.Place,
+			PlaceId: one.PlaceId,
+			Name:    one.Name,
+		}
+		if target.Name == "" {
+			target.Name = target.PlaceId
+		}
+		if target.Place == "ssh" {
+			err = this_.checkBatchSSHPower(requestBean, target.PlaceId)
+			if err != nil {
+				return
+			}
+		}
+		appendTarget(target)
+	}
+	if len(request.GroupIds) == 0 {
+		return
+	}
+
+	groups, err := this_.toolboxService.QueryGroup(&module_toolbox.ToolboxGroupModel{UserId: userId})
+	if err != nil {
+		return
+	}
+	var groupIds = make(map[int64]bool)
+	for _, groupId := range request.GroupIds {
+		groupIds[groupId] = true
+	}
+	// 逐层 加入 子分组，直到 没有 新增
+	for {
+		var added bool
+		for _, group := range groups {
+			if !groupIds[group.GroupId] && groupIds[group.ParentId] {
+				groupIds[group.GroupId] = true
+				added = true
+			}
+		}
+		if !added {
+			break
+		}
+	}
+	toolboxList, err := this_.toolboxService.Query(&module_toolbox.ToolboxModel{UserId: userId, ToolboxType: "ssh"})
+	if err != nil {
+		return
+	}
+	for _, one := range toolboxList {
+		if !groupIds[one.GroupId] {
+			continue
+		}
+		appendTarget(&BatchTarget{
+			Place:   "ssh",
+			PlaceId: strconv.FormatInt(one.ToolboxId, 10),
+			Name:    one.Name,
+		})
+	}
+	return
+}
+
+// BatchExec 多主机 并发 执行，结果 按 目标 顺序 返回
+func (this_ *WorkerFactory) BatchExec(requestBean *base.RequestBean, request *BatchExecRequest) (response *BatchExecResponse, err error) {
+	startTime := time.Now()
+	runbook, err := this_.getBatchRunbook(requestBean.JWT.UserId, request)
+	if err != nil {
+		return
+	}
+	if runbook.Script == "" {
+		err = errors.New("执行脚本不能为空")
+		return
+	}
+	targets, err := this_.getBatchTargets(requestBean, request)
+	if err != nil {
+		return
+	}
+	if len(targets) == 0 {
+		err = errors.New("请选择执行的主机")
+		return
+	}
+
+	concurrency := request.Concurrency
+	if concurrency <= 0 {
+		concurrency = runbook.Concurrency
+	}
+	if concurrency <= 0 {
+		concurrency = batchDefaultConcurrency
+	}
+	if concurrency > batchMaxConcurrency {
+		concurrency = batchMaxConcurrency
+	}
+	timeout := request.Timeout
+	if timeout <= 0 {
+		timeout = runbook.Timeout
+	}
+	if timeout <= 0 {
+		timeout = batchDefaultTimeout
+	}
+
+	// 参数 先 校验，避免 部分主机 已执行
+	var results = make([]*BatchExecResult, len(targets))
+	for i, target := range targets {
+		var params map[string]string
+		params, err = runbook.GetParams(request.Params, request.HostParams[target.GetKey()])
+		if err != nil {
+			err = errors.New("[" + target.Name + "]" + err.Error())
+			return
+		}
+		params["host.key"] = target.GetKey()
+		params["host.name"] = target.Name
+		params["host.place"] = target.Place
+		params["host.placeId"] = target.PlaceId
+		results[i] = &BatchExecResult{
+			HostKey: target.GetKey(),
+			Place:   target.Place,
+			PlaceId: target.PlaceId,
+			Name:    target.Name,
+			Script:  terminal.RenderScript(runbook.Script, params),
+		}
+	}
+
+	var wait sync.WaitGroup
+	var limit = make(chan struct{}, concurrency)
+	for _, result := range results {
+		wait.Add(1)
+		limit <- struct{}{}
+		go func(result *BatchExecResult) {
+			defer func() {
+				if e := recover(); e != nil {
+					this_.Logger.Error("batch exec panic error", zap.Any("hostKey", result.HostKey), zap.Any("error", e))
+					result.ExecResult = &terminal.ExecResult{}
+					result.ExecResult.SetError(errors.New(fmt.Sprint(e)))
+				}
+				<-limit
+				wait.Done()
+			}()
+			result.ExecResult = this_.batchExecOne(result, time.Duration(timeout)*time.Second)
+		}(result)
+	}
+	wait.Wait()
+
+	response = &BatchExecResponse{
+		Results:  results,
+		Duration: time.Since(startTime).Milliseconds(),
+	}
+	var outputs []*terminal.HostOutput
+	for _, one := range results {
+		outputs = append(outputs, &terminal.HostOutput{
+			HostKey:  one.HostKey,
+			Output:   one.Output(),
+			ExitCode: one.ExitCode,
+		})
+	}
+	response.Groups = terminal.GroupOutputs(outputs)
+	return
+}
+
+func (this_ *WorkerFactory) batchExecOne(result *BatchExecResult, timeout time.Duration) (res *terminal.ExecResult) {
+	var err error
+	switch result.Place {
+	case "local":
+		res = terminal.Exec(result.Script, timeout)
+		return
+	case "ssh":
+		var config *ssh.Config
+		config, err = this_.getBatchSSHConfig(result.PlaceId)
+		if err != nil {
+			break
+		}
+		res = ssh.Exec(config, result.Script, timeout)
+		return
+	case "node":
+		if timeout > batchNodeMaxTimeout*time.Second {
+			timeout = batchNodeMaxTimeout * time.Second
+		}
+		res, err = module_node.NewTerminalService(result.PlaceId, this_.nodeService).Exec(result.Script, timeout)
+		if err != nil {
+			break
+		}
+		if res == nil {
+			err = errors.New("节点[" + result.PlaceId + "]执行结果为空")
+		}
+	default:
+		err = errors.New("[" + result.Place + "]终端服务不存在")
+	}
+	if err != nil {
+		res = &terminal.ExecResult{}
+		res.SetError(err)
+	}
+	return
+}
+
+func (this_ *WorkerFactory) checkBatchSSHPower(requestBean *base.RequestBean, placeId string) (err error) {
+	id, err := strconv.ParseInt(placeId, 10, 64)
+	if err != nil {
+		return
+	}
+	tD, err := this_.toolboxService.Get(id)
+	if err != nil {
+		return
+	}
+	if tD == nil {
+		err = errors.New("SSH[" + placeId + "]配置不存在")
+		return
+	}
+	err = this_.toolboxService.CheckToolboxPower(requestBean, tD)
+	return
+}
+
+func (this_ *WorkerFactory) getBatchSSHConfig(placeId string) (config *ssh.Config, err error) {
+	id, err := strconv.ParseInt(placeId, 10, 64)
+	if err != nil {
+		return
+	}
+	tD, err := this_.toolboxService.Get(id)
+	if err != nil {
+		return
+	}
+	if tD == nil || tD.Option == "" {
+		err = errors.New("SSH[" + placeId + "]配置不存在")
+		return
+	}
+	config, sshConfig, err := this_.toolboxService.GetSSHConfig(tD.Option)
+	if err != nil {
+		return
+	}
+	if sshConfig != nil {
+		var sshClient *goSSH.Client
+		sshClient, err = ssh.NewClient(*sshConfig)
+		if err != nil {
+			util.Logger.Error("batch exec ssh NewClient error", zap.Any("address", sshConfig.Address), zap.Error(err))
+			return
+		}
+		config.SSHClient = sshClient
+	}
+	return
+}
@@ -21,6 +21,7 @@ import (
 	"teamide/internal/module/module_node"
 	"teamide/internal/module/module_toolbox"
 	"teamide/pkg/ssh"
+	"teamide/pkg/system"
 	"teamide/pkg/terminal"
 	"time"
 )
@@ -197,6 +198,15 @@ func (this_ *WorkerFactory) Start(key string, param *CreateParam, size *terminal
 	go worker.startReadService(isWindow)
 
 	this_.workerCache[key] = worker
+
+	// SSH 主机 在 终端 打开期间 采集 监控数据
+	if param.place == "ssh" {
+		system.AddMonitorSource(&system.MonitorSource{
+			Key:     "terminal-" + key,
+			HostKey: "ssh-" + param.placeId,
+			Collect: worker.service.SystemMonitorData,
+		})
+	}
 	return
 }
 
@@ -568,6 +578,7 @@ func (this_ *WorkerFactory) stopService(key string) {
 		return
 	}
 	delete(this_.workerCache, key)
+	system.RemoveMonitorSource("terminal-" + key)
 	this_.Logger.Info("stop service", zap.Any("key", key))
 	find.service.Stop()
 }
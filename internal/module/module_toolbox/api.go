@@ -114,12 +114,18 @@ type QuickCommandType struct {
 	Value int    `json:"value,omitempty"`
 }
 
+const (
+	QuickCommandTypeSSHCommand = 1
+	QuickCommandTypeRunbook    = 2
+)
+
 var (
 	QuickCommandTypes []*QuickCommandType
 )
 
 func init() {
-	QuickCommandTypes = append(QuickCommandTypes, &QuickCommandType{Name: "SSH Command", Text: "", Value: 1})
+	QuickCommandTypes = append(QuickCommandTypes, &QuickCommandType{Name: "SSH Command", Text: "", Value: QuickCommandTypeSSHCommand})
+	QuickCommandTypes = append(QuickCommandTypes, &QuickCommandType{Name: "Runbook", Text: "多主机 批量执行 的 运行手册，支持 参数", Value: QuickCommandTypeRunbook})
 }
 
 func GetQuickCommandTypes() []*QuickCommandType {
@@ -150,6 +150,18 @@ func (this_ *ToolboxService) FormatOption(toolboxData *ToolboxModel, decrypt boo
 				delete(optionMap, "password")
 			}
 		}
+		if optionMap["schemaRegistryPassword"] != nil {
+			str, ok := optionMap["schemaRegistryPassword"].(string)
+			if ok {
+				if decrypt {
+					optionMap["schemaRegistryPassword"] = this_.DecryptOptionAttr(str)
+				} else {
+					optionMap["schemaRegistryPassword"] = this_.EncryptOptionAttr(str)
+				}
+			} else {
+				delete(optionMap, "schemaRegistryPassword")
+			}
+		}
 		break
 	case otherWorker_:
 		break
@@ -639,6 +651,9 @@ func kafkaWorker() *ToolboxType {
 				{Label: "用户名", Name: "username", Col: 12},
 				{Label: "密码", Name: "password", Col: 12, ShowPlaintextBtn: true},
 				{Label: "Cert", Name: "certPath", Type: "file", Placeholder: "请上传Cert"},
+				{Label: "Schema Registry（http://127.0.0.1:8081）", Name: "schemaRegistryUrl"},
+				{Label: "Schema Registry 用户名", Name: "schemaRegistryUsername", Col: 12},
+				{Label: "Schema Registry 密码", Name: "schemaRegistryPassword", Type: "password", Col: 12, ShowPlaintextBtn: true},
 			},
 		},
 		OtherForm: map[string]*form.Form{
@@ -734,6 +749,8 @@ func kafkaWorker() *ToolboxType {
 						Options: []*form.Option{
 							{Text: "String", Value: "string"},
 							{Text: "Long（int64）", Value: "long"},
+							{Text: "Avro", Value: "avro"},
+							{Text: "Protobuf", Value: "protobuf"},
 						},
 						Rules: []*form.Rule{
 							{Required: true, Message: "KeyType不能为空"},
@@ -747,6 +764,8 @@ func kafkaWorker() *ToolboxType {
 						Options: []*form.Option{
 							{Text: "String", Value: "string"},
 							{Text: "Long（int64）", Value: "long"},
+							{Text: "Avro", Value: "avro"},
+							{Text: "Protobuf", Value: "protobuf"},
 						},
 						Rules: []*form.Rule{
 							{Required: true, Message: "ValueType不能为空"},
@@ -29,6 +29,16 @@ var (
 	savePower        = base.AppendPower(&base.PowerAction{Action: "save", Text: "Zookeeper保存节点数据", ShouldLogin: true, StandAlone: true, Parent: Power})
 	getChildrenPower = base.AppendPower(&base.PowerAction{Action: "getChildren", Text: "Zookeeper查询子节点", ShouldLogin: true, StandAlone: true, Parent: Power})
 	deletePower      = base.AppendPower(&base.PowerAction{Action: "delete", Text: "Zookeeper删除节点", ShouldLogin: true, StandAlone: true, Parent: Power})
+	getWithStatPower = base.AppendPower(&base.PowerAction{Action: "getWithStat", Text: "Zookeeper获取节点数据及版本", ShouldLogin: true, StandAlone: true, Parent: Power})
+	aclGetPower      = base.AppendPower(&base.PowerAction{Action: "aclGet", Text: "Zookeeper获取节点权限", ShouldLogin: true, StandAlone: true, Parent: Power})
+	aclSetPower      = base.AppendPower(&base.PowerAction{Action: "aclSet", Text: "Zookeeper设置节点权限", ShouldLogin: true, StandAlone: true, Parent: Power})
+	exportPower      = base.AppendPower(&base.PowerAction{Action: "export", Text: "Zookeeper导出节点", ShouldLogin: true, StandAlone: true, Parent: Power})
+	importPlanPower  = base.AppendPower(&base.PowerAction{Action: "importPlan", Text: "Zookeeper导入预览", ShouldLogin: true, StandAlone: true, Parent: Power})
+	importPower      = base.AppendPower(&base.PowerAction{Action: "import", Text: "Zookeeper导入节点", ShouldLogin: true, StandAlone: true, Parent: Power})
+	watchPower       = base.AppendPower(&base.PowerAction{Action: "watch", Text: "Zookeeper监听节点", ShouldLogin: true, StandAlone: true, Parent: Power})
+	websocketPower   = base.AppendPower(&base.PowerAction{Action: "websocket", Text: "Zookeeper监听WebSocket", ShouldLogin: true, StandAlone: true, Parent: Power})
+	watchListPower   = base.AppendPower(&base.PowerAction{Action: "watchList", Text: "Zookeeper监听列表", ShouldLogin: true, StandAlone: true, Parent: Power})
+	watchClosePower  = base.AppendPower(&base.PowerAction{Action: "watchClose", Text: "Zookeeper监听关闭", ShouldLogin: true, StandAlone: true, Parent: Power})
 	closePower       = base.AppendPower(&base.PowerAction{Action: "close", Text: "Zookeeper关闭", ShouldLogin: true, StandAlone: true, Parent: Power})
 )
 
@@ -39,6 +49,16 @@ func (this_ *api) GetApis() (apis []*base.ApiWorker) {
 	apis = append(apis, &base.ApiWorker{Power: savePower, Do: this_.save})
 	apis = append(apis, &base.ApiWorker{Power: getChildrenPower, Do: this_.getChildren})
 	apis = append(apis, &base.ApiWorker{Power: deletePower, Do: this_.delete})
+	apis = append(apis, &base.ApiWorker{Power: getWithStatPower, Do: this_.getWithStat})
+	apis = append(apis, &base.ApiWorker{Power: aclGetPower, Do: this_.aclGet})
+	apis = append(apis, &base.ApiWorker{Power: aclSetPower, Do: this_.aclSet, LogData: removeAclPassword})
+	apis = append(apis, &base.ApiWorker{Power: exportPower, Do: this_.export})
+	apis = append(apis, &base.ApiWorker{Power: importPlanPower, Do: this_.importPlan})
+	apis = append(apis, &base.ApiWorker{Power: importPower, Do: this_._import})
+	apis = append(apis, &base.ApiWorker{Power: watchPower, Do: this_.watch})
+	apis = append(apis, &base.ApiWorker{Power: websocketPower, Do: this_.websocket, IsWebSocket: true})
+	apis = append(apis, &base.ApiWorker{Power: watchListPower, Do: this_.watchList})
+	apis = append(apis, &base.ApiWorker{Power: watchClosePower, Do: this_.watchClose})
 	apis = append(apis, &base.ApiWorker{Power: closePower, Do: this_.close})
 
 	return
@@ -53,8 +73,8 @@ func (this_ *api) getConfig(requestBean *base.RequestBean, c *gin.Context) (conf
 	return
 }
 
-func getService(zkConfig *zookeeper.Config, sshConfig *ssh.Config) (res zookeeper.IService, err error) {
-	key := "zookeeper-" + zkConfig.Address
+func getServiceKey(zkConfig *zookeeper.Config, sshConfig *ssh.Config) (key string) {
+	key = "zookeeper-" + zkConfig.Address
 	if zkConfig.Username != "" {
 		key += "-" + base.GetMd5String(key+zkConfig.Username)
 	}
@@ -65,6 +85,11 @@ func getService(zkConfig *zookeeper.Config, sshConfig *ssh.Config) (res zookeepe
 		key += "-ssh-" + sshConfig.Address
 		key += "-ssh-" + sshConfig.Username
 	}
+	return
+}
+
+func getService(zkConfig *zookeeper.Config, sshConfig *ssh.Config) (res zookeeper.IService, err error) {
+	key := getServiceKey(zkConfig, sshConfig)
 	var serviceInfo *base.ServiceInfo
 	serviceInfo, err = base.GetService(key, func() (res *base.ServiceInfo, err error) {
 		var s zookeeper.IService
@@ -110,8 +135,9 @@ func getService(zkConfig *zookeeper.Config, sshConfig *ssh.Config) (res zookeepe
 }
 
 type BaseRequest struct {
-	Path string `json:"path"`
-	Data string `json:"data"`
+	Path    string `json:"path"`
+	Data    string `json:"data"`
+	Version *int32 `json:"version"` // 保存 时 指定 则 校验 版本，版本 不一致 返回 冲突 错误
 }
 
 func (this_ *api) check(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
@@ -179,6 +205,10 @@ func (this_ *api) save(requestBean *base.RequestBean, c *gin.Context) (res inter
 	if !base.RequestJSON(request, c) {
 		return
 	}
+	if request.Version != nil {
+		res, err = saveWithVersion(config, sshConfig, request)
+		return
+	}
 	var isEx bool
 	isEx, err = service.Exists(request.Path)
 	if err != nil {
@@ -0,0 +1,298 @@
+package module_zookeeper
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/go-zookeeper/zk"
+	"github.com/team-ide/go-tool/zookeeper"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"teamide/pkg/base"
+	"teamide/pkg/ssh"
+	"teamide/pkg/zktree"
+	"time"
+)
+
+type TreeRequest struct {
+	BaseRequest
+	AclList          []*zktree.Acl `json:"aclList"`
+	Aversion         *int32        `json:"aversion"` // 设置 ACL 时 指定 则 校验 ACL 版本
+	Format           string        `json:"format"`   // json、yaml
+	FilePath         string        `json:"filePath"` // 导入 上传 的 文件
+	Content          string        `json:"content"`  // 导入 的 文件 内容，未 指定 文件 时 使用
+	WithAcl          bool          `json:"withAcl"`  // 导出、导入 包含 ACL
+	IncludeEphemeral bool          `json:"includeEphemeral"`
+	MaxNodes         int           `json:"maxNodes"`
+	Delete           bool          `json:"delete"`  // 导入 时 删除 文件 中 不存在 的 节点
+	PlanKey          string        `json:"planKey"` // 预览 返回 的 key，导入 时 必须 指定，校验 目标 在 预览 后 未 变更
+}
+
+func (this_ *TreeRequest) exportOptions() *zktree.ExportOptions {
+	return &zktree.ExportOptions{Acl: this_.WithAcl, IncludeEphemeral: this_.IncludeEphemeral, MaxNodes: this_.MaxNodes}
+}
+
+func (this_ *TreeRequest) planOptions() *zktree.PlanOptions {
+	return &zktree.PlanOptions{Acl: this_.WithAcl, Delete: this_.Delete}
+}
+
+// StatInfo 节点 数据 与 版本，保存 时 回传 Version 用于 校验
+type StatInfo struct {
+	Path           string `json:"path"`
+	Data           string `json:"data"`
+	Version        int32  `json:"version"`
+	Cversion       int32  `json:"cversion"`
+	Aversion       int32  `json:"aversion"`
+	Ctime          int64  `json:"ctime"`
+	Mtime          int64  `json:"mtime"`
+	EphemeralOwner int64  `json:"ephemeralOwner"`
+	DataLength     int32  `json:"dataLength"`
+	NumChildren    int32  `json:"numChildren"`
+}
+
+func newStatInfo(p string, data []byte, stat *zk.Stat) *StatInfo {
+	return &StatInfo{
+		Path:           p,
+		Data:           string(data),
+		Version:        stat.Version,
+		Cversion:       stat.Cversion,
+		Aversion:       stat.Aversion,
+		Ctime:          stat.Ctime,
+		Mtime:          stat.Mtime,
+		EphemeralOwner: stat.EphemeralOwner,
+		DataLength:     stat.DataLength,
+		NumChildren:    stat.NumChildren,
+	}
+}
+
+// saveWithVersion 校验 版本 后 保存，版本 为 -1 时 不 校验，节点 不存在 则 创建
+func saveWithVersion(config *zookeeper.Config, sshConfig *ssh.Config, request *BaseRequest) (res interface{}, err error) {
+	conn, err := getConn(config, sshConfig)
+	if err != nil {
+		return
+	}
+	p, err := zktree.CleanPath(request.Path)
+	if err != nil {
+		return
+	}
+	version := *request.Version
+	stat, err := conn.Set(p, []byte(request.Data), version)
+	if errors.Is(err, zk.ErrNoNode) && version == -1 {
+		if _, err = conn.Create(p, []byte(request.Data), 0, zk.WorldACL(zk.PermAll)); err != nil {
+			err = zktree.ConflictError(p, err)
+			return
+		}
+		_, stat, err = conn.Get(p)
+	}
+	if err != nil {
+		err = zktree.ConflictError(p, err)
+		return
+	}
+	res = newStatInfo(p, []byte(request.Data), stat)
+	return
+}
+
+func (this_ *api) getTreeConn(requestBean *base.RequestBean, c *gin.Context) (conn *zk.Conn, request *TreeRequest, err error) {
+	config, sshConfig, err := this_.getConfig(requestBean, c)
+	if err != nil {
+		return
+	}
+	conn, err = getConn(config, sshConfig)
+	if err != nil {
+		return
+	}
+
+	request = &TreeRequest{}
+	if !base.RequestJSON(request, c) {
+		conn = nil
+		return
+	}
+	return
+}
+
+// getWithStat 获取 节点 数据 与 版本
+func (this_ *api) getWithStat(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	conn, request, err := this_.getTreeConn(requestBean, c)
+	if err != nil || conn == nil {
+		return
+	}
+	data, stat, err := conn.Get(request.Path)
+	if err != nil {
+		err = zktree.ConflictError(request.Path, err)
+		return
+	}
+	res = newStatInfo(request.Path, data, stat)
+	return
+}
+
+func (this_ *api) aclGet(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	conn, request, err := this_.getTreeConn(requestBean, c)
+	if err != nil || conn == nil {
+		return
+	}
+	acl, stat, err := conn.GetACL(request.Path)
+	if err != nil {
+		err = zktree.ConflictError(request.Path, err)
+		return
+	}
+	res = map[string]interface{}{
+		"aclList":  zktree.FromZkAcl(acl),
+		"aversion": stat.Aversion,
+	}
+	return
+}
+
+// removeAclPassword 设置 权限 的 日志 中 去除 digest 密码
+func removeAclPassword(data map[string]interface{}) {
+	aclList, _ := data["aclList"].([]interface{})
+	for _, one := range aclList {
+		if acl, ok := one.(map[string]interface{}); ok {
+			delete(acl, "password")
+		}
+	}
+}
+
+func (this_ *api) aclSet(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	conn, request, err := this_.getTreeConn(requestBean, c)
+	if err != nil || conn == nil {
+		return
+	}
+	acl, err := zktree.ToZkAcl(request.AclList)
+	if err != nil {
+		return
+	}
+	var version int32 = -1
+	if request.Aversion != nil {
+		version = *request.Aversion
+	}
+	stat, err := conn.SetACL(request.Path, acl, version)
+	if err != nil {
+		err = zktree.ConflictError(request.Path, err)
+		return
+	}
+	res = map[string]interface{}{
+		"aclList":  zktree.FromZkAcl(acl),
+		"aversion": stat.Aversion,
+	}
+	return
+}
+
+// export 导出 子树 为 json 或 yaml 文件 下载
+func (this_ *api) export(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	conn, request, err := this_.getTreeConn(requestBean, c)
+	if err != nil || conn == nil {
+		return
+	}
+	format := request.Format
+	if format == "" {
+		format = zktree.FormatJSON
+	}
+	tree, err := zktree.Export(conn, request.Path, request.exportOptions())
+	if err != nil {
+		return
+	}
+	bs, err := zktree.Marshal(tree, format)
+	if err != nil {
+		return
+	}
+
+	name := path.Base(tree.Path)
+	if name == "/" {
+		name = "root"
+	}
+	fileName := "zookeeper-" + name + "-" + time.Now().Format("20060102150405") + "." + format
+
+	c.Header("Content-Type", "application/octet-stream")
+	c.Header("Content-Disposition", "attachment; filename="+url.QueryEscape(fileName))
+	c.Header("Content-Transfer-Encoding", "binary")
+	c.Header("Content-Length", fmt.Sprint(len(bs)))
+	c.Header("download-file-name", fileName)
+
+	_, err = c.Writer.Write(bs)
+	if err != nil {
+		return
+	}
+
+	c.Status(http.StatusOK)
+	res = base.HttpNotResponse
+	return
+}
+
+func (this_ *api) readTree(request *TreeRequest) (tree *zktree.Tree, err error) {
+	var bs []byte
+	format := request.Format
+	if request.FilePath != "" {
+		bs, err = os.ReadFile(this_.toolboxService.GetFilesFile(request.FilePath))
+		if err != nil {
+			return
+		}
+		if format == "" {
+			format = strings.TrimPrefix(strings.ToLower(path.Ext(request.FilePath)), ".")
+		}
+	} else if request.Content != "" {
+		bs = []byte(request.Content)
+	} else {
+		err = errors.New("请上传导入文件")
+		return
+	}
+	tree, err = zktree.Unmarshal(bs, format)
+	return
+}
+
+// importPlan 预览 导入 到 request.Path 的 变更，不 写入
+func (this_ *api) importPlan(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	conn, request, err := this_.getTreeConn(requestBean, c)
+	if err != nil || conn == nil {
+		return
+	}
+	tree, err := this_.readTree(request)
+	if err != nil {
+		return
+	}
+	changes, err := zktree.Plan(conn, tree, request.Path, request.planOptions())
+	if err != nil {
+		return
+	}
+	res = map[string]interface{}{
+		"changes": changes,
+		"planKey": zktree.PlanKey(changes),
+	}
+	return
+}
+
+// _import 重新 比较 后 执行，与 预览 不一致 或 写入 时 版本 冲突 返回 错误
+func (this_ *api) _import(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	conn, request, err := this_.getTreeConn(requestBean, c)
+	if err != nil || conn == nil {
+		return
+	}
+	tree, err := this_.readTree(request)
+	if err != nil {
+		return
+	}
+	changes, err := zktree.Plan(conn, tree, request.Path, request.planOptions())
+	if err != nil {
+		return
+	}
+	if request.PlanKey == "" {
+		err = errors.New("请先预览导入的变更")
+		return
+	}
+	if request.PlanKey != zktree.PlanKey(changes) {
+		err = errors.New("预览后目标节点已变更，请重新预览")
+		return
+	}
+	applied, err := zktree.Apply(conn, changes)
+	if err != nil {
+		err = errors.New(fmt.Sprint("已执行 ", applied, "/", len(changes), " 项变更，", err.Error()))
+		return
+	}
+	res = map[string]interface{}{
+		"changes": changes,
+		"applied": applied,
+	}
+	return
+}
@@ -0,0 +1,111 @@
+package module_zookeeper
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/team-ide/go-tool/util"
+	"go.uber.org/zap"
+	"net/http"
+	"teamide/pkg/base"
+	"teamide/pkg/zkwatch"
+)
+
+type WatchRequest struct {
+	BaseRequest
+	zkwatch.Options
+	WatchKey string `json:"watchKey"`
+}
+
+var upGrader = websocket.Upgrader{
+	ReadBufferSize:  32 * 1024,
+	WriteBufferSize: 32 * 1024,
+	CheckOrigin: func(r *http.Request) bool {
+		return true
+	},
+}
+
+// watch 监听 节点 或 子树，事件 先 推送 到 页签 的 context 监听，连接 websocket 后 推送 到 websocket
+func (this_ *api) watch(requestBean *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	config, sshConfig, err := this_.getConfig(requestBean, c)
+	if err != nil {
+		return
+	}
+
+	request := &WatchRequest{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+	if requestBean.ClientTabKey == "" {
+		err = errors.New("client tab key is null")
+		return
+	}
+
+	service, err := createWatchService(config, sshConfig, request.Path, &request.Options, requestBean.ClientTabKey)
+	if err != nil {
+		return
+	}
+	service.start()
+
+	data := make(map[string]interface{})
+	data["key"] = service.Key
+	res = data
+	return
+}
+
+func (this_ *api) websocket(request *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+
+	if request.JWT == nil || request.JWT.UserId == 0 {
+		err = errors.New("登录用户获取失败")
+		return
+	}
+	key := c.Query("key")
+	if key == "" {
+		err = errors.New("key获取失败")
+		return
+	}
+	//升级get请求为webSocket协议
+	ws, err := upGrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		return
+	}
+
+	service := getWatchService(key)
+	if service == nil {
+		err = errors.New("监听会话[" + key + "]不存在")
+
+		_ = ws.WriteMessage(websocket.TextMessage, []byte(`{"type":"error","error":"service not found"}`))
+		util.Logger.Error("zookeeper websocket start error", zap.Error(err))
+		_ = ws.Close()
+		return
+	}
+	service.attach(ws)
+
+	res = base.HttpNotResponse
+	return
+}
+
+// watchList 当前 页签 的 监听 会话
+func (this_ *api) watchList(requestBean *base.RequestBean, _ *gin.Context) (res interface{}, err error) {
+	var list []*WatchService
+	for _, one := range getWatchServices() {
+		if one.ClientTabKey == requestBean.ClientTabKey {
+			list = append(list, one)
+		}
+	}
+	res = list
+	return
+}
+
+func (this_ *api) watchClose(_ *base.RequestBean, c *gin.Context) (res interface{}, err error) {
+	request := &WatchRequest{}
+	if !base.RequestJSON(request, c) {
+		return
+	}
+
+	service := getWatchService(request.WatchKey)
+	if service != nil {
+		service.stop()
+	}
+	return
+}
@@ -0,0 +1,87 @@
+package module_zookeeper
+
+import (
+	"github.com/go-zookeeper/zk"
+	"github.com/team-ide/go-tool/util"
+	"github.com/team-ide/go-tool/zookeeper"
+	"go.uber.org/zap"
+	goSSH "golang.org/x/crypto/ssh"
+	"net"
+	"strings"
+	"teamide/pkg/base"
+	"teamide/pkg/ssh"
+	"time"
+)
+
+const sessionTimeout = 30 * time.Second
+
+// newConn 创建 驱动 连接，用于 版本 校验 写入、ACL、监听 等 go-tool 未 提供 的 操作
+func newConn(zkConfig *zookeeper.Config, sshConfig *ssh.Config) (conn *zk.Conn, sshClient *goSSH.Client, err error) {
+	var servers []string
+	for _, one := range strings.Split(zkConfig.Address, ",") {
+		if one = strings.TrimSpace(one); one != "" {
+			servers = append(servers, one)
+		}
+	}
+	var dialer zk.Dialer = net.DialTimeout
+	if sshConfig != nil {
+		sshClient, err = ssh.NewClient(*sshConfig)
+		if err != nil {
+			return
+		}
+		dialer = func(network, address string, timeout time.Duration) (net.Conn, error) {
+			return sshClient.Dial(network, address)
+		}
+	}
+	conn, _, err = zk.Connect(servers, sessionTimeout, zk.WithDialer(dialer), zk.WithLogInfo(false))
+	if err == nil && zkConfig.Username != "" {
+		err = conn.AddAuth("digest", []byte(zkConfig.Username+":"+zkConfig.Password))
+	}
+	if err == nil {
+		_, _, err = conn.Exists("/")
+	}
+	if err != nil {
+		if conn != nil {
+			conn.Close()
+			conn = nil
+		}
+		if sshClient != nil {
+			_ = sshClient.Close()
+			sshClient = nil
+		}
+		return
+	}
+	return
+}
+
+// getConn 缓存 的 驱动 连接，监听 使用 独立 连接 不 走 缓存，避免 空闲 回收
+func getConn(zkConfig *zookeeper.Config, sshConfig *ssh.Config) (res *zk.Conn, err error) {
+	key := "conn-" + getServiceKey(zkConfig, sshConfig)
+
+	var serviceInfo *base.ServiceInfo
+	serviceInfo, err = base.GetService(key, func() (res *base.ServiceInfo, err error) {
+		conn, sshClient, err := newConn(zkConfig, sshConfig)
+		if err != nil {
+			util.Logger.Error("getConn error", zap.Any("key", key), zap.Error(err))
+			return
+		}
+		res = &base.ServiceInfo{
+			WaitTime:    10 * 60 * 1000,
+			LastUseTime: util.GetNowMilli(),
+			Service:     conn,
+			Stop: func() {
+				conn.Close()
+				if sshClient != nil {
+					_ = sshClient.Close()
+				}
+			},
+		}
+		return
+	})
+	if err != nil {
+		return
+	}
+	res = serviceInfo.Service.(*zk.Conn)
+	serviceInfo.SetLastUseTime()
+	return
+}
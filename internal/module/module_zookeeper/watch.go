@@ -0,0 +1,243 @@
+package module_zookeeper
+
+import (
+	"encoding/json"
+	"github.com/go-zookeeper/zk"
+	"github.com/gorilla/websocket"
+	"github.com/team-ide/go-tool/util"
+	"github.com/team-ide/go-tool/zookeeper"
+	"go.uber.org/zap"
+	goSSH "golang.org/x/crypto/ssh"
+	"sync"
+	"teamide/internal/context"
+	"teamide/pkg/ssh"
+	"teamide/pkg/zkwatch"
+	"time"
+)
+
+const (
+	// listenEventName 未 连接 websocket 时 通过 context 监听 推送 的 事件 名
+	listenEventName = "zookeeper-watch"
+
+	// watchCheckInterval 检查 页签 是否 关闭 的 间隔
+	watchCheckInterval = 30 * time.Second
+	// watchAttachTimeout 一直 未 连接 websocket 的 监听 超过 该 时间 停止
+	watchAttachTimeout = 10 * time.Minute
+)
+
+var (
+	watchCache     = map[string]*WatchService{}
+	watchCacheLock = &sync.Mutex{}
+)
+
+func getWatchService(key string) (service *WatchService) {
+	watchCacheLock.Lock()
+	defer watchCacheLock.Unlock()
+	service = watchCache[key]
+	return
+}
+
+func removeWatchService(key string) {
+	watchCacheLock.Lock()
+	defer watchCacheLock.Unlock()
+	delete(watchCache, key)
+}
+
+func setWatchService(key string, service *WatchService) {
+	watchCacheLock.Lock()
+	defer watchCacheLock.Unlock()
+	watchCache[key] = service
+}
+
+func getWatchServices() (list []*WatchService) {
+	watchCacheLock.Lock()
+	defer watchCacheLock.Unlock()
+	for _, one := range watchCache {
+		list = append(list, one)
+	}
+	return
+}
+
+// WatchService 一个 监听 会话，连接 websocket 后 事件 推送 到 websocket，否则 推送 到 页签 的 context 监听
+type WatchService struct {
+	Key          string `json:"key"`
+	Path         string `json:"path"`
+	Recursive    bool   `json:"recursive"`
+	ClientTabKey string `json:"-"`
+	StartTime    int64  `json:"startTime"`
+
+	conn      *zk.Conn
+	sshClient *goSSH.Client
+	watcher   *zkwatch.Watcher
+	ws        *websocket.Conn
+	isStopped bool
+	done      chan struct{}
+	lock      sync.Mutex
+	writeLock sync.Mutex
+}
+
+func createWatchService(zkConfig *zookeeper.Config, sshConfig *ssh.Config, path string, options *zkwatch.Options, clientTabKey string) (service *WatchService, err error) {
+	service = &WatchService{
+		Key:          util.GetUUID(),
+		Path:         path,
+		Recursive:    options.Recursive,
+		ClientTabKey: clientTabKey,
+		StartTime:    util.GetNowMilli(),
+		done:         make(chan struct{}),
+	}
+	service.conn, service.sshClient, err = newConn(zkConfig, sshConfig)
+	if err != nil {
+		return
+	}
+	service.watcher, err = zkwatch.New(service.conn, path, options, service.send)
+	if err != nil {
+		service.stop()
+		return
+	}
+	return
+}
+
+func (this_ *WatchService) start() {
+	setWatchService(this_.Key, this_)
+	this_.watcher.Start()
+	go this_.check()
+}
+
+// check 页签 关闭 或 长时间 未 连接 websocket 的 停止 监听，避免 没有 事件 时 一直 占用 连接
+func (this_ *WatchService) check() {
+	defer func() {
+		if e := recover(); e != nil {
+			util.Logger.Error("zookeeper watch check error", zap.Any("error", e))
+		}
+	}()
+	ticker := time.NewTicker(watchCheckInterval)
+	defer ticker.Stop()
+	startTime := time.Now()
+	for {
+		select {
+		case <-this_.done:
+			return
+		case <-ticker.C:
+			if context.GetListener(this_.ClientTabKey) == nil {
+				util.Logger.Info("zookeeper watch client tab closed stop", zap.Any("key", this_.Key), zap.Any("path", this_.Path))
+				this_.stop()
+				return
+			}
+			this_.lock.Lock()
+			idle := this_.ws == nil && time.Since(startTime) > watchAttachTimeout
+			this_.lock.Unlock()
+			if idle {
+				util.Logger.Info("zookeeper watch attach timeout stop", zap.Any("key", this_.Key), zap.Any("path", this_.Path))
+				this_.stop()
+				return
+			}
+		}
+	}
+}
+
+// attach 连接 websocket，之后 的 事件 推送 到 websocket，websocket 关闭 时 停止 会话，重复 连接 时 关闭 之前 的 websocket
+func (this_ *WatchService) attach(ws *websocket.Conn) {
+	this_.lock.Lock()
+	if this_.isStopped {
+		this_.lock.Unlock()
+		_ = ws.Close()
+		return
+	}
+	old := this_.ws
+	this_.ws = ws
+	this_.lock.Unlock()
+	if old != nil {
+		this_.writeLock.Lock()
+		_ = old.Close()
+		this_.writeLock.Unlock()
+	}
+	go this_.startReadWS(ws)
+}
+
+// current websocket 是否 为 当前 连接 的
+func (this_ *WatchService) current(ws *websocket.Conn) bool {
+	this_.lock.Lock()
+	defer this_.lock.Unlock()
+	return this_.ws == ws
+}
+
+func (this_ *WatchService) stop() {
+	this_.lock.Lock()
+	if this_.isStopped {
+		this_.lock.Unlock()
+		return
+	}
+	this_.isStopped = true
+	ws := this_.ws
+	close(this_.done)
+	this_.lock.Unlock()
+
+	removeWatchService(this_.Key)
+	if this_.watcher != nil {
+		this_.watcher.Stop()
+	}
+	if this_.conn != nil {
+		this_.conn.Close()
+	}
+	if this_.sshClient != nil {
+		_ = this_.sshClient.Close()
+	}
+	if ws != nil {
+		_ = ws.Close()
+	}
+}
+
+func (this_ *WatchService) stopped() bool {
+	this_.lock.Lock()
+	defer this_.lock.Unlock()
+	return this_.isStopped
+}
+
+func (this_ *WatchService) send(event *zkwatch.Event) {
+	this_.lock.Lock()
+	ws := this_.ws
+	this_.lock.Unlock()
+
+	if ws == nil {
+		// 页签 已 关闭 的 停止 监听
+		if context.GetListener(this_.ClientTabKey) == nil {
+			go this_.stop()
+			return
+		}
+		context.CallClientTabKeyEvent(this_.ClientTabKey, context.NewListenEvent(listenEventName, map[string]interface{}{
+			"watchKey": this_.Key,
+			"event":    event,
+		}))
+		return
+	}
+
+	bs, _ := json.Marshal(event)
+	this_.writeLock.Lock()
+	err := ws.WriteMessage(websocket.TextMessage, bs)
+	this_.writeLock.Unlock()
+	if err != nil && !this_.stopped() && this_.current(ws) {
+		util.Logger.Error("zookeeper watch ws write error", zap.Error(err))
+		go this_.stop()
+	}
+}
+
+// startReadWS websocket 只 用于 推送，读取 到 错误 即 关闭，已 被 新 连接 替换 的 不 停止 会话
+func (this_ *WatchService) startReadWS(ws *websocket.Conn) {
+	defer func() {
+		if e := recover(); e != nil {
+			util.Logger.Error("zookeeper watch read ws panic error", zap.Any("error", e))
+		}
+		if this_.current(ws) {
+			this_.stop()
+		}
+	}()
+
+	for {
+		if _, _, err := ws.ReadMessage(); err != nil {
+			if !this_.stopped() && this_.current(ws) && !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				util.Logger.Error("zookeeper watch read ws error", zap.Error(err))
+			}
+			return
+		}
+	}
+}
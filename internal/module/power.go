@@ -37,6 +37,16 @@ func (this_ *Api) checkPower(api *base.ApiWorker, JWT *base.JWTBean, c *gin.Cont
 	return find
 }
 
+// hasPower 用户 是否 拥有 权限，用于 接口 内 按 权限 区分 处理，如 数据 脱敏
+func (this_ *Api) hasPower(requestBean *base.RequestBean, power *base.PowerAction) bool {
+	for _, one := range this_.getPowersByJWT(requestBean.JWT) {
+		if one == power {
+			return true
+		}
+	}
+	return false
+}
+
 func (this_ *Api) getPowersByJWT(JWT *base.JWTBean) (powers []*base.PowerAction) {
 	var userId int64 = 0
 	if JWT != nil {
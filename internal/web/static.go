@@ -1,9 +1,11 @@
 package web
 
 import (
+	"crypto/subtle"
 	"errors"
 	"github.com/gin-gonic/gin"
 	"github.com/team-ide/go-tool/util"
+	"go.uber.org/zap"
 	"io"
 	"net/http"
 	"os"
@@ -11,6 +13,7 @@ import (
 	"strings"
 	"teamide/internal/static"
 	"teamide/pkg/base"
+	"teamide/pkg/system"
 )
 
 func (this_ *Server) bindGet(routerGroup *gin.RouterGroup) {
@@ -19,6 +22,9 @@ func (this_ *Server) bindGet(routerGroup *gin.RouterGroup) {
 		path := c.Params.ByName("path")
 		path = re.ReplaceAllLiteralString(path, "/")
 
+		if path == "/metrics" && this_.toMetrics(c) {
+			return
+		}
 		if this_.api.DoApi(path, c) {
 			return
 		}
@@ -32,6 +38,28 @@ func (this_ *Server) bindGet(routerGroup *gin.RouterGroup) {
 	})
 }
 
+// toMetrics Prometheus 采集 各个主机 最新的 监控数据，需要 在 配置 中 开启
+func (this_ *Server) toMetrics(c *gin.Context) bool {
+	metrics := this_.ServerConfig.Metrics
+	if metrics == nil || !metrics.Open {
+		return false
+	}
+	if metrics.Token != "" {
+		token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(metrics.Token)) != 1 {
+			c.Status(http.StatusUnauthorized)
+			return true
+		}
+	}
+	c.Header("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	c.Status(http.StatusOK)
+	err := system.WritePrometheus(c.Writer)
+	if err != nil {
+		this_.Logger.Error("write prometheus metrics error", zap.Error(err))
+	}
+	return true
+}
+
 func (this_ *Server) toIndex(c *gin.Context) bool {
 	return this_.toStaticByName("index.html", c)
 }
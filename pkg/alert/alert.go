@@ -0,0 +1,356 @@
+package alert
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	EventStatusFiring   = "firing"
+	EventStatusResolved = "resolved"
+)
+
+// Rule 告警规则，如：磁盘使用率 > 90 持续 300 秒
+type Rule struct {
+	RuleId     int64      `json:"ruleId,omitempty"`
+	Name       string     `json:"name,omitempty"`
+	HostKey    string     `json:"hostKey,omitempty"` // 主机标识，为空 匹配所有，以 * 结尾 前缀匹配，如：ssh-*、node-*
+	Metric     string     `json:"metric,omitempty"`  // 指标，如：disk_used_percent、node_started
+	Label      string     `json:"label,omitempty"`   // 指标标签，如：磁盘路径 /data，为空 匹配所有
+	Operator   string     `json:"operator,omitempty"`
+	Threshold  float64    `json:"threshold"`
+	For        int64      `json:"for,omitempty"`   // 持续 秒数，满足条件 持续 该时间 才告警
+	Level      string     `json:"level,omitempty"` // 级别，如：info、warning、critical
+	ChannelIds []int64    `json:"channelIds,omitempty"`
+	Silences   []*Silence `json:"silences,omitempty"`
+	Enabled    bool       `json:"enabled,omitempty"`
+}
+
+// Silence 静默时间段，时间段内 只记录 不通知
+type Silence struct {
+	StartTime  int64  `json:"startTime,omitempty"`  // 固定时间段 开始 毫秒
+	EndTime    int64  `json:"endTime,omitempty"`    // 固定时间段 结束 毫秒
+	DailyStart string `json:"dailyStart,omitempty"` // 每天 时间段 开始，如：22:00
+	DailyEnd   string `json:"dailyEnd,omitempty"`   // 每天 时间段 结束，如：06:00，小于 开始 则 跨天
+}
+
+// Sample 某个主机 某个指标的 当前值
+type Sample struct {
+	HostKey string  `json:"hostKey,omitempty"`
+	Metric  string  `json:"metric,omitempty"`
+	Label   string  `json:"label,omitempty"`
+	Value   float64 `json:"value"`
+}
+
+type Event struct {
+	EventId   string  `json:"eventId,omitempty"`
+	RuleId    int64   `json:"ruleId,omitempty"`
+	RuleName  string  `json:"ruleName,omitempty"`
+	Level     string  `json:"level,omitempty"`
+	Status    string  `json:"status,omitempty"`
+	HostKey   string  `json:"hostKey,omitempty"`
+	Metric    string  `json:"metric,omitempty"`
+	Label     string  `json:"label,omitempty"`
+	Value     float64 `json:"value"`
+	Operator  string  `json:"operator,omitempty"`
+	Threshold float64 `json:"threshold"`
+	StartTime int64   `json:"startTime,omitempty"` // 开始 满足条件 时间
+	EndTime   int64   `json:"endTime,omitempty"`   // 恢复 时间
+	Time      int64   `json:"time,omitempty"`
+	Silenced  bool    `json:"silenced,omitempty"`
+	Expired   bool    `json:"expired,omitempty"` // 主机 超过 StaleTime 没有数据，告警 自动 恢复
+	Message   string  `json:"message,omitempty"`
+}
+
+var (
+	Operators = []string{">", ">=", "<", "<=", "==", "!="}
+)
+
+func IsOperator(operator string) bool {
+	for _, one := range Operators {
+		if one == operator {
+			return true
+		}
+	}
+	return false
+}
+
+func (this_ *Rule) Check(value float64) bool {
+	switch this_.Operator {
+	case ">":
+		return value > this_.Threshold
+	case ">=":
+		return value >= this_.Threshold
+	case "<":
+		return value < this_.Threshold
+	case "<=":
+		return value <= this_.Threshold
+	case "==":
+		return value == this_.Threshold
+	case "!=":
+		return value != this_.Threshold
+	}
+	return false
+}
+
+func (this_ *Rule) Match(sample *Sample) bool {
+	if sample.Metric != this_.Metric {
+		return false
+	}
+	if this_.Label != "" && sample.Label != this_.Label {
+		return false
+	}
+	if this_.HostKey == "" {
+		return true
+	}
+	if strings.HasSuffix(this_.HostKey, "*") {
+		return strings.HasPrefix(sample.HostKey, strings.TrimSuffix(this_.HostKey, "*"))
+	}
+	return sample.HostKey == this_.HostKey
+}
+
+func (this_ *Rule) IsSilenced(now time.Time) bool {
+	for _, one := range this_.Silences {
+		if one.Contains(now) {
+			return true
+		}
+	}
+	return false
+}
+
+func (this_ *Silence) Contains(now time.Time) bool {
+	if this_.StartTime > 0 || this_.EndTime > 0 {
+		nowMilli := now.UnixMilli()
+		if nowMilli < this_.StartTime {
+			return false
+		}
+		if this_.EndTime > 0 && nowMilli >= this_.EndTime {
+			return false
+		}
+		if this_.DailyStart == "" {
+			return true
+		}
+	}
+	start, ok := parseDailyMinute(this_.DailyStart)
+	if !ok {
+		return false
+	}
+	end, ok := parseDailyMinute(this_.DailyEnd)
+	if !ok {
+		return false
+	}
+	minute := now.Hour()*60 + now.Minute()
+	if start <= end {
+		return minute >= start && minute < end
+	}
+	return minute >= start || minute < end
+}
+
+func parseDailyMinute(str string) (minute int, ok bool) {
+	ss := strings.Split(strings.TrimSpace(str), ":")
+	if len(ss) != 2 {
+		return
+	}
+	hour, err := strconv.Atoi(ss[0])
+	if err != nil || hour < 0 || hour > 24 {
+		return
+	}
+	m, err := strconv.Atoi(ss[1])
+	if err != nil || m < 0 || m > 59 {
+		return
+	}
+	minute = hour*60 + m
+	ok = true
+	return
+}
+
+// DefaultStaleTime 告警中 的 主机 超过 该时间 没有数据，如：终端 关闭、主机 删除，则 自动 恢复
+const DefaultStaleTime = 5 * 60 * 1000
+
+// NewEngine 告警 规则 计算
+func NewEngine() *Engine {
+	return &Engine{
+		states:    make(map[string]*ruleState),
+		StaleTime: DefaultStaleTime,
+	}
+}
+
+type Engine struct {
+	rules     []*Rule
+	states    map[string]*ruleState
+	lock      sync.Mutex
+	StaleTime int64 // 毫秒
+}
+
+// ruleState 规则 在 某个主机、标签 上的 状态
+type ruleState struct {
+	startTime int64
+	lastTime  int64 // 最后 收到 数据 时间
+	firing    bool
+	event     *Event
+}
+
+// SetRules 设置规则，已删除、禁用 的规则 状态 直接丢弃
+func (this_ *Engine) SetRules(rules []*Rule) {
+	this_.lock.Lock()
+	defer this_.lock.Unlock()
+
+	this_.rules = rules
+	ruleIds := make(map[string]bool)
+	for _, one := range rules {
+		if one.Enabled {
+			ruleIds[strconv.FormatInt(one.RuleId, 10)] = true
+		}
+	}
+	for key := range this_.states {
+		if !ruleIds[strings.Split(key, "/")[0]] {
+			delete(this_.states, key)
+		}
+	}
+}
+
+func (this_ *Engine) GetRules() []*Rule {
+	this_.lock.Lock()
+	defer this_.lock.Unlock()
+
+	return this_.rules
+}
+
+// GetFiring 当前 告警中的 事件
+func (this_ *Engine) GetFiring() (events []*Event) {
+	this_.lock.Lock()
+	defer this_.lock.Unlock()
+
+	for _, one := range this_.states {
+		if one.firing {
+			event := *one.event
+			events = append(events, &event)
+		}
+	}
+	return
+}
+
+// Evaluate 计算规则，返回 新告警 及 恢复 的事件，条件满足 需持续 For 秒 才告警，本次 没有数据的 未告警状态 重新计时
+func (this_ *Engine) Evaluate(now time.Time, samples []*Sample) (events []*Event) {
+	this_.lock.Lock()
+	defer this_.lock.Unlock()
+
+	nowMilli := now.UnixMilli()
+	seen := make(map[string]bool)
+	for _, rule := range this_.rules {
+		if !rule.Enabled {
+			continue
+		}
+		for _, sample := range samples {
+			if !rule.Match(sample) {
+				continue
+			}
+			key := fmt.Sprintf("%d/%s/%s", rule.RuleId, sample.HostKey, sample.Label)
+			seen[key] = true
+			state := this_.states[key]
+			if !rule.Check(sample.Value) {
+				if state != nil && state.firing {
+					event := *state.event
+					event.EventId = key + "/" + strconv.FormatInt(nowMilli, 10)
+					event.Status = EventStatusResolved
+					event.Value = sample.Value
+					event.EndTime = nowMilli
+					event.Time = nowMilli
+					event.Silenced = rule.IsSilenced(now)
+					event.Message = formatMessage(rule, &event)
+					events = append(events, &event)
+				}
+				delete(this_.states, key)
+				continue
+			}
+			if state == nil {
+				state = &ruleState{startTime: nowMilli}
+				this_.states[key] = state
+			}
+			state.lastTime = nowMilli
+			if state.firing {
+				state.event.Value = sample.Value
+				continue
+			}
+			if nowMilli-state.startTime < rule.For*1000 {
+				continue
+			}
+			state.firing = true
+			state.event = &Event{
+				EventId:   key + "/" + strconv.FormatInt(nowMilli, 10),
+				RuleId:    rule.RuleId,
+				RuleName:  rule.Name,
+				Level:     rule.Level,
+				Status:    EventStatusFiring,
+				HostKey:   sample.HostKey,
+				Metric:    sample.Metric,
+				Label:     sample.Label,
+				Value:     sample.Value,
+				Operator:  rule.Operator,
+				Threshold: rule.Threshold,
+				StartTime: state.startTime,
+				Time:      nowMilli,
+				Silenced:  rule.IsSilenced(now),
+			}
+			state.event.Message = formatMessage(rule, state.event)
+			event := *state.event
+			events = append(events, &event)
+		}
+	}
+	for key, state := range this_.states {
+		if seen[key] {
+			continue
+		}
+		if !state.firing {
+			delete(this_.states, key)
+			continue
+		}
+		if this_.StaleTime <= 0 || nowMilli-state.lastTime < this_.StaleTime {
+			continue
+		}
+		event := *state.event
+		event.EventId = key + "/" + strconv.FormatInt(nowMilli, 10)
+		event.Status = EventStatusResolved
+		event.Expired = true
+		event.EndTime = nowMilli
+		event.Time = nowMilli
+		if rule := this_.getRule(event.RuleId); rule != nil {
+			event.Silenced = rule.IsSilenced(now)
+			event.Message = formatMessage(rule, &event)
+		}
+		events = append(events, &event)
+		delete(this_.states, key)
+	}
+	return
+}
+
+func (this_ *Engine) getRule(ruleId int64) *Rule {
+	for _, one := range this_.rules {
+		if one.RuleId == ruleId {
+			return one
+		}
+	}
+	return nil
+}
+
+func formatMessage(rule *Rule, event *Event) string {
+	var title = "[告警]"
+	if event.Expired {
+		title = "[无数据]"
+	} else if event.Status == EventStatusResolved {
+		title = "[恢复]"
+	}
+	metric := event.Metric
+	if m := GetMetric(event.Metric); m != nil {
+		metric = m.Text
+	}
+	if event.Label != "" {
+		metric += "[" + event.Label + "]"
+	}
+	return fmt.Sprintf("%s %s 主机[%s] %s 当前值 %s，规则 %s %s",
+		title, rule.Name, event.HostKey, metric,
+		strconv.FormatFloat(event.Value, 'f', 2, 64), event.Operator, strconv.FormatFloat(event.Threshold, 'f', -1, 64))
+}
@@ -0,0 +1,96 @@
+package alert
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEngineEvaluate(t *testing.T) {
+	engine := NewEngine()
+	engine.SetRules([]*Rule{
+		{RuleId: 1, Name: "磁盘", HostKey: "ssh-*", Metric: MetricDiskUsedPercent, Operator: ">", Threshold: 90, For: 300, Enabled: true},
+		{RuleId: 2, Name: "节点", Metric: MetricNodeStarted, Operator: "==", Threshold: 0, Enabled: true},
+	})
+	start := time.Date(2023, 1, 1, 10, 0, 0, 0, time.Local)
+	disk := func(value float64) []*Sample {
+		return []*Sample{
+			{HostKey: "ssh-1", Metric: MetricDiskUsedPercent, Label: "/data", Value: value},
+			{HostKey: "local", Metric: MetricDiskUsedPercent, Label: "/data", Value: 99},
+		}
+	}
+
+	events := engine.Evaluate(start, disk(95))
+	if len(events) != 0 {
+		t.Fatalf("events size %d", len(events))
+	}
+	events = engine.Evaluate(start.Add(4*time.Minute), disk(95))
+	if len(events) != 0 {
+		t.Fatalf("events size %d", len(events))
+	}
+	events = engine.Evaluate(start.Add(5*time.Minute), disk(96))
+	if len(events) != 1 || events[0].Status != EventStatusFiring || events[0].Label != "/data" || events[0].StartTime != start.UnixMilli() {
+		t.Fatalf("events %+v", events)
+	}
+	// 告警中 不重复 通知
+	events = engine.Evaluate(start.Add(6*time.Minute), disk(97))
+	if len(events) != 0 || len(engine.GetFiring()) != 1 || engine.GetFiring()[0].Value != 97 {
+		t.Fatalf("events size %d", len(events))
+	}
+	events = engine.Evaluate(start.Add(7*time.Minute), disk(50))
+	if len(events) != 1 || events[0].Status != EventStatusResolved || len(engine.GetFiring()) != 0 {
+		t.Fatalf("events %+v", events)
+	}
+
+	// 未持续 满足条件 重新计时
+	engine.Evaluate(start.Add(8*time.Minute), disk(95))
+	engine.Evaluate(start.Add(9*time.Minute), disk(50))
+	events = engine.Evaluate(start.Add(13*time.Minute), disk(95))
+	if len(events) != 0 {
+		t.Fatalf("events size %d", len(events))
+	}
+
+	events = engine.Evaluate(start, []*Sample{NodeSample("node1", false), NodeSample("node2", true)})
+	if len(events) != 1 || events[0].HostKey != "node-node1" {
+		t.Fatalf("events %+v", events)
+	}
+
+	// 告警中 的 主机 不再 上报 超过 StaleTime 自动 恢复
+	events = engine.Evaluate(start.Add(4*time.Minute), nil)
+	if len(events) != 0 || len(engine.GetFiring()) != 1 {
+		t.Fatalf("events %+v", events)
+	}
+	events = engine.Evaluate(start.Add(5*time.Minute), nil)
+	if len(events) != 1 || events[0].Status != EventStatusResolved || !events[0].Expired || len(engine.GetFiring()) != 0 {
+		t.Fatalf("events %+v", events)
+	}
+
+	events = engine.Evaluate(start, []*Sample{NodeSample("node1", false)})
+	if len(events) != 1 {
+		t.Fatalf("events %+v", events)
+	}
+
+	// 禁用 规则 丢弃 状态
+	engine.SetRules([]*Rule{{RuleId: 2, Metric: MetricNodeStarted, Operator: "==", Enabled: false}})
+	if len(engine.GetFiring()) != 0 {
+		t.Fatal("firing not clean")
+	}
+}
+
+func TestSilence(t *testing.T) {
+	day := time.Date(2023, 1, 1, 0, 0, 0, 0, time.Local)
+	night := &Silence{DailyStart: "22:00", DailyEnd: "06:00"}
+	if !night.Contains(day.Add(23*time.Hour)) || !night.Contains(day.Add(5*time.Hour)) || night.Contains(day.Add(12*time.Hour)) {
+		t.Fatal("daily silence error")
+	}
+	fixed := &Silence{StartTime: day.UnixMilli(), EndTime: day.Add(time.Hour).UnixMilli()}
+	if !fixed.Contains(day.Add(time.Minute)) || fixed.Contains(day.Add(2*time.Hour)) {
+		t.Fatal("fixed silence error")
+	}
+
+	engine := NewEngine()
+	engine.SetRules([]*Rule{{RuleId: 1, Name: "CPU", Metric: MetricCpuUsedPercent, Operator: ">=", Threshold: 80, Enabled: true, Silences: []*Silence{fixed}}})
+	events := engine.Evaluate(day.Add(time.Minute), []*Sample{{HostKey: "local", Metric: MetricCpuUsedPercent, Value: 80}})
+	if len(events) != 1 || !events[0].Silenced {
+		t.Fatalf("events %+v", events)
+	}
+}
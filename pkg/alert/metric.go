@@ -0,0 +1,107 @@
+package alert
+
+import (
+	"teamide/pkg/system"
+)
+
+type Metric struct {
+	Name  string `json:"name"`
+	Text  string `json:"text"`
+	Unit  string `json:"unit,omitempty"`
+	Label string `json:"label,omitempty"` // 标签 说明
+}
+
+const (
+	MetricCpuUsedPercent    = "cpu_used_percent"
+	MetricMemoryUsedPercent = "memory_used_percent"
+	MetricDiskUsedPercent   = "disk_used_percent"
+	MetricNetSentSpeed      = "net_sent_speed"
+	MetricNetRecvSpeed      = "net_recv_speed"
+	MetricDiskReadSpeed     = "disk_read_speed"
+	MetricDiskWriteSpeed    = "disk_write_speed"
+	MetricNodeStarted       = "node_started"
+)
+
+var (
+	Metrics = []*Metric{
+		{Name: MetricCpuUsedPercent, Text: "CPU使用率", Unit: "%"},
+		{Name: MetricMemoryUsedPercent, Text: "内存使用率", Unit: "%"},
+		{Name: MetricDiskUsedPercent, Text: "磁盘使用率", Unit: "%", Label: "挂载路径"},
+		{Name: MetricNetSentSpeed, Text: "网络发送速度", Unit: "B/s"},
+		{Name: MetricNetRecvSpeed, Text: "网络接收速度", Unit: "B/s"},
+		{Name: MetricDiskReadSpeed, Text: "磁盘读速度", Unit: "B/s"},
+		{Name: MetricDiskWriteSpeed, Text: "磁盘写速度", Unit: "B/s"},
+		{Name: MetricNodeStarted, Text: "节点已启动", Label: "1：已启动，0：未启动"},
+	}
+)
+
+func GetMetric(name string) *Metric {
+	for _, one := range Metrics {
+		if one.Name == name {
+			return one
+		}
+	}
+	return nil
+}
+
+// MonitorDataSamples 将 监控数据 转为 指标，CPU 取 所有核 平均值，网络 不统计 lo
+func MonitorDataSamples(hostKey string, monitorData *system.MonitorData) (samples []*Sample) {
+	if monitorData == nil {
+		return
+	}
+	add := func(metric string, label string, value float64) {
+		samples = append(samples, &Sample{
+			HostKey: hostKey,
+			Metric:  metric,
+			Label:   label,
+			Value:   value,
+		})
+	}
+	if len(monitorData.CpuPercents) > 0 {
+		var total float64
+		for _, one := range monitorData.CpuPercents {
+			total += one
+		}
+		add(MetricCpuUsedPercent, "", total/float64(len(monitorData.CpuPercents)))
+	}
+	if monitorData.VirtualMemoryStat != nil {
+		add(MetricMemoryUsedPercent, "", monitorData.VirtualMemoryStat.UsedPercent)
+	}
+	for _, one := range monitorData.DiskUsageStats {
+		add(MetricDiskUsedPercent, one.Path, one.UsedPercent)
+	}
+	if len(monitorData.NetIOCountersStats) > 0 {
+		var sent, recv uint64
+		for _, one := range monitorData.NetIOCountersStats {
+			if one.Name == "lo" {
+				continue
+			}
+			sent += one.SpeedSent
+			recv += one.SpeedRecv
+		}
+		add(MetricNetSentSpeed, "", float64(sent))
+		add(MetricNetRecvSpeed, "", float64(recv))
+	}
+	if len(monitorData.DiskIOCountersStats) > 0 {
+		var read, write uint64
+		for _, one := range monitorData.DiskIOCountersStats {
+			read += one.ReadBytesSpeed
+			write += one.WriteBytesSpeed
+		}
+		add(MetricDiskReadSpeed, "", float64(read))
+		add(MetricDiskWriteSpeed, "", float64(write))
+	}
+	return
+}
+
+// NodeSample 节点 状态 指标
+func NodeSample(serverId string, started bool) *Sample {
+	sample := &Sample{
+		HostKey: "node-" + serverId,
+		Metric:  MetricNodeStarted,
+	}
+	if started {
+		sample.Value = 1
+	}
+	return sample
+}
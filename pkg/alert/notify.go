@@ -0,0 +1,276 @@
+package alert
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ChannelTypeWebhook  = "webhook"
+	ChannelTypeEmail    = "email"
+	ChannelTypeDingTalk = "dingtalk"
+	ChannelTypeWeCom    = "wecom"
+	ChannelTypeFeishu   = "feishu"
+)
+
+// Channel 通知渠道，站内通知 不需要配置，所有告警 都会推送给 在线的客户端
+type Channel struct {
+	ChannelId int64             `json:"channelId,omitempty"`
+	Name      string            `json:"name,omitempty"`
+	Type      string            `json:"type,omitempty"`
+	Url       string            `json:"url,omitempty"`     // webhook、机器人 地址
+	Secret    string            `json:"secret,omitempty"`  // 钉钉、飞书 机器人 加签 密钥
+	Headers   map[string]string `json:"headers,omitempty"` // webhook 请求头
+	SmtpHost  string            `json:"smtpHost,omitempty"`
+	SmtpPort  int               `json:"smtpPort,omitempty"`
+	SmtpSSL   bool              `json:"smtpSSL,omitempty"` // 直接使用 SSL 连接，如：465 端口，否则 服务支持 则使用 STARTTLS
+	Username  string            `json:"username,omitempty"`
+	Password  string            `json:"password,omitempty"`
+	From      string            `json:"from,omitempty"`
+	To        []string          `json:"to,omitempty"`
+	Enabled   bool              `json:"enabled,omitempty"`
+}
+
+var (
+	httpClient = &http.Client{
+		Timeout: 10 * time.Second,
+	}
+)
+
+// Send 发送 告警事件
+func Send(channel *Channel, event *Event) (err error) {
+	switch channel.Type {
+	case ChannelTypeWebhook:
+		err = sendWebhook(channel, event)
+	case ChannelTypeEmail:
+		err = sendEmail(channel, event)
+	case ChannelTypeDingTalk:
+		err = sendDingTalk(channel, event)
+	case ChannelTypeWeCom:
+		err = sendRobot(channel.Url, map[string]interface{}{
+			"msgtype": "text",
+			"text": map[string]interface{}{
+				"content": event.Message,
+			},
+		})
+	case ChannelTypeFeishu:
+		err = sendFeishu(channel, event)
+	default:
+		err = errors.New("alert channel type [" + channel.Type + "] not support")
+	}
+	return
+}
+
+func postJSON(postUrl string, headers map[string]string, data interface{}) (body []byte, err error) {
+	if postUrl == "" {
+		err = errors.New("alert channel url is empty")
+		return
+	}
+	bs, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+	req, err := http.NewRequest("POST", postUrl, bytes.NewReader(bs))
+	if err != nil {
+		return
+	}
+	req.Header.Set("Content-Type", "application/json;charset=utf-8")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	body, err = io.ReadAll(res.Body)
+	if err != nil {
+		return
+	}
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		err = errors.New(fmt.Sprintf("alert post [%s] status [%d] body [%s]", postUrl, res.StatusCode, string(body)))
+		return
+	}
+	return
+}
+
+func sendWebhook(channel *Channel, event *Event) (err error) {
+	_, err = postJSON(channel.Url, channel.Headers, event)
+	return
+}
+
+// sendRobot 钉钉、企业微信 机器人，返回 errcode 不为 0 则 失败
+func sendRobot(robotUrl string, data interface{}) (err error) {
+	body, err := postJSON(robotUrl, nil, data)
+	if err != nil {
+		return
+	}
+	res := struct {
+		ErrCode int    `json:"errcode"`
+		ErrMsg  string `json:"errmsg"`
+		Code    int    `json:"code"`
+		Msg     string `json:"msg"`
+	}{}
+	if len(body) > 0 && json.Unmarshal(body, &res) == nil {
+		if res.ErrCode != 0 {
+			err = errors.New(fmt.Sprintf("alert robot error [%d] %s", res.ErrCode, res.ErrMsg))
+			return
+		}
+		if res.Code != 0 {
+			err = errors.New(fmt.Sprintf("alert robot error [%d] %s", res.Code, res.Msg))
+			return
+		}
+	}
+	return
+}
+
+func hmacSha256Base64(key string, data string) string {
+	h := hmac.New(sha256.New, []byte(key))
+	h.Write([]byte(data))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+func sendDingTalk(channel *Channel, event *Event) (err error) {
+	robotUrl := channel.Url
+	if channel.Secret != "" {
+		timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
+		sign := hmacSha256Base64(channel.Secret, timestamp+"\n"+channel.Secret)
+		if strings.Contains(robotUrl, "?") {
+			robotUrl += "&"
+		} else {
+			robotUrl += "?"
+		}
+		robotUrl += "timestamp=" + timestamp + "&sign=" + url.QueryEscape(sign)
+	}
+	err = sendRobot(robotUrl, map[string]interface{}{
+		"msgtype": "text",
+		"text": map[string]interface{}{
+			"content": event.Message,
+		},
+	})
+	return
+}
+
+func sendFeishu(channel *Channel, event *Event) (err error) {
+	data := map[string]interface{}{
+		"msg_type": "text",
+		"content": map[string]interface{}{
+			"text": event.Message,
+		},
+	}
+	if channel.Secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		// 飞书 使用 timestamp + "\n" + secret 作为 密钥 签名 空字符串
+		data["timestamp"] = timestamp
+		data["sign"] = hmacSha256Base64(timestamp+"\n"+channel.Secret, "")
+	}
+	err = sendRobot(channel.Url, data)
+	return
+}
+
+func sendEmail(channel *Channel, event *Event) (err error) {
+	if channel.SmtpHost == "" {
+		err = errors.New("alert email smtp host is empty")
+		return
+	}
+	if len(channel.To) == 0 {
+		err = errors.New("alert email to is empty")
+		return
+	}
+	port := channel.SmtpPort
+	if port == 0 {
+		port = 25
+		if channel.SmtpSSL {
+			port = 465
+		}
+	}
+	from := channel.From
+	if from == "" {
+		from = channel.Username
+	}
+	address := net.JoinHostPort(channel.SmtpHost, strconv.Itoa(port))
+	tlsConfig := &tls.Config{ServerName: channel.SmtpHost}
+
+	var conn net.Conn
+	if channel.SmtpSSL {
+		conn, err = tls.DialWithDialer(&net.Dialer{Timeout: 10 * time.Second}, "tcp", address, tlsConfig)
+	} else {
+		conn, err = net.DialTimeout("tcp", address, 10*time.Second)
+	}
+	if err != nil {
+		return
+	}
+	_ = conn.SetDeadline(time.Now().Add(30 * time.Second))
+	client, err := smtp.NewClient(conn, channel.SmtpHost)
+	if err != nil {
+		_ = conn.Close()
+		return
+	}
+	defer func() { _ = client.Close() }()
+
+	if !channel.SmtpSSL {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			err = client.StartTLS(tlsConfig)
+			if err != nil {
+				return
+			}
+		}
+	}
+	if channel.Username != "" {
+		err = client.Auth(smtp.PlainAuth("", channel.Username, channel.Password, channel.SmtpHost))
+		if err != nil {
+			return
+		}
+	}
+	err = client.Mail(from)
+	if err != nil {
+		return
+	}
+	for _, to := range channel.To {
+		err = client.Rcpt(to)
+		if err != nil {
+			return
+		}
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return
+	}
+	subject := event.Message
+	if len([]rune(subject)) > 60 {
+		subject = string([]rune(subject)[:60]) + "..."
+	}
+	msg := "From: " + from + "\r\n" +
+		"To: " + strings.Join(channel.To, ",") + "\r\n" +
+		"Subject: =?UTF-8?B?" + base64.StdEncoding.EncodeToString([]byte(subject)) + "?=\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=UTF-8\r\n" +
+		"Content-Transfer-Encoding: base64\r\n" +
+		"\r\n" +
+		base64.StdEncoding.EncodeToString([]byte(event.Message)) + "\r\n"
+	_, err = writer.Write([]byte(msg))
+	if err != nil {
+		return
+	}
+	err = writer.Close()
+	if err != nil {
+		return
+	}
+	err = client.Quit()
+	return
+}
@@ -0,0 +1,138 @@
+package alert
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var testEvent = &Event{
+	RuleId:  1,
+	Status:  EventStatusFiring,
+	HostKey: "local",
+	Message: "[告警] 磁盘 主机[local]",
+}
+
+func TestSendWebhook(t *testing.T) {
+	var body map[string]interface{}
+	var token string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token = r.Header.Get("X-Token")
+		_ = json.NewDecoder(r.Body).Decode(&body)
+	}))
+	defer server.Close()
+
+	err := Send(&Channel{Type: ChannelTypeWebhook, Url: server.URL, Headers: map[string]string{"X-Token": "t"}}, testEvent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "t" || body["hostKey"] != "local" || body["status"] != EventStatusFiring {
+		t.Fatalf("webhook body %v", body)
+	}
+}
+
+func TestSendRobot(t *testing.T) {
+	var query string
+	var body map[string]interface{}
+	var response = `{"errcode":0}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		body = nil
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		_, _ = w.Write([]byte(response))
+	}))
+	defer server.Close()
+
+	err := Send(&Channel{Type: ChannelTypeDingTalk, Url: server.URL + "?access_token=a", Secret: "s"}, testEvent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(query, "access_token=a&timestamp=") || !strings.Contains(query, "&sign=") || body["msgtype"] != "text" {
+		t.Fatalf("dingtalk query %s body %v", query, body)
+	}
+
+	err = Send(&Channel{Type: ChannelTypeFeishu, Url: server.URL, Secret: "s"}, testEvent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body["msg_type"] != "text" || body["sign"] == nil {
+		t.Fatalf("feishu body %v", body)
+	}
+
+	response = `{"errcode":93000,"errmsg":"invalid webhook url"}`
+	err = Send(&Channel{Type: ChannelTypeWeCom, Url: server.URL}, testEvent)
+	if err == nil || !strings.Contains(err.Error(), "93000") {
+		t.Fatalf("wecom error %v", err)
+	}
+}
+
+// TestSendEmail 使用 本地 简单 SMTP 服务 接收 邮件
+func TestSendEmail(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = listener.Close() }()
+
+	received := make(chan []string, 1)
+	go func() {
+		conn, e := listener.Accept()
+		if e != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		reader := bufio.NewReader(conn)
+		write := func(line string) { _, _ = conn.Write([]byte(line + "\r\n")) }
+		var lines []string
+		write("220 localhost ESMTP")
+		inData := false
+		for {
+			line, e := reader.ReadString('\n')
+			if e != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			lines = append(lines, line)
+			if inData {
+				if line == "." {
+					inData = false
+					write("250 OK")
+				}
+				continue
+			}
+			switch {
+			case strings.HasPrefix(line, "EHLO"):
+				write("250 localhost")
+			case strings.HasPrefix(line, "DATA"):
+				inData = true
+				write("354 go ahead")
+			case strings.HasPrefix(line, "QUIT"):
+				write("221 bye")
+				received <- lines
+				return
+			default:
+				write("250 OK")
+			}
+		}
+	}()
+
+	addr := listener.Addr().(*net.TCPAddr)
+	err = Send(&Channel{
+		Type:     ChannelTypeEmail,
+		SmtpHost: "127.0.0.1",
+		SmtpPort: addr.Port,
+		From:     "teamide@localhost",
+		To:       []string{"a@localhost", "b@localhost"},
+	}, testEvent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Join(<-received, "\n")
+	if !strings.Contains(lines, "MAIL FROM:<teamide@localhost>") || !strings.Contains(lines, "RCPT TO:<b@localhost>") || !strings.Contains(lines, "Subject: =?UTF-8?B?") {
+		t.Fatal(lines)
+	}
+}
@@ -8,7 +8,8 @@ type ApiWorker struct {
 	IsGet        bool
 	IsWebSocket  bool
 	IsUpload     bool
-	NotRecodeLog bool `json:"notRecodeLog"`
+	NotRecodeLog bool                              `json:"notRecodeLog"`
+	LogData      func(data map[string]interface{}) `json:"-"` // 记录 日志 前 处理 请求 数据，如 去除 密码
 }
 
 type PowerAction struct {
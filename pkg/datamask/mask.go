@@ -0,0 +1,326 @@
+package datamask
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+const (
+	// StrategyPartial 保留 首尾 部分 字符，中间 替换 为 掩码 字符，邮箱 只 处理 @ 前 部分
+	StrategyPartial = "partial"
+	// StrategyHash 以 服务 密钥 计算 的 HMAC-SHA256 前 16 位，相同 值 结果 相同，可用于 比对，没有 密钥 无法 穷举 还原
+	StrategyHash = "hash"
+	// StrategyNull 置 为 空
+	StrategyNull = "null"
+	// StrategyFake 按 原值 格式 生成 假数据，数字 替换 为 数字，字母 替换 为 字母，相同 值 结果 相同
+	StrategyFake = "fake"
+)
+
+// Rule 脱敏 规则，ToolboxId、OwnerName、TableName 为 空 时 匹配 所有，ColumnName 与 ColumnPattern 至少 一个
+type Rule struct {
+	RuleId        int64  `json:"ruleId,omitempty"`
+	ToolboxId     int64  `json:"toolboxId,omitempty"`
+	OwnerName     string `json:"ownerName,omitempty"`
+	TableName     string `json:"tableName,omitempty"`
+	ColumnName    string `json:"columnName,omitempty"`
+	ColumnPattern string `json:"columnPattern,omitempty"` // 列名 正则，忽略 大小写
+	Strategy      string `json:"strategy,omitempty"`
+	KeepStart     int    `json:"keepStart,omitempty"` // partial 保留 前 几位，与 KeepEnd 都为 0 时 各 保留 1/4
+	KeepEnd       int    `json:"keepEnd,omitempty"`
+	MaskChar      string `json:"maskChar,omitempty"` // partial 掩码 字符，默认 *
+}
+
+// ValidateRule 校验 规则
+func ValidateRule(rule *Rule) (err error) {
+	if rule.ColumnName == "" && rule.ColumnPattern == "" {
+		err = errors.New("列名与列名正则不能同时为空")
+		return
+	}
+	if rule.ColumnPattern != "" {
+		if _, err = regexp.Compile("(?i)" + rule.ColumnPattern); err != nil {
+			err = errors.New("列名正则[" + rule.ColumnPattern + "]错误:" + err.Error())
+			return
+		}
+	}
+	switch rule.Strategy {
+	case StrategyPartial, StrategyHash, StrategyNull, StrategyFake:
+	default:
+		err = errors.New("不支持的脱敏策略[" + rule.Strategy + "]，支持 partial、hash、null、fake")
+		return
+	}
+	if rule.KeepStart < 0 || rule.KeepEnd < 0 {
+		err = errors.New("保留位数不能小于0")
+		return
+	}
+	return
+}
+
+type maskRule struct {
+	*Rule
+	pattern *regexp.Regexp
+	score   int
+}
+
+// Masker 按 规则 匹配 列 并 脱敏，规则 越 具体 优先级 越 高
+type Masker struct {
+	rules  []*maskRule
+	secret []byte
+}
+
+// NewMasker 规则 按 工具箱、库、表、列名 的 具体 程度 排序，相同 时 保持 原 顺序，secret 为 hash、fake 策略 的 密钥
+func NewMasker(rules []*Rule, secret []byte) (masker *Masker, err error) {
+	if len(secret) == 0 {
+		err = errors.New("脱敏密钥不能为空")
+		return
+	}
+	masker = &Masker{secret: secret}
+	for _, one := range rules {
+		if err = ValidateRule(one); err != nil {
+			return
+		}
+		rule := &maskRule{Rule: one}
+		if one.ColumnPattern != "" {
+			rule.pattern = regexp.MustCompile("(?i)" + one.ColumnPattern)
+		}
+		if one.ToolboxId != 0 {
+			rule.score += 8
+		}
+		if one.OwnerName != "" {
+			rule.score += 4
+		}
+		if one.TableName != "" {
+			rule.score += 2
+		}
+		if one.ColumnName != "" {
+			rule.score += 1
+		}
+		index := len(masker.rules)
+		for index > 0 && masker.rules[index-1].score < rule.score {
+			index--
+		}
+		masker.rules = append(masker.rules, nil)
+		copy(masker.rules[index+1:], masker.rules[index:])
+		masker.rules[index] = rule
+	}
+	return
+}
+
+// MaskValue 使用 脱敏器 的 密钥 脱敏 值
+func (this_ *Masker) MaskValue(rule *Rule, value interface{}) interface{} {
+	return MaskValue(rule, this_.secret, value)
+}
+
+func (this_ *Masker) IsEmpty() bool {
+	return this_ == nil || len(this_.rules) == 0
+}
+
+// GetRule 列 匹配 的 规则，ownerName、tableName 为 空 表示 未知（如 SQL 执行 结果），此时 不 限制 库、表
+func (this_ *Masker) GetRule(toolboxId int64, ownerName string, tableName string, columnName string) *Rule {
+	if this_ == nil {
+		return nil
+	}
+	for _, one := range this_.rules {
+		if one.ToolboxId != 0 && one.ToolboxId != toolboxId {
+			continue
+		}
+		if one.OwnerName != "" && ownerName != "" && !strings.EqualFold(one.OwnerName, ownerName) {
+			continue
+		}
+		if one.TableName != "" && tableName != "" && !strings.EqualFold(one.TableName, tableName) {
+			continue
+		}
+		if one.ColumnName != "" && !strings.EqualFold(one.ColumnName, columnName) {
+			continue
+		}
+		if one.pattern != nil && !one.pattern.MatchString(columnName) {
+			continue
+		}
+		return one.Rule
+	}
+	return nil
+}
+
+// MaskDataList 脱敏 数据 列表，返回 脱敏 的 列
+func (this_ *Masker) MaskDataList(toolboxId int64, ownerName string, tableName string, dataList []map[string]interface{}) (columns []string) {
+	return this_.MaskResult(toolboxId, ownerName, tableName, map[string]interface{}{"dataList": dataList})
+}
+
+// MaskResult 脱敏 查询 结果 中的 数据 列表，支持 包含 dataList 的 map、包含 DataList 字段 的 结构体 及 其 切片，
+// 按 结果 中 的 列名 匹配，别名 及 表达式 列 不会 被 匹配
+func (this_ *Masker) MaskResult(toolboxId int64, ownerName string, tableName string, result interface{}) (columns []string) {
+	if this_.IsEmpty() || result == nil {
+		return
+	}
+	masked := map[string]bool{}
+	ruleCache := map[string]*Rule{}
+	getRule := func(columnName string) *Rule {
+		rule, ok := ruleCache[columnName]
+		if !ok {
+			rule = this_.GetRule(toolboxId, ownerName, tableName, columnName)
+			ruleCache[columnName] = rule
+		}
+		return rule
+	}
+	maskRow := func(row reflect.Value) {
+		elemType := row.Type().Elem()
+		for _, key := range row.MapKeys() {
+			columnName := fmt.Sprint(key.Interface())
+			rule := getRule(columnName)
+			if rule == nil {
+				continue
+			}
+			value := this_.MaskValue(rule, row.MapIndex(key).Interface())
+			if value == nil {
+				row.SetMapIndex(key, reflect.Zero(elemType))
+			} else if v := reflect.ValueOf(value); v.Type().AssignableTo(elemType) {
+				row.SetMapIndex(key, v)
+			} else if v.Type().ConvertibleTo(elemType) {
+				row.SetMapIndex(key, v.Convert(elemType))
+			} else {
+				row.SetMapIndex(key, reflect.Zero(elemType))
+			}
+			if !masked[columnName] {
+				masked[columnName] = true
+				columns = append(columns, columnName)
+			}
+		}
+	}
+	maskRows := func(rows reflect.Value) {
+		rows = indirect(rows)
+		if rows.Kind() != reflect.Slice {
+			return
+		}
+		for i := 0; i < rows.Len(); i++ {
+			row := indirect(rows.Index(i))
+			if row.Kind() == reflect.Map && row.Type().Key().Kind() == reflect.String && !row.IsNil() {
+				maskRow(row)
+			}
+		}
+	}
+	var walk func(v reflect.Value)
+	walk = func(v reflect.Value) {
+		v = indirect(v)
+		switch v.Kind() {
+		case reflect.Slice:
+			for i := 0; i < v.Len(); i++ {
+				walk(v.Index(i))
+			}
+		case reflect.Map:
+			if v.Type().Key().Kind() != reflect.String || v.IsNil() {
+				return
+			}
+			if dataList := v.MapIndex(reflect.ValueOf("dataList").Convert(v.Type().Key())); dataList.IsValid() {
+				maskRows(dataList)
+			}
+		case reflect.Struct:
+			if dataList := v.FieldByName("DataList"); dataList.IsValid() {
+				maskRows(dataList)
+			}
+		}
+	}
+	walk(reflect.ValueOf(result))
+	return
+}
+
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// MaskValue 按 规则 策略 脱敏 值，空 值 不 处理，非 字符串 值 脱敏 后 为 字符串，secret 为 hash、fake 策略 的 密钥
+func MaskValue(rule *Rule, secret []byte, value interface{}) interface{} {
+	if value == nil || rule == nil {
+		return value
+	}
+	if rule.Strategy == StrategyNull {
+		return nil
+	}
+	var text string
+	switch v := value.(type) {
+	case string:
+		text = v
+	case []byte:
+		text = string(v)
+	default:
+		text = fmt.Sprint(v)
+	}
+	switch rule.Strategy {
+	case StrategyPartial:
+		return maskPartial(text, rule.KeepStart, rule.KeepEnd, rule.MaskChar)
+	case StrategyHash:
+		return hex.EncodeToString(digest(secret, text))[:16]
+	case StrategyFake:
+		return fake(secret, text)
+	}
+	return value
+}
+
+func maskPartial(text string, keepStart int, keepEnd int, maskChar string) string {
+	if text == "" {
+		return text
+	}
+	if maskChar == "" {
+		maskChar = "*"
+	}
+	// 邮箱 保留 域名
+	if index := strings.LastIndex(text, "@"); index > 0 {
+		return maskPartial(text[:index], keepStart, keepEnd, maskChar) + text[index:]
+	}
+	runes := []rune(text)
+	size := len(runes)
+	if keepStart == 0 && keepEnd == 0 {
+		keepStart = size / 4
+		keepEnd = size / 4
+	}
+	// 至少 掩码 一位
+	if keepStart+keepEnd >= size {
+		keepStart = (size - 1) / 2
+		keepEnd = 0
+	}
+	return string(runes[:keepStart]) + strings.Repeat(maskChar, size-keepStart-keepEnd) + string(runes[size-keepEnd:])
+}
+
+var (
+	fakeHanList = []rune("张王李赵刘陈杨黄周吴伟芳娜敏静丽强磊军洋勇艳杰娟涛明超秀霞平")
+)
+
+// digest 密钥 HMAC-SHA256，电话、证件号 等 取值 范围 小，不带 密钥 的 摘要 可以 穷举 还原
+func digest(secret []byte, text string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(text))
+	return mac.Sum(nil)
+}
+
+// fake 保持 长度 及 格式，字符 由 原值 摘要 决定
+func fake(secret []byte, text string) string {
+	sum := digest(secret, text)
+	var builder strings.Builder
+	for i, r := range []rune(text) {
+		b := int(sum[i%len(sum)]) + i/len(sum)
+		switch {
+		case r >= '0' && r <= '9':
+			builder.WriteRune(rune('0' + b%10))
+		case r >= 'a' && r <= 'z':
+			builder.WriteRune(rune('a' + b%26))
+		case r >= 'A' && r <= 'Z':
+			builder.WriteRune(rune('A' + b%26))
+		case unicode.Is(unicode.Han, r):
+			builder.WriteRune(fakeHanList[b%len(fakeHanList)])
+		default:
+			builder.WriteRune(r)
+		}
+	}
+	return builder.String()
+}
@@ -0,0 +1,110 @@
+package datamask
+
+import (
+	"strings"
+	"testing"
+)
+
+var secret = []byte("test-secret")
+
+func TestMaskValue(t *testing.T) {
+	partial := &Rule{Strategy: StrategyPartial, KeepStart: 3, KeepEnd: 4}
+	if v := MaskValue(partial, secret, "13812345678"); v != "138****5678" {
+		t.Fatalf("partial %v", v)
+	}
+	if v := MaskValue(partial, secret, []byte("abc")); v != "a**" {
+		t.Fatalf("partial short %v", v)
+	}
+	if v := MaskValue(&Rule{Strategy: StrategyPartial}, secret, "zhangsan@example.com"); v != "zh****an@example.com" {
+		t.Fatalf("partial email %v", v)
+	}
+	if v := MaskValue(&Rule{Strategy: StrategyPartial}, secret, nil); v != nil {
+		t.Fatalf("partial nil %v", v)
+	}
+	if v := MaskValue(&Rule{Strategy: StrategyNull}, secret, "x"); v != nil {
+		t.Fatalf("null %v", v)
+	}
+
+	hash := MaskValue(&Rule{Strategy: StrategyHash}, secret, 13812345678)
+	if s, _ := hash.(string); len(s) != 16 || hash != MaskValue(&Rule{Strategy: StrategyHash}, secret, "13812345678") {
+		t.Fatalf("hash %v", hash)
+	}
+	// 不同 密钥 结果 不同
+	if hash == MaskValue(&Rule{Strategy: StrategyHash}, []byte("other"), "13812345678") {
+		t.Fatalf("hash secret %v", hash)
+	}
+
+	fake := MaskValue(&Rule{Strategy: StrategyFake}, secret, "Zhang-13812345678@qq.com").(string)
+	if len(fake) != len("Zhang-13812345678@qq.com") || fake[5] != '-' || fake[17] != '@' || fake == "Zhang-13812345678@qq.com" {
+		t.Fatalf("fake %v", fake)
+	}
+	if fake[0] < 'A' || fake[0] > 'Z' || fake[6] < '0' || fake[6] > '9' {
+		t.Fatalf("fake format %v", fake)
+	}
+	if name := MaskValue(&Rule{Strategy: StrategyFake}, secret, "张三").(string); len([]rune(name)) != 2 {
+		t.Fatalf("fake han %v", name)
+	}
+}
+
+func TestMasker(t *testing.T) {
+	if _, err := NewMasker(nil, nil); err == nil {
+		t.Fatal("empty secret should error")
+	}
+	if _, err := NewMasker([]*Rule{{ColumnPattern: "(", Strategy: StrategyNull}}, secret); err == nil {
+		t.Fatal("pattern should error")
+	}
+	if _, err := NewMasker([]*Rule{{ColumnName: "a", Strategy: "x"}}, secret); err == nil {
+		t.Fatal("strategy should error")
+	}
+
+	masker, err := NewMasker([]*Rule{
+		{RuleId: 1, ColumnPattern: "phone|mobile", Strategy: StrategyPartial, KeepStart: 3, KeepEnd: 4},
+		{RuleId: 2, ToolboxId: 1, OwnerName: "db", TableName: "tm_user", ColumnName: "mobile", Strategy: StrategyNull},
+		{RuleId: 3, ToolboxId: 2, ColumnName: "email", Strategy: StrategyHash},
+	}, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rule := masker.GetRule(1, "db", "TM_USER", "Mobile"); rule == nil || rule.RuleId != 2 {
+		t.Fatalf("rule %+v", rule)
+	}
+	if rule := masker.GetRule(1, "db", "tm_order", "mobile"); rule == nil || rule.RuleId != 1 {
+		t.Fatalf("rule %+v", rule)
+	}
+	if rule := masker.GetRule(1, "", "", "mobile"); rule == nil || rule.RuleId != 2 {
+		t.Fatalf("unknown table rule %+v", rule)
+	}
+	if rule := masker.GetRule(1, "db", "tm_user", "email"); rule != nil {
+		t.Fatalf("other toolbox rule %+v", rule)
+	}
+
+	dataList := []map[string]interface{}{
+		{"id": 1, "user_phone": "13812345678", "mobile": "13900000000"},
+		{"id": 2, "user_phone": nil},
+	}
+	columns := masker.MaskDataList(1, "db", "tm_user", dataList)
+	if strings.Join(columns, ",") != "mobile,user_phone" && strings.Join(columns, ",") != "user_phone,mobile" {
+		t.Fatalf("columns %v", columns)
+	}
+	if dataList[0]["user_phone"] != "138****5678" || dataList[0]["mobile"] != nil || dataList[0]["id"] != 1 {
+		t.Fatalf("dataList %v", dataList)
+	}
+
+	type pageResult struct {
+		DataList []map[string]interface{}
+	}
+	result := &pageResult{DataList: []map[string]interface{}{{"phone": "13812345678"}}}
+	masker.MaskResult(1, "db", "t", result)
+	if result.DataList[0]["phone"] != "138****5678" {
+		t.Fatalf("struct %v", result.DataList)
+	}
+
+	executeList := []map[string]interface{}{
+		{"sql": "update", "rowsAffected": 1},
+		{"sql": "select", "dataList": []map[string]string{{"phone": "13812345678"}}},
+	}
+	masker.MaskResult(1, "", "", executeList)
+	if executeList[1]["dataList"].([]map[string]string)[0]["phone"] != "138****5678" {
+		t.Fatalf("executeList %v", executeList)
+	}
+}
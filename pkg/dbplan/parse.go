@@ -0,0 +1,294 @@
+package dbplan
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+func fullScanWarning(object string) string {
+	return "表[" + object + "]全表扫描"
+}
+
+func missingIndexWarning(object string) string {
+	return "表[" + object + "]全表扫描并过滤数据，建议为过滤条件列添加索引"
+}
+
+func fullIndexScanWarning(object string, index string) string {
+	return "表[" + object + "]全索引扫描[" + index + "]"
+}
+
+// ParseMysql 解析 EXPLAIN 表格 输出，每行 为 一个 表的 访问方式
+func ParseMysql(rows []map[string]interface{}) *Node {
+	var children []*Node
+	for _, row := range rows {
+		accessType := getString(row, "type")
+		node := &Node{
+			Operation: strings.TrimSpace(getString(row, "select_type") + " " + accessType),
+			Object:    getString(row, "table"),
+			Index:     getString(row, "key"),
+			Rows:      getFloat(row, "rows"),
+			Extra:     getString(row, "Extra"),
+		}
+		if ref := getString(row, "ref"); ref != "" {
+			node.Filters = append(node.Filters, "ref: "+ref)
+		}
+		if strings.Contains(node.Extra, "Using where") {
+			node.Filters = append(node.Filters, "Using where")
+		}
+		switch accessType {
+		case "ALL":
+			node.FullScan = true
+			node.addWarning(fullScanWarning(node.Object))
+			if node.Index == "" && getString(row, "possible_keys") == "" && strings.Contains(node.Extra, "Using where") {
+				node.addWarning(missingIndexWarning(node.Object))
+			}
+		case "index":
+			node.addWarning(fullIndexScanWarning(node.Object, node.Index))
+		}
+		if strings.Contains(node.Extra, "Using filesort") {
+			node.addWarning("表[" + node.Object + "]使用文件排序")
+		}
+		if strings.Contains(node.Extra, "Using temporary") {
+			node.addWarning("表[" + node.Object + "]使用临时表")
+		}
+		children = append(children, node)
+	}
+	return newRoot(children)
+}
+
+// ParsePostgresql 解析 EXPLAIN (FORMAT JSON) 输出
+func ParsePostgresql(text string) (root *Node, err error) {
+	var list []map[string]interface{}
+	err = json.Unmarshal([]byte(text), &list)
+	if err != nil {
+		return
+	}
+	var children []*Node
+	for _, one := range list {
+		plan, ok := one["Plan"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		children = append(children, parsePostgresqlNode(plan))
+	}
+	root = newRoot(children)
+	return
+}
+
+var (
+	postgresqlFilterKeys = []string{"Index Cond", "Recheck Cond", "Filter", "Join Filter", "Hash Cond", "Merge Cond"}
+)
+
+func parsePostgresqlNode(plan map[string]interface{}) (node *Node) {
+	nodeType := getString(plan, "Node Type")
+	node = &Node{
+		Operation: nodeType,
+		Object:    getString(plan, "Relation Name"),
+		Index:     getString(plan, "Index Name"),
+		Rows:      getFloat(plan, "Plan Rows"),
+		Cost:      getFloat(plan, "Total Cost"),
+	}
+	if joinType := getString(plan, "Join Type"); joinType != "" {
+		node.Operation += " (" + joinType + ")"
+	}
+	for _, key := range postgresqlFilterKeys {
+		if v := getString(plan, key); v != "" {
+			node.Filters = append(node.Filters, key+": "+v)
+		}
+	}
+	if nodeType == "Seq Scan" {
+		node.FullScan = true
+		node.addWarning(fullScanWarning(node.Object))
+		if getString(plan, "Filter") != "" {
+			node.addWarning(missingIndexWarning(node.Object))
+		}
+	}
+	if children, ok := plan["Plans"].([]interface{}); ok {
+		for _, one := range children {
+			if child, ok := one.(map[string]interface{}); ok {
+				node.Children = append(node.Children, parsePostgresqlNode(child))
+			}
+		}
+	}
+	return
+}
+
+// ParseOracle 解析 PLAN_TABLE 数据，需要 ID、PARENT_ID、OPERATION、OPTIONS、OBJECT_NAME、CARDINALITY、COST、ACCESS_PREDICATES、FILTER_PREDICATES 列
+func ParseOracle(rows []map[string]interface{}) *Node {
+	var nodes = make(map[string]*Node)
+	var roots []*Node
+	for _, row := range rows {
+		operation := getString(row, "OPERATION")
+		options := getString(row, "OPTIONS")
+		node := &Node{
+			Operation: strings.TrimSpace(operation + " " + options),
+			Object:    getString(row, "OBJECT_NAME"),
+			Rows:      getFloat(row, "CARDINALITY"),
+			Cost:      getFloat(row, "COST"),
+		}
+		if v := getString(row, "ACCESS_PREDICATES"); v != "" {
+			node.Filters = append(node.Filters, "access: "+v)
+		}
+		filter := getString(row, "FILTER_PREDICATES")
+		if filter != "" {
+			node.Filters = append(node.Filters, "filter: "+filter)
+		}
+		switch {
+		case operation == "TABLE ACCESS" && strings.Contains(options, "FULL"):
+			node.FullScan = true
+			node.addWarning(fullScanWarning(node.Object))
+			if filter != "" {
+				node.addWarning(missingIndexWarning(node.Object))
+			}
+		case operation == "INDEX":
+			node.Index = node.Object
+			if strings.Contains(options, "FULL SCAN") {
+				node.addWarning(fullIndexScanWarning(node.Object, node.Index))
+			}
+		}
+		nodes[getString(row, "ID")] = node
+		parent := nodes[getString(row, "PARENT_ID")]
+		if parent == nil {
+			roots = append(roots, node)
+		} else {
+			parent.Children = append(parent.Children, node)
+		}
+	}
+	return newRoot(roots)
+}
+
+var (
+	// 如：1   #NSET2: [1, 1, 56]
+	dmLineRegexp   = regexp.MustCompile(`^\s*\d*\s*#(\w+):\s*\[([^\]]*)\](.*)$`)
+	dmObjectRegexp = regexp.MustCompile(`(\w+)\((\w+)\)`)
+)
+
+// ParseDm 解析 达梦 EXPLAIN 文本，缩进 表示 层级，[代价, 行数, 字节数]
+func ParseDm(text string) *Node {
+	type level struct {
+		indent int
+		node   *Node
+	}
+	var stack []*level
+	var roots []*Node
+	for _, line := range strings.Split(text, "\n") {
+		match := dmLineRegexp.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		operation := match[1]
+		values := strings.Split(match[2], ",")
+		node := &Node{
+			Operation: operation,
+			Extra:     strings.TrimSpace(strings.TrimLeft(match[3], ";")),
+		}
+		if len(values) >= 2 {
+			node.Cost = toFloat(values[0])
+			node.Rows = toFloat(values[1])
+		}
+		if objectMatch := dmObjectRegexp.FindStringSubmatch(node.Extra); objectMatch != nil {
+			node.Index = objectMatch[1]
+			node.Object = objectMatch[2]
+		}
+		switch {
+		case strings.HasPrefix(operation, "SLCT"):
+			if node.Extra != "" {
+				node.Filters = append(node.Filters, node.Extra)
+			}
+		case strings.HasPrefix(operation, "CSCN"):
+			node.FullScan = true
+			node.addWarning(fullScanWarning(node.Object))
+		case strings.HasPrefix(operation, "SSCN"):
+			node.addWarning(fullIndexScanWarning(node.Object, node.Index))
+		}
+
+		indent := strings.Index(line, "#")
+		for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) == 0 {
+			roots = append(roots, node)
+		} else {
+			parent := stack[len(stack)-1].node
+			parent.Children = append(parent.Children, node)
+			// 全表扫描 后 过滤
+			if node.FullScan && strings.HasPrefix(parent.Operation, "SLCT") {
+				node.addWarning(missingIndexWarning(node.Object))
+			}
+		}
+		stack = append(stack, &level{indent: indent, node: node})
+	}
+	return newRoot(roots)
+}
+
+var (
+	sqliteIndexRegexp = regexp.MustCompile(`USING (?:COVERING )?INDEX (\S+)`)
+)
+
+// ParseSqlite 解析 EXPLAIN QUERY PLAN 输出，需要 id、parent、detail 列
+func ParseSqlite(rows []map[string]interface{}) *Node {
+	var nodes = make(map[string]*Node)
+	var roots []*Node
+	for _, row := range rows {
+		detail := getString(row, "detail")
+		node := &Node{
+			Operation: detail,
+		}
+		fields := strings.Fields(detail)
+		if len(fields) >= 2 && (fields[0] == "SCAN" || fields[0] == "SEARCH") {
+			node.Operation = fields[0]
+			node.Extra = detail
+			node.Object = fields[1]
+			// 旧版本 如：SCAN TABLE t
+			if fields[1] == "TABLE" && len(fields) >= 3 {
+				node.Object = fields[2]
+			}
+			if match := sqliteIndexRegexp.FindStringSubmatch(detail); match != nil {
+				node.Index = match[1]
+			} else if strings.Contains(detail, "INTEGER PRIMARY KEY") {
+				node.Index = "INTEGER PRIMARY KEY"
+			}
+			if start, end := strings.Index(detail, "("), strings.LastIndex(detail, ")"); start >= 0 && end > start {
+				node.Filters = append(node.Filters, detail[start+1:end])
+			}
+			if fields[0] == "SCAN" {
+				if node.Index == "" {
+					node.FullScan = true
+					node.addWarning(fullScanWarning(node.Object))
+				} else {
+					node.addWarning(fullIndexScanWarning(node.Object, node.Index))
+				}
+			}
+		}
+		if strings.Contains(detail, "USE TEMP B-TREE") {
+			node.addWarning("使用临时B树:" + detail)
+		}
+		nodes[getString(row, "id")] = node
+		parent := nodes[getString(row, "parent")]
+		if parent == nil {
+			roots = append(roots, node)
+		} else {
+			parent.Children = append(parent.Children, node)
+		}
+	}
+	return newRoot(roots)
+}
+
+// RowsText 合并 所有行 所有列 为 文本，用于 返回 单列 文本 的 执行计划
+func RowsText(rows []map[string]interface{}) string {
+	var lines []string
+	for _, row := range rows {
+		for _, v := range row {
+			switch s := v.(type) {
+			case []byte:
+				lines = append(lines, string(s))
+			case nil:
+			default:
+				lines = append(lines, fmt.Sprint(s))
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,93 @@
+package dbplan
+
+import (
+	"testing"
+)
+
+func TestParseMysql(t *testing.T) {
+	root := ParseMysql([]map[string]interface{}{
+		{"id": 1, "select_type": "SIMPLE", "table": "user", "type": "ALL", "possible_keys": nil, "key": nil, "rows": []byte("1000"), "Extra": "Using where; Using filesort"},
+		{"id": 1, "select_type": "SIMPLE", "table": "order", "type": "ref", "possible_keys": "idx_user", "key": "idx_user", "ref": "db.user.id", "rows": 3, "Extra": nil},
+	})
+	plan := NewPlan(DialectMysql, root)
+	if root.Operation != "QUERY" || len(root.Children) != 2 {
+		t.Fatalf("root %+v", root)
+	}
+	if plan.FullScans != 1 || len(plan.Warnings) != 3 {
+		t.Fatalf("plan %+v", plan)
+	}
+	if root.Children[0].Rows != 1000 || root.Children[1].Index != "idx_user" {
+		t.Fatalf("children %+v %+v", root.Children[0], root.Children[1])
+	}
+}
+
+func TestParsePostgresql(t *testing.T) {
+	root, err := ParsePostgresql(`[{"Plan": {"Node Type": "Hash Join", "Join Type": "Inner", "Total Cost": 35.5, "Plan Rows": 10, "Hash Cond": "(o.user_id = u.id)",
+"Plans": [{"Node Type": "Seq Scan", "Relation Name": "orders", "Total Cost": 20, "Plan Rows": 100, "Filter": "(status = 1)"},
+{"Node Type": "Index Scan", "Relation Name": "users", "Index Name": "users_pkey", "Total Cost": 8.2, "Plan Rows": 1}]}}]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan := NewPlan(DialectPostgresql, root)
+	if root.Operation != "Hash Join (Inner)" || root.Cost != 35.5 || len(root.Children) != 2 {
+		t.Fatalf("root %+v", root)
+	}
+	if plan.FullScans != 1 || len(plan.Warnings) != 2 || root.Children[0].Filters[0] != "Filter: (status = 1)" {
+		t.Fatalf("plan %+v", plan)
+	}
+}
+
+func TestParseOracle(t *testing.T) {
+	root := ParseOracle([]map[string]interface{}{
+		{"ID": 0, "PARENT_ID": nil, "OPERATION": "SELECT STATEMENT", "COST": 3},
+		{"ID": 1, "PARENT_ID": 0, "OPERATION": "TABLE ACCESS", "OPTIONS": "FULL", "OBJECT_NAME": "EMP", "CARDINALITY": 14, "COST": 3, "FILTER_PREDICATES": "\"DEPTNO\"=10"},
+	})
+	plan := NewPlan(DialectOracle, root)
+	if root.Operation != "SELECT STATEMENT" || len(root.Children) != 1 || root.Children[0].Operation != "TABLE ACCESS FULL" {
+		t.Fatalf("root %+v", root)
+	}
+	if plan.FullScans != 1 || len(plan.Warnings) != 2 {
+		t.Fatalf("plan %+v", plan)
+	}
+}
+
+func TestParseDm(t *testing.T) {
+	root := ParseDm(`1   #NSET2: [1, 10, 56]
+2     #PRJT2: [1, 10, 56]; exp_num(4), is_atom(FALSE)
+3       #SLCT2: [1, 10, 56]; T1.C1 = 1
+4         #CSCN2: [1, 100, 56]; INDEX33555484(T1)`)
+	plan := NewPlan(DialectDm, root)
+	if root.Operation != "NSET2" || root.Rows != 10 {
+		t.Fatalf("root %+v", root)
+	}
+	scan := root.Children[0].Children[0].Children[0]
+	if scan.Operation != "CSCN2" || scan.Object != "T1" || scan.Index != "INDEX33555484" || !scan.FullScan {
+		t.Fatalf("scan %+v", scan)
+	}
+	if plan.FullScans != 1 || len(plan.Warnings) != 2 {
+		t.Fatalf("plan %+v", plan)
+	}
+}
+
+func TestParseSqlite(t *testing.T) {
+	root := ParseSqlite([]map[string]interface{}{
+		{"id": int64(2), "parent": int64(0), "detail": "SCAN t"},
+		{"id": int64(5), "parent": int64(0), "detail": "SEARCH u USING INDEX idx_u (a=?)"},
+		{"id": int64(9), "parent": int64(0), "detail": "USE TEMP B-TREE FOR ORDER BY"},
+	})
+	plan := NewPlan(DialectSqlite, root)
+	if len(root.Children) != 3 || root.Children[1].Index != "idx_u" || root.Children[1].Filters[0] != "a=?" {
+		t.Fatalf("root %+v", root.Children[1])
+	}
+	if plan.FullScans != 1 || len(plan.Warnings) != 2 {
+		t.Fatalf("plan %+v", plan)
+	}
+}
+
+func TestGetDialect(t *testing.T) {
+	for databaseType, dialect := range map[string]string{"mysql": DialectMysql, "opengauss": DialectPostgresql, "kingbase": DialectPostgresql, "dm": DialectDm, "sqlite3": DialectSqlite, "shentong": ""} {
+		if GetDialect(databaseType) != dialect {
+			t.Fatalf("database type %s", databaseType)
+		}
+	}
+}
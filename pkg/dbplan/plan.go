@@ -0,0 +1,131 @@
+package dbplan
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	DialectMysql      = "mysql"
+	DialectPostgresql = "postgresql"
+	DialectOracle     = "oracle"
+	DialectDm         = "dm"
+	DialectSqlite     = "sqlite"
+)
+
+// GetDialect 数据库类型 对应的 执行计划 方言，openGauss、Kingbase 使用 PostgreSQL，不支持的 返回 空
+func GetDialect(databaseType string) string {
+	databaseType = strings.ToLower(databaseType)
+	switch {
+	case strings.Contains(databaseType, "mysql"), strings.Contains(databaseType, "mariadb"):
+		return DialectMysql
+	case strings.Contains(databaseType, "postgres"), strings.Contains(databaseType, "opengauss"), strings.Contains(databaseType, "kingbase"):
+		return DialectPostgresql
+	case strings.Contains(databaseType, "oracle"):
+		return DialectOracle
+	case databaseType == "dm" || strings.Contains(databaseType, "dameng"):
+		return DialectDm
+	case strings.Contains(databaseType, "sqlite"):
+		return DialectSqlite
+	}
+	return ""
+}
+
+// Node 统一的 执行计划 节点
+type Node struct {
+	Operation string   `json:"operation"`
+	Object    string   `json:"object,omitempty"` // 表、索引 等
+	Index     string   `json:"index,omitempty"`  // 使用的 索引
+	Rows      float64  `json:"rows"`             // 预估 行数
+	Cost      float64  `json:"cost"`             // 预估 代价，各数据库 单位 不同
+	Filters   []string `json:"filters,omitempty"`
+	Extra     string   `json:"extra,omitempty"`
+	FullScan  bool     `json:"fullScan,omitempty"`
+	Warnings  []string `json:"warnings,omitempty"`
+	Children  []*Node  `json:"children,omitempty"`
+}
+
+// Plan 执行计划
+type Plan struct {
+	Dialect   string   `json:"dialect"`
+	Root      *Node    `json:"root"`
+	FullScans int      `json:"fullScans"`
+	Warnings  []string `json:"warnings,omitempty"` // 所有节点 的 提示 汇总
+}
+
+func (this_ *Node) addWarning(warning string) {
+	this_.Warnings = append(this_.Warnings, warning)
+}
+
+// NewPlan 汇总 全表扫描 及 提示
+func NewPlan(dialect string, root *Node) (plan *Plan) {
+	plan = &Plan{
+		Dialect: dialect,
+		Root:    root,
+	}
+	var walk func(node *Node)
+	walk = func(node *Node) {
+		if node.FullScan {
+			plan.FullScans++
+		}
+		plan.Warnings = append(plan.Warnings, node.Warnings...)
+		for _, one := range node.Children {
+			walk(one)
+		}
+	}
+	if root != nil {
+		walk(root)
+	}
+	return
+}
+
+// newRoot 多个 顶层 节点 时 使用 虚拟 根节点
+func newRoot(children []*Node) *Node {
+	if len(children) == 1 {
+		return children[0]
+	}
+	root := &Node{
+		Operation: "QUERY",
+		Children:  children,
+	}
+	for _, one := range children {
+		root.Cost += one.Cost
+	}
+	return root
+}
+
+// getValue 忽略 列名 大小写
+func getValue(row map[string]interface{}, name string) interface{} {
+	if v, ok := row[name]; ok {
+		return v
+	}
+	for k, v := range row {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	return nil
+}
+
+func getString(row map[string]interface{}, name string) string {
+	v := getValue(row, name)
+	switch s := v.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(s)
+	case string:
+		return s
+	}
+	return fmt.Sprint(v)
+}
+
+func getFloat(row map[string]interface{}, name string) float64 {
+	return toFloat(getString(row, name))
+}
+
+func toFloat(s string) float64 {
+	f, _ := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	return f
+}
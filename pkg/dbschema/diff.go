@@ -0,0 +1,341 @@
+package dbschema
+
+import (
+	"github.com/team-ide/go-dialect/dialect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	DiffTypeAdd    = "add"    // 源 有，目标 没有
+	DiffTypeDelete = "delete" // 源 没有，目标 有
+	DiffTypeModify = "modify"
+)
+
+// Options 比较 选项
+type Options struct {
+	TableNames    []string `json:"tableNames"` // 只 比较 指定 表，为空 比较 全部
+	IgnoreComment bool     `json:"ignoreComment"`
+	IgnoreDefault bool     `json:"ignoreDefault"`
+	IgnoreIndex   bool     `json:"ignoreIndex"`
+}
+
+// Diff 源 与 目标 的 结构 差异，迁移 脚本 将 目标 修改 为 与 源 一致
+type Diff struct {
+	SourceOwner string       `json:"sourceOwner"`
+	TargetOwner string       `json:"targetOwner"`
+	Tables      []*TableDiff `json:"tables"`
+	AddCount    int          `json:"addCount"`
+	DeleteCount int          `json:"deleteCount"`
+	ModifyCount int          `json:"modifyCount"`
+}
+
+type TableDiff struct {
+	TableName         string              `json:"tableName"`
+	Type              string              `json:"type"`
+	Source            *dialect.TableModel `json:"source,omitempty"`
+	Target            *dialect.TableModel `json:"target,omitempty"`
+	CommentChanged    bool                `json:"commentChanged,omitempty"`
+	PrimaryKeyChanged bool                `json:"primaryKeyChanged,omitempty"`
+	SourcePrimaryKeys []string            `json:"sourcePrimaryKeys,omitempty"`
+	TargetPrimaryKeys []string            `json:"targetPrimaryKeys,omitempty"`
+	Columns           []*ColumnDiff       `json:"columns,omitempty"`
+	Indexes           []*IndexDiff        `json:"indexes,omitempty"`
+}
+
+type ColumnDiff struct {
+	ColumnName string               `json:"columnName"`
+	Type       string               `json:"type"`
+	Source     *dialect.ColumnModel `json:"source,omitempty"`
+	Target     *dialect.ColumnModel `json:"target,omitempty"`
+	Changes    []string             `json:"changes,omitempty"` // 变更 的 属性，如 type、length、notNull
+}
+
+type IndexDiff struct {
+	IndexName string              `json:"indexName"`
+	Type      string              `json:"type"`
+	Source    *dialect.IndexModel `json:"source,omitempty"`
+	Target    *dialect.IndexModel `json:"target,omitempty"`
+}
+
+// IsEmpty 结构 一致
+func (this_ *Diff) IsEmpty() bool {
+	return len(this_.Tables) == 0
+}
+
+// Compare 比较 表 结构，名称 忽略 大小写，结果 按 表名 排序
+func Compare(sourceOwner string, sourceTables []*dialect.TableModel, targetOwner string, targetTables []*dialect.TableModel, options *Options) (diff *Diff) {
+	if options == nil {
+		options = &Options{}
+	}
+	diff = &Diff{
+		SourceOwner: sourceOwner,
+		TargetOwner: targetOwner,
+	}
+	var filter map[string]bool
+	if len(options.TableNames) > 0 {
+		filter = map[string]bool{}
+		for _, one := range options.TableNames {
+			filter[nameKey(one)] = true
+		}
+	}
+	sourceMap := map[string]*dialect.TableModel{}
+	targetMap := map[string]*dialect.TableModel{}
+	var names []string
+	for _, one := range sourceTables {
+		key := nameKey(one.TableName)
+		if filter != nil && !filter[key] {
+			continue
+		}
+		sourceMap[key] = one
+		names = append(names, key)
+	}
+	for _, one := range targetTables {
+		key := nameKey(one.TableName)
+		if filter != nil && !filter[key] {
+			continue
+		}
+		targetMap[key] = one
+		if sourceMap[key] == nil {
+			names = append(names, key)
+		}
+	}
+	sort.Strings(names)
+
+	for _, key := range names {
+		source := sourceMap[key]
+		target := targetMap[key]
+		var tableDiff *TableDiff
+		switch {
+		case target == nil:
+			tableDiff = &TableDiff{TableName: source.TableName, Type: DiffTypeAdd, Source: source}
+			diff.AddCount++
+		case source == nil:
+			tableDiff = &TableDiff{TableName: target.TableName, Type: DiffTypeDelete, Target: target}
+			diff.DeleteCount++
+		default:
+			tableDiff = compareTable(source, target, options)
+			if tableDiff == nil {
+				continue
+			}
+			diff.ModifyCount++
+		}
+		diff.Tables = append(diff.Tables, tableDiff)
+	}
+	return
+}
+
+func compareTable(source *dialect.TableModel, target *dialect.TableModel, options *Options) (tableDiff *TableDiff) {
+	res := &TableDiff{
+		TableName: target.TableName,
+		Type:      DiffTypeModify,
+		Source:    source,
+		Target:    target,
+	}
+	if !options.IgnoreComment && source.TableComment != target.TableComment {
+		res.CommentChanged = true
+	}
+
+	res.SourcePrimaryKeys = GetPrimaryKeys(source)
+	res.TargetPrimaryKeys = GetPrimaryKeys(target)
+	if !equalNames(res.SourcePrimaryKeys, res.TargetPrimaryKeys) {
+		res.PrimaryKeyChanged = true
+	}
+
+	targetColumns := map[string]*dialect.ColumnModel{}
+	for _, one := range target.ColumnList {
+		targetColumns[nameKey(one.ColumnName)] = one
+	}
+	sourceColumns := map[string]bool{}
+	for _, one := range source.ColumnList {
+		key := nameKey(one.ColumnName)
+		sourceColumns[key] = true
+		targetColumn := targetColumns[key]
+		if targetColumn == nil {
+			res.Columns = append(res.Columns, &ColumnDiff{ColumnName: one.ColumnName, Type: DiffTypeAdd, Source: one})
+			continue
+		}
+		changes := compareColumn(one, targetColumn, options)
+		if len(changes) > 0 {
+			res.Columns = append(res.Columns, &ColumnDiff{ColumnName: targetColumn.ColumnName, Type: DiffTypeModify, Source: one, Target: targetColumn, Changes: changes})
+		}
+	}
+	for _, one := range target.ColumnList {
+		if !sourceColumns[nameKey(one.ColumnName)] {
+			res.Columns = append(res.Columns, &ColumnDiff{ColumnName: one.ColumnName, Type: DiffTypeDelete, Target: one})
+		}
+	}
+
+	if !options.IgnoreIndex {
+		res.Indexes = compareIndexes(source.IndexList, target.IndexList)
+	}
+
+	if !res.CommentChanged && !res.PrimaryKeyChanged && len(res.Columns) == 0 && len(res.Indexes) == 0 {
+		return
+	}
+	tableDiff = res
+	return
+}
+
+func compareColumn(source *dialect.ColumnModel, target *dialect.ColumnModel, options *Options) (changes []string) {
+	if normalizeDataType(source.ColumnDataType) != normalizeDataType(target.ColumnDataType) {
+		changes = append(changes, "type")
+	}
+	if source.ColumnLength != target.ColumnLength {
+		changes = append(changes, "length")
+	}
+	if source.ColumnPrecision != target.ColumnPrecision {
+		changes = append(changes, "precision")
+	}
+	if source.ColumnScale != target.ColumnScale {
+		changes = append(changes, "scale")
+	}
+	if source.ColumnNotNull != target.ColumnNotNull {
+		changes = append(changes, "notNull")
+	}
+	if !options.IgnoreDefault && normalizeDefault(source.ColumnDefault) != normalizeDefault(target.ColumnDefault) {
+		changes = append(changes, "default")
+	}
+	if !options.IgnoreComment && source.ColumnComment != target.ColumnComment {
+		changes = append(changes, "comment")
+	}
+	return
+}
+
+// compareIndexes 按 名称 匹配，名称 不同 但 列 及 唯一性 相同 的 视为 同一 索引
+func compareIndexes(sourceList []*dialect.IndexModel, targetList []*dialect.IndexModel) (res []*IndexDiff) {
+	matched := map[*dialect.IndexModel]bool{}
+	findTarget := func(source *dialect.IndexModel) *dialect.IndexModel {
+		for _, one := range targetList {
+			if !matched[one] && nameKey(one.IndexName) == nameKey(source.IndexName) {
+				return one
+			}
+		}
+		for _, one := range targetList {
+			if !matched[one] && equalIndex(source, one) {
+				return one
+			}
+		}
+		return nil
+	}
+	for _, source := range sourceList {
+		target := findTarget(source)
+		if target == nil {
+			res = append(res, &IndexDiff{IndexName: source.IndexName, Type: DiffTypeAdd, Source: source})
+			continue
+		}
+		matched[target] = true
+		if !equalIndex(source, target) {
+			res = append(res, &IndexDiff{IndexName: target.IndexName, Type: DiffTypeModify, Source: source, Target: target})
+		}
+	}
+	for _, target := range targetList {
+		if !matched[target] {
+			res = append(res, &IndexDiff{IndexName: target.IndexName, Type: DiffTypeDelete, Target: target})
+		}
+	}
+	return
+}
+
+func equalIndex(source *dialect.IndexModel, target *dialect.IndexModel) bool {
+	return isUniqueIndex(source) == isUniqueIndex(target) && equalNames(GetIndexColumnNames(source), GetIndexColumnNames(target))
+}
+
+func isUniqueIndex(index *dialect.IndexModel) bool {
+	return strings.Contains(strings.ToLower(index.IndexType), "unique")
+}
+
+// GetIndexColumnNames 索引 列，兼容 只有 ColumnName 的 情况
+func GetIndexColumnNames(index *dialect.IndexModel) (names []string) {
+	names = index.ColumnNames
+	if len(names) == 0 && index.ColumnName != "" {
+		names = strings.Split(index.ColumnName, ",")
+	}
+	return
+}
+
+// GetPrimaryKeys 主键 列，PrimaryKeys 为空 时 从 字段 中 获取
+func GetPrimaryKeys(table *dialect.TableModel) (names []string) {
+	names = table.PrimaryKeys
+	if len(names) > 0 {
+		return
+	}
+	for _, one := range table.ColumnList {
+		if one.PrimaryKey {
+			names = append(names, one.ColumnName)
+		}
+	}
+	return
+}
+
+func equalNames(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if nameKey(a[i]) != nameKey(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func nameKey(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+var (
+	dataTypeSpaceRegexp = regexp.MustCompile(`\s+`)
+	dataTypeSizeRegexp  = regexp.MustCompile(`\s*\([^)]*\)`)
+	// dataTypeAliases 不同 数据库 中 同一 类型 的 名称，长度、精度 单独 比较
+	dataTypeAliases = map[string]string{
+		"INTEGER":                     "INT",
+		"INT4":                        "INT",
+		"INT8":                        "BIGINT",
+		"INT2":                        "SMALLINT",
+		"VARCHAR2":                    "VARCHAR",
+		"CHARACTER VARYING":           "VARCHAR",
+		"CHARACTER":                   "CHAR",
+		"BPCHAR":                      "CHAR",
+		"NUMERIC":                     "DECIMAL",
+		"NUMBER":                      "DECIMAL",
+		"DOUBLE PRECISION":            "DOUBLE",
+		"FLOAT8":                      "DOUBLE",
+		"FLOAT4":                      "FLOAT",
+		"BOOL":                        "BOOLEAN",
+		"CLOB":                        "TEXT",
+		"BYTEA":                       "BLOB",
+		"TIMESTAMP WITHOUT TIME ZONE": "TIMESTAMP",
+		"TIMESTAMP WITH TIME ZONE":    "TIMESTAMPTZ",
+		"TIME WITHOUT TIME ZONE":      "TIME",
+	}
+)
+
+// normalizeDataType 去除 长度，统一 大小写 及 不同 数据库 的 类型 别名，如 int4、INTEGER 视为 INT
+func normalizeDataType(dataType string) string {
+	dataType = strings.ToUpper(strings.TrimSpace(dataType))
+	dataType = dataTypeSizeRegexp.ReplaceAllString(dataType, "")
+	dataType = dataTypeSpaceRegexp.ReplaceAllString(dataType, " ")
+	if alias, ok := dataTypeAliases[dataType]; ok {
+		return alias
+	}
+	return dataType
+}
+
+// normalizeDefault 去除 引号、括号，如 ('0')、'0' 视为 0
+func normalizeDefault(value string) string {
+	value = strings.TrimSpace(value)
+	for len(value) >= 2 {
+		first, last := value[0], value[len(value)-1]
+		if (first == '(' && last == ')') || (first == '\'' && last == '\'') {
+			value = strings.TrimSpace(value[1 : len(value)-1])
+			continue
+		}
+		break
+	}
+	if strings.EqualFold(value, "null") {
+		return ""
+	}
+	return value
+}
@@ -0,0 +1,147 @@
+package dbschema
+
+import (
+	"github.com/team-ide/go-dialect/dialect"
+	"strings"
+	"testing"
+)
+
+func testTables() (source []*dialect.TableModel, target []*dialect.TableModel) {
+	source = []*dialect.TableModel{
+		{
+			TableName:    "user",
+			TableComment: "用户",
+			ColumnList: []*dialect.ColumnModel{
+				{ColumnName: "id", ColumnDataType: "bigint", ColumnLength: 20, ColumnNotNull: true, PrimaryKey: true},
+				{ColumnName: "name", ColumnDataType: "varchar", ColumnLength: 100, ColumnComment: "名称"},
+				{ColumnName: "email", ColumnDataType: "varchar", ColumnLength: 200},
+				{ColumnName: "status", ColumnDataType: "int", ColumnLength: 10, ColumnDefault: "'1'"},
+			},
+			IndexList: []*dialect.IndexModel{
+				{IndexName: "uk_email", IndexType: "unique", ColumnNames: []string{"email"}},
+				{IndexName: "idx_name", ColumnNames: []string{"name"}},
+			},
+		},
+		{
+			TableName: "order",
+			ColumnList: []*dialect.ColumnModel{
+				{ColumnName: "id", ColumnDataType: "bigint", ColumnLength: 20, ColumnNotNull: true, PrimaryKey: true},
+			},
+		},
+	}
+	target = []*dialect.TableModel{
+		{
+			TableName:    "USER",
+			TableComment: "用户表",
+			ColumnList: []*dialect.ColumnModel{
+				{ColumnName: "ID", ColumnDataType: "BIGINT", ColumnLength: 20, ColumnNotNull: true, PrimaryKey: true},
+				{ColumnName: "NAME", ColumnDataType: "varchar", ColumnLength: 50, ColumnComment: "名称"},
+				{ColumnName: "STATUS", ColumnDataType: "int", ColumnLength: 10, ColumnDefault: "1"},
+				{ColumnName: "OLD", ColumnDataType: "varchar", ColumnLength: 10},
+			},
+			IndexList: []*dialect.IndexModel{
+				{IndexName: "IDX_USER_NAME", ColumnName: "NAME"},
+				{IndexName: "IDX_OLD", ColumnNames: []string{"OLD"}},
+			},
+		},
+		{
+			TableName: "log",
+			ColumnList: []*dialect.ColumnModel{
+				{ColumnName: "id", ColumnDataType: "bigint", ColumnLength: 20},
+			},
+		},
+	}
+	return
+}
+
+func TestCompare(t *testing.T) {
+	source, target := testTables()
+	diff := Compare("dev", source, "prod", target, nil)
+	if diff.AddCount != 1 || diff.DeleteCount != 1 || diff.ModifyCount != 1 || len(diff.Tables) != 3 {
+		t.Fatalf("diff %+v", diff)
+	}
+	if diff.Tables[0].TableName != "log" || diff.Tables[0].Type != DiffTypeDelete || diff.Tables[1].Type != DiffTypeAdd {
+		t.Fatalf("tables %+v %+v", diff.Tables[0], diff.Tables[1])
+	}
+	user := diff.Tables[2]
+	if !user.CommentChanged || user.PrimaryKeyChanged {
+		t.Fatalf("user %+v", user)
+	}
+	var columns []string
+	for _, one := range user.Columns {
+		columns = append(columns, one.Type+":"+one.ColumnName+":"+strings.Join(one.Changes, ","))
+	}
+	if strings.Join(columns, "|") != "modify:NAME:length|add:email:|delete:OLD:" {
+		t.Fatalf("columns %v", columns)
+	}
+	var indexes []string
+	for _, one := range user.Indexes {
+		indexes = append(indexes, one.Type+":"+one.IndexName)
+	}
+	// idx_name 与 IDX_USER_NAME 列 相同，视为 同一 索引
+	if strings.Join(indexes, "|") != "add:uk_email|delete:IDX_OLD" {
+		t.Fatalf("indexes %v", indexes)
+	}
+
+	diff = Compare("dev", source, "prod", target, &Options{TableNames: []string{"USER"}, IgnoreComment: true, IgnoreIndex: true})
+	if len(diff.Tables) != 1 || diff.Tables[0].CommentChanged || len(diff.Tables[0].Indexes) != 0 {
+		t.Fatalf("filter diff %+v", diff.Tables)
+	}
+}
+
+func TestNormalizeDataType(t *testing.T) {
+	for _, one := range [][2]string{
+		{"int4", "INTEGER"},
+		{"character varying", "VARCHAR2"},
+		{"varchar(255)", "VARCHAR"},
+		{"numeric", "NUMBER"},
+		{"timestamp  without time zone", "timestamp"},
+		{"int(11) unsigned", "int unsigned"},
+	} {
+		if normalizeDataType(one[0]) != normalizeDataType(one[1]) {
+			t.Fatalf("%s != %s", one[0], one[1])
+		}
+	}
+	if normalizeDataType("int") == normalizeDataType("bigint") || normalizeDataType("text") == normalizeDataType("varchar") {
+		t.Fatal("different types should not equal")
+	}
+}
+
+func TestBuildMigration(t *testing.T) {
+	source, target := testTables()
+	diff := Compare("dev", source, "prod", target, nil)
+	dia, err := dialect.NewDialect(dialect.TypeMysql.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	migration := BuildMigration(dia, nil, diff, nil)
+	if len(migration.Errors) != 0 {
+		t.Fatalf("errors %v", migration.Errors)
+	}
+	var actions []string
+	for _, one := range migration.Steps {
+		actions = append(actions, one.Action)
+	}
+	if strings.Join(actions, ",") != "skipDropTable,createTable,dropIndex,modifyColumn,addColumn,skipDropColumn,addIndex,tableComment" {
+		t.Fatalf("actions %v", actions)
+	}
+	forward := strings.Join(migration.Forward, ";\n")
+	rollback := strings.Join(migration.Rollback, ";\n")
+	if !strings.Contains(forward, "CREATE TABLE") || strings.Contains(forward, "DROP TABLE") || !strings.Contains(rollback, "DROP TABLE") {
+		t.Fatalf("forward:\n%s\nrollback:\n%s", forward, rollback)
+	}
+	if len(migration.Warnings) != 3 {
+		t.Fatalf("warnings %v", migration.Warnings)
+	}
+
+	migration = BuildMigration(dia, nil, diff, &MigrationOptions{DropTable: true, DropColumn: true})
+	forward = strings.Join(migration.Forward, ";\n")
+	if !strings.Contains(forward, "DROP TABLE") || !strings.Contains(strings.ToUpper(forward), "DROP COLUMN") {
+		t.Fatalf("forward:\n%s", forward)
+	}
+	// 回滚 顺序 与 迁移 相反，先 恢复 注释
+	if !strings.Contains(migration.Rollback[0], "用户表") {
+		t.Fatalf("rollback %v", migration.Rollback)
+	}
+}
@@ -0,0 +1,279 @@
+package dbschema
+
+import (
+	"github.com/team-ide/go-dialect/dialect"
+)
+
+const (
+	ActionCreateTable    = "createTable"
+	ActionDropTable      = "dropTable"
+	ActionTableComment   = "tableComment"
+	ActionAddColumn      = "addColumn"
+	ActionModifyColumn   = "modifyColumn"
+	ActionDropColumn     = "dropColumn"
+	ActionAddPrimaryKey  = "addPrimaryKey"
+	ActionDropPrimaryKey = "dropPrimaryKey"
+	ActionAddIndex       = "addIndex"
+	ActionDropIndex      = "dropIndex"
+	ActionSkipDropTable  = "skipDropTable"
+	ActionSkipDropColumn = "skipDropColumn"
+)
+
+const (
+	warningDropTableData   = "删除表将丢失数据，回滚只能恢复表结构"
+	warningDropColumnData  = "删除字段将丢失数据，回滚只能恢复字段结构"
+	warningSkipDropTable   = "目标多出的表未删除，如需删除请开启删除表"
+	warningSkipDropColumn  = "目标多出的字段未删除，如需删除请开启删除字段"
+	warningModifyColumnLen = "字段类型或长度变更，可能导致数据截断"
+)
+
+// MigrationOptions 迁移 选项，删除表、删除字段 会 丢失 数据，默认 不生成
+type MigrationOptions struct {
+	DropTable  bool `json:"dropTable"`
+	DropColumn bool `json:"dropColumn"`
+}
+
+// Step 迁移 步骤，回滚 时 倒序 执行 各步骤 的 Rollback
+type Step struct {
+	TableName string   `json:"tableName"`
+	Action    string   `json:"action"`
+	Name      string   `json:"name,omitempty"` // 字段、索引 名称
+	Forward   []string `json:"forward,omitempty"`
+	Rollback  []string `json:"rollback,omitempty"`
+	Warning   string   `json:"warning,omitempty"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// Migration 迁移 脚本，在 目标 库 执行
+type Migration struct {
+	Steps    []*Step  `json:"steps"`
+	Forward  []string `json:"forward"`
+	Rollback []string `json:"rollback"`
+	Warnings []string `json:"warnings,omitempty"`
+	Errors   []string `json:"errors,omitempty"`
+}
+
+type migrationBuilder struct {
+	dia       dialect.Dialect
+	param     *dialect.ParamModel
+	ownerName string
+	options   *MigrationOptions
+	steps     []*Step
+}
+
+// BuildMigration 使用 目标 库 方言 生成 迁移 及 回滚 脚本，单个 步骤 生成 失败 记录 错误 不影响 其它 步骤
+func BuildMigration(dia dialect.Dialect, param *dialect.ParamModel, diff *Diff, options *MigrationOptions) (migration *Migration) {
+	if param == nil {
+		param = &dialect.ParamModel{}
+	}
+	if options == nil {
+		options = &MigrationOptions{}
+	}
+	builder := &migrationBuilder{
+		dia:       dia,
+		param:     param,
+		ownerName: diff.TargetOwner,
+		options:   options,
+	}
+	for _, one := range diff.Tables {
+		switch one.Type {
+		case DiffTypeAdd:
+			builder.createTable(one)
+		case DiffTypeDelete:
+			builder.dropTable(one)
+		case DiffTypeModify:
+			builder.modifyTable(one)
+		}
+	}
+
+	migration = &Migration{
+		Steps:    builder.steps,
+		Forward:  []string{},
+		Rollback: []string{},
+	}
+	for _, one := range builder.steps {
+		migration.Forward = append(migration.Forward, one.Forward...)
+		if one.Warning != "" {
+			migration.Warnings = append(migration.Warnings, "表["+one.TableName+"]"+one.Name+":"+one.Warning)
+		}
+		if one.Error != "" {
+			migration.Errors = append(migration.Errors, "表["+one.TableName+"]"+one.Name+":"+one.Error)
+		}
+	}
+	for i := len(builder.steps) - 1; i >= 0; i-- {
+		migration.Rollback = append(migration.Rollback, builder.steps[i].Rollback...)
+	}
+	return
+}
+
+func (this_ *migrationBuilder) addStep(step *Step, forward func() ([]string, error), rollback func() ([]string, error)) {
+	var err error
+	if forward != nil {
+		step.Forward, err = forward()
+	}
+	if err == nil && rollback != nil {
+		step.Rollback, err = rollback()
+	}
+	if err != nil {
+		step.Forward = nil
+		step.Rollback = nil
+		step.Error = err.Error()
+	}
+	this_.steps = append(this_.steps, step)
+}
+
+func (this_ *migrationBuilder) createTable(tableDiff *TableDiff) {
+	table := copyTable(tableDiff.Source, this_.ownerName)
+	this_.addStep(&Step{TableName: table.TableName, Action: ActionCreateTable}, func() ([]string, error) {
+		return this_.dia.TableCreateSql(this_.param, this_.ownerName, table)
+	}, func() ([]string, error) {
+		return this_.dia.TableDeleteSql(this_.param, this_.ownerName, table.TableName)
+	})
+}
+
+func (this_ *migrationBuilder) dropTable(tableDiff *TableDiff) {
+	table := copyTable(tableDiff.Target, this_.ownerName)
+	if !this_.options.DropTable {
+		this_.steps = append(this_.steps, &Step{TableName: table.TableName, Action: ActionSkipDropTable, Warning: warningSkipDropTable})
+		return
+	}
+	this_.addStep(&Step{TableName: table.TableName, Action: ActionDropTable, Warning: warningDropTableData}, func() ([]string, error) {
+		return this_.dia.TableDeleteSql(this_.param, this_.ownerName, table.TableName)
+	}, func() ([]string, error) {
+		return this_.dia.TableCreateSql(this_.param, this_.ownerName, table)
+	})
+}
+
+// modifyTable 先 删除 变更的 索引、主键，再 修改 字段，最后 添加 主键、索引
+func (this_ *migrationBuilder) modifyTable(tableDiff *TableDiff) {
+	tableName := tableDiff.Target.TableName
+
+	for _, one := range tableDiff.Indexes {
+		if one.Type == DiffTypeAdd {
+			continue
+		}
+		target := copyIndex(one.Target)
+		this_.addStep(&Step{TableName: tableName, Action: ActionDropIndex, Name: target.IndexName}, func() ([]string, error) {
+			return this_.dia.IndexDeleteSql(this_.param, this_.ownerName, tableName, target.IndexName)
+		}, func() ([]string, error) {
+			return this_.dia.IndexAddSql(this_.param, this_.ownerName, tableName, target)
+		})
+	}
+
+	if tableDiff.PrimaryKeyChanged && len(tableDiff.TargetPrimaryKeys) > 0 {
+		targetKeys := tableDiff.TargetPrimaryKeys
+		this_.addStep(&Step{TableName: tableName, Action: ActionDropPrimaryKey}, func() ([]string, error) {
+			return this_.dia.PrimaryKeyDeleteSql(this_.param, this_.ownerName, tableName)
+		}, func() ([]string, error) {
+			return this_.dia.PrimaryKeyAddSql(this_.param, this_.ownerName, tableName, targetKeys)
+		})
+	}
+
+	for _, one := range tableDiff.Columns {
+		this_.modifyColumn(tableName, one)
+	}
+
+	if tableDiff.PrimaryKeyChanged && len(tableDiff.SourcePrimaryKeys) > 0 {
+		sourceKeys := tableDiff.SourcePrimaryKeys
+		this_.addStep(&Step{TableName: tableName, Action: ActionAddPrimaryKey}, func() ([]string, error) {
+			return this_.dia.PrimaryKeyAddSql(this_.param, this_.ownerName, tableName, sourceKeys)
+		}, func() ([]string, error) {
+			return this_.dia.PrimaryKeyDeleteSql(this_.param, this_.ownerName, tableName)
+		})
+	}
+
+	for _, one := range tableDiff.Indexes {
+		if one.Type == DiffTypeDelete {
+			continue
+		}
+		source := copyIndex(one.Source)
+		this_.addStep(&Step{TableName: tableName, Action: ActionAddIndex, Name: source.IndexName}, func() ([]string, error) {
+			return this_.dia.IndexAddSql(this_.param, this_.ownerName, tableName, source)
+		}, func() ([]string, error) {
+			return this_.dia.IndexDeleteSql(this_.param, this_.ownerName, tableName, source.IndexName)
+		})
+	}
+
+	if tableDiff.CommentChanged {
+		sourceComment := tableDiff.Source.TableComment
+		targetComment := tableDiff.Target.TableComment
+		this_.addStep(&Step{TableName: tableName, Action: ActionTableComment}, func() ([]string, error) {
+			return this_.dia.TableCommentSql(this_.param, this_.ownerName, tableName, sourceComment)
+		}, func() ([]string, error) {
+			return this_.dia.TableCommentSql(this_.param, this_.ownerName, tableName, targetComment)
+		})
+	}
+}
+
+func (this_ *migrationBuilder) modifyColumn(tableName string, columnDiff *ColumnDiff) {
+	switch columnDiff.Type {
+	case DiffTypeAdd:
+		source := copyColumn(columnDiff.Source, columnDiff.Source.ColumnName)
+		this_.addStep(&Step{TableName: tableName, Action: ActionAddColumn, Name: source.ColumnName}, func() ([]string, error) {
+			return this_.dia.ColumnAddSql(this_.param, this_.ownerName, tableName, source)
+		}, func() ([]string, error) {
+			return this_.dia.ColumnDeleteSql(this_.param, this_.ownerName, tableName, source.ColumnName)
+		})
+	case DiffTypeDelete:
+		target := copyColumn(columnDiff.Target, columnDiff.Target.ColumnName)
+		if !this_.options.DropColumn {
+			this_.steps = append(this_.steps, &Step{TableName: tableName, Action: ActionSkipDropColumn, Name: target.ColumnName, Warning: warningSkipDropColumn})
+			return
+		}
+		this_.addStep(&Step{TableName: tableName, Action: ActionDropColumn, Name: target.ColumnName, Warning: warningDropColumnData}, func() ([]string, error) {
+			return this_.dia.ColumnDeleteSql(this_.param, this_.ownerName, tableName, target.ColumnName)
+		}, func() ([]string, error) {
+			return this_.dia.ColumnAddSql(this_.param, this_.ownerName, tableName, target)
+		})
+	case DiffTypeModify:
+		// 保持 目标 字段名，只 修改 属性
+		columnName := columnDiff.Target.ColumnName
+		source := copyColumn(columnDiff.Source, columnName)
+		target := copyColumn(columnDiff.Target, columnName)
+		step := &Step{TableName: tableName, Action: ActionModifyColumn, Name: columnName}
+		for _, change := range columnDiff.Changes {
+			if change == "type" || change == "length" || change == "precision" || change == "scale" {
+				step.Warning = warningModifyColumnLen
+				break
+			}
+		}
+		this_.addStep(step, func() ([]string, error) {
+			return this_.dia.ColumnUpdateSql(this_.param, this_.ownerName, tableName, copyColumn(target, columnName), copyColumn(source, columnName))
+		}, func() ([]string, error) {
+			return this_.dia.ColumnUpdateSql(this_.param, this_.ownerName, tableName, copyColumn(source, columnName), copyColumn(target, columnName))
+		})
+	}
+}
+
+// copyTable 复制 表，避免 修改 比较 结果
+func copyTable(table *dialect.TableModel, ownerName string) (res *dialect.TableModel) {
+	res = &dialect.TableModel{}
+	*res = *table
+	res.OwnerName = ownerName
+	res.PrimaryKeys = GetPrimaryKeys(table)
+	res.ColumnList = nil
+	for _, one := range table.ColumnList {
+		res.ColumnList = append(res.ColumnList, copyColumn(one, one.ColumnName))
+	}
+	res.IndexList = nil
+	for _, one := range table.IndexList {
+		res.IndexList = append(res.IndexList, copyIndex(one))
+	}
+	return
+}
+
+func copyColumn(column *dialect.ColumnModel, columnName string) (res *dialect.ColumnModel) {
+	res = &dialect.ColumnModel{}
+	*res = *column
+	res.ColumnName = columnName
+	// 不 调整 字段 位置
+	res.ColumnAfterColumn = ""
+	return
+}
+
+func copyIndex(index *dialect.IndexModel) (res *dialect.IndexModel) {
+	res = &dialect.IndexModel{}
+	*res = *index
+	res.ColumnNames = GetIndexColumnNames(index)
+	return
+}
@@ -0,0 +1,362 @@
+package erd
+
+import (
+	"fmt"
+	"github.com/team-ide/go-dialect/dialect"
+	"sort"
+	"strings"
+)
+
+const (
+	CardinalityManyToOne = "manyToOne"
+	CardinalityOneToOne  = "oneToOne"
+)
+
+// Model 实体关系 模型
+type Model struct {
+	OwnerName string      `json:"ownerName"`
+	Entities  []*Entity   `json:"entities"`
+	Relations []*Relation `json:"relations"`
+}
+
+type Entity struct {
+	Name       string       `json:"name"`
+	Comment    string       `json:"comment,omitempty"`
+	Attributes []*Attribute `json:"attributes"`
+}
+
+type Attribute struct {
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	Comment    string `json:"comment,omitempty"`
+	NotNull    bool   `json:"notNull,omitempty"`
+	PrimaryKey bool   `json:"primaryKey,omitempty"`
+	ForeignKey bool   `json:"foreignKey,omitempty"`
+}
+
+// Relation From 的 FromColumns 引用 To 的 ToColumns，Inferred 为 按 命名 推断 的 关系
+type Relation struct {
+	Name        string   `json:"name,omitempty"`
+	From        string   `json:"from"`
+	FromColumns []string `json:"fromColumns"`
+	To          string   `json:"to"`
+	ToColumns   []string `json:"toColumns"`
+	Cardinality string   `json:"cardinality"`
+	Inferred    bool     `json:"inferred,omitempty"`
+}
+
+// ForeignKey 声明的 外键，多列 外键 每列 一条
+type ForeignKey struct {
+	Name             string `json:"name"`
+	TableName        string `json:"tableName"`
+	ColumnName       string `json:"columnName"`
+	ReferencedTable  string `json:"referencedTable"`
+	ReferencedColumn string `json:"referencedColumn"`
+}
+
+// Options 生成 选项
+type Options struct {
+	Infer    bool `json:"infer"`    // 按 命名 推断 关系，如 user_id -> user.id
+	OnlyKeys bool `json:"onlyKeys"` // 只 显示 主键、外键 字段
+}
+
+// NewModel 根据 表 结构 及 外键 生成 模型，引用 不在 表 列表中 的 外键 忽略
+func NewModel(ownerName string, tables []*dialect.TableModel, foreignKeys []*ForeignKey, options *Options) (model *Model) {
+	if options == nil {
+		options = &Options{}
+	}
+	model = &Model{
+		OwnerName: ownerName,
+		Entities:  []*Entity{},
+		Relations: []*Relation{},
+	}
+	sort.Slice(tables, func(i, j int) bool {
+		return strings.ToLower(tables[i].TableName) < strings.ToLower(tables[j].TableName)
+	})
+	tableMap := map[string]*dialect.TableModel{}
+	for _, table := range tables {
+		tableMap[strings.ToLower(table.TableName)] = table
+	}
+
+	// 外键 列，key 为 表名.列名
+	fkColumns := map[string]bool{}
+	for _, relation := range groupForeignKeys(foreignKeys) {
+		from := tableMap[strings.ToLower(relation.From)]
+		to := tableMap[strings.ToLower(relation.To)]
+		if from == nil || to == nil {
+			continue
+		}
+		relation.From = from.TableName
+		relation.To = to.TableName
+		// SQLite 外键 未 指定 引用 列 时 引用 主键
+		if toKeys := getPrimaryKeys(to); len(toKeys) == len(relation.ToColumns) {
+			for i, one := range relation.ToColumns {
+				if one == "" {
+					relation.ToColumns[i] = toKeys[i]
+				}
+			}
+		}
+		relation.Cardinality = getCardinality(from, relation.FromColumns)
+		model.Relations = append(model.Relations, relation)
+		for _, one := range relation.FromColumns {
+			fkColumns[columnKey(from.TableName, one)] = true
+		}
+	}
+	if options.Infer {
+		for _, table := range tables {
+			for _, column := range table.ColumnList {
+				if fkColumns[columnKey(table.TableName, column.ColumnName)] {
+					continue
+				}
+				relation := inferRelation(table, column, tableMap)
+				if relation == nil {
+					continue
+				}
+				model.Relations = append(model.Relations, relation)
+				fkColumns[columnKey(table.TableName, column.ColumnName)] = true
+			}
+		}
+	}
+
+	for _, table := range tables {
+		entity := &Entity{
+			Name:       table.TableName,
+			Comment:    table.TableComment,
+			Attributes: []*Attribute{},
+		}
+		primaryKeys := getPrimaryKeys(table)
+		for _, column := range table.ColumnList {
+			attribute := &Attribute{
+				Name:       column.ColumnName,
+				Type:       getTypeText(column),
+				Comment:    column.ColumnComment,
+				NotNull:    column.ColumnNotNull,
+				PrimaryKey: containsFold(primaryKeys, column.ColumnName),
+				ForeignKey: fkColumns[columnKey(table.TableName, column.ColumnName)],
+			}
+			if options.OnlyKeys && !attribute.PrimaryKey && !attribute.ForeignKey {
+				continue
+			}
+			entity.Attributes = append(entity.Attributes, attribute)
+		}
+		model.Entities = append(model.Entities, entity)
+	}
+	return
+}
+
+// groupForeignKeys 按 表 及 约束名 合并 多列 外键，保持 出现 顺序
+func groupForeignKeys(foreignKeys []*ForeignKey) (relations []*Relation) {
+	relationMap := map[string]*Relation{}
+	for _, one := range foreignKeys {
+		key := strings.ToLower(one.TableName) + "." + one.Name
+		relation := relationMap[key]
+		if relation == nil || one.Name == "" {
+			relation = &Relation{
+				Name: one.Name,
+				From: one.TableName,
+				To:   one.ReferencedTable,
+			}
+			relationMap[key] = relation
+			relations = append(relations, relation)
+		}
+		relation.FromColumns = append(relation.FromColumns, one.ColumnName)
+		relation.ToColumns = append(relation.ToColumns, one.ReferencedColumn)
+	}
+	return
+}
+
+// inferRelation 字段 名 为 xxx_id 或 xxxId 时，查找 表 xxx（含 复数、与 当前表 相同 前缀）的 单列 主键
+func inferRelation(table *dialect.TableModel, column *dialect.ColumnModel, tableMap map[string]*dialect.TableModel) (relation *Relation) {
+	name := column.ColumnName
+	lower := strings.ToLower(name)
+	var base string
+	switch {
+	case strings.HasSuffix(lower, "_id") && len(lower) > 3:
+		base = lower[:len(lower)-3]
+	case strings.HasSuffix(name, "Id") && len(name) > 2:
+		base = strings.ToLower(name[:len(name)-2])
+	case strings.HasSuffix(name, "ID") && len(name) > 2 && name != strings.ToUpper(name):
+		base = strings.ToLower(name[:len(name)-2])
+	default:
+		return
+	}
+	primaryKeys := getPrimaryKeys(table)
+	if len(primaryKeys) == 1 && strings.EqualFold(primaryKeys[0], name) {
+		return
+	}
+
+	candidates := []string{base, base + "s", base + "es"}
+	if strings.HasSuffix(base, "y") {
+		candidates = append(candidates, base[:len(base)-1]+"ies")
+	}
+	// 表名 前缀，如 tm_order.user_id -> tm_user
+	if index := strings.Index(table.TableName, "_"); index > 0 {
+		prefix := strings.ToLower(table.TableName[:index+1])
+		for _, one := range candidates {
+			candidates = append(candidates, prefix+one)
+		}
+	}
+
+	for _, one := range candidates {
+		target := tableMap[one]
+		if target == nil || strings.EqualFold(target.TableName, table.TableName) {
+			continue
+		}
+		targetColumn := getReferencedColumn(target, name)
+		if targetColumn == "" {
+			continue
+		}
+		relation = &Relation{
+			From:        table.TableName,
+			FromColumns: []string{name},
+			To:          target.TableName,
+			ToColumns:   []string{targetColumn},
+			Cardinality: getCardinality(table, []string{name}),
+			Inferred:    true,
+		}
+		return
+	}
+	return
+}
+
+// getReferencedColumn 被 引用 列：单列 主键，或 id 列，或 同名 列
+func getReferencedColumn(table *dialect.TableModel, columnName string) string {
+	primaryKeys := getPrimaryKeys(table)
+	if len(primaryKeys) == 1 {
+		if strings.EqualFold(primaryKeys[0], "id") || strings.EqualFold(primaryKeys[0], columnName) {
+			return primaryKeys[0]
+		}
+		return ""
+	}
+	if len(primaryKeys) == 0 {
+		for _, one := range table.ColumnList {
+			if strings.EqualFold(one.ColumnName, "id") {
+				return one.ColumnName
+			}
+		}
+	}
+	return ""
+}
+
+// getCardinality 外键 列 为 主键 或 唯一索引 时 为 一对一
+func getCardinality(table *dialect.TableModel, columns []string) string {
+	if equalFold(getPrimaryKeys(table), columns) {
+		return CardinalityOneToOne
+	}
+	for _, index := range table.IndexList {
+		if !strings.Contains(strings.ToLower(index.IndexType), "unique") {
+			continue
+		}
+		names := index.ColumnNames
+		if len(names) == 0 && index.ColumnName != "" {
+			names = strings.Split(index.ColumnName, ",")
+		}
+		if equalFold(names, columns) {
+			return CardinalityOneToOne
+		}
+	}
+	return CardinalityManyToOne
+}
+
+func getPrimaryKeys(table *dialect.TableModel) (names []string) {
+	names = table.PrimaryKeys
+	if len(names) > 0 {
+		return
+	}
+	for _, one := range table.ColumnList {
+		if one.PrimaryKey {
+			names = append(names, one.ColumnName)
+		}
+	}
+	return
+}
+
+func getTypeText(column *dialect.ColumnModel) string {
+	dataType := strings.ToLower(column.ColumnDataType)
+	switch {
+	case column.ColumnPrecision > 0 && column.ColumnScale > 0:
+		return fmt.Sprintf("%s(%d,%d)", dataType, column.ColumnPrecision, column.ColumnScale)
+	case column.ColumnLength > 0:
+		return fmt.Sprintf("%s(%d)", dataType, column.ColumnLength)
+	}
+	return dataType
+}
+
+func columnKey(tableName string, columnName string) string {
+	return strings.ToLower(tableName) + "." + strings.ToLower(columnName)
+}
+
+func containsFold(list []string, value string) bool {
+	for _, one := range list {
+		if strings.EqualFold(one, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func equalFold(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !strings.EqualFold(strings.TrimSpace(a[i]), strings.TrimSpace(b[i])) {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseForeignKeys 解析 外键 查询 结果，需要 CONSTRAINT_NAME、TABLE_NAME、COLUMN_NAME、REFERENCED_TABLE_NAME、REFERENCED_COLUMN_NAME 列，忽略 大小写
+func ParseForeignKeys(rows []map[string]interface{}) (foreignKeys []*ForeignKey) {
+	for _, row := range rows {
+		foreignKey := &ForeignKey{
+			Name:             getString(row, "CONSTRAINT_NAME"),
+			TableName:        getString(row, "TABLE_NAME"),
+			ColumnName:       getString(row, "COLUMN_NAME"),
+			ReferencedTable:  getString(row, "REFERENCED_TABLE_NAME"),
+			ReferencedColumn: getString(row, "REFERENCED_COLUMN_NAME"),
+		}
+		if foreignKey.TableName == "" || foreignKey.ReferencedTable == "" {
+			continue
+		}
+		foreignKeys = append(foreignKeys, foreignKey)
+	}
+	return
+}
+
+// ParseSqliteForeignKeys 解析 PRAGMA foreign_key_list 结果，需要 id、table、from、to 列
+func ParseSqliteForeignKeys(tableName string, rows []map[string]interface{}) (foreignKeys []*ForeignKey) {
+	for _, row := range rows {
+		foreignKey := &ForeignKey{
+			Name:             "fk_" + tableName + "_" + getString(row, "id"),
+			TableName:        tableName,
+			ColumnName:       getString(row, "from"),
+			ReferencedTable:  getString(row, "table"),
+			ReferencedColumn: getString(row, "to"),
+		}
+		foreignKeys = append(foreignKeys, foreignKey)
+	}
+	return
+}
+
+func getString(row map[string]interface{}, name string) string {
+	v, ok := row[name]
+	if !ok {
+		for key, value := range row {
+			if strings.EqualFold(key, name) {
+				v = value
+				break
+			}
+		}
+	}
+	switch s := v.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(s)
+	case string:
+		return s
+	}
+	return fmt.Sprint(v)
+}
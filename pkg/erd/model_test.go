@@ -0,0 +1,108 @@
+package erd
+
+import (
+	"github.com/team-ide/go-dialect/dialect"
+	"strings"
+	"testing"
+)
+
+func testTables() []*dialect.TableModel {
+	return []*dialect.TableModel{
+		{
+			TableName: "tm_order",
+			ColumnList: []*dialect.ColumnModel{
+				{ColumnName: "id", ColumnDataType: "bigint", PrimaryKey: true},
+				{ColumnName: "user_id", ColumnDataType: "bigint"},
+				{ColumnName: "categoryId", ColumnDataType: "int"},
+				{ColumnName: "amount", ColumnDataType: "decimal", ColumnPrecision: 10, ColumnScale: 2},
+			},
+		},
+		{
+			TableName:    "tm_user",
+			TableComment: "用户",
+			ColumnList: []*dialect.ColumnModel{
+				{ColumnName: "id", ColumnDataType: "bigint", PrimaryKey: true},
+				{ColumnName: "name", ColumnDataType: "varchar", ColumnLength: 100, ColumnComment: "名称 \"昵称\""},
+			},
+		},
+		{
+			TableName: "categories",
+			ColumnList: []*dialect.ColumnModel{
+				{ColumnName: "id", ColumnDataType: "int", PrimaryKey: true},
+			},
+		},
+		{
+			TableName: "tm_user_profile",
+			ColumnList: []*dialect.ColumnModel{
+				{ColumnName: "profile_user", ColumnDataType: "bigint", PrimaryKey: true},
+			},
+		},
+	}
+}
+
+func TestNewModel(t *testing.T) {
+	foreignKeys := ParseForeignKeys([]map[string]interface{}{
+		{"CONSTRAINT_NAME": "fk_profile_user", "TABLE_NAME": "TM_USER_PROFILE", "COLUMN_NAME": "profile_user", "REFERENCED_TABLE_NAME": "tm_user", "REFERENCED_COLUMN_NAME": "id"},
+		{"constraint_name": "fk_missing", "table_name": "tm_order", "column_name": "x", "referenced_table_name": "other", "referenced_column_name": "id"},
+	})
+	if len(foreignKeys) != 2 {
+		t.Fatalf("foreignKeys %v", foreignKeys)
+	}
+
+	model := NewModel("db", testTables(), foreignKeys, nil)
+	if len(model.Entities) != 4 || model.Entities[0].Name != "categories" || len(model.Relations) != 1 {
+		t.Fatalf("model %+v", model)
+	}
+	if relation := model.Relations[0]; relation.From != "tm_user_profile" || relation.Cardinality != CardinalityOneToOne || relation.Inferred {
+		t.Fatalf("relation %+v", relation)
+	}
+
+	model = NewModel("db", testTables(), foreignKeys, &Options{Infer: true, OnlyKeys: true})
+	var relations []string
+	for _, one := range model.Relations {
+		relations = append(relations, one.From+"."+one.FromColumns[0]+"->"+one.To+"."+one.ToColumns[0])
+	}
+	if strings.Join(relations, "|") != "tm_user_profile.profile_user->tm_user.id|tm_order.user_id->tm_user.id|tm_order.categoryId->categories.id" {
+		t.Fatalf("relations %v", relations)
+	}
+	order := model.Entities[1]
+	if order.Name != "tm_order" || len(order.Attributes) != 3 || !order.Attributes[1].ForeignKey {
+		t.Fatalf("order %+v", order.Attributes)
+	}
+}
+
+func TestParseSqliteForeignKeys(t *testing.T) {
+	foreignKeys := ParseSqliteForeignKeys("tm_order", []map[string]interface{}{
+		{"id": int64(0), "seq": int64(0), "table": "tm_user", "from": "user_id", "to": nil},
+	})
+	model := NewModel("main", testTables(), foreignKeys, nil)
+	if len(model.Relations) != 1 || model.Relations[0].ToColumns[0] != "id" {
+		t.Fatalf("relations %+v", model.Relations)
+	}
+}
+
+func TestRender(t *testing.T) {
+	model := NewModel("db", testTables(), nil, &Options{Infer: true})
+
+	text, err := Render(model, FormatDot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(text, `digraph "db" {`) || !strings.Contains(text, `tm_order:c1 -> tm_user:c0 [label="user_id", arrowhead=tee, arrowtail=crow, dir=both, style=dashed];`) || !strings.Contains(text, "名称 &quot;昵称&quot;") {
+		t.Fatalf("dot:\n%s", text)
+	}
+
+	text, _ = Render(model, FormatMermaid)
+	if !strings.Contains(text, "        decimal amount\n") || !strings.Contains(text, `        varchar name "名称 '昵称'"`) || !strings.Contains(text, `    tm_user ||..o{ tm_order : "user_id"`) {
+		t.Fatalf("mermaid:\n%s", text)
+	}
+
+	text, _ = Render(model, FormatPlantUML)
+	if !strings.Contains(text, `entity "tm_user\n用户" as tm_user {`) || !strings.Contains(text, "  * id : bigint <<PK>>\n  --\n") || !strings.Contains(text, "tm_user ||..o{ tm_order : user_id") {
+		t.Fatalf("plantuml:\n%s", text)
+	}
+
+	if _, err = Render(model, "svg"); err == nil {
+		t.Fatal("svg should not support")
+	}
+}
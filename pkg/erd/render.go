@@ -0,0 +1,249 @@
+package erd
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	FormatDot      = "dot"
+	FormatMermaid  = "mermaid"
+	FormatPlantUML = "plantuml"
+)
+
+// Render 导出 为 指定 格式 文本
+func Render(model *Model, format string) (text string, err error) {
+	switch strings.ToLower(format) {
+	case FormatDot, "graphviz":
+		text = RenderDot(model)
+	case FormatMermaid:
+		text = RenderMermaid(model)
+	case FormatPlantUML, "puml":
+		text = RenderPlantUML(model)
+	default:
+		err = errors.New("不支持的格式[" + format + "]，支持 dot、mermaid、plantuml")
+	}
+	return
+}
+
+var (
+	identifierRegexp = regexp.MustCompile(`[^A-Za-z0-9_]`)
+)
+
+// entityIds 实体 标识，名称 可能 包含 特殊字符，统一 转换，重复 时 追加 序号
+func entityIds(model *Model) map[string]string {
+	ids := map[string]string{}
+	used := map[string]bool{}
+	for _, one := range model.Entities {
+		id := identifierRegexp.ReplaceAllString(one.Name, "_")
+		if id == "" || (id[0] >= '0' && id[0] <= '9') {
+			id = "t_" + id
+		}
+		base := id
+		for i := 2; used[strings.ToLower(id)]; i++ {
+			id = base + "_" + strconv.Itoa(i)
+		}
+		used[strings.ToLower(id)] = true
+		ids[one.Name] = id
+	}
+	return ids
+}
+
+func attributeKeys(attribute *Attribute) (keys []string) {
+	if attribute.PrimaryKey {
+		keys = append(keys, "PK")
+	}
+	if attribute.ForeignKey {
+		keys = append(keys, "FK")
+	}
+	return
+}
+
+func relationLabel(relation *Relation) string {
+	if relation.Name != "" {
+		return relation.Name
+	}
+	return strings.Join(relation.FromColumns, ",")
+}
+
+// RenderDot Graphviz DOT，使用 HTML 表格 节点，关系 连接到 字段，推断的 关系 为 虚线
+func RenderDot(model *Model) string {
+	ids := entityIds(model)
+	var builder strings.Builder
+	builder.WriteString("digraph " + dotQuote(model.OwnerName) + " {\n")
+	builder.WriteString("  graph [rankdir=LR, fontname=\"Helvetica\"];\n")
+	builder.WriteString("  node [shape=plaintext, fontname=\"Helvetica\"];\n")
+	builder.WriteString("  edge [fontname=\"Helvetica\", fontsize=10];\n")
+
+	ports := map[string]string{}
+	for _, entity := range model.Entities {
+		id := ids[entity.Name]
+		builder.WriteString("  " + id + " [label=<<TABLE BORDER=\"0\" CELLBORDER=\"1\" CELLSPACING=\"0\" CELLPADDING=\"4\">")
+		title := "<B>" + htmlEscape(entity.Name) + "</B>"
+		if entity.Comment != "" {
+			title += "<BR/>" + htmlEscape(entity.Comment)
+		}
+		builder.WriteString("<TR><TD BGCOLOR=\"lightgrey\" COLSPAN=\"3\">" + title + "</TD></TR>")
+		for i, attribute := range entity.Attributes {
+			port := "c" + strconv.Itoa(i)
+			ports[columnKey(entity.Name, attribute.Name)] = port
+			name := htmlEscape(attribute.Name)
+			if attribute.PrimaryKey {
+				name = "<U>" + name + "</U>"
+			}
+			if keys := attributeKeys(attribute); len(keys) > 0 {
+				name += " " + strings.Join(keys, ",")
+			}
+			builder.WriteString("<TR><TD ALIGN=\"LEFT\" PORT=\"" + port + "\">" + name + "</TD><TD ALIGN=\"LEFT\">" + htmlEscape(attribute.Type) + "</TD><TD ALIGN=\"LEFT\">" + htmlEscape(attribute.Comment) + "</TD></TR>")
+		}
+		builder.WriteString("</TABLE>>];\n")
+	}
+
+	for _, relation := range model.Relations {
+		from := ids[relation.From]
+		to := ids[relation.To]
+		if port := ports[columnKey(relation.From, relation.FromColumns[0])]; port != "" {
+			from += ":" + port
+		}
+		if port := ports[columnKey(relation.To, relation.ToColumns[0])]; port != "" {
+			to += ":" + port
+		}
+		attrs := []string{"label=" + dotQuote(relationLabel(relation))}
+		if relation.Cardinality == CardinalityOneToOne {
+			attrs = append(attrs, "arrowhead=tee", "arrowtail=tee")
+		} else {
+			attrs = append(attrs, "arrowhead=tee", "arrowtail=crow")
+		}
+		attrs = append(attrs, "dir=both")
+		if relation.Inferred {
+			attrs = append(attrs, "style=dashed")
+		}
+		builder.WriteString("  " + from + " -> " + to + " [" + strings.Join(attrs, ", ") + "];\n")
+	}
+	builder.WriteString("}\n")
+	return builder.String()
+}
+
+// RenderMermaid Mermaid erDiagram，推断的 关系 为 虚线
+func RenderMermaid(model *Model) string {
+	ids := entityIds(model)
+	var builder strings.Builder
+	builder.WriteString("erDiagram\n")
+	for _, entity := range model.Entities {
+		builder.WriteString("    " + ids[entity.Name] + " {\n")
+		for _, attribute := range entity.Attributes {
+			// 类型 不支持 逗号、空格
+			dataType := identifierRegexp.ReplaceAllString(strings.SplitN(attribute.Type, "(", 2)[0], "_")
+			if dataType == "" {
+				dataType = "unknown"
+			}
+			line := "        " + dataType + " " + identifierRegexp.ReplaceAllString(attribute.Name, "_")
+			if keys := attributeKeys(attribute); len(keys) > 0 {
+				line += " " + strings.Join(keys, ",")
+			}
+			if attribute.Comment != "" {
+				line += " " + mermaidQuote(attribute.Comment)
+			}
+			builder.WriteString(line + "\n")
+		}
+		builder.WriteString("    }\n")
+	}
+	for _, relation := range model.Relations {
+		line := "||--o{"
+		if relation.Cardinality == CardinalityOneToOne {
+			line = "||--o|"
+		}
+		if relation.Inferred {
+			line = strings.Replace(line, "--", "..", 1)
+		}
+		builder.WriteString("    " + ids[relation.To] + " " + line + " " + ids[relation.From] + " : " + mermaidQuote(relationLabel(relation)) + "\n")
+	}
+	return builder.String()
+}
+
+// RenderPlantUML PlantUML 实体图，主键 在 分隔线 上方
+func RenderPlantUML(model *Model) string {
+	ids := entityIds(model)
+	var builder strings.Builder
+	builder.WriteString("@startuml\n")
+	builder.WriteString("hide circle\n")
+	builder.WriteString("skinparam linetype ortho\n\n")
+	for _, entity := range model.Entities {
+		title := entity.Name
+		if entity.Comment != "" {
+			title += "\\n" + entity.Comment
+		}
+		builder.WriteString("entity " + plantUMLQuote(title) + " as " + ids[entity.Name] + " {\n")
+		var keys, others []*Attribute
+		for _, attribute := range entity.Attributes {
+			if attribute.PrimaryKey {
+				keys = append(keys, attribute)
+			} else {
+				others = append(others, attribute)
+			}
+		}
+		for _, attribute := range keys {
+			builder.WriteString("  * " + plantUMLAttribute(attribute) + "\n")
+		}
+		builder.WriteString("  --\n")
+		for _, attribute := range others {
+			prefix := "  "
+			if attribute.NotNull {
+				prefix = "  * "
+			}
+			builder.WriteString(prefix + plantUMLAttribute(attribute) + "\n")
+		}
+		builder.WriteString("}\n\n")
+	}
+	for _, relation := range model.Relations {
+		line := "||--o{"
+		if relation.Cardinality == CardinalityOneToOne {
+			line = "||--o|"
+		}
+		if relation.Inferred {
+			line = strings.Replace(line, "--", "..", 1)
+		}
+		builder.WriteString(ids[relation.To] + " " + line + " " + ids[relation.From] + " : " + strings.ReplaceAll(relationLabel(relation), "\n", " ") + "\n")
+	}
+	builder.WriteString("@enduml\n")
+	return builder.String()
+}
+
+func plantUMLAttribute(attribute *Attribute) string {
+	text := attribute.Name + " : " + attribute.Type
+	for _, key := range attributeKeys(attribute) {
+		text += " <<" + key + ">>"
+	}
+	if attribute.Comment != "" {
+		text += " // " + strings.ReplaceAll(attribute.Comment, "\n", " ")
+	}
+	return text
+}
+
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, "\\", "\\\\")
+	s = strings.ReplaceAll(s, "\"", "\\\"")
+	s = strings.ReplaceAll(s, "\n", " ")
+	return "\"" + s + "\""
+}
+
+func mermaidQuote(s string) string {
+	s = strings.ReplaceAll(s, "\"", "'")
+	s = strings.ReplaceAll(s, "\n", " ")
+	return "\"" + s + "\""
+}
+
+func plantUMLQuote(s string) string {
+	return "\"" + strings.ReplaceAll(strings.ReplaceAll(s, "\"", "'"), "\n", " ") + "\""
+}
+
+func htmlEscape(s string) string {
+	s = strings.ReplaceAll(s, "&", "&amp;")
+	s = strings.ReplaceAll(s, "<", "&lt;")
+	s = strings.ReplaceAll(s, ">", "&gt;")
+	s = strings.ReplaceAll(s, "\"", "&quot;")
+	s = strings.ReplaceAll(s, "\n", " ")
+	return s
+}
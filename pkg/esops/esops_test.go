@@ -0,0 +1,184 @@
+package esops
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSql(t *testing.T) {
+	if _, err := SqlBody(&SqlQuery{}); err == nil {
+		t.Fatal("empty query should error")
+	}
+	body, err := SqlBody(&SqlQuery{Query: "select * from logs", FetchSize: 100000, TimeZone: "Asia/Shanghai"})
+	if err != nil || body["fetch_size"] != maxFetchSize || body["time_zone"] != "Asia/Shanghai" {
+		t.Fatal(body, err)
+	}
+	body, _ = SqlBody(&SqlQuery{Query: "select 1", Cursor: "abc"})
+	if len(body) != 1 || body["cursor"] != "abc" {
+		t.Fatal(body)
+	}
+
+	res, err := ParseSqlResult([]byte(`{"columns":[{"name":"id","type":"long"},{"name":"msg","type":"text"}],"rows":[[9007199254740993,"a"],[2,null]],"cursor":"next"}`))
+	if err != nil || res.Cursor != "next" || len(res.Columns) != 2 {
+		t.Fatal(res, err)
+	}
+	records := res.Records(res.Columns)
+	if records[0]["id"].(json.Number).String() != "9007199254740993" || records[1]["msg"] != nil {
+		t.Fatal(records)
+	}
+
+	// 下一页 不 返回 列
+	res, err = ParseSqlResult([]byte(`{"rows":[]}`))
+	if err != nil || res.Cursor != "" || res.Rows == nil || len(res.Columns) != 0 {
+		t.Fatal(res, err)
+	}
+}
+
+func TestHealth(t *testing.T) {
+	health, err := ParseHealth([]byte(`{"cluster_name":"es","status":"red","number_of_nodes":2,"unassigned_shards":3,"active_shards_percent_as_number":62.5,
+		"indices":{"b":{"status":"yellow","unassigned_shards":1},"c":{"status":"red","unassigned_shards":2},"a":{"status":"green"}}}`))
+	if err != nil || health.Status != "red" || health.ActiveShardsPercent != 62.5 {
+		t.Fatal(health, err)
+	}
+	indices := health.ProblemIndices()
+	if len(indices) != 2 || indices[0].Name != "c" || indices[1].Name != "b" {
+		t.Fatalf("indices %+v", indices)
+	}
+
+	shards, err := ParseProblemShards([]byte(`[
+		{"index":"b","shard":"10","prirep":"r","state":"UNASSIGNED","unassigned.reason":"NODE_LEFT"},
+		{"index":"b","shard":"2","prirep":"r","state":"UNASSIGNED"},
+		{"index":"b","shard":"2","prirep":"p","state":"INITIALIZING","node":"n1"},
+		{"index":"a","shard":"0","prirep":"p","state":"STARTED","node":"n1"}]`))
+	if err != nil || len(shards) != 3 || !shards[0].IsPrimary() || shards[2].Shard != "10" {
+		t.Fatalf("shards %+v %v", shards, err)
+	}
+	body := AllocationExplainBody(shards[2])
+	if body["shard"] != 10 || body["primary"] != false || body["index"] != "b" {
+		t.Fatal(body)
+	}
+
+	explain, err := ParseAllocationExplain([]byte(`{"index":"b","shard":10,"primary":false,"current_state":"unassigned",
+		"unassigned_info":{"reason":"NODE_LEFT","details":"node_left [n2]"},"can_allocate":"no","allocate_explanation":"cannot allocate",
+		"node_allocation_decisions":[{"node_name":"n1","node_decision":"no","deciders":[
+			{"decider":"same_shard","decision":"NO","explanation":"a copy is already allocated"},
+			{"decider":"disk_threshold","decision":"YES","explanation":"enough disk"}]}]}`))
+	if err != nil || explain.UnassignedReason != "NODE_LEFT" || explain.CanAllocate != "no" || len(explain.NodeDecisions) != 1 {
+		t.Fatal(explain, err)
+	}
+	if reasons := explain.NodeDecisions[0].Reasons; len(reasons) != 1 || reasons[0] != "same_shard: a copy is already allocated" {
+		t.Fatal(reasons)
+	}
+}
+
+func TestIlm(t *testing.T) {
+	list, err := ParseIlmExplain([]byte(`{"indices":{
+		"logs-1":{"index":"logs-1","managed":true,"policy":"logs","phase":"hot","action":"rollover","step":"check-rollover-ready","age":"1d"},
+		"logs-2":{"index":"logs-2","managed":true,"policy":"logs","phase":"hot","action":"rollover","step":"ERROR","failed_step":"check-rollover-ready",
+			"step_info":{"type":"illegal_argument_exception","reason":"rollover_alias is empty"}},
+		"other":{"index":"other","managed":false}}}`))
+	if err != nil || len(list) != 3 {
+		t.Fatal(list, err)
+	}
+	if list[0].Index != "logs-2" || list[0].StepError != "illegal_argument_exception: rollover_alias is empty" || list[2].Managed {
+		t.Fatalf("explain %+v", list)
+	}
+
+	if _, err = IlmAttachBody("", ""); err == nil {
+		t.Fatal("empty policy should error")
+	}
+	body, _ := IlmAttachBody("logs", "logs")
+	if body["index.lifecycle.name"] != "logs" || body["index.lifecycle.rollover_alias"] != "logs" {
+		t.Fatal(body)
+	}
+	if IndexPath("logs-*,app", "/_ilm/explain") != "/logs-%2A,app/_ilm/explain" {
+		t.Fatal(IndexPath("logs-*,app", "/_ilm/explain"))
+	}
+	if IlmPolicyNamePath("a b") != "/_ilm/policy/a%20b" {
+		t.Fatal(IlmPolicyNamePath("a b"))
+	}
+}
+
+func TestTemplate(t *testing.T) {
+	if p, err := TemplatePath(TemplateKindComponent, "base"); err != nil || p != "/_component_template/base" {
+		t.Fatal(p, err)
+	}
+	if p, _ := TemplatePath("", ""); p != "/_index_template" {
+		t.Fatal(p)
+	}
+	if _, err := TemplatePath("legacy", ""); err == nil {
+		t.Fatal("unknown kind should error")
+	}
+
+	list, err := ParseTemplates([]byte(`{"index_templates":[
+		{"name":"logs","index_template":{"index_patterns":["logs-*"],"composed_of":["base"],"priority":200,"data_stream":{}}},
+		{"name":"app","index_template":{"index_patterns":"app-*","version":3}}]}`))
+	if err != nil || len(list) != 2 || list[0].Name != "app" || list[0].IndexPatterns[0] != "app-*" || list[0].Version != 3 {
+		t.Fatalf("templates %+v %v", list, err)
+	}
+	if !list[1].DataStream || list[1].Priority != 200 || list[1].ComposedOf[0] != "base" || list[1].Kind != TemplateKindIndex {
+		t.Fatalf("templates %+v", list[1])
+	}
+
+	list, err = ParseTemplates([]byte(`{"component_templates":[{"name":"base","component_template":{"template":{"settings":{}},"version":1}}]}`))
+	if err != nil || len(list) != 1 || list[0].Kind != TemplateKindComponent || list[0].Version != 1 {
+		t.Fatalf("component %+v %v", list, err)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	if _, err := SnapshotRepositoryPath("", "s"); err == nil {
+		t.Fatal("empty repository should error")
+	}
+	if p, _ := SnapshotRepositoryPath("backup", "snap-1"); p != "/_snapshot/backup/snap-1" {
+		t.Fatal(p)
+	}
+
+	repositories, err := ParseRepositories([]byte(`{"s3":{"type":"s3","settings":{"bucket":"b"}},"fs":{"type":"fs","settings":{"location":"/data"}}}`))
+	if err != nil || len(repositories) != 2 || repositories[0].Name != "fs" || repositories[1].Settings["bucket"] != "b" {
+		t.Fatal(repositories, err)
+	}
+
+	snapshots, err := ParseSnapshots([]byte(`{"snapshots":[
+		{"snapshot":"s1","state":"SUCCESS","indices":["a"],"start_time_in_millis":100,"shards":{"total":1,"successful":1}},
+		{"snapshot":"s2","state":"IN_PROGRESS","indices":["a","b"],"start_time_in_millis":200}]}`))
+	if err != nil || len(snapshots) != 2 || snapshots[0].Snapshot != "s2" || snapshots[1].Shards.Total != 1 {
+		t.Fatal(snapshots, err)
+	}
+
+	progress, err := SnapshotProgress([]byte(`{"snapshots":[{"state":"STARTED","shards_stats":{"initializing":0,"started":2,"done":1,"failed":0,"total":3}}]}`))
+	if err != nil || progress.Done || progress.Finished != 1 || progress.Percent != 33.33 {
+		t.Fatal(progress, err)
+	}
+	progress, _ = SnapshotProgress([]byte(`{"snapshots":[{"state":"FAILED","shards_stats":{"done":1,"failed":2,"total":3}}]}`))
+	if !progress.Done || !progress.Failed || progress.Error == "" || progress.Percent != 100 {
+		t.Fatal(progress)
+	}
+	if _, err = SnapshotProgress([]byte(`{"snapshots":[]}`)); err == nil {
+		t.Fatal("missing snapshot should error")
+	}
+
+	recovery := []byte(`{
+		"a":{"shards":[
+			{"type":"SNAPSHOT","stage":"DONE","start_time_in_millis":1000,"source":{"repository":"backup","snapshot":"s1"}},
+			{"type":"SNAPSHOT","stage":"INDEX","start_time_in_millis":1000,"source":{"repository":"backup","snapshot":"s1"}}]},
+		"old":{"shards":[{"type":"SNAPSHOT","stage":"DONE","start_time_in_millis":10,"source":{"repository":"backup","snapshot":"s1"}}]},
+		"b":{"shards":[{"type":"PEER","stage":"INDEX","start_time_in_millis":1000,"source":{}}]}}`)
+	if last, err := LastRestoreTime(recovery, "backup", "s1"); err != nil || last != 1000 {
+		t.Fatal(last, err)
+	}
+	progress, err = RestoreProgress(recovery, "backup", "s1", 500)
+	if err != nil || progress.Total != 2 || progress.Finished != 1 || progress.Done || progress.Percent != 50 {
+		t.Fatal(progress, err)
+	}
+	if progress, _ = RestoreProgress(recovery, "backup", "s1", 1000); progress.Total != 0 {
+		t.Fatal(progress)
+	}
+	if progress, _ = RestoreProgress(recovery, "backup", "s2", 0); progress.Total != 0 {
+		t.Fatal(progress)
+	}
+	recovery = []byte(`{"a":{"shards":[{"type":"SNAPSHOT","stage":"DONE","start_time_in_millis":1000,"source":{"repository":"backup","snapshot":"s1"}}]}}`)
+	if progress, _ = RestoreProgress(recovery, "backup", "s1", 500); !progress.Done || progress.State != "SUCCESS" || progress.Percent != 100 {
+		t.Fatal(progress)
+	}
+}
@@ -0,0 +1,204 @@
+package esops
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+)
+
+const (
+	HealthPath            = "/_cluster/health"
+	CatShardsPath         = "/_cat/shards"
+	CatShardsColumns      = "index,shard,prirep,state,unassigned.reason,node"
+	AllocationExplainPath = "/_cluster/allocation/explain"
+)
+
+type IndexHealth struct {
+	Name                string `json:"name"`
+	Status              string `json:"status"`
+	NumberOfShards      int    `json:"number_of_shards"`
+	NumberOfReplicas    int    `json:"number_of_replicas"`
+	ActivePrimaryShards int    `json:"active_primary_shards"`
+	ActiveShards        int    `json:"active_shards"`
+	RelocatingShards    int    `json:"relocating_shards"`
+	InitializingShards  int    `json:"initializing_shards"`
+	UnassignedShards    int    `json:"unassigned_shards"`
+}
+
+// ClusterHealth _cluster/health?level=indices 的 结果
+type ClusterHealth struct {
+	ClusterName             string                  `json:"cluster_name"`
+	Status                  string                  `json:"status"`
+	TimedOut                bool                    `json:"timed_out"`
+	NumberOfNodes           int                     `json:"number_of_nodes"`
+	NumberOfDataNodes       int                     `json:"number_of_data_nodes"`
+	ActivePrimaryShards     int                     `json:"active_primary_shards"`
+	ActiveShards            int                     `json:"active_shards"`
+	RelocatingShards        int                     `json:"relocating_shards"`
+	InitializingShards      int                     `json:"initializing_shards"`
+	UnassignedShards        int                     `json:"unassigned_shards"`
+	DelayedUnassignedShards int                     `json:"delayed_unassigned_shards"`
+	NumberOfPendingTasks    int                     `json:"number_of_pending_tasks"`
+	ActiveShardsPercent     float64                 `json:"active_shards_percent_as_number"`
+	Indices                 map[string]*IndexHealth `json:"indices,omitempty"`
+}
+
+var statusOrder = map[string]int{"red": 0, "yellow": 1, "green": 2}
+
+// ProblemIndices 非 green 的 索引，red 在 前
+func (this_ *ClusterHealth) ProblemIndices() (list []*IndexHealth) {
+	for name, one := range this_.Indices {
+		if one.Status == "green" {
+			continue
+		}
+		one.Name = name
+		list = append(list, one)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if statusOrder[list[i].Status] != statusOrder[list[j].Status] {
+			return statusOrder[list[i].Status] < statusOrder[list[j].Status]
+		}
+		return list[i].Name < list[j].Name
+	})
+	return
+}
+
+func ParseHealth(bs []byte) (res *ClusterHealth, err error) {
+	res = &ClusterHealth{}
+	err = json.Unmarshal(bs, res)
+	return
+}
+
+// ShardInfo _cat/shards 的 一行
+type ShardInfo struct {
+	Index            string `json:"index"`
+	Shard            string `json:"shard"`
+	Prirep           string `json:"prirep"`
+	State            string `json:"state"`
+	UnassignedReason string `json:"unassigned.reason,omitempty"`
+	Node             string `json:"node,omitempty"`
+}
+
+func (this_ *ShardInfo) IsPrimary() bool {
+	return this_.Prirep == "p"
+}
+
+// ParseProblemShards 非 STARTED 的 分片，按 索引、分片 排序
+func ParseProblemShards(bs []byte) (list []*ShardInfo, err error) {
+	var all []*ShardInfo
+	if err = json.Unmarshal(bs, &all); err != nil {
+		return
+	}
+	for _, one := range all {
+		if one.State != "STARTED" {
+			list = append(list, one)
+		}
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].Index != list[j].Index {
+			return list[i].Index < list[j].Index
+		}
+		a, _ := strconv.Atoi(list[i].Shard)
+		b, _ := strconv.Atoi(list[j].Shard)
+		if a != b {
+			return a < b
+		}
+		return list[i].IsPrimary() && !list[j].IsPrimary()
+	})
+	return
+}
+
+// AllocationExplainBody 指定 分片 的 分配 说明 请求 体
+func AllocationExplainBody(shard *ShardInfo) map[string]interface{} {
+	number, _ := strconv.Atoi(shard.Shard)
+	return map[string]interface{}{
+		"index":   shard.Index,
+		"shard":   number,
+		"primary": shard.IsPrimary(),
+	}
+}
+
+type NodeDecision struct {
+	NodeName string   `json:"nodeName"`
+	Decision string   `json:"decision"`
+	Reasons  []string `json:"reasons,omitempty"` // 否决 的 decider 及 说明
+}
+
+// AllocationExplain 分配 说明 摘要
+type AllocationExplain struct {
+	Index               string          `json:"index"`
+	Shard               int             `json:"shard"`
+	Primary             bool            `json:"primary"`
+	CurrentState        string          `json:"currentState"`
+	CurrentNode         string          `json:"currentNode,omitempty"`
+	UnassignedReason    string          `json:"unassignedReason,omitempty"`
+	UnassignedDetails   string          `json:"unassignedDetails,omitempty"`
+	CanAllocate         string          `json:"canAllocate,omitempty"`
+	AllocateExplanation string          `json:"allocateExplanation,omitempty"`
+	CanRemain           string          `json:"canRemain,omitempty"`
+	CanRebalance        string          `json:"canRebalance,omitempty"`
+	NodeDecisions       []*NodeDecision `json:"nodeDecisions,omitempty"`
+}
+
+type explainDecider struct {
+	Decider     string `json:"decider"`
+	Decision    string `json:"decision"`
+	Explanation string `json:"explanation"`
+}
+
+type explainResponse struct {
+	Index          string `json:"index"`
+	Shard          int    `json:"shard"`
+	Primary        bool   `json:"primary"`
+	CurrentState   string `json:"current_state"`
+	UnassignedInfo *struct {
+		Reason  string `json:"reason"`
+		Details string `json:"details"`
+	} `json:"unassigned_info"`
+	CurrentNode *struct {
+		Name string `json:"name"`
+	} `json:"current_node"`
+	CanAllocate              string `json:"can_allocate"`
+	AllocateExplanation      string `json:"allocate_explanation"`
+	CanRemainOnCurrentNode   string `json:"can_remain_on_current_node"`
+	CanRebalanceClusterState string `json:"can_rebalance_cluster"`
+	NodeAllocationDecisions  []*struct {
+		NodeName     string            `json:"node_name"`
+		NodeDecision string            `json:"node_decision"`
+		Deciders     []*explainDecider `json:"deciders"`
+	} `json:"node_allocation_decisions"`
+}
+
+func ParseAllocationExplain(bs []byte) (res *AllocationExplain, err error) {
+	response := &explainResponse{}
+	if err = json.Unmarshal(bs, response); err != nil {
+		return
+	}
+	res = &AllocationExplain{
+		Index:               response.Index,
+		Shard:               response.Shard,
+		Primary:             response.Primary,
+		CurrentState:        response.CurrentState,
+		CanAllocate:         response.CanAllocate,
+		AllocateExplanation: response.AllocateExplanation,
+		CanRemain:           response.CanRemainOnCurrentNode,
+		CanRebalance:        response.CanRebalanceClusterState,
+	}
+	if response.UnassignedInfo != nil {
+		res.UnassignedReason = response.UnassignedInfo.Reason
+		res.UnassignedDetails = response.UnassignedInfo.Details
+	}
+	if response.CurrentNode != nil {
+		res.CurrentNode = response.CurrentNode.Name
+	}
+	for _, one := range response.NodeAllocationDecisions {
+		decision := &NodeDecision{NodeName: one.NodeName, Decision: one.NodeDecision}
+		for _, decider := range one.Deciders {
+			if decider.Decision == "NO" {
+				decision.Reasons = append(decision.Reasons, decider.Decider+": "+decider.Explanation)
+			}
+		}
+		res.NodeDecisions = append(res.NodeDecisions, decision)
+	}
+	return
+}
@@ -0,0 +1,106 @@
+package esops
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+const IlmPolicyPath = "/_ilm/policy"
+
+func IlmPolicyNamePath(name string) string {
+	return IlmPolicyPath + "/" + url.PathEscape(name)
+}
+
+// IndexPath 索引 相关 路径，indexName 可以 是 逗号 分隔 或 通配
+func IndexPath(indexName string, suffix string) string {
+	var names []string
+	for _, one := range strings.Split(indexName, ",") {
+		names = append(names, url.PathEscape(strings.TrimSpace(one)))
+	}
+	return "/" + strings.Join(names, ",") + suffix
+}
+
+// IlmIndexExplain 索引 当前 所处 的 生命周期 阶段
+type IlmIndexExplain struct {
+	Index         string `json:"index"`
+	Managed       bool   `json:"managed"`
+	Policy        string `json:"policy,omitempty"`
+	Phase         string `json:"phase,omitempty"`
+	Action        string `json:"action,omitempty"`
+	Step          string `json:"step,omitempty"`
+	Age           string `json:"age,omitempty"`
+	LifecycleDate int64  `json:"lifecycleDate,omitempty"`
+	PhaseTime     int64  `json:"phaseTime,omitempty"`
+	FailedStep    string `json:"failedStep,omitempty"`
+	StepError     string `json:"stepError,omitempty"`
+}
+
+type ilmExplainResponse struct {
+	Indices map[string]*struct {
+		Index               string `json:"index"`
+		Managed             bool   `json:"managed"`
+		Policy              string `json:"policy"`
+		LifecycleDateMillis int64  `json:"lifecycle_date_millis"`
+		Age                 string `json:"age"`
+		Phase               string `json:"phase"`
+		PhaseTimeMillis     int64  `json:"phase_time_millis"`
+		Action              string `json:"action"`
+		Step                string `json:"step"`
+		FailedStep          string `json:"failed_step"`
+		StepInfo            *struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		} `json:"step_info"`
+	} `json:"indices"`
+}
+
+// ParseIlmExplain 解析 <index>/_ilm/explain，执行 失败 的 索引 在 前
+func ParseIlmExplain(bs []byte) (list []*IlmIndexExplain, err error) {
+	response := &ilmExplainResponse{}
+	if err = json.Unmarshal(bs, response); err != nil {
+		return
+	}
+	for name, one := range response.Indices {
+		info := &IlmIndexExplain{
+			Index:         name,
+			Managed:       one.Managed,
+			Policy:        one.Policy,
+			Phase:         one.Phase,
+			Action:        one.Action,
+			Step:          one.Step,
+			Age:           one.Age,
+			LifecycleDate: one.LifecycleDateMillis,
+			PhaseTime:     one.PhaseTimeMillis,
+			FailedStep:    one.FailedStep,
+		}
+		if one.StepInfo != nil && one.StepInfo.Reason != "" {
+			info.StepError = one.StepInfo.Type + ": " + one.StepInfo.Reason
+		}
+		list = append(list, info)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if (list[i].Step == "ERROR") != (list[j].Step == "ERROR") {
+			return list[i].Step == "ERROR"
+		}
+		return list[i].Index < list[j].Index
+	})
+	return
+}
+
+// IlmAttachBody 为 索引 指定 策略 的 settings，rolloverAlias 为 空 时 不 设置
+func IlmAttachBody(policy string, rolloverAlias string) (body map[string]interface{}, err error) {
+	if policy == "" {
+		err = errors.New("生命周期策略不能为空")
+		return
+	}
+	body = map[string]interface{}{
+		"index.lifecycle.name": policy,
+	}
+	if rolloverAlias != "" {
+		body["index.lifecycle.rollover_alias"] = rolloverAlias
+	}
+	return
+}
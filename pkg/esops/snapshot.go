@@ -0,0 +1,202 @@
+package esops
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"sort"
+)
+
+const (
+	SnapshotPath = "/_snapshot"
+	RecoveryPath = "/_recovery"
+)
+
+// SnapshotRepositoryPath 仓库 路径，snapshot 不为 空 时 为 快照 路径
+func SnapshotRepositoryPath(repository string, snapshot string) (res string, err error) {
+	if repository == "" {
+		err = errors.New("快照仓库不能为空")
+		return
+	}
+	res = SnapshotPath + "/" + url.PathEscape(repository)
+	if snapshot != "" {
+		res += "/" + url.PathEscape(snapshot)
+	}
+	return
+}
+
+type Repository struct {
+	Name     string                 `json:"name"`
+	Type     string                 `json:"type"`
+	Settings map[string]interface{} `json:"settings"`
+}
+
+// ParseRepositories 解析 GET _snapshot
+func ParseRepositories(bs []byte) (list []*Repository, err error) {
+	response := map[string]*Repository{}
+	if err = json.Unmarshal(bs, &response); err != nil {
+		return
+	}
+	for name, one := range response {
+		one.Name = name
+		list = append(list, one)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return
+}
+
+type SnapshotInfo struct {
+	Snapshot           string   `json:"snapshot"`
+	Uuid               string   `json:"uuid"`
+	State              string   `json:"state"`
+	Indices            []string `json:"indices"`
+	IncludeGlobalState bool     `json:"include_global_state"`
+	StartTime          int64    `json:"start_time_in_millis"`
+	EndTime            int64    `json:"end_time_in_millis"`
+	DurationInMillis   int64    `json:"duration_in_millis"`
+	Reason             string   `json:"reason,omitempty"`
+	Shards             *struct {
+		Total      int `json:"total"`
+		Failed     int `json:"failed"`
+		Successful int `json:"successful"`
+	} `json:"shards,omitempty"`
+}
+
+// ParseSnapshots 解析 GET _snapshot/<repository>/_all，最近 的 在 前
+func ParseSnapshots(bs []byte) (list []*SnapshotInfo, err error) {
+	response := &struct {
+		Snapshots []*SnapshotInfo `json:"snapshots"`
+	}{}
+	if err = json.Unmarshal(bs, response); err != nil {
+		return
+	}
+	list = response.Snapshots
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].StartTime > list[j].StartTime
+	})
+	return
+}
+
+// Progress 快照 或 恢复 的 进度，按 分片 统计
+type Progress struct {
+	State    string  `json:"state"`
+	Done     bool    `json:"done"`
+	Failed   bool    `json:"failed"`
+	Total    int     `json:"total"`
+	Finished int     `json:"finished"`
+	Percent  float64 `json:"percent"`
+	Error    string  `json:"error,omitempty"`
+}
+
+func (this_ *Progress) count(total int, finished int) {
+	this_.Total = total
+	this_.Finished = finished
+	if total > 0 {
+		this_.Percent = float64(finished*10000/total) / 100
+	}
+}
+
+// SnapshotProgress 解析 GET _snapshot/<repository>/<snapshot>/_status
+func SnapshotProgress(bs []byte) (res *Progress, err error) {
+	response := &struct {
+		Snapshots []*struct {
+			State       string `json:"state"`
+			ShardsStats struct {
+				Done   int `json:"done"`
+				Failed int `json:"failed"`
+				Total  int `json:"total"`
+			} `json:"shards_stats"`
+		} `json:"snapshots"`
+	}{}
+	if err = json.Unmarshal(bs, response); err != nil {
+		return
+	}
+	if len(response.Snapshots) == 0 {
+		err = errors.New("快照不存在")
+		return
+	}
+	one := response.Snapshots[0]
+	res = &Progress{State: one.State}
+	res.count(one.ShardsStats.Total, one.ShardsStats.Done+one.ShardsStats.Failed)
+	switch one.State {
+	case "SUCCESS", "PARTIAL":
+		res.Done = true
+	case "FAILED", "ABORTED":
+		res.Done = true
+		res.Failed = true
+	}
+	if one.ShardsStats.Failed > 0 {
+		res.Error = "存在失败的分片"
+	}
+	return
+}
+
+type recoveryShard struct {
+	Type      string `json:"type"`
+	Stage     string `json:"stage"`
+	StartTime int64  `json:"start_time_in_millis"`
+	Source    struct {
+		Repository string `json:"repository"`
+		Snapshot   string `json:"snapshot"`
+	} `json:"source"`
+}
+
+// snapshotShards GET _recovery 中 从 指定 快照 恢复 的 分片
+func snapshotShards(bs []byte, repository string, snapshot string) (list []*recoveryShard, err error) {
+	response := map[string]*struct {
+		Shards []*recoveryShard `json:"shards"`
+	}{}
+	if err = json.Unmarshal(bs, &response); err != nil {
+		return
+	}
+	for _, index := range response {
+		for _, shard := range index.Shards {
+			if shard.Type == "SNAPSHOT" && shard.Source.Repository == repository && shard.Source.Snapshot == snapshot {
+				list = append(list, shard)
+			}
+		}
+	}
+	return
+}
+
+// LastRestoreTime 之前 从 该 快照 恢复 的 分片 的 最晚 开始 时间，恢复 前 记录 用于 排除 历史 记录
+func LastRestoreTime(bs []byte, repository string, snapshot string) (res int64, err error) {
+	list, err := snapshotShards(bs, repository, snapshot)
+	if err != nil {
+		return
+	}
+	for _, shard := range list {
+		if shard.StartTime > res {
+			res = shard.StartTime
+		}
+	}
+	return
+}
+
+// RestoreProgress 解析 GET _recovery，只 统计 在 since 之后 开始 的 分片，未 开始 恢复 的 分片 不 在 其中
+// 已 开始 的 分片 全部 完成 时 Done 为 true，调用 方 需要 确认 没有 新 的 分片 开始
+func RestoreProgress(bs []byte, repository string, snapshot string, since int64) (res *Progress, err error) {
+	list, err := snapshotShards(bs, repository, snapshot)
+	if err != nil {
+		return
+	}
+	var total, finished int
+	for _, shard := range list {
+		if shard.StartTime <= since {
+			continue
+		}
+		total++
+		if shard.Stage == "DONE" {
+			finished++
+		}
+	}
+	res = &Progress{State: "IN_PROGRESS"}
+	res.count(total, finished)
+	if total > 0 && finished == total {
+		res.State = "SUCCESS"
+		res.Done = true
+	}
+	return
+}
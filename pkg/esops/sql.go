@@ -0,0 +1,95 @@
+package esops
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+)
+
+const (
+	SqlPath          = "/_sql"
+	SqlClosePath     = "/_sql/close"
+	SqlTranslatePath = "/_sql/translate"
+
+	defaultFetchSize = 100
+	maxFetchSize     = 10000
+)
+
+// SqlQuery SQL 查询，Cursor 不为 空 时 查询 下一页
+type SqlQuery struct {
+	Query     string        `json:"query"`
+	Params    []interface{} `json:"params,omitempty"`
+	FetchSize int           `json:"fetchSize"`
+	TimeZone  string        `json:"timeZone,omitempty"`
+	Cursor    string        `json:"cursor,omitempty"`
+}
+
+type SqlColumn struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// SqlResult 只有 第一页 返回 Columns，Cursor 为 空 表示 没有 下一页
+type SqlResult struct {
+	Columns []*SqlColumn    `json:"columns,omitempty"`
+	Rows    [][]interface{} `json:"rows"`
+	Cursor  string          `json:"cursor,omitempty"`
+}
+
+// SqlBody 生成 _sql 请求 体
+func SqlBody(query *SqlQuery) (body map[string]interface{}, err error) {
+	if query.Cursor != "" {
+		body = map[string]interface{}{"cursor": query.Cursor}
+		return
+	}
+	if query.Query == "" {
+		err = errors.New("SQL不能为空")
+		return
+	}
+	fetchSize := query.FetchSize
+	if fetchSize <= 0 {
+		fetchSize = defaultFetchSize
+	}
+	if fetchSize > maxFetchSize {
+		fetchSize = maxFetchSize
+	}
+	body = map[string]interface{}{
+		"query":      query.Query,
+		"fetch_size": fetchSize,
+	}
+	if len(query.Params) > 0 {
+		body["params"] = query.Params
+	}
+	if query.TimeZone != "" {
+		body["time_zone"] = query.TimeZone
+	}
+	return
+}
+
+// ParseSqlResult 解析 format=json 的 结果，数字 保留 为 json.Number 避免 long 精度 丢失
+func ParseSqlResult(bs []byte) (res *SqlResult, err error) {
+	res = &SqlResult{}
+	decoder := json.NewDecoder(bytes.NewReader(bs))
+	decoder.UseNumber()
+	if err = decoder.Decode(res); err != nil {
+		return
+	}
+	if res.Rows == nil {
+		res.Rows = [][]interface{}{}
+	}
+	return
+}
+
+// Records 按 列 名 转换 为 记录
+func (this_ *SqlResult) Records(columns []*SqlColumn) (records []map[string]interface{}) {
+	for _, row := range this_.Rows {
+		record := map[string]interface{}{}
+		for index, value := range row {
+			if index < len(columns) {
+				record[columns[index].Name] = value
+			}
+		}
+		records = append(records, record)
+	}
+	return
+}
@@ -0,0 +1,100 @@
+package esops
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"sort"
+)
+
+const (
+	TemplateKindIndex     = "index"
+	TemplateKindComponent = "component"
+)
+
+// TemplatePath 可组合 索引 模板 与 组件 模板 的 路径，name 为 空 时 返回 列表 路径
+func TemplatePath(kind string, name string) (res string, err error) {
+	switch kind {
+	case TemplateKindIndex, "":
+		res = "/_index_template"
+	case TemplateKindComponent:
+		res = "/_component_template"
+	default:
+		err = errors.New("不支持的模板类型:" + kind)
+		return
+	}
+	if name != "" {
+		res += "/" + url.PathEscape(name)
+	}
+	return
+}
+
+// TemplateInfo 模板 摘要，Template 为 原始 定义 用于 编辑
+type TemplateInfo struct {
+	Name          string                 `json:"name"`
+	Kind          string                 `json:"kind"`
+	IndexPatterns []string               `json:"indexPatterns,omitempty"`
+	ComposedOf    []string               `json:"composedOf,omitempty"`
+	Priority      int64                  `json:"priority,omitempty"`
+	Version       int64                  `json:"version,omitempty"`
+	DataStream    bool                   `json:"dataStream,omitempty"`
+	Template      map[string]interface{} `json:"template"`
+}
+
+type templateResponse struct {
+	IndexTemplates []*struct {
+		Name          string                 `json:"name"`
+		IndexTemplate map[string]interface{} `json:"index_template"`
+	} `json:"index_templates"`
+	ComponentTemplates []*struct {
+		Name              string                 `json:"name"`
+		ComponentTemplate map[string]interface{} `json:"component_template"`
+	} `json:"component_templates"`
+}
+
+// ParseTemplates 解析 GET _index_template 或 _component_template 的 结果
+func ParseTemplates(bs []byte) (list []*TemplateInfo, err error) {
+	response := &templateResponse{}
+	if err = json.Unmarshal(bs, response); err != nil {
+		return
+	}
+	for _, one := range response.IndexTemplates {
+		info := &TemplateInfo{Name: one.Name, Kind: TemplateKindIndex, Template: one.IndexTemplate}
+		info.IndexPatterns = toStrings(one.IndexTemplate["index_patterns"])
+		info.ComposedOf = toStrings(one.IndexTemplate["composed_of"])
+		info.Priority = toInt64(one.IndexTemplate["priority"])
+		info.Version = toInt64(one.IndexTemplate["version"])
+		_, info.DataStream = one.IndexTemplate["data_stream"]
+		list = append(list, info)
+	}
+	for _, one := range response.ComponentTemplates {
+		info := &TemplateInfo{Name: one.Name, Kind: TemplateKindComponent, Template: one.ComponentTemplate}
+		info.Version = toInt64(one.ComponentTemplate["version"])
+		list = append(list, info)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return
+}
+
+func toStrings(value interface{}) (res []string) {
+	switch v := value.(type) {
+	case string:
+		res = append(res, v)
+	case []interface{}:
+		for _, one := range v {
+			if s, ok := one.(string); ok {
+				res = append(res, s)
+			}
+		}
+	}
+	return
+}
+
+func toInt64(value interface{}) int64 {
+	if v, ok := value.(float64); ok {
+		return int64(v)
+	}
+	return 0
+}
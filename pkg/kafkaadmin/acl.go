@@ -0,0 +1,121 @@
+package kafkaadmin
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	// AclResourceTypes 资源 类型
+	AclResourceTypes = []string{"topic", "group", "cluster", "transactionalId", "delegationToken"}
+	// AclPatternTypes 资源 匹配 方式
+	AclPatternTypes = []string{"literal", "prefixed"}
+	// AclOperations 操作
+	AclOperations = []string{"all", "read", "write", "create", "delete", "alter", "describe", "clusterAction", "describeConfigs", "alterConfigs", "idempotentWrite"}
+	// AclPermissions 权限
+	AclPermissions = []string{"allow", "deny"}
+)
+
+// AclBinding 一条 ACL，字段 取值 见 AclResourceTypes 等，比较 时 不 区分 大小写
+type AclBinding struct {
+	ResourceType string `json:"resourceType"`
+	ResourceName string `json:"resourceName"`
+	PatternType  string `json:"patternType,omitempty"` // 默认 literal
+	Principal    string `json:"principal"`             // 如 User:alice
+	Host         string `json:"host,omitempty"`        // 默认 *
+	Operation    string `json:"operation"`
+	Permission   string `json:"permission,omitempty"` // 默认 allow
+}
+
+// AclPlan ACL 变更 预览，已 存在 的 新增、不 存在 的 删除 放入 Skipped
+type AclPlan struct {
+	Create  []*AclBinding `json:"create"`
+	Delete  []*AclBinding `json:"delete"`
+	Skipped []*AclBinding `json:"skipped"`
+}
+
+func checkEnum(name string, value string, values []string) (res string, err error) {
+	for _, one := range values {
+		if strings.EqualFold(one, value) {
+			res = one
+			return
+		}
+	}
+	err = errors.New(name + "[" + value + "]不支持，支持：" + strings.Join(values, "、"))
+	return
+}
+
+// Normalize 补全 默认值 并 校验
+func (this_ *AclBinding) Normalize() (err error) {
+	if this_.PatternType == "" {
+		this_.PatternType = "literal"
+	}
+	if this_.Host == "" {
+		this_.Host = "*"
+	}
+	if this_.Permission == "" {
+		this_.Permission = "allow"
+	}
+	if this_.ResourceType, err = checkEnum("资源类型", this_.ResourceType, AclResourceTypes); err != nil {
+		return
+	}
+	if this_.PatternType, err = checkEnum("匹配方式", this_.PatternType, AclPatternTypes); err != nil {
+		return
+	}
+	if this_.Operation, err = checkEnum("操作", this_.Operation, AclOperations); err != nil {
+		return
+	}
+	if this_.Permission, err = checkEnum("权限", this_.Permission, AclPermissions); err != nil {
+		return
+	}
+	if this_.ResourceName == "" {
+		err = errors.New("资源名称不能为空")
+		return
+	}
+	if !strings.Contains(this_.Principal, ":") {
+		err = errors.New("Principal[" + this_.Principal + "]格式为 User:name")
+		return
+	}
+	return
+}
+
+// Key 唯一 标识
+func (this_ *AclBinding) Key() string {
+	return strings.ToLower(strings.Join([]string{this_.ResourceType, this_.PatternType, this_.Operation, this_.Permission}, "|")) +
+		"|" + this_.ResourceName + "|" + this_.Principal + "|" + this_.Host
+}
+
+// PlanAcls 对比 现有 ACL，计算 实际 需要 新增、删除 的
+func PlanAcls(existing []*AclBinding, create []*AclBinding, remove []*AclBinding) (plan *AclPlan, err error) {
+	plan = &AclPlan{}
+	exists := map[string]bool{}
+	for _, one := range existing {
+		exists[one.Key()] = true
+	}
+	planned := map[string]bool{}
+	for _, one := range create {
+		if err = one.Normalize(); err != nil {
+			return
+		}
+		key := one.Key()
+		if exists[key] || planned[key] {
+			plan.Skipped = append(plan.Skipped, one)
+			continue
+		}
+		planned[key] = true
+		plan.Create = append(plan.Create, one)
+	}
+	for _, one := range remove {
+		if err = one.Normalize(); err != nil {
+			return
+		}
+		key := one.Key()
+		if !exists[key] || planned[key] {
+			plan.Skipped = append(plan.Skipped, one)
+			continue
+		}
+		planned[key] = true
+		plan.Delete = append(plan.Delete, one)
+	}
+	return
+}
@@ -0,0 +1,85 @@
+package kafkaadmin
+
+import (
+	"testing"
+)
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func TestDiffConfig(t *testing.T) {
+	current := []*ConfigEntry{
+		{Name: "retention.ms", Value: "86400000", Source: SourceDynamicTopic},
+		{Name: "cleanup.policy", Value: "delete", IsDefault: true, Source: "default"},
+		{Name: "min.insync.replicas", Value: "2", Source: SourceDynamicTopic},
+		{Name: "segment.bytes", Value: "1073741824", Source: SourceDynamicTopic},
+		{Name: "message.format.version", Value: "3.0", ReadOnly: true, Source: "static"},
+	}
+	items, alter, err := DiffConfig(current, map[string]*string{
+		"retention.ms":        strPtr("604800000"),
+		"cleanup.policy":      strPtr("compact"),
+		"min.insync.replicas": nil,
+		"segment.bytes":       strPtr("1073741824"),
+		"max.message.bytes":   nil,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 3 {
+		t.Fatalf("items %d", len(items))
+	}
+	expected := map[string]string{"cleanup.policy": DiffAdd, "min.insync.replicas": DiffDelete, "retention.ms": DiffUpdate}
+	for _, item := range items {
+		if expected[item.Name] != item.Action {
+			t.Fatalf("item %+v", item)
+		}
+	}
+	if len(alter) != 3 || *alter["retention.ms"] != "604800000" || *alter["cleanup.policy"] != "compact" {
+		t.Fatalf("alter %v", alter)
+	}
+	if value, ok := alter["min.insync.replicas"]; !ok || value != nil {
+		t.Fatalf("alter delete %v", alter)
+	}
+	if _, _, err = DiffConfig(current, map[string]*string{"message.format.version": strPtr("2.8")}); err == nil {
+		t.Fatal("read only should error")
+	}
+	// 增量 修改，未 指定 的 敏感 覆盖 配置 不受 影响
+	sensitive := append(current, &ConfigEntry{Name: "secret", Sensitive: true, Source: SourceDynamicTopic})
+	items, alter, err = DiffConfig(sensitive, map[string]*string{"retention.ms": nil})
+	if err != nil || len(alter) != 1 || items[0].Action != DiffDelete {
+		t.Fatalf("untouched sensitive items %+v alter %v err %v", items, alter, err)
+	}
+	items, alter, err = DiffConfig(sensitive, map[string]*string{"secret": strPtr("x")})
+	if err != nil || items[0].NewValue != maskedValue || items[0].Action != DiffUpdate || *alter["secret"] != "x" {
+		t.Fatalf("sensitive items %+v err %v", items, err)
+	}
+}
+
+func TestPlanAcls(t *testing.T) {
+	existing := []*AclBinding{
+		{ResourceType: "topic", ResourceName: "orders", PatternType: "literal", Principal: "User:alice", Host: "*", Operation: "read", Permission: "allow"},
+	}
+	plan, err := PlanAcls(existing, []*AclBinding{
+		{ResourceType: "TOPIC", ResourceName: "orders", Principal: "User:alice", Operation: "Read"},
+		{ResourceType: "group", ResourceName: "app-", PatternType: "prefixed", Principal: "User:alice", Operation: "read"},
+	}, []*AclBinding{
+		{ResourceType: "topic", ResourceName: "orders", Principal: "User:alice", Operation: "read"},
+		{ResourceType: "topic", ResourceName: "orders", Principal: "User:bob", Operation: "read"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Create) != 1 || plan.Create[0].ResourceType != "group" || len(plan.Delete) != 1 || len(plan.Skipped) != 2 {
+		t.Fatalf("plan create %d delete %d skipped %d", len(plan.Create), len(plan.Delete), len(plan.Skipped))
+	}
+	for _, one := range []*AclBinding{
+		{ResourceType: "queue", ResourceName: "a", Principal: "User:a", Operation: "read"},
+		{ResourceType: "topic", ResourceName: "a", Principal: "alice", Operation: "read"},
+		{ResourceType: "topic", ResourceName: "a", Principal: "User:a", Operation: "fly"},
+	} {
+		if _, err = PlanAcls(nil, []*AclBinding{one}, nil); err == nil {
+			t.Fatalf("acl %+v should error", one)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package kafkaadmin
+
+import (
+	"errors"
+	"sort"
+)
+
+const (
+	// DiffAdd 新增 覆盖 配置
+	DiffAdd = "add"
+	// DiffUpdate 修改 覆盖 配置
+	DiffUpdate = "update"
+	// DiffDelete 删除 覆盖 配置，恢复 默认
+	DiffDelete = "delete"
+
+	// SourceDynamicTopic Topic 级 动态 配置，即 覆盖 配置
+	SourceDynamicTopic = "topic"
+
+	maskedValue = "******"
+)
+
+// ConfigEntry 配置 项，Source 为 topic、broker、cluster、static、default 等
+type ConfigEntry struct {
+	Name      string `json:"name"`
+	Value     string `json:"value"`
+	IsDefault bool   `json:"isDefault"`
+	ReadOnly  bool   `json:"readOnly"`
+	Sensitive bool   `json:"sensitive"`
+	Source    string `json:"source,omitempty"`
+}
+
+// ConfigDiffItem 变更 预览，敏感 配置 的 值 不 展示
+type ConfigDiffItem struct {
+	Name     string `json:"name"`
+	Action   string `json:"action"`
+	OldValue string `json:"oldValue,omitempty"`
+	NewValue string `json:"newValue,omitempty"`
+}
+
+// DiffConfig 计算 变更，changes 中 值 为 nil 表示 恢复 默认；
+// alter 为 需要 增量 修改 的 配置，值 为 nil 表示 删除 覆盖，未 变更 的 覆盖 配置 不 在 其中
+func DiffConfig(current []*ConfigEntry, changes map[string]*string) (items []*ConfigDiffItem, alter map[string]*string, err error) {
+	currentMap := map[string]*ConfigEntry{}
+	alter = map[string]*string{}
+	for _, one := range current {
+		currentMap[one.Name] = one
+	}
+	names := make([]string, 0, len(changes))
+	for name := range changes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := changes[name]
+		find := currentMap[name]
+		if find != nil && find.ReadOnly {
+			err = errors.New("配置[" + name + "]只读，不能修改")
+			return
+		}
+		isOverride := find != nil && find.Source == SourceDynamicTopic && !find.IsDefault
+		item := &ConfigDiffItem{Name: name}
+		if isOverride {
+			item.OldValue = find.Value
+		}
+		switch {
+		case value == nil && !isOverride:
+			continue
+		case value == nil:
+			item.Action = DiffDelete
+		case isOverride && !find.Sensitive && find.Value == *value:
+			continue
+		case isOverride:
+			item.Action = DiffUpdate
+			item.NewValue = *value
+		default:
+			item.Action = DiffAdd
+			item.NewValue = *value
+			if find != nil {
+				item.OldValue = find.Value
+			}
+		}
+		alter[name] = value
+		if find != nil && find.Sensitive {
+			item.OldValue, item.NewValue = maskedValue, maskedValue
+		}
+		items = append(items, item)
+	}
+	return
+}
@@ -0,0 +1,216 @@
+package kafkalag
+
+import (
+	"errors"
+	"sort"
+	"sync"
+)
+
+const (
+	// ResetToEarliest 重置 到 最早
+	ResetToEarliest = "to-earliest"
+	// ResetToLatest 重置 到 最新
+	ResetToLatest = "to-latest"
+	// ResetToTimestamp 重置 到 时间戳 对应 位置
+	ResetToTimestamp = "to-datetime"
+	// ResetToOffset 重置 到 指定 位置
+	ResetToOffset = "to-offset"
+	// ResetShiftBy 在 当前 提交 位置 上 偏移
+	ResetShiftBy = "shift-by"
+)
+
+// PartitionOffset 分区 位置，Committed 为 -1 表示 没有 提交 过
+type PartitionOffset struct {
+	Partition int32 `json:"partition"`
+	LogStart  int64 `json:"logStart"`
+	LogEnd    int64 `json:"logEnd"`
+	Committed int64 `json:"committed"`
+}
+
+// PartitionLag 分区 积压，没有 提交 位置 时 Lag 为 -1，不 计入 合计
+type PartitionLag struct {
+	PartitionOffset
+	Lag int64 `json:"lag"`
+}
+
+// GroupLag 消费组 在 Topic 上 的 积压
+type GroupLag struct {
+	GroupId    string          `json:"groupId"`
+	State      string          `json:"state,omitempty"`
+	Topic      string          `json:"topic"`
+	TotalLag   int64           `json:"totalLag"`
+	Committed  int64           `json:"committed"` // 已 提交 位置 合计，用于 计算 消费 速率
+	Partitions []*PartitionLag `json:"partitions"`
+}
+
+// ComputeLag 计算 积压，提交 位置 落后 于 最早 位置 时 按 最早 位置 计算
+func ComputeLag(groupId string, topic string, offsets []*PartitionOffset) (res *GroupLag) {
+	res = &GroupLag{GroupId: groupId, Topic: topic}
+	sorted := append([]*PartitionOffset{}, offsets...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Partition < sorted[j].Partition })
+	for _, one := range sorted {
+		lag := &PartitionLag{PartitionOffset: *one, Lag: -1}
+		if one.Committed >= 0 {
+			from := one.Committed
+			if from < one.LogStart {
+				from = one.LogStart
+			}
+			lag.Lag = one.LogEnd - from
+			if lag.Lag < 0 {
+				lag.Lag = 0
+			}
+			res.TotalLag += lag.Lag
+			res.Committed += one.Committed
+		}
+		res.Partitions = append(res.Partitions, lag)
+	}
+	return
+}
+
+// Sample 一次 采样，LogEnd 为 Topic 所有 分区 最新 位置 合计
+type Sample struct {
+	Time   int64                `json:"time"` // 毫秒
+	LogEnd int64                `json:"logEnd"`
+	Groups map[string]*GroupLag `json:"groups"`
+}
+
+// Trend 采样 区间 的 速率，条/秒
+type Trend struct {
+	GroupId     string  `json:"groupId"`
+	TotalLag    int64   `json:"totalLag"`
+	LagChange   int64   `json:"lagChange"`   // 区间 内 积压 变化，正数 为 增加
+	ConsumeRate float64 `json:"consumeRate"` // 提交 位置 增长 速率
+	ProduceRate float64 `json:"produceRate"` // 最新 位置 增长 速率
+}
+
+// Sampler 保留 最近 若干 次 采样
+type Sampler struct {
+	max     int
+	samples []*Sample
+	lock    sync.Mutex
+}
+
+// NewSampler 创建，max 小于 2 时 为 2
+func NewSampler(max int) *Sampler {
+	if max < 2 {
+		max = 2
+	}
+	return &Sampler{max: max}
+}
+
+func (this_ *Sampler) Add(sample *Sample) {
+	this_.lock.Lock()
+	defer this_.lock.Unlock()
+	this_.samples = append(this_.samples, sample)
+	if len(this_.samples) > this_.max {
+		this_.samples = append([]*Sample{}, this_.samples[len(this_.samples)-this_.max:]...)
+	}
+}
+
+func (this_ *Sampler) Samples() (res []*Sample) {
+	this_.lock.Lock()
+	defer this_.lock.Unlock()
+	res = append(res, this_.samples...)
+	return
+}
+
+// Trends 按 第一次 与 最后一次 采样 计算 每个 组 的 速率，组 需 在 两次 采样 中 都 存在
+func (this_ *Sampler) Trends() (res []*Trend) {
+	samples := this_.Samples()
+	if len(samples) == 0 {
+		return
+	}
+	first, last := samples[0], samples[len(samples)-1]
+	seconds := float64(last.Time-first.Time) / 1000
+	for groupId, lastLag := range last.Groups {
+		trend := &Trend{GroupId: groupId, TotalLag: lastLag.TotalLag}
+		if firstLag := first.Groups[groupId]; firstLag != nil && seconds > 0 {
+			trend.LagChange = lastLag.TotalLag - firstLag.TotalLag
+			trend.ConsumeRate = float64(lastLag.Committed-firstLag.Committed) / seconds
+			trend.ProduceRate = float64(last.LogEnd-first.LogEnd) / seconds
+		}
+		res = append(res, trend)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].GroupId < res[j].GroupId })
+	return
+}
+
+// ResetOptions 重置 方式，与 kafka-consumer-groups 的 --reset-offsets 一致
+type ResetOptions struct {
+	Strategy  string `json:"strategy"`
+	Timestamp int64  `json:"timestamp,omitempty"` // to-datetime 毫秒
+	Offset    int64  `json:"offset,omitempty"`    // to-offset
+	Shift     int64  `json:"shift,omitempty"`     // shift-by，可 为 负数
+}
+
+// PlanItem 分区 重置 预览
+type PlanItem struct {
+	Partition int32 `json:"partition"`
+	Current   int64 `json:"current"` // -1 表示 没有 提交 过
+	Target    int64 `json:"target"`
+	Change    int64 `json:"change"` // 没有 提交 位置 时 相对 最早 位置
+	LagAfter  int64 `json:"lagAfter"`
+}
+
+func (this_ *ResetOptions) Check() (err error) {
+	switch this_.Strategy {
+	case ResetToEarliest, ResetToLatest, ResetShiftBy:
+	case ResetToTimestamp:
+		if this_.Timestamp <= 0 {
+			err = errors.New("to-datetime 需要 时间戳")
+		}
+	case ResetToOffset:
+		if this_.Offset < 0 {
+			err = errors.New("to-offset 需要 非负 位置")
+		}
+	default:
+		err = errors.New("不支持的重置方式[" + this_.Strategy + "]，支持 to-earliest、to-latest、to-datetime、to-offset、shift-by")
+	}
+	return
+}
+
+// PlanReset 计算 重置 后 的 位置，结果 限制 在 [LogStart, LogEnd]；timestampOffsets 为 to-datetime 时 各 分区 按 时间 查询 的 位置，-1 表示 没有 该 时间 之后 的 消息
+func PlanReset(options *ResetOptions, offsets []*PartitionOffset, timestampOffsets map[int32]int64) (plan []*PlanItem, err error) {
+	if err = options.Check(); err != nil {
+		return
+	}
+	sorted := append([]*PartitionOffset{}, offsets...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Partition < sorted[j].Partition })
+	for _, one := range sorted {
+		item := &PlanItem{Partition: one.Partition, Current: one.Committed}
+		switch options.Strategy {
+		case ResetToEarliest:
+			item.Target = one.LogStart
+		case ResetToLatest:
+			item.Target = one.LogEnd
+		case ResetToTimestamp:
+			find, ok := timestampOffsets[one.Partition]
+			if !ok || find < 0 {
+				find = one.LogEnd
+			}
+			item.Target = find
+		case ResetToOffset:
+			item.Target = options.Offset
+		case ResetShiftBy:
+			from := one.Committed
+			if from < 0 {
+				from = one.LogStart
+			}
+			item.Target = from + options.Shift
+		}
+		if item.Target < one.LogStart {
+			item.Target = one.LogStart
+		}
+		if item.Target > one.LogEnd {
+			item.Target = one.LogEnd
+		}
+		base := one.Committed
+		if base < 0 {
+			base = one.LogStart
+		}
+		item.Change = item.Target - base
+		item.LagAfter = one.LogEnd - item.Target
+		plan = append(plan, item)
+	}
+	return
+}
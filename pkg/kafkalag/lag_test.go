@@ -0,0 +1,81 @@
+package kafkalag
+
+import (
+	"testing"
+)
+
+func testOffsets() []*PartitionOffset {
+	return []*PartitionOffset{
+		{Partition: 1, LogStart: 10, LogEnd: 50, Committed: 5},
+		{Partition: 0, LogStart: 0, LogEnd: 100, Committed: 60},
+		{Partition: 2, LogStart: 0, LogEnd: 30, Committed: -1},
+	}
+}
+
+func TestComputeLag(t *testing.T) {
+	res := ComputeLag("g1", "orders", testOffsets())
+	if res.TotalLag != 80 || res.Committed != 65 {
+		t.Fatalf("total lag %d committed %d", res.TotalLag, res.Committed)
+	}
+	if res.Partitions[0].Partition != 0 || res.Partitions[1].Lag != 40 || res.Partitions[2].Lag != -1 {
+		t.Fatalf("partitions %+v %+v %+v", res.Partitions[0], res.Partitions[1], res.Partitions[2])
+	}
+}
+
+func TestSamplerTrends(t *testing.T) {
+	sampler := NewSampler(3)
+	for i := int64(0); i < 4; i++ {
+		sampler.Add(&Sample{
+			Time:   i * 2000,
+			LogEnd: 100 + i*20,
+			Groups: map[string]*GroupLag{"g1": {GroupId: "g1", TotalLag: 50 + i*10, Committed: 50 + i*10}},
+		})
+	}
+	if len(sampler.Samples()) != 3 {
+		t.Fatalf("samples %d", len(sampler.Samples()))
+	}
+	trends := sampler.Trends()
+	if len(trends) != 1 {
+		t.Fatalf("trends %d", len(trends))
+	}
+	// 保留 第 1~3 次，4 秒 内 提交 增加 20，最新 位置 增加 40
+	trend := trends[0]
+	if trend.LagChange != 20 || trend.ConsumeRate != 5 || trend.ProduceRate != 10 || trend.TotalLag != 80 {
+		t.Fatalf("trend %+v", trend)
+	}
+}
+
+func TestPlanReset(t *testing.T) {
+	tests := []struct {
+		options *ResetOptions
+		targets []int64
+	}{
+		{options: &ResetOptions{Strategy: ResetToEarliest}, targets: []int64{0, 10, 0}},
+		{options: &ResetOptions{Strategy: ResetToLatest}, targets: []int64{100, 50, 30}},
+		{options: &ResetOptions{Strategy: ResetToOffset, Offset: 40}, targets: []int64{40, 40, 30}},
+		{options: &ResetOptions{Strategy: ResetShiftBy, Shift: -20}, targets: []int64{40, 10, 0}},
+		{options: &ResetOptions{Strategy: ResetShiftBy, Shift: 45}, targets: []int64{100, 50, 30}},
+		{options: &ResetOptions{Strategy: ResetToTimestamp, Timestamp: 1}, targets: []int64{70, 50, 30}},
+	}
+	timestampOffsets := map[int32]int64{0: 70, 1: -1}
+	for _, one := range tests {
+		plan, err := PlanReset(one.options, testOffsets(), timestampOffsets)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, item := range plan {
+			if item.Target != one.targets[i] {
+				t.Fatalf("strategy %s partition %d target %d expected %d", one.options.Strategy, item.Partition, item.Target, one.targets[i])
+			}
+		}
+	}
+	plan, _ := PlanReset(&ResetOptions{Strategy: ResetShiftBy, Shift: -20}, testOffsets(), nil)
+	if plan[0].Change != -20 || plan[0].LagAfter != 60 || plan[1].Change != 5 || plan[2].Current != -1 {
+		t.Fatalf("plan %+v %+v %+v", plan[0], plan[1], plan[2])
+	}
+	for _, options := range []*ResetOptions{{Strategy: "none"}, {Strategy: ResetToTimestamp}, {Strategy: ResetToOffset, Offset: -1}} {
+		if _, err := PlanReset(options, testOffsets(), nil); err == nil {
+			t.Fatalf("options %+v should error", options)
+		}
+	}
+}
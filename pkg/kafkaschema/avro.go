@@ -0,0 +1,592 @@
+package kafkaschema
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// AvroSchema 解析 后 的 Avro 结构，逻辑 类型 按 基础 类型 处理
+type AvroSchema struct {
+	Type      string        `json:"type"`
+	Name      string        `json:"name,omitempty"` // 命名 类型 的 全名
+	Fields    []*AvroField  `json:"fields,omitempty"`
+	Symbols   []string      `json:"symbols,omitempty"`
+	Items     *AvroSchema   `json:"items,omitempty"`
+	Values    *AvroSchema   `json:"values,omitempty"`
+	Size      int           `json:"size,omitempty"`
+	Union     []*AvroSchema `json:"union,omitempty"`
+	reference string
+}
+
+type AvroField struct {
+	Name       string      `json:"name"`
+	Type       *AvroSchema `json:"type"`
+	Default    interface{} `json:"default,omitempty"`
+	HasDefault bool        `json:"hasDefault,omitempty"`
+}
+
+type avroParser struct {
+	named map[string]*AvroSchema
+}
+
+// ParseAvroSchema 解析 Avro 结构 JSON
+func ParseAvroSchema(schema string) (res *AvroSchema, err error) {
+	var data interface{}
+	decoder := json.NewDecoder(strings.NewReader(schema))
+	decoder.UseNumber()
+	if err = decoder.Decode(&data); err != nil {
+		err = errors.New("Avro结构解析失败:" + err.Error())
+		return
+	}
+	parser := &avroParser{named: map[string]*AvroSchema{}}
+	res, err = parser.parse(data, "")
+	if err != nil {
+		return
+	}
+	err = parser.resolve(res, map[*AvroSchema]bool{})
+	return
+}
+
+func fullName(name string, namespace string) string {
+	if strings.Contains(name, ".") || namespace == "" {
+		return name
+	}
+	return namespace + "." + name
+}
+
+func (this_ *avroParser) parse(data interface{}, namespace string) (res *AvroSchema, err error) {
+	switch v := data.(type) {
+	case string:
+		switch v {
+		case "null", "boolean", "int", "long", "float", "double", "bytes", "string":
+			res = &AvroSchema{Type: v}
+		default:
+			// 短 名称 先 按 当前 命名空间 查找
+			res = &AvroSchema{reference: v}
+			if !strings.Contains(v, ".") && namespace != "" {
+				res.reference = namespace + "." + v + "|" + v
+			}
+		}
+	case []interface{}:
+		res = &AvroSchema{Type: "union"}
+		for _, one := range v {
+			var branch *AvroSchema
+			if branch, err = this_.parse(one, namespace); err != nil {
+				return
+			}
+			res.Union = append(res.Union, branch)
+		}
+	case map[string]interface{}:
+		typeName, _ := v["type"].(string)
+		if typeName == "" {
+			if _, ok := v["type"].(map[string]interface{}); ok {
+				return this_.parse(v["type"], namespace)
+			}
+			if _, ok := v["type"].([]interface{}); ok {
+				return this_.parse(v["type"], namespace)
+			}
+			err = errors.New("Avro结构缺少type")
+			return
+		}
+		switch typeName {
+		case "record", "error", "enum", "fixed":
+			name, _ := v["name"].(string)
+			if name == "" {
+				err = errors.New("Avro命名类型缺少name")
+				return
+			}
+			if ns, ok := v["namespace"].(string); ok && ns != "" {
+				namespace = ns
+			}
+			res = &AvroSchema{Type: typeName, Name: fullName(name, namespace)}
+			if typeName == "error" {
+				res.Type = "record"
+			}
+			if strings.Contains(name, ".") {
+				namespace = name[:strings.LastIndex(name, ".")]
+			}
+			this_.named[res.Name] = res
+			switch res.Type {
+			case "record":
+				fields, _ := v["fields"].([]interface{})
+				for _, one := range fields {
+					fieldData, _ := one.(map[string]interface{})
+					fieldName, _ := fieldData["name"].(string)
+					if fieldName == "" {
+						err = errors.New("Avro记录[" + res.Name + "]字段缺少name")
+						return
+					}
+					field := &AvroField{Name: fieldName}
+					if field.Type, err = this_.parse(fieldData["type"], namespace); err != nil {
+						return
+					}
+					field.Default, field.HasDefault = fieldData["default"]
+					res.Fields = append(res.Fields, field)
+				}
+			case "enum":
+				symbols, _ := v["symbols"].([]interface{})
+				for _, one := range symbols {
+					res.Symbols = append(res.Symbols, fmt.Sprint(one))
+				}
+			case "fixed":
+				size, _ := v["size"].(json.Number)
+				var n int64
+				if n, err = size.Int64(); err != nil || n < 0 {
+					err = errors.New("Avro fixed[" + res.Name + "]size错误")
+					return
+				}
+				res.Size = int(n)
+			}
+		case "array":
+			res = &AvroSchema{Type: typeName}
+			res.Items, err = this_.parse(v["items"], namespace)
+		case "map":
+			res = &AvroSchema{Type: typeName}
+			res.Values, err = this_.parse(v["values"], namespace)
+		default:
+			res, err = this_.parse(typeName, namespace)
+		}
+	default:
+		err = fmt.Errorf("Avro结构不支持[%v]", data)
+	}
+	return
+}
+
+// resolve 将 名称 引用 替换 为 命名 类型，引用 先 按 命名空间 再 按 短 名称 查找
+func (this_ *avroParser) resolve(schema *AvroSchema, visited map[*AvroSchema]bool) (err error) {
+	if schema == nil || visited[schema] {
+		return
+	}
+	visited[schema] = true
+	resolveOne := func(one **AvroSchema) error {
+		if (*one).reference == "" {
+			return this_.resolve(*one, visited)
+		}
+		for _, name := range strings.Split((*one).reference, "|") {
+			if find := this_.named[name]; find != nil {
+				*one = find
+				return nil
+			}
+		}
+		return errors.New("Avro类型[" + (*one).reference + "]未定义")
+	}
+	for _, field := range schema.Fields {
+		if err = resolveOne(&field.Type); err != nil {
+			return
+		}
+	}
+	for i := range schema.Union {
+		if err = resolveOne(&schema.Union[i]); err != nil {
+			return
+		}
+	}
+	if schema.Items != nil {
+		err = resolveOne(&schema.Items)
+	}
+	if err == nil && schema.Values != nil {
+		err = resolveOne(&schema.Values)
+	}
+	return
+}
+
+// DecodeAvro 解码 Avro 二进制，bytes、fixed 按 Avro JSON 规则 每 字节 转 一个 字符，union 直接 返回 分支 值
+func DecodeAvro(schema *AvroSchema, data []byte) (value interface{}, err error) {
+	reader := bytes.NewReader(data)
+	value, err = decodeAvro(schema, reader)
+	if err == nil && reader.Len() > 0 {
+		err = fmt.Errorf("Avro解码后剩余 %d 字节", reader.Len())
+	}
+	return
+}
+
+func readLong(reader *bytes.Reader) (n int64, err error) {
+	u, err := binary.ReadUvarint(reader)
+	if err != nil {
+		return
+	}
+	n = int64(u>>1) ^ -int64(u&1)
+	return
+}
+
+func readBytes(reader *bytes.Reader) (bs []byte, err error) {
+	size, err := readLong(reader)
+	if err != nil {
+		return
+	}
+	if size < 0 || size > int64(reader.Len()) {
+		err = io.ErrUnexpectedEOF
+		return
+	}
+	bs = make([]byte, size)
+	_, err = io.ReadFull(reader, bs)
+	return
+}
+
+func bytesToAvroString(bs []byte) string {
+	runes := make([]rune, len(bs))
+	for i, b := range bs {
+		runes[i] = rune(b)
+	}
+	return string(runes)
+}
+
+func decodeAvro(schema *AvroSchema, reader *bytes.Reader) (value interface{}, err error) {
+	switch schema.Type {
+	case "null":
+		return nil, nil
+	case "boolean":
+		var b byte
+		b, err = reader.ReadByte()
+		value = b != 0
+	case "int", "long":
+		value, err = readLong(reader)
+	case "float":
+		bs := make([]byte, 4)
+		if _, err = io.ReadFull(reader, bs); err == nil {
+			value = float64(math.Float32frombits(binary.LittleEndian.Uint32(bs)))
+		}
+	case "double":
+		bs := make([]byte, 8)
+		if _, err = io.ReadFull(reader, bs); err == nil {
+			value = math.Float64frombits(binary.LittleEndian.Uint64(bs))
+		}
+	case "bytes":
+		var bs []byte
+		if bs, err = readBytes(reader); err == nil {
+			value = bytesToAvroString(bs)
+		}
+	case "string":
+		var bs []byte
+		if bs, err = readBytes(reader); err == nil {
+			value = string(bs)
+		}
+	case "fixed":
+		bs := make([]byte, schema.Size)
+		if _, err = io.ReadFull(reader, bs); err == nil {
+			value = bytesToAvroString(bs)
+		}
+	case "enum":
+		var index int64
+		if index, err = readLong(reader); err == nil {
+			if index < 0 || index >= int64(len(schema.Symbols)) {
+				err = fmt.Errorf("Avro枚举[%s]下标[%d]越界", schema.Name, index)
+				return
+			}
+			value = schema.Symbols[index]
+		}
+	case "union":
+		var index int64
+		if index, err = readLong(reader); err == nil {
+			if index < 0 || index >= int64(len(schema.Union)) {
+				err = fmt.Errorf("Avro union下标[%d]越界", index)
+				return
+			}
+			value, err = decodeAvro(schema.Union[index], reader)
+		}
+	case "record":
+		data := map[string]interface{}{}
+		for _, field := range schema.Fields {
+			if data[field.Name], err = decodeAvro(field.Type, reader); err != nil {
+				err = errors.New(schema.Name + "." + field.Name + ":" + err.Error())
+				return
+			}
+		}
+		value = data
+	case "array":
+		list := []interface{}{}
+		err = readBlocks(reader, func() error {
+			one, e := decodeAvro(schema.Items, reader)
+			list = append(list, one)
+			return e
+		})
+		value = list
+	case "map":
+		data := map[string]interface{}{}
+		err = readBlocks(reader, func() error {
+			key, e := readBytes(reader)
+			if e != nil {
+				return e
+			}
+			data[string(key)], e = decodeAvro(schema.Values, reader)
+			return e
+		})
+		value = data
+	default:
+		err = errors.New("Avro类型[" + schema.Type + "]不支持")
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return
+}
+
+// readBlocks 数组、Map 按 块 读取，块 数量 为 负 时 后 跟 块 字节 数
+func readBlocks(reader *bytes.Reader, readItem func() error) (err error) {
+	for {
+		var count int64
+		if count, err = readLong(reader); err != nil {
+			return
+		}
+		if count == 0 {
+			return
+		}
+		if count < 0 {
+			count = -count
+			if _, err = readLong(reader); err != nil {
+				return
+			}
+		}
+		for i := int64(0); i < count; i++ {
+			if err = readItem(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// EncodeAvro 按 结构 编码 JSON 值，union 使用 第一个 能 编码 的 分支，也 支持 {"类型":值} 形式 指定 分支
+func EncodeAvro(schema *AvroSchema, value interface{}) (data []byte, err error) {
+	buf := &bytes.Buffer{}
+	err = encodeAvro(schema, value, buf)
+	data = buf.Bytes()
+	return
+}
+
+func writeLong(buf *bytes.Buffer, n int64) {
+	bs := make([]byte, binary.MaxVarintLen64)
+	size := binary.PutUvarint(bs, uint64((n<<1)^(n>>63)))
+	buf.Write(bs[:size])
+}
+
+func toInt64(value interface{}) (n int64, ok bool) {
+	switch v := value.(type) {
+	case json.Number:
+		var e error
+		if n, e = v.Int64(); e == nil {
+			return n, true
+		}
+		f, e := v.Float64()
+		if e == nil && f == math.Trunc(f) {
+			return int64(f), true
+		}
+	case float64:
+		if v == math.Trunc(v) {
+			return int64(v), true
+		}
+	case int:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	}
+	return 0, false
+}
+
+func toFloat64(value interface{}) (f float64, ok bool) {
+	switch v := value.(type) {
+	case json.Number:
+		var e error
+		f, e = v.Float64()
+		return f, e == nil
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	}
+	if n, isInt := toInt64(value); isInt {
+		return float64(n), true
+	}
+	return 0, false
+}
+
+func avroStringToBytes(s string) (bs []byte, err error) {
+	for _, r := range s {
+		if r > 0xff {
+			err = errors.New("bytes 值 只能 包含 \\u0000-\\u00ff 字符")
+			return
+		}
+		bs = append(bs, byte(r))
+	}
+	return
+}
+
+func typeMismatch(schema *AvroSchema, value interface{}) error {
+	name := schema.Type
+	if schema.Name != "" {
+		name = schema.Name
+	}
+	return fmt.Errorf("值[%v]不是Avro类型[%s]", value, name)
+}
+
+func encodeAvro(schema *AvroSchema, value interface{}, buf *bytes.Buffer) (err error) {
+	switch schema.Type {
+	case "null":
+		if value != nil {
+			return typeMismatch(schema, value)
+		}
+	case "boolean":
+		b, ok := value.(bool)
+		if !ok {
+			return typeMismatch(schema, value)
+		}
+		if b {
+			buf.WriteByte(1)
+		} else {
+			buf.WriteByte(0)
+		}
+	case "int", "long":
+		n, ok := toInt64(value)
+		if !ok || (schema.Type == "int" && (n > math.MaxInt32 || n < math.MinInt32)) {
+			return typeMismatch(schema, value)
+		}
+		writeLong(buf, n)
+	case "float":
+		f, ok := toFloat64(value)
+		if !ok {
+			return typeMismatch(schema, value)
+		}
+		bs := make([]byte, 4)
+		binary.LittleEndian.PutUint32(bs, math.Float32bits(float32(f)))
+		buf.Write(bs)
+	case "double":
+		f, ok := toFloat64(value)
+		if !ok {
+			return typeMismatch(schema, value)
+		}
+		bs := make([]byte, 8)
+		binary.LittleEndian.PutUint64(bs, math.Float64bits(f))
+		buf.Write(bs)
+	case "string", "bytes", "fixed":
+		s, ok := value.(string)
+		if !ok {
+			return typeMismatch(schema, value)
+		}
+		bs := []byte(s)
+		if schema.Type != "string" {
+			if bs, err = avroStringToBytes(s); err != nil {
+				return
+			}
+		}
+		if schema.Type == "fixed" {
+			if len(bs) != schema.Size {
+				return fmt.Errorf("Avro fixed[%s]需要 %d 字节", schema.Name, schema.Size)
+			}
+		} else {
+			writeLong(buf, int64(len(bs)))
+		}
+		buf.Write(bs)
+	case "enum":
+		s, ok := value.(string)
+		if !ok {
+			return typeMismatch(schema, value)
+		}
+		for i, one := range schema.Symbols {
+			if one == s {
+				writeLong(buf, int64(i))
+				return
+			}
+		}
+		return typeMismatch(schema, value)
+	case "union":
+		return encodeUnion(schema, value, buf)
+	case "record":
+		data, ok := value.(map[string]interface{})
+		if !ok {
+			return typeMismatch(schema, value)
+		}
+		for _, field := range schema.Fields {
+			one, find := data[field.Name]
+			if !find {
+				if !field.HasDefault {
+					return errors.New(schema.Name + "." + field.Name + ":缺少字段值")
+				}
+				one = field.Default
+				if field.Type.Type == "union" && len(field.Type.Union) > 0 {
+					// 默认值 对应 union 第一个 分支
+					if err = encodeDefaultUnion(field.Type, one, buf); err != nil {
+						return errors.New(schema.Name + "." + field.Name + ":" + err.Error())
+					}
+					continue
+				}
+			}
+			if err = encodeAvro(field.Type, one, buf); err != nil {
+				return errors.New(schema.Name + "." + field.Name + ":" + err.Error())
+			}
+		}
+	case "array":
+		list, ok := value.([]interface{})
+		if !ok {
+			return typeMismatch(schema, value)
+		}
+		if len(list) > 0 {
+			writeLong(buf, int64(len(list)))
+			for _, one := range list {
+				if err = encodeAvro(schema.Items, one, buf); err != nil {
+					return
+				}
+			}
+		}
+		buf.WriteByte(0)
+	case "map":
+		data, ok := value.(map[string]interface{})
+		if !ok {
+			return typeMismatch(schema, value)
+		}
+		if len(data) > 0 {
+			writeLong(buf, int64(len(data)))
+			for _, key := range sortedKeys(data) {
+				writeLong(buf, int64(len(key)))
+				buf.WriteString(key)
+				if err = encodeAvro(schema.Values, data[key], buf); err != nil {
+					return
+				}
+			}
+		}
+		buf.WriteByte(0)
+	default:
+		return errors.New("Avro类型[" + schema.Type + "]不支持")
+	}
+	return
+}
+
+func encodeDefaultUnion(schema *AvroSchema, value interface{}, buf *bytes.Buffer) (err error) {
+	writeLong(buf, 0)
+	return encodeAvro(schema.Union[0], value, buf)
+}
+
+func branchName(schema *AvroSchema) string {
+	if schema.Name != "" {
+		return schema.Name
+	}
+	return schema.Type
+}
+
+func encodeUnion(schema *AvroSchema, value interface{}, buf *bytes.Buffer) (err error) {
+	// {"类型":值} 指定 分支
+	if data, ok := value.(map[string]interface{}); ok && len(data) == 1 {
+		for key, one := range data {
+			for i, branch := range schema.Union {
+				if branchName(branch) == key || (branch.Name != "" && strings.HasSuffix(branch.Name, "."+key)) {
+					writeLong(buf, int64(i))
+					return encodeAvro(branch, one, buf)
+				}
+			}
+		}
+	}
+	for i, branch := range schema.Union {
+		one := &bytes.Buffer{}
+		if encodeAvro(branch, value, one) == nil {
+			writeLong(buf, int64(i))
+			buf.Write(one.Bytes())
+			return
+		}
+	}
+	return typeMismatch(schema, value)
+}
@@ -0,0 +1,89 @@
+package kafkaschema
+
+import (
+	"bytes"
+	"testing"
+)
+
+const testAvroSchema = `{
+  "type": "record", "name": "Order", "namespace": "shop",
+  "fields": [
+    {"name": "id", "type": "long"},
+    {"name": "status", "type": {"type": "enum", "name": "Status", "symbols": ["NEW", "PAID"]}},
+    {"name": "amount", "type": "double"},
+    {"name": "note", "type": ["null", "string"], "default": null},
+    {"name": "tags", "type": {"type": "array", "items": "string"}},
+    {"name": "attrs", "type": {"type": "map", "values": "int"}},
+    {"name": "hash", "type": {"type": "fixed", "name": "Hash", "size": 2}},
+    {"name": "parent", "type": ["null", "Order"], "default": null},
+    {"name": "createdAt", "type": {"type": "long", "logicalType": "timestamp-millis"}}
+  ]
+}`
+
+func TestAvroRoundTrip(t *testing.T) {
+	schema, err := ParseAvroSchema(testAvroSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	local := &Schema{SchemaType: SchemaTypeAvro, avro: schema}
+	value := `{"id":-3,"status":"PAID","amount":12.5,"tags":["a","b"],"attrs":{"x":1},"hash":"\u0001ÿ","parent":{"id":1,"status":"NEW","amount":0,"note":"p","tags":[],"attrs":{},"hash":"ab","createdAt":1},"createdAt":1700000000000}`
+	bs, err := local.Encode(value, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// id=-3 zigzag 为 5
+	if bs[0] != 5 {
+		t.Fatalf("encode id byte %d", bs[0])
+	}
+	res, err := local.Decode(bs, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"amount":12.5,"attrs":{"x":1},"createdAt":1700000000000,"hash":"\u0001ÿ","id":-3,"note":null,"parent":{"amount":0,"attrs":{},"createdAt":1,"hash":"ab","id":1,"note":"p","parent":null,"status":"NEW","tags":[]},"status":"PAID","tags":["a","b"]}`
+	if res != expected {
+		t.Fatalf("decode %s", res)
+	}
+}
+
+func TestAvroUnionBranch(t *testing.T) {
+	schema, err := ParseAvroSchema(`["null", "int", "string"]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		value interface{}
+		bs    []byte
+	}{
+		{value: nil, bs: []byte{0}},
+		{value: int64(1), bs: []byte{2, 2}},
+		{value: "a", bs: []byte{4, 2, 'a'}},
+		{value: map[string]interface{}{"string": "a"}, bs: []byte{4, 2, 'a'}},
+	}
+	for _, one := range tests {
+		bs, err := EncodeAvro(schema, one.value)
+		if err != nil || !bytes.Equal(bs, one.bs) {
+			t.Fatalf("encode %v bytes %v err %v", one.value, bs, err)
+		}
+	}
+	if _, err = EncodeAvro(schema, true); err == nil {
+		t.Fatal("boolean should not match union")
+	}
+}
+
+func TestAvroErrors(t *testing.T) {
+	for _, one := range []string{`{"type":"record","name":"A","fields":[{"name":"b","type":"Missing"}]}`, `{"type":"fixed","name":"F"}`, `not json`} {
+		if _, err := ParseAvroSchema(one); err == nil {
+			t.Fatalf("schema %s should error", one)
+		}
+	}
+	schema, _ := ParseAvroSchema(`{"type":"record","name":"A","fields":[{"name":"b","type":"int"}]}`)
+	if _, err := EncodeAvro(schema, map[string]interface{}{}); err == nil {
+		t.Fatal("missing field should error")
+	}
+	if _, err := DecodeAvro(schema, []byte{}); err == nil {
+		t.Fatal("short data should error")
+	}
+	if _, err := DecodeAvro(schema, []byte{2, 2}); err == nil {
+		t.Fatal("trailing data should error")
+	}
+}
@@ -0,0 +1,439 @@
+package kafkaschema
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ProtoFile 解析 后 的 .proto 文件，只 保留 编解码 需要 的 消息、枚举
+type ProtoFile struct {
+	Package  string
+	Messages []*ProtoMessage // 顶层 消息，按 定义 顺序，用于 Confluent 消息 下标
+	types    map[string]interface{}
+}
+
+type ProtoMessage struct {
+	Name     string // 全名，不 含 前缀 .
+	Fields   []*ProtoField
+	Messages []*ProtoMessage // 嵌套 消息，按 定义 顺序
+	IsMap    bool
+	byNumber map[int]*ProtoField
+}
+
+type ProtoField struct {
+	Name     string
+	Number   int
+	Type     string // 标量 类型 或 消息、枚举 名称
+	Repeated bool
+	Message  *ProtoMessage
+	Enum     *ProtoEnum
+	scope    string
+}
+
+type ProtoEnum struct {
+	Name     string
+	Values   map[string]int32
+	byNumber map[int32]string
+}
+
+var protoScalarTypes = map[string]bool{
+	"double": true, "float": true, "int32": true, "int64": true, "uint32": true, "uint64": true,
+	"sint32": true, "sint64": true, "fixed32": true, "fixed64": true, "sfixed32": true, "sfixed64": true,
+	"bool": true, "string": true, "bytes": true,
+}
+
+type protoParser struct {
+	tokens []string
+	pos    int
+	file   *ProtoFile
+	fields []*ProtoField
+}
+
+// ParseProto 解析 .proto 内容，忽略 import、option、service、extensions 等 与 编解码 无关 的 定义
+func ParseProto(content string) (file *ProtoFile, err error) {
+	parser := &protoParser{
+		tokens: tokenizeProto(content),
+		file:   &ProtoFile{types: map[string]interface{}{}},
+	}
+	if err = parser.parseFile(); err != nil {
+		return
+	}
+	if err = parser.resolve(); err != nil {
+		return
+	}
+	file = parser.file
+	return
+}
+
+func tokenizeProto(content string) (tokens []string) {
+	runes := []rune(content)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '/':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			i += 2
+			for i+1 < len(runes) && !(runes[i] == '*' && runes[i+1] == '/') {
+				i++
+			}
+			i += 2
+		case r == '"' || r == '\'':
+			start := i
+			i++
+			for i < len(runes) && runes[i] != r {
+				if runes[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			i++
+			if i > len(runes) {
+				i = len(runes)
+			}
+			tokens = append(tokens, string(runes[start:i]))
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '.' || r == '-' || r == '+':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_' || runes[i] == '.' || runes[i] == '-' || runes[i] == '+') {
+				i++
+			}
+			tokens = append(tokens, string(runes[start:i]))
+		default:
+			tokens = append(tokens, string(r))
+			i++
+		}
+	}
+	return
+}
+
+func (this_ *protoParser) peek() string {
+	if this_.pos < len(this_.tokens) {
+		return this_.tokens[this_.pos]
+	}
+	return ""
+}
+
+func (this_ *protoParser) next() string {
+	token := this_.peek()
+	this_.pos++
+	return token
+}
+
+func (this_ *protoParser) expect(token string) (err error) {
+	if find := this_.next(); find != token {
+		err = fmt.Errorf("proto解析失败:期望[%s]实际[%s]", token, find)
+	}
+	return
+}
+
+// skipStatement 跳过 到 ; 或 完整 的 {} 块
+func (this_ *protoParser) skipStatement() (err error) {
+	depth := 0
+	for this_.pos < len(this_.tokens) {
+		token := this_.next()
+		switch token {
+		case "{":
+			depth++
+		case "}":
+			depth--
+			if depth == 0 {
+				return
+			}
+		case ";":
+			if depth == 0 {
+				return
+			}
+		}
+	}
+	return errors.New("proto解析失败:语句未结束")
+}
+
+func (this_ *protoParser) parseFile() (err error) {
+	for this_.pos < len(this_.tokens) {
+		switch this_.peek() {
+		case "package":
+			this_.next()
+			this_.file.Package = this_.next()
+			err = this_.expect(";")
+		case "message":
+			var message *ProtoMessage
+			if message, err = this_.parseMessage(this_.file.Package); err == nil {
+				this_.file.Messages = append(this_.file.Messages, message)
+			}
+		case "enum":
+			_, err = this_.parseEnum(this_.file.Package)
+		case ";":
+			this_.next()
+		default:
+			err = this_.skipStatement()
+		}
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+func joinName(scope string, name string) string {
+	if scope == "" {
+		return name
+	}
+	return scope + "." + name
+}
+
+func (this_ *protoParser) parseMessage(scope string) (message *ProtoMessage, err error) {
+	this_.next()
+	message = &ProtoMessage{Name: joinName(scope, this_.next())}
+	this_.file.types[message.Name] = message
+	if err = this_.expect("{"); err != nil {
+		return
+	}
+	for {
+		token := this_.peek()
+		switch token {
+		case "}":
+			this_.next()
+			return
+		case "":
+			err = errors.New("proto解析失败:消息[" + message.Name + "]未结束")
+			return
+		case "message":
+			var nested *ProtoMessage
+			if nested, err = this_.parseMessage(message.Name); err == nil {
+				message.Messages = append(message.Messages, nested)
+			}
+		case "enum":
+			_, err = this_.parseEnum(message.Name)
+		case "oneof":
+			this_.next()
+			this_.next()
+			if err = this_.expect("{"); err != nil {
+				return
+			}
+			for err == nil && this_.peek() != "}" {
+				if this_.peek() == "option" {
+					err = this_.skipStatement()
+					continue
+				}
+				err = this_.parseField(message)
+			}
+			this_.next()
+		case "option", "reserved", "extensions", "extend":
+			err = this_.skipStatement()
+		case ";":
+			this_.next()
+		default:
+			err = this_.parseField(message)
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (this_ *protoParser) parseField(message *ProtoMessage) (err error) {
+	field := &ProtoField{scope: message.Name}
+	token := this_.next()
+	switch token {
+	case "repeated":
+		field.Repeated = true
+		token = this_.next()
+	case "optional", "required":
+		token = this_.next()
+	}
+	if token == "map" {
+		// map<K,V> 转为 repeated 的 Entry 消息，key=1 value=2
+		if err = this_.expect("<"); err != nil {
+			return
+		}
+		keyType := this_.next()
+		if err = this_.expect(","); err != nil {
+			return
+		}
+		valueType := this_.next()
+		if err = this_.expect(">"); err != nil {
+			return
+		}
+		field.Name = this_.next()
+		entry := &ProtoMessage{Name: message.Name + "." + field.Name + "Entry", IsMap: true}
+		entry.Fields = []*ProtoField{
+			{Name: "key", Number: 1, Type: keyType, scope: message.Name},
+			{Name: "value", Number: 2, Type: valueType, scope: message.Name},
+		}
+		this_.fields = append(this_.fields, entry.Fields...)
+		this_.file.types[entry.Name] = entry
+		field.Type = entry.Name
+		field.Repeated = true
+	} else {
+		field.Type = token
+		field.Name = this_.next()
+	}
+	if err = this_.expect("="); err != nil {
+		return
+	}
+	if field.Number, err = strconv.Atoi(this_.next()); err != nil {
+		err = errors.New("proto解析失败:字段[" + message.Name + "." + field.Name + "]编号错误")
+		return
+	}
+	if this_.peek() == "[" {
+		for this_.pos < len(this_.tokens) && this_.next() != "]" {
+		}
+	}
+	if err = this_.expect(";"); err != nil {
+		return
+	}
+	message.Fields = append(message.Fields, field)
+	this_.fields = append(this_.fields, field)
+	return
+}
+
+func (this_ *protoParser) parseEnum(scope string) (enum *ProtoEnum, err error) {
+	this_.next()
+	enum = &ProtoEnum{Name: joinName(scope, this_.next()), Values: map[string]int32{}, byNumber: map[int32]string{}}
+	this_.file.types[enum.Name] = enum
+	if err = this_.expect("{"); err != nil {
+		return
+	}
+	for {
+		token := this_.peek()
+		switch token {
+		case "}":
+			this_.next()
+			return
+		case "":
+			err = errors.New("proto解析失败:枚举[" + enum.Name + "]未结束")
+			return
+		case "option", "reserved":
+			err = this_.skipStatement()
+		case ";":
+			this_.next()
+		default:
+			name := this_.next()
+			if err = this_.expect("="); err != nil {
+				return
+			}
+			var number int64
+			if number, err = strconv.ParseInt(this_.next(), 0, 32); err != nil {
+				err = errors.New("proto解析失败:枚举值[" + enum.Name + "." + name + "]错误")
+				return
+			}
+			enum.Values[name] = int32(number)
+			if _, ok := enum.byNumber[int32(number)]; !ok {
+				enum.byNumber[int32(number)] = name
+			}
+			err = this_.skipStatement()
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// resolve 按 protobuf 作用域 规则 由 内 向 外 查找 类型
+func (this_ *protoParser) resolve() (err error) {
+	for _, message := range this_.file.types {
+		if one, ok := message.(*ProtoMessage); ok {
+			one.byNumber = map[int]*ProtoField{}
+			for _, field := range one.Fields {
+				one.byNumber[field.Number] = field
+			}
+		}
+	}
+	for _, field := range this_.fields {
+		if protoScalarTypes[field.Type] {
+			continue
+		}
+		var find interface{}
+		if strings.HasPrefix(field.Type, ".") {
+			find = this_.file.types[field.Type[1:]]
+		} else {
+			scope := field.scope
+			for find == nil {
+				find = this_.file.types[joinName(scope, field.Type)]
+				if scope == "" {
+					break
+				}
+				if index := strings.LastIndex(scope, "."); index >= 0 {
+					scope = scope[:index]
+				} else {
+					scope = ""
+				}
+			}
+		}
+		switch v := find.(type) {
+		case *ProtoMessage:
+			field.Message = v
+		case *ProtoEnum:
+			field.Enum = v
+		default:
+			return errors.New("proto类型[" + field.Type + "]未定义")
+		}
+	}
+	return
+}
+
+// FindMessage 按 名称 查找 消息，可 不 带 包名，为 空 时 返回 第一个 顶层 消息
+func (this_ *ProtoFile) FindMessage(name string) (message *ProtoMessage, err error) {
+	name = strings.TrimPrefix(name, ".")
+	if name == "" {
+		if len(this_.Messages) == 0 {
+			err = errors.New("proto中没有消息定义")
+			return
+		}
+		message = this_.Messages[0]
+		return
+	}
+	for _, one := range []string{name, joinName(this_.Package, name)} {
+		if find, ok := this_.types[one].(*ProtoMessage); ok && !find.IsMap {
+			message = find
+			return
+		}
+	}
+	err = errors.New("proto消息[" + name + "]不存在")
+	return
+}
+
+// MessageIndexes 返回 消息 在 文件 中 的 下标 路径，用于 Confluent 帧
+func (this_ *ProtoFile) MessageIndexes(message *ProtoMessage) (indexes []int, err error) {
+	var find func(list []*ProtoMessage, path []int) []int
+	find = func(list []*ProtoMessage, path []int) []int {
+		for i, one := range list {
+			current := append(append([]int{}, path...), i)
+			if one == message {
+				return current
+			}
+			if res := find(one.Messages, current); res != nil {
+				return res
+			}
+		}
+		return nil
+	}
+	if indexes = find(this_.Messages, nil); indexes == nil {
+		err = errors.New("proto消息[" + message.Name + "]不在文件中")
+	}
+	return
+}
+
+// MessageByIndexes 按 Confluent 帧 中 的 下标 路径 查找 消息
+func (this_ *ProtoFile) MessageByIndexes(indexes []int) (message *ProtoMessage, err error) {
+	list := this_.Messages
+	for _, index := range indexes {
+		if index < 0 || index >= len(list) {
+			err = fmt.Errorf("proto消息下标%v不存在", indexes)
+			return
+		}
+		message = list[index]
+		list = message.Messages
+	}
+	if message == nil {
+		message, err = this_.FindMessage("")
+	}
+	return
+}
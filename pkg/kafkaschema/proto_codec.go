@@ -0,0 +1,392 @@
+package kafkaschema
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// DecodeProto 按 消息 定义 解码 protobuf 二进制，字段 名 为 key，bytes 转 Base64，枚举 转 名称，未知 字段 忽略
+func DecodeProto(message *ProtoMessage, data []byte) (value map[string]interface{}, err error) {
+	value = map[string]interface{}{}
+	for len(data) > 0 {
+		number, wireType, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			err = protowire.ParseError(n)
+			return
+		}
+		data = data[n:]
+		field := message.byNumber[int(number)]
+		if field == nil {
+			if n = protowire.ConsumeFieldValue(number, wireType, data); n < 0 {
+				err = protowire.ParseError(n)
+				return
+			}
+			data = data[n:]
+			continue
+		}
+		var values []interface{}
+		if values, n, err = decodeProtoField(field, wireType, data); err != nil {
+			err = errors.New(message.Name + "." + field.Name + ":" + err.Error())
+			return
+		}
+		data = data[n:]
+		if field.Message != nil && field.Message.IsMap {
+			entries, _ := value[field.Name].(map[string]interface{})
+			if entries == nil {
+				entries = map[string]interface{}{}
+				value[field.Name] = entries
+			}
+			for _, one := range values {
+				entry := one.(map[string]interface{})
+				entries[fmt.Sprint(entry["key"])] = entry["value"]
+			}
+		} else if field.Repeated {
+			list, _ := value[field.Name].([]interface{})
+			value[field.Name] = append(list, values...)
+		} else {
+			value[field.Name] = values[0]
+		}
+	}
+	fillProtoDefaults(message, value)
+	return
+}
+
+// fillProtoDefaults proto3 默认值 不 写入 二进制，解码 时 补全 方便 查看
+func fillProtoDefaults(message *ProtoMessage, value map[string]interface{}) {
+	for _, field := range message.Fields {
+		if _, ok := value[field.Name]; ok {
+			continue
+		}
+		switch {
+		case field.Message != nil && field.Message.IsMap:
+			value[field.Name] = map[string]interface{}{}
+		case field.Repeated:
+			value[field.Name] = []interface{}{}
+		case field.Message != nil:
+			value[field.Name] = nil
+		case field.Enum != nil:
+			value[field.Name] = field.Enum.byNumber[0]
+		default:
+			value[field.Name] = protoScalarDefault(field.Type)
+		}
+	}
+}
+
+func protoScalarDefault(fieldType string) interface{} {
+	switch fieldType {
+	case "string", "bytes":
+		return ""
+	case "bool":
+		return false
+	case "double", "float":
+		return float64(0)
+	}
+	return int64(0)
+}
+
+func protoWireType(field *ProtoField) protowire.Type {
+	if field.Message != nil {
+		return protowire.BytesType
+	}
+	switch field.Type {
+	case "double", "fixed64", "sfixed64":
+		return protowire.Fixed64Type
+	case "float", "fixed32", "sfixed32":
+		return protowire.Fixed32Type
+	case "string", "bytes":
+		return protowire.BytesType
+	}
+	return protowire.VarintType
+}
+
+func decodeProtoField(field *ProtoField, wireType protowire.Type, data []byte) (values []interface{}, n int, err error) {
+	expected := protoWireType(field)
+	// 打包 的 重复 标量
+	if wireType == protowire.BytesType && expected != protowire.BytesType {
+		var packed []byte
+		if packed, n = protowire.ConsumeBytes(data); n < 0 {
+			err = protowire.ParseError(n)
+			return
+		}
+		for len(packed) > 0 {
+			var one interface{}
+			var size int
+			if one, size, err = decodeProtoScalar(field, expected, packed); err != nil {
+				return
+			}
+			values = append(values, one)
+			packed = packed[size:]
+		}
+		return
+	}
+	if wireType != expected {
+		err = fmt.Errorf("wire类型[%d]与字段类型[%s]不匹配", wireType, field.Type)
+		return
+	}
+	var one interface{}
+	if field.Message != nil {
+		var bs []byte
+		if bs, n = protowire.ConsumeBytes(data); n < 0 {
+			err = protowire.ParseError(n)
+			return
+		}
+		if one, err = DecodeProto(field.Message, bs); err != nil {
+			return
+		}
+	} else if one, n, err = decodeProtoScalar(field, wireType, data); err != nil {
+		return
+	}
+	values = []interface{}{one}
+	return
+}
+
+func decodeProtoScalar(field *ProtoField, wireType protowire.Type, data []byte) (value interface{}, n int, err error) {
+	switch wireType {
+	case protowire.VarintType:
+		var v uint64
+		if v, n = protowire.ConsumeVarint(data); n < 0 {
+			break
+		}
+		switch field.Type {
+		case "bool":
+			value = v != 0
+		case "int32":
+			value = int64(int32(v))
+		case "uint32", "uint64":
+			value = v
+		case "sint32", "sint64":
+			value = protowire.DecodeZigZag(v)
+		default:
+			if field.Enum != nil {
+				if name, ok := field.Enum.byNumber[int32(v)]; ok {
+					value = name
+				} else {
+					value = int64(int32(v))
+				}
+			} else {
+				value = int64(v)
+			}
+		}
+	case protowire.Fixed32Type:
+		var v uint32
+		if v, n = protowire.ConsumeFixed32(data); n < 0 {
+			break
+		}
+		switch field.Type {
+		case "float":
+			value = float64(math.Float32frombits(v))
+		case "sfixed32":
+			value = int64(int32(v))
+		default:
+			value = uint64(v)
+		}
+	case protowire.Fixed64Type:
+		var v uint64
+		if v, n = protowire.ConsumeFixed64(data); n < 0 {
+			break
+		}
+		switch field.Type {
+		case "double":
+			value = math.Float64frombits(v)
+		case "sfixed64":
+			value = int64(v)
+		default:
+			value = v
+		}
+	case protowire.BytesType:
+		var bs []byte
+		if bs, n = protowire.ConsumeBytes(data); n < 0 {
+			break
+		}
+		if field.Type == "bytes" {
+			value = base64.StdEncoding.EncodeToString(bs)
+		} else {
+			value = string(bs)
+		}
+	}
+	if n < 0 {
+		err = protowire.ParseError(n)
+	}
+	return
+}
+
+// EncodeProto 按 消息 定义 编码 JSON 对象，未 定义 的 字段 报错，重复 标量 使用 打包 编码
+func EncodeProto(message *ProtoMessage, value map[string]interface{}) (data []byte, err error) {
+	for name := range value {
+		if message.fieldByName(name) == nil {
+			err = errors.New("proto消息[" + message.Name + "]没有字段[" + name + "]")
+			return
+		}
+	}
+	for _, field := range message.Fields {
+		one, ok := value[field.Name]
+		if !ok || one == nil {
+			continue
+		}
+		if data, err = encodeProtoField(data, field, one); err != nil {
+			err = errors.New(message.Name + "." + field.Name + ":" + err.Error())
+			return
+		}
+	}
+	return
+}
+
+func (this_ *ProtoMessage) fieldByName(name string) *ProtoField {
+	for _, field := range this_.Fields {
+		if field.Name == name {
+			return field
+		}
+	}
+	return nil
+}
+
+func encodeProtoField(data []byte, field *ProtoField, value interface{}) (res []byte, err error) {
+	res = data
+	if field.Message != nil && field.Message.IsMap {
+		entries, ok := value.(map[string]interface{})
+		if !ok {
+			err = fmt.Errorf("值[%v]不是对象", value)
+			return
+		}
+		keyField := field.Message.Fields[0]
+		for _, key := range sortedKeys(entries) {
+			var keyValue interface{} = key
+			switch keyField.Type {
+			case "string":
+			case "bool":
+				keyValue = key == "true"
+			default:
+				keyValue = jsonNumberString(key)
+			}
+			var entry []byte
+			if entry, err = EncodeProto(field.Message, map[string]interface{}{"key": keyValue, "value": entries[key]}); err != nil {
+				return
+			}
+			res = protowire.AppendTag(res, protowire.Number(field.Number), protowire.BytesType)
+			res = protowire.AppendBytes(res, entry)
+		}
+		return
+	}
+	if !field.Repeated {
+		return appendProtoValue(res, field, value, true)
+	}
+	list, ok := value.([]interface{})
+	if !ok {
+		err = fmt.Errorf("值[%v]不是数组", value)
+		return
+	}
+	if protoWireType(field) != protowire.BytesType {
+		var packed []byte
+		for _, one := range list {
+			if packed, err = appendProtoValue(packed, field, one, false); err != nil {
+				return
+			}
+		}
+		res = protowire.AppendTag(res, protowire.Number(field.Number), protowire.BytesType)
+		res = protowire.AppendBytes(res, packed)
+		return
+	}
+	for _, one := range list {
+		if res, err = appendProtoValue(res, field, one, true); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func appendProtoValue(data []byte, field *ProtoField, value interface{}, withTag bool) (res []byte, err error) {
+	wireType := protoWireType(field)
+	res = data
+	if withTag {
+		res = protowire.AppendTag(res, protowire.Number(field.Number), wireType)
+	}
+	if field.Message != nil {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			err = fmt.Errorf("值[%v]不是对象", value)
+			return
+		}
+		var bs []byte
+		if bs, err = EncodeProto(field.Message, object); err != nil {
+			return
+		}
+		res = protowire.AppendBytes(res, bs)
+		return
+	}
+	if field.Enum != nil {
+		if name, ok := value.(string); ok {
+			number, find := field.Enum.Values[name]
+			if !find {
+				err = errors.New("枚举[" + field.Enum.Name + "]没有值[" + name + "]")
+				return
+			}
+			value = int64(number)
+		}
+	}
+	switch field.Type {
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			err = fmt.Errorf("值[%v]不是字符串", value)
+			return
+		}
+		res = protowire.AppendString(res, s)
+	case "bytes":
+		s, ok := value.(string)
+		if !ok {
+			err = fmt.Errorf("值[%v]不是Base64字符串", value)
+			return
+		}
+		var bs []byte
+		if bs, err = base64.StdEncoding.DecodeString(s); err != nil {
+			err = errors.New("bytes 值 需要 Base64:" + err.Error())
+			return
+		}
+		res = protowire.AppendBytes(res, bs)
+	case "bool":
+		b, ok := value.(bool)
+		if !ok {
+			err = fmt.Errorf("值[%v]不是布尔", value)
+			return
+		}
+		res = protowire.AppendVarint(res, protowire.EncodeBool(b))
+	case "double", "float":
+		f, ok := toFloat64(value)
+		if !ok {
+			err = fmt.Errorf("值[%v]不是数字", value)
+			return
+		}
+		if field.Type == "double" {
+			res = protowire.AppendFixed64(res, math.Float64bits(f))
+		} else {
+			res = protowire.AppendFixed32(res, math.Float32bits(float32(f)))
+		}
+	default:
+		n, ok := toInt64(jsonNumberString(value))
+		if !ok {
+			if u, e := strconv.ParseUint(fmt.Sprint(value), 10, 64); e == nil {
+				n, ok = int64(u), true
+			}
+		}
+		if !ok {
+			err = fmt.Errorf("值[%v]不是整数", value)
+			return
+		}
+		switch field.Type {
+		case "sint32", "sint64":
+			res = protowire.AppendVarint(res, protowire.EncodeZigZag(n))
+		case "fixed32", "sfixed32":
+			res = protowire.AppendFixed32(res, uint32(n))
+		case "fixed64", "sfixed64":
+			res = protowire.AppendFixed64(res, uint64(n))
+		default:
+			res = protowire.AppendVarint(res, uint64(n))
+		}
+	}
+	return
+}
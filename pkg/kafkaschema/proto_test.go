@@ -0,0 +1,99 @@
+package kafkaschema
+
+import (
+	"testing"
+)
+
+const testProto = `
+syntax = "proto3";
+package shop.v1;
+
+import "google/protobuf/empty.proto";
+option java_package = "com.shop";
+
+// 订单
+message Order {
+  int64 id = 1;
+  Status status = 2;
+  double amount = 3;
+  repeated string tags = 4;
+  repeated sint32 scores = 5 [packed = true];
+  map<string, Item> items = 6;
+  bytes raw = 7;
+  oneof target {
+    string email = 8;
+    uint64 phone = 9;
+  }
+  reserved 10, 11;
+
+  message Item {
+    string sku = 1;
+    int32 count = 2;
+  }
+}
+
+enum Status {
+  UNKNOWN = 0;
+  PAID = 1;
+}
+
+message Refund {
+  .shop.v1.Order order = 1;
+  Order.Item item = 2;
+}
+`
+
+func TestProtoParse(t *testing.T) {
+	file, err := ParseProto(testProto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file.Package != "shop.v1" || len(file.Messages) != 2 {
+		t.Fatalf("package %s messages %d", file.Package, len(file.Messages))
+	}
+	refund, err := file.FindMessage("Refund")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refund.Fields[0].Message.Name != "shop.v1.Order" || refund.Fields[1].Message.Name != "shop.v1.Order.Item" {
+		t.Fatalf("refund fields %+v %+v", refund.Fields[0], refund.Fields[1])
+	}
+	item, _ := file.FindMessage("shop.v1.Order.Item")
+	indexes, err := file.MessageIndexes(item)
+	if err != nil || len(indexes) != 2 || indexes[0] != 0 || indexes[1] != 0 {
+		t.Fatalf("indexes %v err %v", indexes, err)
+	}
+	if find, _ := file.MessageByIndexes([]int{1}); find != refund {
+		t.Fatal("message by indexes [1] should be Refund")
+	}
+	if _, err = ParseProto(`message A { Missing b = 1; }`); err == nil {
+		t.Fatal("missing type should error")
+	}
+}
+
+func TestProtoRoundTrip(t *testing.T) {
+	file, err := ParseProto(testProto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	local := &Schema{SchemaType: SchemaTypeProtobuf, proto: file}
+	value := `{"id":"9007199254740993","status":"PAID","amount":1.5,"tags":["a","b"],"scores":[-1,2],"items":{"x":{"sku":"x","count":2}},"raw":"AQI=","phone":13800000000}`
+	bs, err := local.Encode(value, "Order")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := local.Decode(bs, "Order")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"amount":1.5,"email":"","id":9007199254740993,"items":{"x":{"count":2,"sku":"x"}},"phone":13800000000,"raw":"AQI=","scores":[-1,2],"status":"PAID","tags":["a","b"]}`
+	if res != expected {
+		t.Fatalf("decode %s", res)
+	}
+	if _, err = local.Encode(`{"none":1}`, "Order"); err == nil {
+		t.Fatal("unknown field should error")
+	}
+	if _, err = local.Encode(`{"status":"NONE"}`, "Order"); err == nil {
+		t.Fatal("unknown enum should error")
+	}
+}
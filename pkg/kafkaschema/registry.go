@@ -0,0 +1,161 @@
+package kafkaschema
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RegistryConfig Schema Registry 连接 配置，有 用户名 时 使用 Basic 认证
+type RegistryConfig struct {
+	Url                string `json:"schemaRegistryUrl,omitempty"`
+	Username           string `json:"schemaRegistryUsername,omitempty"`
+	Password           string `json:"schemaRegistryPassword,omitempty"`
+	InsecureSkipVerify bool   `json:"schemaRegistryInsecureSkipVerify,omitempty"`
+}
+
+// Registry Confluent Schema Registry 客户端，按 ID 的 结构 不可变 会 一直 缓存
+type Registry struct {
+	config *RegistryConfig
+	client *http.Client
+	lock   sync.Mutex
+	byId   map[int]*Schema
+}
+
+// NewRegistry 创建 客户端
+func NewRegistry(config *RegistryConfig) (registry *Registry, err error) {
+	if config == nil || strings.TrimSpace(config.Url) == "" {
+		err = errors.New("Schema Registry 地址 为空")
+		return
+	}
+	if _, err = url.Parse(config.Url); err != nil {
+		return
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if config.InsecureSkipVerify {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	registry = &Registry{
+		config: config,
+		client: &http.Client{Transport: transport, Timeout: 10 * time.Second},
+		byId:   map[int]*Schema{},
+	}
+	return
+}
+
+func (this_ *Registry) get(path string, res interface{}) (err error) {
+	req, err := http.NewRequest("GET", strings.TrimRight(this_.config.Url, "/")+path, nil)
+	if err != nil {
+		return
+	}
+	req.Header.Set("Accept", "application/vnd.schemaregistry.v1+json, application/json")
+	if this_.config.Username != "" {
+		req.SetBasicAuth(this_.config.Username, this_.config.Password)
+	}
+	resp, err := this_.client.Do(req)
+	if err != nil {
+		return
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return
+	}
+	if resp.StatusCode != http.StatusOK {
+		var registryErr struct {
+			ErrorCode int    `json:"error_code"`
+			Message   string `json:"message"`
+		}
+		if json.Unmarshal(body, &registryErr) == nil && registryErr.Message != "" {
+			err = fmt.Errorf("Schema Registry [%s] 错误 [%d]:%s", path, registryErr.ErrorCode, registryErr.Message)
+		} else {
+			err = fmt.Errorf("Schema Registry [%s] 状态 [%d]:%s", path, resp.StatusCode, string(body))
+		}
+		return
+	}
+	err = json.Unmarshal(body, res)
+	return
+}
+
+// GetSchemaById 按 ID 获取 结构
+func (this_ *Registry) GetSchemaById(id int) (schema *Schema, err error) {
+	this_.lock.Lock()
+	schema = this_.byId[id]
+	this_.lock.Unlock()
+	if schema != nil {
+		return
+	}
+	res := &Schema{}
+	if err = this_.get("/schemas/ids/"+strconv.Itoa(id), res); err != nil {
+		return
+	}
+	res.Id = id
+	if err = res.compile(); err != nil {
+		return
+	}
+	this_.lock.Lock()
+	this_.byId[id] = res
+	this_.lock.Unlock()
+	schema = res
+	return
+}
+
+// GetSubjectVersion 获取 主题 指定 版本 的 结构，版本 为 空 时 为 latest，latest 不 缓存
+func (this_ *Registry) GetSubjectVersion(subject string, version string) (schema *Schema, err error) {
+	if version == "" {
+		version = "latest"
+	}
+	res := &Schema{}
+	if err = this_.get("/subjects/"+url.PathEscape(subject)+"/versions/"+url.PathEscape(version), res); err != nil {
+		return
+	}
+	this_.lock.Lock()
+	schema = this_.byId[res.Id]
+	this_.lock.Unlock()
+	if schema != nil {
+		return
+	}
+	if err = res.compile(); err != nil {
+		return
+	}
+	this_.lock.Lock()
+	this_.byId[res.Id] = res
+	this_.lock.Unlock()
+	schema = res
+	return
+}
+
+// GetSubjects 获取 所有 主题
+func (this_ *Registry) GetSubjects() (subjects []string, err error) {
+	err = this_.get("/subjects", &subjects)
+	return
+}
+
+// GetVersions 获取 主题 的 所有 版本
+func (this_ *Registry) GetVersions(subject string) (versions []int, err error) {
+	err = this_.get("/subjects/"+url.PathEscape(subject)+"/versions", &versions)
+	return
+}
+
+// Decode 解码 Confluent 帧 格式 数据，按 结构 ID 获取 结构 后 转为 JSON
+func (this_ *Registry) Decode(data []byte) (value string, schemaId int, err error) {
+	schemaId, payload, err := SplitFrame(data)
+	if err != nil {
+		return
+	}
+	schema, err := this_.GetSchemaById(schemaId)
+	if err != nil {
+		return
+	}
+	value, err = schema.DecodeFramedPayload(payload)
+	return
+}
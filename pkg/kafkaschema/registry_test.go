@@ -0,0 +1,119 @@
+package kafkaschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newTestRegistry 本地 Schema Registry 替身，只 实现 按 ID、按 主题 版本 查询
+func newTestRegistry(t *testing.T, schemas []*Schema) (server *httptest.Server, calls *int) {
+	calls = new(int)
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*calls++
+		if username, password, ok := r.BasicAuth(); !ok || username != "user" || password != "pass" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error_code":40101,"message":"Unauthorized"}`))
+			return
+		}
+		for _, one := range schemas {
+			subjectPath := "/subjects/" + one.Subject + "/versions/"
+			if r.URL.Path == "/schemas/ids/"+itoa(one.Id) ||
+				r.URL.Path == subjectPath+itoa(one.Version) || r.URL.Path == subjectPath+"latest" {
+				bs, _ := json.Marshal(one)
+				_, _ = w.Write(bs)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"error_code":40403,"message":"Schema not found"}`))
+	}))
+	t.Cleanup(server.Close)
+	return
+}
+
+func itoa(n int) string {
+	bs, _ := json.Marshal(n)
+	return string(bs)
+}
+
+func TestRegistry(t *testing.T) {
+	server, calls := newTestRegistry(t, []*Schema{
+		{Id: 7, Subject: "orders-value", Version: 1, Schema: `{"type":"record","name":"Order","fields":[{"name":"id","type":"long"}]}`},
+		{Id: 8, Subject: "refund-value", Version: 2, SchemaType: SchemaTypeProtobuf, Schema: testProto},
+	})
+	registry, err := NewRegistry(&RegistryConfig{Url: server.URL, Username: "user", Password: "pass"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	schema, err := registry.GetSubjectVersion("orders-value", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bs, err := schema.Encode(`{"id":2}`, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(bs, []byte{0, 0, 0, 0, 7, 4}) {
+		t.Fatalf("avro frame %v", bs)
+	}
+	value, schemaId, err := registry.Decode(bs)
+	if err != nil || schemaId != 7 || value != `{"id":2}` {
+		t.Fatalf("decode %s id %d err %v", value, schemaId, err)
+	}
+	before := *calls
+	if _, _, err = registry.Decode(bs); err != nil || *calls != before {
+		t.Fatalf("schema by id should be cached, calls %d -> %d", before, *calls)
+	}
+
+	schema, err = registry.GetSubjectVersion("refund-value", "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bs, err = schema.Encode(`{"item":{"sku":"x"}}`, "Refund")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 消息 下标 [1] 编码 为 数量 1、下标 1，zigzag 后 为 2、2
+	if !bytes.Equal(bs[:7], []byte{0, 0, 0, 0, 8, 2, 2}) {
+		t.Fatalf("protobuf frame %v", bs)
+	}
+	value, _, err = registry.Decode(bs)
+	if err != nil || value != `{"item":{"count":0,"sku":"x"},"order":null}` {
+		t.Fatalf("decode %s err %v", value, err)
+	}
+
+	if _, _, err = registry.Decode([]byte{0, 0, 0, 0, 9, 1}); err == nil || !strings.Contains(err.Error(), "40403") {
+		t.Fatalf("missing schema err %v", err)
+	}
+	if _, _, err = registry.Decode([]byte("plain")); err == nil {
+		t.Fatal("plain data should error")
+	}
+	unauthorized, _ := NewRegistry(&RegistryConfig{Url: server.URL})
+	if _, err = unauthorized.GetSchemaById(7); err == nil || !strings.Contains(err.Error(), "40101") {
+		t.Fatalf("unauthorized err %v", err)
+	}
+}
+
+func TestLocalSchema(t *testing.T) {
+	if _, err := NewLocalSchema("a.txt", "x"); err == nil {
+		t.Fatal("unknown ext should error")
+	}
+	schema, err := NewLocalSchema("order.avsc", `{"type":"record","name":"Order","fields":[{"name":"id","type":"long"}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bs, err := schema.Encode(`{"id":2}`, "")
+	if err != nil || !bytes.Equal(bs, []byte{4}) {
+		t.Fatalf("local encode %v err %v", bs, err)
+	}
+	// 本地 结构 也 能 解码 带 帧 的 数据
+	value, err := schema.Decode([]byte{0, 0, 0, 0, 7, 4}, "")
+	if err != nil || value != `{"id":2}` {
+		t.Fatalf("local decode %s err %v", value, err)
+	}
+}
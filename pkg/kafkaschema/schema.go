@@ -0,0 +1,261 @@
+package kafkaschema
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	SchemaTypeAvro     = "AVRO"
+	SchemaTypeProtobuf = "PROTOBUF"
+	SchemaTypeJson     = "JSON"
+
+	// magicByte Confluent 帧 首 字节，后 跟 4 字节 大端 结构 ID
+	magicByte = 0
+)
+
+// Schema 结构，Id 为 0 时 为 本地 文件，编码 不 加 Confluent 帧
+type Schema struct {
+	Id         int                `json:"id,omitempty"`
+	Subject    string             `json:"subject,omitempty"`
+	Version    int                `json:"version,omitempty"`
+	SchemaType string             `json:"schemaType,omitempty"` // 为 空 时 为 AVRO
+	Schema     string             `json:"schema,omitempty"`
+	References []*SchemaReference `json:"references,omitempty"`
+
+	avro  *AvroSchema
+	proto *ProtoFile
+}
+
+type SchemaReference struct {
+	Name    string `json:"name,omitempty"`
+	Subject string `json:"subject,omitempty"`
+	Version int    `json:"version,omitempty"`
+}
+
+// NewLocalSchema 按 文件 扩展名 加载 本地 .avsc、.proto 结构
+func NewLocalSchema(fileName string, content string) (schema *Schema, err error) {
+	schema = &Schema{Schema: content}
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".avsc", ".json":
+		schema.SchemaType = SchemaTypeAvro
+	case ".proto":
+		schema.SchemaType = SchemaTypeProtobuf
+	default:
+		err = errors.New("本地结构文件[" + fileName + "]只支持.avsc、.proto")
+		return
+	}
+	err = schema.compile()
+	return
+}
+
+func (this_ *Schema) compile() (err error) {
+	if this_.SchemaType == "" {
+		this_.SchemaType = SchemaTypeAvro
+	}
+	switch this_.SchemaType {
+	case SchemaTypeAvro:
+		this_.avro, err = ParseAvroSchema(this_.Schema)
+	case SchemaTypeProtobuf:
+		this_.proto, err = ParseProto(this_.Schema)
+		if err != nil && len(this_.References) > 0 {
+			err = errors.New(err.Error() + "，暂 不 支持 引用 其它 主题 的 proto")
+		}
+	case SchemaTypeJson:
+	default:
+		err = errors.New("结构类型[" + this_.SchemaType + "]不支持")
+	}
+	return
+}
+
+// SplitFrame 拆分 Confluent 帧，返回 结构 ID 与 剩余 数据
+func SplitFrame(data []byte) (schemaId int, payload []byte, err error) {
+	if len(data) < 5 || data[0] != magicByte {
+		err = errors.New("数据 不是 Confluent 帧 格式")
+		return
+	}
+	schemaId = int(binary.BigEndian.Uint32(data[1:5]))
+	payload = data[5:]
+	return
+}
+
+// readMessageIndexes protobuf 帧 在 结构 ID 后 为 zigzag 变长 的 下标 数量 与 下标，数量 为 0 表示 [0]
+func readMessageIndexes(payload []byte) (indexes []int, rest []byte, err error) {
+	reader := bytes.NewReader(payload)
+	count, err := readLong(reader)
+	if err != nil || count < 0 || count > int64(reader.Len()) {
+		err = errors.New("protobuf 帧 消息 下标 错误")
+		return
+	}
+	if count == 0 {
+		indexes = []int{0}
+	}
+	for i := int64(0); i < count; i++ {
+		var index int64
+		if index, err = readLong(reader); err != nil {
+			err = errors.New("protobuf 帧 消息 下标 错误")
+			return
+		}
+		indexes = append(indexes, int(index))
+	}
+	rest = payload[len(payload)-reader.Len():]
+	return
+}
+
+func writeMessageIndexes(buf *bytes.Buffer, indexes []int) {
+	if len(indexes) == 1 && indexes[0] == 0 {
+		buf.WriteByte(0)
+		return
+	}
+	writeLong(buf, int64(len(indexes)))
+	for _, index := range indexes {
+		writeLong(buf, int64(index))
+	}
+}
+
+// DecodeFramedPayload 解码 去掉 魔数 与 结构 ID 后 的 数据
+func (this_ *Schema) DecodeFramedPayload(payload []byte) (value string, err error) {
+	var data interface{}
+	switch this_.SchemaType {
+	case SchemaTypeAvro:
+		data, err = DecodeAvro(this_.avro, payload)
+	case SchemaTypeProtobuf:
+		var indexes []int
+		if indexes, payload, err = readMessageIndexes(payload); err != nil {
+			return
+		}
+		var message *ProtoMessage
+		if message, err = this_.proto.MessageByIndexes(indexes); err != nil {
+			return
+		}
+		data, err = DecodeProto(message, payload)
+	case SchemaTypeJson:
+		value = string(payload)
+		return
+	}
+	if err != nil {
+		return
+	}
+	value, err = toJSON(data)
+	return
+}
+
+// Decode 使用 本结构 解码，数据 为 Confluent 帧 时 先 去掉 帧 头，protobuf 按 消息 名称 解码，名称 为 空 时 取 帧 中 的 下标 或 第一个 消息
+func (this_ *Schema) Decode(data []byte, messageName string) (value string, err error) {
+	if _, payload, e := SplitFrame(data); e == nil {
+		if this_.SchemaType != SchemaTypeProtobuf || messageName == "" {
+			if value, err = this_.DecodeFramedPayload(payload); err == nil {
+				return
+			}
+		} else if _, rest, e := readMessageIndexes(payload); e == nil {
+			if value, err = this_.decodeRaw(rest, messageName); err == nil {
+				return
+			}
+		}
+	}
+	value, err = this_.decodeRaw(data, messageName)
+	return
+}
+
+func (this_ *Schema) decodeRaw(data []byte, messageName string) (value string, err error) {
+	var res interface{}
+	switch this_.SchemaType {
+	case SchemaTypeAvro:
+		res, err = DecodeAvro(this_.avro, data)
+	case SchemaTypeProtobuf:
+		var message *ProtoMessage
+		if message, err = this_.proto.FindMessage(messageName); err != nil {
+			return
+		}
+		res, err = DecodeProto(message, data)
+	default:
+		value = string(data)
+		return
+	}
+	if err != nil {
+		return
+	}
+	value, err = toJSON(res)
+	return
+}
+
+// Encode 将 JSON 按 结构 编码，注册 中心 的 结构 加 Confluent 帧，protobuf 消息 名称 为 空 时 取 第一个 消息
+func (this_ *Schema) Encode(value string, messageName string) (data []byte, err error) {
+	var object interface{}
+	decoder := json.NewDecoder(strings.NewReader(value))
+	decoder.UseNumber()
+	if err = decoder.Decode(&object); err != nil {
+		err = errors.New("值 不是 合法 JSON:" + err.Error())
+		return
+	}
+	buf := &bytes.Buffer{}
+	if this_.Id > 0 {
+		buf.WriteByte(magicByte)
+		bs := make([]byte, 4)
+		binary.BigEndian.PutUint32(bs, uint32(this_.Id))
+		buf.Write(bs)
+	}
+	var bs []byte
+	switch this_.SchemaType {
+	case SchemaTypeAvro:
+		bs, err = EncodeAvro(this_.avro, object)
+	case SchemaTypeProtobuf:
+		var message *ProtoMessage
+		if message, err = this_.proto.FindMessage(messageName); err != nil {
+			return
+		}
+		var indexes []int
+		if indexes, err = this_.proto.MessageIndexes(message); err != nil {
+			return
+		}
+		fields, ok := object.(map[string]interface{})
+		if !ok {
+			err = errors.New("protobuf 值 需要 JSON 对象")
+			return
+		}
+		if this_.Id > 0 {
+			writeMessageIndexes(buf, indexes)
+		}
+		bs, err = EncodeProto(message, fields)
+	default:
+		bs = []byte(value)
+	}
+	if err != nil {
+		return
+	}
+	buf.Write(bs)
+	data = buf.Bytes()
+	return
+}
+
+func toJSON(data interface{}) (value string, err error) {
+	bs, err := json.Marshal(data)
+	if err != nil {
+		err = fmt.Errorf("转 JSON 失败:%s", err.Error())
+		return
+	}
+	value = string(bs)
+	return
+}
+
+func sortedKeys(data map[string]interface{}) (keys []string) {
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return
+}
+
+// jsonNumberString 64 位 整数 可能 以 字符串 传入，转为 数字 处理
+func jsonNumberString(value interface{}) interface{} {
+	if s, ok := value.(string); ok {
+		return json.Number(s)
+	}
+	return value
+}
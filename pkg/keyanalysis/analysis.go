@@ -0,0 +1,304 @@
+package keyanalysis
+
+import (
+	"container/heap"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	// CsvBigKeys 大 Key 列表
+	CsvBigKeys = "bigKeys"
+	// CsvNoExpire 没有 过期 时间 的 Key 列表
+	CsvNoExpire = "noExpire"
+	// CsvPrefix 前缀 汇总
+	CsvPrefix = "prefix"
+	// OtherPattern 前缀 个数 超过 限制 后 新 出现 的 前缀 合并 到 其中
+	OtherPattern = "(other)"
+
+	defaultSeparators  = ":"
+	defaultDepth       = 2
+	defaultTopSize     = 100
+	defaultMaxPrefixes = 1000
+)
+
+var variableSegment = regexp.MustCompile(`^(\d+|[0-9a-fA-F]{16,}|[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12})$`)
+
+// KeyInfo 单个 Key 的 采样 结果，TTL 为 -1 表示 没有 过期 时间
+type KeyInfo struct {
+	Key    string `json:"key"`
+	Type   string `json:"type"`
+	Memory int64  `json:"memory"`
+	TTL    int64  `json:"ttl"`
+	Node   string `json:"node,omitempty"`
+}
+
+// Options 汇总 配置
+type Options struct {
+	Separators  string `json:"separators,omitempty"`  // 前缀 分隔符，默认 :
+	Depth       int    `json:"depth,omitempty"`       // 前缀 取 前 几段，默认 2
+	TopSize     int    `json:"topSize,omitempty"`     // 大 Key、无过期 Key 保留 个数，默认 100
+	MaxPrefixes int    `json:"maxPrefixes,omitempty"` // 前缀 最多 个数，超过 后 合并 到 (other)，默认 1000
+}
+
+// PrefixStat 前缀 汇总
+type PrefixStat struct {
+	Pattern       string           `json:"pattern"`
+	Count         int64            `json:"count"`
+	Memory        int64            `json:"memory"`
+	NoExpireCount int64            `json:"noExpireCount"`
+	MemoryShare   float64          `json:"memoryShare"` // 内存 占比，百分比
+	Types         map[string]int64 `json:"types"`
+}
+
+// Report 分析 结果
+type Report struct {
+	KeyCount       int64            `json:"keyCount"`
+	Memory         int64            `json:"memory"`
+	NoExpireCount  int64            `json:"noExpireCount"`
+	NoExpireMemory int64            `json:"noExpireMemory"`
+	Types          map[string]int64 `json:"types"`
+	BigKeys        []*KeyInfo       `json:"bigKeys"`
+	NoExpireKeys   []*KeyInfo       `json:"noExpireKeys"` // 没有 过期 时间 的 Key 中 内存 最大 的
+	Prefixes       []*PrefixStat    `json:"prefixes"`
+}
+
+// Analyzer 汇总 Key 采样 结果，非 并发 安全
+type Analyzer struct {
+	options        *Options
+	keyCount       int64
+	memory         int64
+	noExpireCount  int64
+	noExpireMemory int64
+	types          map[string]int64
+	bigKeys        *topKeys
+	noExpireKeys   *topKeys
+	prefixes       map[string]*PrefixStat
+	other          *PrefixStat
+}
+
+// NewAnalyzer 新建，options 为 空 时 使用 默认
+func NewAnalyzer(options *Options) (analyzer *Analyzer) {
+	opts := &Options{}
+	if options != nil {
+		*opts = *options
+	}
+	if opts.Separators == "" {
+		opts.Separators = defaultSeparators
+	}
+	if opts.Depth <= 0 {
+		opts.Depth = defaultDepth
+	}
+	if opts.TopSize <= 0 {
+		opts.TopSize = defaultTopSize
+	}
+	if opts.MaxPrefixes <= 0 {
+		opts.MaxPrefixes = defaultMaxPrefixes
+	}
+	analyzer = &Analyzer{
+		options:      opts,
+		types:        map[string]int64{},
+		bigKeys:      &topKeys{size: opts.TopSize},
+		noExpireKeys: &topKeys{size: opts.TopSize},
+		prefixes:     map[string]*PrefixStat{},
+	}
+	return
+}
+
+// PrefixPattern Key 的 前缀 模式，取 前 depth 段，数字、UUID、长 十六进制 段 替换 为 *，后面 还有 时 追加 *
+func PrefixPattern(key string, separators string, depth int) string {
+	if separators == "" {
+		separators = defaultSeparators
+	}
+	if depth <= 0 {
+		depth = defaultDepth
+	}
+	var pattern strings.Builder
+	segment := 0
+	start := 0
+	for i := 0; i <= len(key); i++ {
+		if i < len(key) && strings.IndexByte(separators, key[i]) < 0 {
+			continue
+		}
+		one := key[start:i]
+		if variableSegment.MatchString(one) {
+			one = "*"
+		}
+		pattern.WriteString(one)
+		segment++
+		if i == len(key) {
+			break
+		}
+		pattern.WriteByte(key[i])
+		if segment >= depth {
+			pattern.WriteString("*")
+			break
+		}
+		start = i + 1
+	}
+	return pattern.String()
+}
+
+// Add 加入 一个 Key
+func (this_ *Analyzer) Add(info *KeyInfo) {
+	noExpire := info.TTL == -1
+	this_.keyCount++
+	this_.memory += info.Memory
+	this_.types[info.Type]++
+	if noExpire {
+		this_.noExpireCount++
+		this_.noExpireMemory += info.Memory
+		this_.noExpireKeys.add(info)
+	}
+	this_.bigKeys.add(info)
+
+	pattern := PrefixPattern(info.Key, this_.options.Separators, this_.options.Depth)
+	stat := this_.prefixes[pattern]
+	if stat == nil {
+		if len(this_.prefixes) >= this_.options.MaxPrefixes {
+			if this_.other == nil {
+				this_.other = &PrefixStat{
+					Pattern: OtherPattern,
+					Types:   map[string]int64{},
+				}
+			}
+			stat = this_.other
+		} else {
+			stat = &PrefixStat{
+				Pattern: pattern,
+				Types:   map[string]int64{},
+			}
+			this_.prefixes[pattern] = stat
+		}
+	}
+	stat.Count++
+	stat.Memory += info.Memory
+	stat.Types[info.Type]++
+	if noExpire {
+		stat.NoExpireCount++
+	}
+}
+
+// Report 当前 结果，大 Key 按 内存 倒序，前缀 按 内存 倒序，超过 个数 限制 的 前缀 合并 为 (other)
+func (this_ *Analyzer) Report() (report *Report) {
+	report = &Report{
+		KeyCount:       this_.keyCount,
+		Memory:         this_.memory,
+		NoExpireCount:  this_.noExpireCount,
+		NoExpireMemory: this_.noExpireMemory,
+		Types:          map[string]int64{},
+		BigKeys:        this_.bigKeys.sorted(),
+		NoExpireKeys:   this_.noExpireKeys.sorted(),
+		Prefixes:       []*PrefixStat{},
+	}
+	for k, v := range this_.types {
+		report.Types[k] = v
+	}
+	var prefixes []*PrefixStat
+	for _, one := range this_.prefixes {
+		prefixes = append(prefixes, one)
+	}
+	if this_.other != nil {
+		prefixes = append(prefixes, this_.other)
+	}
+	for _, one := range prefixes {
+		stat := &PrefixStat{
+			Pattern:       one.Pattern,
+			Count:         one.Count,
+			Memory:        one.Memory,
+			NoExpireCount: one.NoExpireCount,
+			Types:         map[string]int64{},
+		}
+		for k, v := range one.Types {
+			stat.Types[k] = v
+		}
+		if this_.memory > 0 {
+			stat.MemoryShare = float64(one.Memory) * 100 / float64(this_.memory)
+		}
+		report.Prefixes = append(report.Prefixes, stat)
+	}
+	sort.Slice(report.Prefixes, func(i, j int) bool {
+		if report.Prefixes[i].Memory != report.Prefixes[j].Memory {
+			return report.Prefixes[i].Memory > report.Prefixes[j].Memory
+		}
+		return report.Prefixes[i].Pattern < report.Prefixes[j].Pattern
+	})
+	return
+}
+
+// WriteCSV 输出 结果 中 的 一类 数据
+func WriteCSV(w io.Writer, report *Report, kind string) (err error) {
+	writer := csv.NewWriter(w)
+	switch kind {
+	case CsvBigKeys, CsvNoExpire:
+		list := report.BigKeys
+		if kind == CsvNoExpire {
+			list = report.NoExpireKeys
+		}
+		_ = writer.Write([]string{"key", "type", "memory", "ttl", "node"})
+		for _, one := range list {
+			_ = writer.Write([]string{one.Key, one.Type, fmt.Sprint(one.Memory), fmt.Sprint(one.TTL), one.Node})
+		}
+	case CsvPrefix:
+		_ = writer.Write([]string{"pattern", "count", "memory", "memoryShare", "noExpireCount", "types"})
+		for _, one := range report.Prefixes {
+			var types []string
+			for k, v := range one.Types {
+				types = append(types, fmt.Sprint(k, "=", v))
+			}
+			sort.Strings(types)
+			_ = writer.Write([]string{one.Pattern, fmt.Sprint(one.Count), fmt.Sprint(one.Memory),
+				fmt.Sprintf("%.2f", one.MemoryShare), fmt.Sprint(one.NoExpireCount), strings.Join(types, ";")})
+		}
+	default:
+		err = errors.New("不支持的导出类型[" + kind + "]，支持 bigKeys、noExpire、prefix")
+		return
+	}
+	writer.Flush()
+	err = writer.Error()
+	return
+}
+
+// topKeys 内存 最大 的 size 个，小顶堆
+type topKeys struct {
+	size int
+	list []*KeyInfo
+}
+
+func (this_ *topKeys) Len() int           { return len(this_.list) }
+func (this_ *topKeys) Less(i, j int) bool { return this_.list[i].Memory < this_.list[j].Memory }
+func (this_ *topKeys) Swap(i, j int)      { this_.list[i], this_.list[j] = this_.list[j], this_.list[i] }
+func (this_ *topKeys) Push(x interface{}) { this_.list = append(this_.list, x.(*KeyInfo)) }
+func (this_ *topKeys) Pop() interface{} {
+	n := len(this_.list)
+	one := this_.list[n-1]
+	this_.list = this_.list[:n-1]
+	return one
+}
+
+func (this_ *topKeys) add(info *KeyInfo) {
+	if len(this_.list) < this_.size {
+		heap.Push(this_, info)
+		return
+	}
+	if info.Memory > this_.list[0].Memory {
+		this_.list[0] = info
+		heap.Fix(this_, 0)
+	}
+}
+
+func (this_ *topKeys) sorted() (list []*KeyInfo) {
+	list = append([]*KeyInfo{}, this_.list...)
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Memory != list[j].Memory {
+			return list[i].Memory > list[j].Memory
+		}
+		return list[i].Key < list[j].Key
+	})
+	return
+}
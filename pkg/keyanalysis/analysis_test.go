@@ -0,0 +1,91 @@
+package keyanalysis
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestPrefixPattern(t *testing.T) {
+	tests := []struct {
+		key     string
+		depth   int
+		pattern string
+	}{
+		{key: "user:1001:profile", depth: 2, pattern: "user:*:*"},
+		{key: "user:1001", depth: 2, pattern: "user:*"},
+		{key: "user", depth: 2, pattern: "user"},
+		{key: "order:detail:20230101:1", depth: 2, pattern: "order:detail:*"},
+		{key: "session:5f0c2a4e-1b2c-4d3e-8f9a-0b1c2d3e4f5a", depth: 3, pattern: "session:*"},
+		{key: "cache:", depth: 2, pattern: "cache:"},
+		{key: "a:b:c", depth: 1, pattern: "a:*"},
+	}
+	for _, one := range tests {
+		pattern := PrefixPattern(one.key, ":", one.depth)
+		if pattern != one.pattern {
+			t.Fatalf("key [%s] depth [%d] pattern [%s] want [%s]", one.key, one.depth, pattern, one.pattern)
+		}
+	}
+	if pattern := PrefixPattern("a.b/c", "./", 2); pattern != "a.b/*" {
+		t.Fatalf("separators pattern [%s]", pattern)
+	}
+}
+
+func TestAnalyzer(t *testing.T) {
+	analyzer := NewAnalyzer(&Options{TopSize: 2})
+	analyzer.Add(&KeyInfo{Key: "user:1:name", Type: "string", Memory: 100, TTL: -1})
+	analyzer.Add(&KeyInfo{Key: "user:2:name", Type: "string", Memory: 300, TTL: 60})
+	analyzer.Add(&KeyInfo{Key: "order:1", Type: "hash", Memory: 500, TTL: -1})
+	analyzer.Add(&KeyInfo{Key: "order:2", Type: "hash", Memory: 100, TTL: -1})
+
+	report := analyzer.Report()
+	if report.KeyCount != 4 || report.Memory != 1000 || report.NoExpireCount != 3 || report.NoExpireMemory != 700 {
+		t.Fatalf("report %+v", report)
+	}
+	if len(report.BigKeys) != 2 || report.BigKeys[0].Key != "order:1" || report.BigKeys[1].Key != "user:2:name" {
+		t.Fatalf("big keys %+v %+v", report.BigKeys[0], report.BigKeys[1])
+	}
+	if len(report.NoExpireKeys) != 2 || report.NoExpireKeys[0].Key != "order:1" || report.NoExpireKeys[1].Memory != 100 {
+		t.Fatalf("no expire keys %+v", report.NoExpireKeys)
+	}
+	if len(report.Prefixes) != 2 || report.Prefixes[0].Pattern != "order:*" || report.Prefixes[0].MemoryShare != 60 ||
+		report.Prefixes[1].Pattern != "user:*:*" || report.Prefixes[1].NoExpireCount != 1 {
+		t.Fatalf("prefixes %+v %+v", report.Prefixes[0], report.Prefixes[1])
+	}
+	if report.Types["hash"] != 2 || report.Types["string"] != 2 {
+		t.Fatalf("types %+v", report.Types)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := WriteCSV(buf, report, CsvPrefix); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 || lines[1] != "order:*,2,600,60.00,2,hash=2" {
+		t.Fatalf("csv %s", buf.String())
+	}
+	if err := WriteCSV(buf, report, "x"); err == nil {
+		t.Fatal("unknown kind should error")
+	}
+}
+
+func TestAnalyzerMaxPrefixes(t *testing.T) {
+	analyzer := NewAnalyzer(&Options{MaxPrefixes: 2})
+	analyzer.Add(&KeyInfo{Key: "a:1", Type: "string", Memory: 10, TTL: -1})
+	analyzer.Add(&KeyInfo{Key: "b:1", Type: "string", Memory: 20, TTL: 60})
+	analyzer.Add(&KeyInfo{Key: "c:1", Type: "hash", Memory: 30, TTL: -1})
+	analyzer.Add(&KeyInfo{Key: "d:1", Type: "string", Memory: 40, TTL: 60})
+	analyzer.Add(&KeyInfo{Key: "a:2", Type: "string", Memory: 10, TTL: 60})
+
+	report := analyzer.Report()
+	if len(report.Prefixes) != 3 {
+		t.Fatalf("prefixes %d", len(report.Prefixes))
+	}
+	other := report.Prefixes[0]
+	if other.Pattern != OtherPattern || other.Count != 2 || other.Memory != 70 || other.NoExpireCount != 1 || other.Types["hash"] != 1 {
+		t.Fatalf("other %+v", other)
+	}
+	if report.Prefixes[1].Pattern != "a:*" || report.Prefixes[1].Count != 2 {
+		t.Fatalf("prefixes %+v", report.Prefixes[1])
+	}
+}
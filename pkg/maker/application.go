@@ -243,6 +243,9 @@ func (this_ *Application) Remove(modelType *modelers.Type, modelName string, isP
 	if exist {
 		err = os.RemoveAll(modelPath)
 	}
+	if !isPack {
+		_ = os.Remove(strings.TrimSuffix(modelPath, ".yml") + testFileSuffix)
+	}
 
 	this_.removeElement(modelType, element.Key)
 
@@ -306,6 +309,15 @@ func (this_ *Application) Rename(modelType *modelers.Type, oldModelName string,
 	if err != nil {
 		return
 	}
+	if !isPack {
+		oldTestPath := strings.TrimSuffix(oldModelPath, ".yml") + testFileSuffix
+		if testExist, _ := util.PathExists(oldTestPath); testExist {
+			err = os.Rename(oldTestPath, strings.TrimSuffix(newModelPath, ".yml")+testFileSuffix)
+			if err != nil {
+				return
+			}
+		}
+	}
 
 	this_.removeElement(modelType, oldElement.Key)
 
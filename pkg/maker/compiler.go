@@ -295,12 +295,17 @@ func (this_ *Compiler) BindComponent(componentType, name string, create func() (
 		return
 	}
 
-	var scriptVar = componentType
-	if name != "default" {
+	err = this_.setScriptVar(getComponentScriptVar(componentType, name), component)
+
+	return
+}
+
+// getComponentScriptVar 组件 在脚本中的 变量名，默认组件 为 类型，其它为 类型_名称，如：db、db_2
+func getComponentScriptVar(componentType, name string) (scriptVar string) {
+	scriptVar = componentType
+	if name != "" && name != "default" {
 		scriptVar = componentType + "_" + name
 	}
-	err = this_.setScriptVar(scriptVar, component)
-
 	return
 }
 
@@ -33,10 +33,13 @@ func (this_ *Script) CompileScript(script string) (compileProgram *CompileProgra
 }
 
 type CompileProgram struct {
+	key        string // 模型 key 如：service/user/insert
 	script     *Script
 	code       string
 	program    *goja.Program
 	astProgram *ast.Program
+	debugLines []int    // 调试脚本 可断点的行
+	debugNames []string // 调试脚本 声明的变量
 }
 
 func (this_ *CompilerMethod) CompileMethod(method *CompilerMethod) (res *CompilerMethodResult, err error) {
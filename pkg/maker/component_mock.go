@@ -0,0 +1,223 @@
+package maker
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/team-ide/go-tool/util"
+	"go.uber.org/zap"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ComponentMock 组件模拟，按 录制的响应 返回
+type ComponentMock struct {
+	Component string        `json:"component,omitempty" yaml:"component,omitempty"` // 组件 脚本变量名，如：db、db_2、redis
+	Method    string        `json:"method,omitempty" yaml:"method,omitempty"`       // 方法名，如：selectOne、get
+	Args      []interface{} `json:"args,omitempty" yaml:"args,omitempty"`           // 参数，为空 则匹配任意参数
+	Result    interface{}   `json:"result,omitempty" yaml:"result,omitempty"`       // 返回值
+	Error     string        `json:"error,omitempty" yaml:"error,omitempty"`         // 返回异常
+}
+
+func hasComponentMock(mocks []*ComponentMock, scriptVar string) bool {
+	for _, one := range mocks {
+		if one.Component == scriptVar {
+			return true
+		}
+	}
+	return false
+}
+
+func getComponentMockScriptVars(mocks []*ComponentMock) (scriptVars []string) {
+	cache := make(map[string]bool)
+	for _, one := range mocks {
+		if one.Component == "" || cache[one.Component] {
+			continue
+		}
+		cache[one.Component] = true
+		scriptVars = append(scriptVars, one.Component)
+	}
+	return
+}
+
+// newComponentMockContext 组件 模拟 脚本上下文，同一方法 多个响应 按调用顺序 依次返回，用完后 最后一个 重复返回
+func newComponentMockContext(scriptVar string, mocks []*ComponentMock) (ctx map[string]interface{}) {
+	ctx = make(map[string]interface{})
+	methodMocks := make(map[string][]*ComponentMock)
+	for _, one := range mocks {
+		if one.Component != scriptVar || one.Method == "" {
+			continue
+		}
+		method := util.FirstToLower(one.Method)
+		methodMocks[method] = append(methodMocks[method], one)
+	}
+	for method, list := range methodMocks {
+		invoker := &componentMockInvoker{
+			scriptVar: scriptVar,
+			method:    method,
+			mocks:     list,
+			used:      make(map[*ComponentMock]bool),
+		}
+		ctx[method] = invoker.invoke
+		ctx[strings.ToUpper(method[:1])+method[1:]] = invoker.invoke
+	}
+	return
+}
+
+type componentMockInvoker struct {
+	scriptVar string
+	method    string
+	mocks     []*ComponentMock
+	used      map[*ComponentMock]bool
+	lock      sync.Mutex
+}
+
+func (this_ *componentMockInvoker) invoke(args ...interface{}) (res interface{}, err error) {
+	this_.lock.Lock()
+	defer this_.lock.Unlock()
+
+	// 参数 匹配的 优先，其次 是 未设置参数的
+	var matches []*ComponentMock
+	for _, one := range this_.mocks {
+		if one.Args != nil && jsonEqual(one.Args, args) {
+			matches = append(matches, one)
+		}
+	}
+	if len(matches) == 0 {
+		for _, one := range this_.mocks {
+			if one.Args == nil {
+				matches = append(matches, one)
+			}
+		}
+	}
+	if len(matches) == 0 {
+		err = errors.New("component [" + this_.scriptVar + "] method [" + this_.method + "] mock not found")
+		util.Logger.Error("component mock invoke error", zap.Any("args", args), zap.Error(err))
+		return
+	}
+	mock := matches[len(matches)-1]
+	for _, one := range matches {
+		if !this_.used[one] {
+			mock = one
+			break
+		}
+	}
+	this_.used[mock] = true
+
+	if mock.Error != "" {
+		err = errors.New(mock.Error)
+		return
+	}
+	res = mock.Result
+	return
+}
+
+// NewComponentRecorder 组件 调用记录器，记录的调用 可以直接作为 ComponentMock 使用
+func NewComponentRecorder() *ComponentRecorder {
+	return &ComponentRecorder{}
+}
+
+type ComponentRecorder struct {
+	calls []*ComponentMock
+	lock  sync.Mutex
+}
+
+func (this_ *ComponentRecorder) GetCalls() (calls []*ComponentMock) {
+	this_.lock.Lock()
+	defer this_.lock.Unlock()
+
+	calls = append(calls, this_.calls...)
+	return
+}
+
+func (this_ *ComponentRecorder) record(call *ComponentMock) {
+	this_.lock.Lock()
+	defer this_.lock.Unlock()
+
+	this_.calls = append(this_.calls, call)
+}
+
+// wrap 将 组件 上下文中的 方法 包装，调用时 记录 参数 及 响应
+func (this_ *ComponentRecorder) wrap(scriptVar string, component interface{}) interface{} {
+	ctx, ok := component.(map[string]interface{})
+	if !ok {
+		return component
+	}
+	var names []string
+	for name := range ctx {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	wrapped := make(map[string]interface{})
+	for _, name := range names {
+		value := ctx[name]
+		vOf := reflect.ValueOf(value)
+		if value == nil || vOf.Kind() != reflect.Func {
+			wrapped[name] = value
+			continue
+		}
+		method := util.FirstToLower(name)
+		wrapped[name] = reflect.MakeFunc(vOf.Type(), func(in []reflect.Value) (out []reflect.Value) {
+			if vOf.Type().IsVariadic() {
+				out = vOf.CallSlice(in)
+			} else {
+				out = vOf.Call(in)
+			}
+			call := &ComponentMock{
+				Component: scriptVar,
+				Method:    method,
+				Args:      []interface{}{},
+			}
+			for i, one := range in {
+				if vOf.Type().IsVariadic() && i == len(in)-1 {
+					for n := 0; n < one.Len(); n++ {
+						call.Args = append(call.Args, one.Index(n).Interface())
+					}
+					continue
+				}
+				call.Args = append(call.Args, one.Interface())
+			}
+			for _, one := range out {
+				if !one.IsValid() || !one.CanInterface() {
+					continue
+				}
+				if e, isErr := one.Interface().(error); isErr {
+					if e != nil {
+						call.Error = e.Error()
+					}
+					continue
+				}
+				if one.Type().Implements(errorType) {
+					continue
+				}
+				call.Result = one.Interface()
+			}
+			this_.record(call)
+			return
+		}).Interface()
+	}
+	return wrapped
+}
+
+var (
+	errorType = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// jsonEqual 转为 JSON 后比较，避免 数字类型 等差异
+func jsonEqual(a, b interface{}) bool {
+	aBs, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	bBs, err := json.Marshal(b)
+	if err != nil {
+		return false
+	}
+	var aV, bV interface{}
+	if json.Unmarshal(aBs, &aV) != nil || json.Unmarshal(bBs, &bV) != nil {
+		return false
+	}
+	return reflect.DeepEqual(aV, bV)
+}
@@ -0,0 +1,524 @@
+package maker
+
+import (
+	"errors"
+	"fmt"
+	"github.com/dop251/goja"
+	"github.com/dop251/goja/ast"
+	"github.com/team-ide/go-tool/util"
+	"go.uber.org/zap"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	// debugHookName 调试时 插入到每个语句前的 钩子函数名称
+	debugHookName = "__maker_debug"
+
+	DebugActionContinue = "continue"
+	DebugActionStepOver = "stepOver"
+	DebugActionStepInto = "stepInto"
+	DebugActionStepOut  = "stepOut"
+	DebugActionStop     = "stop"
+
+	debugModeRun      = 0
+	debugModeStepInto = 1
+	debugModeStepOver = 2
+	debugModeStepOut  = 3
+)
+
+var (
+	DebugStoppedError = errors.New("debug is stopped")
+)
+
+// CompileDebugScript 编译 带调试钩子的 脚本，每个语句前插入钩子调用，用于 断点、单步
+func (this_ *Script) CompileDebugScript(script string) (compileProgram *CompileProgram, err error) {
+	runScript := `(function (){
+` + script + `
+})()`
+	astProgram, err := goja.Parse("", runScript)
+	if err != nil {
+		util.Logger.Error("compile debug script parse error", zap.Any("error", err))
+		return
+	}
+	function := astProgram.Body[0].(*ast.ExpressionStatement).Expression.(*ast.CallExpression).Callee.(*ast.FunctionLiteral)
+
+	instrument := &debugInstrument{
+		code:      runScript,
+		nameCache: make(map[string]bool),
+	}
+	instrument.statements(function.Body.List)
+
+	debugCode := instrument.toCode()
+	program, err := goja.Compile("", debugCode, false)
+	if err != nil {
+		util.Logger.Error("compile debug script error", zap.Any("error", err))
+		return
+	}
+
+	compileProgram = &CompileProgram{
+		code:       debugCode,
+		program:    program,
+		script:     this_,
+		astProgram: astProgram,
+		debugLines: instrument.lines,
+		debugNames: instrument.names,
+	}
+
+	return
+}
+
+type debugInstrument struct {
+	code      string
+	inserts   []*debugInsert
+	lines     []int
+	names     []string
+	nameCache map[string]bool
+}
+
+// debugInsert 插入到 offset 处的 代码，钩子 或 包装 单语句的 大括号
+type debugInsert struct {
+	offset int
+	text   string
+}
+
+func (this_ *debugInstrument) statements(list []ast.Statement) {
+	for _, one := range list {
+		this_.statement(one)
+	}
+}
+
+func (this_ *debugInstrument) statement(statement ast.Statement) {
+	switch s := statement.(type) {
+	case *ast.BlockStatement:
+		this_.statements(s.List)
+		return
+	case *ast.EmptyStatement, *ast.FunctionDeclaration, *ast.ClassDeclaration:
+		return
+	}
+	offset := this_.statementStart(statement)
+	if offset >= 0 && offset <= len(this_.code) {
+		// 包装的 function 头 占用第一行，换行数 即为 脚本中的行号（从 1 开始）
+		line := strings.Count(this_.code[:offset], "\n")
+		this_.lines = append(this_.lines, line)
+		this_.inserts = append(this_.inserts, &debugInsert{
+			offset: offset,
+			text:   fmt.Sprintf(";%s(%d, function(__expression){return eval(__expression)});", debugHookName, line),
+		})
+	}
+	this_.children(statement)
+}
+
+// children 插入 语句内部 子语句的 钩子
+func (this_ *debugInstrument) children(statement ast.Statement) {
+	switch s := statement.(type) {
+	case *ast.VariableStatement:
+		this_.bindings(s.List)
+	case *ast.LexicalDeclaration:
+		this_.bindings(s.List)
+	case *ast.IfStatement:
+		this_.body(s.Consequent)
+		if s.Alternate != nil {
+			this_.body(s.Alternate)
+		}
+	case *ast.ForStatement:
+		if v, ok := s.Initializer.(*ast.ForLoopInitializerVarDeclList); ok {
+			this_.bindings(v.List)
+		}
+		if v, ok := s.Initializer.(*ast.ForLoopInitializerLexicalDecl); ok {
+			this_.bindings(v.LexicalDeclaration.List)
+		}
+		this_.body(s.Body)
+	case *ast.ForInStatement:
+		this_.body(s.Body)
+	case *ast.ForOfStatement:
+		this_.body(s.Body)
+	case *ast.WhileStatement:
+		this_.body(s.Body)
+	case *ast.DoWhileStatement:
+		this_.body(s.Body)
+	case *ast.LabelledStatement:
+		// 标签 后 的 循环 不能 包装，否则 continue 标签 失效
+		this_.children(s.Statement)
+	case *ast.WithStatement:
+		this_.body(s.Body)
+	case *ast.SwitchStatement:
+		for _, c := range s.Body {
+			this_.statements(c.Consequent)
+		}
+	case *ast.TryStatement:
+		if s.Body != nil {
+			this_.statements(s.Body.List)
+		}
+		if s.Catch != nil && s.Catch.Body != nil {
+			this_.statements(s.Catch.Body.List)
+		}
+		if s.Finally != nil {
+			this_.statements(s.Finally.List)
+		}
+	}
+}
+
+// body 块语句 直接插入钩子，单语句（如 else if）用 大括号 包装 后 插入
+func (this_ *debugInstrument) body(statement ast.Statement) {
+	switch s := statement.(type) {
+	case *ast.BlockStatement:
+		this_.statements(s.List)
+		return
+	case *ast.EmptyStatement, *ast.FunctionDeclaration, *ast.ClassDeclaration:
+		return
+	}
+	start := this_.statementStart(statement)
+	end := this_.statementEnd(statement)
+	if start < 0 || end > len(this_.code) || start >= end {
+		this_.children(statement)
+		return
+	}
+	this_.inserts = append(this_.inserts, &debugInsert{offset: start, text: "{"})
+	this_.inserts = append(this_.inserts, &debugInsert{offset: end, text: "}"})
+	this_.statement(statement)
+}
+
+// statementStart 语句 开始 位置，解析器 未 记录 if 的 位置，从 条件 向前 查找
+func (this_ *debugInstrument) statementStart(statement ast.Statement) (start int) {
+	start = int(statement.Idx0()) - 1
+	if s, ok := statement.(*ast.IfStatement); ok && s.If == 0 {
+		start = strings.LastIndex(this_.code[:int(s.Test.Idx0())-1], "if")
+	}
+	return
+}
+
+// statementEnd 语句 结束 位置，表达式 语句 的 Idx1 不含 分号，需要 包含 在 大括号 内
+func (this_ *debugInstrument) statementEnd(statement ast.Statement) (end int) {
+	end = int(statement.Idx1()) - 1
+	for i := end; i < len(this_.code); i++ {
+		switch this_.code[i] {
+		case ' ', '\t':
+			continue
+		case ';':
+			end = i + 1
+		}
+		break
+	}
+	return
+}
+
+func (this_ *debugInstrument) bindings(list []*ast.Binding) {
+	for _, one := range list {
+		if identifier, ok := one.Target.(*ast.Identifier); ok {
+			name := identifier.Name.String()
+			if this_.nameCache[name] {
+				continue
+			}
+			this_.nameCache[name] = true
+			this_.names = append(this_.names, name)
+		}
+	}
+}
+
+func (this_ *debugInstrument) toCode() (code string) {
+	// 同一位置 按 记录顺序 插入，先 大括号 后 钩子
+	sort.SliceStable(this_.inserts, func(i, j int) bool {
+		return this_.inserts[i].offset < this_.inserts[j].offset
+	})
+	var last int
+	for _, one := range this_.inserts {
+		code += this_.code[last:one.offset]
+		code += one.text
+		last = one.offset
+	}
+	code += this_.code[last:]
+	return
+}
+
+// NewDebugger 新建 调试器，onPause 在 脚本 暂停时 回调
+func NewDebugger(onPause func(pause *DebugPause)) (debugger *Debugger) {
+	debugger = &Debugger{
+		breakpoints: make(map[string]map[int]bool),
+		command:     make(chan *debugCommand),
+		OnPause:     onPause,
+	}
+	return
+}
+
+// Debugger 服务、函数 脚本调试器，一个调试器 同一时间只调试一次调用
+type Debugger struct {
+	breakpoints map[string]map[int]bool
+	lock        sync.Mutex
+	commandLock sync.Mutex
+	evaluating  bool
+	mode        int
+	stepDepth   int
+	depth       int
+	stopped     bool
+	paused      *DebugPause
+	command     chan *debugCommand
+	OnPause     func(pause *DebugPause)
+}
+
+// DebugPause 暂停时 的 位置 及 变量
+type DebugPause struct {
+	Key    string                 `json:"key"`
+	Line   int                    `json:"line"`
+	Depth  int                    `json:"depth"`
+	Args   map[string]interface{} `json:"args"`
+	Vars   map[string]interface{} `json:"vars"`
+	Locals map[string]interface{} `json:"locals"`
+	Ctx    interface{}            `json:"ctx"`
+}
+
+type debugCommand struct {
+	action     string
+	expression string
+	result     chan *DebugEvaluateResult
+}
+
+// DebugEvaluateResult 暂停时 表达式 求值结果
+type DebugEvaluateResult struct {
+	Value interface{} `json:"value"`
+	Error string      `json:"error,omitempty"`
+}
+
+// SetBreakpoints 设置 某个 服务、函数 的断点，key 如：service/user/insert
+func (this_ *Debugger) SetBreakpoints(key string, lines []int) {
+	this_.lock.Lock()
+	defer this_.lock.Unlock()
+
+	cache := make(map[int]bool)
+	for _, line := range lines {
+		cache[line] = true
+	}
+	this_.breakpoints[key] = cache
+}
+
+func (this_ *Debugger) GetBreakpoints() (res map[string][]int) {
+	this_.lock.Lock()
+	defer this_.lock.Unlock()
+
+	res = make(map[string][]int)
+	for key, cache := range this_.breakpoints {
+		var lines []int
+		for line := range cache {
+			lines = append(lines, line)
+		}
+		sort.Ints(lines)
+		res[key] = lines
+	}
+	return
+}
+
+func (this_ *Debugger) GetPaused() (pause *DebugPause) {
+	this_.lock.Lock()
+	defer this_.lock.Unlock()
+
+	pause = this_.paused
+	return
+}
+
+func (this_ *Debugger) IsStopped() bool {
+	this_.lock.Lock()
+	defer this_.lock.Unlock()
+
+	return this_.stopped
+}
+
+// Resume 继续执行，action 为 continue、stepOver、stepInto、stepOut、stop
+func (this_ *Debugger) Resume(action string) (err error) {
+	switch action {
+	case DebugActionContinue, DebugActionStepOver, DebugActionStepInto, DebugActionStepOut:
+	case DebugActionStop:
+		this_.Stop()
+		return
+	default:
+		err = errors.New("debug action [" + action + "] not support")
+		return
+	}
+	this_.commandLock.Lock()
+	defer this_.commandLock.Unlock()
+
+	this_.lock.Lock()
+	paused := this_.paused
+	this_.paused = nil
+	this_.lock.Unlock()
+	if paused == nil {
+		err = errors.New("debug is not paused")
+		return
+	}
+	this_.command <- &debugCommand{action: action}
+	return
+}
+
+// Evaluate 暂停时 在当前作用域 执行表达式
+func (this_ *Debugger) Evaluate(expression string) (res *DebugEvaluateResult, err error) {
+	this_.commandLock.Lock()
+	defer this_.commandLock.Unlock()
+
+	if this_.GetPaused() == nil {
+		err = errors.New("debug is not paused")
+		return
+	}
+	command := &debugCommand{
+		expression: expression,
+		result:     make(chan *DebugEvaluateResult, 1),
+	}
+	this_.command <- command
+	res = <-command.result
+	return
+}
+
+// Stop 停止调试，暂停中的脚本 将被中断
+func (this_ *Debugger) Stop() {
+	this_.commandLock.Lock()
+	defer this_.commandLock.Unlock()
+
+	this_.lock.Lock()
+	this_.stopped = true
+	paused := this_.paused
+	this_.paused = nil
+	this_.lock.Unlock()
+
+	if paused != nil {
+		this_.command <- &debugCommand{action: DebugActionStop}
+	}
+}
+
+func (this_ *Debugger) enter() {
+	this_.lock.Lock()
+	defer this_.lock.Unlock()
+	this_.depth++
+}
+
+func (this_ *Debugger) exit() {
+	this_.lock.Lock()
+	defer this_.lock.Unlock()
+	this_.depth--
+}
+
+func (this_ *Debugger) setEvaluating(evaluating bool) {
+	this_.lock.Lock()
+	defer this_.lock.Unlock()
+	this_.evaluating = evaluating
+}
+
+func (this_ *Debugger) shouldPause(key string, line int) (depth int, should bool) {
+	this_.lock.Lock()
+	defer this_.lock.Unlock()
+
+	depth = this_.depth
+	if this_.evaluating {
+		return
+	}
+	if this_.breakpoints[key] != nil && this_.breakpoints[key][line] {
+		should = true
+		return
+	}
+	switch this_.mode {
+	case debugModeStepInto:
+		should = true
+	case debugModeStepOver:
+		should = depth <= this_.stepDepth
+	case debugModeStepOut:
+		should = depth < this_.stepDepth
+	}
+	return
+}
+
+func (this_ *Debugger) newHook(p *CompileProgram, invokeData *InvokeData) func(line int, eval func(string) (interface{}, error)) {
+	return func(line int, eval func(string) (interface{}, error)) {
+		if this_.IsStopped() {
+			invokeData.script.vm.Interrupt(DebugStoppedError)
+			return
+		}
+		depth, should := this_.shouldPause(p.key, line)
+		if !should {
+			return
+		}
+
+		pause := &DebugPause{
+			Key:    p.key,
+			Line:   line,
+			Depth:  depth,
+			Args:   make(map[string]interface{}),
+			Vars:   make(map[string]interface{}),
+			Locals: make(map[string]interface{}),
+		}
+		for _, one := range invokeData.GetArgs() {
+			pause.Args[one.Name] = debugEvalName(eval, one.Name)
+		}
+		for _, one := range invokeData.GetVars() {
+			pause.Vars[one.Name] = debugEvalName(eval, one.Name)
+		}
+		for _, name := range p.debugNames {
+			pause.Locals[name] = debugEvalName(eval, name)
+		}
+		pause.Ctx = debugEvalName(eval, "ctx")
+
+		this_.lock.Lock()
+		this_.paused = pause
+		this_.mode = debugModeRun
+		this_.lock.Unlock()
+
+		util.Logger.Debug("debug paused", zap.Any("key", p.key), zap.Any("line", line))
+		if this_.OnPause != nil {
+			this_.OnPause(pause)
+		}
+
+		for command := range this_.command {
+			if command.result != nil {
+				res := &DebugEvaluateResult{}
+				this_.setEvaluating(true)
+				v, err := eval(command.expression)
+				this_.setEvaluating(false)
+				if err != nil {
+					res.Error = err.Error()
+				} else {
+					res.Value = debugValue(v)
+				}
+				command.result <- res
+				continue
+			}
+
+			this_.lock.Lock()
+			this_.stepDepth = depth
+			switch command.action {
+			case DebugActionStepInto:
+				this_.mode = debugModeStepInto
+			case DebugActionStepOver:
+				this_.mode = debugModeStepOver
+			case DebugActionStepOut:
+				this_.mode = debugModeStepOut
+			default:
+				this_.mode = debugModeRun
+			}
+			this_.lock.Unlock()
+
+			if command.action == DebugActionStop {
+				invokeData.script.vm.Interrupt(DebugStoppedError)
+			}
+			return
+		}
+	}
+}
+
+func debugEvalName(eval func(string) (interface{}, error), name string) (value interface{}) {
+	v, err := eval("typeof " + name + " === 'undefined' ? undefined : " + name)
+	if err != nil {
+		return
+	}
+	value = debugValue(v)
+	return
+}
+
+// debugValue 函数等 无法序列化的值 转为 描述字符串
+func debugValue(value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+	switch reflect.TypeOf(value).Kind() {
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		return "[" + reflect.TypeOf(value).Kind().String() + "]"
+	}
+	return value
+}
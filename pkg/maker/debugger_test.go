@@ -0,0 +1,118 @@
+package maker
+
+import (
+	"github.com/dop251/goja"
+	"testing"
+)
+
+func TestDebugger(t *testing.T) {
+	script := &Script{
+		vm:          goja.New(),
+		dataContext: make(map[string]interface{}),
+	}
+	code := `var a = 1
+if (a > 0) {
+  a++
+}
+for (let i = 0; i < 2; i++) {
+  a += i
+}
+return a + userId`
+	p, err := script.CompileDebugScript(code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.key = "service/test"
+
+	var pauses []*DebugPause
+	var debugger *Debugger
+	debugger = NewDebugger(func(pause *DebugPause) {
+		pauses = append(pauses, pause)
+		go func() {
+			res, e := debugger.Evaluate("a * 10")
+			if e != nil || res.Error != "" {
+				t.Error("evaluate error", e, res)
+			}
+			if pause.Line == 3 {
+				_ = debugger.Resume(DebugActionStepOver)
+			} else {
+				_ = debugger.Resume(DebugActionContinue)
+			}
+		}()
+	})
+	debugger.SetBreakpoints("service/test", []int{3})
+
+	invokeData := &InvokeData{
+		script: script,
+	}
+	_ = script.Set("userId", 100)
+	_ = script.Set(debugHookName, debugger.newHook(p, invokeData))
+	debugger.enter()
+	v, err := script.vm.RunProgram(p.program)
+	debugger.exit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.ToInteger() != 103 {
+		t.Fatal("result error", v.Export())
+	}
+	if len(pauses) != 2 || pauses[0].Line != 3 || pauses[1].Line != 5 {
+		t.Fatal("pauses error", pauses)
+	}
+	if pauses[1].Locals["a"] != int64(2) {
+		t.Fatal("locals error", pauses[1].Locals)
+	}
+}
+
+func TestDebuggerElseIf(t *testing.T) {
+	script := &Script{
+		vm:          goja.New(),
+		dataContext: make(map[string]interface{}),
+	}
+	code := `var a = 0
+if (userId > 100) a = 1; else if (userId > 10)
+  a = 2
+else a = 3;
+for (var i = 0; i < 2; i++) a++
+return a`
+	p, err := script.CompileDebugScript(code)
+	if err != nil {
+		t.Fatal(err, p)
+	}
+	p.key = "service/test"
+
+	var lines []int
+	var debugger *Debugger
+	debugger = NewDebugger(func(pause *DebugPause) {
+		lines = append(lines, pause.Line)
+		go func() {
+			_ = debugger.Resume(DebugActionContinue)
+		}()
+	})
+	debugger.SetBreakpoints("service/test", []int{2, 3, 4, 5})
+
+	invokeData := &InvokeData{
+		script: script,
+	}
+	_ = script.Set("userId", 50)
+	_ = script.Set(debugHookName, debugger.newHook(p, invokeData))
+	debugger.enter()
+	v, err := script.vm.RunProgram(p.program)
+	debugger.exit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.ToInteger() != 4 {
+		t.Fatal("result error", v.Export())
+	}
+	// else if 所在行 与 其 分支 都 可以 断点，循环 单语句 每次 都 暂停
+	expected := []int{2, 2, 3, 5, 5, 5}
+	if len(lines) != len(expected) {
+		t.Fatal("pauses error", lines)
+	}
+	for i := range expected {
+		if lines[i] != expected[i] {
+			t.Fatal("pauses error", lines)
+		}
+	}
+}
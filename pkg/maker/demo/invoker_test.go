@@ -9,8 +9,10 @@ import (
 	"github.com/team-ide/go-tool/zookeeper"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"strings"
 	"sync"
 	"teamide/pkg/maker"
+	"teamide/pkg/maker/modelers"
 	"testing"
 	"time"
 )
@@ -113,3 +115,31 @@ func TestInvokerZk(t *testing.T) {
 	}()
 	wait.Wait()
 }
+
+// 调试 与 非调试 调用，service 调用 storage 的 参数 绑定 一致
+func TestInvokerDebugArgs(t *testing.T) {
+	app, err := LoadDemoApp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mocks := []*maker.ComponentMock{
+		{Component: "db", Method: "delete", Result: 1},
+		{Component: "redis", Method: "del"},
+	}
+	var calls []string
+	for _, debugger := range []*maker.Debugger{nil, maker.NewDebugger(func(pause *maker.DebugPause) {})} {
+		options := &maker.InvokerOptions{Debugger: debugger, Mocks: mocks}
+		_, recorder, err := maker.InvokeModel(app, modelers.TypeService, "user/delete", map[string]interface{}{"userId": 1}, options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		bs, _ := json.Marshal(recorder.GetCalls())
+		calls = append(calls, string(bs))
+	}
+	if calls[0] != calls[1] {
+		t.Fatalf("calls not equal\n%s\n%s", calls[0], calls[1])
+	}
+	if !strings.Contains(calls[0], `{"userId":1}`) {
+		t.Fatalf("storage args error %s", calls[0])
+	}
+}
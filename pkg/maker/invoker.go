@@ -10,8 +10,16 @@ import (
 )
 
 func NewInvoker(compiler *Compiler) (runner *Invoker, err error) {
+	return NewInvokerByOptions(compiler, nil)
+}
+
+func NewInvokerByOptions(compiler *Compiler, options *InvokerOptions) (runner *Invoker, err error) {
+	if options == nil {
+		options = &InvokerOptions{}
+	}
 	runner = &Invoker{
 		Compiler: compiler,
+		options:  options,
 	}
 
 	err = runner.init()
@@ -19,8 +27,15 @@ func NewInvoker(compiler *Compiler) (runner *Invoker, err error) {
 	return
 }
 
+type InvokerOptions struct {
+	Debugger *Debugger          // 调试器，不为空 则 编译带调试钩子的脚本
+	Mocks    []*ComponentMock   // 组件模拟，被模拟的组件 不会创建 真实连接
+	Recorder *ComponentRecorder // 记录 组件调用 及 响应
+}
+
 type Invoker struct {
 	*Compiler
+	options *InvokerOptions
 }
 
 type Error struct {
@@ -80,7 +95,7 @@ func (this_ *Invoker) init() (err error) {
 
 	// 初始化服务
 	for _, one := range this_.GetConfigRedisList() {
-		err = this_.BindComponent("redis", one.Name, func() (component interface{}, err error) {
+		err = this_.bindComponent("redis", one.Name, func() (component interface{}, err error) {
 			return NewComponentRedis(one)
 		})
 		if err != nil {
@@ -88,7 +103,7 @@ func (this_ *Invoker) init() (err error) {
 		}
 	}
 	for _, one := range this_.GetConfigDbList() {
-		err = this_.BindComponent("db", one.Name, func() (component interface{}, err error) {
+		err = this_.bindComponent("db", one.Name, func() (component interface{}, err error) {
 			return NewComponentDb(one)
 		})
 		if err != nil {
@@ -96,7 +111,7 @@ func (this_ *Invoker) init() (err error) {
 		}
 	}
 	for _, one := range this_.GetConfigZkList() {
-		err = this_.BindComponent("zk", one.Name, func() (component interface{}, err error) {
+		err = this_.bindComponent("zk", one.Name, func() (component interface{}, err error) {
 			return NewComponentZk(one)
 		})
 		if err != nil {
@@ -104,7 +119,7 @@ func (this_ *Invoker) init() (err error) {
 		}
 	}
 	for _, one := range this_.GetConfigEsList() {
-		err = this_.BindComponent("es", one.Name, func() (component interface{}, err error) {
+		err = this_.bindComponent("es", one.Name, func() (component interface{}, err error) {
 			return NewComponentEs(one)
 		})
 		if err != nil {
@@ -112,7 +127,7 @@ func (this_ *Invoker) init() (err error) {
 		}
 	}
 	for _, one := range this_.GetConfigKafkaList() {
-		err = this_.BindComponent("kafka", one.Name, func() (component interface{}, err error) {
+		err = this_.bindComponent("kafka", one.Name, func() (component interface{}, err error) {
 			return NewComponentKafka(one)
 		})
 		if err != nil {
@@ -120,13 +135,23 @@ func (this_ *Invoker) init() (err error) {
 		}
 	}
 	for _, one := range this_.GetConfigMongodbList() {
-		err = this_.BindComponent("mongodb", one.Name, func() (component interface{}, err error) {
+		err = this_.bindComponent("mongodb", one.Name, func() (component interface{}, err error) {
 			return NewComponentMongodb(one)
 		})
 		if err != nil {
 			return
 		}
 	}
+	// 未配置的组件 也可以 被模拟
+	for _, scriptVar := range getComponentMockScriptVars(this_.options.Mocks) {
+		if _, find := this_.script.dataContext[scriptVar]; find {
+			continue
+		}
+		err = this_.bindComponentMock(scriptVar)
+		if err != nil {
+			return
+		}
+	}
 
 	err = this_.setScriptVar("storage", this_.storageContext)
 	if err != nil {
@@ -153,8 +178,50 @@ func (this_ *Invoker) init() (err error) {
 	return
 }
 
+func (this_ *Invoker) bindComponent(componentType, name string, create func() (component interface{}, err error)) (err error) {
+	scriptVar := getComponentScriptVar(componentType, name)
+	if hasComponentMock(this_.options.Mocks, scriptVar) {
+		err = this_.bindComponentMock(scriptVar)
+		return
+	}
+	err = this_.BindComponent(componentType, name, create)
+	if err != nil {
+		return
+	}
+	if this_.options.Recorder != nil {
+		err = this_.setScriptVar(scriptVar, this_.options.Recorder.wrap(scriptVar, this_.script.dataContext[scriptVar]))
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (this_ *Invoker) bindComponentMock(scriptVar string) (err error) {
+	var component interface{} = newComponentMockContext(scriptVar, this_.options.Mocks)
+	if this_.options.Recorder != nil {
+		component = this_.options.Recorder.wrap(scriptVar, component)
+	}
+	err = this_.setScriptVar(scriptVar, component)
+	return
+}
+
+// compileScript 设置了调试器 则编译 带调试钩子的脚本
+func (this_ *Invoker) compileScript(modelType *modelers.Type, name string, script string) (p *CompileProgram, err error) {
+	if this_.options.Debugger != nil {
+		p, err = this_.script.CompileDebugScript(script)
+	} else {
+		p, err = this_.script.CompileScript(script)
+	}
+	if err != nil {
+		return
+	}
+	p.key = modelType.Name + "/" + name
+	return
+}
+
 func (this_ *Invoker) BindFunc(f *modelers.FuncModel) (err error) {
-	this_.funcProgram[f.Name], err = this_.script.CompileScript(f.Func)
+	this_.funcProgram[f.Name], err = this_.compileScript(modelers.TypeFunc, f.Name, f.Func)
 	if err != nil {
 		util.Logger.Error("invoker bind func compile script error", zap.Any("name", f.Name), zap.Any("error", err))
 		return
@@ -171,14 +238,22 @@ func (this_ *Invoker) BindFunc(f *modelers.FuncModel) (err error) {
 	return
 }
 
+// bindArgModels 脚本 间 调用 与 编译 一致，第一个参数 为 ctx，调试、测试用例、普通 调用 相同
+func (this_ *Invoker) bindArgModels(args []*modelers.ArgModel) (argModels []*modelers.ArgModel) {
+	argModels = append(argModels, &modelers.ArgModel{Name: "ctx", Type: "context"})
+	argModels = append(argModels, args...)
+	return
+}
+
 func (this_ *Invoker) BindStorage(storage *modelers.StorageModel) (err error) {
-	this_.storageProgram[storage.Name], err = this_.script.CompileScript(storage.Func)
+	this_.storageProgram[storage.Name], err = this_.compileScript(modelers.TypeStorage, storage.Name, storage.Func)
 	if err != nil {
 		util.Logger.Error("invoker bind storage compile script error", zap.Any("name", storage.Name), zap.Any("error", err))
 		return
 	}
+	argModels := this_.bindArgModels(storage.Args)
 	var run = func(args ...interface{}) (res any, err error) {
-		data, err := this_.NewInvokeDataByArgs(storage.Args, args)
+		data, err := this_.NewInvokeDataByArgs(argModels, args)
 		if err != nil {
 			return
 		}
@@ -190,13 +265,14 @@ func (this_ *Invoker) BindStorage(storage *modelers.StorageModel) (err error) {
 }
 
 func (this_ *Invoker) BindService(service *modelers.ServiceModel) (err error) {
-	this_.serviceProgram[service.Name], err = this_.script.CompileScript(service.Func)
+	this_.serviceProgram[service.Name], err = this_.compileScript(modelers.TypeService, service.Name, service.Func)
 	if err != nil {
 		util.Logger.Error("invoker bind service compile script error", zap.Any("name", service.Name), zap.Any("error", err))
 		return
 	}
+	argModels := this_.bindArgModels(service.Args)
 	var run = func(args ...interface{}) (res any, err error) {
-		data, err := this_.NewInvokeDataByArgs(service.Args, args)
+		data, err := this_.NewInvokeDataByArgs(argModels, args)
 		if err != nil {
 			return
 		}
@@ -314,6 +390,24 @@ func (this_ *Invoker) InvokeStorage(storage *modelers.StorageModel, invokeData *
 	return
 }
 
+func (this_ *Invoker) InvokeFuncByName(name string, invokeData *InvokeData) (res interface{}, err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			err = errors.New("invoke func by name [" + name + "] error:" + fmt.Sprint(e))
+			util.Logger.Error("invoke func by name error", zap.Any("error", err))
+		}
+	}()
+
+	f := this_.GetFunc(name)
+	if f == nil {
+		err = errors.New("func [" + name + "] is not exist")
+		util.Logger.Error("invoke func by name error", zap.Any("error", err))
+		return
+	}
+	res, err = this_.InvokeFunc(f, invokeData)
+	return
+}
+
 func (this_ *Invoker) InvokeFunc(f *modelers.FuncModel, invokeData *InvokeData) (res interface{}, err error) {
 	if f == nil {
 		err = errors.New("invoke func error, func is null")
@@ -352,6 +446,15 @@ func (this_ *Invoker) InvokeFunc(f *modelers.FuncModel, invokeData *InvokeData)
 
 func (this_ *Invoker) InvokeProgram(from string, p *CompileProgram, invokeData *InvokeData) (res interface{}, err error) {
 
+	if this_.options.Debugger != nil {
+		this_.options.Debugger.enter()
+		defer this_.options.Debugger.exit()
+		err = invokeData.scriptSet(debugHookName, this_.options.Debugger.newHook(p, invokeData))
+		if err != nil {
+			return
+		}
+	}
+
 	//var res interface{}
 	v, err := invokeData.script.vm.RunProgram(p.program)
 	if err != nil {
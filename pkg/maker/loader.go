@@ -59,7 +59,7 @@ func (this_ *Application) loadFiles(parent *modelers.Element, modelType *modeler
 }
 
 func (this_ *Application) loadFile(parent *modelers.Element, modelType *modelers.Type, filePath string) (model interface{}, element *modelers.Element) {
-	if !(strings.HasSuffix(filePath, ".yml")) {
+	if !(strings.HasSuffix(filePath, ".yml")) || strings.HasSuffix(filePath, testFileSuffix) {
 		return
 	}
 
@@ -0,0 +1,346 @@
+package maker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/dop251/goja"
+	"github.com/team-ide/go-tool/util"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+	"os"
+	"strings"
+	"teamide/pkg/maker/modelers"
+	"time"
+)
+
+var (
+	// testFileSuffix 测试用例 文件后缀，保存在模型旁，如：service/user/insert.test.yml
+	testFileSuffix = ".test.yml"
+)
+
+// ModelTest 服务、函数 的 测试用例
+type ModelTest struct {
+	Cases []*TestCase `json:"cases,omitempty" yaml:"cases,omitempty"`
+}
+
+type TestCase struct {
+	Name   string                 `json:"name,omitempty" yaml:"name,omitempty"`
+	Args   map[string]interface{} `json:"args,omitempty" yaml:"args,omitempty"`     // 入参，按参数名称
+	Mocks  []*ComponentMock       `json:"mocks,omitempty" yaml:"mocks,omitempty"`   // 组件模拟
+	Expect *TestExpect            `json:"expect,omitempty" yaml:"expect,omitempty"` // 期望
+}
+
+type TestExpect struct {
+	Result interface{} `json:"result,omitempty" yaml:"result,omitempty"` // 期望返回值，转为 JSON 比较，为空 不比较
+	Error  string      `json:"error,omitempty" yaml:"error,omitempty"`   // 期望异常，异常信息 包含 即可，如：USER_IS_NULL
+}
+
+type ModelTestResult struct {
+	Key   string            `json:"key"`
+	Pass  int               `json:"pass"`
+	Fail  int               `json:"fail"`
+	Error string            `json:"error,omitempty"`
+	Cases []*TestCaseResult `json:"cases"`
+}
+
+type TestCaseResult struct {
+	Name    string           `json:"name"`
+	Pass    bool             `json:"pass"`
+	Result  interface{}      `json:"result,omitempty"`
+	Error   string           `json:"error,omitempty"`
+	Message string           `json:"message,omitempty"` // 失败原因
+	Use     int64            `json:"use"`
+	Calls   []*ComponentMock `json:"calls,omitempty"` // 组件调用记录
+}
+
+func checkTestModelType(modelType *modelers.Type) (err error) {
+	if modelType != modelers.TypeService && modelType != modelers.TypeFunc {
+		err = errors.New("model type [" + modelType.Name + "] not support test")
+		return
+	}
+	return
+}
+
+func (this_ *Application) getModelTestPath(modelType *modelers.Type, modelName string) (path string, err error) {
+	err = checkTestModelType(modelType)
+	if err != nil {
+		return
+	}
+	modelName = FormatName(modelName)
+	if modelName == "" {
+		err = errors.New("model name is empty")
+		return
+	}
+	path, err = this_.getModePath(modelType, modelName, false)
+	if err != nil {
+		return
+	}
+	path = strings.TrimSuffix(path, ".yml") + testFileSuffix
+	return
+}
+
+// GetModelTest 读取 模型的 测试用例，不存在 返回空
+func (this_ *Application) GetModelTest(modelType *modelers.Type, modelName string) (test *ModelTest, err error) {
+	path, err := this_.getModelTestPath(modelType, modelName)
+	if err != nil {
+		return
+	}
+	exist, err := util.PathExists(path)
+	if err != nil || !exist {
+		return
+	}
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	test = &ModelTest{}
+	err = yaml.Unmarshal(bs, test)
+	if err != nil {
+		util.Logger.Error("model test yaml to model error", zap.Any("path", path), zap.Error(err))
+		return
+	}
+	return
+}
+
+// SaveModelTest 保存 模型的 测试用例
+func (this_ *Application) SaveModelTest(modelType *modelers.Type, modelName string, test *ModelTest) (err error) {
+	path, err := this_.getModelTestPath(modelType, modelName)
+	if err != nil {
+		return
+	}
+	if test == nil {
+		test = &ModelTest{}
+	}
+	bs, err := yaml.Marshal(test)
+	if err != nil {
+		return
+	}
+
+	this_.doLocker.Lock()
+	defer this_.doLocker.Unlock()
+
+	err = os.WriteFile(path, bs, 0666)
+	return
+}
+
+// GetModelTestNames 查询 有测试用例的 模型名称
+func (this_ *Application) GetModelTestNames(modelType *modelers.Type) (names []string, err error) {
+	err = checkTestModelType(modelType)
+	if err != nil {
+		return
+	}
+	for _, one := range this_.getModelTypeItems(modelType) {
+		var path string
+		path, err = this_.getModelTestPath(modelType, one.GetName())
+		if err != nil {
+			return
+		}
+		if exist, _ := util.PathExists(path); exist {
+			names = append(names, one.GetName())
+		}
+	}
+	return
+}
+
+// RunModelTests 批量执行 测试用例，names 为空 执行该类型 所有的测试用例
+func RunModelTests(app *Application, modelType *modelers.Type, names []string) (results []*ModelTestResult, err error) {
+	if len(names) == 0 {
+		names, err = app.GetModelTestNames(modelType)
+		if err != nil {
+			return
+		}
+	}
+	for _, name := range names {
+		results = append(results, RunModelTest(app, modelType, name))
+	}
+	return
+}
+
+// RunModelTest 执行 某个模型的 所有测试用例，每个用例 使用独立的 Invoker 以隔离 组件模拟
+func RunModelTest(app *Application, modelType *modelers.Type, name string) (result *ModelTestResult) {
+	result = &ModelTestResult{
+		Key: modelType.Name + "/" + name,
+	}
+	test, err := app.GetModelTest(modelType, name)
+	if err != nil {
+		result.Error = err.Error()
+		return
+	}
+	if test == nil {
+		result.Error = "model [" + result.Key + "] test not found"
+		return
+	}
+	for i, one := range test.Cases {
+		caseResult := runTestCase(app, modelType, name, one)
+		if caseResult.Name == "" {
+			caseResult.Name = fmt.Sprintf("case %d", i+1)
+		}
+		if caseResult.Pass {
+			result.Pass++
+		} else {
+			result.Fail++
+		}
+		result.Cases = append(result.Cases, caseResult)
+	}
+	return
+}
+
+func runTestCase(app *Application, modelType *modelers.Type, name string, testCase *TestCase) (result *TestCaseResult) {
+	result = &TestCaseResult{
+		Name: testCase.Name,
+	}
+	startTime := time.Now()
+	defer func() {
+		if e := recover(); e != nil {
+			result.Pass = false
+			result.Message = fmt.Sprint(e)
+		}
+		result.Use = time.Now().UnixMilli() - startTime.UnixMilli()
+	}()
+
+	res, recorder, err := InvokeModel(app, modelType, name, testCase.Args, &InvokerOptions{
+		Mocks: testCase.Mocks,
+	})
+	if recorder != nil {
+		result.Calls = recorder.GetCalls()
+	}
+	result.Result = res
+	if err != nil {
+		result.Error = app.getInvokeErrorMessage(err)
+	}
+
+	expect := testCase.Expect
+	if expect == nil {
+		expect = &TestExpect{}
+	}
+	if expect.Error != "" {
+		if err == nil {
+			result.Message = "expect error [" + expect.Error + "] but not error"
+			return
+		}
+		if !strings.Contains(result.Error, expect.Error) {
+			result.Message = "expect error [" + expect.Error + "] but error [" + result.Error + "]"
+			return
+		}
+	} else if err != nil {
+		result.Message = "unexpected error [" + result.Error + "]"
+		return
+	}
+	if expect.Result != nil && !jsonEqual(expect.Result, res) {
+		expectBs, _ := json.Marshal(expect.Result)
+		resBs, _ := json.Marshal(res)
+		result.Message = "expect result [" + string(expectBs) + "] but result [" + string(resBs) + "]"
+		return
+	}
+	result.Pass = true
+	return
+}
+
+// InvokeModel 按参数名称 调用 服务、函数，返回 组件调用记录
+func InvokeModel(app *Application, modelType *modelers.Type, name string, args map[string]interface{}, options *InvokerOptions) (res interface{}, recorder *ComponentRecorder, err error) {
+	err = checkTestModelType(modelType)
+	if err != nil {
+		return
+	}
+	if options == nil {
+		options = &InvokerOptions{}
+	}
+	if options.Recorder == nil {
+		options.Recorder = NewComponentRecorder()
+	}
+	recorder = options.Recorder
+
+	compiler, err := NewCompiler(app)
+	if err != nil {
+		return
+	}
+	invoker, err := NewInvokerByOptions(compiler, options)
+	if err != nil {
+		return
+	}
+
+	var argModels []*modelers.ArgModel
+	if modelType == modelers.TypeService {
+		service := app.GetService(name)
+		if service == nil {
+			err = errors.New("service [" + name + "] is not exist")
+			return
+		}
+		argModels = service.Args
+	} else {
+		f := app.GetFunc(name)
+		if f == nil {
+			err = errors.New("func [" + name + "] is not exist")
+			return
+		}
+		argModels = f.Args
+	}
+	invokeData, err := invoker.NewInvokeData()
+	if err != nil {
+		return
+	}
+	if modelType == modelers.TypeService {
+		ctx := args["ctx"]
+		if ctx == nil {
+			ctx = map[string]interface{}{}
+		}
+		err = invokeData.AddVar("ctx", ctx, "context")
+		if err != nil {
+			return
+		}
+	}
+	for _, argModel := range argModels {
+		value := args[argModel.Name]
+		if value != nil {
+			// 结构体 参数 按 JSON 字符串 传入
+			if _, isStr := value.(string); !isStr {
+				var valueType *ValueType
+				valueType, err = invoker.GetValueType(argModel.Type)
+				if err != nil {
+					return
+				}
+				if valueType.Struct != nil {
+					var bs []byte
+					bs, err = json.Marshal(value)
+					if err != nil {
+						return
+					}
+					value = string(bs)
+				}
+			}
+		}
+		err = invokeData.AddVar(argModel.Name, value, argModel.Type)
+		if err != nil {
+			return
+		}
+	}
+
+	if modelType == modelers.TypeService {
+		res, err = invoker.InvokeServiceByName(name, invokeData)
+	} else {
+		res, err = invoker.InvokeFuncByName(name, invokeData)
+	}
+	return
+}
+
+// getInvokeErrorMessage 脚本中 抛出的 异常 如：throw error.USER_IS_NULL，信息中 带上 异常名称
+func (this_ *Application) getInvokeErrorMessage(err error) (message string) {
+	message = err.Error()
+	exception, ok := err.(*goja.Exception)
+	if !ok || exception.Value() == nil {
+		return
+	}
+	e, ok := exception.Value().Export().(*Error)
+	if !ok {
+		return
+	}
+	message = e.Error()
+	for name, value := range this_.errorContext {
+		if value == e {
+			message = name + " " + message
+			break
+		}
+	}
+	return
+}
@@ -0,0 +1,100 @@
+package mongoops
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func parseJSON(t *testing.T, text string) (res map[string]interface{}) {
+	if err := json.Unmarshal([]byte(text), &res); err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
+func TestSummarizeReplSet(t *testing.T) {
+	status := parseJSON(t, `{
+		"set": "rs0", "date": {"$date": "2024-01-01T00:00:10Z"},
+		"members": [
+			{"_id": 2, "name": "c:27017", "state": 2, "stateStr": "SECONDARY", "health": 1, "optimeDate": {"$date": "2024-01-01T00:00:02.500Z"}, "syncSourceHost": "a:27017", "pingMs": {"$numberLong": "3"}},
+			{"_id": 0, "name": "a:27017", "state": 1, "stateStr": "PRIMARY", "health": 1, "optimeDate": {"$date": "2024-01-01T00:00:10Z"}, "self": true},
+			{"_id": 1, "name": "b:27017", "state": 8, "stateStr": "(not reachable/healthy)", "health": 0, "optimeDate": {"$date": {"$numberLong": "0"}}, "lastHeartbeatMessage": "connection refused"}
+		]
+	}`)
+	res := SummarizeReplSet(status)
+	if res.Set != "rs0" || res.Primary != "a:27017" || len(res.Members) != 3 || res.Members[0].Name != "a:27017" {
+		t.Fatalf("replset %+v", res)
+	}
+	if res.MaxLagSeconds != 7.5 || res.Members[2].LagSeconds != 7.5 || res.Members[2].PingMs != 3 || res.Members[1].LagSeconds != 0 {
+		t.Fatalf("lag %+v %+v", res.Members[2], res.Members[1])
+	}
+	if res.Members[1].Message != "connection refused" || !res.Members[0].Self {
+		t.Fatalf("member %+v", res.Members[1])
+	}
+}
+
+func TestChunkDistribution(t *testing.T) {
+	collections := []map[string]interface{}{
+		parseJSON(t, `{"_id": "db.orders", "key": {"userId": "hashed"}, "uuid": {"$binary": {"base64": "AAA=", "subType": "04"}}}`),
+		parseJSON(t, `{"_id": "db.logs", "key": {"_id": 1}}`),
+		parseJSON(t, `{"_id": "db.old", "key": {"_id": 1}, "dropped": true}`),
+	}
+	counts := []map[string]interface{}{
+		parseJSON(t, `{"_id": {"coll": {"$binary": {"base64": "AAA=", "subType": "04"}}, "shard": "s0"}, "count": 3}`),
+		parseJSON(t, `{"_id": {"coll": {"$binary": {"base64": "AAA=", "subType": "04"}}, "shard": "s1"}, "count": 2}`),
+		parseJSON(t, `{"_id": {"coll": "db.logs", "shard": "s0"}, "count": 1}`),
+		parseJSON(t, `{"_id": {"coll": "db.old", "shard": "s0"}, "count": 9}`),
+	}
+	res := ChunkDistribution(collections, counts)
+	if len(res) != 2 || res[0].Ns != "db.logs" || res[0].Chunks != 1 || res[1].Chunks != 5 || res[1].Shards["s1"] != 2 {
+		t.Fatalf("chunks %+v", res)
+	}
+}
+
+func TestOps(t *testing.T) {
+	status := parseJSON(t, `{"inprog": [
+		{"opid": 12, "op": "query", "ns": "db.a", "secs_running": 1, "client": "1.1.1.1:1"},
+		{"opid": "s0:99", "op": "update", "ns": "db.b", "secs_running": {"$numberLong": "30"}, "client_s": "2.2.2.2:2", "waitingForLock": true}
+	]}`)
+	ops := SummarizeOps(status["inprog"].([]interface{}))
+	if len(ops) != 2 || ops[0].OpId != "s0:99" || ops[0].Client != "2.2.2.2:2" || !ops[0].WaitingForLock || ops[1].OpId != int64(12) {
+		t.Fatalf("ops %+v %+v", ops[0], ops[1])
+	}
+	for value, expected := range map[interface{}]interface{}{float64(12): int64(12), "13": int64(13), "s0:99": "s0:99"} {
+		if opId, err := ParseOpId(value); err != nil || opId != expected {
+			t.Fatalf("opid %v %v %v", value, opId, err)
+		}
+	}
+	if _, err := ParseOpId("abc"); err == nil {
+		t.Fatal("bad opid should error")
+	}
+}
+
+func TestUserCommand(t *testing.T) {
+	command, err := UserCommand(&UserSpec{User: "app", Password: "p", Roles: []*RoleRef{{Role: "readWrite"}, {Role: "read", Db: "other"}}}, "db", false)
+	if err != nil || command[0].Key != "createUser" || command[1].Key != "pwd" {
+		t.Fatalf("create %v %v", command, err)
+	}
+	bs, _ := json.Marshal(command.Map()["roles"])
+	if string(bs) != `[[{"Key":"role","Value":"readWrite"},{"Key":"db","Value":"db"}],[{"Key":"role","Value":"read"},{"Key":"db","Value":"other"}]]` {
+		t.Fatalf("roles %s", bs)
+	}
+	if _, err = UserCommand(&UserSpec{User: "app"}, "db", false); err == nil {
+		t.Fatal("create without password should error")
+	}
+	if _, err = UserCommand(&UserSpec{User: "app"}, "db", true); err == nil {
+		t.Fatal("empty update should error")
+	}
+	command, err = UserCommand(&UserSpec{User: "app", Password: "n"}, "db", true)
+	if err != nil || len(command) != 2 || command[0].Key != "updateUser" {
+		t.Fatalf("update %v %v", command, err)
+	}
+
+	command, err = RoleCommand(&RoleSpec{Role: "r", Privileges: []*Privilege{{Resource: &Resource{Db: "db", Collection: "c"}, Actions: []string{"find"}}, {Resource: &Resource{Cluster: true}, Actions: []string{"serverStatus"}}}}, "db", false)
+	if err != nil || len(command) != 3 || command[2].Key != "roles" {
+		t.Fatalf("role %v %v", command, err)
+	}
+	if _, err = RoleCommand(&RoleSpec{Role: "r", Privileges: []*Privilege{{Resource: &Resource{}}}}, "db", false); err == nil {
+		t.Fatal("privilege without actions should error")
+	}
+}
@@ -0,0 +1,78 @@
+package mongoops
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// OpInfo currentOp 中 一个 操作 的 摘要
+type OpInfo struct {
+	OpId           interface{} `json:"opid"` // mongod 为 数字，mongos 为 "shard:opid"
+	Op             string      `json:"op"`
+	Ns             string      `json:"ns,omitempty"`
+	Desc           string      `json:"desc,omitempty"`
+	Client         string      `json:"client,omitempty"`
+	Shard          string      `json:"shard,omitempty"`
+	SecsRunning    int64       `json:"secsRunning"`
+	PlanSummary    string      `json:"planSummary,omitempty"`
+	WaitingForLock bool        `json:"waitingForLock,omitempty"`
+	Command        interface{} `json:"command,omitempty"`
+}
+
+// SummarizeOps 提取 inprog 中 的 操作，按 运行 时间 倒序
+func SummarizeOps(inprog []interface{}) (ops []*OpInfo) {
+	for _, one := range inprog {
+		m, ok := one.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		op := &OpInfo{
+			OpId:           m["opid"],
+			Op:             toString(m["op"]),
+			Ns:             toString(m["ns"]),
+			Desc:           toString(m["desc"]),
+			Client:         toString(m["client"]),
+			Shard:          toString(m["shard"]),
+			SecsRunning:    toInt64(m["secs_running"]),
+			PlanSummary:    toString(m["planSummary"]),
+			WaitingForLock: m["waitingForLock"] == true,
+			Command:        m["command"],
+		}
+		if op.Client == "" {
+			op.Client = toString(m["client_s"])
+		}
+		if n, isNumber := op.OpId.(float64); isNumber {
+			op.OpId = int64(n)
+		}
+		ops = append(ops, op)
+	}
+	sort.SliceStable(ops, func(i, j int) bool { return ops[i].SecsRunning > ops[j].SecsRunning })
+	return
+}
+
+// ParseOpId killOp 的 op 参数：数字 或 mongos 的 "shard:opid"
+func ParseOpId(value interface{}) (opId interface{}, err error) {
+	switch v := value.(type) {
+	case float64:
+		opId = int64(v)
+	case int64:
+		opId = v
+	case int32:
+		opId = int64(v)
+	case int:
+		opId = int64(v)
+	case string:
+		v = strings.TrimSpace(v)
+		if n, e := strconv.ParseInt(v, 10, 64); e == nil {
+			opId = n
+		} else if strings.Contains(v, ":") {
+			opId = v
+		}
+	}
+	if opId == nil {
+		err = errors.New("opid格式错误")
+	}
+	return
+}
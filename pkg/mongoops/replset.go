@@ -0,0 +1,150 @@
+package mongoops
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// MemberStatus 副本集 成员 状态，LagSeconds 为 相对 主节点 的 复制 延迟，主节点 或 无法 计算 时 为 0
+type MemberStatus struct {
+	Id             int64   `json:"id"`
+	Name           string  `json:"name"`
+	State          int64   `json:"state"`
+	StateStr       string  `json:"stateStr"`
+	Health         float64 `json:"health"`
+	Uptime         int64   `json:"uptime"`
+	OptimeDate     int64   `json:"optimeDate,omitempty"`
+	LagSeconds     float64 `json:"lagSeconds"`
+	PingMs         int64   `json:"pingMs,omitempty"`
+	SyncSourceHost string  `json:"syncSourceHost,omitempty"`
+	Self           bool    `json:"self,omitempty"`
+	Message        string  `json:"message,omitempty"`
+}
+
+// ReplSetStatus replSetGetStatus 摘要
+type ReplSetStatus struct {
+	Set           string          `json:"set"`
+	Date          int64           `json:"date,omitempty"`
+	Primary       string          `json:"primary,omitempty"`
+	MaxLagSeconds float64         `json:"maxLagSeconds"`
+	Members       []*MemberStatus `json:"members"`
+}
+
+// SummarizeReplSet 从 replSetGetStatus 结果（Relaxed Extended JSON 解析 的 map）中 提取 成员 状态 并 计算 延迟
+func SummarizeReplSet(status map[string]interface{}) (res *ReplSetStatus) {
+	res = &ReplSetStatus{
+		Set:  toString(status["set"]),
+		Date: toMillis(status["date"]),
+	}
+	members, _ := status["members"].([]interface{})
+	var primaryOptime int64
+	for _, one := range members {
+		m, ok := one.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		member := &MemberStatus{
+			Id:             toInt64(m["_id"]),
+			Name:           toString(m["name"]),
+			State:          toInt64(m["state"]),
+			StateStr:       toString(m["stateStr"]),
+			Health:         toFloat64(m["health"]),
+			Uptime:         toInt64(m["uptime"]),
+			OptimeDate:     toMillis(m["optimeDate"]),
+			PingMs:         toInt64(m["pingMs"]),
+			SyncSourceHost: toString(m["syncSourceHost"]),
+			Self:           m["self"] == true,
+			Message:        toString(m["lastHeartbeatMessage"]),
+		}
+		if member.Message == "" {
+			member.Message = toString(m["infoMessage"])
+		}
+		if member.StateStr == "PRIMARY" {
+			res.Primary = member.Name
+			primaryOptime = member.OptimeDate
+		}
+		res.Members = append(res.Members, member)
+	}
+	if primaryOptime > 0 {
+		for _, member := range res.Members {
+			if member.StateStr != "SECONDARY" || member.OptimeDate <= 0 {
+				continue
+			}
+			member.LagSeconds = float64(primaryOptime-member.OptimeDate) / 1000
+			if member.LagSeconds < 0 {
+				member.LagSeconds = 0
+			}
+			if member.LagSeconds > res.MaxLagSeconds {
+				res.MaxLagSeconds = member.LagSeconds
+			}
+		}
+	}
+	sort.Slice(res.Members, func(i, j int) bool { return res.Members[i].Id < res.Members[j].Id })
+	return
+}
+
+// toMillis 解析 Relaxed Extended JSON 的 日期：{"$date": "RFC3339"} 或 {"$date": {"$numberLong": "毫秒"}}
+func toMillis(value interface{}) int64 {
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		return 0
+	}
+	switch date := m["$date"].(type) {
+	case string:
+		t, err := time.Parse(time.RFC3339Nano, date)
+		if err != nil {
+			return 0
+		}
+		return t.UnixMilli()
+	case map[string]interface{}:
+		return toInt64(date["$numberLong"])
+	}
+	return toInt64(m["$date"])
+}
+
+func toString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case nil:
+		return ""
+	}
+	bs, _ := json.Marshal(value)
+	return string(bs)
+}
+
+func toInt64(value interface{}) int64 {
+	switch v := value.(type) {
+	case int:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case int64:
+		return v
+	case float64:
+		return int64(v)
+	case string:
+		n, _ := strconv.ParseInt(v, 10, 64)
+		return n
+	case map[string]interface{}:
+		// Canonical 格式 {"$numberLong": "1"}
+		return toInt64(v["$numberLong"])
+	}
+	return 0
+}
+
+func toFloat64(value interface{}) float64 {
+	switch v := value.(type) {
+	case float64:
+		return v
+	case int64:
+		return float64(v)
+	case int32:
+		return float64(v)
+	case int:
+		return float64(v)
+	}
+	return 0
+}
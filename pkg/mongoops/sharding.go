@@ -0,0 +1,49 @@
+package mongoops
+
+import (
+	"sort"
+)
+
+// ShardedCollection 分片 集合 的 chunk 分布
+type ShardedCollection struct {
+	Ns     string           `json:"ns"`
+	Key    interface{}      `json:"key"`
+	Unique bool             `json:"unique,omitempty"`
+	Chunks int64            `json:"chunks"`
+	Shards map[string]int64 `json:"shards"`
+}
+
+// ChunkDistribution 合并 config.collections 与 按 集合、分片 分组 的 chunk 数量。
+// chunkCounts 每行 为 {"_id": {"coll": ns 或 uuid, "shard": 分片}, "count": 数量}，
+// 5.0 之后 config.chunks 使用 uuid 关联 集合，之前 使用 ns
+func ChunkDistribution(collections []map[string]interface{}, chunkCounts []map[string]interface{}) (res []*ShardedCollection) {
+	byKey := map[string]*ShardedCollection{}
+	for _, one := range collections {
+		if one["dropped"] == true {
+			continue
+		}
+		collection := &ShardedCollection{
+			Ns:     toString(one["_id"]),
+			Key:    one["key"],
+			Unique: one["unique"] == true,
+			Shards: map[string]int64{},
+		}
+		byKey[collection.Ns] = collection
+		if uuid, ok := one["uuid"]; ok {
+			byKey[toString(uuid)] = collection
+		}
+		res = append(res, collection)
+	}
+	for _, one := range chunkCounts {
+		id, _ := one["_id"].(map[string]interface{})
+		collection := byKey[toString(id["coll"])]
+		if collection == nil {
+			continue
+		}
+		count := toInt64(one["count"])
+		collection.Shards[toString(id["shard"])] += count
+		collection.Chunks += count
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Ns < res[j].Ns })
+	return
+}
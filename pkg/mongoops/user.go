@@ -0,0 +1,130 @@
+package mongoops
+
+import (
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// RoleRef 角色 引用，Db 为 空 时 为 当前 库
+type RoleRef struct {
+	Role string `json:"role"`
+	Db   string `json:"db,omitempty"`
+}
+
+// Resource 权限 资源，Cluster 为 true 时 为 集群 资源，Db、Collection 为 空 表示 全部
+type Resource struct {
+	Db         string `json:"db"`
+	Collection string `json:"collection"`
+	Cluster    bool   `json:"cluster,omitempty"`
+}
+
+type Privilege struct {
+	Resource *Resource `json:"resource"`
+	Actions  []string  `json:"actions"`
+}
+
+// UserSpec 用户，修改 时 Password 为 空 不 修改 密码，Roles 为 nil 不 修改 角色
+type UserSpec struct {
+	User       string                 `json:"user"`
+	Password   string                 `json:"password,omitempty"`
+	Roles      []*RoleRef             `json:"roles"`
+	CustomData map[string]interface{} `json:"customData,omitempty"`
+}
+
+// RoleSpec 自定义 角色，修改 时 Privileges、Roles 为 nil 不 修改
+type RoleSpec struct {
+	Role       string       `json:"role"`
+	Privileges []*Privilege `json:"privileges"`
+	Roles      []*RoleRef   `json:"roles"`
+}
+
+func buildRoles(roles []*RoleRef, db string) (res bson.A, err error) {
+	res = bson.A{}
+	for _, one := range roles {
+		if one == nil || one.Role == "" {
+			err = errors.New("角色不能为空")
+			return
+		}
+		roleDb := one.Db
+		if roleDb == "" {
+			roleDb = db
+		}
+		res = append(res, bson.D{{Key: "role", Value: one.Role}, {Key: "db", Value: roleDb}})
+	}
+	return
+}
+
+// UserCommand 生成 createUser 或 updateUser 命令
+func UserCommand(spec *UserSpec, db string, isUpdate bool) (command bson.D, err error) {
+	if spec == nil || spec.User == "" {
+		err = errors.New("用户名不能为空")
+		return
+	}
+	if isUpdate {
+		command = bson.D{{Key: "updateUser", Value: spec.User}}
+	} else {
+		if spec.Password == "" {
+			err = errors.New("密码不能为空")
+			return
+		}
+		command = bson.D{{Key: "createUser", Value: spec.User}}
+	}
+	if spec.Password != "" {
+		command = append(command, bson.E{Key: "pwd", Value: spec.Password})
+	}
+	if spec.Roles != nil || !isUpdate {
+		var roles bson.A
+		if roles, err = buildRoles(spec.Roles, db); err != nil {
+			return
+		}
+		command = append(command, bson.E{Key: "roles", Value: roles})
+	}
+	if spec.CustomData != nil {
+		command = append(command, bson.E{Key: "customData", Value: spec.CustomData})
+	}
+	if isUpdate && len(command) == 1 {
+		err = errors.New("没有需要修改的内容")
+	}
+	return
+}
+
+// RoleCommand 生成 createRole 或 updateRole 命令
+func RoleCommand(spec *RoleSpec, db string, isUpdate bool) (command bson.D, err error) {
+	if spec == nil || spec.Role == "" {
+		err = errors.New("角色名不能为空")
+		return
+	}
+	if isUpdate {
+		command = bson.D{{Key: "updateRole", Value: spec.Role}}
+	} else {
+		command = bson.D{{Key: "createRole", Value: spec.Role}}
+	}
+	if spec.Privileges != nil || !isUpdate {
+		privileges := bson.A{}
+		for _, one := range spec.Privileges {
+			if one == nil || one.Resource == nil || len(one.Actions) == 0 {
+				err = errors.New("权限需要指定资源和操作")
+				return
+			}
+			var resource bson.D
+			if one.Resource.Cluster {
+				resource = bson.D{{Key: "cluster", Value: true}}
+			} else {
+				resource = bson.D{{Key: "db", Value: one.Resource.Db}, {Key: "collection", Value: one.Resource.Collection}}
+			}
+			privileges = append(privileges, bson.D{{Key: "resource", Value: resource}, {Key: "actions", Value: one.Actions}})
+		}
+		command = append(command, bson.E{Key: "privileges", Value: privileges})
+	}
+	if spec.Roles != nil || !isUpdate {
+		var roles bson.A
+		if roles, err = buildRoles(spec.Roles, db); err != nil {
+			return
+		}
+		command = append(command, bson.E{Key: "roles", Value: roles})
+	}
+	if isUpdate && len(command) == 1 {
+		err = errors.New("没有需要修改的内容")
+	}
+	return
+}
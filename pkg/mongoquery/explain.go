@@ -0,0 +1,89 @@
+package mongoquery
+
+import (
+	"sort"
+)
+
+// ExplainSummary explain executionStats 摘要，分片 时 为 各 分片 合计
+type ExplainSummary struct {
+	Stages              []string `json:"stages"`
+	Indexes             []string `json:"indexes"`
+	CollScan            bool     `json:"collScan"`
+	NReturned           int64    `json:"nReturned"`
+	TotalKeysExamined   int64    `json:"totalKeysExamined"`
+	TotalDocsExamined   int64    `json:"totalDocsExamined"`
+	ExecutionTimeMillis int64    `json:"executionTimeMillis"`
+}
+
+// SummarizeExplain 从 explain 结果 中 提取 执行 阶段、使用 的 索引 与 扫描 数量，
+// 兼容 find、aggregate（$cursor 阶段）、分片 与 SBE 的 queryPlan 结构
+func SummarizeExplain(explain map[string]interface{}) (summary *ExplainSummary) {
+	summary = &ExplainSummary{}
+	stages := map[string]bool{}
+	indexes := map[string]bool{}
+	var walk func(value interface{}, inPlan bool, inStats bool)
+	walk = func(value interface{}, inPlan bool, inStats bool) {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if inPlan {
+				if stage, ok := v["stage"].(string); ok {
+					if !stages[stage] {
+						stages[stage] = true
+						summary.Stages = append(summary.Stages, stage)
+					}
+					if stage == "COLLSCAN" {
+						summary.CollScan = true
+					}
+				}
+				if name, ok := v["indexName"].(string); ok {
+					indexes[name] = true
+				}
+			}
+			_, hasDocs := v["totalDocsExamined"]
+			_, hasTime := v["executionTimeMillis"]
+			if hasDocs && hasTime && !inStats {
+				// 分片 时 外层 已 是 合计，不再 累加 内层 各 分片
+				inStats = true
+				summary.NReturned += toInt64(v["nReturned"])
+				summary.TotalKeysExamined += toInt64(v["totalKeysExamined"])
+				summary.TotalDocsExamined += toInt64(v["totalDocsExamined"])
+				if t := toInt64(v["executionTimeMillis"]); t > summary.ExecutionTimeMillis {
+					summary.ExecutionTimeMillis = t
+				}
+			}
+			keys := make([]string, 0, len(v))
+			for key := range v {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				// executionStats 中 的 executionStages 与 winningPlan 重复，只 遍历 计划
+				walk(v[key], inPlan || key == "winningPlan", inStats)
+			}
+		case []interface{}:
+			for _, one := range v {
+				walk(one, inPlan, inStats)
+			}
+		}
+	}
+	walk(explain, false, false)
+	for name := range indexes {
+		summary.Indexes = append(summary.Indexes, name)
+	}
+	sort.Strings(summary.Indexes)
+	return
+}
+
+func toInt64(value interface{}) int64 {
+	switch v := value.(type) {
+	case int:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case int64:
+		return v
+	case float64:
+		return int64(v)
+	}
+	return 0
+}
@@ -0,0 +1,95 @@
+package mongoquery
+
+import (
+	"encoding/json"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"testing"
+)
+
+func TestParsePipeline(t *testing.T) {
+	pipeline, err := ParsePipeline(`[
+		{"$match": {"_id": {"$oid": "5f1d7a6b8e4b2c3d4e5f6a7b"}, "at": {"$gte": {"$date": "2024-01-01T00:00:00Z"}}}},
+		{"$group": {"_id": "$type", "n": {"$sum": 1}}}
+	]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pipeline) != 2 || pipeline[1][0].Key != "$group" {
+		t.Fatalf("pipeline %v", pipeline)
+	}
+	match := pipeline[0][0].Value.(primitive.D)
+	if _, ok := match[0].Value.(primitive.ObjectID); !ok {
+		t.Fatalf("oid %T", match[0].Value)
+	}
+
+	pipeline, err = ParsePipeline(`{"$count": "n"}`)
+	if err != nil || len(pipeline) != 1 {
+		t.Fatalf("single stage %v %v", pipeline, err)
+	}
+	for _, text := range []string{"", `[{"match": {}}]`, `[{"$match": {}, "$limit": 1}]`, `[{`} {
+		if _, err = ParsePipeline(text); err == nil {
+			t.Fatalf("pipeline %s should error", text)
+		}
+	}
+}
+
+func TestPagePipeline(t *testing.T) {
+	pipeline, _ := ParsePipeline(`[{"$match": {}}]`)
+	page := PagePipeline(pipeline, 3, 10)
+	if len(page) != 3 || page[1][0].Value.(int64) != 20 || page[2][0].Value.(int64) != 10 {
+		t.Fatalf("page %v", page)
+	}
+	if len(PagePipeline(pipeline, 1, 10)) != 2 || len(pipeline) != 1 {
+		t.Fatal("first page should not skip or change pipeline")
+	}
+	if count := CountPipeline(pipeline); count[1][0].Key != "$count" {
+		t.Fatalf("count %v", count)
+	}
+	out, _ := ParsePipeline(`[{"$match": {}}, {"$out": "target"}]`)
+	if !IsWritePipeline(out) || IsWritePipeline(pipeline) {
+		t.Fatal("write pipeline")
+	}
+}
+
+func TestSummarizeExplain(t *testing.T) {
+	explain := map[string]interface{}{}
+	text := `{
+		"stages": [{"$cursor": {
+			"queryPlanner": {
+				"winningPlan": {"stage": "FETCH", "inputStage": {"stage": "IXSCAN", "indexName": "type_1"}},
+				"rejectedPlans": [{"stage": "COLLSCAN"}]
+			},
+			"executionStats": {"nReturned": 5, "executionTimeMillis": 3, "totalKeysExamined": 5, "totalDocsExamined": 5,
+				"executionStages": {"stage": "FETCH"}}
+		}}, {"$group": {}}]
+	}`
+	if err := json.Unmarshal([]byte(text), &explain); err != nil {
+		t.Fatal(err)
+	}
+	summary := SummarizeExplain(explain)
+	if summary.CollScan || len(summary.Indexes) != 1 || summary.Indexes[0] != "type_1" || len(summary.Stages) != 2 {
+		t.Fatalf("summary %+v", summary)
+	}
+	if summary.NReturned != 5 || summary.TotalDocsExamined != 5 || summary.ExecutionTimeMillis != 3 {
+		t.Fatalf("stats %+v", summary)
+	}
+
+	sharded := map[string]interface{}{}
+	text = `{
+		"queryPlanner": {"winningPlan": {"stage": "SHARD_MERGE", "shards": [
+			{"winningPlan": {"stage": "COLLSCAN"}}, {"winningPlan": {"queryPlan": {"stage": "COLLSCAN"}}}
+		]}},
+		"executionStats": {"nReturned": 10, "executionTimeMillis": 8, "totalKeysExamined": 0, "totalDocsExamined": 100,
+			"executionStages": {"shards": [
+				{"nReturned": 4, "executionTimeMillis": 8, "totalKeysExamined": 0, "totalDocsExamined": 40},
+				{"nReturned": 6, "executionTimeMillis": 5, "totalKeysExamined": 0, "totalDocsExamined": 60}
+			]}}
+	}`
+	if err := json.Unmarshal([]byte(text), &sharded); err != nil {
+		t.Fatal(err)
+	}
+	summary = SummarizeExplain(sharded)
+	if !summary.CollScan || summary.NReturned != 10 || summary.TotalDocsExamined != 100 || len(summary.Indexes) != 0 {
+		t.Fatalf("sharded %+v", summary)
+	}
+}
@@ -0,0 +1,79 @@
+package mongoquery
+
+import (
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"strconv"
+	"strings"
+)
+
+// ParsePipeline 解析 Extended JSON 聚合 管道，支持 数组 或 单个 阶段
+func ParsePipeline(text string) (pipeline []bson.D, err error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		err = errors.New("pipeline is empty")
+		return
+	}
+	if !strings.HasPrefix(text, "[") {
+		text = "[" + text + "]"
+	}
+	// UnmarshalExtJSON 顶层 只能 是 文档，包装 一层
+	wrap := struct {
+		Pipeline []bson.D `bson:"pipeline"`
+	}{}
+	err = bson.UnmarshalExtJSON([]byte(`{"pipeline":`+text+`}`), false, &wrap)
+	if err != nil {
+		err = errors.New("pipeline parse error:" + err.Error())
+		return
+	}
+	for i, stage := range wrap.Pipeline {
+		if len(stage) != 1 || !strings.HasPrefix(stage[0].Key, "$") {
+			err = errors.New("pipeline stage [" + strconv.Itoa(i) + "] must be a single $ operator")
+			return
+		}
+	}
+	pipeline = wrap.Pipeline
+	return
+}
+
+// ParseDoc 解析 Extended JSON 文档，为 空 时 返回 空 文档
+func ParseDoc(text string) (doc bson.D, err error) {
+	doc = bson.D{}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return
+	}
+	err = bson.UnmarshalExtJSON([]byte(text), false, &doc)
+	return
+}
+
+// IsWritePipeline 最后 一个 阶段 为 $out 或 $merge 时 结果 写入 集合，不能 分页
+func IsWritePipeline(pipeline []bson.D) bool {
+	if len(pipeline) == 0 {
+		return false
+	}
+	key := pipeline[len(pipeline)-1][0].Key
+	return key == "$out" || key == "$merge"
+}
+
+// PagePipeline 追加 分页 阶段，pageNo 从 1 开始
+func PagePipeline(pipeline []bson.D, pageNo int64, pageSize int64) (res []bson.D) {
+	if pageNo < 1 {
+		pageNo = 1
+	}
+	res = append(res, pipeline...)
+	if pageNo > 1 {
+		res = append(res, bson.D{{Key: "$skip", Value: (pageNo - 1) * pageSize}})
+	}
+	if pageSize > 0 {
+		res = append(res, bson.D{{Key: "$limit", Value: pageSize}})
+	}
+	return
+}
+
+// CountPipeline 追加 $count 阶段，结果 字段 为 total
+func CountPipeline(pipeline []bson.D) (res []bson.D) {
+	res = append(res, pipeline...)
+	res = append(res, bson.D{{Key: "$count", Value: "total"}})
+	return
+}
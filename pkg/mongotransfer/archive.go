@@ -0,0 +1,226 @@
+package mongotransfer
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"hash"
+	"hash/crc64"
+	"io"
+)
+
+// archive 格式 同 mongo-tools：
+// 魔数、Header、每个 集合 的 CollectionMetadata、结束符，
+// 然后 为 若干 段 NamespaceHeader + 文档 + 结束符，集合 结尾 为 EOF 为 true 的 NamespaceHeader（带 CRC）+ 结束符
+const (
+	archiveMagicNumber   uint32 = 0x8199e26d
+	archiveFormatVersion        = "0.1"
+	archiveTerminator    int32  = -1
+	// archiveMaxDocSize 文档 最大 16MB，超过 视为 文件 损坏，防止 按 错误 的 长度 分配 内存
+	archiveMaxDocSize int32 = 16 * 1024 * 1024
+)
+
+var archiveCrcTable = crc64.MakeTable(crc64.ECMA)
+
+type archiveHeader struct {
+	ConcurrentCollections int32  `bson:"concurrent_collections"`
+	FormatVersion         string `bson:"version"`
+	ServerVersion         string `bson:"server_version"`
+	ToolVersion           string `bson:"tool_version"`
+}
+
+type archiveCollectionMetadata struct {
+	Database   string `bson:"db"`
+	Collection string `bson:"collection"`
+	Metadata   string `bson:"metadata"`
+	Size       int    `bson:"size"`
+	Type       string `bson:"type"`
+}
+
+type archiveNamespaceHeader struct {
+	Database   string `bson:"db"`
+	Collection string `bson:"collection"`
+	EOF        bool   `bson:"EOF"`
+	CRC        int64  `bson:"CRC"`
+}
+
+type archiveWriter struct {
+	w           *bufio.Writer
+	database    string
+	collection  string
+	headerWrote bool
+	crc         hash.Hash64
+	terminator  []byte
+}
+
+func newArchiveWriter(w io.Writer, options *WriterOptions) (res *archiveWriter, err error) {
+	if options.Database == "" || options.Collection == "" {
+		err = errors.New("archive database or collection is empty")
+		return
+	}
+	res = &archiveWriter{
+		w:          bufio.NewWriter(w),
+		database:   options.Database,
+		collection: options.Collection,
+		crc:        crc64.New(archiveCrcTable),
+		terminator: []byte{0xFF, 0xFF, 0xFF, 0xFF},
+	}
+
+	magic := make([]byte, 4)
+	binary.LittleEndian.PutUint32(magic, archiveMagicNumber)
+	if _, err = res.w.Write(magic); err != nil {
+		return
+	}
+	if err = res.writeDoc(&archiveHeader{ConcurrentCollections: 1, FormatVersion: archiveFormatVersion, ToolVersion: "teamide"}); err != nil {
+		return
+	}
+	indexes := bson.A{}
+	for _, one := range options.Indexes {
+		indexes = append(indexes, one)
+	}
+	metadata, err := bson.MarshalExtJSON(bson.D{
+		{Key: "options", Value: bson.D{}},
+		{Key: "indexes", Value: indexes},
+		{Key: "collectionName", Value: options.Collection},
+		{Key: "type", Value: "collection"},
+	}, true, false)
+	if err != nil {
+		return
+	}
+	if err = res.writeDoc(&archiveCollectionMetadata{
+		Database:   options.Database,
+		Collection: options.Collection,
+		Metadata:   string(metadata),
+		Type:       "collection",
+	}); err != nil {
+		return
+	}
+	_, err = res.w.Write(res.terminator)
+	return
+}
+
+func (this_ *archiveWriter) writeDoc(value interface{}) (err error) {
+	bs, err := bson.Marshal(value)
+	if err != nil {
+		return
+	}
+	_, err = this_.w.Write(bs)
+	return
+}
+
+func (this_ *archiveWriter) Write(doc bson.Raw) (err error) {
+	if !this_.headerWrote {
+		this_.headerWrote = true
+		if err = this_.writeDoc(&archiveNamespaceHeader{Database: this_.database, Collection: this_.collection}); err != nil {
+			return
+		}
+	}
+	_, _ = this_.crc.Write(doc)
+	_, err = this_.w.Write(doc)
+	return
+}
+
+func (this_ *archiveWriter) Close() (err error) {
+	if this_.headerWrote {
+		if _, err = this_.w.Write(this_.terminator); err != nil {
+			return
+		}
+	}
+	if err = this_.writeDoc(&archiveNamespaceHeader{
+		Database:   this_.database,
+		Collection: this_.collection,
+		EOF:        true,
+		CRC:        int64(this_.crc.Sum64()),
+	}); err != nil {
+		return
+	}
+	if _, err = this_.w.Write(this_.terminator); err != nil {
+		return
+	}
+	err = this_.w.Flush()
+	return
+}
+
+type archiveReader struct {
+	r          *bufio.Reader
+	collection string
+	namespace  *archiveNamespaceHeader
+}
+
+func newArchiveReader(r io.Reader, collection string) (res *archiveReader, err error) {
+	res = &archiveReader{r: bufio.NewReader(r), collection: collection}
+	magic := make([]byte, 4)
+	if _, err = io.ReadFull(res.r, magic); err != nil {
+		return
+	}
+	if binary.LittleEndian.Uint32(magic) != archiveMagicNumber {
+		err = errors.New("not a mongodump archive")
+		return
+	}
+	// Header 后 为 CollectionMetadata，直到 结束符
+	for i := 0; ; i++ {
+		var bs []byte
+		if bs, err = res.readDoc(); err != nil {
+			return
+		}
+		if bs == nil {
+			if i == 0 {
+				err = errors.New("archive header is empty")
+			}
+			return
+		}
+	}
+}
+
+// readDoc 读取 一个 BSON 文档，遇到 结束符 返回 nil
+func (this_ *archiveReader) readDoc() (bs []byte, err error) {
+	sizeBytes := make([]byte, 4)
+	if _, err = io.ReadFull(this_.r, sizeBytes); err != nil {
+		return
+	}
+	size := int32(binary.LittleEndian.Uint32(sizeBytes))
+	if size == archiveTerminator {
+		return
+	}
+	if size < 5 || size > archiveMaxDocSize {
+		err = errors.New("archive bson size error")
+		return
+	}
+	bs = make([]byte, size)
+	copy(bs, sizeBytes)
+	_, err = io.ReadFull(this_.r, bs[4:])
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return
+}
+
+func (this_ *archiveReader) Next() (doc bson.D, err error) {
+	for {
+		var bs []byte
+		bs, err = this_.readDoc()
+		if err != nil {
+			return
+		}
+		if bs == nil {
+			this_.namespace = nil
+			continue
+		}
+		if this_.namespace == nil {
+			header := &archiveNamespaceHeader{}
+			if err = bson.Unmarshal(bs, header); err != nil {
+				return
+			}
+			if !header.EOF {
+				this_.namespace = header
+			}
+			continue
+		}
+		if this_.collection != "" && this_.namespace.Collection != this_.collection {
+			continue
+		}
+		err = bson.Unmarshal(bs, &doc)
+		return
+	}
+}
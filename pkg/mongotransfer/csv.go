@@ -0,0 +1,226 @@
+package mongotransfer
+
+import (
+	"encoding/csv"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type csvWriter struct {
+	w      *csv.Writer
+	fields []string
+}
+
+func newCSVWriter(w io.Writer, fields []string) (res *csvWriter, err error) {
+	if len(fields) == 0 {
+		err = errors.New("csv fields is empty")
+		return
+	}
+	res = &csvWriter{w: csv.NewWriter(w), fields: fields}
+	err = res.w.Write(fields)
+	return
+}
+
+func (this_ *csvWriter) Write(doc bson.Raw) (err error) {
+	record := make([]string, len(this_.fields))
+	for i, field := range this_.fields {
+		value, e := doc.LookupErr(strings.Split(field, ".")...)
+		if e != nil {
+			continue
+		}
+		record[i] = csvValue(value)
+	}
+	err = this_.w.Write(record)
+	return
+}
+
+func (this_ *csvWriter) Close() error {
+	this_.w.Flush()
+	return this_.w.Error()
+}
+
+// csvValue 同 mongoexport：ObjectId 为 ObjectId(hex)，时间 为 ISO 8601，文档 与 数组 为 Extended JSON
+func csvValue(value bson.RawValue) string {
+	switch value.Type {
+	case bsontype.String:
+		return value.StringValue()
+	case bsontype.ObjectID:
+		return "ObjectId(" + value.ObjectID().Hex() + ")"
+	case bsontype.Boolean:
+		return strconv.FormatBool(value.Boolean())
+	case bsontype.Int32:
+		return strconv.FormatInt(int64(value.Int32()), 10)
+	case bsontype.Int64:
+		return strconv.FormatInt(value.Int64(), 10)
+	case bsontype.Double:
+		return strconv.FormatFloat(value.Double(), 'f', -1, 64)
+	case bsontype.DateTime:
+		return value.Time().UTC().Format("2006-01-02T15:04:05.000Z")
+	case bsontype.Null, bsontype.Undefined:
+		return ""
+	case bsontype.Decimal128:
+		return value.Decimal128().String()
+	}
+	bs, err := bson.MarshalExtJSON(bson.D{{Key: "v", Value: value}}, false, false)
+	if err != nil {
+		return value.String()
+	}
+	// 去掉 外层 {"v": }
+	s := string(bs)
+	return strings.TrimSuffix(strings.TrimPrefix(s, `{"v":`), "}")
+}
+
+type csvColumn struct {
+	path     []string
+	dataType string
+}
+
+type csvReader struct {
+	r            *csv.Reader
+	columns      []*csvColumn
+	ignoreBlanks bool
+}
+
+var csvTypes = []string{"auto", "string", "int32", "int64", "double", "boolean", "date", "objectId"}
+
+// parseCSVHeader 字段 可 带 类型 后缀，如 age.int32()，不 带 时 为 auto
+func parseCSVHeader(header []string) (columns []*csvColumn, err error) {
+	for _, name := range header {
+		name = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+		column := &csvColumn{dataType: "auto"}
+		if strings.HasSuffix(name, ")") {
+			index := strings.LastIndex(name, ".")
+			if index > 0 && strings.HasSuffix(name[index:], "()") {
+				dataType := name[index+1 : len(name)-2]
+				find := false
+				for _, one := range csvTypes {
+					if strings.EqualFold(one, dataType) {
+						column.dataType = one
+						find = true
+					}
+				}
+				if !find {
+					err = errors.New("CSV字段[" + name + "]类型不支持，支持：" + strings.Join(csvTypes, "、"))
+					return
+				}
+				name = name[:index]
+			}
+		}
+		if name == "" {
+			err = errors.New("csv field name is empty")
+			return
+		}
+		column.path = strings.Split(name, ".")
+		columns = append(columns, column)
+	}
+	return
+}
+
+func newCSVReader(r io.Reader, ignoreBlanks bool) (res *csvReader, err error) {
+	res = &csvReader{r: csv.NewReader(r), ignoreBlanks: ignoreBlanks}
+	res.r.FieldsPerRecord = -1
+	header, err := res.r.Read()
+	if err != nil {
+		return
+	}
+	res.columns, err = parseCSVHeader(header)
+	return
+}
+
+func (this_ *csvReader) Next() (doc bson.D, err error) {
+	record, err := this_.r.Read()
+	if err != nil {
+		return
+	}
+	doc = bson.D{}
+	for i, column := range this_.columns {
+		text := ""
+		if i < len(record) {
+			text = record[i]
+		}
+		if text == "" && this_.ignoreBlanks {
+			continue
+		}
+		var value interface{}
+		value, err = parseCSVValue(text, column.dataType)
+		if err != nil {
+			err = &RecordError{Message: "CSV字段[" + strings.Join(column.path, ".") + "]值[" + text + "]解析失败:" + err.Error()}
+			return
+		}
+		doc = setPath(doc, column.path, value)
+	}
+	return
+}
+
+func parseCSVValue(text string, dataType string) (value interface{}, err error) {
+	switch dataType {
+	case "string":
+		value = text
+	case "int32":
+		var v int64
+		v, err = strconv.ParseInt(text, 10, 32)
+		value = int32(v)
+	case "int64":
+		value, err = strconv.ParseInt(text, 10, 64)
+	case "double":
+		value, err = strconv.ParseFloat(text, 64)
+	case "boolean":
+		value, err = strconv.ParseBool(text)
+	case "date":
+		var t time.Time
+		t, err = time.Parse(time.RFC3339Nano, text)
+		value = primitive.NewDateTimeFromTime(t)
+	case "objectId":
+		value, err = primitive.ObjectIDFromHex(strings.TrimSuffix(strings.TrimPrefix(text, "ObjectId("), ")"))
+	default:
+		value = autoValue(text)
+	}
+	return
+}
+
+// autoValue 同 mongoimport：整数 优先 int32，其次 int64、double，ObjectId(hex) 转 ObjectId，其它 为 字符串
+func autoValue(text string) interface{} {
+	if strings.HasPrefix(text, "ObjectId(") && strings.HasSuffix(text, ")") {
+		if id, err := primitive.ObjectIDFromHex(text[9 : len(text)-1]); err == nil {
+			return id
+		}
+	}
+	if v, err := strconv.ParseInt(text, 10, 64); err == nil {
+		if v >= math.MinInt32 && v <= math.MaxInt32 {
+			return int32(v)
+		}
+		return v
+	}
+	if v, err := strconv.ParseFloat(text, 64); err == nil && !strings.ContainsAny(text, "xXnN") {
+		return v
+	}
+	return text
+}
+
+// setPath 按 a.b 路径 设置 嵌套 文档 的 值
+func setPath(doc bson.D, path []string, value interface{}) bson.D {
+	for i, one := range doc {
+		if one.Key != path[0] {
+			continue
+		}
+		if len(path) == 1 {
+			doc[i].Value = value
+			return doc
+		}
+		if sub, ok := one.Value.(bson.D); ok {
+			doc[i].Value = setPath(sub, path[1:], value)
+			return doc
+		}
+	}
+	if len(path) == 1 {
+		return append(doc, bson.E{Key: path[0], Value: value})
+	}
+	return append(doc, bson.E{Key: path[0], Value: setPath(bson.D{}, path[1:], value)})
+}
@@ -0,0 +1,86 @@
+package mongotransfer
+
+import (
+	"bufio"
+	"encoding/json"
+	"go.mongodb.org/mongo-driver/bson"
+	"io"
+)
+
+type jsonWriter struct {
+	w *bufio.Writer
+}
+
+func newJSONWriter(w io.Writer) *jsonWriter {
+	return &jsonWriter{w: bufio.NewWriter(w)}
+}
+
+// Write 使用 Canonical Extended JSON，保证 类型 导入 后 不变
+func (this_ *jsonWriter) Write(doc bson.Raw) (err error) {
+	bs, err := bson.MarshalExtJSON(doc, true, false)
+	if err != nil {
+		return
+	}
+	if _, err = this_.w.Write(bs); err != nil {
+		return
+	}
+	err = this_.w.WriteByte('\n')
+	return
+}
+
+func (this_ *jsonWriter) Close() error {
+	return this_.w.Flush()
+}
+
+type jsonReader struct {
+	r       *bufio.Reader
+	decoder *json.Decoder
+	isArray bool
+}
+
+func newJSONReader(r io.Reader) *jsonReader {
+	return &jsonReader{r: bufio.NewReader(r)}
+}
+
+// init 跳过 开头 空白，以 [ 开头 的 为 JSON 数组
+func (this_ *jsonReader) init() (err error) {
+	for {
+		var b byte
+		b, err = this_.r.ReadByte()
+		if err != nil {
+			return
+		}
+		if b == ' ' || b == '\t' || b == '\r' || b == '\n' || b == 0xEF || b == 0xBB || b == 0xBF {
+			continue
+		}
+		_ = this_.r.UnreadByte()
+		this_.isArray = b == '['
+		break
+	}
+	this_.decoder = json.NewDecoder(this_.r)
+	if this_.isArray {
+		// 读取 [，之后 Decode 会 处理 元素 间 的 逗号
+		_, err = this_.decoder.Token()
+	}
+	return
+}
+
+func (this_ *jsonReader) Next() (doc bson.D, err error) {
+	if this_.decoder == nil {
+		if err = this_.init(); err != nil {
+			return
+		}
+	}
+	if this_.isArray && !this_.decoder.More() {
+		err = io.EOF
+		return
+	}
+	var raw json.RawMessage
+	if err = this_.decoder.Decode(&raw); err != nil {
+		return
+	}
+	if e := bson.UnmarshalExtJSON(raw, false, &doc); e != nil {
+		err = &RecordError{Message: "Extended JSON解析失败:" + e.Error()}
+	}
+	return
+}
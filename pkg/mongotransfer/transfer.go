@@ -0,0 +1,95 @@
+package mongotransfer
+
+import (
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"io"
+)
+
+const (
+	// FormatJSON Extended JSON，每行 一个 文档，导入 时 也 支持 JSON 数组
+	FormatJSON = "json"
+	// FormatCSV 首行 为 字段，导入 时 字段 可 带 类型 如 age.int32()
+	FormatCSV = "csv"
+	// FormatArchive mongodump --archive 格式，可 使用 mongorestore --archive 还原
+	FormatArchive = "archive"
+)
+
+// Writer 导出 写入，Close 写入 结尾 数据，不 关闭 底层 io.Writer
+type Writer interface {
+	Write(doc bson.Raw) error
+	Close() error
+}
+
+// Reader 导入 读取，读取 完 返回 io.EOF
+type Reader interface {
+	Next() (doc bson.D, err error)
+}
+
+// WriterOptions Fields 为 CSV 字段，支持 a.b 嵌套；Database、Collection、Indexes 用于 archive
+type WriterOptions struct {
+	Fields     []string
+	Database   string
+	Collection string
+	Indexes    []bson.Raw
+}
+
+// ReaderOptions IgnoreBlanks 为 CSV 空值 不 写入 字段；Collection 为 archive 中 读取 的 集合，为 空 读取 全部
+type ReaderOptions struct {
+	IgnoreBlanks bool
+	Collection   string
+}
+
+// RecordError 单条 记录 解析 错误，可 跳过 继续 读取
+type RecordError struct {
+	Message string
+}
+
+func (this_ *RecordError) Error() string {
+	return this_.Message
+}
+
+// Ext 文件 后缀
+func Ext(format string) string {
+	switch format {
+	case FormatCSV:
+		return ".csv"
+	case FormatArchive:
+		return ".archive"
+	}
+	return ".json"
+}
+
+func NewWriter(format string, w io.Writer, options *WriterOptions) (writer Writer, err error) {
+	if options == nil {
+		options = &WriterOptions{}
+	}
+	switch format {
+	case FormatJSON, "":
+		writer = newJSONWriter(w)
+	case FormatCSV:
+		writer, err = newCSVWriter(w, options.Fields)
+	case FormatArchive:
+		writer, err = newArchiveWriter(w, options)
+	default:
+		err = errors.New("format [" + format + "] not support")
+	}
+	return
+}
+
+func NewReader(format string, r io.Reader, options *ReaderOptions) (reader Reader, err error) {
+	if options == nil {
+		options = &ReaderOptions{}
+	}
+	switch format {
+	case FormatJSON, "":
+		reader = newJSONReader(r)
+	case FormatCSV:
+		reader, err = newCSVReader(r, options.IgnoreBlanks)
+	case FormatArchive:
+		reader, err = newArchiveReader(r, options.Collection)
+	default:
+		err = errors.New("format [" + format + "] not support")
+	}
+	return
+}
@@ -0,0 +1,164 @@
+package mongotransfer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testDocs(t *testing.T) (docs []bson.Raw) {
+	id, _ := primitive.ObjectIDFromHex("5f1d7a6b8e4b2c3d4e5f6a7b")
+	at := primitive.NewDateTimeFromTime(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	for _, one := range []bson.D{
+		{{Key: "_id", Value: id}, {Key: "name", Value: "a,b"}, {Key: "n", Value: int64(1) << 40}, {Key: "at", Value: at},
+			{Key: "addr", Value: bson.D{{Key: "city", Value: "sh"}}}, {Key: "tags", Value: bson.A{"x", int32(1)}}},
+		{{Key: "_id", Value: int32(2)}, {Key: "name", Value: "b"}, {Key: "f", Value: 1.5}},
+	} {
+		bs, err := bson.Marshal(one)
+		if err != nil {
+			t.Fatal(err)
+		}
+		docs = append(docs, bs)
+	}
+	return
+}
+
+func roundTrip(t *testing.T, format string, writerOptions *WriterOptions, readerOptions *ReaderOptions, docs []bson.Raw) (text string, res []bson.D) {
+	buf := &bytes.Buffer{}
+	writer, err := NewWriter(format, buf, writerOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, doc := range docs {
+		if err = writer.Write(doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	text = buf.String()
+	reader, err := NewReader(format, buf, readerOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for {
+		var doc bson.D
+		doc, err = reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		res = append(res, doc)
+	}
+	return
+}
+
+func TestJSON(t *testing.T) {
+	docs := testDocs(t)
+	text, res := roundTrip(t, FormatJSON, nil, nil, docs)
+	if strings.Count(text, "\n") != 2 || !strings.Contains(text, `{"$numberLong":"1099511627776"}`) {
+		t.Fatalf("json %s", text)
+	}
+	for i, doc := range res {
+		bs, _ := bson.Marshal(doc)
+		if !bytes.Equal(bs, docs[i]) {
+			t.Fatalf("doc %d %v", i, doc)
+		}
+	}
+
+	reader, _ := NewReader(FormatJSON, strings.NewReader(` [{"a": 1}, {"b": {"$oid": "5f1d7a6b8e4b2c3d4e5f6a7b"}}] `), nil)
+	count := 0
+	for {
+		doc, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		count++
+		if count == 2 {
+			if _, ok := doc[0].Value.(primitive.ObjectID); !ok {
+				t.Fatalf("oid %T", doc[0].Value)
+			}
+		}
+	}
+	if count != 2 {
+		t.Fatalf("array count %d", count)
+	}
+}
+
+func TestCSV(t *testing.T) {
+	docs := testDocs(t)
+	text, res := roundTrip(t, FormatCSV, &WriterOptions{Fields: []string{"_id", "name", "n", "at", "addr.city", "tags", "f"}}, &ReaderOptions{IgnoreBlanks: true}, docs)
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	if len(lines) != 3 || lines[1] != `ObjectId(5f1d7a6b8e4b2c3d4e5f6a7b),"a,b",1099511627776,2024-01-02T03:04:05.000Z,sh,"[""x"",1]",` {
+		t.Fatalf("csv %s", text)
+	}
+	if _, ok := res[0][0].Value.(primitive.ObjectID); !ok || res[0][2].Value.(int64) != 1<<40 || res[1][0].Value.(int32) != 2 || res[1][2].Value.(float64) != 1.5 {
+		t.Fatalf("csv docs %v", res)
+	}
+	if addr := res[0][4].Value.(bson.D); addr[0].Key != "city" || addr[0].Value != "sh" {
+		t.Fatalf("nested %v", res[0][4])
+	}
+
+	reader, err := NewReader(FormatCSV, strings.NewReader("code.string(),at.date(),ok.boolean()\n007,2024-01-02T03:04:05Z,true\n"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := reader.Next()
+	if err != nil || doc[0].Value != "007" || doc[2].Value != true {
+		t.Fatalf("typed %v %v", doc, err)
+	}
+	reader, _ = NewReader(FormatCSV, strings.NewReader("n.int32()\nx\n1\n"), nil)
+	if _, err = reader.Next(); err == nil {
+		t.Fatal("bad int32 should error")
+	} else if _, ok := err.(*RecordError); !ok {
+		t.Fatalf("record error %T", err)
+	}
+	if doc, err = reader.Next(); err != nil || doc[0].Value != int32(1) {
+		t.Fatalf("after record error %v %v", doc, err)
+	}
+	if _, err = NewReader(FormatCSV, strings.NewReader("a.uuid()\n1\n"), nil); err == nil {
+		t.Fatal("unknown type should error")
+	}
+}
+
+func TestArchive(t *testing.T) {
+	docs := testDocs(t)
+	index, _ := bson.Marshal(bson.D{{Key: "v", Value: int32(2)}, {Key: "key", Value: bson.D{{Key: "_id", Value: int32(1)}}}, {Key: "name", Value: "_id_"}})
+	text, res := roundTrip(t, FormatArchive, &WriterOptions{Database: "db", Collection: "users", Indexes: []bson.Raw{index}}, nil, docs)
+	if !strings.Contains(text, `"indexes":[{"v":{"$numberInt":"2"}`) || len(res) != 2 {
+		t.Fatalf("archive %d", len(res))
+	}
+	bs, _ := bson.Marshal(res[1])
+	if !bytes.Equal(bs, docs[1]) {
+		t.Fatalf("archive doc %v", res[1])
+	}
+	_, res = roundTrip(t, FormatArchive, &WriterOptions{Database: "db", Collection: "users"}, &ReaderOptions{Collection: "other"}, docs)
+	if len(res) != 0 {
+		t.Fatalf("filtered %d", len(res))
+	}
+	_, res = roundTrip(t, FormatArchive, &WriterOptions{Database: "db", Collection: "users"}, nil, nil)
+	if len(res) != 0 {
+		t.Fatal("empty archive")
+	}
+	if _, err := NewReader(FormatArchive, strings.NewReader("abcd"), nil); err == nil {
+		t.Fatal("magic should error")
+	}
+	// 损坏 的 长度 不 分配 内存
+	huge := make([]byte, 8)
+	binary.LittleEndian.PutUint32(huge, archiveMagicNumber)
+	binary.LittleEndian.PutUint32(huge[4:], uint32(archiveMaxDocSize+1))
+	if _, err := NewReader(FormatArchive, bytes.NewReader(huge), nil); err == nil {
+		t.Fatal("huge size should error")
+	}
+}
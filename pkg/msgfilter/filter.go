@@ -0,0 +1,273 @@
+package msgfilter
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DataTypeString 原样 转 字符串，默认
+	DataTypeString = "string"
+	// DataTypeLong 8 字节 大端 整数
+	DataTypeLong = "long"
+	// DataTypeInt 4 字节 大端 整数
+	DataTypeInt = "int"
+	// DataTypeDouble 8 字节 大端 浮点数
+	DataTypeDouble = "double"
+	// DataTypeHex 十六进制
+	DataTypeHex = "hex"
+	// DataTypeBase64 Base64
+	DataTypeBase64 = "base64"
+)
+
+// Message 过滤 使用 的 消息，Key、Value 为 解码 后 的 字符串
+type Message struct {
+	Key     string
+	Value   string
+	Headers map[string]string
+}
+
+// Filter 消息 过滤，各 条件 同时 满足 才 匹配，为 空 的 条件 不 检查
+type Filter struct {
+	Key       string            `json:"key,omitempty"`       // Key 包含
+	KeyRegex  string            `json:"keyRegex,omitempty"`  // Key 正则
+	Headers   map[string]string `json:"headers,omitempty"`   // Header 等于，值 为 空 时 只 检查 存在
+	JsonPath  string            `json:"jsonPath,omitempty"`  // Value 为 JSON 时 的 路径，如 $.user.id、$.items[0].name、$.items[*].sku
+	JsonValue string            `json:"jsonValue,omitempty"` // 路径 值 等于，为 空 时 只 检查 路径 存在
+	Value     string            `json:"value,omitempty"`     // Value 包含
+
+	keyRegex *regexp.Regexp
+	jsonPath []*pathStep
+}
+
+type pathStep struct {
+	name     string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+// Compile 校验 并 预编译 正则、路径
+func (this_ *Filter) Compile() (err error) {
+	this_.keyRegex = nil
+	this_.jsonPath = nil
+	if this_.KeyRegex != "" {
+		this_.keyRegex, err = regexp.Compile(this_.KeyRegex)
+		if err != nil {
+			err = errors.New("Key正则[" + this_.KeyRegex + "]错误:" + err.Error())
+			return
+		}
+	}
+	if this_.JsonPath != "" {
+		this_.jsonPath, err = ParsePath(this_.JsonPath)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// Match 是否 匹配，需 先 调用 Compile
+func (this_ *Filter) Match(message *Message) bool {
+	if this_.Key != "" && !strings.Contains(message.Key, this_.Key) {
+		return false
+	}
+	if this_.keyRegex != nil && !this_.keyRegex.MatchString(message.Key) {
+		return false
+	}
+	for name, value := range this_.Headers {
+		find, ok := message.Headers[name]
+		if !ok || (value != "" && find != value) {
+			return false
+		}
+	}
+	if this_.Value != "" && !strings.Contains(message.Value, this_.Value) {
+		return false
+	}
+	if this_.jsonPath != nil {
+		var data interface{}
+		decoder := json.NewDecoder(strings.NewReader(message.Value))
+		decoder.UseNumber()
+		if decoder.Decode(&data) != nil {
+			return false
+		}
+		values := lookup(data, this_.jsonPath)
+		if len(values) == 0 {
+			return false
+		}
+		if this_.JsonValue == "" {
+			return true
+		}
+		for _, one := range values {
+			if valueString(one) == this_.JsonValue {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// ParsePath 解析 JSONPath，支持 $、.name、['name']、[index]、[*]、.*
+func ParsePath(path string) (steps []*pathStep, err error) {
+	steps = []*pathStep{}
+	s := strings.TrimSpace(path)
+	s = strings.TrimPrefix(s, "$")
+	for len(s) > 0 {
+		switch s[0] {
+		case '.':
+			s = s[1:]
+			end := strings.IndexAny(s, ".[")
+			if end < 0 {
+				end = len(s)
+			}
+			name := s[:end]
+			if name == "" {
+				err = errors.New("JSONPath[" + path + "]格式错误")
+				return
+			}
+			if name == "*" {
+				steps = append(steps, &pathStep{wildcard: true})
+			} else {
+				steps = append(steps, &pathStep{name: name})
+			}
+			s = s[end:]
+		case '[':
+			end := strings.IndexByte(s, ']')
+			if end < 0 {
+				err = errors.New("JSONPath[" + path + "]缺少]")
+				return
+			}
+			inner := strings.TrimSpace(s[1:end])
+			s = s[end+1:]
+			switch {
+			case inner == "*":
+				steps = append(steps, &pathStep{wildcard: true})
+			case len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0]:
+				steps = append(steps, &pathStep{name: inner[1 : len(inner)-1]})
+			default:
+				var index int
+				index, err = strconv.Atoi(inner)
+				if err != nil {
+					err = errors.New("JSONPath[" + path + "]下标[" + inner + "]错误")
+					return
+				}
+				steps = append(steps, &pathStep{index: index, isIndex: true})
+			}
+		default:
+			err = errors.New("JSONPath[" + path + "]格式错误")
+			return
+		}
+	}
+	return
+}
+
+// Lookup 按 路径 查找 值，通配 时 可能 有 多个
+func Lookup(data interface{}, path string) (values []interface{}, err error) {
+	steps, err := ParsePath(path)
+	if err != nil {
+		return
+	}
+	values = lookup(data, steps)
+	return
+}
+
+func lookup(data interface{}, steps []*pathStep) (values []interface{}) {
+	current := []interface{}{data}
+	for _, step := range steps {
+		var next []interface{}
+		for _, one := range current {
+			switch v := one.(type) {
+			case map[string]interface{}:
+				if step.wildcard {
+					for _, child := range v {
+						next = append(next, child)
+					}
+				} else if child, ok := v[step.name]; ok && !step.isIndex {
+					next = append(next, child)
+				}
+			case []interface{}:
+				if step.wildcard {
+					next = append(next, v...)
+				} else if step.isIndex {
+					index := step.index
+					if index < 0 {
+						index += len(v)
+					}
+					if index >= 0 && index < len(v) {
+						next = append(next, v[index])
+					}
+				}
+			}
+		}
+		current = next
+	}
+	values = current
+	return
+}
+
+func valueString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case nil:
+		return "null"
+	case json.Number:
+		return v.String()
+	case map[string]interface{}, []interface{}:
+		bs, _ := json.Marshal(v)
+		return string(bs)
+	}
+	return fmt.Sprint(value)
+}
+
+// CheckDataType 校验 数据 类型，为 空 时 为 string
+func CheckDataType(dataType string) (err error) {
+	switch dataType {
+	case "", DataTypeString, DataTypeHex, DataTypeBase64, DataTypeLong, DataTypeInt, DataTypeDouble:
+	default:
+		err = errors.New("不支持的数据类型[" + dataType + "]")
+	}
+	return
+}
+
+// Decode 按 类型 将 字节 转为 展示 字符串，长度 不符 时 返回 错误
+func Decode(bs []byte, dataType string) (value string, err error) {
+	switch dataType {
+	case "", DataTypeString:
+		value = string(bs)
+	case DataTypeHex:
+		value = hex.EncodeToString(bs)
+	case DataTypeBase64:
+		value = base64.StdEncoding.EncodeToString(bs)
+	case DataTypeLong:
+		if len(bs) != 8 {
+			err = fmt.Errorf("long 需要 8 字节，实际 %d 字节", len(bs))
+			return
+		}
+		value = strconv.FormatInt(int64(binary.BigEndian.Uint64(bs)), 10)
+	case DataTypeInt:
+		if len(bs) != 4 {
+			err = fmt.Errorf("int 需要 4 字节，实际 %d 字节", len(bs))
+			return
+		}
+		value = strconv.FormatInt(int64(int32(binary.BigEndian.Uint32(bs))), 10)
+	case DataTypeDouble:
+		if len(bs) != 8 {
+			err = fmt.Errorf("double 需要 8 字节，实际 %d 字节", len(bs))
+			return
+		}
+		value = strconv.FormatFloat(math.Float64frombits(binary.BigEndian.Uint64(bs)), 'g', -1, 64)
+	default:
+		err = CheckDataType(dataType)
+	}
+	return
+}
@@ -0,0 +1,97 @@
+package msgfilter
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestLookup(t *testing.T) {
+	var data interface{}
+	_ = json.Unmarshal([]byte(`{"user":{"id":7,"name":"a"},"items":[{"sku":"x"},{"sku":"y"}],"a.b":true}`), &data)
+	tests := map[string]int{
+		"$.user.id":       1,
+		"$.items[1].sku":  1,
+		"$.items[-1].sku": 1,
+		"$.items[*].sku":  2,
+		"$['a.b']":        1,
+		"$.user.*":        2,
+		"$.none":          0,
+		"$.items[5]":      0,
+	}
+	for path, count := range tests {
+		values, err := Lookup(data, path)
+		if err != nil || len(values) != count {
+			t.Fatalf("path [%s] values %v err %v", path, values, err)
+		}
+	}
+	for _, path := range []string{"$.", "$[1", "user", "$[x]"} {
+		if _, err := ParsePath(path); err == nil {
+			t.Fatalf("path [%s] should error", path)
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	message := &Message{
+		Key:     "order-1001",
+		Value:   `{"status":"paid","amount":12.50,"items":[{"sku":"x"}]}`,
+		Headers: map[string]string{"source": "app", "trace": "t1"},
+	}
+	tests := []struct {
+		filter *Filter
+		match  bool
+	}{
+		{filter: &Filter{}, match: true},
+		{filter: &Filter{Key: "1001"}, match: true},
+		{filter: &Filter{KeyRegex: `^order-\d+$`}, match: true},
+		{filter: &Filter{KeyRegex: `^user-`}, match: false},
+		{filter: &Filter{Headers: map[string]string{"source": "app"}}, match: true},
+		{filter: &Filter{Headers: map[string]string{"trace": ""}}, match: true},
+		{filter: &Filter{Headers: map[string]string{"source": "web"}}, match: false},
+		{filter: &Filter{JsonPath: "$.status", JsonValue: "paid"}, match: true},
+		{filter: &Filter{JsonPath: "$.amount", JsonValue: "12.50"}, match: true},
+		{filter: &Filter{JsonPath: "$.items[*].sku", JsonValue: "x"}, match: true},
+		{filter: &Filter{JsonPath: "$.refund"}, match: false},
+		{filter: &Filter{Value: "paid", Key: "order"}, match: true},
+	}
+	for _, one := range tests {
+		if err := one.filter.Compile(); err != nil {
+			t.Fatal(err)
+		}
+		if one.filter.Match(message) != one.match {
+			t.Fatalf("filter %+v match should be %v", one.filter, one.match)
+		}
+	}
+	filter := &Filter{JsonPath: "$.a"}
+	_ = filter.Compile()
+	if filter.Match(&Message{Value: "not json"}) {
+		t.Fatal("not json should not match")
+	}
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		bs       []byte
+		dataType string
+		value    string
+	}{
+		{bs: []byte("abc"), dataType: "", value: "abc"},
+		{bs: []byte{0, 0, 0, 0, 0, 0, 1, 0}, dataType: DataTypeLong, value: "256"},
+		{bs: []byte{0xff, 0xff, 0xff, 0xff}, dataType: DataTypeInt, value: "-1"},
+		{bs: []byte{0x3f, 0xf0, 0, 0, 0, 0, 0, 0}, dataType: DataTypeDouble, value: "1"},
+		{bs: []byte{1, 0xab}, dataType: DataTypeHex, value: "01ab"},
+		{bs: []byte("hi"), dataType: DataTypeBase64, value: "aGk="},
+	}
+	for _, one := range tests {
+		value, err := Decode(one.bs, one.dataType)
+		if err != nil || value != one.value {
+			t.Fatalf("decode %v %s value %s err %v", one.bs, one.dataType, value, err)
+		}
+	}
+	if _, err := Decode([]byte{1}, DataTypeLong); err == nil {
+		t.Fatal("short long should error")
+	}
+	if _, err := Decode([]byte{1}, "avro"); err == nil || CheckDataType("avro") == nil {
+		t.Fatal("unknown type should error")
+	}
+}
@@ -50,6 +50,10 @@ type SystemData struct {
 	QueryResponse *system.QueryResponse `json:"queryResponse,omitempty"`
 	Info          *system.Info          `json:"info,omitempty"`
 	MonitorData   *system.MonitorData   `json:"monitorData,omitempty"`
+	ProcessList   []*system.ProcessInfo `json:"processList,omitempty"`
+	PortList      []*system.PortInfo    `json:"portList,omitempty"`
+	Pid           int32                 `json:"pid,omitempty"`
+	Signal        string                `json:"signal,omitempty"`
 }
 
 type WorkData struct {
@@ -91,6 +95,10 @@ type TerminalWorkData struct {
 	ReadKey   string         `json:"readKey,omitempty"`
 	Size      *terminal.Size `json:"size,omitempty"`
 	IsWindows bool           `json:"isWindows,omitempty"`
+
+	Script     string               `json:"script,omitempty"`
+	Timeout    int64                `json:"timeout,omitempty"` // 执行 超时 毫秒
+	ExecResult *terminal.ExecResult `json:"execResult,omitempty"`
 }
 
 type StatusChange struct {
@@ -137,6 +137,33 @@ func (this_ *Server) SystemQueryMonitorData(lineNodeIdList []string, request *sy
 	return
 }
 
+func (this_ *Server) SystemProcesses(lineNodeIdList []string) (processList []*system.ProcessInfo, err error) {
+	res, err := this_.systemProcesses(lineNodeIdList)
+	if err != nil {
+		return
+	}
+	if res != nil {
+		processList = res.ProcessList
+	}
+	return
+}
+
+func (this_ *Server) SystemPorts(lineNodeIdList []string) (portList []*system.PortInfo, err error) {
+	res, err := this_.systemPorts(lineNodeIdList)
+	if err != nil {
+		return
+	}
+	if res != nil {
+		portList = res.PortList
+	}
+	return
+}
+
+func (this_ *Server) SystemSignal(lineNodeIdList []string, pid int32, signal string) (err error) {
+	err = this_.systemSignal(lineNodeIdList, pid, signal)
+	return
+}
+
 func (this_ *Server) SystemCleanMonitorData(lineNodeIdList []string) {
 	_ = this_.systemCleanMonitorData(lineNodeIdList)
 	return
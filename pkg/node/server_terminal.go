@@ -4,6 +4,7 @@ import (
 	"github.com/team-ide/go-tool/util"
 	"go.uber.org/zap"
 	"teamide/pkg/terminal"
+	"time"
 )
 
 func (this_ *Server) TerminalStart(lineNodeIdList []string, size *terminal.Size, onRead func(buf []byte) (err error)) (key string, err error) {
@@ -65,3 +66,12 @@ func (this_ *Server) TerminalStop(lineNodeIdList []string, key string) (err erro
 	}
 	return
 }
+
+// TerminalExec 在 节点 执行 脚本，节点 调用 最长 等待 60 秒
+func (this_ *Server) TerminalExec(lineNodeIdList []string, script string, timeout time.Duration) (execResult *terminal.ExecResult, err error) {
+	execResult, err = this_.workTerminalExec(lineNodeIdList, script, timeout.Milliseconds())
+	if err != nil {
+		return
+	}
+	return
+}
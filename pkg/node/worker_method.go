@@ -6,6 +6,7 @@ import (
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 	"teamide/pkg/filework"
+	"teamide/pkg/terminal"
 	"time"
 )
 
@@ -47,11 +48,15 @@ var (
 	methodTerminalChangeSize MethodType = 403
 	methodTerminalStop       MethodType = 404
 	methodTerminalIsWindows  MethodType = 405
+	methodTerminalExec       MethodType = 406
 
 	methodSystemGetInfo          MethodType = 501
 	methodSystemQueryMonitorData MethodType = 502
 	methodSystemCleanMonitorData MethodType = 503
 	methodSystemMonitorData      MethodType = 504
+	methodSystemProcesses        MethodType = 505
+	methodSystemPorts            MethodType = 506
+	methodSystemSignal           MethodType = 507
 
 	methodSendBytesStart MethodType = 601
 	methodSendBytes      MethodType = 602
@@ -277,6 +282,30 @@ func (this_ *Worker) doMethod(method MethodType, msg *Message) (res *Message, er
 			res.SystemData = response
 		}
 		return
+	case methodSystemProcesses:
+		var response *SystemData
+		response, err = this_.systemProcesses(msg.LineNodeIdList)
+		if err != nil {
+			return
+		}
+		res.SystemData = response
+		return
+	case methodSystemPorts:
+		var response *SystemData
+		response, err = this_.systemPorts(msg.LineNodeIdList)
+		if err != nil {
+			return
+		}
+		res.SystemData = response
+		return
+	case methodSystemSignal:
+		if msg.SystemData != nil {
+			err = this_.systemSignal(msg.LineNodeIdList, msg.SystemData.Pid, msg.SystemData.Signal)
+			if err != nil {
+				return
+			}
+		}
+		return
 
 	case methodFileExist:
 		if msg.FileWorkData != nil {
@@ -425,6 +454,18 @@ func (this_ *Worker) doMethod(method MethodType, msg *Message) (res *Message, er
 			}
 		}
 		return
+	case methodTerminalExec:
+		if msg.TerminalWorkData != nil {
+			var execResult *terminal.ExecResult
+			execResult, err = this_.workTerminalExec(msg.LineNodeIdList, msg.TerminalWorkData.Script, msg.TerminalWorkData.Timeout)
+			if err != nil {
+				return
+			}
+			res.TerminalWorkData = &TerminalWorkData{
+				ExecResult: execResult,
+			}
+		}
+		return
 
 	case methodSendBytesStart:
 		err = this_.workSendBytesStart(msg.LineNodeIdList, msg.SendKey)
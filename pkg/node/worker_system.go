@@ -103,3 +103,68 @@ func (this_ *Worker) systemMonitorData(lineNodeIdList []string) (response *Syste
 	return
 
 }
+
+func (this_ *Worker) systemProcesses(lineNodeIdList []string) (response *SystemData, err error) {
+	var resMsg *Message
+	send, err := this_.sendToNext(lineNodeIdList, "", func(listener *MessageListener) (e error) {
+		resMsg, e = this_.Call(listener, methodSystemProcesses, &Message{
+			LineNodeIdList: lineNodeIdList,
+		})
+		return
+	})
+	if err != nil {
+		return
+	}
+	if send {
+		if resMsg != nil {
+			response = resMsg.SystemData
+		}
+		return
+	}
+
+	response = &SystemData{}
+	response.ProcessList, err = system.GetProcesses()
+	return
+}
+
+func (this_ *Worker) systemPorts(lineNodeIdList []string) (response *SystemData, err error) {
+	var resMsg *Message
+	send, err := this_.sendToNext(lineNodeIdList, "", func(listener *MessageListener) (e error) {
+		resMsg, e = this_.Call(listener, methodSystemPorts, &Message{
+			LineNodeIdList: lineNodeIdList,
+		})
+		return
+	})
+	if err != nil {
+		return
+	}
+	if send {
+		if resMsg != nil {
+			response = resMsg.SystemData
+		}
+		return
+	}
+
+	response = &SystemData{}
+	response.PortList, err = system.GetPorts()
+	return
+}
+
+func (this_ *Worker) systemSignal(lineNodeIdList []string, pid int32, signal string) (err error) {
+	send, err := this_.sendToNext(lineNodeIdList, "", func(listener *MessageListener) (e error) {
+		_, e = this_.Call(listener, methodSystemSignal, &Message{
+			LineNodeIdList: lineNodeIdList,
+			SystemData: &SystemData{
+				Pid:    pid,
+				Signal: signal,
+			},
+		})
+		return
+	})
+	if err != nil || send {
+		return
+	}
+
+	err = system.SignalProcess(pid, signal)
+	return
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/team-ide/go-tool/util"
 	"go.uber.org/zap"
 	"teamide/pkg/terminal"
+	"time"
 )
 
 func (this_ *Worker) workTerminalStart(lineNodeIdList []string, size *terminal.Size, readKey string) (key string, err error) {
@@ -176,3 +177,31 @@ func (this_ *Worker) workTerminalWrite(lineNodeIdList []string, key string, buf
 
 	return
 }
+
+func (this_ *Worker) workTerminalExec(lineNodeIdList []string, script string, timeout int64) (execResult *terminal.ExecResult, err error) {
+	send, err := this_.sendToNext(lineNodeIdList, "", func(listener *MessageListener) (e error) {
+		res, e := this_.Call(listener, methodTerminalExec, &Message{
+			LineNodeIdList: lineNodeIdList,
+			TerminalWorkData: &TerminalWorkData{
+				Script:  script,
+				Timeout: timeout,
+			},
+		})
+		if e != nil {
+			return
+		}
+
+		if res != nil && res.TerminalWorkData != nil {
+			execResult = res.TerminalWorkData.ExecResult
+		}
+
+		return
+	})
+	if err != nil || send {
+		return
+	}
+
+	execResult = terminal.Exec(script, time.Duration(timeout)*time.Millisecond)
+
+	return
+}
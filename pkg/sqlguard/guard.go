@@ -0,0 +1,229 @@
+package sqlguard
+
+import (
+	"strings"
+)
+
+const (
+	// RiskDrop 删除 库、表 等 对象，或 ALTER 中 删除 列、分区
+	RiskDrop = "drop"
+	// RiskTruncate 清空 表
+	RiskTruncate = "truncate"
+	// RiskNoWhere UPDATE、DELETE 没有 WHERE 条件
+	RiskNoWhere = "noWhere"
+	// RiskRows 影响 行数 超过 限制
+	RiskRows = "rows"
+	// RiskRowsUnknown 无法 统计 影响 行数
+	RiskRowsUnknown = "rowsUnknown"
+)
+
+// Risk 危险 操作
+type Risk struct {
+	Type    string `json:"type"`
+	Sql     string `json:"sql,omitempty"`
+	Message string `json:"message"`
+	Rows    int64  `json:"rows,omitempty"`
+}
+
+// Statement 语句 分析 结果
+type Statement struct {
+	Sql      string `json:"sql"`
+	Command  string `json:"command"`            // 主 语句 关键字，大写，WITH 开头 时 为 其后 的 语句
+	HasWhere bool   `json:"hasWhere,omitempty"` // 是否 有 顶层 WHERE，子查询 中的 不算
+	CountSql string `json:"countSql,omitempty"` // UPDATE、DELETE 影响 行数 统计 SQL
+}
+
+type word struct {
+	text  string
+	start int
+	end   int
+	depth int
+}
+
+// scanWords 顶层 及 括号 内 的 关键字，跳过 字符串、引号 标识符、注释
+func scanWords(sql string) (words []*word) {
+	depth := 0
+	size := len(sql)
+	for i := 0; i < size; {
+		c := sql[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			i = skipQuote(sql, i, c)
+		case c == '-' && i+1 < size && sql[i+1] == '-':
+			for i < size && sql[i] != '\n' {
+				i++
+			}
+		case c == '/' && i+1 < size && sql[i+1] == '*':
+			end := strings.Index(sql[i+2:], "*/")
+			if end < 0 {
+				i = size
+			} else {
+				i += end + 4
+			}
+		case c == '$':
+			i = skipDollarQuote(sql, i)
+		case c == '(':
+			depth++
+			i++
+		case c == ')':
+			if depth > 0 {
+				depth--
+			}
+			i++
+		case isWordChar(c):
+			start := i
+			for i < size && isWordChar(sql[i]) {
+				i++
+			}
+			words = append(words, &word{
+				text:  strings.ToUpper(sql[start:i]),
+				start: start,
+				end:   i,
+				depth: depth,
+			})
+		default:
+			i++
+		}
+	}
+	return
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c >= 0x80
+}
+
+func skipQuote(sql string, i int, quote byte) int {
+	i++
+	for i < len(sql) {
+		if sql[i] == '\\' && quote != '`' {
+			i += 2
+			continue
+		}
+		if sql[i] == quote {
+			if i+1 < len(sql) && sql[i+1] == quote {
+				i += 2
+				continue
+			}
+			return i + 1
+		}
+		i++
+	}
+	return i
+}
+
+// skipDollarQuote PostgreSQL $tag$...$tag$ 字符串，不是 时 跳过 $
+func skipDollarQuote(sql string, i int) int {
+	end := strings.IndexByte(sql[i+1:], '$')
+	if end < 0 {
+		return i + 1
+	}
+	tag := sql[i : i+end+2]
+	for _, c := range []byte(tag[1 : len(tag)-1]) {
+		if !isWordChar(c) {
+			return i + 1
+		}
+	}
+	closeIndex := strings.Index(sql[i+len(tag):], tag)
+	if closeIndex < 0 {
+		return len(sql)
+	}
+	return i + len(tag) + closeIndex + len(tag)
+}
+
+// Parse 分析 单条 语句
+func Parse(sql string) (statement *Statement) {
+	statement = &Statement{
+		Sql: strings.TrimSpace(sql),
+	}
+	words := scanWords(sql)
+	if len(words) == 0 {
+		return
+	}
+	commandIndex := 0
+	if words[0].text == "WITH" {
+		for i, one := range words {
+			if one.depth == 0 && (one.text == "SELECT" || one.text == "INSERT" || one.text == "UPDATE" || one.text == "DELETE" || one.text == "MERGE") {
+				commandIndex = i
+				break
+			}
+		}
+	}
+	command := words[commandIndex]
+	statement.Command = command.text
+	if command.text != "UPDATE" && command.text != "DELETE" {
+		return
+	}
+
+	var fromIndex, setIndex, whereIndex, endIndex = -1, -1, -1, -1
+	for i := commandIndex + 1; i < len(words); i++ {
+		one := words[i]
+		if one.depth != 0 {
+			continue
+		}
+		switch one.text {
+		case "FROM":
+			if fromIndex < 0 && setIndex < 0 {
+				fromIndex = i
+			}
+		case "SET":
+			if setIndex < 0 {
+				setIndex = i
+			}
+		case "WHERE":
+			if whereIndex < 0 {
+				whereIndex = i
+			}
+		case "ORDER", "LIMIT", "RETURNING":
+			if whereIndex >= 0 && endIndex < 0 {
+				endIndex = i
+			}
+		}
+	}
+	statement.HasWhere = whereIndex >= 0
+	if !statement.HasWhere {
+		return
+	}
+
+	tableStart := command.end
+	tableEnd := words[whereIndex].start
+	if command.text == "UPDATE" {
+		if setIndex < 0 {
+			return
+		}
+		tableEnd = words[setIndex].start
+	} else if fromIndex >= 0 {
+		tableStart = words[fromIndex].end
+	}
+	whereEnd := len(sql)
+	if endIndex >= 0 {
+		whereEnd = words[endIndex].start
+	}
+	whereSql := strings.TrimRight(strings.TrimSpace(sql[words[whereIndex].start:whereEnd]), ";")
+	statement.CountSql = sql[:command.start] + "SELECT COUNT(*) FROM " + strings.TrimSpace(sql[tableStart:tableEnd]) + " " + whereSql
+	return
+}
+
+// Check 结构 上 的 危险 操作：DROP、TRUNCATE、没有 WHERE 的 UPDATE 及 DELETE
+func Check(statement *Statement) (risk *Risk) {
+	switch statement.Command {
+	case "DROP":
+		risk = &Risk{Type: RiskDrop, Message: "删除数据库对象"}
+	case "ALTER":
+		for _, one := range scanWords(statement.Sql) {
+			if one.depth == 0 && one.text == "DROP" {
+				risk = &Risk{Type: RiskDrop, Message: "修改结构时删除列、索引或分区"}
+				break
+			}
+		}
+	case "TRUNCATE":
+		risk = &Risk{Type: RiskTruncate, Message: "清空表数据"}
+	case "UPDATE", "DELETE":
+		if !statement.HasWhere {
+			risk = &Risk{Type: RiskNoWhere, Message: statement.Command + " 没有 WHERE 条件，将影响全表"}
+		}
+	}
+	if risk != nil {
+		risk.Sql = statement.Sql
+	}
+	return
+}
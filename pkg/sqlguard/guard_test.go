@@ -0,0 +1,47 @@
+package sqlguard
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		sql      string
+		command  string
+		hasWhere bool
+		countSql string
+	}{
+		{sql: "select * from t where a = 1", command: "SELECT", hasWhere: false},
+		{sql: "DELETE FROM t", command: "DELETE"},
+		{sql: "delete from t where id = 1 limit 10", command: "DELETE", hasWhere: true, countSql: "SELECT COUNT(*) FROM t where id = 1"},
+		{sql: "DELETE t WHERE id = 1;", command: "DELETE", hasWhere: true, countSql: "SELECT COUNT(*) FROM t WHERE id = 1"},
+		{sql: "UPDATE t SET a = (SELECT b FROM x WHERE x.id = 1)", command: "UPDATE"},
+		{sql: "UPDATE t SET a = 'where' -- where\n", command: "UPDATE"},
+		{sql: "update a join b on a.id = b.id set a.x = 1 where b.y = 'it''s'", command: "UPDATE", hasWhere: true, countSql: "SELECT COUNT(*) FROM a join b on a.id = b.id where b.y = 'it''s'"},
+		{sql: "WITH d AS (SELECT id FROM x WHERE y = 1) DELETE FROM t WHERE id IN (SELECT id FROM d)", command: "DELETE", hasWhere: true, countSql: "WITH d AS (SELECT id FROM x WHERE y = 1) SELECT COUNT(*) FROM t WHERE id IN (SELECT id FROM d)"},
+		{sql: "UPDATE t SET a = $$ where $$", command: "UPDATE"},
+	}
+	for _, one := range tests {
+		statement := Parse(one.sql)
+		if statement.Command != one.command || statement.HasWhere != one.hasWhere || statement.CountSql != one.countSql {
+			t.Fatalf("sql [%s] statement %+v", one.sql, statement)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := map[string]string{
+		"drop table t":                    RiskDrop,
+		"ALTER TABLE t DROP COLUMN a":     RiskDrop,
+		"ALTER TABLE t ADD COLUMN a int":  "",
+		"truncate table t":                RiskTruncate,
+		"delete from t":                   RiskNoWhere,
+		"update t set a = 1 where id = 1": "",
+		"insert into t values (1)":        "",
+		"/* drop */ select 1":             "",
+	}
+	for sql, riskType := range tests {
+		risk := Check(Parse(sql))
+		if (risk == nil && riskType != "") || (risk != nil && risk.Type != riskType) {
+			t.Fatalf("sql [%s] risk %+v", sql, risk)
+		}
+	}
+}
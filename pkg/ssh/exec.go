@@ -0,0 +1,73 @@
+package ssh
+
+import (
+	"bytes"
+	"errors"
+	"golang.org/x/crypto/ssh"
+	"teamide/pkg/terminal"
+	"time"
+)
+
+// Exec 连接 远程主机 执行 脚本，执行完成 关闭 连接 及 跳板机 连接
+func Exec(config *Config, script string, timeout time.Duration) (res *terminal.ExecResult) {
+	res = &terminal.ExecResult{
+		StartTime: time.Now().UnixMilli(),
+	}
+	defer func() {
+		res.EndTime = time.Now().UnixMilli()
+		res.Duration = res.EndTime - res.StartTime
+		if config.SSHClient != nil {
+			_ = config.SSHClient.Close()
+		}
+	}()
+
+	if timeout <= 0 {
+		timeout = time.Minute
+	}
+	client, err := NewClient(*config)
+	if err != nil {
+		res.SetError(err)
+		return
+	}
+	defer func() { _ = client.Close() }()
+
+	session, err := client.NewSession()
+	if err != nil {
+		res.SetError(err)
+		return
+	}
+	defer func() { _ = session.Close() }()
+
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	session.Stdout = stdout
+	session.Stderr = stderr
+
+	var done = make(chan error, 1)
+	go func() {
+		done <- session.Run(script)
+	}()
+	select {
+	case err = <-done:
+	case <-time.After(timeout):
+		// 关闭 连接 结束 远程 执行，输出 保留 已读取的 部分
+		_ = session.Close()
+		_ = client.Close()
+		<-done
+		res.Stdout = stdout.String()
+		res.Stderr = stderr.String()
+		res.SetError(terminal.ExecTimeoutError)
+		return
+	}
+	res.Stdout = stdout.String()
+	res.Stderr = stderr.String()
+	if err != nil {
+		var exitError *ssh.ExitError
+		if errors.As(err, &exitError) {
+			res.ExitCode = exitError.ExitStatus()
+			return
+		}
+		res.SetError(err)
+	}
+	return
+}
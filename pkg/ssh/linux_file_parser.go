@@ -6,11 +6,14 @@ import (
 	"github.com/shirou/gopsutil/v3/cpu"
 	"github.com/shirou/gopsutil/v3/disk"
 	"github.com/shirou/gopsutil/v3/mem"
+	"github.com/shirou/gopsutil/v3/net"
+	"github.com/team-ide/go-tool/util"
 	"math"
 	"os"
 	"regexp"
 	"strconv"
 	"strings"
+	"teamide/pkg/system"
 )
 
 func ParseProcCpuInfo(cpuInfoText string, readLines func(filepath string) []string) ([]cpu.InfoStat, error) {
@@ -940,3 +943,73 @@ func getSusePlatform(contents []string) string {
 	}
 	return "suse"
 }
+
+func ParseProcNetDev(netDevText string) ([]net.IOCountersStat, error) {
+	lines := strings.Split(netDevText, "\n")
+	ret := make([]net.IOCountersStat, 0, len(lines))
+
+	// 表头 没有 “:”，会被 忽略
+	for _, line := range lines {
+		separatorPos := strings.LastIndex(line, ":")
+		if separatorPos == -1 {
+			continue
+		}
+		interfaceName := strings.TrimSpace(line[:separatorPos])
+		if interfaceName == "" {
+			continue
+		}
+
+		fields := strings.Fields(strings.TrimSpace(line[separatorPos+1:]))
+		if len(fields) < 16 {
+			continue
+		}
+		var values []uint64
+		for _, i := range []int{0, 1, 2, 3, 4, 8, 9, 10, 11, 12} {
+			value, err := strconv.ParseUint(fields[i], 10, 64)
+			if err != nil {
+				return ret, err
+			}
+			values = append(values, value)
+		}
+		ret = append(ret, net.IOCountersStat{
+			Name:        interfaceName,
+			BytesRecv:   values[0],
+			PacketsRecv: values[1],
+			Errin:       values[2],
+			Dropin:      values[3],
+			Fifoin:      values[4],
+			BytesSent:   values[5],
+			PacketsSent: values[6],
+			Errout:      values[7],
+			Dropout:     values[8],
+			Fifoout:     values[9],
+		})
+	}
+	return ret, nil
+}
+
+// ParseDfText 解析 df -P -B1 输出：Filesystem 1-blocks Used Available Capacity Mounted
+func ParseDfText(dfText string) (res []*system.DiskUsageStat) {
+	lines := strings.Split(dfText, "\n")
+	if len(lines) < 2 {
+		return
+	}
+	for _, line := range lines[1:] {
+		fields := strings.Fields(line)
+		if len(fields) < 6 {
+			continue
+		}
+		d := &system.DiskUsageStat{
+			Path:  strings.Join(fields[5:], " "),
+			Total: util.StringToUint64(fields[1]),
+			Used:  util.StringToUint64(fields[2]),
+			Free:  util.StringToUint64(fields[3]),
+		}
+		if d.Total == 0 {
+			continue
+		}
+		d.UsedPercent = float64(d.Used) / float64(d.Total) * 100
+		res = append(res, d)
+	}
+	return
+}
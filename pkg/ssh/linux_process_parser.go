@@ -0,0 +1,186 @@
+package ssh
+
+import (
+	"encoding/hex"
+	"net"
+	"strconv"
+	"strings"
+	"teamide/pkg/system"
+)
+
+// ProcProcessContext 计算 进程 信息 需要的 主机 参数
+type ProcProcessContext struct {
+	Users    map[string]string // uid -> 用户名
+	ClkTck   float64           // 每秒 时钟 滴答数，getconf CLK_TCK
+	PageSize uint64            // 内存页 大小，getconf PAGESIZE
+	MemTotal uint64
+	BootTime int64 // 启动时间 毫秒
+}
+
+// ParseEtcPasswd 解析 /etc/passwd，返回 uid -> 用户名
+func ParseEtcPasswd(passwdText string) (users map[string]string) {
+	users = make(map[string]string)
+	for _, line := range strings.Split(passwdText, "\n") {
+		fields := strings.Split(line, ":")
+		if len(fields) < 3 {
+			continue
+		}
+		users[fields[2]] = fields[0]
+	}
+	return
+}
+
+// ParseProcProcessText 解析 进程 采集脚本 输出，每个进程 依次为：#pid、stat、cmdline、Uid 行，读取失败的 进程 忽略
+func ParseProcProcessText(text string, context *ProcProcessContext) (res []*system.ProcessInfo) {
+	lines := strings.Split(text, "\n")
+	for i := 0; i < len(lines); i++ {
+		if !strings.HasPrefix(lines[i], "#") {
+			continue
+		}
+		var block []string
+		for j := i + 1; j < len(lines) && !strings.HasPrefix(lines[j], "#"); j++ {
+			block = append(block, lines[j])
+		}
+		if len(block) < 3 {
+			continue
+		}
+		one := parseProcPidStat(block[0], context)
+		if one == nil {
+			continue
+		}
+		one.Cmdline = strings.TrimSpace(block[1])
+		uidFields := strings.Fields(block[2])
+		if len(uidFields) >= 2 && uidFields[0] == "Uid:" {
+			one.Username = context.Users[uidFields[1]]
+			if one.Username == "" {
+				one.Username = uidFields[1]
+			}
+		}
+		res = append(res, one)
+	}
+	return
+}
+
+// parseProcPidStat 解析 /proc/[pid]/stat，进程名 可能包含 空格、括号，以 最后一个 “)” 分隔
+func parseProcPidStat(statText string, context *ProcProcessContext) (one *system.ProcessInfo) {
+	start := strings.Index(statText, "(")
+	end := strings.LastIndex(statText, ")")
+	if start < 0 || end < start {
+		return
+	}
+	pid, err := strconv.ParseInt(strings.TrimSpace(statText[:start]), 10, 32)
+	if err != nil {
+		return
+	}
+	// state ppid pgrp session tty_nr tpgid flags minflt cminflt majflt cmajflt utime stime cutime cstime priority nice num_threads itrealvalue starttime vsize rss
+	fields := strings.Fields(statText[end+1:])
+	if len(fields) < 22 {
+		return
+	}
+	one = &system.ProcessInfo{
+		Pid:    int32(pid),
+		Name:   statText[start+1 : end],
+		Status: fields[0],
+	}
+	ppid, _ := strconv.ParseInt(fields[1], 10, 32)
+	one.Ppid = int32(ppid)
+	numThreads, _ := strconv.ParseInt(fields[17], 10, 32)
+	one.NumThreads = int32(numThreads)
+
+	utime, _ := strconv.ParseFloat(fields[11], 64)
+	stime, _ := strconv.ParseFloat(fields[12], 64)
+	startTime, _ := strconv.ParseFloat(fields[19], 64)
+	rss, _ := strconv.ParseUint(fields[21], 10, 64)
+	if context.ClkTck > 0 {
+		one.CpuTime = (utime + stime) / context.ClkTck
+		if context.BootTime > 0 {
+			one.CreateTime = context.BootTime + int64(startTime*1000/context.ClkTck)
+		}
+	}
+	one.MemRss = rss * context.PageSize
+	if context.MemTotal > 0 {
+		one.MemPercent = float64(one.MemRss) * 100 / float64(context.MemTotal)
+	}
+	return
+}
+
+// ParseProcFdSocket 解析 ls -l /proc/[0-9]*/fd/ 输出，返回 socket inode -> pid
+func ParseProcFdSocket(fdText string) (inodes map[string]int32) {
+	inodes = make(map[string]int32)
+	var pid int32
+	for _, line := range strings.Split(fdText, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "/proc/") && strings.HasSuffix(line, ":") {
+			ss := strings.Split(line, "/")
+			pid = 0
+			if len(ss) > 2 {
+				v, err := strconv.ParseInt(ss[2], 10, 32)
+				if err == nil {
+					pid = int32(v)
+				}
+			}
+			continue
+		}
+		index := strings.Index(line, "socket:[")
+		if index < 0 || pid == 0 {
+			continue
+		}
+		inode := strings.TrimSuffix(line[index+len("socket:["):], "]")
+		inodes[inode] = pid
+	}
+	return
+}
+
+// ParseProcNetSocket 解析 /proc/net/tcp、tcp6、udp、udp6，TCP 只返回 LISTEN，UDP 返回 未连接的 绑定端口
+func ParseProcNetSocket(socketText string, protocol string, inodes map[string]int32) (res []*system.PortInfo) {
+	isTcp := strings.HasPrefix(protocol, "tcp")
+	for _, line := range strings.Split(socketText, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 10 || fields[0] == "sl" {
+			continue
+		}
+		var status string
+		switch {
+		case isTcp && fields[3] == "0A":
+			status = "LISTEN"
+		case !isTcp && fields[3] == "07":
+		default:
+			continue
+		}
+		ip, port, ok := parseProcNetAddress(fields[1])
+		if !ok {
+			continue
+		}
+		res = append(res, &system.PortInfo{
+			Protocol:  protocol,
+			LocalIp:   ip,
+			LocalPort: port,
+			Status:    status,
+			Pid:       inodes[fields[9]],
+		})
+	}
+	return
+}
+
+// parseProcNetAddress 解析 如：0100007F:1F90，IP 按 4 字节 小端 存储
+func parseProcNetAddress(address string) (ip string, port uint32, ok bool) {
+	ss := strings.Split(address, ":")
+	if len(ss) != 2 {
+		return
+	}
+	bs, err := hex.DecodeString(ss[0])
+	if err != nil || (len(bs) != 4 && len(bs) != 16) {
+		return
+	}
+	for i := 0; i < len(bs); i += 4 {
+		bs[i], bs[i+1], bs[i+2], bs[i+3] = bs[i+3], bs[i+2], bs[i+1], bs[i]
+	}
+	p, err := strconv.ParseUint(ss[1], 16, 32)
+	if err != nil {
+		return
+	}
+	ip = net.IP(bs).String()
+	port = uint32(p)
+	ok = true
+	return
+}
@@ -0,0 +1,80 @@
+package ssh
+
+import (
+	"testing"
+)
+
+func TestParseProcProcessText(t *testing.T) {
+	text := `#1
+1 (systemd) S 0 1 1 0 -1 4194560 100 200 10 20 150 50 0 0 20 0 1 0 500 1000 2048 18446744073709551615
+/sbin/init splash 
+Uid:	0	0	0	0
+
+#1234
+1234 (my (app) x) R 1 1234 1234 0 -1 4194560 100 200 10 20 300 100 0 0 20 0 4 0 1000 1000 1024 18446744073709551615
+java -jar app.jar 
+Uid:	1000	1000	1000	1000
+
+#999
+`
+	users := ParseEtcPasswd("root:x:0:0:root:/root:/bin/bash\nteam:x:1000:1000::/home/team:/bin/sh\n")
+	list := ParseProcProcessText(text, &ProcProcessContext{
+		Users:    users,
+		ClkTck:   100,
+		PageSize: 4096,
+		MemTotal: 4096 * 10240,
+		BootTime: 1000000,
+	})
+	if len(list) != 2 {
+		t.Fatalf("process size %d", len(list))
+	}
+	one := list[1]
+	if one.Pid != 1234 || one.Ppid != 1 || one.Name != "my (app) x" || one.Status != "R" || one.Username != "team" {
+		t.Fatalf("process %+v", one)
+	}
+	if one.CpuTime != 4 || one.NumThreads != 4 || one.CreateTime != 1000000+10000 || one.MemRss != 1024*4096 || one.MemPercent != 10 {
+		t.Fatalf("process %+v", one)
+	}
+	if one.Cmdline != "java -jar app.jar" || list[0].Username != "root" {
+		t.Fatalf("process %+v", one)
+	}
+}
+
+func TestParseProcNetSocket(t *testing.T) {
+	inodes := ParseProcFdSocket(`/proc/1234/fd/:
+total 0
+lr-x------ 1 team team 64 Jan  1 10:00 0 -> /dev/null
+lrwx------ 1 team team 64 Jan  1 10:00 5 -> socket:[22222]
+
+/proc/1/fd/:
+lrwx------ 1 root root 64 Jan  1 10:00 7 -> socket:[11111]
+`)
+	if inodes["22222"] != 1234 || inodes["11111"] != 1 {
+		t.Fatalf("inodes %v", inodes)
+	}
+
+	tcp := `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 0100007F:1F90 00000000:0000 0A 00000000:00000000 00:00000000 00000000  1000        0 22222 1 0000000000000000 100 0 0 10 0
+   1: 0100007F:1F90 0100007F:C350 01 00000000:00000000 00:00000000 00000000  1000        0 33333 1 0000000000000000 100 0 0 10 0
+`
+	ports := ParseProcNetSocket(tcp, "tcp", inodes)
+	if len(ports) != 1 || ports[0].LocalIp != "127.0.0.1" || ports[0].LocalPort != 8080 || ports[0].Pid != 1234 || ports[0].Status != "LISTEN" {
+		t.Fatalf("ports %+v", ports[0])
+	}
+
+	tcp6 := `  sl  local_address                         remote_address                        st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000000000000000000001000000:0016 00000000000000000000000000000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 11111 1 0000000000000000 100 0 0 10 0
+`
+	ports = ParseProcNetSocket(tcp6, "tcp6", inodes)
+	if len(ports) != 1 || ports[0].LocalIp != "::1" || ports[0].LocalPort != 22 || ports[0].Pid != 1 {
+		t.Fatalf("ports %+v", ports[0])
+	}
+
+	udp := `   sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode ref pointer drops
+  100: 00000000:0044 00000000:0000 07 00000000:00000000 00:00000000 00000000     0        0 44444 2 0000000000000000 0
+`
+	ports = ParseProcNetSocket(udp, "udp", inodes)
+	if len(ports) != 1 || ports[0].LocalIp != "0.0.0.0" || ports[0].LocalPort != 68 || ports[0].Pid != 0 {
+		t.Fatalf("ports %+v", ports[0])
+	}
+}
@@ -0,0 +1,102 @@
+package ssh
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"teamide/pkg/system"
+	"time"
+)
+
+const (
+	// 每个进程 输出：#pid、stat、cmdline、Uid，进程 可能 在读取过程中 退出，忽略 错误
+	procProcessScript = `cd /proc && for p in [0-9]*; do echo "#$p"; cat $p/stat 2>/dev/null; echo; tr '\0\n' '  ' < $p/cmdline 2>/dev/null; echo; grep -m1 '^Uid:' $p/status 2>/dev/null; echo; done; true`
+	procFdScript      = `ls -l /proc/[0-9]*/fd/ 2>/dev/null; true`
+)
+
+// SystemProcesses 读取 远程主机 /proc 获取 进程列表
+func (this_ *terminalService) SystemProcesses() (res []*system.ProcessInfo, err error) {
+	context := &ProcProcessContext{
+		ClkTck:   100,
+		PageSize: 4096,
+	}
+	confText, _ := this_.runCmd("getconf CLK_TCK; getconf PAGESIZE")
+	confLines := strings.Fields(confText)
+	if len(confLines) == 2 {
+		if v, e := strconv.ParseFloat(confLines[0], 64); e == nil && v > 0 {
+			context.ClkTck = v
+		}
+		if v, e := strconv.ParseUint(confLines[1], 10, 64); e == nil && v > 0 {
+			context.PageSize = v
+		}
+	}
+	statText, err := this_.readSSHFile("/proc/stat")
+	if err != nil {
+		return
+	}
+	for _, line := range strings.Split(statText, "\n") {
+		if strings.HasPrefix(line, "btime ") {
+			bootTime, _ := strconv.ParseInt(strings.TrimSpace(strings.TrimPrefix(line, "btime ")), 10, 64)
+			context.BootTime = bootTime * 1000
+			break
+		}
+	}
+	if memInfo, e := this_.GetMemInfo(); e == nil && memInfo != nil {
+		context.MemTotal = memInfo.Total
+	}
+	passwdText, _ := this_.readSSHFile("/etc/passwd")
+	context.Users = ParseEtcPasswd(passwdText)
+
+	text, err := this_.runCmd(procProcessScript)
+	if err != nil {
+		return
+	}
+	res = ParseProcProcessText(text, context)
+	this_.processCounter.CpuPercents(res, time.Now().UnixMilli())
+	return
+}
+
+// SystemPorts 读取 远程主机 /proc/net 获取 监听端口，非 root 用户 只能 关联 自己的 进程
+func (this_ *terminalService) SystemPorts() (res []*system.PortInfo, err error) {
+	fdText, _ := this_.runCmd(procFdScript)
+	inodes := ParseProcFdSocket(fdText)
+
+	for _, protocol := range []string{"tcp", "tcp6", "udp", "udp6"} {
+		var socketText string
+		socketText, err = this_.readSSHFile("/proc/net/" + protocol)
+		if err != nil {
+			return
+		}
+		res = append(res, ParseProcNetSocket(socketText, protocol, inodes)...)
+	}
+
+	var names = make(map[int32]string)
+	for _, one := range res {
+		if one.Pid == 0 {
+			continue
+		}
+		name, ok := names[one.Pid]
+		if !ok {
+			name, _ = this_.readSSHFile("/proc/" + strconv.Itoa(int(one.Pid)) + "/comm")
+			name = strings.TrimSpace(name)
+			names[one.Pid] = name
+		}
+		one.ProcessName = name
+	}
+	system.SortPorts(res)
+	return
+}
+
+// SystemSignal 使用 kill 命令 向 远程主机 进程 发送信号
+func (this_ *terminalService) SystemSignal(pid int32, signal string) (err error) {
+	if !system.IsSignal(signal) {
+		err = errors.New("signal [" + signal + "] not support")
+		return
+	}
+	if pid <= 0 {
+		err = errors.New("pid [" + strconv.Itoa(int(pid)) + "] error")
+		return
+	}
+	_, err = this_.runCmd("kill -s " + signal + " " + strconv.Itoa(int(pid)))
+	return
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/shirou/gopsutil/v3/cpu"
 	"github.com/shirou/gopsutil/v3/disk"
 	"github.com/shirou/gopsutil/v3/mem"
+	"github.com/shirou/gopsutil/v3/net"
 	"github.com/team-ide/go-tool/util"
 	"go.uber.org/zap"
 	"golang.org/x/crypto/ssh"
@@ -22,30 +23,35 @@ import (
 
 func NewTerminalService(config *Config, lastUser string, lastDir string) (res *terminalService) {
 	res = &terminalService{
-		config:     config,
-		lastUser:   lastUser,
-		lastDir:    lastDir,
-		sshClient2: config.SSHClient,
+		config:         config,
+		lastUser:       lastUser,
+		lastDir:        lastDir,
+		sshClient2:     config.SSHClient,
+		ioCounter:      system.NewIOCounter(),
+		processCounter: system.NewProcessCounter(),
 	}
 	return
 }
 
 type terminalService struct {
-	config       *Config
-	sshClient2   *ssh.Client
-	sshClient    *ssh.Client
-	sshSession   *ssh.Session
-	stdout       io.Reader
-	stdin        io.Writer
-	onClose      func()
-	readeLock    sync.Mutex
-	readeErrLock sync.Mutex
-	writeLock    sync.Mutex
-	isStopped    bool
-	lastActive   time.Time
-	lastUser     string
-	lastDir      string
-	sftpClient   *sftp.Client
+	config         *Config
+	sshClient2     *ssh.Client
+	sshClient      *ssh.Client
+	sshSession     *ssh.Session
+	stdout         io.Reader
+	stdin          io.Writer
+	onClose        func()
+	readeLock      sync.Mutex
+	readeErrLock   sync.Mutex
+	writeLock      sync.Mutex
+	isStopped      bool
+	lastActive     time.Time
+	lastUser       string
+	lastDir        string
+	sftpClient     *sftp.Client
+	sftpLock       sync.Mutex
+	ioCounter      *system.IOCounter
+	processCounter *system.ProcessCounter
 }
 
 func (this_ *terminalService) IsWindows() (isWindows bool, err error) {
@@ -55,10 +61,12 @@ func (this_ *terminalService) IsWindows() (isWindows bool, err error) {
 
 func (this_ *terminalService) Stop() {
 	this_.isStopped = true
+	this_.sftpLock.Lock()
 	if this_.sftpClient != nil {
 		_ = this_.sftpClient.Close()
 		this_.sftpClient = nil
 	}
+	this_.sftpLock.Unlock()
 	if this_.sshSession != nil {
 		_ = this_.sshSession.Close()
 		this_.sshSession = nil
@@ -116,7 +124,10 @@ func (this_ *terminalService) TestClient() (err error) {
 	return
 }
 
-func (this_ *terminalService) readSSHFile(filepath string) (text string, err error) {
+func (this_ *terminalService) getSftpClient() (sftpClient *sftp.Client, err error) {
+	this_.sftpLock.Lock()
+	defer this_.sftpLock.Unlock()
+
 	if this_.sftpClient == nil {
 		if this_.sshClient == nil {
 			err = errors.New("ssh client is null")
@@ -127,7 +138,16 @@ func (this_ *terminalService) readSSHFile(filepath string) (text string, err err
 			return
 		}
 	}
-	f, err := this_.sftpClient.Open(filepath)
+	sftpClient = this_.sftpClient
+	return
+}
+
+func (this_ *terminalService) readSSHFile(filepath string) (text string, err error) {
+	sftpClient, err := this_.getSftpClient()
+	if err != nil {
+		return
+	}
+	f, err := sftpClient.Open(filepath)
 	if err != nil {
 		if os.IsNotExist(err) {
 			err = nil
@@ -164,18 +184,11 @@ func (this_ *terminalService) runCmd(cmd string) (text string, err error) {
 }
 
 func (this_ *terminalService) fileExist(filepath string) (exist bool) {
-	var err error
-	if this_.sftpClient == nil {
-		if this_.sshClient == nil {
-			err = errors.New("ssh client is null")
-			return
-		}
-		this_.sftpClient, err = sftp.NewClient(this_.sshClient)
-		if err != nil {
-			return
-		}
+	sftpClient, err := this_.getSftpClient()
+	if err != nil {
+		return
 	}
-	f, err := this_.sftpClient.Stat(filepath)
+	f, err := sftpClient.Stat(filepath)
 	if err != nil {
 		if os.IsNotExist(err) {
 			err = nil
@@ -320,7 +333,36 @@ func (this_ *terminalService) SystemInfo() (res *system.Info, err error) {
 }
 
 func (this_ *terminalService) SystemMonitorData() (res *system.MonitorData, err error) {
-	return system.GetCacheOrNew()
+	return this_.MonitorData()
+}
+
+// MonitorData 读取 远程主机 /proc 下的文件 采集 监控数据
+func (this_ *terminalService) MonitorData() (res *system.MonitorData, err error) {
+	res = &system.MonitorData{
+		StartTime: util.GetNowMilli(),
+	}
+	defer func() {
+		res.EndTime = util.GetNowMilli()
+	}()
+
+	res.CpuPercents, err = this_.GetCpuPercent()
+	if err != nil {
+		return
+	}
+	memInfo, err := this_.GetMemInfo()
+	if err != nil {
+		return
+	}
+	res.VirtualMemoryStat = system.ToVirtualMemoryStat(memInfo)
+
+	diskStats, _ := this_.GetDiskStats()
+	res.DiskIOCountersStats = this_.ioCounter.DiskIOCounters(diskStats)
+
+	netStats, _ := this_.GetNetStats()
+	res.NetIOCountersStats = this_.ioCounter.NetIOCounters(netStats)
+
+	res.DiskUsageStats, _ = this_.GetDiskUsageStats()
+	return
 }
 
 func (this_ *terminalService) Info() (res *system.Info, err error) {
@@ -384,25 +426,16 @@ func (this_ *terminalService) Info() (res *system.Info, err error) {
 		}
 	}
 
-	text, _ = this_.runCmd("df -B1")
-	lines = strings.Split(text, "\n")
-	if len(lines) > 1 {
-		for _, line := range lines[1:] {
-			line = string(r.ReplaceAll([]byte(line), []byte(" ")))
-			//fmt.Println(line)
-			vs := strings.Split(line, " ")
-			if len(vs) == 6 {
-				d := &system.DiskUsageStat{
-					Path:  vs[5],
-					Total: util.StringToUint64(vs[3]),
-					Used:  util.StringToUint64(vs[2]),
-				}
-				d.Free = d.Total - d.Used
-				d.UsedPercent = float64(d.Used) / float64(d.Total) * 100
-				res.Disks = append(res.Disks, d)
-			}
-		}
+	res.Disks, _ = this_.GetDiskUsageStats()
+	return
+}
+
+func (this_ *terminalService) GetDiskUsageStats() (res []*system.DiskUsageStat, err error) {
+	text, err := this_.runCmd("df -P -B1")
+	if err != nil {
+		return
 	}
+	res = ParseDfText(text)
 	return
 }
 
@@ -619,3 +652,15 @@ func (this_ *terminalService) GetDiskStats() (res map[string]disk.IOCountersStat
 	}
 	return
 }
+
+func (this_ *terminalService) GetNetStats() (res []net.IOCountersStat, err error) {
+	netDevText, err := this_.readSSHFile("/proc/net/dev")
+	if err != nil {
+		return
+	}
+	res, err = ParseProcNetDev(netDevText)
+	if err != nil {
+		return
+	}
+	return
+}
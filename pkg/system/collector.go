@@ -0,0 +1,187 @@
+package system
+
+import (
+	"errors"
+	"fmt"
+	"github.com/team-ide/go-tool/util"
+	"go.uber.org/zap"
+	"sort"
+	"sync"
+	"teamide/pkg/task"
+)
+
+const (
+	// LocalHostKey 本机 主机标识
+	LocalHostKey = "local"
+
+	metricStoreCleanTaskKey = "system-metric-store-clean-task-key"
+)
+
+var (
+	metricStore     *MetricStore
+	metricStoreLock = &sync.Mutex{}
+
+	monitorSources     []*MonitorSource
+	monitorSourcesLock = &sync.Mutex{}
+	collectingHosts    = make(map[string]bool)
+
+	latestMonitorDataCache     = make(map[string]*MonitorData)
+	latestMonitorDataCacheLock = &sync.Mutex{}
+)
+
+// MonitorSource 其它主机的 监控数据 来源，如：SSH 终端、节点，同一主机 有多个来源 只采集 第一个
+type MonitorSource struct {
+	Key     string                       `json:"key"`     // 来源标识，如：终端会话 key
+	HostKey string                       `json:"hostKey"` // 主机标识，如：ssh-1、node-xxx
+	Collect func() (*MonitorData, error) `json:"-"`
+}
+
+func AddMonitorSource(source *MonitorSource) {
+	if source == nil || source.Key == "" || source.HostKey == "" || source.Collect == nil {
+		return
+	}
+	monitorSourcesLock.Lock()
+	defer monitorSourcesLock.Unlock()
+
+	var list []*MonitorSource
+	for _, one := range monitorSources {
+		if one.Key != source.Key {
+			list = append(list, one)
+		}
+	}
+	monitorSources = append(list, source)
+}
+
+func RemoveMonitorSource(key string) {
+	monitorSourcesLock.Lock()
+	defer monitorSourcesLock.Unlock()
+
+	var list []*MonitorSource
+	for _, one := range monitorSources {
+		if one.Key != key {
+			list = append(list, one)
+		}
+	}
+	monitorSources = list
+}
+
+// OpenMetricStore 开启 监控数据 持久化，未开启 只在内存中 保留 最近的数据
+func OpenMetricStore(dir string, saveDays int) (err error) {
+	metricStoreLock.Lock()
+	defer metricStoreLock.Unlock()
+
+	if metricStore != nil {
+		return
+	}
+	store, err := NewMetricStore(dir, DefaultMetricTiers(saveDays))
+	if err != nil {
+		return
+	}
+	metricStore = store
+
+	cleanMetricStore()
+	// 每小时 清理一次
+	err = task.AddCronTask(&task.CronTask{
+		Spec: "0 0 * * * *",
+		Task: &task.Task{
+			Key: metricStoreCleanTaskKey,
+			Do:  cleanMetricStore,
+		},
+	})
+	return
+}
+
+func GetMetricStore() *MetricStore {
+	return metricStore
+}
+
+func cleanMetricStore() {
+	store := metricStore
+	if store == nil {
+		return
+	}
+	flushCount, err := store.FlushIdle()
+	if err != nil {
+		util.Logger.Error("metric store flush idle error", zap.Error(err))
+	}
+	if flushCount > 0 {
+		util.Logger.Info("metric store flush idle end", zap.Any("flushCount", flushCount))
+	}
+	deleteCount, err := store.Clean()
+	if err != nil {
+		util.Logger.Error("metric store clean error", zap.Error(err))
+		return
+	}
+	if deleteCount > 0 {
+		util.Logger.Info("metric store clean end", zap.Any("deleteCount", deleteCount))
+	}
+}
+
+// onMonitorData 采集到 监控数据，记录 最新数据 并 持久化
+func onMonitorData(hostKey string, monitorData *MonitorData) {
+	latestMonitorDataCacheLock.Lock()
+	latestMonitorDataCache[hostKey] = monitorData
+	latestMonitorDataCacheLock.Unlock()
+
+	store := metricStore
+	if store == nil {
+		return
+	}
+	err := store.Append(hostKey, NewMetricPoint(monitorData))
+	if err != nil {
+		util.Logger.Error("metric store append error", zap.Any("hostKey", hostKey), zap.Error(err))
+	}
+}
+
+// GetLatestMonitorData 各个主机 最新的 监控数据
+func GetLatestMonitorData() (res map[string]*MonitorData) {
+	latestMonitorDataCacheLock.Lock()
+	defer latestMonitorDataCacheLock.Unlock()
+
+	res = make(map[string]*MonitorData)
+	for hostKey, one := range latestMonitorDataCache {
+		res[hostKey] = one
+	}
+	return
+}
+
+// collectMonitorSources 采集 其它主机，每个主机 异步采集，上次 未完成 则跳过
+func collectMonitorSources() {
+	monitorSourcesLock.Lock()
+	var hostKeys []string
+	var hostSource = make(map[string]*MonitorSource)
+	for _, one := range monitorSources {
+		if hostSource[one.HostKey] != nil || collectingHosts[one.HostKey] {
+			continue
+		}
+		hostSource[one.HostKey] = one
+		hostKeys = append(hostKeys, one.HostKey)
+		collectingHosts[one.HostKey] = true
+	}
+	monitorSourcesLock.Unlock()
+
+	sort.Strings(hostKeys)
+	for _, hostKey := range hostKeys {
+		go collectMonitorSource(hostSource[hostKey])
+	}
+}
+
+func collectMonitorSource(source *MonitorSource) {
+	defer func() {
+		if e := recover(); e != nil {
+			util.Logger.Error("collect monitor source panic error", zap.Any("hostKey", source.HostKey), zap.Error(errors.New(fmt.Sprint(e))))
+		}
+		monitorSourcesLock.Lock()
+		delete(collectingHosts, source.HostKey)
+		monitorSourcesLock.Unlock()
+	}()
+	monitorData, err := source.Collect()
+	if err != nil {
+		util.Logger.Error("collect monitor source error", zap.Any("hostKey", source.HostKey), zap.Error(err))
+		return
+	}
+	if monitorData == nil {
+		return
+	}
+	onMonitorData(source.HostKey, monitorData)
+}
@@ -10,7 +10,6 @@ import (
 	"go.uber.org/zap"
 	"sync"
 	"teamide/pkg/task"
-	"time"
 )
 
 var (
@@ -52,6 +51,9 @@ func StartCollectMonitorData() {
 					monitorDataList = monitorDataList[len(monitorDataList)-CollectMaxSize+1:]
 				}
 				monitorDataList = append(monitorDataList, monitorData)
+
+				onMonitorData(LocalHostKey, monitorData)
+				collectMonitorSources()
 			},
 		},
 	}
@@ -71,6 +73,9 @@ func QueryMonitorData(request *QueryRequest) (response *QueryResponse) {
 		startTimestamp = request.Timestamp
 		size = request.Size
 	}
+	if request != nil && metricStore != nil && isRangeQuery(request) {
+		return queryStoreMonitorData(request)
+	}
 	if size <= 0 {
 		size = 100
 	}
@@ -93,6 +98,39 @@ func QueryMonitorData(request *QueryRequest) (response *QueryResponse) {
 	return
 }
 
+// isRangeQuery 指定 时间范围 或 其它主机 从 持久化数据 中 查询
+func isRangeQuery(request *QueryRequest) bool {
+	if request.StartTime > 0 || request.EndTime > 0 {
+		return true
+	}
+	return request.HostKey != "" && request.HostKey != LocalHostKey
+}
+
+func queryStoreMonitorData(request *QueryRequest) (response *QueryResponse) {
+	response = &QueryResponse{}
+	startTime := request.StartTime
+	if request.Timestamp > 0 && request.Timestamp >= startTime {
+		startTime = request.Timestamp + 1
+	}
+	tier, points, err := metricStore.Query(request.HostKey, request.Tier, startTime, request.EndTime)
+	if err != nil {
+		util.Logger.Error("metric store query error", zap.Any("request", request), zap.Error(err))
+		return
+	}
+	if tier != nil {
+		response.Tier = tier.Name
+	}
+	for _, one := range points {
+		if request.Size > 0 && len(response.MonitorDataList) >= request.Size {
+			break
+		}
+		response.MonitorDataList = append(response.MonitorDataList, one.ToMonitorData())
+		response.LastTimestamp = one.Time
+	}
+	response.Size = len(response.MonitorDataList)
+	return
+}
+
 func CleanMonitorData() {
 	monitorDataListLock.Lock()
 	defer monitorDataListLock.Unlock()
@@ -147,46 +185,7 @@ func GetInfo() (info *Info) {
 
 	virtualMemoryStat, _ := mem.VirtualMemory()
 	if virtualMemoryStat != nil {
-		info.Memory = &VirtualMemoryStat{
-			Total:          virtualMemoryStat.Total,
-			Available:      virtualMemoryStat.Available,
-			Used:           virtualMemoryStat.Used,
-			UsedPercent:    virtualMemoryStat.UsedPercent,
-			Free:           virtualMemoryStat.Free,
-			Active:         virtualMemoryStat.Active,
-			Inactive:       virtualMemoryStat.Inactive,
-			Wired:          virtualMemoryStat.Wired,
-			Laundry:        virtualMemoryStat.Laundry,
-			Buffers:        virtualMemoryStat.Buffers,
-			Cached:         virtualMemoryStat.Cached,
-			WriteBack:      virtualMemoryStat.WriteBack,
-			Dirty:          virtualMemoryStat.Dirty,
-			WriteBackTmp:   virtualMemoryStat.WriteBackTmp,
-			Shared:         virtualMemoryStat.Shared,
-			Slab:           virtualMemoryStat.Slab,
-			Sreclaimable:   virtualMemoryStat.Sreclaimable,
-			Sunreclaim:     virtualMemoryStat.Sunreclaim,
-			PageTables:     virtualMemoryStat.PageTables,
-			SwapCached:     virtualMemoryStat.SwapCached,
-			CommitLimit:    virtualMemoryStat.CommitLimit,
-			CommittedAS:    virtualMemoryStat.CommittedAS,
-			HighTotal:      virtualMemoryStat.HighTotal,
-			HighFree:       virtualMemoryStat.HighFree,
-			LowTotal:       virtualMemoryStat.LowTotal,
-			LowFree:        virtualMemoryStat.LowFree,
-			SwapTotal:      virtualMemoryStat.SwapTotal,
-			SwapFree:       virtualMemoryStat.SwapFree,
-			Mapped:         virtualMemoryStat.Mapped,
-			VmallocTotal:   virtualMemoryStat.VmallocTotal,
-			VmallocUsed:    virtualMemoryStat.VmallocUsed,
-			VmallocChunk:   virtualMemoryStat.VmallocChunk,
-			HugePagesTotal: virtualMemoryStat.HugePagesTotal,
-			HugePagesFree:  virtualMemoryStat.HugePagesFree,
-			HugePagesRsvd:  virtualMemoryStat.HugePagesRsvd,
-			HugePagesSurp:  virtualMemoryStat.HugePagesSurp,
-			HugePageSize:   virtualMemoryStat.HugePageSize,
-			AnonHugePages:  virtualMemoryStat.AnonHugePages,
-		}
+		info.Memory = ToVirtualMemoryStat(virtualMemoryStat)
 	}
 
 	ps, _ := disk.Partitions(true)
@@ -243,7 +242,47 @@ func GetMonitorData() (monitorData *MonitorData, err error) {
 	if err != nil {
 		return
 	}
-	monitorData.VirtualMemoryStat = &VirtualMemoryStat{
+	monitorData.VirtualMemoryStat = ToVirtualMemoryStat(virtualMemoryStat)
+
+	diskStat, _ := disk.IOCounters("/")
+	monitorData.DiskIOCountersStats = localIOCounter.DiskIOCounters(diskStat)
+
+	netIOCountersStats, _ := net.IOCounters(true)
+	monitorData.NetIOCountersStats = localIOCounter.NetIOCounters(netIOCountersStats)
+
+	monitorData.DiskUsageStats = GetDiskUsageStats()
+	return
+}
+
+// GetDiskUsageStats 物理分区 使用情况
+func GetDiskUsageStats() (list []*DiskUsageStat) {
+	ps, _ := disk.Partitions(false)
+	for _, p := range ps {
+		diskUsageStat, _ := disk.Usage(p.Mountpoint)
+		if diskUsageStat == nil || diskUsageStat.Total == 0 {
+			continue
+		}
+		list = append(list, &DiskUsageStat{
+			Path:              diskUsageStat.Path,
+			Fstype:            diskUsageStat.Fstype,
+			Total:             diskUsageStat.Total,
+			Free:              diskUsageStat.Free,
+			Used:              diskUsageStat.Used,
+			UsedPercent:       diskUsageStat.UsedPercent,
+			InodesTotal:       diskUsageStat.InodesTotal,
+			InodesUsed:        diskUsageStat.InodesUsed,
+			InodesFree:        diskUsageStat.InodesFree,
+			InodesUsedPercent: diskUsageStat.InodesUsedPercent,
+		})
+	}
+	return
+}
+
+var localIOCounter = NewIOCounter()
+
+// ToVirtualMemoryStat 转换 内存信息
+func ToVirtualMemoryStat(virtualMemoryStat *mem.VirtualMemoryStat) *VirtualMemoryStat {
+	return &VirtualMemoryStat{
 		Total:          virtualMemoryStat.Total,
 		Available:      virtualMemoryStat.Available,
 		Used:           virtualMemoryStat.Used,
@@ -283,90 +322,4 @@ func GetMonitorData() (monitorData *MonitorData, err error) {
 		HugePageSize:   virtualMemoryStat.HugePageSize,
 		AnonHugePages:  virtualMemoryStat.AnonHugePages,
 	}
-
-	diskStat, _ := disk.IOCounters("/")
-	diskStatCache := lastDiskIOCountersStatCache
-	newSce := uint64(time.Now().Unix())
-	if diskStat != nil {
-		for _, one := range diskStat {
-			nInfo := &DiskIOCountersStat{
-				Name:             one.Name,
-				ReadCount:        one.ReadCount,
-				MergedReadCount:  one.MergedReadCount,
-				WriteCount:       one.WriteCount,
-				MergedWriteCount: one.MergedWriteCount,
-				ReadBytes:        one.ReadBytes,
-				WriteBytes:       one.WriteBytes,
-				ReadTime:         one.ReadTime,
-				WriteTime:        one.WriteTime,
-				IopsInProgress:   one.IopsInProgress,
-				IoTime:           one.IoTime,
-				WeightedIO:       one.WeightedIO,
-				SerialNumber:     one.SerialNumber,
-				Label:            one.Label,
-			}
-			find := diskStatCache[nInfo.Name]
-			if find.ReadBytes > 0 && nInfo.ReadBytes > find.ReadBytes {
-				nInfo.ReadBytesSpeed = (nInfo.ReadBytes - find.ReadBytes) / (newSce - lastDiskIOCounters)
-			}
-			if find.WriteBytes > 0 && nInfo.WriteBytes > find.WriteBytes {
-				nInfo.WriteBytesSpeed = (nInfo.WriteBytes - find.WriteBytes) / (newSce - lastDiskIOCounters)
-			}
-			if find.ReadCount > 0 && nInfo.ReadCount > find.ReadCount {
-				nInfo.ReadCountIncrease = nInfo.ReadCount - find.ReadCount
-			}
-			if find.WriteCount > 0 && nInfo.WriteCount > find.WriteCount {
-				nInfo.WriteCountIncrease = nInfo.WriteCount - find.WriteCount
-			}
-			monitorData.DiskIOCountersStats = append(monitorData.DiskIOCountersStats, nInfo)
-		}
-	}
-	diskStatCache = make(map[string]disk.IOCountersStat)
-	if diskStat != nil {
-		for _, one := range diskStat {
-			diskStatCache[one.Name] = one
-		}
-	}
-	lastDiskIOCounters = newSce
-	lastDiskIOCountersStatCache = diskStatCache
-
-	newSce = uint64(time.Now().Unix())
-	netIOCountersStats, _ := net.IOCounters(true)
-	netStatCache := lastNetIOCountersStatCache
-	for _, one := range netIOCountersStats {
-		nInfo := &NetIOCountersStat{
-			Name:        one.Name,
-			BytesSent:   one.BytesSent,
-			BytesRecv:   one.BytesRecv,
-			PacketsSent: one.PacketsSent,
-			PacketsRecv: one.PacketsRecv,
-			Errin:       one.Errin,
-			Errout:      one.Errout,
-			Dropin:      one.Dropin,
-			Dropout:     one.Dropout,
-			Fifoin:      one.Fifoin,
-			Fifoout:     one.Fifoout,
-		}
-		find := netStatCache[one.Name]
-		if find.BytesSent > 0 && nInfo.BytesSent > find.BytesSent {
-			nInfo.SpeedSent = (nInfo.BytesSent - find.BytesSent) / (newSce - lastNetIOCounters)
-		}
-		if find.BytesRecv > 0 && nInfo.BytesRecv > find.BytesRecv {
-			nInfo.SpeedRecv = (nInfo.BytesRecv - find.BytesRecv) / (newSce - lastNetIOCounters)
-		}
-		monitorData.NetIOCountersStats = append(monitorData.NetIOCountersStats, nInfo)
-	}
-	netStatCache = make(map[string]net.IOCountersStat)
-	for _, one := range netIOCountersStats {
-		netStatCache[one.Name] = one
-	}
-	lastNetIOCounters = newSce
-	lastNetIOCountersStatCache = netStatCache
-	return
 }
-
-var lastDiskIOCounters uint64
-var lastDiskIOCountersStatCache = make(map[string]disk.IOCountersStat)
-
-var lastNetIOCounters uint64
-var lastNetIOCountersStatCache = make(map[string]net.IOCountersStat)
@@ -0,0 +1,115 @@
+package system
+
+import (
+	"github.com/shirou/gopsutil/v3/disk"
+	"github.com/shirou/gopsutil/v3/net"
+	"sync"
+	"time"
+)
+
+// NewIOCounter 磁盘、网络 计数器，根据 上次采集的 计数 计算 速度，每个主机 使用独立的 IOCounter
+func NewIOCounter() *IOCounter {
+	return &IOCounter{
+		lastDiskIOCountersStatCache: make(map[string]disk.IOCountersStat),
+		lastNetIOCountersStatCache:  make(map[string]net.IOCountersStat),
+	}
+}
+
+type IOCounter struct {
+	lastDiskIOCounters          uint64
+	lastDiskIOCountersStatCache map[string]disk.IOCountersStat
+	lastNetIOCounters           uint64
+	lastNetIOCountersStatCache  map[string]net.IOCountersStat
+	lock                        sync.Mutex
+}
+
+func (this_ *IOCounter) DiskIOCounters(diskStat map[string]disk.IOCountersStat) (list []*DiskIOCountersStat) {
+	this_.lock.Lock()
+	defer this_.lock.Unlock()
+
+	diskStatCache := this_.lastDiskIOCountersStatCache
+	newSce := uint64(time.Now().Unix())
+	useSce := newSce - this_.lastDiskIOCounters
+	for _, one := range diskStat {
+		nInfo := &DiskIOCountersStat{
+			Name:             one.Name,
+			ReadCount:        one.ReadCount,
+			MergedReadCount:  one.MergedReadCount,
+			WriteCount:       one.WriteCount,
+			MergedWriteCount: one.MergedWriteCount,
+			ReadBytes:        one.ReadBytes,
+			WriteBytes:       one.WriteBytes,
+			ReadTime:         one.ReadTime,
+			WriteTime:        one.WriteTime,
+			IopsInProgress:   one.IopsInProgress,
+			IoTime:           one.IoTime,
+			WeightedIO:       one.WeightedIO,
+			SerialNumber:     one.SerialNumber,
+			Label:            one.Label,
+		}
+		find := diskStatCache[nInfo.Name]
+		if useSce > 0 {
+			if find.ReadBytes > 0 && nInfo.ReadBytes > find.ReadBytes {
+				nInfo.ReadBytesSpeed = (nInfo.ReadBytes - find.ReadBytes) / useSce
+			}
+			if find.WriteBytes > 0 && nInfo.WriteBytes > find.WriteBytes {
+				nInfo.WriteBytesSpeed = (nInfo.WriteBytes - find.WriteBytes) / useSce
+			}
+		}
+		if find.ReadCount > 0 && nInfo.ReadCount > find.ReadCount {
+			nInfo.ReadCountIncrease = nInfo.ReadCount - find.ReadCount
+		}
+		if find.WriteCount > 0 && nInfo.WriteCount > find.WriteCount {
+			nInfo.WriteCountIncrease = nInfo.WriteCount - find.WriteCount
+		}
+		list = append(list, nInfo)
+	}
+	diskStatCache = make(map[string]disk.IOCountersStat)
+	for _, one := range diskStat {
+		diskStatCache[one.Name] = one
+	}
+	this_.lastDiskIOCounters = newSce
+	this_.lastDiskIOCountersStatCache = diskStatCache
+	return
+}
+
+func (this_ *IOCounter) NetIOCounters(netIOCountersStats []net.IOCountersStat) (list []*NetIOCountersStat) {
+	this_.lock.Lock()
+	defer this_.lock.Unlock()
+
+	netStatCache := this_.lastNetIOCountersStatCache
+	newSce := uint64(time.Now().Unix())
+	useSce := newSce - this_.lastNetIOCounters
+	for _, one := range netIOCountersStats {
+		nInfo := &NetIOCountersStat{
+			Name:        one.Name,
+			BytesSent:   one.BytesSent,
+			BytesRecv:   one.BytesRecv,
+			PacketsSent: one.PacketsSent,
+			PacketsRecv: one.PacketsRecv,
+			Errin:       one.Errin,
+			Errout:      one.Errout,
+			Dropin:      one.Dropin,
+			Dropout:     one.Dropout,
+			Fifoin:      one.Fifoin,
+			Fifoout:     one.Fifoout,
+		}
+		find := netStatCache[one.Name]
+		if useSce > 0 {
+			if find.BytesSent > 0 && nInfo.BytesSent > find.BytesSent {
+				nInfo.SpeedSent = (nInfo.BytesSent - find.BytesSent) / useSce
+			}
+			if find.BytesRecv > 0 && nInfo.BytesRecv > find.BytesRecv {
+				nInfo.SpeedRecv = (nInfo.BytesRecv - find.BytesRecv) / useSce
+			}
+		}
+		list = append(list, nInfo)
+	}
+	netStatCache = make(map[string]net.IOCountersStat)
+	for _, one := range netIOCountersStats {
+		netStatCache[one.Name] = one
+	}
+	this_.lastNetIOCounters = newSce
+	this_.lastNetIOCountersStatCache = netStatCache
+	return
+}
@@ -0,0 +1,248 @@
+package system
+
+import (
+	"errors"
+	"github.com/shirou/gopsutil/v3/net"
+	"github.com/shirou/gopsutil/v3/process"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+type ProcessInfo struct {
+	Pid        int32   `json:"pid"`
+	Ppid       int32   `json:"ppid"`
+	Name       string  `json:"name,omitempty"`
+	Username   string  `json:"username,omitempty"`
+	Status     string  `json:"status,omitempty"`
+	Cmdline    string  `json:"cmdline,omitempty"`
+	CpuTime    float64 `json:"cpuTime"` // 用户态 + 内核态 累计 CPU 秒数
+	CpuPercent float64 `json:"cpuPercent"`
+	MemRss     uint64  `json:"memRss"`
+	MemPercent float64 `json:"memPercent"`
+	NumThreads int32   `json:"numThreads"`
+	CreateTime int64   `json:"createTime,omitempty"` // 启动时间 毫秒
+}
+
+// ProcessTree 进程树，父进程 不存在的 作为 根节点
+type ProcessTree struct {
+	*ProcessInfo
+	Children []*ProcessTree `json:"children,omitempty"`
+}
+
+// PortInfo 监听的 端口，TCP 只返回 LISTEN 状态
+type PortInfo struct {
+	Protocol    string `json:"protocol,omitempty"` // tcp、tcp6、udp、udp6
+	LocalIp     string `json:"localIp,omitempty"`
+	LocalPort   uint32 `json:"localPort"`
+	Status      string `json:"status,omitempty"`
+	Pid         int32  `json:"pid"`
+	ProcessName string `json:"processName,omitempty"`
+}
+
+const (
+	SignalTerm = "TERM"
+	SignalKill = "KILL"
+	SignalInt  = "INT"
+	SignalHup  = "HUP"
+	SignalStop = "STOP"
+	SignalCont = "CONT"
+)
+
+var (
+	Signals = []string{SignalTerm, SignalKill, SignalInt, SignalHup, SignalStop, SignalCont}
+)
+
+func IsSignal(signal string) bool {
+	for _, one := range Signals {
+		if one == signal {
+			return true
+		}
+	}
+	return false
+}
+
+// BuildProcessTree 根据 父进程ID 组装 进程树，按 PID 排序
+func BuildProcessTree(list []*ProcessInfo) (roots []*ProcessTree) {
+	var nodes = make(map[int32]*ProcessTree)
+	var sorted = make([]*ProcessInfo, len(list))
+	copy(sorted, list)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Pid < sorted[j].Pid
+	})
+	for _, one := range sorted {
+		nodes[one.Pid] = &ProcessTree{ProcessInfo: one}
+	}
+	for _, one := range sorted {
+		node := nodes[one.Pid]
+		parent := nodes[one.Ppid]
+		if parent == nil || one.Ppid == one.Pid {
+			roots = append(roots, node)
+			continue
+		}
+		parent.Children = append(parent.Children, node)
+	}
+	return
+}
+
+// NewProcessCounter 进程 CPU 计数器，根据 上次采集的 CPU 时间 计算 使用率，首次 使用 启动以来的 平均值
+func NewProcessCounter() *ProcessCounter {
+	return &ProcessCounter{
+		lastCache: make(map[int32]*processSample),
+	}
+}
+
+type ProcessCounter struct {
+	lastCache map[int32]*processSample
+	lastTime  int64
+	lock      sync.Mutex
+}
+
+type processSample struct {
+	createTime int64
+	cpuTime    float64
+}
+
+// CpuPercents 计算 CpuPercent，nowMilli 为 采集时间
+func (this_ *ProcessCounter) CpuPercents(list []*ProcessInfo, nowMilli int64) {
+	this_.lock.Lock()
+	defer this_.lock.Unlock()
+
+	var cache = make(map[int32]*processSample)
+	useMilli := nowMilli - this_.lastTime
+	for _, one := range list {
+		cache[one.Pid] = &processSample{createTime: one.CreateTime, cpuTime: one.CpuTime}
+		find := this_.lastCache[one.Pid]
+		if find != nil && find.createTime == one.CreateTime && useMilli > 0 {
+			if one.CpuTime > find.cpuTime {
+				one.CpuPercent = (one.CpuTime - find.cpuTime) * 1000 * 100 / float64(useMilli)
+			}
+			continue
+		}
+		if one.CreateTime > 0 && nowMilli > one.CreateTime {
+			one.CpuPercent = one.CpuTime * 1000 * 100 / float64(nowMilli-one.CreateTime)
+		}
+	}
+	this_.lastCache = cache
+	this_.lastTime = nowMilli
+}
+
+var (
+	localProcessCounter = NewProcessCounter()
+)
+
+// GetProcesses 本机 进程列表，单个进程 读取失败的 字段 忽略
+func GetProcesses() (list []*ProcessInfo, err error) {
+	processes, err := process.Processes()
+	if err != nil {
+		return
+	}
+	for _, p := range processes {
+		one := &ProcessInfo{
+			Pid: p.Pid,
+		}
+		one.Ppid, _ = p.Ppid()
+		one.Name, _ = p.Name()
+		one.Username, _ = p.Username()
+		status, _ := p.Status()
+		one.Status = strings.Join(status, ",")
+		one.Cmdline, _ = p.Cmdline()
+		if times, _ := p.Times(); times != nil {
+			one.CpuTime = times.User + times.System
+		}
+		if memoryInfo, _ := p.MemoryInfo(); memoryInfo != nil {
+			one.MemRss = memoryInfo.RSS
+		}
+		memPercent, _ := p.MemoryPercent()
+		one.MemPercent = float64(memPercent)
+		one.NumThreads, _ = p.NumThreads()
+		one.CreateTime, _ = p.CreateTime()
+		list = append(list, one)
+	}
+	localProcessCounter.CpuPercents(list, time.Now().UnixMilli())
+	return
+}
+
+// GetPorts 本机 监听的 端口
+func GetPorts() (list []*PortInfo, err error) {
+	connections, err := net.Connections("inet")
+	if err != nil {
+		return
+	}
+	var names = make(map[int32]string)
+	for _, one := range connections {
+		var protocol string
+		switch one.Type {
+		case syscall.SOCK_STREAM:
+			if one.Status != "LISTEN" {
+				continue
+			}
+			protocol = "tcp"
+		case syscall.SOCK_DGRAM:
+			protocol = "udp"
+		default:
+			continue
+		}
+		if one.Family == syscall.AF_INET6 {
+			protocol += "6"
+		}
+		port := &PortInfo{
+			Protocol:  protocol,
+			LocalIp:   one.Laddr.IP,
+			LocalPort: one.Laddr.Port,
+			Status:    one.Status,
+			Pid:       one.Pid,
+		}
+		if one.Pid > 0 {
+			name, ok := names[one.Pid]
+			if !ok {
+				if p, e := process.NewProcess(one.Pid); e == nil {
+					name, _ = p.Name()
+				}
+				names[one.Pid] = name
+			}
+			port.ProcessName = name
+		}
+		list = append(list, port)
+	}
+	SortPorts(list)
+	return
+}
+
+func SortPorts(list []*PortInfo) {
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].LocalPort == list[j].LocalPort {
+			return list[i].Protocol < list[j].Protocol
+		}
+		return list[i].LocalPort < list[j].LocalPort
+	})
+}
+
+// SignalProcess 向 本机 进程 发送信号
+func SignalProcess(pid int32, signal string) (err error) {
+	if !IsSignal(signal) {
+		err = errors.New("signal [" + signal + "] not support")
+		return
+	}
+	p, err := process.NewProcess(pid)
+	if err != nil {
+		return
+	}
+	switch signal {
+	case SignalTerm:
+		err = p.Terminate()
+	case SignalKill:
+		err = p.Kill()
+	case SignalStop:
+		err = p.Suspend()
+	case SignalCont:
+		err = p.Resume()
+	case SignalInt:
+		err = p.SendSignal(syscall.SIGINT)
+	case SignalHup:
+		err = p.SendSignal(syscall.SIGHUP)
+	}
+	return
+}
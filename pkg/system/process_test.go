@@ -0,0 +1,51 @@
+package system
+
+import (
+	"testing"
+)
+
+func TestBuildProcessTree(t *testing.T) {
+	roots := BuildProcessTree([]*ProcessInfo{
+		{Pid: 30, Ppid: 2},
+		{Pid: 2, Ppid: 1},
+		{Pid: 1, Ppid: 0},
+		{Pid: 20, Ppid: 2},
+		{Pid: 50, Ppid: 40},
+	})
+	if len(roots) != 2 || roots[0].Pid != 1 || roots[1].Pid != 50 {
+		t.Fatalf("roots %+v", roots)
+	}
+	children := roots[0].Children[0].Children
+	if len(children) != 2 || children[0].Pid != 20 || children[1].Pid != 30 {
+		t.Fatalf("children %+v", children)
+	}
+}
+
+func TestProcessCounter(t *testing.T) {
+	counter := NewProcessCounter()
+	list := []*ProcessInfo{{Pid: 1, CreateTime: 10000, CpuTime: 5}, {Pid: 2, CreateTime: 10000, CpuTime: 5}}
+	counter.CpuPercents(list, 20000)
+	// 首次 使用 启动以来的 平均值
+	if list[0].CpuPercent != 50 || list[1].CpuPercent != 50 {
+		t.Fatalf("cpu percent %v %v", list[0].CpuPercent, list[1].CpuPercent)
+	}
+	list = []*ProcessInfo{{Pid: 1, CreateTime: 10000, CpuTime: 6}, {Pid: 2, CreateTime: 15000, CpuTime: 1}}
+	counter.CpuPercents(list, 22000)
+	if list[0].CpuPercent != 50 || list[1].CpuPercent != 1.0/7*100 {
+		t.Fatalf("cpu percent %v %v", list[0].CpuPercent, list[1].CpuPercent)
+	}
+}
+
+func TestGetProcesses(t *testing.T) {
+	list, err := GetProcesses()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) == 0 {
+		t.Fatal("process list is empty")
+	}
+	_, err = GetPorts()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
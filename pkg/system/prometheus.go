@@ -0,0 +1,97 @@
+package system
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type prometheusMetric struct {
+	name    string
+	help    string
+	typ     string
+	samples []string
+}
+
+// WritePrometheus 按 Prometheus 文本格式 输出 各个主机 最新的 监控数据
+func WritePrometheus(w io.Writer) (err error) {
+	latest := GetLatestMonitorData()
+	var hostKeys []string
+	for hostKey := range latest {
+		hostKeys = append(hostKeys, hostKey)
+	}
+	sort.Strings(hostKeys)
+
+	var metrics []*prometheusMetric
+	var metricCache = make(map[string]*prometheusMetric)
+	add := func(name string, typ string, help string, labels [][2]string, value string) {
+		metric := metricCache[name]
+		if metric == nil {
+			metric = &prometheusMetric{name: name, typ: typ, help: help}
+			metricCache[name] = metric
+			metrics = append(metrics, metric)
+		}
+		var ls []string
+		for _, label := range labels {
+			ls = append(ls, label[0]+`="`+escapePrometheusLabel(label[1])+`"`)
+		}
+		metric.samples = append(metric.samples, name+"{"+strings.Join(ls, ",")+"} "+value)
+	}
+	formatUint := func(v uint64) string { return strconv.FormatUint(v, 10) }
+	formatFloat := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+
+	for _, hostKey := range hostKeys {
+		one := latest[hostKey]
+		host := [2]string{"host", hostKey}
+		add("teamide_monitor_collect_timestamp_seconds", "gauge", "Time of the last monitor data collect.", [][2]string{host}, formatFloat(float64(one.StartTime)/1000))
+		for i, v := range one.CpuPercents {
+			add("teamide_cpu_usage_percent", "gauge", "CPU usage percent per core.", [][2]string{host, {"cpu", strconv.Itoa(i)}}, formatFloat(v))
+		}
+		if one.VirtualMemoryStat != nil {
+			add("teamide_memory_total_bytes", "gauge", "Total memory in bytes.", [][2]string{host}, formatUint(one.VirtualMemoryStat.Total))
+			add("teamide_memory_used_bytes", "gauge", "Used memory in bytes.", [][2]string{host}, formatUint(one.VirtualMemoryStat.Used))
+			add("teamide_memory_used_percent", "gauge", "Used memory percent.", [][2]string{host}, formatFloat(one.VirtualMemoryStat.UsedPercent))
+		}
+		for _, n := range one.NetIOCountersStats {
+			labels := [][2]string{host, {"interface", n.Name}}
+			add("teamide_network_sent_bytes_total", "counter", "Network bytes sent.", labels, formatUint(n.BytesSent))
+			add("teamide_network_received_bytes_total", "counter", "Network bytes received.", labels, formatUint(n.BytesRecv))
+			add("teamide_network_sent_bytes_per_second", "gauge", "Network bytes sent per second.", labels, formatUint(n.SpeedSent))
+			add("teamide_network_received_bytes_per_second", "gauge", "Network bytes received per second.", labels, formatUint(n.SpeedRecv))
+		}
+		for _, d := range one.DiskUsageStats {
+			labels := [][2]string{host, {"path", d.Path}}
+			add("teamide_filesystem_size_bytes", "gauge", "Filesystem size in bytes.", labels, formatUint(d.Total))
+			add("teamide_filesystem_used_bytes", "gauge", "Filesystem used in bytes.", labels, formatUint(d.Used))
+			add("teamide_filesystem_used_percent", "gauge", "Filesystem used percent.", labels, formatFloat(d.UsedPercent))
+		}
+		for _, d := range one.DiskIOCountersStats {
+			labels := [][2]string{host, {"device", d.Name}}
+			add("teamide_disk_read_bytes_total", "counter", "Disk bytes read.", labels, formatUint(d.ReadBytes))
+			add("teamide_disk_written_bytes_total", "counter", "Disk bytes written.", labels, formatUint(d.WriteBytes))
+			add("teamide_disk_read_bytes_per_second", "gauge", "Disk bytes read per second.", labels, formatUint(d.ReadBytesSpeed))
+			add("teamide_disk_written_bytes_per_second", "gauge", "Disk bytes written per second.", labels, formatUint(d.WriteBytesSpeed))
+		}
+	}
+
+	writer := bufio.NewWriter(w)
+	for _, metric := range metrics {
+		_, _ = fmt.Fprintf(writer, "# HELP %s %s\n", metric.name, metric.help)
+		_, _ = fmt.Fprintf(writer, "# TYPE %s %s\n", metric.name, metric.typ)
+		for _, sample := range metric.samples {
+			_, _ = writer.WriteString(sample + "\n")
+		}
+	}
+	err = writer.Flush()
+	return
+}
+
+func escapePrometheusLabel(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, "\n", `\n`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	return value
+}
@@ -0,0 +1,672 @@
+package system
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"github.com/team-ide/go-tool/util"
+	"go.uber.org/zap"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MetricTier 数据精度，Interval 为 0 表示 原始采集数据（10秒），其它 按 Interval 降采样 取平均值
+type MetricTier struct {
+	Name     string `json:"name"`
+	Interval int64  `json:"interval"` // 毫秒
+	SaveDays int    `json:"saveDays"` // 保留天数
+}
+
+var (
+	// metricQueryMaxPoints 自动选择精度时，单次查询 最多 点数
+	metricQueryMaxPoints int64 = 2000
+	// metricQueryDefaultSpan 未 指定 开始时间 时 查询 最近 1小时
+	metricQueryDefaultSpan int64 = 60 * 60 * 1000
+	// metricBucketMaxPoints 降采样 时间段 最多 累计 点数，超过 的 不再 参与 平均
+	metricBucketMaxPoints = 1000
+	// metricRecordMaxSize 单条 记录 最大 长度，超过 认为 文件 损坏
+	metricRecordMaxSize uint64 = 64 * 1024
+	metricFileSuffix           = ".dat"
+	// metricBucketFileName 降采样 当前时间段 的 原始数据，重启 后 恢复
+	metricBucketFileName = "bucket" + metricFileSuffix
+	metricDayLayout      = "20060102"
+	metricHostKeyRegexp  = regexp.MustCompile(`[^A-Za-z0-9_.-]`)
+)
+
+// DefaultMetricTiers 原始数据 保留1天，分钟数据 保留7天，小时数据 保留 saveDays 天
+func DefaultMetricTiers(saveDays int) []*MetricTier {
+	if saveDays <= 0 {
+		saveDays = 30
+	}
+	return []*MetricTier{
+		{Name: "raw", Interval: 0, SaveDays: 1},
+		{Name: "1m", Interval: 60 * 1000, SaveDays: 7},
+		{Name: "1h", Interval: 60 * 60 * 1000, SaveDays: saveDays},
+	}
+}
+
+// MetricPoint 持久化的 监控点，只保留 图表 需要的 字段
+type MetricPoint struct {
+	Time           int64
+	CpuPercents    []float64
+	MemTotal       uint64
+	MemUsed        uint64
+	MemUsedPercent float64
+	Nets           []*MetricIO // Out：发送，In：接收
+	Disks          []*MetricIO // Out：写，In：读
+}
+
+type MetricIO struct {
+	Name     string
+	OutBytes uint64
+	InBytes  uint64
+	OutSpeed uint64
+	InSpeed  uint64
+}
+
+func NewMetricPoint(monitorData *MonitorData) (point *MetricPoint) {
+	point = &MetricPoint{
+		Time:        monitorData.StartTime,
+		CpuPercents: monitorData.CpuPercents,
+	}
+	if monitorData.VirtualMemoryStat != nil {
+		point.MemTotal = monitorData.VirtualMemoryStat.Total
+		point.MemUsed = monitorData.VirtualMemoryStat.Used
+		point.MemUsedPercent = monitorData.VirtualMemoryStat.UsedPercent
+	}
+	for _, one := range monitorData.NetIOCountersStats {
+		point.Nets = append(point.Nets, &MetricIO{
+			Name:     one.Name,
+			OutBytes: one.BytesSent,
+			InBytes:  one.BytesRecv,
+			OutSpeed: one.SpeedSent,
+			InSpeed:  one.SpeedRecv,
+		})
+	}
+	for _, one := range monitorData.DiskIOCountersStats {
+		point.Disks = append(point.Disks, &MetricIO{
+			Name:     one.Name,
+			OutBytes: one.WriteBytes,
+			InBytes:  one.ReadBytes,
+			OutSpeed: one.WriteBytesSpeed,
+			InSpeed:  one.ReadBytesSpeed,
+		})
+	}
+	return
+}
+
+func (this_ *MetricPoint) ToMonitorData() (monitorData *MonitorData) {
+	monitorData = &MonitorData{
+		StartTime:   this_.Time,
+		EndTime:     this_.Time,
+		CpuPercents: this_.CpuPercents,
+		VirtualMemoryStat: &VirtualMemoryStat{
+			Total:       this_.MemTotal,
+			Used:        this_.MemUsed,
+			UsedPercent: this_.MemUsedPercent,
+		},
+	}
+	for _, one := range this_.Nets {
+		monitorData.NetIOCountersStats = append(monitorData.NetIOCountersStats, &NetIOCountersStat{
+			Name:      one.Name,
+			BytesSent: one.OutBytes,
+			BytesRecv: one.InBytes,
+			SpeedSent: one.OutSpeed,
+			SpeedRecv: one.InSpeed,
+		})
+	}
+	for _, one := range this_.Disks {
+		monitorData.DiskIOCountersStats = append(monitorData.DiskIOCountersStats, &DiskIOCountersStat{
+			Name:            one.Name,
+			WriteBytes:      one.OutBytes,
+			ReadBytes:       one.InBytes,
+			WriteBytesSpeed: one.OutSpeed,
+			ReadBytesSpeed:  one.InSpeed,
+		})
+	}
+	return
+}
+
+// NewMetricStore 监控数据 持久化，目录结构：{dir}/{hostKey}/{tier}/{yyyyMMdd}.dat
+func NewMetricStore(dir string, tiers []*MetricTier) (store *MetricStore, err error) {
+	if dir == "" {
+		err = errors.New("metric store dir is empty")
+		return
+	}
+	if len(tiers) == 0 {
+		tiers = DefaultMetricTiers(0)
+	}
+	dir = util.FormatPath(dir)
+	if !strings.HasSuffix(dir, "/") {
+		dir += "/"
+	}
+	err = os.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		return
+	}
+	store = &MetricStore{
+		dir:     dir,
+		tiers:   tiers,
+		buckets: make(map[string]*metricBucket),
+	}
+	err = store.loadBuckets()
+	return
+}
+
+type MetricStore struct {
+	dir     string
+	tiers   []*MetricTier
+	buckets map[string]*metricBucket
+	lock    sync.Mutex
+}
+
+// metricBucket 降采样 当前时间段 的 累计数据，同时 追加 到 bucket 文件 中
+type metricBucket struct {
+	hostKey string
+	tier    *MetricTier
+	start   int64
+	points  []*MetricPoint
+}
+
+func (this_ *MetricStore) getBucketFilePath(hostKey string, tier *MetricTier) string {
+	return this_.dir + hostKey + "/" + tier.Name + "/" + metricBucketFileName
+}
+
+// loadBuckets 恢复 上次 退出 时 未 结束 的 时间段
+func (this_ *MetricStore) loadBuckets() (err error) {
+	hostDirs, err := os.ReadDir(this_.dir)
+	if err != nil {
+		return
+	}
+	for _, hostDir := range hostDirs {
+		if !hostDir.IsDir() {
+			continue
+		}
+		for _, tier := range this_.tiers {
+			if tier.Interval <= 0 {
+				continue
+			}
+			var points []*MetricPoint
+			points, err = readMetricFile(this_.getBucketFilePath(hostDir.Name(), tier))
+			if err != nil {
+				return
+			}
+			if len(points) == 0 {
+				continue
+			}
+			start := points[0].Time - points[0].Time%tier.Interval
+			bucket := &metricBucket{hostKey: hostDir.Name(), tier: tier, start: start}
+			for _, one := range points {
+				if one.Time-one.Time%tier.Interval == start && len(bucket.points) < metricBucketMaxPoints {
+					bucket.points = append(bucket.points, one)
+				}
+			}
+			this_.buckets[hostDir.Name()+"/"+tier.Name] = bucket
+		}
+	}
+	return
+}
+
+// flushBucket 写入 时间段 平均值，并 删除 bucket 文件
+func (this_ *MetricStore) flushBucket(bucketKey string, bucket *metricBucket) (err error) {
+	err = this_.write(bucket.hostKey, bucket.tier, aggregateMetricPoints(bucket.start, bucket.points))
+	if err != nil {
+		return
+	}
+	delete(this_.buckets, bucketKey)
+	err = os.Remove(this_.getBucketFilePath(bucket.hostKey, bucket.tier))
+	if os.IsNotExist(err) {
+		err = nil
+	}
+	return
+}
+
+// FlushIdle 写入 已 结束 且 超过 一个 周期 未 收到 数据 的 时间段，如：主机 不再 上报
+func (this_ *MetricStore) FlushIdle() (flushCount int, err error) {
+	this_.lock.Lock()
+	defer this_.lock.Unlock()
+
+	nowTime := util.GetNowMilli()
+	for bucketKey, bucket := range this_.buckets {
+		if bucket.start+2*bucket.tier.Interval > nowTime {
+			continue
+		}
+		err = this_.flushBucket(bucketKey, bucket)
+		if err != nil {
+			return
+		}
+		flushCount++
+	}
+	return
+}
+
+func FormatHostKey(hostKey string) string {
+	if hostKey == "" {
+		return LocalHostKey
+	}
+	return metricHostKeyRegexp.ReplaceAllString(hostKey, "_")
+}
+
+func (this_ *MetricStore) GetTiers() []*MetricTier {
+	return this_.tiers
+}
+
+func (this_ *MetricStore) getTier(name string) *MetricTier {
+	for _, one := range this_.tiers {
+		if one.Name == name {
+			return one
+		}
+	}
+	return nil
+}
+
+// Append 写入 原始数据，并 累计到 各个 降采样 精度，时间段 结束后 写入 平均值
+func (this_ *MetricStore) Append(hostKey string, point *MetricPoint) (err error) {
+	hostKey = FormatHostKey(hostKey)
+
+	this_.lock.Lock()
+	defer this_.lock.Unlock()
+
+	for _, tier := range this_.tiers {
+		if tier.Interval <= 0 {
+			err = this_.write(hostKey, tier, point)
+			if err != nil {
+				return
+			}
+			continue
+		}
+		start := point.Time - point.Time%tier.Interval
+		bucketKey := hostKey + "/" + tier.Name
+		bucket := this_.buckets[bucketKey]
+		if bucket != nil && bucket.start != start {
+			err = this_.flushBucket(bucketKey, bucket)
+			if err != nil {
+				return
+			}
+			bucket = nil
+		}
+		if bucket == nil {
+			bucket = &metricBucket{hostKey: hostKey, tier: tier, start: start}
+			this_.buckets[bucketKey] = bucket
+		}
+		if len(bucket.points) >= metricBucketMaxPoints {
+			continue
+		}
+		bucket.points = append(bucket.points, point)
+		err = this_.appendFile(this_.getBucketFilePath(hostKey, tier), point)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (this_ *MetricStore) getFilePath(hostKey string, tier *MetricTier, day time.Time) string {
+	return this_.dir + hostKey + "/" + tier.Name + "/" + day.Format(metricDayLayout) + metricFileSuffix
+}
+
+func (this_ *MetricStore) write(hostKey string, tier *MetricTier, point *MetricPoint) (err error) {
+	return this_.appendFile(this_.getFilePath(hostKey, tier, time.UnixMilli(point.Time)), point)
+}
+
+func (this_ *MetricStore) appendFile(path string, point *MetricPoint) (err error) {
+	err = os.MkdirAll(filepath.Dir(path), os.ModePerm)
+	if err != nil {
+		return
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		return
+	}
+	defer func() { _ = f.Close() }()
+
+	_, err = f.Write(encodeMetricRecord(point))
+	return
+}
+
+// ChooseTier 根据 开始时间 及 时间范围 选择 保留数据 且 点数 不超过 上限 的 最高精度
+func (this_ *MetricStore) ChooseTier(startTime int64, endTime int64) (tier *MetricTier) {
+	span := endTime - startTime
+	nowTime := util.GetNowMilli()
+	for _, one := range this_.tiers {
+		tier = one
+		if startTime < nowTime-int64(one.SaveDays)*24*60*60*1000 {
+			continue
+		}
+		interval := one.Interval
+		if interval <= 0 {
+			interval = 10 * 1000
+		}
+		if span/interval <= metricQueryMaxPoints {
+			return
+		}
+	}
+	return
+}
+
+// Query 查询 时间范围内的 数据，包含 开始时间，不包含 结束时间，未 指定 开始时间 查询 结束时间 前 1小时
+func (this_ *MetricStore) Query(hostKey string, tierName string, startTime int64, endTime int64) (tier *MetricTier, points []*MetricPoint, err error) {
+	hostKey = FormatHostKey(hostKey)
+	if endTime <= 0 {
+		endTime = util.GetNowMilli()
+	}
+	if startTime <= 0 {
+		startTime = endTime - metricQueryDefaultSpan
+	}
+	if tierName != "" {
+		tier = this_.getTier(tierName)
+		if tier == nil {
+			err = errors.New("metric tier [" + tierName + "] not found")
+			return
+		}
+	} else {
+		tier = this_.ChooseTier(startTime, endTime)
+	}
+	// 超过 保留天数 的 文件 已 删除，不再 逐天 查找
+	minStartTime := util.GetNowMilli() - int64(tier.SaveDays+1)*24*60*60*1000
+	if startTime < minStartTime {
+		startTime = minStartTime
+	}
+	if startTime >= endTime {
+		return
+	}
+
+	start := time.UnixMilli(startTime)
+	day := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, start.Location())
+	end := time.UnixMilli(endTime)
+	for !day.After(end) {
+		var list []*MetricPoint
+		list, err = readMetricFile(this_.getFilePath(hostKey, tier, day))
+		if err != nil {
+			return
+		}
+		for _, one := range list {
+			if one.Time >= startTime && one.Time < endTime {
+				points = append(points, one)
+			}
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+	sort.SliceStable(points, func(i, j int) bool {
+		return points[i].Time < points[j].Time
+	})
+	return
+}
+
+// Clean 删除 超过 保留天数 的 文件
+func (this_ *MetricStore) Clean() (deleteCount int, err error) {
+	hostDirs, err := os.ReadDir(this_.dir)
+	if err != nil {
+		return
+	}
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	for _, hostDir := range hostDirs {
+		if !hostDir.IsDir() {
+			continue
+		}
+		for _, tier := range this_.tiers {
+			tierDir := this_.dir + hostDir.Name() + "/" + tier.Name + "/"
+			files, _ := os.ReadDir(tierDir)
+			deleteBefore := today.AddDate(0, 0, -tier.SaveDays)
+			for _, file := range files {
+				if file.IsDir() || file.Name() == metricBucketFileName || !strings.HasSuffix(file.Name(), metricFileSuffix) {
+					continue
+				}
+				day, e := time.ParseInLocation(metricDayLayout, strings.TrimSuffix(file.Name(), metricFileSuffix), now.Location())
+				if e != nil || !day.Before(deleteBefore) {
+					continue
+				}
+				e = os.Remove(tierDir + file.Name())
+				if e != nil {
+					util.Logger.Error("metric file remove error", zap.Any("path", tierDir+file.Name()), zap.Error(e))
+					continue
+				}
+				deleteCount++
+			}
+		}
+	}
+	return
+}
+
+// aggregateMetricPoints 降采样：百分比、速度 取平均值，累计计数 取最后一个
+func aggregateMetricPoints(start int64, points []*MetricPoint) (res *MetricPoint) {
+	res = &MetricPoint{
+		Time: start,
+	}
+	size := len(points)
+	if size == 0 {
+		return
+	}
+	last := points[size-1]
+	res.MemTotal = last.MemTotal
+
+	var memUsed float64
+	var cpuCount = make(map[int]int)
+	for _, one := range points {
+		memUsed += float64(one.MemUsed)
+		res.MemUsedPercent += one.MemUsedPercent
+		for i, v := range one.CpuPercents {
+			if i >= len(res.CpuPercents) {
+				res.CpuPercents = append(res.CpuPercents, 0)
+			}
+			res.CpuPercents[i] += v
+			cpuCount[i]++
+		}
+	}
+	for i := range res.CpuPercents {
+		res.CpuPercents[i] = res.CpuPercents[i] / float64(cpuCount[i])
+	}
+	res.MemUsed = uint64(memUsed / float64(size))
+	res.MemUsedPercent = res.MemUsedPercent / float64(size)
+
+	res.Nets = aggregateMetricIOs(points, func(point *MetricPoint) []*MetricIO { return point.Nets })
+	res.Disks = aggregateMetricIOs(points, func(point *MetricPoint) []*MetricIO { return point.Disks })
+	return
+}
+
+func aggregateMetricIOs(points []*MetricPoint, get func(point *MetricPoint) []*MetricIO) (res []*MetricIO) {
+	cache := make(map[string]*MetricIO)
+	counts := make(map[string]uint64)
+	for _, point := range points {
+		for _, one := range get(point) {
+			find := cache[one.Name]
+			if find == nil {
+				find = &MetricIO{Name: one.Name}
+				cache[one.Name] = find
+				res = append(res, find)
+			}
+			find.OutBytes = one.OutBytes
+			find.InBytes = one.InBytes
+			find.OutSpeed += one.OutSpeed
+			find.InSpeed += one.InSpeed
+			counts[one.Name]++
+		}
+	}
+	for _, one := range res {
+		one.OutSpeed = one.OutSpeed / counts[one.Name]
+		one.InSpeed = one.InSpeed / counts[one.Name]
+	}
+	return
+}
+
+// encodeMetricRecord 记录格式：长度(uvarint) + 内容，内容 整数 使用 varint，百分比 使用 float32
+func encodeMetricRecord(point *MetricPoint) []byte {
+	var body []byte
+	body = appendVarint(body, point.Time)
+	body = appendUvarint(body, uint64(len(point.CpuPercents)))
+	for _, v := range point.CpuPercents {
+		body = appendFloat32(body, v)
+	}
+	body = appendUvarint(body, point.MemTotal)
+	body = appendUvarint(body, point.MemUsed)
+	body = appendFloat32(body, point.MemUsedPercent)
+	for _, ios := range [][]*MetricIO{point.Nets, point.Disks} {
+		body = appendUvarint(body, uint64(len(ios)))
+		for _, one := range ios {
+			body = appendUvarint(body, uint64(len(one.Name)))
+			body = append(body, one.Name...)
+			body = appendUvarint(body, one.OutBytes)
+			body = appendUvarint(body, one.InBytes)
+			body = appendUvarint(body, one.OutSpeed)
+			body = appendUvarint(body, one.InSpeed)
+		}
+	}
+	var record []byte
+	record = appendUvarint(record, uint64(len(body)))
+	return append(record, body...)
+}
+
+func appendVarint(bs []byte, v int64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutVarint(buf[:], v)
+	return append(bs, buf[:n]...)
+}
+
+func appendUvarint(bs []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], v)
+	return append(bs, buf[:n]...)
+}
+
+func appendFloat32(bs []byte, v float64) []byte {
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], math.Float32bits(float32(v)))
+	return append(bs, buf[:]...)
+}
+
+var errMetricRecord = errors.New("metric record is invalid")
+
+func decodeMetricRecord(body []byte) (point *MetricPoint, err error) {
+	reader := &metricReader{bs: body}
+	point = &MetricPoint{}
+	point.Time = reader.varint()
+	cpuSize := reader.uvarint()
+	for i := uint64(0); i < cpuSize && reader.err == nil; i++ {
+		point.CpuPercents = append(point.CpuPercents, float64(reader.float32()))
+	}
+	point.MemTotal = reader.uvarint()
+	point.MemUsed = reader.uvarint()
+	point.MemUsedPercent = float64(reader.float32())
+	for n := 0; n < 2; n++ {
+		var ios []*MetricIO
+		size := reader.uvarint()
+		for i := uint64(0); i < size && reader.err == nil; i++ {
+			one := &MetricIO{}
+			one.Name = reader.string()
+			one.OutBytes = reader.uvarint()
+			one.InBytes = reader.uvarint()
+			one.OutSpeed = reader.uvarint()
+			one.InSpeed = reader.uvarint()
+			ios = append(ios, one)
+		}
+		if n == 0 {
+			point.Nets = ios
+		} else {
+			point.Disks = ios
+		}
+	}
+	err = reader.err
+	return
+}
+
+// readMetricFile 读取 文件中 所有记录，最后一条 不完整（如：写入时 进程退出）则 忽略
+func readMetricFile(path string) (points []*MetricPoint, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		}
+		return
+	}
+	defer func() { _ = f.Close() }()
+
+	reader := bufio.NewReader(f)
+	for {
+		size, e := binary.ReadUvarint(reader)
+		if e != nil {
+			break
+		}
+		if size > metricRecordMaxSize {
+			util.Logger.Error("metric record size too large", zap.Any("path", path), zap.Any("size", size))
+			break
+		}
+		body := make([]byte, size)
+		_, e = io.ReadFull(reader, body)
+		if e != nil {
+			break
+		}
+		point, e := decodeMetricRecord(body)
+		if e != nil {
+			util.Logger.Error("metric record decode error", zap.Any("path", path), zap.Error(e))
+			continue
+		}
+		points = append(points, point)
+	}
+	return
+}
+
+type metricReader struct {
+	bs  []byte
+	err error
+}
+
+func (this_ *metricReader) varint() (v int64) {
+	if this_.err != nil {
+		return
+	}
+	v, n := binary.Varint(this_.bs)
+	if n <= 0 {
+		this_.err = errMetricRecord
+		return
+	}
+	this_.bs = this_.bs[n:]
+	return
+}
+
+func (this_ *metricReader) uvarint() (v uint64) {
+	if this_.err != nil {
+		return
+	}
+	v, n := binary.Uvarint(this_.bs)
+	if n <= 0 {
+		this_.err = errMetricRecord
+		return
+	}
+	this_.bs = this_.bs[n:]
+	return
+}
+
+func (this_ *metricReader) float32() (v float32) {
+	if this_.err != nil {
+		return
+	}
+	if len(this_.bs) < 4 {
+		this_.err = errMetricRecord
+		return
+	}
+	v = math.Float32frombits(binary.LittleEndian.Uint32(this_.bs))
+	this_.bs = this_.bs[4:]
+	return
+}
+
+func (this_ *metricReader) string() (v string) {
+	size := this_.uvarint()
+	if this_.err != nil {
+		return
+	}
+	if uint64(len(this_.bs)) < size {
+		this_.err = errMetricRecord
+		return
+	}
+	v = string(this_.bs[:size])
+	this_.bs = this_.bs[size:]
+	return
+}
@@ -0,0 +1,124 @@
+package system
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricStore(t *testing.T) {
+	store, err := NewMetricStore(t.TempDir(), DefaultMetricTiers(30))
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now().Add(-time.Hour).Truncate(time.Hour).UnixMilli()
+	for i := int64(0); i < 13; i++ {
+		monitorData := &MonitorData{
+			StartTime:   start + i*10*1000,
+			CpuPercents: []float64{float64(i), 50},
+			VirtualMemoryStat: &VirtualMemoryStat{
+				Total:       1000,
+				Used:        uint64(100 + i),
+				UsedPercent: float64(10 + i),
+			},
+			NetIOCountersStats: []*NetIOCountersStat{
+				{Name: "eth0", BytesSent: uint64(i * 100), SpeedSent: 10},
+			},
+		}
+		err = store.Append("ssh-1", NewMetricPoint(monitorData))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, points, err := store.Query("ssh-1", "raw", start, start+60*1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(points) != 6 {
+		t.Fatalf("raw points size %d", len(points))
+	}
+	if points[5].CpuPercents[0] != 5 || points[5].MemUsed != 105 || points[5].Nets[0].OutBytes != 500 {
+		t.Fatalf("raw point error %+v", points[5])
+	}
+
+	// 第2分钟 开始后 写入 第1分钟 平均值
+	tier, points, err := store.Query("ssh-1", "1m", start, start+3*60*1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tier.Name != "1m" || len(points) != 2 {
+		t.Fatalf("1m points size %d", len(points))
+	}
+	if points[0].Time != start || points[0].CpuPercents[0] != 2.5 || points[0].Nets[0].OutBytes != 500 || points[0].Nets[0].OutSpeed != 10 {
+		t.Fatalf("1m point error %+v", points[0])
+	}
+
+	// 重启 后 恢复 未 结束 的 时间段，主机 不再 上报 时 由 FlushIdle 写入
+	store, err = NewMetricStore(store.dir, DefaultMetricTiers(30))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bucket := store.buckets["ssh-1/1h"]; bucket == nil || bucket.start != start || len(bucket.points) != 13 {
+		t.Fatalf("bucket not restored %+v", bucket)
+	}
+	flushCount, err := store.FlushIdle()
+	if err != nil || flushCount != 1 || len(store.buckets) != 1 {
+		t.Fatal(flushCount, err, len(store.buckets))
+	}
+	_, points, _ = store.Query("ssh-1", "1m", start, start+3*60*1000)
+	if len(points) != 3 || points[2].Time != start+2*60*1000 {
+		t.Fatalf("1m points after flush %d", len(points))
+	}
+
+	// 未 指定 开始时间 查询 最近 1小时
+	_, points, _ = store.Query("ssh-1", "raw", 0, start+60*1000)
+	if len(points) != 6 {
+		t.Fatalf("default span points size %d", len(points))
+	}
+
+	tier = store.ChooseTier(start, start+60*60*1000)
+	if tier.Name != "raw" {
+		t.Fatalf("choose tier %s", tier.Name)
+	}
+	tier = store.ChooseTier(start, start+24*60*60*1000)
+	if tier.Name != "1m" {
+		t.Fatalf("choose tier %s", tier.Name)
+	}
+}
+
+func TestReadMetricFileSizeLimit(t *testing.T) {
+	path := t.TempDir() + "/test.dat"
+	bs := encodeMetricRecord(&MetricPoint{Time: 1})
+	bs = appendUvarint(bs, metricRecordMaxSize+1)
+	bs = append(bs, encodeMetricRecord(&MetricPoint{Time: 2})...)
+	err := os.WriteFile(path, bs, 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	points, err := readMetricFile(path)
+	if err != nil || len(points) != 1 || points[0].Time != 1 {
+		t.Fatal(points, err)
+	}
+}
+
+func TestWritePrometheus(t *testing.T) {
+	onMonitorData("ssh-\"1\"", &MonitorData{
+		CpuPercents:       []float64{12.5},
+		VirtualMemoryStat: &VirtualMemoryStat{Total: 1024},
+	})
+	buf := &bytes.Buffer{}
+	err := WritePrometheus(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := buf.String()
+	if !strings.Contains(text, `teamide_cpu_usage_percent{host="ssh-\"1\"",cpu="0"} 12.5`) {
+		t.Fatal(text)
+	}
+	if !strings.Contains(text, "# TYPE teamide_memory_total_bytes gauge") {
+		t.Fatal(text)
+	}
+}
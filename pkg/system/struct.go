@@ -128,6 +128,7 @@ type MonitorData struct {
 	CpuPercents         []float64             `json:"cpuPercents,omitempty"`
 	NetIOCountersStats  []*NetIOCountersStat  `json:"netIOCountersStats,omitempty"`
 	DiskIOCountersStats []*DiskIOCountersStat `json:"diskIOCountersStats,omitempty"`
+	DiskUsageStats      []*DiskUsageStat      `json:"diskUsageStats,omitempty"`
 	StartTime           int64                 `json:"startTime,omitempty"`
 	EndTime             int64                 `json:"endTime,omitempty"`
 }
@@ -140,12 +141,17 @@ type Info struct {
 }
 
 type QueryRequest struct {
-	Timestamp int64 `json:"timestamp,omitempty"`
-	Size      int   `json:"size,omitempty"`
+	Timestamp int64  `json:"timestamp,omitempty"`
+	Size      int    `json:"size,omitempty"`
+	HostKey   string `json:"hostKey,omitempty"`   // 主机标识，如：local、ssh-1、node-xxx，为空 则为 本机
+	StartTime int64  `json:"startTime,omitempty"` // 范围查询 开始时间 毫秒，查询 持久化 数据
+	EndTime   int64  `json:"endTime,omitempty"`   // 范围查询 结束时间 毫秒，为空 则为 当前时间
+	Tier      string `json:"tier,omitempty"`      // 数据精度，如：raw、1m、1h，为空 根据 时间范围 自动选择
 }
 
 type QueryResponse struct {
 	LastTimestamp   int64          `json:"lastTimestamp,omitempty"`
 	MonitorDataList []*MonitorData `json:"monitorDataList,omitempty"`
 	Size            int            `json:"size,omitempty"`
+	Tier            string         `json:"tier,omitempty"`
 }
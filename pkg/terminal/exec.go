@@ -0,0 +1,95 @@
+package terminal
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// ExecResult 执行 命令、脚本 的 结果，超时 或 无法执行 ExitCode 为 -1
+type ExecResult struct {
+	Stdout    string `json:"stdout"`
+	Stderr    string `json:"stderr"`
+	ExitCode  int    `json:"exitCode"`
+	StartTime int64  `json:"startTime,omitempty"`
+	EndTime   int64  `json:"endTime,omitempty"`
+	Duration  int64  `json:"duration"` // 耗时 毫秒
+	Error     string `json:"error,omitempty"`
+}
+
+// Output 标准输出 及 错误输出
+func (this_ *ExecResult) Output() string {
+	if this_.Stderr == "" {
+		return this_.Stdout
+	}
+	if this_.Stdout == "" {
+		return this_.Stderr
+	}
+	return this_.Stdout + "\n" + this_.Stderr
+}
+
+// SetError 记录 无法执行 或 超时 的 错误
+func (this_ *ExecResult) SetError(err error) {
+	this_.ExitCode = -1
+	this_.Error = err.Error()
+}
+
+var (
+	ExecTimeoutError = errors.New("执行超时")
+
+	execWaitDelay = 2 * time.Second
+)
+
+// Exec 在本机 执行 脚本，Windows 使用 cmd，其它 使用 bash 或 sh
+func Exec(script string, timeout time.Duration) (res *ExecResult) {
+	res = &ExecResult{
+		StartTime: time.Now().UnixMilli(),
+	}
+	defer func() {
+		res.EndTime = time.Now().UnixMilli()
+		res.Duration = res.EndTime - res.StartTime
+	}()
+
+	if timeout <= 0 {
+		timeout = time.Minute
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if IsWindows() {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", script)
+	} else {
+		shell := "bash"
+		if _, err := os.Stat("/bin/bash"); os.IsNotExist(err) {
+			shell = "sh"
+		}
+		cmd = exec.CommandContext(ctx, shell, "-c", script)
+	}
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	// 脚本 中 后台 启动 的 子进程 不会 随 超时 被 杀掉，不 等待 其 关闭 输出
+	setWaitDelay(cmd, execWaitDelay)
+
+	err := cmd.Run()
+	res.Stdout = stdout.String()
+	res.Stderr = stderr.String()
+	if ctx.Err() == context.DeadlineExceeded {
+		res.SetError(ExecTimeoutError)
+		return
+	}
+	if err != nil {
+		var exitError *exec.ExitError
+		if errors.As(err, &exitError) {
+			res.ExitCode = exitError.ExitCode()
+			return
+		}
+		res.SetError(err)
+	}
+	return
+}
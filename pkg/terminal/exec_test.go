@@ -0,0 +1,25 @@
+package terminal
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExec(t *testing.T) {
+	if IsWindows() {
+		return
+	}
+	res := Exec("echo out; echo err 1>&2; exit 3", 0)
+	if res.Stdout != "out\n" || res.Stderr != "err\n" || res.ExitCode != 3 || res.Error != "" {
+		t.Fatalf("exec %+v", res)
+	}
+	res = Exec("sleep 2", 100*time.Millisecond)
+	if res.ExitCode != -1 || res.Error != ExecTimeoutError.Error() {
+		t.Fatalf("timeout %+v", res)
+	}
+	// 后台 子进程 持有 输出 管道，超时 后 不 等待 其 结束
+	res = Exec("sleep 10 & sleep 10", 100*time.Millisecond)
+	if res.Error != ExecTimeoutError.Error() || res.Duration > 5000 {
+		t.Fatalf("background timeout %+v", res)
+	}
+}
@@ -0,0 +1,13 @@
+//go:build go1.20
+
+package terminal
+
+import (
+	"os/exec"
+	"time"
+)
+
+// setWaitDelay 超时 杀掉 进程 后，子进程 仍 持有 输出 管道 时 最多 再 等待 delay
+func setWaitDelay(cmd *exec.Cmd, delay time.Duration) {
+	cmd.WaitDelay = delay
+}
@@ -0,0 +1,12 @@
+//go:build !go1.20
+
+package terminal
+
+import (
+	"os/exec"
+	"time"
+)
+
+// setWaitDelay go1.20 以下 不支持 WaitDelay
+func setWaitDelay(_ *exec.Cmd, _ time.Duration) {
+}
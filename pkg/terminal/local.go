@@ -119,3 +119,15 @@ func (this_ *localService) SystemInfo() (res *system.Info, err error) {
 func (this_ *localService) SystemMonitorData() (res *system.MonitorData, err error) {
 	return system.GetCacheOrNew()
 }
+
+func (this_ *localService) SystemProcesses() (res []*system.ProcessInfo, err error) {
+	return system.GetProcesses()
+}
+
+func (this_ *localService) SystemPorts() (res []*system.PortInfo, err error) {
+	return system.GetPorts()
+}
+
+func (this_ *localService) SystemSignal(pid int32, signal string) (err error) {
+	return system.SignalProcess(pid, signal)
+}
@@ -0,0 +1,170 @@
+package terminal
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Runbook 保存的 运行手册，脚本中 使用 ${name} 引用参数，每个主机 可以 设置 不同的 参数值
+type Runbook struct {
+	Script      string          `json:"script,omitempty"`
+	Params      []*RunbookParam `json:"params,omitempty"`
+	Concurrency int             `json:"concurrency,omitempty"`
+	Timeout     int             `json:"timeout,omitempty"` // 单个主机 超时 秒数
+}
+
+type RunbookParam struct {
+	Name     string `json:"name,omitempty"`
+	Comment  string `json:"comment,omitempty"`
+	Default  string `json:"default,omitempty"`
+	Required bool   `json:"required,omitempty"`
+}
+
+var (
+	scriptParamRegexp = regexp.MustCompile(`\$\{\s*([\w.\-]+)\s*}`)
+)
+
+// GetParams 合并 参数，优先级：主机参数 > 公共参数 > 默认值，必填参数 为空 报错
+func (this_ *Runbook) GetParams(values map[string]string, hostValues map[string]string) (params map[string]string, err error) {
+	params = make(map[string]string)
+	for _, one := range this_.Params {
+		params[one.Name] = one.Default
+	}
+	for k, v := range values {
+		params[k] = v
+	}
+	for k, v := range hostValues {
+		params[k] = v
+	}
+	for _, one := range this_.Params {
+		if one.Required && params[one.Name] == "" {
+			err = errors.New("参数[" + one.Name + "]不能为空")
+			return
+		}
+	}
+	return
+}
+
+// RenderScript 替换 脚本中的 ${name}，未设置的 保持原样，如：脚本 自身的 ${HOME}
+func RenderScript(script string, params map[string]string) string {
+	return scriptParamRegexp.ReplaceAllStringFunc(script, func(s string) string {
+		name := scriptParamRegexp.FindStringSubmatch(s)[1]
+		value, ok := params[name]
+		if !ok {
+			return s
+		}
+		return value
+	})
+}
+
+type HostOutput struct {
+	HostKey  string `json:"hostKey"`
+	Output   string `json:"output"`
+	ExitCode int    `json:"exitCode"`
+}
+
+// OutputGroup 输出 及 退出码 相同的 主机 分为 一组
+type OutputGroup struct {
+	Output   string      `json:"output"`
+	ExitCode int         `json:"exitCode"`
+	HostKeys []string    `json:"hostKeys"`
+	Diff     []*DiffLine `json:"diff,omitempty"` // 与 基准组 的 差异
+}
+
+const (
+	DiffEqual  = "="
+	DiffInsert = "+"
+	DiffDelete = "-"
+
+	// 超过 该行数 的 输出 只比较 前面部分
+	diffMaxLines = 2000
+)
+
+type DiffLine struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// GroupOutputs 按 输出 分组，主机数 最多的 组 作为 基准，其它组 计算 与 基准 的 行差异
+func GroupOutputs(outputs []*HostOutput) (groups []*OutputGroup) {
+	var groupCache = make(map[string]*OutputGroup)
+	for _, one := range outputs {
+		output := strings.TrimRight(one.Output, "\r\n")
+		key := strconv.Itoa(one.ExitCode) + "\x00" + output
+		group := groupCache[key]
+		if group == nil {
+			group = &OutputGroup{
+				Output:   output,
+				ExitCode: one.ExitCode,
+			}
+			groupCache[key] = group
+			groups = append(groups, group)
+		}
+		group.HostKeys = append(group.HostKeys, one.HostKey)
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		return len(groups[i].HostKeys) > len(groups[j].HostKeys)
+	})
+	if len(groups) < 2 {
+		return
+	}
+	baseLines := splitLines(groups[0].Output)
+	for _, group := range groups[1:] {
+		group.Diff = DiffLines(baseLines, splitLines(group.Output))
+	}
+	return
+}
+
+func splitLines(text string) []string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	lines := strings.Split(text, "\n")
+	if len(lines) > diffMaxLines {
+		lines = lines[:diffMaxLines]
+	}
+	return lines
+}
+
+// DiffLines 基于 最长公共子序列 计算 行差异
+func DiffLines(a []string, b []string) (res []*DiffLine) {
+	n, m := len(a), len(b)
+	lcs := make([][]int32, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			res = append(res, &DiffLine{Type: DiffEqual, Text: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			res = append(res, &DiffLine{Type: DiffDelete, Text: a[i]})
+			i++
+		default:
+			res = append(res, &DiffLine{Type: DiffInsert, Text: b[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		res = append(res, &DiffLine{Type: DiffDelete, Text: a[i]})
+	}
+	for ; j < m; j++ {
+		res = append(res, &DiffLine{Type: DiffInsert, Text: b[j]})
+	}
+	return
+}
@@ -0,0 +1,51 @@
+package terminal
+
+import (
+	"testing"
+)
+
+func TestRenderScript(t *testing.T) {
+	runbook := &Runbook{
+		Script: "systemctl ${action} ${ service } && echo ${HOME} ${host.name}",
+		Params: []*RunbookParam{
+			{Name: "action", Default: "status"},
+			{Name: "service", Required: true},
+		},
+	}
+	_, err := runbook.GetParams(nil, nil)
+	if err == nil {
+		t.Fatal("required param should error")
+	}
+	params, err := runbook.GetParams(map[string]string{"service": "nginx"}, map[string]string{"action": "restart"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	params["host.name"] = "web-1"
+	script := RenderScript(runbook.Script, params)
+	if script != "systemctl restart nginx && echo ${HOME} web-1" {
+		t.Fatalf("script [%s]", script)
+	}
+}
+
+func TestGroupOutputs(t *testing.T) {
+	groups := GroupOutputs([]*HostOutput{
+		{HostKey: "a", Output: "v1\nok\n"},
+		{HostKey: "b", Output: "v2\nok", ExitCode: 0},
+		{HostKey: "c", Output: "v1\nok"},
+		{HostKey: "d", Output: "v1\nok", ExitCode: 1},
+	})
+	if len(groups) != 3 {
+		t.Fatalf("groups %d", len(groups))
+	}
+	if len(groups[0].HostKeys) != 2 || groups[0].HostKeys[0] != "a" || groups[0].HostKeys[1] != "c" || groups[0].Diff != nil {
+		t.Fatalf("base group %+v", groups[0])
+	}
+	diff := groups[1].Diff
+	if len(diff) != 3 || diff[0].Type != DiffDelete || diff[0].Text != "v1" || diff[1].Type != DiffInsert || diff[1].Text != "v2" || diff[2].Type != DiffEqual {
+		t.Fatalf("diff %+v %+v %+v", diff[0], diff[1], diff[2])
+	}
+	// 输出 相同，退出码 不同
+	if groups[2].ExitCode != 1 || len(groups[2].Diff) != 2 {
+		t.Fatalf("exit code group %+v", groups[2])
+	}
+}
@@ -18,4 +18,8 @@ type Service interface {
 
 	SystemInfo() (res *system.Info, err error)
 	SystemMonitorData() (res *system.MonitorData, err error)
+
+	SystemProcesses() (res []*system.ProcessInfo, err error)
+	SystemPorts() (res []*system.PortInfo, err error)
+	SystemSignal(pid int32, signal string) (err error)
 }
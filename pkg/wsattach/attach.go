@@ -0,0 +1,109 @@
+package wsattach
+
+import (
+	"errors"
+	"github.com/gorilla/websocket"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Upgrader 会话 websocket 升级，会话 通过 key 及 用户 校验，不 校验 来源
+var Upgrader = websocket.Upgrader{
+	ReadBufferSize:  32 * 1024,
+	WriteBufferSize: 32 * 1024,
+	CheckOrigin: func(r *http.Request) bool {
+		return true
+	},
+}
+
+// ErrClosed 会话 已 关闭
+var ErrClosed = errors.New("会话已关闭")
+
+// Socket 会话 当前 连接 的 websocket，重复 连接 时 替换 并 关闭 之前 的，超时 未 连接 时 回调
+type Socket struct {
+	ws        *websocket.Conn
+	attached  bool
+	isClosed  bool
+	timer     *time.Timer
+	lock      sync.Mutex
+	writeLock sync.Mutex
+}
+
+// New 超过 attachTimeout 未 连接 websocket 时 执行 onTimeout，由 onTimeout 释放 会话
+func New(attachTimeout time.Duration, onTimeout func()) (socket *Socket) {
+	socket = &Socket{}
+	socket.timer = time.AfterFunc(attachTimeout, func() {
+		socket.lock.Lock()
+		idle := !socket.attached && !socket.isClosed
+		socket.lock.Unlock()
+		if idle {
+			onTimeout()
+		}
+	})
+	return
+}
+
+// Attach 连接 websocket，首次 连接 时 first 为 true，已 关闭 的 关闭 ws 并 返回 ErrClosed
+func (this_ *Socket) Attach(ws *websocket.Conn) (first bool, err error) {
+	this_.lock.Lock()
+	if this_.isClosed {
+		this_.lock.Unlock()
+		_ = ws.Close()
+		err = ErrClosed
+		return
+	}
+	first = !this_.attached
+	this_.attached = true
+	old := this_.ws
+	this_.ws = ws
+	this_.lock.Unlock()
+
+	this_.timer.Stop()
+	if old != nil {
+		this_.writeLock.Lock()
+		_ = old.Close()
+		this_.writeLock.Unlock()
+	}
+	return
+}
+
+// Current websocket 是否 为 当前 连接 的，被 替换 的 websocket 出错 时 不 应 停止 会话
+func (this_ *Socket) Current(ws *websocket.Conn) bool {
+	this_.lock.Lock()
+	defer this_.lock.Unlock()
+	return this_.ws == ws
+}
+
+// Write 写入 当前 websocket，返回 写入 的 websocket，未 连接 时 不 写入
+func (this_ *Socket) Write(data []byte) (ws *websocket.Conn, err error) {
+	this_.lock.Lock()
+	ws = this_.ws
+	this_.lock.Unlock()
+	if ws == nil {
+		return
+	}
+	this_.writeLock.Lock()
+	err = ws.WriteMessage(websocket.TextMessage, data)
+	this_.writeLock.Unlock()
+	return
+}
+
+// Close 停止 超时 并 关闭 当前 websocket，之后 连接 的 直接 关闭
+func (this_ *Socket) Close() {
+	this_.lock.Lock()
+	if this_.isClosed {
+		this_.lock.Unlock()
+		return
+	}
+	this_.isClosed = true
+	ws := this_.ws
+	this_.lock.Unlock()
+
+	this_.timer.Stop()
+	if ws != nil {
+		this_.writeLock.Lock()
+		_ = ws.Close()
+		this_.writeLock.Unlock()
+	}
+}
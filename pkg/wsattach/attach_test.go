@@ -0,0 +1,114 @@
+package wsattach
+
+import (
+	"github.com/gorilla/websocket"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newConnPair 建立 一对 websocket 连接，返回 服务端 与 客户端
+func newConnPair(t *testing.T) (server *websocket.Conn, client *websocket.Conn) {
+	serverCh := make(chan *websocket.Conn, 1)
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := Upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		serverCh <- ws
+	}))
+	t.Cleanup(httpServer.Close)
+
+	client, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(httpServer.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+	server = <-serverCh
+	return
+}
+
+func TestSocketAttach(t *testing.T) {
+	socket := New(time.Minute, func() {
+		t.Error("attached socket timeout")
+	})
+
+	server1, client1 := newConnPair(t)
+	first, err := socket.Attach(server1)
+	if err != nil || !first {
+		t.Fatalf("first attach: first=%v err=%v", first, err)
+	}
+	if _, err = socket.Write([]byte("one")); err != nil {
+		t.Fatal(err)
+	}
+	if _, bs, err := client1.ReadMessage(); err != nil || string(bs) != "one" {
+		t.Fatalf("read one: %s %v", bs, err)
+	}
+
+	// 重复 连接 替换 并 关闭 之前 的
+	server2, client2 := newConnPair(t)
+	first, err = socket.Attach(server2)
+	if err != nil || first {
+		t.Fatalf("second attach: first=%v err=%v", first, err)
+	}
+	if socket.Current(server1) || !socket.Current(server2) {
+		t.Fatal("current socket not replaced")
+	}
+	if _, _, err = client1.ReadMessage(); err == nil {
+		t.Fatal("replaced socket not closed")
+	}
+	ws, err := socket.Write([]byte("two"))
+	if err != nil || ws != server2 {
+		t.Fatalf("write two: %v", err)
+	}
+	if _, bs, err := client2.ReadMessage(); err != nil || string(bs) != "two" {
+		t.Fatalf("read two: %s %v", bs, err)
+	}
+
+	// 关闭 后 连接 的 直接 关闭
+	socket.Close()
+	if _, _, err = client2.ReadMessage(); err == nil {
+		t.Fatal("socket not closed")
+	}
+	server3, client3 := newConnPair(t)
+	if _, err = socket.Attach(server3); err != ErrClosed {
+		t.Fatalf("attach after close: %v", err)
+	}
+	if _, _, err = client3.ReadMessage(); err == nil {
+		t.Fatal("socket attached after close not closed")
+	}
+}
+
+func TestSocketAttachTimeout(t *testing.T) {
+	timeout := make(chan struct{})
+	socket := New(20*time.Millisecond, func() {
+		close(timeout)
+	})
+	if ws, err := socket.Write([]byte("none")); ws != nil || err != nil {
+		t.Fatalf("write without attach: %v %v", ws, err)
+	}
+	select {
+	case <-timeout:
+	case <-time.After(time.Second):
+		t.Fatal("attach timeout not called")
+	}
+
+	// 已 连接 或 已 关闭 的 不 回调
+	for _, attach := range []bool{true, false} {
+		socket = New(20*time.Millisecond, func() {
+			t.Errorf("timeout called, attach=%v", attach)
+		})
+		if attach {
+			server, _ := newConnPair(t)
+			if _, err := socket.Attach(server); err != nil {
+				t.Fatal(err)
+			}
+		} else {
+			socket.Close()
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
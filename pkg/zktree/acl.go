@@ -0,0 +1,130 @@
+package zktree
+
+import (
+	"errors"
+	"github.com/go-zookeeper/zk"
+	"strings"
+)
+
+// Acl 节点 权限，Perms 为 cdrwa 组合
+// digest 的 Id 为 user:base64(sha1(user:password))，设置 Password 时 根据 Id 中 的 user 计算
+type Acl struct {
+	Scheme   string `json:"scheme" yaml:"scheme"`
+	Id       string `json:"id" yaml:"id"`
+	Perms    string `json:"perms" yaml:"perms"`
+	Password string `json:"password,omitempty" yaml:"-"`
+}
+
+var permChars = []struct {
+	char byte
+	perm int32
+}{
+	{'c', zk.PermCreate},
+	{'d', zk.PermDelete},
+	{'r', zk.PermRead},
+	{'w', zk.PermWrite},
+	{'a', zk.PermAdmin},
+}
+
+func FormatPerms(perms int32) string {
+	var res []byte
+	for _, one := range permChars {
+		if perms&one.perm != 0 {
+			res = append(res, one.char)
+		}
+	}
+	return string(res)
+}
+
+func ParsePerms(perms string) (res int32, err error) {
+	for _, c := range []byte(strings.ToLower(perms)) {
+		find := false
+		for _, one := range permChars {
+			if one.char == c {
+				res |= one.perm
+				find = true
+				break
+			}
+		}
+		if !find {
+			err = errors.New("权限[" + perms + "]格式错误，只能包含 cdrwa")
+			return
+		}
+	}
+	if res == 0 {
+		err = errors.New("权限不能为空")
+	}
+	return
+}
+
+func FromZkAcl(list []zk.ACL) (res []*Acl) {
+	for _, one := range list {
+		res = append(res, &Acl{Scheme: one.Scheme, Id: one.ID, Perms: FormatPerms(one.Perms)})
+	}
+	return
+}
+
+// ToZkAcl 转换 为 zk 权限，支持 world、ip、digest、auth
+func ToZkAcl(list []*Acl) (res []zk.ACL, err error) {
+	if len(list) == 0 {
+		err = errors.New("权限列表不能为空")
+		return
+	}
+	for _, one := range list {
+		var perms int32
+		if perms, err = ParsePerms(one.Perms); err != nil {
+			return
+		}
+		switch one.Scheme {
+		case "world":
+			res = append(res, zk.ACL{Perms: perms, Scheme: "world", ID: "anyone"})
+		case "auth":
+			res = append(res, zk.ACL{Perms: perms, Scheme: "auth", ID: ""})
+		case "ip":
+			if one.Id == "" {
+				err = errors.New("ip 权限需要指定地址")
+				return
+			}
+			res = append(res, zk.ACL{Perms: perms, Scheme: "ip", ID: one.Id})
+		case "digest":
+			user := one.Id
+			if index := strings.Index(user, ":"); index >= 0 {
+				user = user[:index]
+			}
+			if user == "" {
+				err = errors.New("digest 权限需要指定用户")
+				return
+			}
+			if one.Password != "" {
+				res = append(res, zk.DigestACL(perms, user, one.Password)...)
+			} else if strings.Contains(one.Id, ":") {
+				res = append(res, zk.ACL{Perms: perms, Scheme: "digest", ID: one.Id})
+			} else {
+				err = errors.New("digest 权限[" + one.Id + "]需要指定密码或 user:digest")
+				return
+			}
+		default:
+			err = errors.New("不支持的权限类型[" + one.Scheme + "]")
+			return
+		}
+	}
+	return
+}
+
+// EqualAcl 比较 权限，忽略 顺序
+func EqualAcl(a []zk.ACL, b []zk.ACL) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	count := map[zk.ACL]int{}
+	for _, one := range a {
+		count[one]++
+	}
+	for _, one := range b {
+		if count[one] == 0 {
+			return false
+		}
+		count[one]--
+	}
+	return true
+}
@@ -0,0 +1,268 @@
+package zktree
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/go-zookeeper/zk"
+	"sort"
+	"strings"
+)
+
+const (
+	ChangeCreate = "create"
+	ChangeUpdate = "update"
+	ChangeAcl    = "acl"
+	ChangeDelete = "delete"
+)
+
+// Target 写入 节点，*zk.Conn 已 实现
+type Target interface {
+	Source
+	Create(path string, data []byte, flags int32, acl []zk.ACL) (string, error)
+	Set(path string, data []byte, version int32) (*zk.Stat, error)
+	SetACL(path string, acl []zk.ACL, version int32) (*zk.Stat, error)
+	Delete(path string, version int32) error
+}
+
+type PlanOptions struct {
+	Acl    bool `json:"acl"`    // 导入 权限，未 导出 权限 的 节点 创建 时 使用 world:anyone:cdrwa
+	Delete bool `json:"delete"` // 删除 目标 中 文件 里 不存在 的 节点
+}
+
+// Change 导入 时 的 一项 变更，Version 为 读取 时 的 版本，执行 时 用于 校验 目标 未被 修改
+type Change struct {
+	Type    string `json:"type"`
+	Path    string `json:"path"`
+	OldData string `json:"oldData,omitempty"`
+	NewData string `json:"newData,omitempty"`
+	OldAcl  []*Acl `json:"oldAcl,omitempty"`
+	NewAcl  []*Acl `json:"newAcl,omitempty"`
+	Version int32  `json:"version"`
+
+	data []byte
+	acl  []zk.ACL
+}
+
+// displayData 非 UTF-8 数据 以 base64: 前缀 展示
+func displayData(data []byte) string {
+	node := NewNode("", data)
+	if node.Encoding != "" {
+		return node.Encoding + ":" + node.Data
+	}
+	return node.Data
+}
+
+// Plan 比较 文件 与 目标 子树，生成 变更，创建 与 修改 按 先序 排列，删除 按 深度 倒序 排列 在 最后
+func Plan(target Source, tree *Tree, root string, options *PlanOptions) (changes []*Change, err error) {
+	if options == nil {
+		options = &PlanOptions{}
+	}
+	if root, err = CleanPath(root); err != nil {
+		return
+	}
+	if tree == nil || tree.Root == nil {
+		err = errors.New("没有需要导入的节点")
+		return
+	}
+	var deletes []*Change
+
+	// 创建 不存在 的 上级 节点
+	for index := 1; index < len(root); index++ {
+		if root[index] != '/' {
+			continue
+		}
+		parent := root[:index]
+		if _, _, err = target.Get(parent); err == nil {
+			continue
+		}
+		if !errors.Is(err, zk.ErrNoNode) {
+			err = errors.New("读取节点[" + parent + "]失败:" + err.Error())
+			return
+		}
+		err = nil
+		changes = append(changes, &Change{Type: ChangeCreate, Path: parent, acl: zk.WorldACL(zk.PermAll)})
+	}
+
+	var plan func(p string, node *Node, exists bool) (err error)
+	plan = func(p string, node *Node, exists bool) (err error) {
+		data, err := node.GetData()
+		if err != nil {
+			return
+		}
+		var acl []zk.ACL
+		if options.Acl && len(node.Acl) > 0 {
+			if acl, err = ToZkAcl(node.Acl); err != nil {
+				err = errors.New("节点[" + p + "]权限错误:" + err.Error())
+				return
+			}
+		}
+
+		var oldData []byte
+		var stat *zk.Stat
+		if exists {
+			oldData, stat, err = target.Get(p)
+			if errors.Is(err, zk.ErrNoNode) {
+				exists = false
+				err = nil
+			} else if err != nil {
+				err = errors.New("读取节点[" + p + "]失败:" + err.Error())
+				return
+			}
+		}
+
+		if !exists {
+			change := &Change{Type: ChangeCreate, Path: p, NewData: displayData(data), NewAcl: node.Acl, data: data, acl: acl}
+			if change.acl == nil {
+				change.acl = zk.WorldACL(zk.PermAll)
+			}
+			changes = append(changes, change)
+			for _, child := range node.Children {
+				if err = checkChildName(p, child); err != nil {
+					return
+				}
+				if err = plan(JoinPath(p, child.Name), child, false); err != nil {
+					return
+				}
+			}
+			return
+		}
+
+		if string(oldData) != string(data) {
+			changes = append(changes, &Change{Type: ChangeUpdate, Path: p, OldData: displayData(oldData), NewData: displayData(data), Version: stat.Version, data: data})
+		}
+		if acl != nil {
+			var oldAcl []zk.ACL
+			var aclStat *zk.Stat
+			if oldAcl, aclStat, err = target.GetACL(p); err != nil {
+				err = errors.New("读取节点[" + p + "]权限失败:" + err.Error())
+				return
+			}
+			if !EqualAcl(oldAcl, acl) {
+				changes = append(changes, &Change{Type: ChangeAcl, Path: p, OldAcl: FromZkAcl(oldAcl), NewAcl: node.Acl, Version: aclStat.Aversion, acl: acl})
+			}
+		}
+
+		names, _, err := target.Children(p)
+		if err != nil {
+			err = errors.New("读取节点[" + p + "]子节点失败:" + err.Error())
+			return
+		}
+		existNames := map[string]bool{}
+		for _, name := range names {
+			existNames[name] = true
+		}
+		nodeNames := map[string]bool{}
+		for _, child := range node.Children {
+			if err = checkChildName(p, child); err != nil {
+				return
+			}
+			nodeNames[child.Name] = true
+			if err = plan(JoinPath(p, child.Name), child, existNames[child.Name]); err != nil {
+				return
+			}
+		}
+		if options.Delete {
+			sort.Strings(names)
+			for _, name := range names {
+				childPath := JoinPath(p, name)
+				if nodeNames[name] || childPath == "/zookeeper" {
+					continue
+				}
+				if err = planDelete(target, childPath, &deletes); err != nil {
+					return
+				}
+			}
+		}
+		return
+	}
+	if err = plan(root, tree.Root, true); err != nil {
+		return
+	}
+
+	sort.SliceStable(deletes, func(i, j int) bool {
+		return strings.Count(deletes[i].Path, "/") > strings.Count(deletes[j].Path, "/")
+	})
+	changes = append(changes, deletes...)
+	return
+}
+
+// checkChildName 子节点 名称 不能 为 空 或 包含 /，否则 拼接 后 的 路径 会 指向 其它 节点
+func checkChildName(p string, child *Node) (err error) {
+	if child.Name == "" || strings.Contains(child.Name, "/") {
+		err = errors.New("节点[" + p + "]下的子节点名称[" + child.Name + "]错误")
+	}
+	return
+}
+
+// planDelete 删除 节点 及 子树
+func planDelete(target Source, p string, deletes *[]*Change) (err error) {
+	data, stat, err := target.Get(p)
+	if errors.Is(err, zk.ErrNoNode) {
+		err = nil
+		return
+	}
+	if err != nil {
+		err = errors.New("读取节点[" + p + "]失败:" + err.Error())
+		return
+	}
+	*deletes = append(*deletes, &Change{Type: ChangeDelete, Path: p, OldData: displayData(data), Version: stat.Version})
+	names, _, err := target.Children(p)
+	if err != nil {
+		err = errors.New("读取节点[" + p + "]子节点失败:" + err.Error())
+		return
+	}
+	for _, name := range names {
+		if err = planDelete(target, JoinPath(p, name), deletes); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// PlanKey 变更 的 摘要，用于 确认 执行 时 的 变更 与 预览 一致
+func PlanKey(changes []*Change) string {
+	bs, _ := json.Marshal(changes)
+	sum := md5.Sum(bs)
+	return hex.EncodeToString(sum[:])
+}
+
+// Apply 按 顺序 执行 变更，遇到 错误 停止，返回 已 执行 数
+func Apply(target Target, changes []*Change) (applied int, err error) {
+	for _, change := range changes {
+		switch change.Type {
+		case ChangeCreate:
+			_, err = target.Create(change.Path, change.data, 0, change.acl)
+		case ChangeUpdate:
+			_, err = target.Set(change.Path, change.data, change.Version)
+		case ChangeAcl:
+			_, err = target.SetACL(change.Path, change.acl, change.Version)
+		case ChangeDelete:
+			err = target.Delete(change.Path, change.Version)
+		default:
+			err = errors.New("不支持的变更类型[" + change.Type + "]")
+		}
+		if err != nil {
+			err = ConflictError(change.Path, err)
+			return
+		}
+		applied++
+	}
+	return
+}
+
+// ConflictError 版本 冲突 等 错误 附加 节点 路径 与 说明
+func ConflictError(p string, err error) error {
+	switch {
+	case errors.Is(err, zk.ErrBadVersion):
+		return errors.New("节点[" + p + "]已被其他人修改，请刷新后重试")
+	case errors.Is(err, zk.ErrNodeExists):
+		return errors.New("节点[" + p + "]已存在，请刷新后重试")
+	case errors.Is(err, zk.ErrNoNode):
+		return errors.New("节点[" + p + "]不存在，请刷新后重试")
+	case errors.Is(err, zk.ErrNotEmpty):
+		return errors.New("节点[" + p + "]下存在子节点，请刷新后重试")
+	}
+	return errors.New("节点[" + p + "]:" + err.Error())
+}
@@ -0,0 +1,207 @@
+package zktree
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-zookeeper/zk"
+	"gopkg.in/yaml.v3"
+	"path"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+
+	encodingBase64 = "base64"
+
+	defaultMaxNodes = 10000
+)
+
+// Source 读取 节点，*zk.Conn 已 实现
+type Source interface {
+	Get(path string) ([]byte, *zk.Stat, error)
+	Children(path string) ([]string, *zk.Stat, error)
+	GetACL(path string) ([]zk.ACL, *zk.Stat, error)
+}
+
+// Tree 导出 的 子树 文件
+type Tree struct {
+	Path string `json:"path" yaml:"path"`
+	Time int64  `json:"time" yaml:"time"`
+	Root *Node  `json:"root" yaml:"root"`
+}
+
+// Node 子树 节点，非 UTF-8 数据 使用 base64 编码
+type Node struct {
+	Name     string  `json:"name" yaml:"name"`
+	Data     string  `json:"data,omitempty" yaml:"data,omitempty"`
+	Encoding string  `json:"encoding,omitempty" yaml:"encoding,omitempty"`
+	Acl      []*Acl  `json:"acl,omitempty" yaml:"acl,omitempty"`
+	Children []*Node `json:"children,omitempty" yaml:"children,omitempty"`
+}
+
+type ExportOptions struct {
+	Acl              bool `json:"acl"`              // 导出 权限
+	IncludeEphemeral bool `json:"includeEphemeral"` // 导出 临时 节点，默认 跳过
+	MaxNodes         int  `json:"maxNodes"`         // 最多 导出 节点 数，默认 10000
+}
+
+func NewNode(name string, data []byte) (node *Node) {
+	node = &Node{Name: name}
+	node.SetData(data)
+	return
+}
+
+func (this_ *Node) SetData(data []byte) {
+	if utf8.Valid(data) {
+		this_.Data = string(data)
+		this_.Encoding = ""
+	} else {
+		this_.Data = base64.StdEncoding.EncodeToString(data)
+		this_.Encoding = encodingBase64
+	}
+}
+
+func (this_ *Node) GetData() (data []byte, err error) {
+	switch this_.Encoding {
+	case "":
+		data = []byte(this_.Data)
+	case encodingBase64:
+		data, err = base64.StdEncoding.DecodeString(this_.Data)
+	default:
+		err = errors.New("节点[" + this_.Name + "]数据编码[" + this_.Encoding + "]不支持")
+	}
+	return
+}
+
+// JoinPath 拼接 子 节点 路径
+func JoinPath(parent string, name string) string {
+	if parent == "/" {
+		return "/" + name
+	}
+	return parent + "/" + name
+}
+
+// CleanPath 校验 并 规范 节点 路径
+func CleanPath(p string) (res string, err error) {
+	if !strings.HasPrefix(p, "/") {
+		err = errors.New("节点路径[" + p + "]必须以 / 开头")
+		return
+	}
+	res = path.Clean(p)
+	return
+}
+
+// Export 导出 子树，导出 根 节点 时 跳过 /zookeeper 系统 节点
+func Export(source Source, root string, options *ExportOptions) (tree *Tree, err error) {
+	if options == nil {
+		options = &ExportOptions{}
+	}
+	maxNodes := options.MaxNodes
+	if maxNodes <= 0 {
+		maxNodes = defaultMaxNodes
+	}
+	if root, err = CleanPath(root); err != nil {
+		return
+	}
+	tree = &Tree{Path: root, Time: time.Now().UnixMilli()}
+	count := 0
+
+	var export func(p string, name string) (node *Node, err error)
+	export = func(p string, name string) (node *Node, err error) {
+		count++
+		if count > maxNodes {
+			err = fmt.Errorf("导出节点数超过限制 %d", maxNodes)
+			return
+		}
+		data, stat, err := source.Get(p)
+		if errors.Is(err, zk.ErrNoNode) && p != root {
+			// 读取 子 节点 列表 后 被 删除
+			err = nil
+			return
+		}
+		if err != nil {
+			err = errors.New("读取节点[" + p + "]失败:" + err.Error())
+			return
+		}
+		if stat.EphemeralOwner != 0 && !options.IncludeEphemeral && p != root {
+			return
+		}
+		node = NewNode(name, data)
+		if options.Acl {
+			var acl []zk.ACL
+			if acl, _, err = source.GetACL(p); err != nil {
+				err = errors.New("读取节点[" + p + "]权限失败:" + err.Error())
+				return
+			}
+			node.Acl = FromZkAcl(acl)
+		}
+		names, _, err := source.Children(p)
+		if err != nil {
+			err = errors.New("读取节点[" + p + "]子节点失败:" + err.Error())
+			return
+		}
+		sort.Strings(names)
+		for _, childName := range names {
+			childPath := JoinPath(p, childName)
+			if childPath == "/zookeeper" {
+				continue
+			}
+			var child *Node
+			if child, err = export(childPath, childName); err != nil {
+				return
+			}
+			if child != nil {
+				node.Children = append(node.Children, child)
+			}
+		}
+		return
+	}
+	tree.Root, err = export(root, path.Base(root))
+	return
+}
+
+// Marshal 输出 为 json 或 yaml
+func Marshal(tree *Tree, format string) (bs []byte, err error) {
+	switch format {
+	case FormatJSON, "":
+		bs, err = json.MarshalIndent(tree, "", "  ")
+	case FormatYAML, "yml":
+		bs, err = yaml.Marshal(tree)
+	default:
+		err = errors.New("不支持的格式[" + format + "]")
+	}
+	return
+}
+
+// Unmarshal 解析 json 或 yaml，format 为 空 时 根据 内容 判断
+func Unmarshal(bs []byte, format string) (tree *Tree, err error) {
+	if format == "" {
+		format = FormatYAML
+		if text := strings.TrimSpace(string(bs)); strings.HasPrefix(text, "{") {
+			format = FormatJSON
+		}
+	}
+	tree = &Tree{}
+	switch format {
+	case FormatJSON:
+		err = json.Unmarshal(bs, tree)
+	case FormatYAML, "yml":
+		err = yaml.Unmarshal(bs, tree)
+	default:
+		err = errors.New("不支持的格式[" + format + "]")
+	}
+	if err != nil {
+		return
+	}
+	if tree.Root == nil {
+		err = errors.New("文件中没有节点数据")
+	}
+	return
+}
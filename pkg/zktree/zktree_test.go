@@ -0,0 +1,271 @@
+package zktree
+
+import (
+	"github.com/go-zookeeper/zk"
+	"path"
+	"sort"
+	"testing"
+)
+
+type memNode struct {
+	data []byte
+	stat zk.Stat
+	acl  []zk.ACL
+}
+
+// memTarget 内存 中 的 zk 树
+type memTarget struct {
+	nodes map[string]*memNode
+}
+
+func newMemTarget() *memTarget {
+	return &memTarget{nodes: map[string]*memNode{"/": {}, "/zookeeper": {}}}
+}
+
+func (this_ *memTarget) Get(p string) ([]byte, *zk.Stat, error) {
+	one := this_.nodes[p]
+	if one == nil {
+		return nil, nil, zk.ErrNoNode
+	}
+	stat := one.stat
+	return one.data, &stat, nil
+}
+
+func (this_ *memTarget) Children(p string) (names []string, stat *zk.Stat, err error) {
+	if this_.nodes[p] == nil {
+		return nil, nil, zk.ErrNoNode
+	}
+	for one := range this_.nodes {
+		if one != "/" && path.Dir(one) == p {
+			names = append(names, path.Base(one))
+		}
+	}
+	sort.Strings(names)
+	return
+}
+
+func (this_ *memTarget) GetACL(p string) ([]zk.ACL, *zk.Stat, error) {
+	one := this_.nodes[p]
+	if one == nil {
+		return nil, nil, zk.ErrNoNode
+	}
+	stat := one.stat
+	return one.acl, &stat, nil
+}
+
+func (this_ *memTarget) Create(p string, data []byte, flags int32, acl []zk.ACL) (string, error) {
+	if this_.nodes[p] != nil {
+		return "", zk.ErrNodeExists
+	}
+	if this_.nodes[path.Dir(p)] == nil {
+		return "", zk.ErrNoNode
+	}
+	this_.nodes[p] = &memNode{data: data, acl: acl}
+	return p, nil
+}
+
+func (this_ *memTarget) Set(p string, data []byte, version int32) (*zk.Stat, error) {
+	one := this_.nodes[p]
+	if one == nil {
+		return nil, zk.ErrNoNode
+	}
+	if version != -1 && version != one.stat.Version {
+		return nil, zk.ErrBadVersion
+	}
+	one.data = data
+	one.stat.Version++
+	stat := one.stat
+	return &stat, nil
+}
+
+func (this_ *memTarget) SetACL(p string, acl []zk.ACL, version int32) (*zk.Stat, error) {
+	one := this_.nodes[p]
+	if one == nil {
+		return nil, zk.ErrNoNode
+	}
+	if version != -1 && version != one.stat.Aversion {
+		return nil, zk.ErrBadVersion
+	}
+	one.acl = acl
+	one.stat.Aversion++
+	stat := one.stat
+	return &stat, nil
+}
+
+func (this_ *memTarget) Delete(p string, version int32) error {
+	one := this_.nodes[p]
+	if one == nil {
+		return zk.ErrNoNode
+	}
+	if names, _, _ := this_.Children(p); len(names) > 0 {
+		return zk.ErrNotEmpty
+	}
+	if version != -1 && version != one.stat.Version {
+		return zk.ErrBadVersion
+	}
+	delete(this_.nodes, p)
+	return nil
+}
+
+func (this_ *memTarget) put(p string, data string) *memNode {
+	one := &memNode{data: []byte(data), acl: zk.WorldACL(zk.PermAll)}
+	this_.nodes[p] = one
+	return one
+}
+
+func TestAcl(t *testing.T) {
+	if FormatPerms(zk.PermAll) != "cdrwa" || FormatPerms(zk.PermRead|zk.PermWrite) != "rw" {
+		t.Fatal(FormatPerms(zk.PermAll))
+	}
+	if perms, err := ParsePerms("RW"); err != nil || perms != zk.PermRead|zk.PermWrite {
+		t.Fatal(perms, err)
+	}
+	if _, err := ParsePerms("rx"); err == nil {
+		t.Fatal("bad perms should error")
+	}
+	acl, err := ToZkAcl([]*Acl{
+		{Scheme: "world", Perms: "r"},
+		{Scheme: "ip", Id: "10.0.0.0/8", Perms: "cdrwa"},
+		{Scheme: "digest", Id: "admin", Password: "secret", Perms: "a"},
+	})
+	if err != nil || len(acl) != 3 || acl[0].ID != "anyone" {
+		t.Fatal(acl, err)
+	}
+	if expected := zk.DigestACL(zk.PermAdmin, "admin", "secret")[0]; acl[2] != expected {
+		t.Fatalf("digest %v %v", acl[2], expected)
+	}
+	if _, err = ToZkAcl([]*Acl{{Scheme: "digest", Id: "admin", Perms: "a"}}); err == nil {
+		t.Fatal("digest without password should error")
+	}
+	if !EqualAcl(acl, []zk.ACL{acl[2], acl[0], acl[1]}) || EqualAcl(acl, acl[:2]) {
+		t.Fatal("equal acl")
+	}
+}
+
+func TestExport(t *testing.T) {
+	source := newMemTarget()
+	source.put("/app", "root")
+	source.put("/app/a", "1")
+	source.put("/app/a/x", string([]byte{0xff, 0x00}))
+	source.put("/app/b", "")
+	source.put("/app/b/provider", "ephemeral").stat.EphemeralOwner = 1
+
+	tree, err := Export(source, "/app/", &ExportOptions{Acl: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tree.Path != "/app" || tree.Root.Name != "app" || tree.Root.Data != "root" || len(tree.Root.Children) != 2 {
+		t.Fatalf("tree %+v", tree.Root)
+	}
+	a, b := tree.Root.Children[0], tree.Root.Children[1]
+	if a.Name != "a" || a.Children[0].Encoding != "base64" || len(b.Children) != 0 || a.Acl[0].Perms != "cdrwa" {
+		t.Fatalf("children %+v %+v", a, b)
+	}
+	if _, err = Export(source, "/app", &ExportOptions{MaxNodes: 2}); err == nil {
+		t.Fatal("max nodes should error")
+	}
+
+	whole, err := Export(source, "/", nil)
+	if err != nil || len(whole.Root.Children) != 1 || whole.Root.Children[0].Name != "app" {
+		t.Fatalf("export root %+v %v", whole.Root, err)
+	}
+
+	for _, format := range []string{FormatJSON, FormatYAML} {
+		bs, err := Marshal(tree, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := Unmarshal(bs, "")
+		if err != nil {
+			t.Fatal(format, err)
+		}
+		data, err := res.Root.Children[0].Children[0].GetData()
+		if err != nil || len(data) != 2 || data[0] != 0xff || res.Root.Children[0].Acl[0].Id != "anyone" {
+			t.Fatalf("%s roundtrip %v %v", format, data, err)
+		}
+	}
+	if _, err = Unmarshal([]byte("path: /a"), ""); err == nil {
+		t.Fatal("empty tree should error")
+	}
+}
+
+func TestPlanApply(t *testing.T) {
+	source := newMemTarget()
+	source.put("/app", "root")
+	source.put("/app/a", "1")
+	source.put("/app/a/x", "x")
+	source.put("/app/c", "c")
+	source.nodes["/app/c"].acl = zk.WorldACL(zk.PermRead)
+	tree, err := Export(source, "/app", &ExportOptions{Acl: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 上级 节点 不存在 时 一并 创建
+	target := newMemTarget()
+	changes, err := Plan(target, tree, "/env/prod/app", &PlanOptions{Acl: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 6 || changes[0].Path != "/env" || changes[2].Path != "/env/prod/app" || changes[2].Type != ChangeCreate {
+		t.Fatalf("changes %+v", changes)
+	}
+	if applied, err := Apply(target, changes); err != nil || applied != 6 {
+		t.Fatal(applied, err)
+	}
+	if string(target.nodes["/env/prod/app/a/x"].data) != "x" || target.nodes["/env/prod/app/c"].acl[0].Perms != zk.PermRead {
+		t.Fatalf("applied %+v", target.nodes)
+	}
+
+	// 再次 导入 没有 变更
+	if changes, err = Plan(target, tree, "/env/prod/app", &PlanOptions{Acl: true, Delete: true}); err != nil || len(changes) != 0 {
+		t.Fatalf("no changes %+v %v", changes, err)
+	}
+
+	target.put("/env/prod/app/a", "changed").stat.Version = 3
+	target.nodes["/env/prod/app/c"].acl = zk.WorldACL(zk.PermAll)
+	target.put("/env/prod/app/extra", "")
+	target.put("/env/prod/app/extra/deep", "")
+	changes, err = Plan(target, tree, "/env/prod/app", &PlanOptions{Acl: true, Delete: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var types []string
+	for _, change := range changes {
+		types = append(types, change.Type+" "+change.Path)
+	}
+	expected := []string{"update /env/prod/app/a", "acl /env/prod/app/c", "delete /env/prod/app/extra/deep", "delete /env/prod/app/extra"}
+	if len(types) != len(expected) {
+		t.Fatalf("changes %v", types)
+	}
+	for i := range expected {
+		if types[i] != expected[i] {
+			t.Fatalf("changes %v", types)
+		}
+	}
+	if changes[0].OldData != "changed" || changes[0].NewData != "1" || changes[0].Version != 3 {
+		t.Fatalf("update %+v", changes[0])
+	}
+
+	// 预览 后 目标 被 修改，执行 时 版本 冲突
+	_, _ = target.Set("/env/prod/app/a", []byte("other"), -1)
+	if replan, _ := Plan(target, tree, "/env/prod/app", &PlanOptions{Acl: true, Delete: true}); PlanKey(replan) == PlanKey(changes) {
+		t.Fatal("plan key should change")
+	}
+	applied, err := Apply(target, changes)
+	if err == nil || applied != 0 || string(target.nodes["/env/prod/app/a"].data) != "other" {
+		t.Fatal(applied, err)
+	}
+
+	// 子节点 名称 错误，目标 存在 与 不存在 时 都 校验
+	for _, name := range []string{"", "/", "a/b"} {
+		bad := &Tree{Root: &Node{Name: "app", Children: []*Node{{Name: "new", Children: []*Node{{Name: name}}}}}}
+		if _, err = Plan(target, bad, "/env/prod/app", nil); err == nil {
+			t.Fatalf("child name [%s] should error", name)
+		}
+		bad = &Tree{Root: &Node{Name: "app", Children: []*Node{{Name: name}}}}
+		if _, err = Plan(target, bad, "/env/prod/app", nil); err == nil {
+			t.Fatalf("child name [%s] should error", name)
+		}
+	}
+}
@@ -0,0 +1,368 @@
+package zkwatch
+
+import (
+	"context"
+	"errors"
+	"github.com/go-zookeeper/zk"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	EventCreated         = "created"
+	EventDeleted         = "deleted"
+	EventDataChanged     = "dataChanged"
+	EventChildrenChanged = "childrenChanged"
+	EventError           = "error"
+
+	defaultMaxNodes = 1000
+	retryInterval   = time.Second
+)
+
+// Conn 监听 使用 的 连接，*zk.Conn 已 实现
+type Conn interface {
+	GetW(path string) ([]byte, *zk.Stat, <-chan zk.Event, error)
+	ChildrenW(path string) ([]string, *zk.Stat, <-chan zk.Event, error)
+	ExistsW(path string) (bool, *zk.Stat, <-chan zk.Event, error)
+}
+
+// Options 监听 配置
+type Options struct {
+	Recursive bool `json:"recursive"` // 监听 整个 子树
+	MaxNodes  int  `json:"maxNodes"`  // 子树 最多 监听 节点 数，默认 1000
+}
+
+// Event 节点 变更 事件，Old、New 为 变更 前后 的 数据 与 版本
+type Event struct {
+	Type        string   `json:"type"`
+	Path        string   `json:"path"`
+	OldData     string   `json:"oldData,omitempty"`
+	NewData     string   `json:"newData,omitempty"`
+	OldVersion  int32    `json:"oldVersion"`
+	NewVersion  int32    `json:"newVersion"`
+	OldCversion int32    `json:"oldCversion"`
+	NewCversion int32    `json:"newCversion"`
+	Added       []string `json:"added,omitempty"`
+	Removed     []string `json:"removed,omitempty"`
+	Error       string   `json:"error,omitempty"`
+	Time        int64    `json:"time"`
+}
+
+type node struct {
+	path      string
+	cancel    context.CancelFunc
+	exists    bool
+	data      []byte
+	stat      *zk.Stat
+	watchKids bool
+	restart   bool
+}
+
+// Watcher 监听 一个 节点 或 子树，watch 触发 后 自动 重新 注册
+type Watcher struct {
+	conn    Conn
+	root    string
+	options *Options
+	onEvent func(event *Event)
+
+	ctx       context.Context
+	cancel    context.CancelFunc
+	nodes     map[string]*node
+	overLimit bool
+	lock      sync.Mutex
+}
+
+func New(conn Conn, root string, options *Options, onEvent func(event *Event)) (watcher *Watcher, err error) {
+	if root == "" || !strings.HasPrefix(root, "/") {
+		err = errors.New("监听路径[" + root + "]格式错误")
+		return
+	}
+	if len(root) > 1 {
+		root = strings.TrimSuffix(root, "/")
+	}
+	if options == nil {
+		options = &Options{}
+	}
+	if options.MaxNodes <= 0 {
+		options.MaxNodes = defaultMaxNodes
+	}
+	watcher = &Watcher{
+		conn:    conn,
+		root:    root,
+		options: options,
+		onEvent: onEvent,
+		nodes:   map[string]*node{},
+	}
+	return
+}
+
+func (this_ *Watcher) Start() {
+	this_.ctx, this_.cancel = context.WithCancel(context.Background())
+	this_.watch(this_.ctx, this_.root, false)
+}
+
+func (this_ *Watcher) Stop() {
+	if this_.cancel != nil {
+		this_.cancel()
+	}
+}
+
+// NodeCount 当前 监听 的 节点 数
+func (this_ *Watcher) NodeCount() int {
+	this_.lock.Lock()
+	defer this_.lock.Unlock()
+	return len(this_.nodes)
+}
+
+func childPath(parent string, name string) string {
+	if parent == "/" {
+		return "/" + name
+	}
+	return parent + "/" + name
+}
+
+func (this_ *Watcher) emit(event *Event) {
+	if this_.ctx.Err() != nil {
+		return
+	}
+	event.Time = time.Now().UnixMilli()
+	this_.onEvent(event)
+}
+
+func (this_ *Watcher) emitError(path string, err error) {
+	this_.emit(&Event{Type: EventError, Path: path, Error: err.Error()})
+}
+
+// watch 开始 监听 节点，isNew 为 true 表示 监听 开始 后 新建 的 节点，需 推送 created
+func (this_ *Watcher) watch(parent context.Context, path string, isNew bool) {
+	this_.lock.Lock()
+	if _, find := this_.nodes[path]; find {
+		this_.lock.Unlock()
+		return
+	}
+	if path != this_.root && len(this_.nodes) >= this_.options.MaxNodes {
+		overLimit := this_.overLimit
+		this_.overLimit = true
+		this_.lock.Unlock()
+		if !overLimit {
+			this_.emitError(path, errors.New("监听节点数超过限制，后续新增节点不再监听"))
+		}
+		return
+	}
+	ctx, cancel := context.WithCancel(parent)
+	one := &node{path: path, cancel: cancel}
+	this_.nodes[path] = one
+	this_.lock.Unlock()
+
+	go this_.dataLoop(ctx, one, isNew)
+}
+
+func (this_ *Watcher) removeNode(one *node) {
+	this_.lock.Lock()
+	if this_.nodes[one.path] == one {
+		delete(this_.nodes, one.path)
+	}
+	this_.lock.Unlock()
+	one.cancel()
+}
+
+func (this_ *Watcher) isWatching(path string) bool {
+	this_.lock.Lock()
+	defer this_.lock.Unlock()
+	_, find := this_.nodes[path]
+	return find
+}
+
+// wait 等待 watch 触发，返回 false 表示 监听 已 停止
+func (this_ *Watcher) wait(ctx context.Context, ch <-chan zk.Event) (event zk.Event, ok bool) {
+	select {
+	case <-ctx.Done():
+		return
+	case event = <-ch:
+	}
+	if event.Type == zk.EventNotWatching {
+		if errors.Is(event.Err, zk.ErrClosing) || errors.Is(event.Err, zk.ErrConnectionClosed) {
+			return
+		}
+		// 会话 过期 等 导致 watch 失效，稍后 重新 注册
+		if !this_.sleep(ctx) {
+			return
+		}
+	}
+	ok = true
+	return
+}
+
+func (this_ *Watcher) sleep(ctx context.Context) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(retryInterval):
+		return true
+	}
+}
+
+// dataLoop 监听 节点 数据，根 节点 删除 后 等待 重新 创建，子 节点 删除 后 退出
+func (this_ *Watcher) dataLoop(ctx context.Context, one *node, isNew bool) {
+	defer this_.removeNode(one)
+
+	first := true
+	for ctx.Err() == nil {
+		data, stat, ch, err := this_.conn.GetW(one.path)
+		if errors.Is(err, zk.ErrNoNode) {
+			if one.exists {
+				one.exists = false
+				this_.emit(&Event{Type: EventDeleted, Path: one.path, OldData: string(one.data), OldVersion: one.stat.Version, OldCversion: one.stat.Cversion})
+			}
+			if one.path != this_.root {
+				return
+			}
+			var exists bool
+			exists, _, ch, err = this_.conn.ExistsW(one.path)
+			if err == nil && exists {
+				continue
+			}
+		}
+		if err != nil {
+			if errors.Is(err, zk.ErrClosing) || errors.Is(err, zk.ErrConnectionClosed) {
+				return
+			}
+			this_.emitError(one.path, err)
+			if !this_.sleep(ctx) {
+				return
+			}
+			continue
+		}
+
+		if stat != nil {
+			created := !one.exists || stat.Czxid != one.stat.Czxid
+			if !one.exists {
+				if !first || isNew {
+					this_.emit(&Event{Type: EventCreated, Path: one.path, NewData: string(data), NewVersion: stat.Version, NewCversion: stat.Cversion})
+				}
+			} else if stat.Czxid != one.stat.Czxid {
+				// 删除 后 重新 创建
+				this_.emit(&Event{Type: EventDeleted, Path: one.path, OldData: string(one.data), OldVersion: one.stat.Version, OldCversion: one.stat.Cversion})
+				this_.emit(&Event{Type: EventCreated, Path: one.path, NewData: string(data), NewVersion: stat.Version, NewCversion: stat.Cversion})
+			} else if stat.Version != one.stat.Version {
+				this_.emit(&Event{Type: EventDataChanged, Path: one.path, OldData: string(one.data), NewData: string(data), OldVersion: one.stat.Version, NewVersion: stat.Version, OldCversion: one.stat.Cversion, NewCversion: stat.Cversion})
+			}
+			one.exists = true
+			one.data = data
+			one.stat = stat
+			if created {
+				this_.startChildren(ctx, one, first && !isNew)
+			}
+		}
+		first = false
+
+		if _, ok := this_.wait(ctx, ch); !ok {
+			return
+		}
+	}
+}
+
+// startChildren 节点 存在 时 启动 子 节点 监听，节点 删除 后 由 childrenLoop 自行 退出
+func (this_ *Watcher) startChildren(ctx context.Context, one *node, initial bool) {
+	this_.lock.Lock()
+	if one.watchKids {
+		// 旧 的 childrenLoop 可能 正在 因 节点 删除 退出，退出 后 重新 启动
+		one.restart = true
+		this_.lock.Unlock()
+		return
+	}
+	one.watchKids = true
+	this_.lock.Unlock()
+
+	go this_.childrenLoop(ctx, one, initial)
+}
+
+func (this_ *Watcher) childrenLoop(ctx context.Context, one *node, initial bool) {
+	restart := false
+	defer func() {
+		if restart {
+			go this_.childrenLoop(ctx, one, false)
+		}
+	}()
+	defer func() {
+		this_.lock.Lock()
+		restart = one.restart && ctx.Err() == nil
+		one.restart = false
+		one.watchKids = restart
+		this_.lock.Unlock()
+	}()
+
+	var previous []string
+	var previousStat *zk.Stat
+	first := true
+	for ctx.Err() == nil {
+		children, stat, ch, err := this_.conn.ChildrenW(one.path)
+		if errors.Is(err, zk.ErrNoNode) {
+			return
+		}
+		if err != nil {
+			if errors.Is(err, zk.ErrClosing) || errors.Is(err, zk.ErrConnectionClosed) {
+				return
+			}
+			this_.emitError(one.path, err)
+			if !this_.sleep(ctx) {
+				return
+			}
+			continue
+		}
+		sort.Strings(children)
+
+		if !first {
+			added, removed := DiffChildren(previous, children)
+			if len(added) > 0 || len(removed) > 0 {
+				event := &Event{Type: EventChildrenChanged, Path: one.path, Added: added, Removed: removed, NewVersion: stat.Version, NewCversion: stat.Cversion}
+				if previousStat != nil {
+					event.OldVersion = previousStat.Version
+					event.OldCversion = previousStat.Cversion
+				}
+				this_.emit(event)
+			}
+		}
+		if this_.options.Recursive {
+			// 与 当前 监听 中 的 节点 比较，删除 后 快速 重建 的 节点 也 能 重新 监听
+			isNew := !(first && initial)
+			for _, name := range children {
+				path := childPath(one.path, name)
+				if !this_.isWatching(path) {
+					this_.watch(ctx, path, isNew)
+				}
+			}
+		}
+		previous = children
+		previousStat = stat
+		first = false
+
+		event, ok := this_.wait(ctx, ch)
+		if !ok || event.Type == zk.EventNodeDeleted {
+			return
+		}
+	}
+}
+
+// DiffChildren 比较 排序 后 的 子 节点 列表
+func DiffChildren(old []string, new []string) (added []string, removed []string) {
+	oldSet := map[string]bool{}
+	for _, name := range old {
+		oldSet[name] = true
+	}
+	newSet := map[string]bool{}
+	for _, name := range new {
+		newSet[name] = true
+		if !oldSet[name] {
+			added = append(added, name)
+		}
+	}
+	for _, name := range old {
+		if !newSet[name] {
+			removed = append(removed, name)
+		}
+	}
+	return
+}
@@ -0,0 +1,264 @@
+package zkwatch
+
+import (
+	"github.com/go-zookeeper/zk"
+	"path"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeNode struct {
+	data     []byte
+	stat     zk.Stat
+	children map[string]bool
+}
+
+// fakeConn 内存 中 的 zk 树，watch 触发 一次 后 移除
+type fakeConn struct {
+	lock       sync.Mutex
+	zxid       int64
+	nodes      map[string]*fakeNode
+	dataWatch  map[string][]chan zk.Event
+	childWatch map[string][]chan zk.Event
+}
+
+func newFakeConn() *fakeConn {
+	return &fakeConn{
+		nodes:      map[string]*fakeNode{"/": {children: map[string]bool{}}},
+		dataWatch:  map[string][]chan zk.Event{},
+		childWatch: map[string][]chan zk.Event{},
+	}
+}
+
+func (this_ *fakeConn) addWatch(watches map[string][]chan zk.Event, p string) <-chan zk.Event {
+	ch := make(chan zk.Event, 1)
+	watches[p] = append(watches[p], ch)
+	return ch
+}
+
+func (this_ *fakeConn) fire(watches map[string][]chan zk.Event, p string, eventType zk.EventType) {
+	for _, ch := range watches[p] {
+		ch <- zk.Event{Type: eventType, Path: p}
+	}
+	delete(watches, p)
+}
+
+func (this_ *fakeConn) GetW(p string) ([]byte, *zk.Stat, <-chan zk.Event, error) {
+	this_.lock.Lock()
+	defer this_.lock.Unlock()
+	one := this_.nodes[p]
+	if one == nil {
+		return nil, nil, nil, zk.ErrNoNode
+	}
+	stat := one.stat
+	return one.data, &stat, this_.addWatch(this_.dataWatch, p), nil
+}
+
+func (this_ *fakeConn) ChildrenW(p string) ([]string, *zk.Stat, <-chan zk.Event, error) {
+	this_.lock.Lock()
+	defer this_.lock.Unlock()
+	one := this_.nodes[p]
+	if one == nil {
+		return nil, nil, nil, zk.ErrNoNode
+	}
+	var children []string
+	for name := range one.children {
+		children = append(children, name)
+	}
+	stat := one.stat
+	return children, &stat, this_.addWatch(this_.childWatch, p), nil
+}
+
+func (this_ *fakeConn) ExistsW(p string) (bool, *zk.Stat, <-chan zk.Event, error) {
+	this_.lock.Lock()
+	defer this_.lock.Unlock()
+	ch := this_.addWatch(this_.dataWatch, p)
+	one := this_.nodes[p]
+	if one == nil {
+		return false, nil, ch, nil
+	}
+	stat := one.stat
+	return true, &stat, ch, nil
+}
+
+func (this_ *fakeConn) create(p string, data string) {
+	this_.lock.Lock()
+	defer this_.lock.Unlock()
+	this_.zxid++
+	this_.nodes[p] = &fakeNode{data: []byte(data), stat: zk.Stat{Czxid: this_.zxid}, children: map[string]bool{}}
+	parent := this_.nodes[path.Dir(p)]
+	parent.children[path.Base(p)] = true
+	parent.stat.Cversion++
+	this_.fire(this_.dataWatch, p, zk.EventNodeCreated)
+	this_.fire(this_.childWatch, path.Dir(p), zk.EventNodeChildrenChanged)
+}
+
+func (this_ *fakeConn) set(p string, data string) {
+	this_.lock.Lock()
+	defer this_.lock.Unlock()
+	one := this_.nodes[p]
+	one.data = []byte(data)
+	one.stat.Version++
+	this_.fire(this_.dataWatch, p, zk.EventNodeDataChanged)
+}
+
+func (this_ *fakeConn) delete(p string) {
+	this_.lock.Lock()
+	defer this_.lock.Unlock()
+	delete(this_.nodes, p)
+	parent := this_.nodes[path.Dir(p)]
+	delete(parent.children, path.Base(p))
+	parent.stat.Cversion++
+	this_.fire(this_.dataWatch, p, zk.EventNodeDeleted)
+	this_.fire(this_.childWatch, p, zk.EventNodeDeleted)
+	this_.fire(this_.childWatch, path.Dir(p), zk.EventNodeChildrenChanged)
+}
+
+// expect 等待 收到 全部 期望 事件（不 要求 顺序），key 为 type + path
+func expect(t *testing.T, events chan *Event, keys ...string) (res map[string]*Event) {
+	t.Helper()
+	res = map[string]*Event{}
+	want := map[string]bool{}
+	for _, key := range keys {
+		want[key] = true
+	}
+	timeout := time.After(3 * time.Second)
+	for len(want) > 0 {
+		select {
+		case event := <-events:
+			key := event.Type + " " + event.Path
+			if !want[key] {
+				t.Fatalf("unexpected event %+v, want %v", event, want)
+			}
+			delete(want, key)
+			res[key] = event
+		case <-timeout:
+			t.Fatalf("timeout, want %v", want)
+		}
+	}
+	return
+}
+
+func waitNodes(t *testing.T, watcher *Watcher, count int) {
+	t.Helper()
+	for i := 0; i < 300; i++ {
+		if watcher.NodeCount() == count {
+			// 等待 子 节点 watch 注册
+			time.Sleep(20 * time.Millisecond)
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("node count %d, want %d", watcher.NodeCount(), count)
+}
+
+func TestWatcher(t *testing.T) {
+	conn := newFakeConn()
+	conn.create("/app", "")
+	conn.create("/app/a", "1")
+
+	events := make(chan *Event, 100)
+	watcher, err := New(conn, "/app/", &Options{Recursive: true}, func(event *Event) { events <- event })
+	if err != nil {
+		t.Fatal(err)
+	}
+	watcher.Start()
+	defer watcher.Stop()
+	waitNodes(t, watcher, 2)
+
+	conn.set("/app/a", "2")
+	res := expect(t, events, "dataChanged /app/a")
+	if event := res["dataChanged /app/a"]; event.OldData != "1" || event.NewData != "2" || event.OldVersion != 0 || event.NewVersion != 1 {
+		t.Fatalf("data event %+v", event)
+	}
+
+	// 数据 变更 后 watch 重新 注册
+	conn.set("/app/a", "3")
+	expect(t, events, "dataChanged /app/a")
+
+	conn.create("/app/b", "x")
+	res = expect(t, events, "childrenChanged /app", "created /app/b")
+	if event := res["childrenChanged /app"]; len(event.Added) != 1 || event.Added[0] != "b" || event.NewCversion != event.OldCversion+1 {
+		t.Fatalf("children event %+v", event)
+	}
+	if res["created /app/b"].NewData != "x" {
+		t.Fatalf("created event %+v", res["created /app/b"])
+	}
+	waitNodes(t, watcher, 3)
+
+	conn.create("/app/b/c", "deep")
+	expect(t, events, "childrenChanged /app/b", "created /app/b/c")
+	waitNodes(t, watcher, 4)
+
+	conn.delete("/app/a")
+	res = expect(t, events, "childrenChanged /app", "deleted /app/a")
+	if event := res["deleted /app/a"]; event.OldData != "3" || event.OldVersion != 2 {
+		t.Fatalf("deleted event %+v", event)
+	}
+	if event := res["childrenChanged /app"]; len(event.Removed) != 1 || event.Removed[0] != "a" {
+		t.Fatalf("children event %+v", event)
+	}
+	waitNodes(t, watcher, 3)
+
+	// 根 节点 删除 后 等待 重新 创建
+	conn.delete("/app/b/c")
+	expect(t, events, "childrenChanged /app/b", "deleted /app/b/c")
+	conn.delete("/app/b")
+	expect(t, events, "childrenChanged /app", "deleted /app/b")
+	conn.delete("/app")
+	expect(t, events, "deleted /app")
+	waitNodes(t, watcher, 1)
+
+	conn.create("/app", "again")
+	expect(t, events, "created /app")
+	time.Sleep(50 * time.Millisecond)
+	conn.create("/app/d", "")
+	expect(t, events, "childrenChanged /app", "created /app/d")
+
+	watcher.Stop()
+	conn.set("/app/d", "stopped")
+	select {
+	case event := <-events:
+		t.Fatalf("event after stop %+v", event)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestWatcherMaxNodes(t *testing.T) {
+	conn := newFakeConn()
+	conn.create("/app", "")
+	for _, name := range []string{"a", "b", "c"} {
+		conn.create("/app/"+name, "")
+	}
+	events := make(chan *Event, 100)
+	watcher, err := New(conn, "/app", &Options{Recursive: true, MaxNodes: 2}, func(event *Event) { events <- event })
+	if err != nil {
+		t.Fatal(err)
+	}
+	watcher.Start()
+	defer watcher.Stop()
+
+	select {
+	case event := <-events:
+		if event.Type != EventError {
+			t.Fatalf("event %+v", event)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("timeout")
+	}
+	waitNodes(t, watcher, 2)
+
+	if _, err = New(conn, "app", nil, nil); err == nil {
+		t.Fatal("relative path should error")
+	}
+}
+
+func TestDiffChildren(t *testing.T) {
+	added, removed := DiffChildren([]string{"a", "b", "c"}, []string{"b", "c", "d", "e"})
+	sort.Strings(added)
+	if len(added) != 2 || added[0] != "d" || added[1] != "e" || len(removed) != 1 || removed[0] != "a" {
+		t.Fatalf("added %v removed %v", added, removed)
+	}
+}